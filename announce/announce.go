@@ -0,0 +1,246 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package announce renders the state of a bar as plain descriptive text, for
+speech synthesis or a braille display, so that the bar can be used without
+seeing it.
+
+Each segment is described by the module it belongs to (by default, the
+package of the module, e.g. "battery") and its text, without any markup or
+icons, e.g. "battery: 85% charging". Urgent segments are announced as such,
+e.g. "battery, urgent: 5%". Once started, only segments that changed are
+announced, so the readout keeps up with the bar.
+
+Typical usage would be:
+
+	b := bar.New().Add(clock, battery, wifi)
+	a := announce.New(b, announce.Speak()).
+		Label("0", "time").
+		Mute("2").
+		Start()
+	b.Run()
+
+where modules are named as in the bar's Stats, i.e. by the order they were
+added. A braille display or screen reader can instead read the lines from a
+file or pipe, using announce.Writer.
+*/
+package announce
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/pango"
+)
+
+var log = logging.New("announce")
+
+// Sink receives each line to announce.
+type Sink func(line string)
+
+// Writer returns a sink that writes each line to w.
+func Writer(w io.Writer) Sink {
+	return func(line string) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			log.Error("could not write announcement", "error", err)
+		}
+	}
+}
+
+// Speak returns a sink that speaks each line using spd-say (from
+// speech-dispatcher), with any additional arguments, e.g. Speak("-r", "30")
+// to speak faster.
+func Speak(args ...string) Sink {
+	return func(line string) {
+		if err := speak(append(append([]string(nil), args...), "--", line)); err != nil {
+			log.Error("could not speak announcement", "error", err)
+		}
+	}
+}
+
+// speak runs spd-say with the given arguments, replaced in tests.
+var speak = func(args []string) error {
+	return exec.Command("spd-say", args...).Run()
+}
+
+// Announcer announces changes to a bar.
+type Announcer struct {
+	bar  *bar.I3Bar
+	sink Sink
+	stop func()
+
+	mutex  sync.Mutex
+	labels map[string]string
+	muted  map[string]bool
+	// The last announced segments, by module name and index, and the
+	// order in which they were shown.
+	last  map[string]segment
+	order []string
+}
+
+// segment is an announced segment.
+type segment struct {
+	label string
+	line  string
+}
+
+// New creates an announcer for the bar, which sends each line to the sink.
+// It does nothing until it is started.
+func New(b *bar.I3Bar, sink Sink) *Announcer {
+	return &Announcer{
+		bar:    b,
+		sink:   sink,
+		labels: map[string]string{},
+		muted:  map[string]bool{},
+	}
+}
+
+// Label sets the label used to announce the named module, instead of the
+// package of the module.
+func (a *Announcer) Label(name, label string) *Announcer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.labels[name] = label
+	return a
+}
+
+// Mute stops announcing changes to the named module, e.g. for a clock
+// that shows seconds.
+func (a *Announcer) Mute(name string) *Announcer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.muted[name] = true
+	return a
+}
+
+// Start announces the current state of the bar, and then any changes
+// whenever the bar is printed.
+func (a *Announcer) Start() *Announcer {
+	updates, stop := a.bar.Subscribe()
+	a.mutex.Lock()
+	a.stop = stop
+	a.mutex.Unlock()
+	a.Announce()
+	go func() {
+		for range updates {
+			a.Announce()
+		}
+	}()
+	return a
+}
+
+// Stop stops announcing changes.
+func (a *Announcer) Stop() {
+	a.mutex.Lock()
+	stop := a.stop
+	a.stop = nil
+	a.mutex.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// Announce announces all segments that changed since the last
+// announcement, including segments that are no longer shown.
+func (a *Announcer) Announce() {
+	for _, line := range a.changes(false) {
+		a.sink(line)
+	}
+}
+
+// ReadAll announces every segment on the bar, e.g. from a hotkey to hear
+// the whole bar again.
+func (a *Announcer) ReadAll() {
+	for _, line := range a.changes(true) {
+		a.sink(line)
+	}
+}
+
+// changes returns the lines to announce, and records the current state.
+func (a *Announcer) changes(all bool) []string {
+	modules := a.bar.Stats().Modules
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	current := map[string]segment{}
+	var order []string
+	var lines []string
+	for _, m := range modules {
+		if a.muted[m.Name] {
+			continue
+		}
+		label, ok := a.labels[m.Name]
+		if !ok {
+			label = typeLabel(m.Type)
+		}
+		for idx, s := range m.Output {
+			text := plainText(s)
+			if text == "" {
+				continue
+			}
+			line := label + ": " + text
+			if urgent, _ := s["urgent"].(bool); urgent {
+				line = label + ", urgent: " + text
+			}
+			key := fmt.Sprintf("%s/%d", m.Name, idx)
+			current[key] = segment{label, line}
+			order = append(order, key)
+			if all || a.last[key].line != line {
+				lines = append(lines, line)
+			}
+		}
+	}
+	if !all {
+		for _, key := range a.order {
+			if _, ok := current[key]; !ok {
+				lines = append(lines, a.last[key].label+": hidden")
+			}
+		}
+	}
+	a.last, a.order = current, order
+	return lines
+}
+
+// typeLabel returns the package of a module's type, e.g. "battery" for
+// "*battery.module".
+func typeLabel(typeName string) string {
+	typeName = strings.TrimLeft(typeName, "*")
+	if idx := strings.Index(typeName, "."); idx >= 0 {
+		return typeName[:idx]
+	}
+	return typeName
+}
+
+// plainText returns the text of a segment without markup, icons, or
+// redundant whitespace, since icons are drawn using private use characters
+// that screen readers cannot describe.
+func plainText(s bar.Segment) string {
+	text := s.Text()
+	if markup, _ := s["markup"].(bar.Markup); markup == bar.MarkupPango {
+		text = pango.PlainText(text)
+	}
+	text = strings.Map(func(r rune) rune {
+		if unicode.In(r, unicode.Co) {
+			return ' '
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package announce
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+// recorder is a sink that collects the announced lines.
+type recorder chan string
+
+func (r recorder) sink(line string) {
+	r <- line
+}
+
+func (r recorder) next(t *testing.T, message string) string {
+	select {
+	case line := <-r:
+		return line
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected an announcement", message)
+		return ""
+	}
+}
+
+func (r recorder) assertNone(t *testing.T, message string) {
+	select {
+	case line := <-r:
+		assert.Fail(t, "unexpected announcement", "%s: %q", message, line)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	m0, m1, m2 := testModule.New(t), testModule.New(t), testModule.New(t)
+	b := testBar.New(t)
+	b.Bar.Add(m0, m1, m2)
+	r := make(recorder, 10)
+	a := New(b.Bar, r.sink).Label("1", "wifi").Mute("2").Start()
+	defer a.Stop()
+	b.Start()
+	defer b.Close()
+
+	m0.Output(outputs.Text("hello"))
+	b.NextOutput("on output")
+	assert.Equal(t, "module: hello", r.next(t, "on output"),
+		"labelled with the module's package")
+
+	m2.Output(outputs.Text("tick"))
+	b.NextOutput("on muted output")
+	r.assertNone(t, "on muted output")
+
+	m1.Output(bar.Output{
+		bar.NewSegment("connected"),
+		bar.NewSegment("\uf1eb <b>home &amp; away</b>").Markup(bar.MarkupPango),
+	})
+	b.NextOutput("on output")
+	assert.Equal(t, "wifi: connected", r.next(t, "first segment"))
+	assert.Equal(t, "wifi: home & away", r.next(t, "second segment, without markup"))
+
+	m1.Output(bar.Output{bar.NewSegment("connected"), bar.NewSegment("work")})
+	b.NextOutput("on output")
+	assert.Equal(t, "wifi: work", r.next(t, "only changed segments"))
+	r.assertNone(t, "unchanged segments")
+
+	m0.Output(outputs.Error(errors.New("offline")))
+	b.NextOutput("on error")
+	assert.Equal(t, "module, urgent: offline", r.next(t, "on error"))
+
+	m1.Output(outputs.Text("connected"))
+	b.NextOutput("on output")
+	assert.Equal(t, "wifi: hidden", r.next(t, "removed segment"))
+
+	a.ReadAll()
+	assert.Equal(t, "module, urgent: offline", r.next(t, "read all"))
+	assert.Equal(t, "wifi: connected", r.next(t, "read all"))
+	r.assertNone(t, "muted module")
+}
+
+func TestSinks(t *testing.T) {
+	out := new(bytes.Buffer)
+	Writer(out)("battery: 85%")
+	Writer(out)("clock: 10:30")
+	assert.Equal(t, "battery: 85%\nclock: 10:30\n", out.String())
+
+	var args []string
+	speak = func(a []string) error {
+		args = a
+		return nil
+	}
+	Speak("-r", "30")("battery: 85%")
+	assert.Equal(t, []string{"-r", "30", "--", "battery: 85%"}, args)
+}
+
+func TestPlainText(t *testing.T) {
+	for _, tc := range []struct {
+		segment  bar.Segment
+		expected string
+	}{
+		{bar.NewSegment("  a \n b "), "a b"},
+		{bar.NewSegment("<b>not markup</b>"), "<b>not markup</b>"},
+		{bar.NewSegment("<b>bold</b> &amp; &lt;3").Markup(bar.MarkupPango), "bold & <3"},
+		{bar.NewSegment("<b>unclosed").Markup(bar.MarkupPango), "<b>unclosed"},
+		{bar.NewSegment("\ue1a4 50%"), "50%"},
+	} {
+		assert.Equal(t, tc.expected, plainText(tc.segment), tc.segment.Text())
+	}
+}
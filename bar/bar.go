@@ -15,6 +15,8 @@
 // Package bar allows a user to create a go binary that follows the i3bar protocol.
 package bar
 
+import "encoding/json"
+
 // TextAlignment defines the alignment of text within a block.
 // Using TextAlignment rather than string opens up the possibility of i18n without
 // requiring each module to know the current locale.
@@ -89,6 +91,68 @@ const (
 	ScrollRight Button = 7
 )
 
+// Modifiers is a set of keyboard modifiers held during a mouse event.
+type Modifiers int
+
+const (
+	// ShiftKey is the shift key.
+	ShiftKey Modifiers = 1 << iota
+	// ControlKey is the control key.
+	ControlKey
+	// Mod1Key is usually the alt key.
+	Mod1Key
+	// Mod2Key is usually num lock.
+	Mod2Key
+	// Mod3Key is usually unassigned.
+	Mod3Key
+	// Mod4Key is usually the super (windows) key.
+	Mod4Key
+	// Mod5Key is usually AltGr.
+	Mod5Key
+	// LockKey is caps lock.
+	LockKey
+)
+
+// modifierNames are the names used by i3bar for each modifier, in the
+// same order as the Modifiers bits.
+var modifierNames = []string{
+	"Shift", "Control", "Mod1", "Mod2", "Mod3", "Mod4", "Mod5", "Lock",
+}
+
+// Has returns true if all the given modifiers are held.
+func (m Modifiers) Has(modifiers Modifiers) bool {
+	return m&modifiers == modifiers
+}
+
+// MarshalJSON encodes the modifiers as a list of names, as used by i3bar.
+func (m Modifiers) MarshalJSON() ([]byte, error) {
+	names := []string{}
+	for i, name := range modifierNames {
+		if m&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return json.Marshal(names)
+}
+
+// UnmarshalJSON decodes the modifiers from a list of names, ignoring any
+// unknown names.
+func (m *Modifiers) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*m = 0
+	for _, name := range names {
+		for i, n := range modifierNames {
+			if name == n {
+				*m |= 1 << uint(i)
+			}
+		}
+	}
+	return nil
+}
+
 /*
 Event represents a mouse event meant for a single module.
 
@@ -100,10 +164,11 @@ The Instance is passed through unchanged from the output segments, so
 it can be used to filter events for a module with multiple output segments.
 */
 type Event struct {
-	Button   Button `json:"button"`
-	X        int    `json:"x,omitempty"`
-	Y        int    `json:"y,omitempty"`
-	Instance string `json:"instance"`
+	Button    Button    `json:"button"`
+	X         int       `json:"x,omitempty"`
+	Y         int       `json:"y,omitempty"`
+	Instance  string    `json:"instance"`
+	Modifiers Modifiers `json:"modifiers,omitempty"`
 }
 
 // Module represents a single bar module. A bar is just a list of modules.
@@ -133,3 +198,11 @@ type Pausable interface {
 	// as well as immediately updating their output (or triggering a process to do so).
 	Resume()
 }
+
+// Refresher is an additional interface modules may implement if they can be asked to
+// update their output, e.g. by fetching new data, outside of their regular schedule.
+type Refresher interface {
+	// Update will be called by the bar when a refresh is requested for the module,
+	// for example from a script using I3Bar.Refresh.
+	Update()
+}
@@ -15,15 +15,24 @@
 package bar_test
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchrcom/testify/assert"
 
+	"github.com/soumya92/barista/i3ipc"
 	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
 	"github.com/soumya92/barista/testing/mockio"
 	// testing/module depends on bar, hence the '.' import and package name.
 	. "github.com/soumya92/barista/bar"
@@ -96,10 +105,6 @@ func TestSingleModule(t *testing.T) {
 	out = readOutputTexts(t, mockStdout)
 	assert.Equal(t, []string{"other"}, out,
 		"output updates when module sends an update")
-
-	assert.Panics(t,
-		func() { bar.Add(testModule.New(t)) },
-		"adding a module to a running bar")
 }
 
 func TestMultipleModules(t *testing.T) {
@@ -257,6 +262,15 @@ func TestClickEvents(t *testing.T) {
 	assert.Equal(t, Event{X: 9, Y: 7}, evt, "event values are passed through")
 	module1.AssertNotClicked("only target module receives the event")
 
+	mockStdin.WriteString(fmt.Sprintf(
+		"{\"name\": \"%s\", \"button\": 4, \"modifiers\": [\"Shift\", \"Mod4\", \"Foo\"]},",
+		module2_name))
+	evt = module2.AssertClicked("when getting an event with modifiers")
+	assert.Equal(t, Event{Button: ScrollUp, Modifiers: ShiftKey | Mod4Key}, evt,
+		"modifiers are decoded, ignoring unknown modifiers")
+	assert.True(t, evt.Modifiers.Has(ShiftKey))
+	assert.False(t, evt.Modifiers.Has(ShiftKey|ControlKey))
+
 	mockStdin.WriteString("{\"name\":\"blah\",\"x\":9},")
 	module1.AssertNotClicked("with weird module name")
 	module2.AssertNotClicked("with weird module name")
@@ -322,3 +336,611 @@ func TestSignalHandlingSuppression(t *testing.T) {
 		func() { b.SuppressSignals(false) },
 		"Cannot suppress signal handling after Run")
 }
+
+func TestMultipleOutputs(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+	otherStdin := mockio.Stdin()
+	otherStdout := mockio.Stdout()
+
+	clock := testModule.New(t)
+	stats := testModule.New(t)
+	external := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).
+		Output("eDP-1").
+		AddOutput("HDMI-1", otherStdin, otherStdout).
+		Add(clock).
+		AddTo([]string{"eDP-1"}, stats).
+		AddTo([]string{"HDMI-1"}, external)
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started on main stream")
+	_, err = otherStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started on other stream")
+	mockStdin.WriteString("[")
+	otherStdin.WriteString("[")
+
+	clock.Output(outputs.Text("10:00"))
+	assert.Equal(t, []string{"10:00"}, readOutputTexts(t, mockStdout),
+		"untagged module shown on main output")
+	assert.Equal(t, []string{"10:00"}, readOutputTexts(t, otherStdout),
+		"untagged module shown on other output")
+
+	stats.Output(outputs.Text("cpu"))
+	assert.Equal(t, []string{"10:00", "cpu"}, readOutputTexts(t, mockStdout),
+		"tagged module shown on its output")
+	assert.Equal(t, []string{"10:00"}, readOutputTexts(t, otherStdout),
+		"tagged module not shown on other outputs")
+
+	external.Output(outputs.Text("ext"))
+	assert.Equal(t, []string{"10:00", "cpu"}, readOutputTexts(t, mockStdout))
+	out := readOutput(t, otherStdout)
+	assert.Equal(t, 2, len(out), "tagged module shown on its output")
+	externalName := out[1]["name"].(string)
+
+	otherStdin.WriteString(fmt.Sprintf("{\"name\": \"%s\"},", externalName))
+	external.AssertClicked("click events from other outputs")
+
+	assert.Panics(t,
+		func() { b.AddOutput("DP-1", mockio.Stdin(), mockio.Stdout()) },
+		"adding an output to a running bar")
+}
+
+func TestListenOutput(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+	dir, err := ioutil.TempDir("", "barista")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "HDMI-1.sock")
+
+	clock := testModule.New(t)
+	external := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).
+		Add(clock).
+		AddTo([]string{"HDMI-1"}, external)
+	assert.NoError(t, b.ListenOutput("HDMI-1", socket))
+	go b.Run()
+
+	_, err = mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started on main stream")
+	clock.Output(outputs.Text("10:00"))
+	readOutput(t, mockStdout)
+	external.Output(outputs.Text("ext"))
+	assert.Equal(t, []string{"10:00"}, readOutputTexts(t, mockStdout),
+		"tagged module not shown on main output")
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	header, err := reader.ReadString('[')
+	assert.Nil(t, err, "header and array start sent to client")
+	assert.Contains(t, header, `"version":1`)
+	line, err := reader.ReadString('\n')
+	assert.Nil(t, err, "current bar sent to client")
+	var texts []map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(strings.TrimSuffix(line, ",\n")), &texts))
+	assert.Equal(t, 2, len(texts), "tagged module shown on client's output")
+	readOutput(t, mockStdout)
+}
+
+// closeRecorder is a writer that records when it is closed.
+type closeRecorder struct {
+	*io.PipeWriter
+	closed chan struct{}
+}
+
+func (c closeRecorder) Close() error {
+	close(c.closed)
+	return c.PipeWriter.Close()
+}
+
+func TestDroppedOutputIsClosed(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+	pr, pw := io.Pipe()
+	otherStdout := closeRecorder{pw, make(chan struct{})}
+
+	clock := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).
+		AddOutput("HDMI-1", mockio.Stdin(), otherStdout).
+		Add(clock)
+	go b.Run()
+
+	reader := bufio.NewReader(pr)
+	_, err := reader.ReadString('[')
+	assert.Nil(t, err, "output array started on other stream")
+	_, err = mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started on main stream")
+
+	// Simulate the client going away.
+	pr.Close()
+	clock.Output(outputs.Text("10:00"))
+	assert.Equal(t, []string{"10:00"}, readOutputTexts(t, mockStdout))
+	select {
+	case <-otherStdout.closed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "writer closed when stream is dropped")
+	}
+
+	clock.Output(outputs.Text("10:01"))
+	assert.Equal(t, []string{"10:01"}, readOutputTexts(t, mockStdout),
+		"main stream unaffected by dropped stream")
+}
+
+func TestReordering(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := testModule.New(t)
+	module3 := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2, module3)
+	assert.True(t, b.Move(module3, 1), "moving before the bar starts")
+	assert.False(t, b.Move(testModule.New(t), 0), "moving a module not on the bar")
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	mockStdin.WriteString("[")
+	module1.Output(outputs.Text("1"))
+	readOutput(t, mockStdout)
+	module2.Output(outputs.Text("2"))
+	readOutput(t, mockStdout)
+	module3.Output(outputs.Text("3"))
+	assert.Equal(t, []string{"1", "3", "2"}, readOutputTexts(t, mockStdout),
+		"order changed before starting")
+
+	b.MoveToFront(module2)
+	out := readOutput(t, mockStdout)
+	texts := []string{}
+	for _, o := range out {
+		texts = append(texts, o["full_text"].(string))
+	}
+	assert.Equal(t, []string{"2", "1", "3"}, texts, "bar reprinted on reordering")
+
+	mockStdin.WriteString(fmt.Sprintf("{\"name\": \"%s\"},", out[0]["name"]))
+	module2.AssertClicked("events routed to moved module")
+	module1.AssertNotClicked("events routed to moved module")
+
+	b.Move(module2, 10)
+	assert.Equal(t, []string{"1", "3", "2"}, readOutputTexts(t, mockStdout),
+		"moving past the end")
+
+	b.MoveAt(0, 2)
+	assert.Equal(t, []string{"3", "2", "1"}, readOutputTexts(t, mockStdout),
+		"moving by index")
+	assert.False(t, b.MoveAt(3, 0), "moving an index not on the bar")
+
+	b.ResetOrder()
+	assert.Equal(t, []string{"1", "2", "3"}, readOutputTexts(t, mockStdout),
+		"original order restored")
+}
+
+func TestAddRemoveWhileRunning(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2)
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	mockStdin.WriteString("[")
+	module1.Output(outputs.Text("1"))
+	readOutput(t, mockStdout)
+	module2.Output(outputs.Text("2"))
+	assert.Equal(t, []string{"1", "2"}, readOutputTexts(t, mockStdout))
+
+	module3 := testModule.New(t)
+	b.Add(module3)
+	assert.Equal(t, []string{"1", "2"}, readOutputTexts(t, mockStdout),
+		"bar reprinted when adding a module")
+	module3.Output(outputs.Text("3"))
+	out := readOutput(t, mockStdout)
+	module3.AssertStarted("module added while running is streamed")
+	assert.Equal(t, 3, len(out), "module added while running is shown")
+	assert.Equal(t, "3", out[2]["full_text"], "added at the end of the bar")
+
+	mockStdin.WriteString(fmt.Sprintf("{\"name\": \"%s\"},", out[2]["name"]))
+	module3.AssertClicked("events routed to module added while running")
+
+	assert.True(t, b.Remove(module2), "removing a module on the bar")
+	assert.Equal(t, []string{"1", "3"}, readOutputTexts(t, mockStdout),
+		"bar reprinted when removing a module")
+	assert.False(t, b.Remove(module2), "removing a module twice")
+	assert.False(t, b.Move(module2, 0), "moving a removed module")
+
+	mockStdin.WriteString(fmt.Sprintf("{\"name\": \"%s\"},", out[1]["name"]))
+	module2.AssertNotClicked("events not routed to removed module")
+
+	module2.Output(outputs.Text("removed"))
+	module1.Output(outputs.Text("1b"))
+	assert.Equal(t, []string{"1b", "3"}, readOutputTexts(t, mockStdout),
+		"output from removed module is ignored")
+
+	b.ResetOrder()
+	assert.Equal(t, []string{"1b", "3"}, readOutputTexts(t, mockStdout),
+		"removed module not restored when resetting order")
+	assert.False(t, b.RemoveAt(5), "removing an index not on the bar")
+}
+
+func TestStats(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2)
+	stats := b.Stats()
+	assert.Equal(t, int64(0), stats.Prints, "no prints before starting")
+	assert.Equal(t, 2, len(stats.Modules), "all modules included")
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	module1.Output(outputs.Text("1"))
+	readOutput(t, mockStdout)
+	module1.Output(outputs.Text("1b"))
+	readOutput(t, mockStdout)
+	module2.Output(outputs.Text("2"))
+	readOutput(t, mockStdout)
+	b.MoveToFront(module2)
+	readOutput(t, mockStdout)
+
+	stats = b.Stats()
+	assert.True(t, stats.Prints >= 3, "prints counted")
+	assert.Equal(t, "1", stats.Modules[0].Name, "modules in display order")
+	assert.Equal(t, int64(1), stats.Modules[0].Updates)
+	assert.Equal(t, "0", stats.Modules[1].Name, "modules in display order")
+	assert.Equal(t, int64(2), stats.Modules[1].Updates)
+	assert.Equal(t, "*module.TestModule", stats.Modules[1].Type)
+	assert.Equal(t, "1b", stats.Modules[1].Output[0].Text(), "last output")
+	assert.False(t, stats.Modules[1].LastUpdate.IsZero(), "last update time")
+
+	mockStdin.WriteString("[{\"name\": \"0\"},")
+	module1.AssertClicked("event sent to module")
+	assert.Equal(t, int64(1), b.Stats().Modules[1].Events, "events counted")
+	assert.Equal(t, int64(0), b.Stats().Modules[0].Events)
+}
+
+func TestClickRefreshSubscribe(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := sliceModule{outputs.Text("2")}
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2)
+	updates, stop := b.Subscribe()
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	readOutput(t, mockStdout)
+	module1.Output(outputs.Text("1"))
+	readOutput(t, mockStdout)
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		assert.Fail(t, "subscriber not notified on print")
+	}
+
+	assert.True(t, b.Click("0", Event{Button: ButtonRight}))
+	assert.Equal(t, ButtonRight, module1.AssertClicked("click from api").Button)
+	assert.True(t, b.Refresh("0"))
+	module1.AssertUpdated("refresh from api")
+
+	assert.False(t, b.Click("1", Event{}), "module does not handle clicks")
+	assert.False(t, b.Refresh("1"), "module cannot be refreshed")
+	assert.False(t, b.Click("5", Event{}), "no such module")
+	assert.False(t, b.Refresh("x"), "no such module")
+
+	stop()
+	module1.Output(outputs.Text("1b"))
+	readOutput(t, mockStdout)
+	select {
+	case <-updates:
+		assert.Fail(t, "notified after stopping subscription")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module)
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	module.Output(Output{
+		NewSegment("a").Instance("x"),
+		NewSegment("b"),
+		NewSegment("c").Instance("x"),
+	})
+	readOutput(t, mockStdout)
+
+	var clicked []string
+	b.Intercept(func(o Output, e Event) bool {
+		clicked = nil
+		for _, s := range o {
+			clicked = append(clicked, s.Text())
+		}
+		return e.Modifiers.Has(ShiftKey)
+	})
+
+	assert.True(t, b.Click("0", Event{Button: ButtonMiddle, Instance: "x", Modifiers: ShiftKey}))
+	assert.Equal(t, []string{"a", "c"}, clicked, "segments with the instance")
+	module.AssertNotClicked("intercepted event")
+
+	assert.True(t, b.Click("0", Event{Button: ButtonLeft}))
+	assert.Equal(t, []string{"b"}, clicked, "segments without an instance")
+	module.AssertClicked("event not consumed by handler")
+
+	b.Intercept(func(Output, Event) bool { return true })
+	b.Click("0", Event{Button: ButtonLeft})
+	assert.Equal(t, []string{"b"}, clicked, "later handler called first")
+	module.AssertNotClicked("intercepted by later handler")
+}
+
+func TestObserve(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2)
+	observed := make(chan string, 10)
+	b.Observe(func(name string, o Output) {
+		observed <- name + ":" + o[0].Text()
+	})
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	module1.Output(outputs.Text("a"))
+	readOutput(t, mockStdout)
+	module2.Output(outputs.Text("b"))
+	readOutput(t, mockStdout)
+	module1.Output(outputs.Text("c"))
+	readOutput(t, mockStdout)
+	assert.Equal(t, "0:a", <-observed)
+	assert.Equal(t, "1:b", <-observed)
+	assert.Equal(t, "0:c", <-observed)
+
+	b.Remove(module2)
+	readOutput(t, mockStdout)
+	module2.Output(outputs.Text("d"))
+	assert.Empty(t, observed, "removed modules are not observed")
+}
+
+// sliceModule is a module of a non-comparable type.
+type sliceModule []Output
+
+func (s sliceModule) Stream() <-chan Output {
+	ch := make(chan Output, len(s))
+	for _, o := range s {
+		ch <- o
+	}
+	return ch
+}
+
+func TestReorderingNonComparable(t *testing.T) {
+	module1 := sliceModule{outputs.Text("1")}
+	module2 := sliceModule{outputs.Text("2")}
+	b := NewOnIo(mockio.Stdin(), mockio.Stdout()).Add(module1, module2)
+	assert.NotPanics(t, func() {
+		assert.False(t, b.Move(module2, 0), "non-comparable modules are not found")
+	})
+	assert.True(t, b.MoveAt(1, 0), "non-comparable modules can be moved by index")
+}
+
+func TestLayout(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2)
+	b.Layout(Layout{}.Separators(false))
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	module1.Output(Output{NewSegment("1"), NewSegment("1b")})
+	out := readOutput(t, mockStdout)
+	assert.Nil(t, out[0]["separator"], "inner segments are not affected")
+	assert.Equal(t, false, out[1]["separator"], "layout applied to last segment")
+
+	module2.Output(Output{NewSegment("2").Separator(true)})
+	out = readOutput(t, mockStdout)
+	assert.Equal(t, true, out[2]["separator"], "module's own separator is kept")
+
+	b.Layout(Layout{}.Spacing(12))
+	out = readOutput(t, mockStdout)
+	assert.Nil(t, out[1]["separator"], "layout changed while running")
+	assert.Equal(t, float64(12), out[1]["separator_block_width"],
+		"layout changed while running")
+	assert.Equal(t, true, out[2]["separator"], "module's own separator is kept")
+}
+
+func TestProtocolGolden(t *testing.T) {
+	module1 := testModule.New(t)
+	module2 := testModule.New(t)
+	b := testBar.Run(t, module1, module2)
+	defer b.Close()
+
+	module1.Output(outputs.Text("text"))
+	b.NextOutput("on first module update")
+
+	module2.Output(Output{
+		NewSegment("<b>pango & stuff</b>").
+			ShortText("short").
+			Markup(MarkupPango).
+			Color(Color("#ff0000")).
+			Background(Color("#00ff00")).
+			Border(Color("#0000ff")).
+			MinWidth(100).
+			Align(AlignCenter).
+			Urgent(true).
+			Separator(false).
+			SeparatorWidth(12).
+			Instance("instance"),
+		NewSegment("unicode: é☃"),
+	})
+	b.NextOutput("on second module update")
+
+	module1.Output(outputs.Empty())
+	b.NextOutput("on empty output")
+
+	b.AssertGolden(filepath.Join("testdata", "protocol.golden"))
+}
+
+func focusedTexts(out []map[string]interface{}) []string {
+	var focused []string
+	for _, segment := range out {
+		if segment["background"] == "#285577" {
+			focused = append(focused, segment["full_text"].(string))
+		}
+	}
+	return focused
+}
+
+func TestFocus(t *testing.T) {
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+
+	module1 := testModule.New(t)
+	module2 := sliceModule{outputs.Text("2")}
+	module3 := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module1, module2, module3)
+	go b.Run()
+
+	_, err := mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	readOutput(t, mockStdout)
+	module1.Output(multiOutput("1a", "1b"))
+	readOutput(t, mockStdout)
+	module3.Output(outputs.Text("3"))
+	out := readOutput(t, mockStdout)
+	assert.Empty(t, focusedTexts(out), "nothing focused initially")
+	_, _, ok := b.Focused()
+	assert.False(t, ok, "not in focus mode")
+	assert.False(t, b.Activate(ButtonLeft), "activate outside focus mode")
+
+	b.FocusNext()
+	assert.Equal(t, []string{"1a"}, focusedTexts(readOutput(t, mockStdout)),
+		"first segment focused")
+	b.FocusNext()
+	assert.Equal(t, []string{"1b"}, focusedTexts(readOutput(t, mockStdout)))
+	b.FocusNext()
+	assert.Equal(t, []string{"3"}, focusedTexts(readOutput(t, mockStdout)),
+		"modules that do not handle clicks are skipped")
+	b.FocusNext()
+	assert.Equal(t, []string{"1a"}, focusedTexts(readOutput(t, mockStdout)),
+		"focus wraps around")
+	b.FocusPrevious()
+	assert.Equal(t, []string{"3"}, focusedTexts(readOutput(t, mockStdout)),
+		"focus wraps around")
+	b.FocusPrevious()
+	out = readOutput(t, mockStdout)
+	assert.Equal(t, []string{"1b"}, focusedTexts(out))
+	assert.Equal(t, "#ffffff", out[1]["color"], "default focus style")
+	assert.Equal(t, "#4c7899", out[1]["border"], "default focus style")
+
+	name, instance, ok := b.Focused()
+	assert.True(t, ok, "in focus mode")
+	assert.Equal(t, "0", name)
+	assert.Equal(t, "instance_1", instance)
+	assert.True(t, b.Activate(ButtonRight))
+	e := module1.AssertClicked("on activate")
+	assert.Equal(t, ButtonRight, e.Button)
+	assert.Equal(t, "instance_1", e.Instance)
+
+	module1.Output(outputs.Text("1"))
+	assert.Empty(t, focusedTexts(readOutput(t, mockStdout)),
+		"focused segment is gone")
+	b.FocusNext()
+	assert.Equal(t, []string{"1"}, focusedTexts(readOutput(t, mockStdout)),
+		"focus restarts when focused segment is gone")
+
+	b.FocusStyle(func(s Segment) Segment { return s.Urgent(true) })
+	out = readOutput(t, mockStdout)
+	assert.Equal(t, true, out[0]["urgent"], "focus style changed while running")
+	b.FocusNext()
+	out = readOutput(t, mockStdout)
+	assert.Equal(t, true, out[2]["urgent"], "custom focus style")
+	assert.Nil(t, out[2]["background"], "custom focus style")
+
+	b.Unfocus()
+	out = readOutput(t, mockStdout)
+	assert.Nil(t, out[2]["urgent"], "unfocused")
+	_, _, ok = b.Focused()
+	assert.False(t, ok, "not in focus mode after unfocus")
+}
+
+func TestFocusBindings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "focus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "i3")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	mockStdin := mockio.Stdin()
+	mockStdout := mockio.Stdout()
+	module := testModule.New(t)
+	b := NewOnIo(mockStdin, mockStdout).Add(module).FocusBindings(i3ipc.Socket(socket))
+	go b.Run()
+	_, err = mockStdout.ReadUntil('[', time.Second)
+	assert.Nil(t, err, "output array started without any errors")
+	module.Output(outputs.Text("1"))
+	readOutput(t, mockStdout)
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	msgType, payload, err := i3ipc.ReadMessage(conn)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(i3ipc.Subscribe), msgType)
+	assert.Equal(t, `["binding"]`, string(payload))
+	assert.Nil(t, i3ipc.WriteMessage(conn, i3ipc.Subscribe, `{"success":true}`))
+
+	binding := func(command string) {
+		event, _ := json.Marshal(map[string]interface{}{
+			"change":  "run",
+			"binding": map[string]string{"command": command},
+		})
+		assert.Nil(t, i3ipc.WriteMessage(conn, i3ipc.BindingEvent, string(event)))
+	}
+
+	binding(`mode "bar"; nop barista focus next`)
+	assert.Equal(t, []string{"1"}, focusedTexts(readOutput(t, mockStdout)),
+		"focused from binding")
+	binding("nop barista focus activate middle")
+	assert.Equal(t, ButtonMiddle, module.AssertClicked("activated from binding").Button)
+	binding("nop barista focus activate")
+	assert.Equal(t, ButtonLeft, module.AssertClicked("activated from binding").Button)
+	binding("nop barista focus activate nose")
+	binding("nop other")
+	module.AssertNotClicked("on unknown commands")
+	binding(`nop   barista focus exit, mode "default"`)
+	assert.Empty(t, focusedTexts(readOutput(t, mockStdout)), "unfocused from binding")
+}
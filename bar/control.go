@@ -0,0 +1,145 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+// Click sends an event to the named module as if it was clicked in i3bar,
+// and returns false if there is no such module or it does not handle
+// clicks. Names are the "name" of the module's segments, as shown in Stats.
+func (b *I3Bar) Click(name string, e Event) bool {
+	module, ok := b.get(name)
+	if !ok {
+		log.Fine("event for unknown module", "module", name)
+		return false
+	}
+	log.Fine("event", "module", name, "button", e.Button)
+	module.outputMutex.Lock()
+	module.events++
+	module.outputMutex.Unlock()
+	if b.intercepted(module, e) {
+		return true
+	}
+	// Check that the module actually supports click events.
+	clickable, ok := module.Module.(Clickable)
+	if ok {
+		// Goroutine to prevent click handlers from blocking the bar.
+		go clickable.Click(e)
+	}
+	return ok
+}
+
+// Intercept adds a handler that sees click events on all modules before
+// they do, along with the segments of the module's last output that were
+// clicked, i.e. those with the event's instance. If the handler returns
+// true, the event is not sent to the module. Handlers added later are
+// called first. The segments must not be modified.
+func (b *I3Bar) Intercept(handler func(Output, Event) bool) *I3Bar {
+	b.interceptMutex.Lock()
+	defer b.interceptMutex.Unlock()
+	b.interceptors = append(b.interceptors, handler)
+	return b
+}
+
+// intercepted calls the intercept handlers for a click on the module, and
+// returns true if any of them consumed the event.
+func (b *I3Bar) intercepted(module *i3Module, e Event) bool {
+	b.interceptMutex.Lock()
+	handlers := b.interceptors
+	b.interceptMutex.Unlock()
+	if len(handlers) == 0 {
+		return false
+	}
+	var clicked Output
+	module.outputMutex.Lock()
+	for _, s := range module.LastOutput {
+		if instance, _ := s["instance"].(string); instance == e.Instance {
+			clicked = append(clicked, s)
+		}
+	}
+	module.outputMutex.Unlock()
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if handlers[i](clicked, e) {
+			log.Fine("event intercepted", "module", module.Name, "button", e.Button)
+			return true
+		}
+	}
+	return false
+}
+
+// Observe adds a handler that is called with the name and output of a
+// module each time it outputs anything, e.g. to record outputs for
+// debugging. Handlers are called from the module's output goroutine, so
+// they must not block. The output must not be modified.
+func (b *I3Bar) Observe(handler func(name string, o Output)) *I3Bar {
+	b.observerMutex.Lock()
+	defer b.observerMutex.Unlock()
+	b.observers = append(b.observers, handler)
+	return b
+}
+
+// observe calls the observe handlers with the output of a module.
+func (b *I3Bar) observe(name string, o Output) {
+	b.observerMutex.Lock()
+	handlers := b.observers
+	b.observerMutex.Unlock()
+	for _, handler := range handlers {
+		handler(name, o)
+	}
+}
+
+// Refresh asks the named module to update its output, and returns false if
+// there is no such module or it cannot be refreshed (see Refresher).
+func (b *I3Bar) Refresh(name string) bool {
+	module, ok := b.get(name)
+	if !ok {
+		return false
+	}
+	refresher, ok := module.Module.(Refresher)
+	if ok {
+		log.Fine("refresh", "module", name)
+		go refresher.Update()
+	}
+	return ok
+}
+
+// Subscribe returns a channel that receives a value each time the bar is
+// printed, e.g. to mirror the outputs elsewhere, and a function to stop the
+// subscription. Prints that happen while a value is pending are coalesced,
+// so slow subscribers do not block the bar.
+func (b *I3Bar) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	b.subscribersMutex.Lock()
+	defer b.subscribersMutex.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = map[chan struct{}]bool{}
+	}
+	b.subscribers[ch] = true
+	return ch, func() {
+		b.subscribersMutex.Lock()
+		defer b.subscribersMutex.Unlock()
+		delete(b.subscribers, ch)
+	}
+}
+
+// notifySubscribers signals all subscribers after the bar is printed.
+func (b *I3Bar) notifySubscribers() {
+	b.subscribersMutex.Lock()
+	defer b.subscribersMutex.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
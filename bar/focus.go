@@ -0,0 +1,251 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/soumya92/barista/i3ipc"
+)
+
+// focusState is the segment highlighted in focus mode, and the style used
+// to highlight it. module is nil when the bar is not in focus mode.
+type focusState struct {
+	module *i3Module
+	index  int
+	style  func(Segment) Segment
+}
+
+// apply returns the segment at the given index of the module's output,
+// highlighted if it is the focused segment. The original segment is not
+// modified.
+func (f focusState) apply(m *i3Module, index int, s Segment) Segment {
+	if f.module != m || f.index != index {
+		return s
+	}
+	highlighted := Segment{}
+	for k, v := range s {
+		highlighted[k] = v
+	}
+	return f.style(highlighted)
+}
+
+// DefaultFocusStyle highlights the focused segment using the colours that
+// i3 uses for focused windows by default.
+func DefaultFocusStyle(s Segment) Segment {
+	return s.Color("#ffffff").Background("#285577").Border("#4c7899")
+}
+
+// FocusStyle sets the function that highlights the focused segment in
+// focus mode. It is called with a copy of the segment, which it can modify.
+func (b *I3Bar) FocusStyle(style func(Segment) Segment) *I3Bar {
+	b.focusMutex.Lock()
+	b.focus.style = style
+	b.focusMutex.Unlock()
+	b.reprint()
+	return b
+}
+
+// focusTarget is a segment that can be focused.
+type focusTarget struct {
+	module *i3Module
+	index  int
+}
+
+// focusTargets returns the segments that can be focused, in the order they
+// are shown on the main output. Only modules that handle clicks can be
+// focused, since activating a segment clicks it.
+func (b *I3Bar) focusTargets() []focusTarget {
+	modules, _ := b.modules()
+	var targets []focusTarget
+	for _, m := range modules {
+		if _, ok := m.Module.(Clickable); !ok || !m.shownOn(b.stdio.output) {
+			continue
+		}
+		m.outputMutex.Lock()
+		count := len(m.LastOutput)
+		m.outputMutex.Unlock()
+		for i := 0; i < count; i++ {
+			targets = append(targets, focusTarget{m, i})
+		}
+	}
+	return targets
+}
+
+// FocusNext moves the highlight to the next segment on the bar, wrapping
+// around at the end, and enters focus mode if the bar is not already in it,
+// focusing the first segment. Together with Activate, this makes the bar
+// usable from the keyboard, e.g. using FocusBindings or dbusapi.
+func (b *I3Bar) FocusNext() {
+	b.moveFocus(1)
+}
+
+// FocusPrevious moves the highlight to the previous segment on the bar,
+// wrapping around at the start, and enters focus mode if the bar is not
+// already in it, focusing the last segment.
+func (b *I3Bar) FocusPrevious() {
+	b.moveFocus(-1)
+}
+
+func (b *I3Bar) moveFocus(delta int) {
+	targets := b.focusTargets()
+	b.focusMutex.Lock()
+	pos := -1
+	for i, t := range targets {
+		if t.module == b.focus.module && t.index == b.focus.index {
+			pos = i
+		}
+	}
+	switch {
+	case len(targets) == 0:
+		b.focus.module = nil
+	case pos < 0:
+		// Start from either end if the bar was not in focus mode, or the
+		// focused segment is gone, e.g. if its module's output shrank.
+		first := targets[0]
+		if delta < 0 {
+			first = targets[len(targets)-1]
+		}
+		b.focus.module, b.focus.index = first.module, first.index
+	default:
+		next := targets[((pos+delta)%len(targets)+len(targets))%len(targets)]
+		b.focus.module, b.focus.index = next.module, next.index
+	}
+	if b.focus.module != nil {
+		log.Fine("focus", "module", b.focus.module.Name, "segment", b.focus.index)
+	}
+	b.focusMutex.Unlock()
+	b.reprint()
+}
+
+// Focused returns the name of the module and the instance of the segment
+// that is highlighted in focus mode. ok is false if the bar is not in focus
+// mode.
+func (b *I3Bar) Focused() (name, instance string, ok bool) {
+	b.focusMutex.Lock()
+	m, index := b.focus.module, b.focus.index
+	b.focusMutex.Unlock()
+	if m == nil {
+		return "", "", false
+	}
+	m.outputMutex.Lock()
+	defer m.outputMutex.Unlock()
+	if index < len(m.LastOutput) {
+		instance, _ = m.LastOutput[index]["instance"].(string)
+	}
+	return m.Name, instance, true
+}
+
+// Activate clicks the segment that is highlighted in focus mode with the
+// given button, and returns false if the bar is not in focus mode, or the
+// segment's module did not handle the click. The bar stays in focus mode.
+func (b *I3Bar) Activate(button Button) bool {
+	name, instance, ok := b.Focused()
+	if !ok {
+		return false
+	}
+	return b.Click(name, Event{Button: button, Instance: instance})
+}
+
+// Unfocus leaves focus mode, removing the highlight.
+func (b *I3Bar) Unfocus() {
+	b.focusMutex.Lock()
+	b.focus.module = nil
+	b.focusMutex.Unlock()
+	b.reprint()
+}
+
+// focusCommand is the prefix of i3 binding commands handled by
+// FocusBindings.
+const focusCommand = "nop barista focus"
+
+// focusButtons are the buttons that can be named in "activate" commands.
+var focusButtons = map[string]Button{
+	"left":        ButtonLeft,
+	"middle":      ButtonMiddle,
+	"right":       ButtonRight,
+	"scroll-up":   ScrollUp,
+	"scroll-down": ScrollDown,
+}
+
+// FocusBindings controls focus mode from key bindings of the window manager
+// at the other end of the IPC client, e.g. i3ipc.I3() or i3ipc.Sway(). The
+// bindings run "nop" commands, which i3 ignores, but reports to the bar:
+//
+//	nop barista focus next
+//	nop barista focus previous
+//	nop barista focus activate [left|middle|right|scroll-up|scroll-down]
+//	nop barista focus exit
+//
+// For example, to navigate the bar in a binding mode:
+//
+//	bindsym $mod+b mode "bar"; nop barista focus next
+//	mode "bar" {
+//	    bindsym Left nop barista focus previous
+//	    bindsym Right nop barista focus next
+//	    bindsym Return nop barista focus activate
+//	    bindsym Escape nop barista focus exit; mode "default"
+//	}
+func (b *I3Bar) FocusBindings(c i3ipc.Client) *I3Bar {
+	go func() {
+		err := c.Subscribe([]string{"binding"}, b.handleBinding)
+		log.Error("binding events failed", "error", err)
+	}()
+	return b
+}
+
+func (b *I3Bar) handleBinding(msgType uint32, payload []byte) {
+	if msgType != i3ipc.BindingEvent {
+		return
+	}
+	var event struct {
+		Binding struct{ Command string }
+	}
+	if json.Unmarshal(payload, &event) != nil {
+		return
+	}
+	// Bindings can run several commands, separated by ';' or ','.
+	commands := strings.FieldsFunc(event.Binding.Command, func(r rune) bool {
+		return r == ';' || r == ','
+	})
+	for _, cmd := range commands {
+		cmd = strings.Join(strings.Fields(cmd), " ")
+		if !strings.HasPrefix(cmd, focusCommand+" ") {
+			continue
+		}
+		args := strings.Fields(strings.TrimPrefix(cmd, focusCommand))
+		switch args[0] {
+		case "next":
+			b.FocusNext()
+		case "previous", "prev":
+			b.FocusPrevious()
+		case "activate":
+			button := ButtonLeft
+			if len(args) > 1 {
+				var ok bool
+				if button, ok = focusButtons[args[1]]; !ok {
+					log.Error("unknown button", "command", cmd)
+					continue
+				}
+			}
+			b.Activate(button)
+		case "exit":
+			b.Unfocus()
+		default:
+			log.Error("unknown focus command", "command", cmd)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+// Layout controls the separators and spacing between modules, instead of
+// relying only on i3bar's global separator settings, and how outputs are
+// shortened when the bar is too wide. A layout only fills in the settings
+// that a module does not set itself, so modules that need a specific
+// separator or short text keep it. The zero value does not change any
+// outputs.
+type Layout struct {
+	separator    bool
+	hasSeparator bool
+	spacing      int
+	hasSpacing   bool
+	shortText    bool
+	maxWidth     int
+}
+
+// Separators sets whether a separator is drawn after each module.
+func (l Layout) Separators(separator bool) Layout {
+	l.separator = separator
+	l.hasSeparator = true
+	return l
+}
+
+// Spacing sets the gap after each module, in pixels.
+func (l Layout) Spacing(spacing int) Layout {
+	l.spacing = spacing
+	l.hasSpacing = true
+	return l
+}
+
+// ShortText sets whether segments without a short_text get one derived
+// from their text using Shorten, which i3bar uses when the bar does not fit.
+func (l Layout) ShortText(shortText bool) Layout {
+	l.shortText = shortText
+	return l
+}
+
+// MaxWidth sets the width budget for the entire bar, in characters, since
+// the bar cannot measure text in i3bar's font. When the text of all
+// segments is wider, segments are shortened one at a time until the bar
+// fits (see Fit), rather than i3bar shortening all of them at once. It only
+// applies to the bar's layout, and is best combined with ShortText.
+func (l Layout) MaxWidth(width int) Layout {
+	l.maxWidth = width
+	return l
+}
+
+// Apply returns the output with the layout applied to its last segment,
+// which controls the separator after the module, and short text derived
+// for all segments if enabled. The original output is not modified.
+func (l Layout) Apply(o Output) Output {
+	if len(o) == 0 || (!l.hasSeparator && !l.hasSpacing && !l.shortText) {
+		return o
+	}
+	if l.shortText {
+		o = l.applyShortText(o)
+	}
+	last := Segment{}
+	for k, v := range o[len(o)-1] {
+		last[k] = v
+	}
+	if _, ok := last["separator"]; !ok && l.hasSeparator {
+		last.Separator(l.separator)
+	}
+	if _, ok := last["separator_block_width"]; !ok && l.hasSpacing {
+		last.SeparatorWidth(l.spacing)
+	}
+	out := append(Output(nil), o[:len(o)-1]...)
+	return append(out, last)
+}
+
+// applyShortText returns the output with short text derived for any
+// segments that do not have one, and where it would be shorter.
+func (l Layout) applyShortText(o Output) Output {
+	out := make(Output, len(o))
+	for i, s := range o {
+		out[i] = s
+		if _, ok := s["short_text"]; ok {
+			continue
+		}
+		text, _ := s["full_text"].(string)
+		if short := Shorten(text); short != text && short != "" {
+			shortened := Segment{}
+			for k, v := range s {
+				shortened[k] = v
+			}
+			out[i] = shortened.ShortText(short)
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestLayoutApply(t *testing.T) {
+	assert.Empty(t, Layout{}.Separators(false).Apply(Output{}), "empty output")
+
+	out := Output{NewSegment("a"), NewSegment("b")}
+	assert.Equal(t, out, Layout{}.Apply(out), "zero layout")
+
+	laidOut := Layout{}.Separators(false).Spacing(5).Apply(out)
+	assert.Equal(t, 2, len(laidOut))
+	assert.Equal(t, out[0], laidOut[0], "inner segments are not changed")
+	assert.Equal(t, false, laidOut[1]["separator"])
+	assert.Equal(t, 5, laidOut[1]["separator_block_width"])
+	assert.Nil(t, out[1]["separator"], "original output is not modified")
+
+	out = Output{NewSegment("a").Separator(true).SeparatorWidth(20)}
+	laidOut = Layout{}.Separators(false).Spacing(5).Apply(out)
+	assert.Equal(t, true, laidOut[0]["separator"], "module settings are kept")
+	assert.Equal(t, 20, laidOut[0]["separator_block_width"], "module settings are kept")
+}
+
+func TestLayoutShortText(t *testing.T) {
+	out := Output{
+		NewSegment("CPU: 12%"),
+		NewSegment("Memory: 2 GiB").ShortText("mem"),
+		NewSegment("12:30"),
+	}
+	laidOut := Layout{}.ShortText(true).Apply(out)
+	assert.Equal(t, "12%", laidOut[0]["short_text"], "short text derived")
+	assert.Equal(t, "mem", laidOut[1]["short_text"], "module's short text is kept")
+	assert.Nil(t, laidOut[2]["short_text"], "no short text if not shorter")
+	assert.Nil(t, laidOut[2]["separator"], "separators not changed")
+	assert.Nil(t, out[0]["short_text"], "original output is not modified")
+	assert.Nil(t, Layout{}.Apply(out)[0]["short_text"], "disabled by default")
+}
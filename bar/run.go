@@ -18,13 +18,22 @@ import (
 	"bufio"
 	"encoding/json"
 	"io"
+	"net"
 	"os"
 	"os/signal"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/timing"
 )
 
+var log = logging.New("bar")
+
 // i3Output is sent to i3bar. It groups together one or more Segments.
 type i3Output []Segment
 
@@ -47,69 +56,388 @@ type i3Module struct {
 	Module
 	Name       string
 	LastOutput i3Output
+	// Guards LastOutput, since the bar can be printed at any time, e.g.
+	// when modules are reordered or a new stream connects.
+	outputMutex sync.Mutex
+	// Outputs restricts the module to the named outputs (monitors),
+	// if not empty.
+	Outputs []string
+	// Set when the module is removed from the bar, after which its output
+	// is discarded. Guarded by outputMutex.
+	removed bool
+	// Statistics for debugging, also guarded by outputMutex.
+	updates    int64
+	lastUpdate time.Time
+	latency    time.Duration
+	events     int64
+}
+
+// shownOn returns true if the module should be shown on the named output.
+func (m *i3Module) shownOn(output string) bool {
+	if len(m.Outputs) == 0 {
+		return true
+	}
+	for _, o := range m.Outputs {
+		if o == output {
+			return true
+		}
+	}
+	return false
+}
+
+// i3Stream is an i3bar protocol stream for a single output (monitor).
+type i3Stream struct {
+	output string
+	// The Reader to read events from (e.g. stdin)
+	reader io.Reader
+	// The Writer to write bar output to (e.g. stdout)
+	writer io.Writer
+	// A json encoder set to write to the output stream.
+	encoder *json.Encoder
+}
+
+// start writes the header and starts the infinite array of outputs.
+func (s *i3Stream) start(header i3Header) error {
+	// Set up the encoder for the output stream,
+	// so that module outputs can be written directly.
+	s.encoder = json.NewEncoder(s.writer)
+	if err := s.encoder.Encode(&header); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.writer, "[")
+	return err
+}
+
+// close closes the stream's writer if it can be closed, e.g. a connection
+// accepted by ListenOutput, when the stream is removed from the bar.
+func (s *i3Stream) close() {
+	if c, ok := s.writer.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// print outputs the entire bar for the stream's output, using the last
+// output for each module.
+func (s *i3Stream) print(modules []*i3Module, layout Layout, focus focusState) error {
+	// i3bar requires the entire bar to be printed at once, so we just take the
+	// last cached value for each module and construct the current bar.
+	// The bar will update any modules before calling this method, so the
+	// LastOutput property of each module will represent the current state.
+	var outputs []Segment
+	for _, m := range modules {
+		if !m.shownOn(s.output) {
+			continue
+		}
+		m.outputMutex.Lock()
+		last := m.LastOutput
+		m.outputMutex.Unlock()
+		for i, segment := range layout.Apply(Output(last)) {
+			outputs = append(outputs, focus.apply(m, i, segment))
+		}
+	}
+	outputs = layout.Fit(outputs)
+	if err := s.encoder.Encode(outputs); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.writer, ",\n")
+	return err
 }
 
 // output converts the module's output to i3Output by adding the name (position),
-// sets the module's last output to the converted i3Output, and signals the bar
-// to update its output.
-func (m *i3Module) output(ch chan<- interface{}) {
+// sets the module's last output to the converted i3Output, passes it to the
+// observe function, and signals the bar to update its output.
+func (m *i3Module) output(ch chan<- interface{}, observe func(string, Output)) {
 	for o := range m.Stream() {
 		var i3out i3Output
 		for _, segment := range o {
 			segment["name"] = m.Name
 			i3out = append(i3out, segment)
 		}
-		m.LastOutput = i3out
+		m.outputMutex.Lock()
+		removed := m.removed
+		start := time.Now()
+		if !removed {
+			m.LastOutput = i3out
+			m.updates++
+			m.lastUpdate = start
+		}
+		m.outputMutex.Unlock()
+		// Removed modules are still drained, since modules cannot be stopped,
+		// but their output no longer updates the bar.
+		if removed {
+			continue
+		}
+		observe(m.Name, o)
 		ch <- nil
+		m.outputMutex.Lock()
+		m.latency += time.Since(start)
+		m.outputMutex.Unlock()
 	}
 }
 
 // I3Bar is a "bar" instance that handles events and streams output.
 type I3Bar struct {
-	// The list of modules that make up this bar.
+	// The list of modules that make up this bar. Removed modules are kept
+	// (but marked removed), so that the index can be used as the name.
 	i3Modules []*i3Module
+	// The modules in the order they are shown on the bar, which can be
+	// changed at runtime. The names of the modules do not change, so
+	// events can always be routed using i3Modules.
+	order []*i3Module
+	// The layout applied to all modules, which can also be changed at
+	// runtime. Guarded by orderMutex along with the order, the list of
+	// modules, and the started flag.
+	layout     Layout
+	orderMutex sync.Mutex
 	// The channel that receives a signal on module updates.
 	update chan interface{}
 	// The channel that aggregates all events from i3.
 	events chan i3Event
-	// The Reader to read events from (e.g. stdin)
-	reader io.Reader
-	// The Writer to write bar output to (e.g. stdout)
-	writer io.Writer
-	// A json encoder set to write to the output stream.
-	encoder *json.Encoder
-	// Flipped when Run() is called, to prevent issues with modules
-	// being added after the bar has been started.
+	// The main stream, e.g. stdin/stdout.
+	stdio *i3Stream
+	// Additional streams for other outputs, which are removed on errors.
+	streams      []*i3Stream
+	streamsMutex sync.Mutex
+	// Flipped when Run() is called, so that modules added after the bar
+	// has been started are streamed immediately.
 	started bool
 	// Suppress pause/resume signal handling to workaround potential
 	// weirdness with signals.
 	suppressSignals bool
+	// Closed once the bar is running, so that streams can be added.
+	running chan struct{}
+	// Statistics for debugging, guarded by statsMutex.
+	startTime  time.Time
+	prints     int64
+	printTime  time.Duration
+	statsMutex sync.Mutex
+	// Channels that are signalled after the bar is printed.
+	subscribers      map[chan struct{}]bool
+	subscribersMutex sync.Mutex
+	// Handlers that see click events before the modules do.
+	interceptors   []func(Output, Event) bool
+	interceptMutex sync.Mutex
+	// Handlers that see every output from every module.
+	observers     []func(string, Output)
+	observerMutex sync.Mutex
+	// The segment highlighted in focus mode, see FocusNext.
+	focus      focusState
+	focusMutex sync.Mutex
 }
 
-// Add adds a module to a bar, and returns the bar for chaining.
+// Add adds a module to a bar, and returns the bar for chaining. Modules
+// can also be added while the bar is running, and are shown at the end of
+// the bar.
 func (b *I3Bar) Add(modules ...Module) *I3Bar {
 	for _, m := range modules {
-		b.addModule(m)
+		b.addModule(m, nil)
 	}
 	// Return the bar for chaining (e.g. bar.Add(x, y).Run())
 	return b
 }
 
-// addModule adds a single module to the bar.
-func (b *I3Bar) addModule(module Module) {
-	// Panic if adding modules to an already running bar.
-	// TODO: Support this in the future.
-	if b.started {
-		panic("Cannot add modules after .Run()")
+// AddTo adds modules to a bar that are only shown on the named outputs
+// (monitors), and returns the bar for chaining. Modules added using Add
+// are shown on all outputs.
+func (b *I3Bar) AddTo(outputs []string, modules ...Module) *I3Bar {
+	for _, m := range modules {
+		b.addModule(m, outputs)
 	}
+	return b
+}
+
+// addModule adds a single module to the bar, and starts streaming it if
+// the bar is already running.
+func (b *I3Bar) addModule(module Module, outputs []string) {
+	b.orderMutex.Lock()
 	// Use the position of the module in the list as the "name", so when i3bar
 	// sends us events, we can use atoi(name) to get the correct module.
 	name := strconv.Itoa(len(b.i3Modules))
-	i3Module := i3Module{
-		Module: module,
-		Name:   name,
+	i3Module := &i3Module{
+		Module:  module,
+		Name:    name,
+		Outputs: outputs,
+	}
+	b.i3Modules = append(b.i3Modules, i3Module)
+	b.order = append(b.order, i3Module)
+	started := b.started
+	b.orderMutex.Unlock()
+	if started {
+		go i3Module.output(b.update, b.observe)
+		b.reprint()
+	}
+}
+
+// Remove removes a module from the bar, and returns false if the module
+// is not on the bar. Modules can be removed while the bar is running, and
+// no longer receive events, but are not otherwise stopped. Modules are found
+// by equality, so modules of non-comparable types (e.g. func or slice types)
+// must be removed using RemoveAt instead.
+func (b *I3Bar) Remove(module Module) bool {
+	if idx := b.indexOf(module); idx >= 0 {
+		return b.RemoveAt(idx)
+	}
+	return false
+}
+
+// RemoveAt removes the module that was added to the bar at the given index,
+// where 0 is the first module added, and returns false if there is no such
+// module, or if it has already been removed.
+func (b *I3Bar) RemoveAt(index int) bool {
+	b.orderMutex.Lock()
+	module, ok := b.at(index)
+	if !ok {
+		b.orderMutex.Unlock()
+		return false
+	}
+	module.outputMutex.Lock()
+	module.removed = true
+	module.outputMutex.Unlock()
+	for idx, m := range b.order {
+		if m == module {
+			b.order = append(b.order[:idx], b.order[idx+1:]...)
+			break
+		}
+	}
+	b.orderMutex.Unlock()
+	b.reprint()
+	return true
+}
+
+// indexOf returns the index of the given module among the modules on
+// the bar, or -1 if the module is not on the bar.
+func (b *I3Bar) indexOf(module Module) int {
+	b.orderMutex.Lock()
+	defer b.orderMutex.Unlock()
+	for idx, m := range b.i3Modules {
+		if sameModule(m.Module, module) && !isRemoved(m) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// at returns the module that was added to the bar at the given index,
+// if it has not been removed. Must be called with orderMutex held.
+func (b *I3Bar) at(index int) (*i3Module, bool) {
+	if index < 0 || index >= len(b.i3Modules) {
+		return nil, false
+	}
+	module := b.i3Modules[index]
+	if isRemoved(module) {
+		return nil, false
+	}
+	return module, true
+}
+
+// isRemoved returns true if the module has been removed from the bar.
+func isRemoved(m *i3Module) bool {
+	m.outputMutex.Lock()
+	defer m.outputMutex.Unlock()
+	return m.removed
+}
+
+// Move moves a module to the given position on the bar, where 0 is the
+// first (leftmost) position, and returns false if the module is not on the
+// bar. Positions beyond the end of the bar move the module to the end.
+// Modules can be moved while the bar is running, e.g. to temporarily
+// promote a module to the front of the bar. Modules are found by equality,
+// so modules of non-comparable types (e.g. func or slice types) must be
+// moved using MoveAt instead.
+func (b *I3Bar) Move(module Module, position int) bool {
+	if idx := b.indexOf(module); idx >= 0 {
+		return b.MoveAt(idx, position)
+	}
+	return false
+}
+
+// MoveAt moves the module that was added to the bar at the given index,
+// where 0 is the first module added, to the given position on the bar, and
+// returns false if there is no such module.
+func (b *I3Bar) MoveAt(index, position int) bool {
+	b.orderMutex.Lock()
+	module, ok := b.at(index)
+	if !ok {
+		b.orderMutex.Unlock()
+		return false
+	}
+	from := -1
+	for idx, m := range b.order {
+		if m == module {
+			from = idx
+		}
+	}
+	b.order = append(b.order[:from], b.order[from+1:]...)
+	if position < 0 {
+		position = 0
+	}
+	if position > len(b.order) {
+		position = len(b.order)
+	}
+	b.order = append(b.order[:position], append([]*i3Module{module}, b.order[position:]...)...)
+	b.orderMutex.Unlock()
+	b.reprint()
+	return true
+}
+
+// sameModule returns true if both modules are the same, without panicking
+// on modules of non-comparable types, which are never considered the same.
+func sameModule(a, b Module) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || t == nil || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// MoveToFront moves a module to the first (leftmost) position on the bar,
+// and returns false if the module is not on the bar.
+func (b *I3Bar) MoveToFront(module Module) bool {
+	return b.Move(module, 0)
+}
+
+// ResetOrder restores the original order of modules on the bar, i.e. the
+// order in which they were added.
+func (b *I3Bar) ResetOrder() {
+	b.orderMutex.Lock()
+	b.order = nil
+	for _, m := range b.i3Modules {
+		if !isRemoved(m) {
+			b.order = append(b.order, m)
+		}
+	}
+	b.orderMutex.Unlock()
+	b.reprint()
+}
+
+// Layout sets the separators and spacing between modules on the bar, e.g.
+// Layout(bar.Layout{}.Separators(false).Spacing(12)) for gaps instead of
+// separators. Modules that set their own separators, or groups with their own layout,
+// are not affected. The layout can be changed while the bar is running, and
+// its MaxWidth applies to each output of the bar separately.
+func (b *I3Bar) Layout(layout Layout) *I3Bar {
+	b.orderMutex.Lock()
+	b.layout = layout
+	b.orderMutex.Unlock()
+	b.reprint()
+	return b
+}
+
+// modules returns the modules in the order they are shown on the bar,
+// and the layout to apply to them.
+func (b *I3Bar) modules() ([]*i3Module, Layout) {
+	b.orderMutex.Lock()
+	defer b.orderMutex.Unlock()
+	return append([]*i3Module(nil), b.order...), b.layout
+}
+
+// reprint prints the bar again if it is running, e.g. after reordering.
+func (b *I3Bar) reprint() {
+	select {
+	case <-b.running:
+		go func() { b.update <- nil }()
+	default:
 	}
-	b.i3Modules = append(b.i3Modules, &i3Module)
 }
 
 // SuppressSignals instructs the bar to skip the pause/resume signal handling.
@@ -122,70 +450,149 @@ func (b *I3Bar) SuppressSignals(suppressSignals bool) *I3Bar {
 	return b
 }
 
-// Run sets up all the streams and enters the main loop.
-func (b *I3Bar) Run() error {
-	var signalChan chan os.Signal
-	if !b.suppressSignals {
-		// Set up signal handlers for USR1/2 to pause/resume supported modules.
-		signalChan = make(chan os.Signal, 2)
-		signal.Notify(signalChan, syscall.SIGUSR1, syscall.SIGUSR2)
+// Output sets the name of the output (monitor) that the bar's main stream,
+// e.g. stdin/stdout, is for. Modules added using AddTo are only shown on
+// their outputs. Must be called before Run.
+func (b *I3Bar) Output(name string) *I3Bar {
+	if b.started {
+		panic("Cannot change the output after .Run()")
 	}
+	b.stdio.output = name
+	return b
+}
 
-	// Mark the bar as started.
-	b.started = true
+// AddOutput adds a stream for another output (monitor), so that a single
+// process can serve the bars on all outputs, e.g. over named pipes. Must be
+// called before Run.
+func (b *I3Bar) AddOutput(name string, reader io.Reader, writer io.Writer) *I3Bar {
+	if b.started {
+		panic("Cannot add outputs after .Run()")
+	}
+	b.streams = append(b.streams, &i3Stream{output: name, reader: reader, writer: writer})
+	return b
+}
 
-	// Read events from the input stream, pipe them to the events channel.
-	go b.readEvents()
-	for _, m := range b.i3Modules {
-		go m.output(b.update)
+// ListenOutput serves the bar for another output (monitor) on a unix
+// socket, to each client that connects, e.g. a status_command of
+// "socat STDIO UNIX-CONNECT:/path/to/socket" in the i3 bar configuration
+// for the output. It returns immediately, and clients are served once the
+// bar is running.
+func (b *I3Bar) ListenOutput(name, socket string) error {
+	os.Remove(socket)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(&i3Stream{output: name, reader: conn, writer: conn})
+		}
+	}()
+	return nil
+}
+
+// serve starts an additional stream on a running bar.
+func (b *I3Bar) serve(s *i3Stream) {
+	<-b.running
+	if s.start(b.header()) != nil {
+		s.close()
+		return
 	}
+	b.streamsMutex.Lock()
+	b.streams = append(b.streams, s)
+	b.streamsMutex.Unlock()
+	go b.readEvents(s.reader)
+	// Print the current bar to the new stream.
+	b.update <- nil
+}
 
-	// Write header.
+// header returns the header for each stream.
+func (b *I3Bar) header() i3Header {
 	header := i3Header{
 		Version:     1,
 		ClickEvents: true,
 	}
-
 	if !b.suppressSignals {
 		// Go doesn't allow us to handle the default SIGSTOP,
 		// so we'll use SIGUSR1 and SIGUSR2 for pause/resume.
 		header.StopSignal = int(syscall.SIGUSR1)
 		header.ContSignal = int(syscall.SIGUSR2)
 	}
-	// Set up the encoder for the output stream,
-	// so that module outputs can be written directly.
-	b.encoder = json.NewEncoder(b.writer)
-	if err := b.encoder.Encode(&header); err != nil {
-		return err
+	return header
+}
+
+// Run sets up all the streams and enters the main loop.
+func (b *I3Bar) Run() error {
+	var signalChan chan os.Signal
+	if !b.suppressSignals {
+		// Set up signal handlers for USR1/2 to pause/resume supported modules.
+		signalChan = make(chan os.Signal, 2)
+		signal.Notify(signalChan, syscall.SIGUSR1, syscall.SIGUSR2)
+	}
+
+	// Mark the bar as started, and start streaming all modules added so far.
+	// Any modules added after this are streamed as they are added.
+	b.statsMutex.Lock()
+	b.startTime = time.Now()
+	b.statsMutex.Unlock()
+	b.orderMutex.Lock()
+	b.started = true
+	for _, m := range b.i3Modules {
+		go m.output(b.update, b.observe)
 	}
-	// Start the infinite array.
-	if _, err := io.WriteString(b.writer, "["); err != nil {
+	b.orderMutex.Unlock()
+
+	// Read events from the input streams, pipe them to the events channel.
+	go b.readEvents(b.stdio.reader)
+
+	// Write the header and start the infinite array on each stream.
+	header := b.header()
+	if err := b.stdio.start(header); err != nil {
 		return err
 	}
+	b.streamsMutex.Lock()
+	streams := b.streams[:0]
+	for _, s := range b.streams {
+		if s.start(header) == nil {
+			streams = append(streams, s)
+			go b.readEvents(s.reader)
+		} else {
+			s.close()
+		}
+	}
+	b.streams = streams
+	b.streamsMutex.Unlock()
+	close(b.running)
 
 	// Infinite arrays on both sides.
 	for {
 		select {
 		case _ = <-b.update:
 			// The complete bar needs to printed on each update.
+			start := time.Now()
 			if err := b.print(); err != nil {
 				return err
 			}
+			b.statsMutex.Lock()
+			b.prints++
+			b.printTime += time.Since(start)
+			b.statsMutex.Unlock()
+			b.notifySubscribers()
 		case event := <-b.events:
 			// Events are stripped of the name before being dispatched to the
 			// correct module.
-			if module, ok := b.get(event.Name); ok {
-				// Check that the module actually supports click events.
-				if clickable, ok := module.Module.(Clickable); ok {
-					// Goroutine to prevent click handlers from blocking the bar.
-					go clickable.Click(event.Event)
-				}
-			}
+			b.Click(event.Name, event.Event)
 		case sig := <-signalChan:
 			switch sig {
 			case syscall.SIGUSR1:
+				log.Fine("pausing")
 				b.pause()
 			case syscall.SIGUSR2:
+				log.Fine("resuming")
 				b.resume()
 			}
 		}
@@ -210,30 +617,38 @@ func New() *I3Bar {
 // NewOnIo constructs a new bar with an input and output stream, for maximum flexibility.
 func NewOnIo(reader io.Reader, writer io.Writer) *I3Bar {
 	return &I3Bar{
-		update: make(chan interface{}),
-		events: make(chan i3Event),
-		reader: reader,
-		writer: writer,
+		update:  make(chan interface{}),
+		events:  make(chan i3Event),
+		stdio:   &i3Stream{reader: reader, writer: writer},
+		running: make(chan struct{}),
+		focus:   focusState{style: DefaultFocusStyle},
 	}
 }
 
-// print outputs the entire bar, using the last output for each module.
+// print outputs the entire bar to each stream, using the last output for
+// each module. Errors on the main stream are returned, while streams for
+// other outputs are removed on errors, e.g. when a client disconnects.
 func (b *I3Bar) print() error {
-	// i3bar requires the entire bar to be printed at once, so we just take the
-	// last cached value for each module and construct the current bar.
-	// The bar will update any modules before calling this method, so the
-	// LastOutput property of each module will represent the current state.
-	var outputs []Segment
-	for _, m := range b.i3Modules {
-		for _, segment := range m.LastOutput {
-			outputs = append(outputs, segment)
-		}
-	}
-	if err := b.encoder.Encode(outputs); err != nil {
+	modules, layout := b.modules()
+	b.focusMutex.Lock()
+	focus := b.focus
+	b.focusMutex.Unlock()
+	if err := b.stdio.print(modules, layout, focus); err != nil {
 		return err
 	}
-	_, err := io.WriteString(b.writer, ",\n")
-	return err
+	b.streamsMutex.Lock()
+	defer b.streamsMutex.Unlock()
+	streams := b.streams[:0]
+	for _, s := range b.streams {
+		if err := s.print(modules, layout, focus); err == nil {
+			streams = append(streams, s)
+		} else {
+			log.Info("removing stream", "output", s.output, "error", err)
+			s.close()
+		}
+	}
+	b.streams = streams
+	return nil
 }
 
 // get finds the module that corresponds to the given "name" from i3.
@@ -242,16 +657,15 @@ func (b *I3Bar) get(name string) (*i3Module, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if index < 0 || len(b.i3Modules) <= index {
-		return nil, false
-	}
-	return b.i3Modules[index], true
+	b.orderMutex.Lock()
+	defer b.orderMutex.Unlock()
+	return b.at(index)
 }
 
 // readEvents parses the infinite stream of events received from i3.
-func (b *I3Bar) readEvents() {
+func (b *I3Bar) readEvents(input io.Reader) {
 	// Buffered I/O to allow complete events to be read in at once.
-	reader := bufio.NewReader(b.reader)
+	reader := bufio.NewReader(input)
 	// Consume opening '['
 	if rune, _, err := reader.ReadRune(); err != nil || rune != '[' {
 		return
@@ -280,18 +694,24 @@ func (b *I3Bar) readEvents() {
 	}
 }
 
-// pause instructs all pausable modules to suspend processing.
+// pause instructs all pausable modules to suspend processing,
+// and suspends all schedulers.
 func (b *I3Bar) pause() {
-	for _, m := range b.i3Modules {
+	timing.Pause()
+	modules, _ := b.modules()
+	for _, m := range modules {
 		if pausable, ok := m.Module.(Pausable); ok {
 			go pausable.Pause()
 		}
 	}
 }
 
-// resume instructs all pausable modules to continue processing.
+// resume instructs all pausable modules to continue processing,
+// and triggers any schedulers that were due while paused.
 func (b *I3Bar) resume() {
-	for _, m := range b.i3Modules {
+	timing.Resume()
+	modules, _ := b.modules()
+	for _, m := range modules {
 		if pausable, ok := m.Module.(Pausable); ok {
 			go pausable.Resume()
 		}
@@ -0,0 +1,159 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markupTag matches pango tags, so that shortening only changes text.
+var markupTag = regexp.MustCompile(`<[^>]*>`)
+
+// label matches a leading label such as "CPU: " or "Free space: ".
+var label = regexp.MustCompile(`^\s*\p{L}[\p{L}\p{N} -]{0,15}:\s+`)
+
+// units are the abbreviations for units that follow a number.
+var units = map[string]string{
+	"TiB": "T", "GiB": "G", "MiB": "M", "KiB": "K", "kiB": "K",
+	"TB": "T", "GB": "G", "MB": "M", "KB": "K", "kB": "K",
+	"GHz": "G", "MHz": "M", "kHz": "K",
+	"days": "d", "day": "d",
+	"hours": "h", "hour": "h", "hrs": "h", "hr": "h",
+	"minutes": "m", "minute": "m", "mins": "m", "min": "m",
+	"seconds": "s", "second": "s", "secs": "s", "sec": "s",
+	"percent": "%", "°C": "°", "°F": "°",
+}
+
+// unit matches a number followed by one of the units, e.g. "2.5 GiB".
+var unit = regexp.MustCompile(`(\d)\s?(` + unitNames() + `)\b`)
+
+// unitNames returns the units as alternatives for a regexp, longest first.
+func unitNames() string {
+	var names []string
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	for i, name := range names {
+		names[i] = regexp.QuoteMeta(name)
+	}
+	return strings.Join(names, "|")
+}
+
+// Shorten returns a shorter version of a segment's text, suitable for its
+// short_text, by removing icons (drawn with private use characters),
+// dropping a leading label, abbreviating units that follow numbers, and
+// collapsing whitespace, e.g. "Memory: 2.5 GiB free" becomes "2.5G free".
+// Pango tags are kept, so the text can be used with the segment's markup.
+func Shorten(text string) string {
+	tags := markupTag.FindAllString(text, -1)
+	chunks := markupTag.Split(text, -1)
+	for i, chunk := range chunks {
+		chunks[i] = strings.Map(func(r rune) rune {
+			if unicode.In(r, unicode.Co) {
+				return -1
+			}
+			return r
+		}, chunk)
+	}
+	// Only the first text of the segment can be a label, and it is kept
+	// if there is no other text.
+	for i, chunk := range chunks {
+		if strings.TrimSpace(chunk) == "" {
+			continue
+		}
+		loc := label.FindStringIndex(chunk)
+		if loc != nil && strings.TrimSpace(chunk[loc[1]:]+strings.Join(chunks[i+1:], "")) != "" {
+			chunks[i] = leadingSpace(chunk) + chunk[loc[1]:]
+		}
+		break
+	}
+	var out strings.Builder
+	for i, chunk := range chunks {
+		chunk = unit.ReplaceAllStringFunc(chunk, func(match string) string {
+			parts := unit.FindStringSubmatch(match)
+			return parts[1] + units[parts[2]]
+		})
+		out.WriteString(spaces.ReplaceAllString(chunk, " "))
+		if i < len(tags) {
+			out.WriteString(tags[i])
+		}
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// spaces matches runs of whitespace, which are collapsed to a single space.
+var spaces = regexp.MustCompile(`\s+`)
+
+// leadingSpace returns " " if the text starts with whitespace, to keep the
+// text separated from any preceding tags when its label is dropped.
+func leadingSpace(text string) string {
+	if r, _ := utf8.DecodeRuneInString(text); unicode.IsSpace(r) {
+		return " "
+	}
+	return ""
+}
+
+// width returns the number of characters shown for the segment's text.
+func width(s Segment, key string) int {
+	text, _ := s[key].(string)
+	if markup, _ := s["markup"].(Markup); markup == MarkupPango {
+		text = html.UnescapeString(markupTag.ReplaceAllString(text, ""))
+	}
+	return utf8.RuneCountInString(text)
+}
+
+// Fit returns the segments of the entire bar, with segments shortened to
+// their short_text one at a time, starting from the left, until the total
+// width is within the layout's MaxWidth. Segments at the end of the bar,
+// e.g. a clock, keep their full text the longest. The original segments
+// are not modified.
+func (l Layout) Fit(segments []Segment) []Segment {
+	if l.maxWidth <= 0 {
+		return segments
+	}
+	total := 0
+	for _, s := range segments {
+		total += width(s, "full_text")
+	}
+	var out []Segment
+	for i, s := range segments {
+		if total <= l.maxWidth {
+			return append(out, segments[i:]...)
+		}
+		short, ok := s["short_text"].(string)
+		if saved := width(s, "full_text") - width(s, "short_text"); ok && saved > 0 {
+			shortened := Segment{}
+			for k, v := range s {
+				shortened[k] = v
+			}
+			shortened["full_text"] = short
+			s = shortened
+			total -= saved
+		}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestShorten(t *testing.T) {
+	for _, tc := range []struct{ text, expected string }{
+		{"", ""},
+		{"12:30", "12:30"},
+		{"CPU: 12%", "12%"},
+		{"Free space: 2.5 GiB", "2.5G"},
+		{"Memory:", "Memory:"},
+		{"Uptime: 3 days, 4 hours", "3d, 4h"},
+		{" 21°C  cloudy", "21° cloudy"},
+		{"Disk 512 MB/s", "Disk 512M/s"},
+		{"15 minutes ago", "15m ago"},
+		{"5 minions", "5 minions"},
+		{"90 percent", "90%"},
+		{`<span font="Material Icons">` + "\ue1a3" + `</span> Battery: <b>80 percent</b>`,
+			`<span font="Material Icons"></span> <b>80%</b>`},
+		{"\ue1a4  Wi-Fi:  home", "home"},
+		{`<b>Load:</b> 1.5`, `<b>Load:</b> 1.5`},
+	} {
+		assert.Equal(t, tc.expected, Shorten(tc.text), tc.text)
+	}
+}
+
+func TestFit(t *testing.T) {
+	segments := []Segment{
+		NewSegment("Network: 12 MB/s").ShortText("12M"),
+		NewSegment("Memory: 2 GiB").ShortText("2G"),
+		NewSegment("Ignored"),
+		NewSegment("<b>Big</b> &amp; long").Markup(MarkupPango).ShortText("long"),
+		NewSegment("Tuesday 12:30").ShortText("12:30"),
+	}
+	texts := func(segments []Segment) []string {
+		var texts []string
+		for _, s := range segments {
+			texts = append(texts, s.Text())
+		}
+		return texts
+	}
+	all := texts(segments)
+
+	assert.Equal(t, segments, Layout{}.Fit(segments), "no budget")
+	assert.Equal(t, all, texts(Layout{}.MaxWidth(100).Fit(segments)), "within budget")
+	assert.Equal(t, []string{"12M", "2G", "Ignored", "<b>Big</b> &amp; long", "Tuesday 12:30"},
+		texts(Layout{}.MaxWidth(45).Fit(segments)), "shortened from the left")
+	assert.Equal(t, []string{"12M", "2G", "Ignored", "long", "12:30"},
+		texts(Layout{}.MaxWidth(10).Fit(segments)), "all shortened")
+	assert.Equal(t, all, texts(segments), "original segments not modified")
+	assert.Equal(t, "2G", Layout{}.MaxWidth(45).Fit(segments)[1]["short_text"],
+		"short text is kept")
+}
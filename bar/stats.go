@@ -0,0 +1,85 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stats are statistics about a running bar, for debugging slow or busy bars.
+type Stats struct {
+	// The time the bar was started, or zero if it is not running.
+	Started time.Time
+	// The number of times the bar was printed, and the total time spent
+	// printing it to all streams.
+	Prints    int64
+	PrintTime time.Duration
+	// The modules on the bar, in the order they are shown.
+	Modules []ModuleStats
+}
+
+// ModuleStats are statistics about a single module on the bar.
+type ModuleStats struct {
+	// The name of the module in the i3bar protocol.
+	Name string
+	// The go type of the module, e.g. *clock.module.
+	Type string
+	// The number of outputs sent by the module, and the time of the last one.
+	Updates    int64
+	LastUpdate time.Time
+	// The total time that outputs from the module waited for the bar, which
+	// grows if the bar is slow to print, or other modules update too often.
+	Latency time.Duration
+	// The number of click events sent to the module.
+	Events int64
+	// The last output from the module.
+	Output Output
+	// The last error from the module, for modules that report errors, e.g.
+	// those built on base.Base.
+	Error error
+}
+
+// errorReporter is implemented by modules that can report their last error.
+type errorReporter interface {
+	LastError() error
+}
+
+// Stats returns the current statistics for the bar.
+func (b *I3Bar) Stats() Stats {
+	b.statsMutex.Lock()
+	stats := Stats{Started: b.startTime, Prints: b.prints, PrintTime: b.printTime}
+	b.statsMutex.Unlock()
+	modules, _ := b.modules()
+	for _, m := range modules {
+		var err error
+		if reporter, ok := m.Module.(errorReporter); ok {
+			err = reporter.LastError()
+		}
+		m.outputMutex.Lock()
+		stats.Modules = append(stats.Modules, ModuleStats{
+			Name:       m.Name,
+			Type:       fmt.Sprintf("%T", m.Module),
+			Updates:    m.updates,
+			LastUpdate: m.lastUpdate,
+			Latency:    m.latency,
+			Events:     m.events,
+			Output:     Output(m.LastOutput),
+			Error:      err,
+		})
+		m.outputMutex.Unlock()
+	}
+	return stats
+}
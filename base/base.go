@@ -12,16 +12,44 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package base provides some helpers to make constructing bar modules easier.
+/*
+Package base provides some helpers to make constructing bar modules easier.
+
+Modules built on base follow a common pattern for formatting, so that the
+output of every module can be customised by the user:
+
+	// Info contains everything the module knows, e.g. the battery level.
+	type Info struct { ... }
+
+	type Module interface {
+	  base.WithClickHandler
+	  // OutputFunc configures a module to display the output of a user-defined function.
+	  OutputFunc(func(Info) bar.Output) Module
+	  // OutputTemplate configures a module to display the output of a template.
+	  OutputTemplate(func(interface{}) bar.Output) Module
+	}
+
+where OutputTemplate passes the Info to the template, for use with
+outputs.TextTemplate and outputs.PangoTemplate, and the module provides a
+sensible default output. Modules that need to re-render, e.g. after the
+output function changes, should keep the last Info and call Output again.
+*/
 package base
 
 import (
-	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/errlog"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
 	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/pango/icons"
+	"github.com/soumya92/barista/profiling"
 )
 
 // Base is a simple module that satisfies the bar.Module interface, while adding
@@ -32,10 +60,19 @@ type Base struct {
 	clickHandler   func(bar.Event)
 	updateFunc     func()
 	paused         bool
+	closed         bool
 	updateOnResume bool
 	outputOnResume bar.Output
-	lastError      error
-	scheduler      scheduler.Scheduler
+	scheduler      scheduler.Backoff
+	themeOnce      sync.Once
+	// lastError and the update status have their own mutex, so that they
+	// can be read for diagnostics even if the module is stuck while holding
+	// its lock.
+	lastError  error
+	running    int
+	busySince  time.Time
+	profile    *profiling.Profile
+	errorMutex sync.Mutex
 }
 
 // Module implements bar's Module, Clickable, and Pausable,
@@ -57,6 +94,14 @@ type WithClickHandler interface {
 
 // Stream starts up the worker goroutine, and channels its output to the bar.
 func (b *Base) Stream() <-chan bar.Output {
+	// Re-render with the new colors or icons when the color scheme or icon
+	// set changes. Only register once, even if the module is streamed again.
+	b.themeOnce.Do(func() {
+		n := NewNotifier()
+		colors.OnChange(n.Notify)
+		icons.OnChange(n.Notify)
+		go b.updateOnThemeChange(n.C)
+	})
 	b.Resume()
 	// Constructed when New is called, but is not directly exposed to extending
 	// modules. Use Output or Clear to control the bar output.
@@ -66,12 +111,10 @@ func (b *Base) Stream() <-chan bar.Output {
 // Click handles click events from the bar.
 // A middle click will always force an update, but if the module
 // is currently in an error state, the configured click handler
-// will be replaced by one that shows the error message using
-// i3-nagbar on left click and updates the module on right click
+// will be replaced by one that shows the recent errors using
+// errlog.Show on left click and updates the module on right click
 func (b *Base) Click(e bar.Event) {
-	b.Lock()
-	err := b.lastError
-	b.Unlock()
+	err := b.LastError()
 	if err == nil {
 		if e.Button == bar.ButtonMiddle {
 			b.Update()
@@ -86,13 +129,13 @@ func (b *Base) Click(e bar.Event) {
 	}
 	switch e.Button {
 	case bar.ButtonRight, bar.ButtonMiddle:
-		b.Lock()
+		b.errorMutex.Lock()
 		b.lastError = nil
-		b.Unlock()
+		b.errorMutex.Unlock()
 		b.Clear()
 		b.Update()
 	case bar.ButtonLeft:
-		go exec.Command("i3-nagbar", "-m", err.Error()).Run()
+		go errlog.Show()
 	}
 }
 
@@ -111,6 +154,10 @@ func (b *Base) Resume() {
 	var doUpdate bool
 
 	b.Lock()
+	if b.closed {
+		b.Unlock()
+		return
+	}
 	b.paused = false
 	if b.outputOnResume != nil {
 		doOutput = b.outputOnResume
@@ -130,6 +177,20 @@ func (b *Base) Resume() {
 	}
 }
 
+// Close stops the module permanently, e.g. when it is removed from the bar,
+// since the bar does not stop removed modules. Scheduled updates are
+// cancelled, and any later updates and outputs are discarded.
+func (b *Base) Close() error {
+	b.Lock()
+	b.closed = true
+	b.paused = true
+	b.updateOnResume = false
+	b.outputOnResume = nil
+	b.Unlock()
+	b.scheduler.Stop()
+	return nil
+}
+
 // Update marks the module as ready for an update.
 // The actual update may not happen immediately, e.g. if the bar is hidden.
 func (b *Base) Update() {
@@ -138,11 +199,79 @@ func (b *Base) Update() {
 	if b.updateFunc == nil {
 		return
 	}
+	if b.closed {
+		return
+	}
 	if b.paused {
 		b.updateOnResume = true
 		return
 	}
-	go b.updateFunc()
+	go b.runUpdate(b.updateFunc)
+}
+
+// runUpdate runs the update function, keeping track of running updates,
+// and profiling them if enabled.
+func (b *Base) runUpdate(updateFunc func()) {
+	b.errorMutex.Lock()
+	if b.running == 0 {
+		b.busySince = scheduler.Now()
+	}
+	b.running++
+	if b.profile == nil && profiling.Enabled() {
+		// Named after the package of the update function, since that is
+		// usually the package of the module.
+		fn := runtime.FuncForPC(reflect.ValueOf(updateFunc).Pointer())
+		name := "unknown"
+		if fn != nil {
+			name = packageName(fn.Name())
+		}
+		b.profile = profiling.New(name)
+	}
+	profile := b.profile
+	b.errorMutex.Unlock()
+	defer func() {
+		b.errorMutex.Lock()
+		b.running--
+		b.errorMutex.Unlock()
+	}()
+	if profile != nil {
+		defer profile.Start()()
+	}
+	updateFunc()
+}
+
+// Busy returns the time since which the module has been continuously
+// running updates, or the zero time if no update is running. This can be
+// used to detect modules that are stuck, e.g. on a hung request.
+func (b *Base) Busy() time.Time {
+	b.errorMutex.Lock()
+	defer b.errorMutex.Unlock()
+	if b.running == 0 {
+		return time.Time{}
+	}
+	return b.busySince
+}
+
+// updateOnThemeChange updates the module on each color scheme or icon set
+// change. Changes are coalesced, and updates run one at a time, so that a
+// busy module, e.g. one waiting on the bar, never blocks the change, and
+// does not pile up updates.
+func (b *Base) updateOnThemeChange(changes <-chan struct{}) {
+	for range changes {
+		b.Lock()
+		updateFunc := b.updateFunc
+		if b.closed {
+			updateFunc = nil
+		}
+		if b.paused && updateFunc != nil {
+			b.updateOnResume = true
+			updateFunc = nil
+		}
+		b.Unlock()
+		if updateFunc != nil {
+			b.runUpdate(updateFunc)
+		}
+	}
 }
 
 // UnlockAndUpdate unlocks the base mutex and marks the module as
@@ -175,7 +304,7 @@ func New() *Base {
 		// Trigger an initial update when Stream is first called.
 		updateOnResume: true,
 	}
-	b.scheduler = scheduler.Do(b.Update)
+	b.scheduler = scheduler.DoWithBackoff(b.Update)
 	return b
 }
 
@@ -198,8 +327,16 @@ func (b *Base) Clear() {
 
 // Output updates the module's output.
 func (b *Base) Output(out bar.Output) {
+	b.scheduler.Success()
+	b.output(out)
+}
+
+func (b *Base) output(out bar.Output) {
 	b.Lock()
 	defer b.Unlock()
+	if b.closed {
+		return
+	}
 	if b.paused {
 		b.outputOnResume = out
 		return
@@ -209,18 +346,50 @@ func (b *Base) Output(out bar.Output) {
 
 // Error shows an error on the bar.
 // It shows an urgent "Error" on the bar (or the full text if it fits),
-// and when clicked shows the full error using i3-nagbar.
+// and adds the error to the errlog, which is shown when clicked.
 func (b *Base) Error(err error) bool {
 	if err == nil {
 		return false
 	}
-	b.Lock()
+	errlog.Add(callerPackage(), err)
+	b.errorMutex.Lock()
 	b.lastError = err
-	b.Unlock()
-	b.Output(outputs.Error(err))
+	b.errorMutex.Unlock()
+	b.scheduler.Failure()
+	b.output(outputs.Error(err))
 	return true
 }
 
+// LastError returns the last error shown using Error, or nil if there is
+// no error, or it has been cleared by clicking the module.
+func (b *Base) LastError() error {
+	b.errorMutex.Lock()
+	defer b.errorMutex.Unlock()
+	return b.lastError
+}
+
+// callerPackage returns the package that called Error, relative to barista
+// for built-in modules, e.g. "modules/weather".
+func callerPackage() string {
+	pc, _, _, ok := runtime.Caller(2)
+	fn := runtime.FuncForPC(pc)
+	if !ok || fn == nil {
+		return "unknown"
+	}
+	return packageName(fn.Name())
+}
+
+// packageName returns the package of a function, relative to barista for
+// built-in packages. Function names are e.g. "github.com/a/b.(*module).update",
+// so the package ends at the first "." after the last "/".
+func packageName(name string) string {
+	slash := strings.LastIndex(name, "/") + 1
+	if dot := strings.Index(name[slash:], "."); dot >= 0 {
+		name = name[:slash+dot]
+	}
+	return strings.TrimPrefix(name, "github.com/soumya92/barista/")
+}
+
 // Schedule returns the scheduler for the module's update function.
 // This allows derived modules to change the update frequency, or
 // even enable and disable scheduled updates, without needing to
@@ -228,3 +397,10 @@ func (b *Base) Error(err error) bool {
 func (b *Base) Schedule() scheduler.Scheduler {
 	return b.scheduler
 }
+
+// BackoffOnError makes scheduled updates back off exponentially while the
+// module shows errors, up to the given maximum delay, and returns to the
+// normal schedule once the module updates its output successfully.
+func (b *Base) BackoffOnError(max time.Duration) {
+	b.scheduler.MaxDelay(max)
+}
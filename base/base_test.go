@@ -25,8 +25,12 @@ import (
 	"github.com/stretchrcom/testify/assert"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/errlog"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
 	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/pango/icons"
+	"github.com/soumya92/barista/profiling"
 	testModule "github.com/soumya92/barista/testing/module"
 )
 
@@ -40,9 +44,11 @@ func TestError(t *testing.T) {
 	assert.True(t, b.Error(fmt.Errorf("test error")), "returns true for non-nil error")
 	err := o.AssertError("on error")
 	assert.Equal(t, "test error", err, "error message is displayed on the output")
+	assert.Equal(t, "test error", b.LastError().Error(), "last error is kept")
 
 	assert.False(t, b.Error(nil), "returns false for nil error")
 	o.AssertNoOutput("on nil error")
+	assert.NotNil(t, b.LastError(), "nil error does not clear last error")
 }
 
 // TestUpdateAndScheduler tests that update functions (including nil)
@@ -90,6 +96,139 @@ func TestUpdateAndScheduler(t *testing.T) {
 	assertNoUpdate("when stopped")
 }
 
+// TestBackoffOnError tests that scheduled updates back off while the module
+// shows errors, and return to the normal schedule on success.
+func TestBackoffOnError(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	b := New()
+	o := testModule.NewOutputTester(t, b)
+
+	var err error
+	b.OnUpdate(func() {
+		if !b.Error(err) {
+			b.Output(bar.Output{bar.NewSegment("test")})
+		}
+	})
+	b.BackoffOnError(time.Hour)
+	b.Schedule().Every(time.Minute)
+
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertOutput("on refresh")
+
+	err = fmt.Errorf("offline")
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertError("on error")
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertNoOutput("while backing off")
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertError("after backoff delay")
+	scheduler.AdvanceBy(3 * time.Minute)
+	o.AssertNoOutput("while backing off")
+
+	err = nil
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertOutput("after longer backoff delay")
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertOutput("normal schedule restored")
+}
+
+// TestBusy tests that modules report how long updates have been running.
+func TestBusy(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	b := New()
+	o := testModule.NewOutputTester(t, b)
+	assert.True(t, b.Busy().IsZero(), "not busy when no updates have run")
+
+	started := make(chan bool)
+	finish := make(chan bool)
+	b.OnUpdate(func() {
+		started <- true
+		<-finish
+		b.Output(outputs.Text("done"))
+	})
+	start := scheduler.Now()
+	b.Update()
+	<-started
+	scheduler.AdvanceBy(time.Minute)
+	assert.Equal(t, start, b.Busy(), "busy since update started")
+
+	b.Update()
+	<-started
+	finish <- true
+	o.AssertOutput("first update finished")
+	assert.Equal(t, start, b.Busy(), "busy while any update is running")
+
+	finish <- true
+	o.AssertOutput("second update finished")
+	// The update function returns some time after its output.
+	for i := 0; i < 100 && !b.Busy().IsZero(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, b.Busy().IsZero(), "not busy after updates finish")
+}
+
+// TestProfiling tests that updates are profiled when profiling is enabled.
+func TestProfiling(t *testing.T) {
+	profiling.Enable(true)
+	defer profiling.Enable(false)
+	b := New()
+	o := testModule.NewOutputTester(t, b)
+	b.OnUpdate(func() { b.Output(outputs.Text("done")) })
+	b.Update()
+	o.AssertOutput("on update")
+
+	var stats profiling.Stats
+	// The profile is recorded after the update function returns.
+	for i := 0; i < 100 && stats.Refreshes == 0; i++ {
+		time.Sleep(time.Millisecond)
+		if report := profiling.Report(); len(report) > 0 {
+			stats = report[0]
+		}
+	}
+	assert.Equal(t, "base", stats.Name, "named after the update function's package")
+	assert.Equal(t, int64(1), stats.Refreshes)
+}
+
+// TestColorSchemeChange tests that modules are updated when the color
+// scheme changes, so that they can use the new colors.
+func TestColorSchemeChange(t *testing.T) {
+	b := New()
+	b.OnUpdate(func() {
+		b.Output(outputs.Text("test").Color(colors.Scheme("good")))
+	})
+	o := testModule.NewOutputTester(t, b)
+	o.AssertOutput("on start")
+
+	colors.Set("good", colors.Hex("#00ff00"))
+	out := o.AssertOutput("on scheme change")
+	assert.Equal(t, colors.Hex("#00ff00"), out[0]["color"])
+
+	colors.Replace(map[string]string{"good": "#007700"})
+	out = o.AssertOutput("on scheme replace")
+	assert.Equal(t, colors.Hex("#007700"), out[0]["color"])
+}
+
+func TestIconSetChange(t *testing.T) {
+	icons.Register("base-test-a", icons.NewProvider(map[string]string{"icon": "a"}))
+	icons.Register("base-test-b", icons.NewProvider(map[string]string{"icon": "b"}))
+	icons.Use("base-test-a")
+	defer icons.Use()
+
+	b := New()
+	b.OnUpdate(func() {
+		b.Output(outputs.Pango(icons.Icon("icon")))
+	})
+	o := testModule.NewOutputTester(t, b)
+	out := o.AssertOutput("on start")
+	assert.Equal(t, "a", out[0]["full_text"])
+
+	icons.Use("base-test-b", "base-test-a")
+	out = o.AssertOutput("on icon set change")
+	assert.Equal(t, "b", out[0]["full_text"])
+}
+
 // TestPauseResume tests that pause/resume work as expected, i.e. no
 // updates occur while the module is paused, and calls to update are
 // queued up properly and execute on resume.
@@ -153,6 +292,24 @@ func TestPauseResume(t *testing.T) {
 	o.AssertNoOutput("only last output emitted on resume")
 }
 
+func TestClose(t *testing.T) {
+	scheduler.TestMode(true)
+	b := New()
+	b.OnUpdate(func() { b.Output(outputs.Text("test")) })
+	o := testModule.NewOutputTester(t, b)
+	o.AssertOutput("when started")
+	b.Schedule().Every(time.Minute)
+
+	assert.Nil(t, b.Close())
+	scheduler.AdvanceBy(time.Minute)
+	o.AssertNoOutput("scheduled updates after close")
+	b.Update()
+	o.AssertNoOutput("update after close")
+	b.Resume()
+	b.Output(outputs.Text("other"))
+	o.AssertNoOutput("output after close")
+}
+
 // TestClickUpdates tests the update behaviour on click events,
 // for both the normal case and the error case.
 func TestClickUpdates(t *testing.T) {
@@ -239,7 +396,24 @@ func TestClickUpdates(t *testing.T) {
 		assertNotClicked("when in error state")
 	}
 
-	// TODO: Test shelling out to i3-nagbar on left-click.
+	shown := make(chan []errlog.Entry, 1)
+	errlog.SetViewer(func(e []errlog.Entry) { shown <- e })
+	defer errlog.SetViewer(errlog.Notification())
+	errlog.Clear()
+	b.Error(fmt.Errorf("another error"))
+	o.AssertOutput("on error")
+
+	b.Click(clickEvent(bar.ButtonLeft))
+	assertNoUpdate("on left click when error'd")
+	assertNotClicked("when showing errors")
+	select {
+	case history := <-shown:
+		assert.Len(t, history, 1)
+		assert.Equal(t, "base", history[0].Source, "error source")
+		assert.EqualError(t, history[0].Error, "another error")
+	case <-time.After(time.Second):
+		assert.Fail(t, "error history not shown on left click")
+	}
 }
 
 func hammerOnBase(b *Base, done chan<- interface{}) {
@@ -0,0 +1,109 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package cache provides a disk-backed cache for the results of expensive or
+rate-limited lookups, so that restarting the bar does not refetch everything.
+
+Values are stored as JSON, one file per key, along with the time they were
+saved, in the "barista" directory under $XDG_CACHE_HOME (falling back to
+~/.cache), following the XDG base directory specification. Unlike state,
+cached values can be removed at any time, so modules must always be able to
+fetch them again.
+
+Typical usage would be:
+
+	var rates map[string]float64
+	if saved, ok := cache.Load("exchange-usd", &rates); ok && scheduler.Now().Before(saved.Add(time.Hour)) {
+		// use the cached rates.
+	} else {
+		rates = fetchRates()
+		cache.Save("exchange-usd", rates)
+	}
+*/
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/base/state"
+)
+
+// entry is the on-disk representation of a cached value.
+type entry struct {
+	Saved time.Time
+	Value json.RawMessage
+}
+
+// Dir returns the directory where cached values are stored.
+func Dir() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(dir, "barista")
+}
+
+// store keeps one file per key in the cache directory.
+var store = state.In(Dir)
+
+// Load restores the value cached under the given key into value, which must
+// be a pointer, and returns the time at which it was saved. If nothing was
+// restored, ok is false and value is unchanged. Missing or invalid cache
+// files are ignored, since the value can always be fetched again.
+func Load(key string, value interface{}) (saved time.Time, ok bool) {
+	var e entry
+	if !store.Load(key, &e) || e.Value == nil {
+		return time.Time{}, false
+	}
+	if json.Unmarshal(e.Value, value) != nil {
+		return time.Time{}, false
+	}
+	return e.Saved, true
+}
+
+// Fresh restores the value cached under the given key into value, and
+// returns true if a value was restored and it was saved less than maxAge
+// ago. Stale values are not restored.
+func Fresh(key string, maxAge time.Duration, value interface{}) bool {
+	var raw json.RawMessage
+	saved, ok := Load(key, &raw)
+	if !ok || !scheduler.Now().Before(saved.Add(maxAge)) {
+		return false
+	}
+	return json.Unmarshal(raw, value) == nil
+}
+
+// Save caches the value under the given key, along with the current time,
+// replacing any previously cached value. The file is replaced atomically, so
+// concurrent readers never see a partially written value.
+//
+// Callers can usually ignore the error, since the cache only avoids
+// unnecessary requests, and the value will be fetched again if it is missing.
+func Save(key string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return store.Save(key, entry{Saved: scheduler.Now(), Value: bytes})
+}
+
+// Clear removes the value cached under the given key, if any.
+func Clear(key string) error {
+	return store.Clear(key)
+}
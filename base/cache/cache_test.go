@@ -0,0 +1,107 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+func withCacheDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", dir)
+	return dir, func() {
+		os.Unsetenv("XDG_CACHE_HOME")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDir(t *testing.T) {
+	os.Unsetenv("XDG_CACHE_HOME")
+	os.Setenv("HOME", "/home/user")
+	assert.Equal(t, "/home/user/.cache/barista", Dir(), "default cache directory")
+	os.Setenv("XDG_CACHE_HOME", "/cache")
+	defer os.Unsetenv("XDG_CACHE_HOME")
+	assert.Equal(t, "/cache/barista", Dir(), "uses $XDG_CACHE_HOME")
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, cleanup := withCacheDir(t)
+	defer cleanup()
+	scheduler.TestMode(true)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	scheduler.AdvanceTo(start)
+
+	var rates map[string]float64
+	_, ok := Load("rates", &rates)
+	assert.False(t, ok, "nothing to load")
+	assert.Nil(t, rates, "value unchanged when nothing to load")
+
+	assert.NoError(t, Save("rates", map[string]float64{"EUR": 0.8}))
+	scheduler.AdvanceBy(time.Minute)
+	saved, ok := Load("rates", &rates)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]float64{"EUR": 0.8}, rates, "value restored")
+	assert.Equal(t, start, saved.In(time.UTC), "saved time restored")
+
+	var weather struct{ Location string }
+	assert.NoError(t, Save("weather/new york", struct{ Location string }{"NYC"}))
+	assert.True(t, Fresh("weather/new york", time.Minute, &weather), "keys are escaped")
+	assert.Equal(t, "NYC", weather.Location)
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "barista"))
+	assert.Equal(t, 2, len(files), "one file per key, no leftover temporary files")
+
+	scheduler.AdvanceBy(time.Minute)
+	weather.Location = "unchanged"
+	assert.False(t, Fresh("weather/new york", time.Minute, &weather), "stale value")
+	assert.Equal(t, "unchanged", weather.Location, "stale value is not restored")
+	assert.True(t, Fresh("weather/new york", time.Hour, &weather), "fresh for a longer max age")
+	assert.Equal(t, "NYC", weather.Location)
+
+	ioutil.WriteFile(filepath.Join(dir, "barista", "invalid"), []byte("{"), 0644)
+	_, ok = Load("invalid", &rates)
+	assert.False(t, ok, "invalid cache file is ignored")
+	ioutil.WriteFile(filepath.Join(dir, "barista", "plain"), []byte(`{"EUR": 1}`), 0644)
+	_, ok = Load("plain", &rates)
+	assert.False(t, ok, "file without a cache entry is ignored")
+	assert.False(t, Fresh("plain", time.Hour, &rates), "file without a cache entry is ignored")
+	assert.Equal(t, map[string]float64{"EUR": 0.8}, rates, "value unchanged for invalid cache")
+
+	assert.NoError(t, Clear("rates"))
+	_, ok = Load("rates", &rates)
+	assert.False(t, ok, "cleared")
+	assert.NoError(t, Clear("rates"), "clearing missing value")
+}
+
+func TestSaveErrors(t *testing.T) {
+	dir, cleanup := withCacheDir(t)
+	defer cleanup()
+
+	assert.Error(t, Save("func", func() {}), "unencodable value")
+
+	ioutil.WriteFile(filepath.Join(dir, "barista"), []byte("not a directory"), 0644)
+	assert.Error(t, Save("key", 1), "cache directory cannot be created")
+}
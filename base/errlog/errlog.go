@@ -0,0 +1,194 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errlog keeps a bounded in-memory log of recent module errors, so
+that errors can be reviewed in full after the bar has moved on, instead of
+only the (often truncated) latest error on the bar.
+
+Errors shown using base.Base's Error are added automatically, and left
+clicking a module built on base.Base while it shows an error opens the
+history using the configured Viewer. By default, the history is shown in a
+desktop notification, but it can also be opened in a pager:
+
+	errlog.SetViewer(errlog.Pager("x-terminal-emulator", "-e", "less"))
+
+Other modules can add their errors using Add, and show the history from a
+click handler using Show.
+*/
+package errlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/notify"
+)
+
+var log = logging.New("errlog")
+
+// Entry is an error in the log.
+type Entry struct {
+	Time time.Time
+	// The source of the error, usually the package of the module,
+	// e.g. "modules/weather".
+	Source string
+	Error  error
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %s: %v", e.Time.Format("15:04:05"), e.Source, e.Error)
+}
+
+// DefaultSize is the number of errors kept by default.
+const DefaultSize = 50
+
+var (
+	mutex   sync.Mutex
+	size    = DefaultSize
+	entries []Entry
+)
+
+// SetSize sets the maximum number of errors kept, discarding the oldest
+// errors if there are more.
+func SetSize(maxEntries int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	size = maxEntries
+	trim()
+}
+
+// trim discards the oldest entries beyond the size limit. It must be called
+// with the mutex held.
+func trim() {
+	if len(entries) > size {
+		entries = append([]Entry(nil), entries[len(entries)-size:]...)
+	}
+}
+
+// Add adds an error from the given source to the log. Nil errors are ignored.
+func Add(source string, err error) {
+	if err == nil {
+		return
+	}
+	log.Fine("error", "source", source, "error", err)
+	mutex.Lock()
+	defer mutex.Unlock()
+	entries = append(entries, Entry{scheduler.Now(), source, err})
+	trim()
+}
+
+// Recent returns the errors in the log, oldest first.
+func Recent() []Entry {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return append([]Entry(nil), entries...)
+}
+
+// Clear removes all errors from the log.
+func Clear() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	entries = nil
+}
+
+// Format formats the entries one per line, newest first.
+func Format(entries []Entry) string {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[len(entries)-1-i] = e.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Viewer shows the error history to the user.
+type Viewer func([]Entry)
+
+var viewer = Notification()
+
+// SetViewer sets the viewer used to show the error history.
+func SetViewer(v Viewer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	viewer = v
+}
+
+// Show shows the recent errors using the configured Viewer. It does nothing
+// if there are no errors in the log.
+func Show() {
+	history := Recent()
+	mutex.Lock()
+	v := viewer
+	mutex.Unlock()
+	if len(history) > 0 {
+		v(history)
+	}
+}
+
+// Notification returns a viewer that shows the error history, newest first,
+// in a desktop notification. Repeated views replace the notification.
+func Notification() Viewer {
+	n := notify.New("barista").RateLimit(0)
+	return func(history []Entry) {
+		err := n.Notify(notify.Notification{
+			Summary: fmt.Sprintf("Recent errors (%d)", len(history)),
+			Body:    Format(history),
+			Urgency: notify.Normal,
+		})
+		if err != nil {
+			log.Error("could not show errors", "error", err)
+		}
+	}
+}
+
+// Pager returns a viewer that writes the error history, newest first, to a
+// temporary file and runs the given command with the file's path as an
+// additional argument, e.g. Pager("x-terminal-emulator", "-e", "less").
+// The file is removed once the command exits.
+func Pager(name string, args ...string) Viewer {
+	return func(history []Entry) {
+		f, err := ioutil.TempFile("", "barista-errors")
+		if err != nil {
+			log.Error("could not show errors", "error", err)
+			return
+		}
+		_, err = f.WriteString(Format(history) + "\n")
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		cmd := exec.Command(name, append(append([]string(nil), args...), f.Name())...)
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			log.Error("could not show errors", "error", err)
+			return
+		}
+		go func() {
+			cmd.Wait()
+			os.Remove(f.Name())
+		}()
+	}
+}
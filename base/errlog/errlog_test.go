@@ -0,0 +1,105 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errlog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+func sources(entries []Entry) []string {
+	var s []string
+	for _, e := range entries {
+		s = append(s, e.Source)
+	}
+	return s
+}
+
+func TestLog(t *testing.T) {
+	scheduler.TestMode(true)
+	Clear()
+	defer SetSize(DefaultSize)
+
+	assert.Empty(t, Recent())
+	Add("a", nil)
+	assert.Empty(t, Recent(), "nil errors are ignored")
+
+	SetSize(3)
+	for _, src := range []string{"a", "b", "c", "d"} {
+		Add(src, fmt.Errorf("error from %s", src))
+		scheduler.AdvanceBy(time.Minute)
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, sources(Recent()),
+		"oldest errors are discarded")
+
+	SetSize(2)
+	history := Recent()
+	assert.Equal(t, []string{"c", "d"}, sources(history),
+		"shrinking discards oldest errors")
+	assert.Equal(t, time.Minute, history[1].Time.Sub(history[0].Time))
+	assert.Equal(t,
+		history[1].Time.Format("15:04:05")+" d: error from d\n"+
+			history[0].Time.Format("15:04:05")+" c: error from c",
+		Format(history), "formats newest first")
+
+	Clear()
+	assert.Empty(t, Recent())
+}
+
+func TestShow(t *testing.T) {
+	Clear()
+	var shown [][]Entry
+	SetViewer(func(e []Entry) { shown = append(shown, e) })
+	defer SetViewer(Notification())
+
+	Show()
+	assert.Empty(t, shown, "nothing shown without errors")
+
+	Add("src", errors.New("oops"))
+	Show()
+	assert.Len(t, shown, 1)
+	assert.Equal(t, []string{"src"}, sources(shown[0]))
+}
+
+func TestPager(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "errlog")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	Pager("sh", "-c", `cp "$1" `+out, "sh")([]Entry{
+		{Source: "a", Error: errors.New("first")},
+		{Source: "b", Error: errors.New("second")},
+	})
+
+	var contents []byte
+	for i := 0; i < 50 && len(contents) == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+		contents, _ = ioutil.ReadFile(out)
+	}
+	assert.Equal(t, "00:00:00 b: second\n00:00:00 a: first\n", string(contents))
+
+	assert.NotPanics(t, func() {
+		Pager("/does/not/exist")([]Entry{{Source: "a", Error: errors.New("x")}})
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package hostfs provides access to the host's /proc and /sys filesystems,
+for modules that read system information from them.
+
+All paths are resolved relative to a configurable root, which defaults to
+"/". This allows running the bar in a container with the host's
+filesystems mounted elsewhere, e.g.
+
+	hostfs.SetRoot("/host")
+
+after which modules reading "/proc/meminfo" will read "/host/proc/meminfo".
+It also allows tests to use a fixture tree on disk instead of the real
+/proc and /sys.
+*/
+package hostfs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	mutex   sync.RWMutex
+	root    = "/"
+	current = afero.NewBasePathFs(afero.NewOsFs(), "/")
+)
+
+// SetRoot sets the directory that all paths are resolved relative to.
+func SetRoot(dir string) {
+	if dir == "" {
+		dir = "/"
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	root = filepath.Clean(dir)
+	current = afero.NewBasePathFs(afero.NewOsFs(), root)
+}
+
+// Root returns the directory that all paths are resolved relative to.
+func Root() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return root
+}
+
+// Path returns the real path of the given path on the host, for use with
+// functions that do not support afero (e.g. readlink, or file watchers).
+func Path(path string) string {
+	return filepath.Join(Root(), path)
+}
+
+// Fs returns a filesystem that resolves all paths relative to the root.
+// The filesystem always uses the current root, so it can be stored in a
+// variable before SetRoot is called.
+func Fs() afero.Fs {
+	return rootFs{}
+}
+
+// rootFs delegates all operations to the filesystem for the current root.
+type rootFs struct{}
+
+func (rootFs) fs() afero.Fs {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return current
+}
+
+func (r rootFs) Create(name string) (afero.File, error) {
+	return r.fs().Create(name)
+}
+
+func (r rootFs) Mkdir(name string, perm os.FileMode) error {
+	return r.fs().Mkdir(name, perm)
+}
+
+func (r rootFs) MkdirAll(path string, perm os.FileMode) error {
+	return r.fs().MkdirAll(path, perm)
+}
+
+func (r rootFs) Open(name string) (afero.File, error) {
+	return r.fs().Open(name)
+}
+
+func (r rootFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return r.fs().OpenFile(name, flag, perm)
+}
+
+func (r rootFs) Remove(name string) error {
+	return r.fs().Remove(name)
+}
+
+func (r rootFs) RemoveAll(path string) error {
+	return r.fs().RemoveAll(path)
+}
+
+func (r rootFs) Rename(oldname, newname string) error {
+	return r.fs().Rename(oldname, newname)
+}
+
+func (r rootFs) Stat(name string) (os.FileInfo, error) {
+	return r.fs().Stat(name)
+}
+
+func (r rootFs) Name() string {
+	return "hostfs"
+}
+
+func (r rootFs) Chmod(name string, mode os.FileMode) error {
+	return r.fs().Chmod(name, mode)
+}
+
+func (r rootFs) Chown(name string, uid, gid int) error {
+	return r.fs().Chown(name, uid, gid)
+}
+
+func (r rootFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return r.fs().Chtimes(name, atime, mtime)
+}
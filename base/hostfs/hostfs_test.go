@@ -0,0 +1,60 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hostfs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "proc", "42"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "proc", "uptime"), []byte("1.5 2.5\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "proc", "42", "comm"), []byte("foo\n"), 0644))
+
+	fs := Fs()
+	assert.Equal(t, "/", Root())
+	assert.Equal(t, "/proc/uptime", Path("/proc/uptime"))
+
+	SetRoot(dir)
+	defer SetRoot("")
+	assert.Equal(t, dir, Root())
+	assert.Equal(t, filepath.Join(dir, "proc", "uptime"), Path("/proc/uptime"))
+
+	data, err := afero.ReadFile(fs, "/proc/uptime")
+	assert.Nil(t, err, "uses the new root for existing filesystems")
+	assert.Equal(t, "1.5 2.5\n", string(data))
+
+	comms, err := afero.Glob(fs, "/proc/*/comm")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/proc/42/comm"}, comms)
+
+	_, err = fs.Stat("/proc/meminfo")
+	assert.True(t, os.IsNotExist(err), "files outside the fixture tree")
+
+	_, err = afero.ReadFile(fs, "/proc/../../etc/passwd")
+	assert.NotNil(t, err, "paths cannot escape the root")
+
+	SetRoot("")
+	assert.Equal(t, "/", Root())
+}
@@ -0,0 +1,292 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package httpclient provides a shared HTTP client for modules backed by web
+APIs, so that they handle the network consistently.
+
+Clients reuse connections, make conditional requests (using ETag and
+Last-Modified) so that unchanged responses are not downloaded again, cache
+responses for as long as the server allows (using Cache-Control), can limit
+the rate of requests to each host, and apply a common timeout and retry
+policy.
+
+Typical usage would be:
+
+	var client = httpclient.New().RateLimit(time.Second)
+
+	func (p provider) Get() (Info, error) {
+		var r response
+		err := client.GetJSON(p.url, &r)
+		...
+	}
+*/
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+// transport is shared by all clients, so that connections are reused.
+var (
+	transportMutex sync.RWMutex
+	transport      = http.DefaultTransport
+)
+
+// SetTransport replaces the transport used by all clients, including
+// existing ones, and returns the previous transport. This allows tests to
+// stub responses (see testing/httpclient) without a network.
+func SetTransport(t http.RoundTripper) http.RoundTripper {
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	previous := transport
+	transport = t
+	return previous
+}
+
+// Transport returns a transport that makes requests using the current
+// transport, for packages that need an http.Client of their own, e.g. to
+// make authenticated requests, but should still be stubbed in tests.
+func Transport() http.RoundTripper {
+	return sharedTransport{}
+}
+
+// sharedTransport makes requests using the current transport.
+type sharedTransport struct{}
+
+func (sharedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	transportMutex.RLock()
+	t := transport
+	transportMutex.RUnlock()
+	return t.RoundTrip(r)
+}
+
+// sleep waits for the given duration, replaced in tests.
+var sleep = time.Sleep
+
+// Client makes GET requests to web APIs.
+type Client struct {
+	client *http.Client
+
+	mutex    sync.Mutex
+	retries  int
+	backoff  time.Duration
+	interval time.Duration
+	cache    map[string]*entry
+	next     map[string]time.Time
+}
+
+// entry is a cached response for a url.
+type entry struct {
+	body         []byte
+	etag         string
+	lastModified string
+	expires      time.Time
+}
+
+// New constructs a new client with the default policy: requests time out
+// after 10 seconds, and failed requests are retried twice, after 1 and 2
+// seconds. Requests are not rate limited by default.
+func New() *Client {
+	return &Client{
+		client:  &http.Client{Transport: sharedTransport{}, Timeout: 10 * time.Second},
+		retries: 2,
+		backoff: time.Second,
+		cache:   map[string]*entry{},
+		next:    map[string]time.Time{},
+	}
+}
+
+// Timeout sets the timeout for each request, including reading the response.
+func (c *Client) Timeout(timeout time.Duration) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.client = &http.Client{Transport: sharedTransport{}, Timeout: timeout}
+	return c
+}
+
+// Retry sets the number of times a failed request is retried, and the delay
+// before the first retry, which doubles for each subsequent retry. Only
+// network errors, server errors (5xx), and rate limit errors (429) are
+// retried, since other errors will not go away on their own.
+func (c *Client) Retry(retries int, backoff time.Duration) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.retries = retries
+	c.backoff = backoff
+	return c
+}
+
+// RateLimit sets the minimum interval between requests to the same host.
+// Requests made sooner wait until the interval has elapsed.
+func (c *Client) RateLimit(interval time.Duration) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.interval = interval
+	return c
+}
+
+// Get returns the body of the response for the given url. A cached body is
+// returned if it is still fresh, or if the server reports that it has not
+// been modified. Responses other than 200 OK are returned as errors.
+func (c *Client) Get(u string) ([]byte, error) {
+	c.mutex.Lock()
+	cached := c.cache[u]
+	retries, backoff := c.retries, c.backoff
+	c.mutex.Unlock()
+	if cached != nil && scheduler.Now().Before(cached.expires) {
+		return cached.body, nil
+	}
+	for attempt := 0; ; attempt++ {
+		body, retry, err := c.fetch(u, cached)
+		if err == nil || !retry || attempt >= retries {
+			return body, err
+		}
+		sleep(backoff << uint(attempt))
+	}
+}
+
+// GetJSON fetches the given url and decodes the JSON response into out.
+func (c *Client) GetJSON(u string, out interface{}) error {
+	body, err := c.Get(u)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// fetch makes a single request for the url, and returns the response body,
+// or an error and whether the request should be retried.
+func (c *Client) fetch(u string, cached *entry) (body []byte, retry bool, err error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+	c.wait(req.URL)
+	c.mutex.Lock()
+	client := c.client
+	c.mutex.Unlock()
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer response.Body.Close()
+	switch {
+	case response.StatusCode == http.StatusNotModified && cached != nil:
+		body = cached.body
+	case response.StatusCode == http.StatusOK:
+		body, err = ioutil.ReadAll(response.Body)
+		if err != nil {
+			return nil, true, err
+		}
+	default:
+		retry = response.StatusCode == http.StatusTooManyRequests ||
+			response.StatusCode >= 500
+		return nil, retry, fmt.Errorf("%s: %s", u, response.Status)
+	}
+	header := response.Header
+	if response.StatusCode == http.StatusNotModified {
+		// Servers may omit the validators from a 304 response.
+		header = header.Clone()
+		if header.Get("ETag") == "" {
+			header.Set("ETag", cached.etag)
+		}
+		if header.Get("Last-Modified") == "" {
+			header.Set("Last-Modified", cached.lastModified)
+		}
+	}
+	c.store(u, body, header)
+	return body, false, nil
+}
+
+// wait blocks until a request can be made to the url's host, respecting the
+// rate limit, and reserves the next slot for the host.
+func (c *Client) wait(u *url.URL) {
+	c.mutex.Lock()
+	if c.interval <= 0 {
+		c.mutex.Unlock()
+		return
+	}
+	now := scheduler.Now()
+	next := c.next[u.Host]
+	if next.Before(now) {
+		next = now
+	}
+	c.next[u.Host] = next.Add(c.interval)
+	c.mutex.Unlock()
+	if delay := next.Sub(now); delay > 0 {
+		sleep(delay)
+	}
+}
+
+// store caches the response body if the headers allow it to be reused,
+// either until it expires or by making a conditional request.
+func (c *Client) store(u string, body []byte, header http.Header) {
+	e := &entry{
+		body:         body,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+	}
+	maxAge, canStore := cacheControl(header.Get("Cache-Control"))
+	if maxAge > 0 {
+		e.expires = scheduler.Now().Add(maxAge)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !canStore || (maxAge <= 0 && e.etag == "" && e.lastModified == "") {
+		delete(c.cache, u)
+		return
+	}
+	c.cache[u] = e
+}
+
+// cacheControl parses a Cache-Control header into the duration for which
+// the response is fresh, and whether it can be stored at all.
+func cacheControl(value string) (maxAge time.Duration, canStore bool) {
+	canStore = true
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return 0, false
+		case directive == "no-cache":
+			// Can be stored, but must always be revalidated.
+			return 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil {
+				maxAge = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return maxAge, canStore
+}
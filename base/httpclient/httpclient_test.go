@@ -0,0 +1,255 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+// server is a test server that records requests, and responds using a
+// handler that can be changed by the test.
+type server struct {
+	*httptest.Server
+	sync.Mutex
+	requests []*http.Request
+	handler  http.HandlerFunc
+}
+
+func newServer() *server {
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		s.requests = append(s.requests, r)
+		h := s.handler
+		s.Unlock()
+		h(w, r)
+	}))
+	return s
+}
+
+func (s *server) respond(h http.HandlerFunc) {
+	s.Lock()
+	defer s.Unlock()
+	s.handler = h
+	s.requests = nil
+}
+
+func (s *server) requestCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.requests)
+}
+
+func (s *server) lastRequest() *http.Request {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+// recordSleeps replaces sleep with a function that records the delays.
+func recordSleeps() *[]time.Duration {
+	var sleeps []time.Duration
+	sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return &sleeps
+}
+
+func TestGet(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	c := New()
+
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"temp": 20}`)
+	})
+	var r struct{ Temp int }
+	assert.NoError(t, c.GetJSON(s.URL, &r))
+	assert.Equal(t, 20, r.Temp)
+
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"temp": 21}`)
+	})
+	assert.NoError(t, c.GetJSON(s.URL, &r))
+	assert.Equal(t, 21, r.Temp, "not cached without cache headers")
+	assert.Empty(t, s.lastRequest().Header.Get("If-None-Match"))
+
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `not json`)
+	})
+	assert.Error(t, c.GetJSON(s.URL, &r), "invalid json")
+
+	s.respond(http.NotFound)
+	_, err := c.Get(s.URL)
+	assert.Error(t, err, "on non-200 status")
+	assert.Equal(t, 1, s.requestCount(), "client errors are not retried")
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	c := New()
+
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "v1")
+	})
+	body, err := c.Get(s.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(body))
+	body, err = c.Get(s.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(body), "cached body on 304")
+	assert.Equal(t, 2, s.requestCount(), "revalidated with the server")
+
+	lastModified := "Wed, 01 Mar 2017 00:00:00 GMT"
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		fmt.Fprint(w, "v2")
+	})
+	body, _ = c.Get(s.URL)
+	assert.Equal(t, "v2", string(body), "new body when modified")
+	assert.Equal(t, `"v1"`, s.lastRequest().Header.Get("If-None-Match"))
+	body, _ = c.Get(s.URL)
+	assert.Equal(t, "v2", string(body), "cached body on 304")
+	assert.Equal(t, lastModified, s.lastRequest().Header.Get("If-Modified-Since"))
+}
+
+func TestCacheControl(t *testing.T) {
+	scheduler.TestMode(true)
+	s := newServer()
+	defer s.Close()
+	c := New()
+
+	version := 0
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		version++
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		fmt.Fprint(w, version)
+	})
+	body, _ := c.Get(s.URL)
+	assert.Equal(t, "1", string(body))
+	scheduler.AdvanceBy(30 * time.Second)
+	body, _ = c.Get(s.URL)
+	assert.Equal(t, "1", string(body), "fresh response is cached")
+	assert.Equal(t, 1, s.requestCount())
+	scheduler.AdvanceBy(31 * time.Second)
+	body, _ = c.Get(s.URL)
+	assert.Equal(t, "2", string(body), "refetched when stale")
+
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		version++
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("ETag", "x")
+		fmt.Fprint(w, version)
+	})
+	scheduler.AdvanceBy(time.Minute)
+	c.Get(s.URL)
+	c.Get(s.URL)
+	assert.Empty(t, s.lastRequest().Header.Get("If-None-Match"), "no-store is not cached")
+
+	maxAge, canStore := cacheControl("no-cache")
+	assert.Equal(t, time.Duration(0), maxAge)
+	assert.True(t, canStore)
+	maxAge, _ = cacheControl("Max-Age=10, must-revalidate")
+	assert.Equal(t, 10*time.Second, maxAge)
+}
+
+func TestRetry(t *testing.T) {
+	sleeps := recordSleeps()
+	defer func() { sleep = time.Sleep }()
+	s := newServer()
+	defer s.Close()
+	c := New()
+
+	attempts := 0
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+	body, err := c.Get(s.URL)
+	assert.NoError(t, err, "succeeds after retries")
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *sleeps,
+		"exponential backoff")
+
+	*sleeps = nil
+	s.respond(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	_, err = c.Retry(1, time.Minute).Get(s.URL)
+	assert.Error(t, err, "fails after retries")
+	assert.Equal(t, 2, s.requestCount())
+	assert.Equal(t, []time.Duration{time.Minute}, *sleeps)
+
+	*sleeps = nil
+	before := s.requestCount()
+	_, err = c.Retry(0, 0).Get(s.URL)
+	assert.Error(t, err)
+	assert.Equal(t, before+1, s.requestCount(), "retries disabled")
+
+	*sleeps = nil
+	s.Close()
+	_, err = c.Retry(1, time.Second).Get(s.URL)
+	assert.Error(t, err, "on network error")
+	assert.Equal(t, 1, len(*sleeps), "network errors are retried")
+}
+
+func TestRateLimit(t *testing.T) {
+	scheduler.TestMode(true)
+	sleeps := recordSleeps()
+	defer func() { sleep = time.Sleep }()
+	s := newServer()
+	defer s.Close()
+	other := newServer()
+	defer other.Close()
+	s.respond(func(w http.ResponseWriter, r *http.Request) {})
+	other.respond(func(w http.ResponseWriter, r *http.Request) {})
+
+	c := New().RateLimit(10 * time.Second)
+	c.Get(s.URL)
+	assert.Empty(t, *sleeps, "first request is not delayed")
+	c.Get(s.URL + "/other/path")
+	assert.Equal(t, []time.Duration{10 * time.Second}, *sleeps, "limited per host")
+	c.Get(other.URL)
+	assert.Equal(t, 1, len(*sleeps), "other hosts are not limited")
+
+	*sleeps = nil
+	scheduler.AdvanceBy(time.Minute)
+	c.Get(s.URL)
+	assert.Empty(t, *sleeps, "not delayed after interval")
+	scheduler.AdvanceBy(4 * time.Second)
+	c.Get(s.URL)
+	assert.Equal(t, []time.Duration{6 * time.Second}, *sleeps, "delayed for the remaining interval")
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package listeners provides a set of functions to call when some shared
+// state changes, e.g. the color scheme or the current location.
+package listeners
+
+import "sync"
+
+// Set holds functions that are notified of changes, keyed by a unique id so
+// that they can be removed. The zero value is an empty set ready to use.
+type Set struct {
+	mutex     sync.Mutex
+	listeners map[int]func()
+	nextID    int
+}
+
+// Add adds a function that will be called on each Notify, and returns a
+// function that removes it.
+func (s *Set) Add(f func()) (remove func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listeners == nil {
+		s.listeners = map[int]func(){}
+	}
+	id := s.nextID
+	s.nextID++
+	s.listeners[id] = f
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+// Notify calls all functions in the set. They are called without holding
+// the lock, so they can add or remove listeners.
+func (s *Set) Notify() {
+	s.mutex.Lock()
+	ls := make([]func(), 0, len(s.listeners))
+	for _, f := range s.listeners {
+		ls = append(ls, f)
+	}
+	s.mutex.Unlock()
+	for _, f := range ls {
+		f()
+	}
+}
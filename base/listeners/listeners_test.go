@@ -0,0 +1,46 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package listeners
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	var s Set
+	s.Notify()
+
+	calls := map[string]int{}
+	removeA := s.Add(func() { calls["a"]++ })
+	s.Add(func() { calls["b"]++ })
+	s.Notify()
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, calls, "all listeners called")
+
+	removeA()
+	s.Notify()
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, calls, "removed listener not called")
+	removeA()
+
+	var removeC func()
+	removeC = s.Add(func() {
+		calls["c"]++
+		removeC()
+	})
+	s.Notify()
+	s.Notify()
+	assert.Equal(t, 1, calls["c"], "listener can remove itself")
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// How long to wait for the user to authorize in the browser.
+var authTimeout = 5 * time.Minute
+
+// openBrowser opens the url in the user's browser, replaced in tests.
+var openBrowser = func(u string) error {
+	return exec.Command("xdg-open", u).Start()
+}
+
+// setupOutput is where the interactive setup writes its prompts.
+var setupOutput io.Writer = os.Stdout
+
+// InteractiveSetup runs the authorization flow for each registered endpoint
+// that does not have a stored token yet, opening a browser for each, and
+// stores the resulting tokens. It is meant to be run from a terminal, e.g.
+// as a setup command of the bar, rather than while the bar is running.
+func InteractiveSetup() error {
+	for _, e := range registered() {
+		if e.Authorized() {
+			fmt.Fprintf(setupOutput, "%s: already authorized\n", e.key)
+			continue
+		}
+		if err := e.Authorize(); err != nil {
+			return fmt.Errorf("%s: %v", e.key, err)
+		}
+		fmt.Fprintf(setupOutput, "%s: authorized\n", e.key)
+	}
+	return nil
+}
+
+// Authorize runs the authorization flow for installed apps for the
+// endpoint, replacing any stored token. The authorization url is printed
+// and opened in a browser, which redirects to a temporary server on the
+// loopback interface once the user has authorized access. PKCE is used, so
+// the authorization code is useless to anyone who intercepts it.
+func (e *Endpoint) Authorize() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	redirectURL := "http://" + listener.Addr().String() + "/"
+
+	state, err := randomString()
+	if err != nil {
+		return err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return err
+	}
+	authURL, err := e.authURL(redirectURL, state, challenge(verifier))
+	if err != nil {
+		return err
+	}
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Invalid state", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			http.Error(w, "Authorization failed: "+q.Get("error"), http.StatusForbidden)
+			sendErr(errs, fmt.Errorf("authorization failed: %s", q.Get("error")))
+			return
+		case q.Get("code") == "":
+			http.Error(w, "Missing authorization code", http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, "Authorized, you can close this window.\n")
+		select {
+		case codes <- q.Get("code"):
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	fmt.Fprintf(setupOutput, "%s: visit the following url to authorize access:\n%s\n", e.key, authURL)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Fprintf(setupOutput, "could not open browser: %v\n", err)
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return err
+	case <-time.After(authTimeout):
+		return errors.New("timed out waiting for authorization")
+	}
+	t, err := e.requestToken(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return err
+	}
+	if t.RefreshToken == "" {
+		return errors.New("no refresh token issued, check the AuthParams for the provider")
+	}
+	return e.save(t)
+}
+
+// authURL returns the url that the user visits to authorize access.
+func (e *Endpoint) authURL(redirectURL, state, challenge string) (string, error) {
+	e.mutex.Lock()
+	config := e.config
+	e.mutex.Unlock()
+	u, err := url.Parse(config.AuthURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, v := range config.AuthParams {
+		q.Set(k, v)
+	}
+	q.Set("response_type", "code")
+	q.Set("client_id", config.ClientID)
+	q.Set("redirect_uri", redirectURL)
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	if len(config.Scopes) > 0 {
+		q.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// randomString returns a random url-safe string, for the state and
+// the PKCE verifier.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// challenge returns the S256 PKCE challenge for the verifier.
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func sendErr(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package oauth provides authenticated http.Clients for modules backed by
+web APIs that use OAuth 2.0, e.g. calendars or mail.
+
+Modules register the OAuth configuration of the API they use, and ask for
+a client when they need to make requests. Tokens are obtained using the
+authorization flow for installed apps: the user authorizes the app in a
+browser, which redirects to a temporary server on the loopback interface.
+Only the resulting tokens are stored, encrypted, using a Store (by default
+the desktop keyring). Access tokens are refreshed automatically.
+
+Typical usage in a module would be:
+
+	var endpoint = oauth.Register("gmail", oauth.Config{
+		ClientID:     "...",
+		ClientSecret: "...",
+		AuthURL:      "https://accounts.google.com/o/oauth2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		Scopes:       []string{"https://www.googleapis.com/auth/gmail.readonly"},
+		AuthParams:   map[string]string{"access_type": "offline"},
+	})
+
+	func (m *module) update() {
+		client, err := endpoint.Client()
+		if m.Error(err) {
+			return
+		}
+		resp, err := client.Get("https://www.googleapis.com/gmail/v1/...")
+		...
+	}
+
+Since authorization requires a browser, it is not done by the bar itself.
+Instead, the bar should provide a setup command that runs the interactive
+first-run setup for all registered endpoints, e.g.
+
+	if len(os.Args) > 1 && os.Args[1] == "setup-oauth" {
+		if err := oauth.InteractiveSetup(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+*/
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/base/httpclient"
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+// ErrNotAuthorized is returned for endpoints that do not have a stored
+// token, or whose authorization was revoked, until the interactive setup
+// is run again.
+var ErrNotAuthorized = errors.New("oauth: not authorized, run the interactive setup")
+
+// Config is the OAuth configuration of a web API.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	Scopes       []string
+	// AuthParams are added to the authorization url, for providers that
+	// require additional parameters, e.g. to issue refresh tokens.
+	AuthParams map[string]string
+}
+
+// token is the stored token for an endpoint.
+type token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// Time before expiry to refresh access tokens, so that tokens do not
+// expire while a request is in flight.
+const expiryDelta = time.Minute
+
+func (t *token) valid() bool {
+	return t != nil && t.AccessToken != "" &&
+		(t.Expiry.IsZero() || scheduler.Now().Add(expiryDelta).Before(t.Expiry))
+}
+
+// Endpoint is a registered OAuth configuration, which hands out clients
+// authenticated with its stored token.
+type Endpoint struct {
+	key    string
+	config Config
+
+	mutex sync.Mutex
+	token *token
+	// Held while refreshing, so that concurrent requests share a refresh.
+	refreshMutex sync.Mutex
+}
+
+var (
+	endpointsMutex sync.Mutex
+	endpoints      = map[string]*Endpoint{}
+)
+
+// Register registers the configuration of a web API under a key, which is
+// used to store its token, and returns the endpoint for it. Registering the
+// same key again replaces the configuration, but keeps the endpoint.
+func Register(key string, config Config) *Endpoint {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	e, ok := endpoints[key]
+	if !ok {
+		e = &Endpoint{key: key}
+		endpoints[key] = e
+	}
+	e.mutex.Lock()
+	e.config = config
+	e.mutex.Unlock()
+	return e
+}
+
+// registered returns all registered endpoints, sorted by key.
+func registered() []*Endpoint {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	var keys []string
+	for key := range endpoints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var out []*Endpoint
+	for _, key := range keys {
+		out = append(out, endpoints[key])
+	}
+	return out
+}
+
+// Key returns the key the endpoint was registered under.
+func (e *Endpoint) Key() string {
+	return e.key
+}
+
+// Authorized returns true if a token is stored for the endpoint.
+func (e *Endpoint) Authorized() bool {
+	t, err := e.load()
+	return err == nil && t != nil
+}
+
+// Client returns an http.Client that authenticates each request with the
+// endpoint's access token, refreshing it as needed. It returns
+// ErrNotAuthorized if no token is stored yet.
+func (e *Endpoint) Client() (*http.Client, error) {
+	t, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrNotAuthorized
+	}
+	return &http.Client{
+		Transport: &transport{e},
+		Timeout:   10 * time.Second,
+	}, nil
+}
+
+// load returns the token for the endpoint, from memory if possible, or from
+// the store otherwise. It returns nil if no token is stored.
+func (e *Endpoint) load() (*token, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.token != nil {
+		return e.token, nil
+	}
+	data, err := currentStore().Load(e.key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	t := new(token)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("oauth: invalid token for %s: %v", e.key, err)
+	}
+	e.token = t
+	return t, nil
+}
+
+// save stores the token for the endpoint.
+func (e *Endpoint) save(t *token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := currentStore().Save(e.key, data); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	e.token = t
+	e.mutex.Unlock()
+	return nil
+}
+
+// forget removes the token for the endpoint, from memory and the store.
+func (e *Endpoint) forget() error {
+	e.mutex.Lock()
+	e.token = nil
+	e.mutex.Unlock()
+	return currentStore().Delete(e.key)
+}
+
+// accessToken returns a valid access token, refreshing it if needed.
+func (e *Endpoint) accessToken() (*token, error) {
+	e.refreshMutex.Lock()
+	defer e.refreshMutex.Unlock()
+	t, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrNotAuthorized
+	}
+	if t.valid() {
+		return t, nil
+	}
+	if t.RefreshToken == "" {
+		return nil, ErrNotAuthorized
+	}
+	refreshed, err := e.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {t.RefreshToken},
+	})
+	if err == ErrNotAuthorized {
+		// Forget the revoked token, so that the interactive setup
+		// authorizes the endpoint again.
+		if err := e.forget(); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if refreshed.RefreshToken == "" {
+		// Most providers only issue refresh tokens on authorization.
+		refreshed.RefreshToken = t.RefreshToken
+	}
+	return refreshed, e.save(refreshed)
+}
+
+// tokenResponse is the response from the token url, see RFC 6749.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// requestToken makes a request to the token url with the given parameters,
+// in addition to the client credentials.
+func (e *Endpoint) requestToken(params url.Values) (*token, error) {
+	e.mutex.Lock()
+	config := e.config
+	e.mutex.Unlock()
+	params.Set("client_id", config.ClientID)
+	if config.ClientSecret != "" {
+		params.Set("client_secret", config.ClientSecret)
+	}
+	client := &http.Client{Transport: httpclient.Transport(), Timeout: 10 * time.Second}
+	resp, err := client.PostForm(config.TokenURL, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var r tokenResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("oauth: %s: %s", config.TokenURL, resp.Status)
+	}
+	switch {
+	case r.Error == "invalid_grant":
+		// The refresh token was revoked or has expired.
+		return nil, ErrNotAuthorized
+	case r.Error != "":
+		return nil, fmt.Errorf("oauth: %s: %s", r.Error, r.Description)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("oauth: %s: %s", config.TokenURL, resp.Status)
+	case r.AccessToken == "":
+		return nil, errors.New("oauth: no access token in response")
+	}
+	t := &token{
+		AccessToken:  r.AccessToken,
+		RefreshToken: r.RefreshToken,
+		TokenType:    r.TokenType,
+	}
+	if r.ExpiresIn > 0 {
+		t.Expiry = scheduler.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// transport adds the access token to each request.
+type transport struct {
+	endpoint *Endpoint
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	tok, err := t.endpoint.accessToken()
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	tokenType := tok.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	// RoundTrippers must not modify the request.
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", tokenType+" "+tok.AccessToken)
+	return httpclient.Transport().RoundTrip(r)
+}
@@ -0,0 +1,284 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+	testHttp "github.com/soumya92/barista/testing/httpclient"
+)
+
+// memoryStore is an in-memory store for tests.
+type memoryStore struct {
+	sync.Mutex
+	values map[string][]byte
+}
+
+func (m *memoryStore) Load(key string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.values[key], nil
+}
+
+func (m *memoryStore) Save(key string, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryStore) Delete(key string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryStore) token(t *testing.T, key string) token {
+	m.Lock()
+	defer m.Unlock()
+	var tok token
+	assert.Nil(t, json.Unmarshal(m.values[key], &tok), "stored token")
+	return tok
+}
+
+var now = time.Date(2018, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func setup(t *testing.T) (*memoryStore, *testHttp.Server) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(now)
+	s := &memoryStore{values: map[string][]byte{}}
+	SetStore(s)
+	setupOutput = ioutil.Discard
+	authTimeout = time.Second
+	endpointsMutex.Lock()
+	endpoints = map[string]*Endpoint{}
+	endpointsMutex.Unlock()
+	return s, testHttp.Stub(t)
+}
+
+var testConfig = Config{
+	ClientID:     "client-id",
+	ClientSecret: "client-secret",
+	AuthURL:      "https://auth.example.com/authorize?prompt=consent",
+	TokenURL:     "https://auth.example.com/token",
+	Scopes:       []string{"read", "write"},
+	AuthParams:   map[string]string{"access_type": "offline"},
+}
+
+// browser simulates a user authorizing access in a browser, by visiting the
+// redirect url with the given query parameters, and returns the query
+// received in the authorization url.
+func browser(t *testing.T, params url.Values) <-chan url.Values {
+	queries := make(chan url.Values, 10)
+	openBrowser = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		assert.Nil(t, err)
+		assert.Equal(t, "auth.example.com", u.Host)
+		q := u.Query()
+		queries <- q
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		response := url.Values{}
+		for k, v := range params {
+			response[k] = v
+		}
+		if response.Get("state") == "" {
+			response.Set("state", q.Get("state"))
+		}
+		redirect.RawQuery = response.Encode()
+		go func() {
+			resp, err := http.Get(redirect.String())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+	return queries
+}
+
+func TestAuthorize(t *testing.T) {
+	store, stub := setup(t)
+	defer stub.Close()
+	stub.Handle(testConfig.TokenURL).JSON(map[string]interface{}{
+		"access_token":  "access",
+		"refresh_token": "refresh",
+		"token_type":    "bearer",
+		"expires_in":    3600,
+	})
+	stub.Handle("https://api.example.com/").Body("data")
+
+	e := Register("test", testConfig)
+	_, err := e.Client()
+	assert.Equal(t, ErrNotAuthorized, err, "before authorization")
+	assert.False(t, e.Authorized())
+
+	queries := browser(t, url.Values{"code": {"the-code"}})
+	assert.Nil(t, e.Authorize())
+	assert.True(t, e.Authorized())
+
+	q := <-queries
+	assert.Equal(t, "client-id", q.Get("client_id"))
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, "read write", q.Get("scope"))
+	assert.Equal(t, "offline", q.Get("access_type"), "auth params")
+	assert.Equal(t, "consent", q.Get("prompt"), "existing query")
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	requests := stub.Requests(testConfig.TokenURL)
+	assert.Equal(t, 1, len(requests))
+	r := requests[0]
+	assert.Nil(t, r.ParseForm())
+	assert.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+	assert.Equal(t, "the-code", r.PostForm.Get("code"))
+	assert.Equal(t, q.Get("redirect_uri"), r.PostForm.Get("redirect_uri"))
+	assert.Equal(t, "client-secret", r.PostForm.Get("client_secret"))
+	assert.Equal(t, q.Get("code_challenge"), challenge(r.PostForm.Get("code_verifier")),
+		"verifier matches challenge")
+
+	tok := store.token(t, "test")
+	assert.Equal(t, "refresh", tok.RefreshToken)
+	assert.Equal(t, now.Add(time.Hour), tok.Expiry)
+
+	client, err := e.Client()
+	assert.Nil(t, err)
+	resp, err := client.Get("https://api.example.com/foo")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "data", string(body))
+	api := stub.Requests("https://api.example.com/")
+	assert.Equal(t, 1, len(api))
+	assert.Equal(t, "Bearer access", api[0].Header.Get("Authorization"))
+}
+
+func TestAuthorizeErrors(t *testing.T) {
+	_, stub := setup(t)
+	defer stub.Close()
+	e := Register("errors", testConfig)
+
+	browser(t, url.Values{"error": {"access_denied"}})
+	assert.Error(t, e.Authorize(), "user denied access")
+
+	browser(t, url.Values{"code": {"code"}, "state": {"wrong"}})
+	assert.Error(t, e.Authorize(), "state mismatch times out")
+
+	stub.Handle(testConfig.TokenURL).Status(400).JSON(map[string]string{
+		"error": "invalid_request", "error_description": "bad code",
+	}).Times(1)
+	browser(t, url.Values{"code": {"code"}})
+	err := e.Authorize()
+	assert.Contains(t, err.Error(), "bad code")
+
+	stub.Handle(testConfig.TokenURL).JSON(map[string]string{"access_token": "a"})
+	err = e.Authorize()
+	assert.Contains(t, err.Error(), "no refresh token")
+	assert.False(t, e.Authorized())
+}
+
+func TestRefresh(t *testing.T) {
+	store, stub := setup(t)
+	defer stub.Close()
+	stored, _ := json.Marshal(token{
+		AccessToken:  "old",
+		RefreshToken: "refresh",
+		Expiry:       now.Add(time.Hour),
+	})
+	store.Save("refresh", stored)
+	stub.Handle("https://api.example.com/").Body("data")
+	stub.Handle(testConfig.TokenURL).JSON(map[string]interface{}{
+		"access_token": "new",
+		"expires_in":   3600,
+	}).Times(1)
+
+	e := Register("refresh", testConfig)
+	client, err := e.Client()
+	assert.Nil(t, err)
+	get := func() string {
+		resp, err := client.Get("https://api.example.com/")
+		if !assert.Nil(t, err) {
+			return ""
+		}
+		resp.Body.Close()
+		api := stub.Requests("https://api.example.com/")
+		return api[len(api)-1].Header.Get("Authorization")
+	}
+
+	assert.Equal(t, "Bearer old", get(), "stored token")
+	assert.Empty(t, stub.Requests(testConfig.TokenURL), "not refreshed while valid")
+
+	scheduler.AdvanceBy(59*time.Minute + 30*time.Second)
+	assert.Equal(t, "Bearer new", get(), "refreshed near expiry")
+	requests := stub.Requests(testConfig.TokenURL)
+	assert.Equal(t, 1, len(requests))
+	requests[0].ParseForm()
+	assert.Equal(t, "refresh_token", requests[0].PostForm.Get("grant_type"))
+	assert.Equal(t, "refresh", requests[0].PostForm.Get("refresh_token"))
+
+	tok := store.token(t, "refresh")
+	assert.Equal(t, "new", tok.AccessToken)
+	assert.Equal(t, "refresh", tok.RefreshToken, "refresh token is kept")
+
+	assert.Equal(t, "Bearer new", get(), "refreshed token is reused")
+	assert.Equal(t, 1, len(stub.Requests(testConfig.TokenURL)))
+
+	scheduler.AdvanceBy(2 * time.Hour)
+	stub.Handle(testConfig.TokenURL).Status(400).JSON(map[string]string{"error": "invalid_grant"})
+	_, err = client.Get("https://api.example.com/")
+	assert.True(t, errors.Is(err, ErrNotAuthorized), "revoked refresh token")
+	assert.False(t, e.Authorized(), "revoked token is forgotten")
+	value, _ := store.Load("refresh")
+	assert.Nil(t, value, "revoked token is deleted from the store")
+
+	stub.Handle(testConfig.TokenURL).JSON(map[string]interface{}{
+		"access_token": "again", "refresh_token": "refresh2", "expires_in": 3600,
+	})
+	browser(t, url.Values{"code": {"code"}})
+	assert.Nil(t, InteractiveSetup())
+	assert.True(t, e.Authorized(), "authorized again by the interactive setup")
+	assert.Equal(t, "Bearer again", get(), "new token is used")
+}
+
+func TestInteractiveSetup(t *testing.T) {
+	store, stub := setup(t)
+	defer stub.Close()
+	stub.Handle(testConfig.TokenURL).JSON(map[string]interface{}{
+		"access_token": "access", "refresh_token": "refresh",
+	})
+	stored, _ := json.Marshal(token{AccessToken: "a", RefreshToken: "r"})
+	store.Save("done", stored)
+	Register("done", testConfig)
+	Register("pending", testConfig)
+
+	queries := browser(t, url.Values{"code": {"code"}})
+	assert.Nil(t, InteractiveSetup())
+	assert.Equal(t, 1, len(queries), "only unauthorized endpoints")
+	assert.Equal(t, "refresh", store.token(t, "pending").RefreshToken)
+	assert.Equal(t, "r", store.token(t, "done").RefreshToken)
+
+	stub.Handle(testConfig.TokenURL).Status(500)
+	Register("failing", testConfig)
+	assert.Error(t, InteractiveSetup())
+}
@@ -0,0 +1,232 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store stores tokens, which must be kept secret, since the refresh
+// tokens allow access to the user's data until they are revoked.
+type Store interface {
+	// Load returns the value stored under the key, or nil if there is none.
+	Load(key string) ([]byte, error)
+	// Save stores the value under the key, replacing any previous value.
+	Save(key string, value []byte) error
+	// Delete removes the value stored under the key, if there is one.
+	Delete(key string) error
+}
+
+var (
+	storeMutex sync.RWMutex
+	store      Store = Keyring()
+)
+
+// SetStore sets the store used for the tokens of all endpoints. It should
+// be called before any clients are requested, e.g. in main, since tokens
+// that were already loaded are not reloaded from the new store.
+func SetStore(s Store) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	store = s
+}
+
+func currentStore() Store {
+	storeMutex.RLock()
+	defer storeMutex.RUnlock()
+	return store
+}
+
+// secretTool runs secret-tool (from libsecret) with the given input,
+// replaced in tests.
+var secretTool = func(input string, args ...string) (string, error) {
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) == 0 && len(out) == 0 {
+		// secret-tool exits with an error without any output if the secret
+		// does not exist.
+		return "", nil
+	}
+	return string(out), err
+}
+
+// keyring stores tokens in the desktop keyring using libsecret.
+type keyring struct{}
+
+// Keyring returns a store that keeps tokens in the desktop keyring (e.g.
+// gnome-keyring or KWallet), using secret-tool from libsecret. This is the
+// default store.
+func Keyring() Store {
+	return keyring{}
+}
+
+func (keyring) Load(key string) ([]byte, error) {
+	out, err := secretTool("", "lookup", "application", "barista", "oauth", key)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (keyring) Save(key string, value []byte) error {
+	_, err := secretTool(string(value),
+		"store", "--label", "barista oauth: "+key,
+		"application", "barista", "oauth", key)
+	return err
+}
+
+func (keyring) Delete(key string) error {
+	_, err := secretTool("", "clear", "application", "barista", "oauth", key)
+	return err
+}
+
+// encryptedFile stores tokens in a file, encrypted with a master key.
+type encryptedFile struct {
+	path string
+	key  [sha256.Size]byte
+	// Guards the file, since saving rewrites all of the tokens.
+	mutex sync.Mutex
+}
+
+// EncryptedFile returns a store that keeps tokens in a single file,
+// encrypted using AES-GCM with the given master key, for systems without
+// a keyring. The master key is not stretched, so it should be random
+// rather than a passphrase, e.g. read from a file generated using
+// `head -c 32 /dev/urandom`, and kept out of any synced dotfiles.
+func EncryptedFile(path string, masterKey []byte) Store {
+	return &encryptedFile{path: path, key: sha256.Sum256(masterKey)}
+}
+
+func (f *encryptedFile) Load(key string) ([]byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	values, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return values[key], nil
+}
+
+func (f *encryptedFile) Save(key string, value []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	values, err := f.read()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return f.write(values)
+}
+
+func (f *encryptedFile) Delete(key string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	values, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return f.write(values)
+}
+
+func (f *encryptedFile) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(f.key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// read decrypts all the values in the file. A missing file has no values.
+func (f *encryptedFile) read() (map[string][]byte, error) {
+	values := map[string][]byte{}
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := f.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("oauth: token file is corrupt")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("oauth: could not decrypt token file, wrong master key?")
+	}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// write encrypts the values, and atomically replaces the file, which is
+// only readable by the user.
+func (f *encryptedFile) write(values map[string][]byte) error {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	gcm, err := f.gcm()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	var data bytes.Buffer
+	data.Write(nonce)
+	data.Write(gcm.Seal(nil, nonce, plaintext, nil))
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
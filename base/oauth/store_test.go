@@ -0,0 +1,114 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestEncryptedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oauth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config", "tokens")
+
+	s := EncryptedFile(path, []byte("master key"))
+	value, err := s.Load("foo")
+	assert.Nil(t, err, "missing file")
+	assert.Nil(t, value)
+
+	assert.Nil(t, s.Save("foo", []byte("secret-foo")))
+	assert.Nil(t, s.Save("bar", []byte("secret-bar")))
+	value, _ = s.Load("foo")
+	assert.Equal(t, "secret-foo", string(value))
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "only readable by user")
+	data, _ := ioutil.ReadFile(path)
+	assert.NotContains(t, string(data), "secret", "encrypted")
+
+	value, err = EncryptedFile(path, []byte("master key")).Load("bar")
+	assert.Nil(t, err)
+	assert.Equal(t, "secret-bar", string(value), "read with new store")
+
+	_, err = EncryptedFile(path, []byte("wrong key")).Load("bar")
+	assert.Error(t, err, "wrong master key")
+	assert.Error(t, EncryptedFile(path, []byte("wrong key")).Save("baz", nil),
+		"does not overwrite with wrong master key")
+
+	assert.Nil(t, s.Delete("foo"))
+	assert.Nil(t, s.Delete("missing"))
+	value, err = s.Load("foo")
+	assert.Nil(t, err)
+	assert.Nil(t, value, "deleted")
+	value, _ = s.Load("bar")
+	assert.Equal(t, "secret-bar", string(value), "other values kept")
+
+	ioutil.WriteFile(path, []byte("x"), 0600)
+	_, err = s.Load("foo")
+	assert.Error(t, err, "corrupt file")
+}
+
+func TestKeyring(t *testing.T) {
+	secrets := map[string]string{}
+	var inputs []string
+	secretTool = func(input string, args ...string) (string, error) {
+		inputs = append(inputs, input)
+		key := args[len(args)-1]
+		switch args[0] {
+		case "lookup":
+			if key == "broken" {
+				return "", errors.New("no keyring")
+			}
+			return secrets[key], nil
+		case "store":
+			assert.Equal(t, "--label", args[1])
+			assert.True(t, strings.Contains(args[2], key), "label includes key")
+			secrets[key] = input
+			return "", nil
+		case "clear":
+			delete(secrets, key)
+			return "", nil
+		}
+		return "", errors.New("unexpected command")
+	}
+
+	k := Keyring()
+	value, err := k.Load("foo")
+	assert.Nil(t, err)
+	assert.Nil(t, value, "missing secret")
+
+	assert.Nil(t, k.Save("foo", []byte("secret")))
+	assert.Equal(t, "secret", inputs[len(inputs)-1], "secret is passed on stdin")
+	value, err = k.Load("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", string(value))
+
+	assert.Nil(t, k.Delete("foo"))
+	value, err = k.Load("foo")
+	assert.Nil(t, err)
+	assert.Nil(t, value, "deleted secret")
+
+	_, err = k.Load("broken")
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff is a scheduler that backs off exponentially while failures are
+// reported, so that a flaky API or a missing network connection does not
+// cause an update (and an error) at every interval.
+//
+// Only repeating schedules (Every and EveryAligned) back off. After each
+// failure, the delay until the next trigger doubles, up to the maximum
+// delay, and the first success restores the original schedule.
+type Backoff interface {
+	Scheduler
+
+	// MaxDelay sets the maximum delay between triggers while backing off.
+	// Backoff is disabled if the maximum is not longer than the interval.
+	MaxDelay(time.Duration) Backoff
+
+	// Failure reports a failed update, and delays the next trigger.
+	Failure()
+
+	// Success reports a successful update, and restores the original
+	// schedule if the scheduler was backing off.
+	Success()
+}
+
+type backoff struct {
+	Scheduler
+	mutex    sync.Mutex
+	max      time.Duration
+	interval time.Duration
+	aligned  bool
+	// delay is the current delay between triggers, or zero if the
+	// scheduler is not backing off.
+	delay time.Duration
+}
+
+// DoWithBackoff creates a backoff scheduler that calls the given function
+// when triggered. Backoff is disabled until a maximum delay is set.
+func DoWithBackoff(f func()) Backoff {
+	b := &backoff{}
+	b.Scheduler = Do(func() {
+		b.trigger()
+		f()
+	})
+	return b
+}
+
+func (b *backoff) At(when time.Time) Scheduler {
+	b.reset(0, false)
+	b.Scheduler.At(when)
+	return b
+}
+
+func (b *backoff) After(delay time.Duration) Scheduler {
+	b.reset(0, false)
+	b.Scheduler.After(delay)
+	return b
+}
+
+func (b *backoff) Every(interval time.Duration) Scheduler {
+	b.reset(interval, false)
+	b.Scheduler.Every(interval)
+	return b
+}
+
+func (b *backoff) EveryAligned(interval time.Duration) Scheduler {
+	b.reset(interval, true)
+	b.Scheduler.EveryAligned(interval)
+	return b
+}
+
+func (b *backoff) Stop() {
+	b.reset(0, false)
+	b.Scheduler.Stop()
+}
+
+func (b *backoff) MaxDelay(max time.Duration) Backoff {
+	b.mutex.Lock()
+	b.max = max
+	b.mutex.Unlock()
+	return b
+}
+
+func (b *backoff) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.interval == 0 || b.max <= b.interval {
+		return
+	}
+	if b.delay == 0 {
+		b.delay = b.interval
+	}
+	b.delay *= 2
+	if b.delay > b.max {
+		b.delay = b.max
+	}
+	b.Scheduler.After(b.delay)
+}
+
+func (b *backoff) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.delay == 0 {
+		return
+	}
+	b.delay = 0
+	if b.aligned {
+		b.Scheduler.EveryAligned(b.interval)
+	} else {
+		b.Scheduler.Every(b.interval)
+	}
+}
+
+// reset records the schedule to restore after backing off, and stops
+// any backoff in progress.
+func (b *backoff) reset(interval time.Duration, aligned bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.interval = interval
+	b.aligned = aligned
+	b.delay = 0
+}
+
+// trigger schedules the next trigger while backing off, so that updates
+// continue at the current delay even if they report neither success nor
+// failure.
+func (b *backoff) trigger() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.delay > 0 {
+		b.Scheduler.After(b.delay)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	TestMode(true)
+	defer TestMode(false)
+	AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+
+	d := newDoFunc(t)
+	b := DoWithBackoff(d.Func).MaxDelay(5 * time.Minute)
+	b.Every(time.Minute)
+	AdvanceBy(time.Minute)
+	d.assertCalled("at interval")
+
+	b.Failure()
+	b.Success()
+	AdvanceBy(time.Minute)
+	d.assertCalled("success restores interval")
+
+	for _, delay := range []time.Duration{2, 4, 5, 5} {
+		b.Failure()
+		AdvanceBy((delay - 1) * time.Minute)
+		d.assertNotCalled("while backing off")
+		AdvanceBy(time.Minute)
+		d.assertCalled("after backoff delay")
+	}
+
+	AdvanceBy(5 * time.Minute)
+	d.assertCalled("without reported results, the delay is kept")
+
+	b.Success()
+	AdvanceBy(time.Minute)
+	d.assertCalled("after success")
+	AdvanceBy(time.Minute)
+	d.assertCalled("after success")
+
+	b.EveryAligned(time.Minute)
+	b.Failure()
+	AdvanceBy(time.Minute)
+	d.assertNotCalled("aligned schedule backs off")
+	AdvanceBy(time.Minute)
+	d.assertCalled("aligned schedule backs off")
+	b.Success()
+	AdvanceBy(time.Minute)
+	d.assertCalled("aligned schedule restored")
+
+	b.After(time.Minute)
+	b.Failure()
+	AdvanceBy(time.Minute)
+	d.assertCalled("one-off triggers ignore failures")
+
+	b.Every(time.Minute).Stop()
+	b.Failure()
+	AdvanceBy(time.Hour)
+	d.assertNotCalled("stopped scheduler ignores failures")
+
+	b.MaxDelay(0).Every(time.Minute)
+	b.Failure()
+	AdvanceBy(time.Minute)
+	d.assertCalled("without a maximum delay")
+	b.Stop()
+}
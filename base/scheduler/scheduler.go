@@ -16,7 +16,10 @@
 Package scheduler provides a testable interface for scheduling tasks.
 
 This makes it simple to update a module at a fixed interval or
-at a fixed point in time.
+at a fixed point in time. Schedules include time spent while the system
+is suspended, so anything that fell due during a suspend is triggered
+immediately on resume. All schedulers can also be paused together,
+e.g. while the bar is hidden, using Pause and Resume.
 
 Typical usage would be:
     scheduler.Do(module.Update).At(someTime)
@@ -29,6 +32,8 @@ or keeping track of the scheduler to update it later:
 package scheduler
 
 import (
+	"reflect"
+	"runtime"
 	"sort"
 	"sync"
 	"time"
@@ -49,26 +54,48 @@ type Scheduler interface {
 	// This will replace any pending triggers.
 	Every(time.Duration) Scheduler
 
+	// EveryAligned sets the scheduler to trigger at an interval, aligned
+	// to multiples of the interval, e.g. at the start of every minute for
+	// time.Minute. This keeps clocks from showing a stale minute, and
+	// allows modules with the same interval to update together. Intervals
+	// are aligned to the zero time in UTC, so for intervals of an hour or
+	// more, ticks are only aligned to local boundaries in timezones with
+	// a whole-hour offset.
+	// This will replace any pending triggers.
+	EveryAligned(time.Duration) Scheduler
+
 	// Stop cancels all further triggers for the scheduler.
 	Stop()
+
+	// NextTrigger returns the time of the next trigger, or the zero time
+	// if the scheduler is not scheduled to trigger. A time in the past
+	// means the trigger is overdue, e.g. while schedulers are paused.
+	NextTrigger() time.Time
+
+	// LastTrigger returns the time the scheduler last triggered, or the
+	// zero time if it has never triggered. Along with NextTrigger, this
+	// can be used to show when modules will next refresh, and to detect
+	// stalled schedules.
+	LastTrigger() time.Time
 }
 
-// scheduler holds either a timer or a ticker that triggers
-// the given function.
+// scheduler holds a timer that triggers the given function.
 type scheduler struct {
-	timer  *time.Timer
-	ticker *time.Ticker
-	do     func()
-	mutex  sync.Mutex
-	// For test mode, keep track of the next triggers.
+	timer timer
+	do    func()
+	mutex sync.Mutex
+	// Keep track of the next triggers, for test mode and introspection.
 	nextTrigger time.Time
 	interval    time.Duration
+	lastTrigger time.Time
+	// The interval given to Every, before scaling (see SetScale).
+	requested time.Duration
 }
 
 // Do creates a scheduler that calls the given function when triggered.
 func Do(f func()) Scheduler {
 	s := &scheduler{do: f}
-	if testMode {
+	if inTestMode() {
 		testSchedulers = append(testSchedulers, s)
 	}
 	return s
@@ -80,62 +107,282 @@ func (s *scheduler) At(when time.Time) Scheduler {
 
 func (s *scheduler) After(delay time.Duration) Scheduler {
 	s.Stop()
+	setActive(s, true)
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if testMode {
-		s.nextTrigger = Now().Add(delay)
+	s.nextTrigger = Now().Add(delay)
+	if inTestMode() {
 		return s
 	}
-	s.timer = time.AfterFunc(delay, s.do)
+	s.timer = newTimer(delay, 0, s.trigger)
 	return s
 }
 
 func (s *scheduler) Every(interval time.Duration) Scheduler {
 	s.Stop()
+	setActive(s, true)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextTrigger = Now()
+	s.requested = interval
+	interval = scaled(interval)
+	s.interval = interval
+	if inTestMode() {
+		return s
+	}
+	s.timer = newTimer(interval, interval, s.trigger)
+	return s
+}
+
+func (s *scheduler) EveryAligned(interval time.Duration) Scheduler {
+	s.Stop()
+	setActive(s, true)
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if testMode {
-		s.nextTrigger = Now()
-		s.interval = interval
+	s.nextTrigger = Now().Truncate(interval)
+	s.interval = interval
+	if inTestMode() {
 		return s
 	}
-	s.ticker = time.NewTicker(interval)
-	go func() {
+	// Repeating timers drift away from the boundaries over time, so use a timer
+	// for each tick instead.
+	var tick func()
+	schedule := func() {
+		now := time.Now()
+		s.timer = newTimer(now.Truncate(interval).Add(interval).Sub(now), 0, tick)
+	}
+	tick = func() {
 		s.mutex.Lock()
-		ticker := s.ticker
+		timer := s.timer
 		s.mutex.Unlock()
-		if ticker == nil {
-			// Scheduler stopped before goroutine was started.
+		if timer == nil {
+			// Scheduler stopped or replaced since this tick was scheduled.
 			return
 		}
-		for range ticker.C {
-			s.do()
+		s.trigger()
+		s.mutex.Lock()
+		if s.timer == timer {
+			schedule()
 		}
-	}()
+		s.mutex.Unlock()
+	}
+	schedule()
 	return s
 }
 
 func (s *scheduler) Stop() {
+	pauseMutex.Lock()
+	delete(waiting, s)
+	pauseMutex.Unlock()
+	setActive(s, false)
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if testMode {
-		s.nextTrigger = time.Time{}
-		s.interval = time.Duration(0)
+	s.nextTrigger = time.Time{}
+	s.interval = time.Duration(0)
+	s.requested = time.Duration(0)
+	if inTestMode() {
 		return
 	}
 	if s.timer != nil {
 		s.timer.Stop()
 		s.timer = nil
 	}
-	if s.ticker != nil {
-		s.ticker.Stop()
-		s.ticker = nil
+}
+
+// trigger calls the scheduled function, unless schedulers are paused,
+// in which case the call is deferred until they are resumed.
+func (s *scheduler) trigger() {
+	pauseMutex.Lock()
+	if paused {
+		waiting[s] = true
+		pauseMutex.Unlock()
+		return
+	}
+	pauseMutex.Unlock()
+	s.run()
+}
+
+// run records the trigger and calls the scheduled function.
+func (s *scheduler) run() {
+	activeMutex.Lock()
+	s.mutex.Lock()
+	now := Now()
+	s.lastTrigger = now
+	if s.interval == 0 && !s.nextTrigger.After(now) {
+		// One-off trigger, unless it was rescheduled in the meantime.
+		s.nextTrigger = time.Time{}
+		delete(active, s)
+	}
+	s.mutex.Unlock()
+	activeMutex.Unlock()
+	s.do()
+}
+
+func (s *scheduler) NextTrigger() time.Time {
+	return s.tickAfter(Now())
+}
+
+func (s *scheduler) LastTrigger() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastTrigger
+}
+
+// paused tracks whether all schedulers are paused, and waiting tracks
+// the schedulers that were triggered while paused.
+var paused = false
+var waiting = map[*scheduler]bool{}
+var pauseMutex sync.Mutex
+
+// Pause suspends all schedulers, e.g. when the bar is hidden. Schedulers
+// that are triggered while paused are deferred until Resume is called,
+// and are only triggered once, however many times they were due.
+func Pause() {
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+	paused = true
+}
+
+// Resume continues all schedulers, and triggers all the schedulers that
+// were due while paused together.
+func Resume() {
+	pauseMutex.Lock()
+	paused = false
+	due := waiting
+	waiting = map[*scheduler]bool{}
+	pauseMutex.Unlock()
+	for s := range due {
+		go s.run()
 	}
 }
 
+// scale multiplies the intervals of schedulers set using Every.
+var scale = 1.0
+var scaleMutex sync.Mutex
+
+// SetScale multiplies the intervals of all schedulers set using Every by
+// the given factor, e.g. 3 to refresh modules a third as often while on
+// battery, or 1 to restore the normal intervals. Repeating schedulers are
+// rescaled in place, so the time remaining until their next trigger is
+// scaled too, rather than restarting the interval. Schedulers set using
+// EveryAligned are not scaled, so that clocks stay accurate.
+func SetScale(factor float64) {
+	if factor <= 0 {
+		factor = 1
+	}
+	scaleMutex.Lock()
+	scale = factor
+	scaleMutex.Unlock()
+	// Rescale while holding the locks, so that schedulers that are stopped
+	// or rescheduled concurrently are not overwritten.
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	for s := range active {
+		s.mutex.Lock()
+		if s.requested > 0 && s.interval > 0 {
+			s.rescale(time.Duration(float64(s.requested) * factor))
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// rescale changes the interval of a repeating scheduler, keeping the same
+// fraction of the interval remaining until the next trigger. The scheduler's
+// mutex must be held.
+func (s *scheduler) rescale(interval time.Duration) {
+	if interval == s.interval {
+		return
+	}
+	now := Now()
+	elapsedIntervals := now.Sub(s.nextTrigger) / s.interval
+	next := s.nextTrigger.Add(s.interval * (elapsedIntervals + 1))
+	remaining := time.Duration(float64(next.Sub(now)) * float64(interval) / float64(s.interval))
+	// nextTrigger is the start of the current interval, see tickAfter.
+	s.nextTrigger = now.Add(remaining).Add(-interval)
+	s.interval = interval
+	if inTestMode() {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = newTimer(remaining, interval, s.trigger)
+}
+
+// scaled returns the interval multiplied by the current scale.
+func scaled(interval time.Duration) time.Duration {
+	scaleMutex.Lock()
+	defer scaleMutex.Unlock()
+	return time.Duration(float64(interval) * scale)
+}
+
+// active tracks the schedulers that are scheduled to trigger, so that they
+// can be reported by CurrentState. activeMutex must be acquired before the
+// mutex of any scheduler, if both are needed.
+var active = map[*scheduler]bool{}
+var activeMutex sync.Mutex
+
+func setActive(s *scheduler, isActive bool) {
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	if isActive {
+		active[s] = true
+	} else {
+		delete(active, s)
+	}
+}
+
+// Info describes a scheduler that is scheduled to trigger.
+type Info struct {
+	// The name of the function called by the scheduler, e.g.
+	// "github.com/soumya92/barista/base.(*Base).Update-fm".
+	Func string
+	Next time.Time
+	Last time.Time
+	// The interval for repeating schedulers, or zero for one-off triggers.
+	Interval time.Duration
+}
+
+// State describes all schedulers, e.g. to diagnose modules that have
+// stopped updating.
+type State struct {
+	Paused bool
+	// The number of schedulers that were due while paused, which will be
+	// triggered on resume.
+	Waiting    int
+	Schedulers []Info
+}
+
+// CurrentState returns the current state of all schedulers, with the
+// schedulers that are scheduled to trigger sorted by next trigger time.
+func CurrentState() State {
+	pauseMutex.Lock()
+	state := State{Paused: paused, Waiting: len(waiting)}
+	pauseMutex.Unlock()
+	now := Now()
+	activeMutex.Lock()
+	for s := range active {
+		next := s.tickAfter(now)
+		s.mutex.Lock()
+		info := Info{
+			Func:     runtime.FuncForPC(reflect.ValueOf(s.do).Pointer()).Name(),
+			Next:     next,
+			Last:     s.lastTrigger,
+			Interval: s.interval,
+		}
+		s.mutex.Unlock()
+		state.Schedulers = append(state.Schedulers, info)
+	}
+	activeMutex.Unlock()
+	sort.Slice(state.Schedulers, func(i, j int) bool {
+		return state.Schedulers[i].Next.Before(state.Schedulers[j].Next)
+	})
+	return state
+}
+
 // tickAfter returns the next trigger time for the scheduler.
 // This is used in test mode to determine the next firing scheduler
-// and advance time to it.
+// and advance time to it, and to report the next trigger time.
 func (s *scheduler) tickAfter(now time.Time) time.Time {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -148,7 +395,7 @@ func (s *scheduler) tickAfter(now time.Time) time.Time {
 
 // Now returns the current time. Used for testing.
 func Now() time.Time {
-	if testMode {
+	if inTestMode() {
 		nowMutex.Lock()
 		defer nowMutex.Unlock()
 		return nowInTest
@@ -160,15 +407,35 @@ func Now() time.Time {
 // In test mode schedulers do not fire automatically, and time
 // does not pass at all, until NextTick() or Advance* is called.
 func TestMode(enabled bool) {
+	testModeMutex.Lock()
+	testMode = enabled
+	testModeMutex.Unlock()
 	nowMutex.Lock()
 	defer nowMutex.Unlock()
-	testMode = enabled
 	nowInTest = time.Time{}
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+	paused = false
+	waiting = map[*scheduler]bool{}
+	scaleMutex.Lock()
+	defer scaleMutex.Unlock()
+	scale = 1
 }
 
 // testMode tracks whether all schedulers are in test mode.
 var testMode = false
 
+var testModeMutex sync.RWMutex
+
+// inTestMode returns true if schedulers are in test mode. testMode is
+// guarded by its own mutex, since tests can change it while modules from
+// earlier tests are still updating.
+func inTestMode() bool {
+	testModeMutex.RLock()
+	defer testModeMutex.RUnlock()
+	return testMode
+}
+
 // nowInTest tracks the current time in test mode.
 var nowInTest time.Time
 var nowMutex sync.Mutex
@@ -217,7 +484,7 @@ func AdvanceTo(newTime time.Time) {
 		nextTick := s.tickAfter(now)
 		if nextTick.After(now) && !nextTick.After(newTime) {
 			setNowTo(nextTick)
-			go s.do()
+			go s.trigger()
 		}
 	}
 	setNowTo(newTime)
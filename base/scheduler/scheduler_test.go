@@ -15,6 +15,7 @@
 package scheduler
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -88,6 +89,32 @@ func TestSchedulers(t *testing.T) {
 	d2.assertNotCalled("after first trigger")
 }
 
+func TestAligned(t *testing.T) {
+	interval := 20 * time.Millisecond
+	ch := make(chan time.Time, 10)
+	sch := Do(func() { ch <- time.Now() }).EveryAligned(interval)
+	for i := 0; i < 3; i++ {
+		select {
+		case tick := <-ch:
+			offset := tick.Sub(tick.Truncate(interval))
+			assert.True(t, offset < 10*time.Millisecond,
+				"tick %d is %v after boundary", i, offset)
+		case <-time.After(time.Second):
+			assert.Fail(t, "expected a tick")
+		}
+	}
+	sch.Stop()
+	time.Sleep(2 * interval)
+	assert.Empty(t, ch, "when stopped")
+
+	d := newDoFunc(t)
+	sch = Do(d.Func).EveryAligned(time.Hour)
+	sch.After(5 * time.Millisecond)
+	d.assertCalled("after replacing aligned schedule")
+	d.assertNotCalled("after replacing aligned schedule")
+	sch.Stop()
+}
+
 func TestTestMode(t *testing.T) {
 	d1 := newDoFunc(t)
 	d2 := newDoFunc(t)
@@ -164,4 +191,200 @@ func TestTestMode(t *testing.T) {
 
 	AdvanceBy(10 * time.Millisecond)
 	d1.assertCalled("after interval elapses")
+
+	AdvanceBy(30 * time.Second)
+	d1.assertNotCalled("before interval elapses")
+	sch1.EveryAligned(time.Minute)
+	aligned := Now().Truncate(time.Minute)
+	assert.NotEqual(t, aligned, Now())
+	assert.Equal(t, aligned.Add(time.Minute), NextTick(), "aligned scheduler")
+	d1.assertCalled("aligned scheduler")
+	assert.Equal(t, aligned.Add(2*time.Minute), NextTick(), "aligned scheduler")
+	d1.assertCalled("aligned scheduler")
+}
+
+func TestPause(t *testing.T) {
+	TestMode(true)
+	d1 := newDoFunc(t)
+	d2 := newDoFunc(t)
+	d3 := newDoFunc(t)
+	sch1 := Do(d1.Func).Every(time.Minute)
+	sch2 := Do(d2.Func).After(time.Hour)
+	Do(d3.Func).After(time.Minute)
+
+	Pause()
+	AdvanceBy(time.Minute)
+	AdvanceBy(time.Minute)
+	d1.assertNotCalled("while paused")
+	d3.assertNotCalled("while paused")
+
+	Resume()
+	d1.assertCalled("on resume")
+	d1.assertNotCalled("only once on resume")
+	d2.assertNotCalled("not yet due")
+	d3.assertCalled("on resume")
+
+	Pause()
+	AdvanceBy(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	sch1.Stop()
+	Resume()
+	d1.assertNotCalled("stopped while paused")
+
+	AdvanceBy(time.Hour)
+	d2.assertCalled("after resuming")
+	sch2.Stop()
+}
+
+func TestScale(t *testing.T) {
+	TestMode(true)
+	AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	d1 := newDoFunc(t)
+	d2 := newDoFunc(t)
+	sch1 := Do(d1.Func).Every(time.Minute)
+	sch2 := Do(d2.Func).EveryAligned(time.Minute)
+	defer sch1.Stop()
+	defer sch2.Stop()
+
+	SetScale(3)
+	assert.Equal(t, Now().Add(3*time.Minute), sch1.NextTrigger(), "interval scaled")
+	assert.Equal(t, Now().Add(time.Minute), sch2.NextTrigger(), "aligned not scaled")
+	AdvanceBy(time.Minute)
+	d2.assertCalled("aligned scheduler")
+	d1.assertNotCalled("scaled scheduler")
+	AdvanceBy(2 * time.Minute)
+	d1.assertCalled("after scaled interval")
+
+	sch1.Every(time.Second)
+	assert.Equal(t, Now().Add(3*time.Second), sch1.NextTrigger(), "new intervals scaled")
+	SetScale(1)
+	assert.Equal(t, Now().Add(time.Second), sch1.NextTrigger(), "normal interval restored")
+	AdvanceBy(time.Second)
+	d1.assertCalled("after normal interval")
+
+	sch1.Every(time.Minute)
+	AdvanceBy(40 * time.Second)
+	SetScale(2)
+	assert.Equal(t, Now().Add(40*time.Second), sch1.NextTrigger(),
+		"remaining time scaled, not restarted")
+	SetScale(1)
+	assert.Equal(t, Now().Add(20*time.Second), sch1.NextTrigger(),
+		"toggling scale does not delay the next trigger")
+	AdvanceBy(20 * time.Second)
+	d1.assertCalled("after rescaled remaining time")
+	assert.Equal(t, Now().Add(time.Minute), sch1.NextTrigger(), "normal interval")
+
+	sch1.Stop()
+	SetScale(2)
+	assert.True(t, sch1.NextTrigger().IsZero(), "stopped scheduler not rescheduled")
+	sch1.After(time.Minute)
+	SetScale(1)
+	assert.Equal(t, Now().Add(time.Minute), sch1.NextTrigger(), "one-off trigger not scaled")
+
+	SetScale(0)
+	sch1.Every(time.Second)
+	assert.Equal(t, Now().Add(time.Second), sch1.NextTrigger(), "invalid scale ignored")
+}
+
+func TestNextAndLastTrigger(t *testing.T) {
+	TestMode(true)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	AdvanceTo(start)
+	d := newDoFunc(t)
+	sch := Do(d.Func)
+	assert.True(t, sch.NextTrigger().IsZero(), "when not scheduled")
+	assert.True(t, sch.LastTrigger().IsZero(), "when never triggered")
+
+	sch.After(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), sch.NextTrigger(), "one-off trigger")
+	AdvanceBy(time.Minute)
+	d.assertCalled("one-off trigger")
+	assert.True(t, sch.NextTrigger().IsZero(), "after one-off trigger")
+	assert.Equal(t, start.Add(time.Minute), sch.LastTrigger())
+
+	sch.Every(10 * time.Minute)
+	assert.Equal(t, start.Add(11*time.Minute), sch.NextTrigger(), "repeated trigger")
+	AdvanceBy(10 * time.Minute)
+	d.assertCalled("repeated trigger")
+	assert.Equal(t, start.Add(21*time.Minute), sch.NextTrigger(), "repeated trigger")
+	assert.Equal(t, start.Add(11*time.Minute), sch.LastTrigger())
+
+	sch.EveryAligned(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), sch.NextTrigger(), "aligned trigger")
+
+	sch.Stop()
+	assert.True(t, sch.NextTrigger().IsZero(), "when stopped")
+	assert.Equal(t, start.Add(11*time.Minute), sch.LastTrigger(), "when stopped")
+}
+
+func TestNextTriggerRealMode(t *testing.T) {
+	TestMode(false)
+	d := newDoFunc(t)
+	sch := Do(d.Func).After(time.Hour)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), sch.NextTrigger(), time.Second)
+
+	sch.Every(5 * time.Millisecond)
+	d.assertCalled("repeated trigger")
+	SetScale(2)
+	d.assertCalled("after rescaling")
+	SetScale(1)
+	d.assertCalled("after restoring scale")
+	assert.WithinDuration(t, time.Now(), sch.LastTrigger(), time.Second)
+	assert.True(t, sch.NextTrigger().After(sch.LastTrigger()), "next after last")
+	sch.Stop()
+	assert.True(t, sch.NextTrigger().IsZero(), "when stopped")
+}
+
+func TestCurrentState(t *testing.T) {
+	TestMode(true)
+	defer TestMode(false)
+	start := Now()
+
+	// Filter out schedulers from other tests.
+	mine := func(s State) []Info {
+		var infos []Info
+		for _, i := range s.Schedulers {
+			if strings.Contains(i.Func, "TestCurrentState") {
+				infos = append(infos, i)
+			}
+		}
+		return infos
+	}
+
+	triggered := make(chan struct{}, 1)
+	once := Do(func() { triggered <- struct{}{} }).After(time.Minute)
+	repeating := Do(func() {}).Every(time.Second)
+	infos := mine(CurrentState())
+	if assert.Equal(t, 2, len(infos), "scheduled schedulers included") {
+		assert.Equal(t, start.Add(time.Second), infos[0].Next, "sorted by next trigger")
+		assert.Equal(t, time.Second, infos[0].Interval)
+		assert.Equal(t, start.Add(time.Minute), infos[1].Next)
+		assert.Equal(t, time.Duration(0), infos[1].Interval)
+		assert.True(t, infos[1].Last.IsZero(), "not triggered yet")
+	}
+
+	repeating.Stop()
+	infos = mine(CurrentState())
+	assert.Equal(t, 1, len(infos), "stopped scheduler removed")
+
+	AdvanceBy(time.Minute)
+	<-triggered
+	assert.Empty(t, mine(CurrentState()), "one-off scheduler removed after triggering")
+
+	once.After(time.Second)
+	assert.Equal(t, 1, len(mine(CurrentState())), "rescheduled scheduler included")
+
+	Pause()
+	AdvanceBy(time.Minute)
+	for i := 0; i < 100 && CurrentState().Waiting == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	state := CurrentState()
+	assert.True(t, state.Paused)
+	// Schedulers left over from other tests may also be waiting.
+	assert.True(t, state.Waiting > 0, "schedulers due while paused")
+	Resume()
+	<-triggered
+	assert.False(t, CurrentState().Paused)
+	once.Stop()
 }
@@ -0,0 +1,141 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// timer calls a function after a delay, and optionally at an interval
+// after that, until stopped.
+type timer interface {
+	Stop()
+}
+
+// newTimer creates a timer that calls f after the given delay, and then
+// every interval if the interval is non-zero.
+//
+// Go's timers use the monotonic clock, which does not advance while the
+// system is suspended, so a timer set for 5 minutes before a suspend still
+// waits for most of 5 minutes after resuming. Timers here use timerfd with
+// CLOCK_BOOTTIME instead, which includes the time spent suspended, so any
+// schedules that fell due during a suspend fire immediately on resume.
+func newTimer(delay, interval time.Duration, f func()) timer {
+	if t, err := newBootTimer(delay, interval, f); err == nil {
+		return t
+	}
+	// CLOCK_BOOTTIME timers need linux 3.15 or later.
+	return newGoTimer(delay, interval, f)
+}
+
+// bootTimer is a timer backed by a CLOCK_BOOTTIME timerfd.
+type bootTimer struct {
+	file    *os.File
+	stopped int32
+}
+
+func newBootTimer(delay, interval time.Duration, f func()) (*bootTimer, error) {
+	fd, err := unix.TimerfdCreate(unix.CLOCK_BOOTTIME, unix.TFD_NONBLOCK|unix.TFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	if delay <= 0 {
+		// A zero value disarms the timer, so use the smallest delay instead.
+		delay = 1
+	}
+	spec := unix.ItimerSpec{
+		Value:    unix.NsecToTimespec(int64(delay)),
+		Interval: unix.NsecToTimespec(int64(interval)),
+	}
+	if err := unix.TimerfdSettime(fd, 0, &spec, nil); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	// Since the fd is non-blocking, reads go through the runtime poller,
+	// and can be interrupted by closing the file.
+	t := &bootTimer{file: os.NewFile(uintptr(fd), "timerfd")}
+	go t.run(f, interval > 0)
+	return t, nil
+}
+
+func (t *bootTimer) run(f func(), repeat bool) {
+	defer t.file.Close()
+	// Each read blocks until the timer expires, and returns the number of
+	// expirations since the last read, so missed ticks are coalesced.
+	buf := make([]byte, 8)
+	for {
+		if _, err := t.file.Read(buf); err != nil {
+			return
+		}
+		if atomic.LoadInt32(&t.stopped) != 0 {
+			return
+		}
+		f()
+		if !repeat {
+			return
+		}
+	}
+}
+
+func (t *bootTimer) Stop() {
+	atomic.StoreInt32(&t.stopped, 1)
+	t.file.Close()
+}
+
+// goTimer is a timer that uses Go's timers, for systems that do not
+// support CLOCK_BOOTTIME timers.
+type goTimer struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+func newGoTimer(delay, interval time.Duration, f func()) *goTimer {
+	t := &goTimer{stop: make(chan struct{})}
+	go t.run(delay, interval, f)
+	return t
+}
+
+func (t *goTimer) run(delay, interval time.Duration, f func()) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-t.stop:
+		return
+	}
+	f()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.stop:
+			return
+		}
+		f()
+	}
+}
+
+func (t *goTimer) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func testTimer(t *testing.T, newTimer func(time.Duration, time.Duration, func()) timer) {
+	d := newDoFunc(t)
+	tmr := newTimer(5*time.Millisecond, 0, d.Func)
+	d.assertCalled("after delay")
+	d.assertNotCalled("only once")
+	tmr.Stop()
+
+	newTimer(0, 0, d.Func)
+	d.assertCalled("with zero delay")
+
+	newTimer(20*time.Millisecond, 0, d.Func).Stop()
+	time.Sleep(30 * time.Millisecond)
+	d.assertNotCalled("when stopped")
+
+	tmr = newTimer(10*time.Millisecond, 25*time.Millisecond, d.Func)
+	d.assertCalled("after delay")
+	d.assertCalled("at interval")
+	d.assertCalled("at interval")
+	tmr.Stop()
+	time.Sleep(30 * time.Millisecond)
+	d.assertNotCalled("when stopped")
+}
+
+func TestBootTimer(t *testing.T) {
+	tmr, err := newBootTimer(time.Hour, 0, func() {})
+	if err != nil {
+		t.Skipf("CLOCK_BOOTTIME timers not supported: %v", err)
+	}
+	tmr.Stop()
+	testTimer(t, func(delay, interval time.Duration, f func()) timer {
+		tmr, _ := newBootTimer(delay, interval, f)
+		return tmr
+	})
+}
+
+func TestGoTimer(t *testing.T) {
+	testTimer(t, func(delay, interval time.Duration, f func()) timer {
+		return newGoTimer(delay, interval, f)
+	})
+}
@@ -0,0 +1,196 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package secret provides references to API keys, tokens, and passwords, so
+that bar configurations do not need to include them in source.
+
+A Secret can be resolved from an environment variable, a file, the output
+of a command (e.g. a password manager), or the desktop keyring. Secrets are
+resolved when first needed, and the value is kept for subsequent uses.
+Failures are not kept, so a secret that is not yet available (e.g. a locked
+keyring) will be retried on the next use.
+
+Typical usage would be:
+
+	stocks.New(stocks.Finnhub(secret.Env("FINNHUB_API_KEY")), "GOOG")
+	syncthing.New(secret.File("~/.config/barista/syncthing-key"))
+	homeassistant.New(server, secret.Command("pass", "show", "hass"), ...)
+*/
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Secret is a reference to a secret value, such as an API key.
+type Secret interface {
+	// Get returns the value of the secret, or an error if it could not be
+	// resolved.
+	Get() (string, error)
+}
+
+// source is a secret resolved using a function, which caches the value
+// once it has been resolved successfully.
+type source struct {
+	desc    string
+	resolve func() (string, error)
+
+	mutex    sync.Mutex
+	value    string
+	resolved bool
+}
+
+func (s *source) Get() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.resolved {
+		return s.value, nil
+	}
+	value, err := s.resolve()
+	if err == nil && value == "" {
+		err = errors.New("empty value")
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %v", s.desc, err)
+	}
+	s.value = value
+	s.resolved = true
+	return value, nil
+}
+
+// String describes where the secret comes from, without its value, so that
+// secrets can be safely logged.
+func (s *source) String() string {
+	return s.desc
+}
+
+func newSource(desc string, resolve func() (string, error)) Secret {
+	return &source{desc: desc, resolve: resolve}
+}
+
+// Value returns a secret with a fixed value.
+func Value(value string) Secret {
+	return newSource("value", func() (string, error) { return value, nil })
+}
+
+// Env returns a secret read from the given environment variable.
+func Env(name string) Secret {
+	return newSource("env:"+name, func() (string, error) {
+		return os.Getenv(name), nil
+	})
+}
+
+// File returns a secret read from the given file. A leading "~/" is
+// expanded to the home directory, and trailing whitespace is ignored.
+func File(path string) Secret {
+	return newSource("file:"+path, func() (string, error) {
+		file := path
+		if strings.HasPrefix(file, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			file = filepath.Join(home, file[2:])
+		}
+		contents, err := ioutil.ReadFile(file)
+		return strings.TrimRight(string(contents), " \t\r\n"), err
+	})
+}
+
+// Command returns a secret read from the output of the given command. Only
+// the first line of the output is used, which matches the convention used
+// by password managers such as pass.
+func Command(name string, args ...string) Secret {
+	desc := "cmd:" + strings.Join(append([]string{name}, args...), " ")
+	return newSource(desc, func() (string, error) {
+		out, err := run(name, args...)
+		return firstLine(out), err
+	})
+}
+
+// Keyring returns a secret read from the desktop keyring (e.g. GNOME
+// Keyring or KWallet) using secret-tool from libsecret. The attributes are
+// given as key-value pairs, matching the secret-tool invocation used to
+// store the secret, e.g.
+//
+//	secret-tool store --label "Finnhub" service finnhub
+//	secret.Keyring("service", "finnhub")
+func Keyring(attributes ...string) Secret {
+	desc := "keyring:" + strings.Join(attributes, " ")
+	return newSource(desc, func() (string, error) {
+		if len(attributes) == 0 || len(attributes)%2 != 0 {
+			return "", errors.New("attributes must be key-value pairs")
+		}
+		out, err := run("secret-tool", append([]string{"lookup"}, attributes...)...)
+		return firstLine(out), err
+	})
+}
+
+// Parse parses a secret reference, for use in configuration files:
+//
+//	env:NAME                  environment variable NAME
+//	file:PATH                 contents of the file at PATH
+//	cmd:COMMAND               output of COMMAND, run using sh -c
+//	keyring:KEY=VAL,KEY=VAL   keyring entry with the given attributes
+//
+// Any other string is used as the value of the secret.
+func Parse(ref string) (Secret, error) {
+	scheme, rest := "", ref
+	if idx := strings.Index(ref, ":"); idx >= 0 {
+		scheme, rest = ref[:idx], ref[idx+1:]
+	}
+	switch scheme {
+	case "env":
+		return Env(rest), nil
+	case "file":
+		return File(rest), nil
+	case "cmd":
+		return Command("sh", "-c", rest), nil
+	case "keyring":
+		var attributes []string
+		for _, pair := range strings.Split(rest, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("secret: invalid keyring attribute %q", pair)
+			}
+			attributes = append(attributes, kv[0], kv[1])
+		}
+		return Keyring(attributes...), nil
+	}
+	return Value(ref), nil
+}
+
+func firstLine(out string) string {
+	if idx := strings.IndexAny(out, "\r\n"); idx >= 0 {
+		out = out[:idx]
+	}
+	return out
+}
+
+// run runs a command and returns its output, replaced in tests.
+var run = func(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	val, err := Value("abcd").Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", val)
+
+	_, err = Value("").Get()
+	assert.Error(t, err, "empty secret")
+	assert.Equal(t, "value", fmt.Sprintf("%v", Value("abcd")),
+		"does not print the value")
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("BARISTA_TEST_SECRET", "from-env")
+	defer os.Unsetenv("BARISTA_TEST_SECRET")
+	val, err := Env("BARISTA_TEST_SECRET").Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", val)
+
+	_, err = Env("BARISTA_TEST_UNSET_SECRET").Get()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "env:BARISTA_TEST_UNSET_SECRET")
+}
+
+func TestFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "secret")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key")
+
+	s := File(path)
+	_, err := s.Get()
+	assert.Error(t, err, "missing file")
+
+	ioutil.WriteFile(path, []byte("from-file\n"), 0600)
+	val, err := s.Get()
+	assert.NoError(t, err, "retried after failure")
+	assert.Equal(t, "from-file", val)
+
+	ioutil.WriteFile(path, []byte("changed"), 0600)
+	val, _ = s.Get()
+	assert.Equal(t, "from-file", val, "value is kept once resolved")
+
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", dir)
+	val, err = File("~/key").Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", val, "expands home directory")
+}
+
+func TestCommandAndKeyring(t *testing.T) {
+	var calls [][]string
+	out, outErr := "", error(nil)
+	run = func(name string, args ...string) (string, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return out, outErr
+	}
+
+	out = "hunter2\nurl: example.com\n"
+	val, err := Command("pass", "show", "api").Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", val, "uses first line of output")
+
+	out, outErr = "", errors.New("locked")
+	s := Keyring("service", "finnhub")
+	_, err = s.Get()
+	assert.Error(t, err)
+
+	out, outErr = "token\n", nil
+	val, err = s.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "token", val)
+
+	assert.Equal(t, [][]string{
+		{"pass", "show", "api"},
+		{"secret-tool", "lookup", "service", "finnhub"},
+		{"secret-tool", "lookup", "service", "finnhub"},
+	}, calls)
+
+	_, err = Keyring("service").Get()
+	assert.Error(t, err, "odd number of attributes")
+	assert.Len(t, calls, 3, "does not run secret-tool")
+}
+
+func TestParse(t *testing.T) {
+	var calls [][]string
+	run = func(name string, args ...string) (string, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return "out", nil
+	}
+	os.Setenv("BARISTA_TEST_SECRET", "from-env")
+	defer os.Unsetenv("BARISTA_TEST_SECRET")
+
+	for ref, expected := range map[string]string{
+		"env:BARISTA_TEST_SECRET":         "from-env",
+		"cmd:pass show api":               "out",
+		"keyring:service=finnhub,user=me": "out",
+		"plain-api-key":                   "plain-api-key",
+		"unknown:scheme":                  "unknown:scheme",
+	} {
+		s, err := Parse(ref)
+		assert.NoError(t, err, ref)
+		val, err := s.Get()
+		assert.NoError(t, err, ref)
+		assert.Equal(t, expected, val, ref)
+	}
+	assert.Contains(t, calls, []string{"sh", "-c", "pass show api"})
+	assert.Contains(t, calls, []string{"secret-tool", "lookup",
+		"service", "finnhub", "user", "me"})
+
+	_, err := Parse("keyring:service")
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package state provides simple persistence for module state, so that modules
+can restore things like counts, seen items, or selections across restarts.
+
+Values are stored as JSON, one file per key, in the "barista" directory
+under $XDG_STATE_HOME (falling back to ~/.local/state), following the XDG
+base directory specification.
+
+Typical usage would be:
+
+	var seen []string
+	state.Load("rss-news", &seen)
+	// ... later, after the state changes:
+	state.Save("rss-news", seen)
+*/
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory where state is stored.
+func Dir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".local", "state")
+	}
+	return filepath.Join(dir, "barista")
+}
+
+// Store saves values as JSON files in a directory, one file per key. The
+// package-level functions use a store in the state directory, and other
+// packages can use a Store for their own directory, e.g. base/cache.
+type Store struct {
+	dir func() string
+}
+
+// In returns a store for the directory returned by dir, which is called for
+// each operation so that the store follows changes to the environment.
+func In(dir func() string) Store {
+	return Store{dir}
+}
+
+// std is the store in the state directory.
+var std = In(Dir)
+
+// file returns the path of the file that stores the value for a key.
+// Keys are escaped, so any string can be used as a key.
+func (s Store) file(key string) string {
+	return filepath.Join(s.dir(), url.PathEscape(key))
+}
+
+// Load restores the value saved under the given key into value, which must
+// be a pointer, and returns true if a value was restored. Missing or invalid
+// state is ignored, leaving value unchanged, since modules need to work
+// without any saved state anyway.
+func Load(key string, value interface{}) bool {
+	return std.Load(key, value)
+}
+
+// Save saves the value under the given key, replacing any previously saved
+// value. The file is replaced atomically, so a crash while saving never
+// leaves partially written state.
+func Save(key string, value interface{}) error {
+	return std.Save(key, value)
+}
+
+// Clear removes the value saved under the given key, if any.
+func Clear(key string) error {
+	return std.Clear(key)
+}
+
+// Load restores the value saved under the given key, as the package-level
+// Load does for the state directory.
+func (s Store) Load(key string, value interface{}) bool {
+	bytes, err := ioutil.ReadFile(s.file(key))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(bytes, value) == nil
+}
+
+// Save saves the value under the given key, replacing the file atomically.
+func (s Store) Save(key string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	path := s.file(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(bytes)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Clear removes the value saved under the given key, if any.
+func (s Store) Clear(key string) error {
+	err := os.Remove(s.file(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
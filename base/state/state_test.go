@@ -0,0 +1,82 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func withStateDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("XDG_STATE_HOME", dir)
+	return dir, func() {
+		os.Unsetenv("XDG_STATE_HOME")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDir(t *testing.T) {
+	os.Unsetenv("XDG_STATE_HOME")
+	os.Setenv("HOME", "/home/user")
+	assert.Equal(t, "/home/user/.local/state/barista", Dir(), "default state directory")
+	os.Setenv("XDG_STATE_HOME", "/state")
+	defer os.Unsetenv("XDG_STATE_HOME")
+	assert.Equal(t, "/state/barista", Dir(), "uses $XDG_STATE_HOME")
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, cleanup := withStateDir(t)
+	defer cleanup()
+
+	var count int
+	assert.False(t, Load("counter", &count), "nothing to load")
+	assert.Equal(t, 0, count, "value unchanged when nothing to load")
+
+	assert.NoError(t, Save("counter", 42))
+	assert.True(t, Load("counter", &count))
+	assert.Equal(t, 42, count, "value restored")
+
+	assert.NoError(t, Save("counter", 7))
+	assert.True(t, Load("counter", &count))
+	assert.Equal(t, 7, count, "value replaced")
+
+	seen := map[string]bool{"a": true}
+	assert.NoError(t, Save("rss/news feed", seen))
+	restored := map[string]bool{}
+	assert.True(t, Load("rss/news feed", &restored), "keys are escaped")
+	assert.Equal(t, seen, restored)
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "barista"))
+	assert.Equal(t, 2, len(files), "one file per key, no leftover temporary files")
+
+	ioutil.WriteFile(filepath.Join(dir, "barista", "invalid"), []byte("{"), 0644)
+	count = 3
+	assert.False(t, Load("invalid", &count), "invalid state is ignored")
+	assert.Equal(t, 3, count, "value unchanged for invalid state")
+
+	assert.NoError(t, Clear("counter"))
+	assert.False(t, Load("counter", &count), "cleared")
+	assert.NoError(t, Clear("counter"), "clearing missing state")
+
+	assert.Error(t, Save("func", func() {}), "unserialisable values")
+}
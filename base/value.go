@@ -0,0 +1,82 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import "sync"
+
+// Notifier provides a coalescing notification channel, for signalling
+// "something changed" from one goroutine to another. Notifications are not
+// queued, so many calls to Notify before the receiver gets around to it
+// result in a single notification, and Notify never blocks.
+type Notifier struct {
+	// C receives a value after one or more calls to Notify.
+	C <-chan struct{}
+
+	ch chan struct{}
+}
+
+// NewNotifier creates a new notifier.
+func NewNotifier() *Notifier {
+	ch := make(chan struct{}, 1)
+	return &Notifier{C: ch, ch: ch}
+}
+
+// Notify sends a notification, unless one is already pending.
+func (n *Notifier) Notify() {
+	select {
+	case n.ch <- struct{}{}:
+	default:
+	}
+}
+
+// Value provides thread-safe storage of a value, and notifies subscribers
+// when the value changes, so that modules can re-render when their data is
+// updated from another goroutine. The zero value is ready to use, and holds
+// nil until Set is called.
+type Value struct {
+	mutex       sync.RWMutex
+	value       interface{}
+	subscribers []*Notifier
+}
+
+// Get returns the current value.
+func (v *Value) Get() interface{} {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.value
+}
+
+// Set updates the value, and notifies all subscribers.
+func (v *Value) Set(value interface{}) {
+	v.mutex.Lock()
+	v.value = value
+	subscribers := v.subscribers
+	v.mutex.Unlock()
+	for _, n := range subscribers {
+		n.Notify()
+	}
+}
+
+// Subscribe returns a channel that receives a notification when the value
+// is set. Notifications are coalesced, so a subscriber that is busy when
+// the value changes several times is only notified once, and should use
+// Get to retrieve the latest value.
+func (v *Value) Subscribe() <-chan struct{} {
+	n := NewNotifier()
+	v.mutex.Lock()
+	v.subscribers = append(v.subscribers, n)
+	v.mutex.Unlock()
+	return n.C
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func assertNotified(t *testing.T, ch <-chan struct{}, message string) {
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected notification", message)
+	}
+}
+
+func assertNotNotified(t *testing.T, ch <-chan struct{}, message string) {
+	select {
+	case <-ch:
+		assert.Fail(t, "unexpected notification", message)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	n := NewNotifier()
+	assertNotNotified(t, n.C, "before notify")
+
+	n.Notify()
+	assertNotified(t, n.C, "after notify")
+	assertNotNotified(t, n.C, "only once")
+
+	n.Notify()
+	n.Notify()
+	n.Notify()
+	assertNotified(t, n.C, "after multiple notify calls")
+	assertNotNotified(t, n.C, "notifications are coalesced")
+}
+
+func TestValue(t *testing.T) {
+	var v Value
+	assert.Nil(t, v.Get(), "zero value")
+
+	sub1 := v.Subscribe()
+	v.Set("foo")
+	assert.Equal(t, "foo", v.Get())
+	assertNotified(t, sub1, "on set")
+
+	sub2 := v.Subscribe()
+	assertNotNotified(t, sub2, "new subscriber before set")
+	v.Set("bar")
+	v.Set("baz")
+	assertNotified(t, sub1, "on set")
+	assertNotNotified(t, sub1, "notifications are coalesced")
+	assertNotified(t, sub2, "on set")
+	assert.Equal(t, "baz", v.Get(), "latest value")
+}
+
+func TestValueConcurrency(t *testing.T) {
+	var v Value
+	sub := v.Subscribe()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v.Set(i)
+			v.Get()
+		}(i)
+	}
+	wg.Wait()
+	assertNotified(t, sub, "after concurrent sets")
+	assert.IsType(t, 0, v.Get())
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// base16Names are the names of the colors in a Base16 scheme.
+var base16Names = []string{
+	"base00", "base01", "base02", "base03", "base04", "base05", "base06", "base07",
+	"base08", "base09", "base0A", "base0B", "base0C", "base0D", "base0E", "base0F",
+}
+
+// LoadFromBase16 loads a Base16 scheme from a YAML file, setting the colors
+// "base00" to "base0F" in the scheme. Both the original format, with colors
+// as top-level keys without a leading "#", and the newer format, with colors
+// under a "palette" key, are supported.
+func LoadFromBase16(filename string) error {
+	data, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return err
+	}
+	var s struct {
+		Palette map[string]string `yaml:"palette"`
+		Colors  map[string]string `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	palette := s.Palette
+	if palette == nil {
+		palette = s.Colors
+	}
+	loaded := map[string]bar.Color{}
+	for _, name := range base16Names {
+		value, ok := palette[name]
+		if !ok {
+			// Some schemes use lowercase hex digits in the names.
+			value, ok = palette[strings.ToLower(name)]
+		}
+		if !ok {
+			continue
+		}
+		if !strings.HasPrefix(value, "#") {
+			value = "#" + value
+		}
+		if color := Hex(value); color != "" {
+			loaded[name] = color
+		}
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("%s: not a base16 scheme", filename)
+	}
+	setAll(loaded, false)
+	return nil
+}
+
+// SemanticFromBase16 sets the common "good", "degraded", and "bad" colors
+// from the Base16 colors in the scheme, using the green (base0B), yellow
+// (base0A), and red (base08) accent colors.
+func SemanticFromBase16() {
+	loaded := map[string]bar.Color{}
+	for name, base := range map[string]string{
+		"good":     "base0B",
+		"degraded": "base0A",
+		"bad":      "base08",
+	} {
+		if color := Scheme(base); color != "" {
+			loaded[name] = color
+		}
+	}
+	setAll(loaded, false)
+}
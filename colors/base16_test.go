@@ -0,0 +1,80 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+)
+
+func TestLoadFromBase16(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "gruvbox.yaml", []byte(`
+scheme: "Gruvbox dark, medium"
+author: "Dawid Kurek (dawikur@gmail.com)"
+base00: "282828"
+base01: "3c3836"
+base08: "fb4934"
+base0A: "fabd2f"
+base0B: "b8bb26"
+base0f: "d65d0e"
+`), 0644)
+	afero.WriteFile(fs, "palette.yaml", []byte(`
+system: "base16"
+name: "Nord"
+variant: "dark"
+palette:
+  base00: "#2E3440"
+  base08: "#BF616A"
+  base0B: "#A3BE8C"
+`), 0644)
+	afero.WriteFile(fs, "invalid.yaml", []byte("- not: [a scheme"), 0644)
+	afero.WriteFile(fs, "other.yaml", []byte("colors: {good: '#0f0'}"), 0644)
+
+	scheme = map[string]bar.Color{}
+	assert.Error(t, LoadFromBase16("non-existent"), "non-existent file")
+	assert.Error(t, LoadFromBase16("invalid.yaml"), "invalid yaml")
+	assert.Error(t, LoadFromBase16("other.yaml"), "not a base16 scheme")
+
+	assert.NoError(t, LoadFromBase16("gruvbox.yaml"))
+	assertSchemeEquals(t, map[string]string{
+		"base00": "#282828",
+		"base01": "#3c3836",
+		"base08": "#fb4934",
+		"base0A": "#fabd2f",
+		"base0B": "#b8bb26",
+		"base0F": "#d65d0e",
+	}, "base16 scheme")
+
+	SemanticFromBase16()
+	assert.Equal(t, "#b8bb26", string(Scheme("good")))
+	assert.Equal(t, "#fabd2f", string(Scheme("degraded")))
+	assert.Equal(t, "#fb4934", string(Scheme("bad")))
+
+	scheme = map[string]bar.Color{}
+	assert.NoError(t, LoadFromBase16("palette.yaml"))
+	SemanticFromBase16()
+	assertSchemeEquals(t, map[string]string{
+		"base00": "#2e3440",
+		"base08": "#bf616a",
+		"base0B": "#a3be8c",
+		"good":   "#a3be8c",
+		"bad":    "#bf616a",
+	}, "palette format, missing degraded color")
+}
@@ -12,17 +12,28 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package colors provides helper functions to manage color and color schemes.
+/*
+Package colors provides helper functions to manage color and color schemes.
+
+Colors are bar.Color values, which can be used directly as segment colors,
+e.g. outputs.Text("...").Color(colors.Hex("#f00")), and as pango attributes,
+e.g. pango.Span(colors.Scheme("bad"), "..."). The color scheme is a global
+registry of named colors, so that modules can use semantic colors such as
+"good" and "bad", and bar authors can restyle all modules in one place using
+Set or the Load* functions.
+*/
 package colors
 
 import (
 	"bufio"
 	"strings"
+	"sync"
 
 	"github.com/lucasb-eyer/go-colorful"
 	"github.com/spf13/afero"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/listeners"
 )
 
 // Hex sanity-checks and constructs a color from a hex-string.
@@ -40,9 +51,55 @@ func Colorful(c colorful.Color) bar.Color {
 	return bar.Color(c.Hex())
 }
 
+// RGB constructs a color from 8-bit red, green, and blue components.
+func RGB(r, g, b uint8) bar.Color {
+	return Colorful(colorful.Color{
+		R: float64(r) / 255.0,
+		G: float64(g) / 255.0,
+		B: float64(b) / 255.0,
+	})
+}
+
+// named holds the basic HTML color names.
+var named = map[string]string{
+	"black":   "#000000",
+	"silver":  "#c0c0c0",
+	"gray":    "#808080",
+	"grey":    "#808080",
+	"white":   "#ffffff",
+	"maroon":  "#800000",
+	"red":     "#ff0000",
+	"purple":  "#800080",
+	"fuchsia": "#ff00ff",
+	"magenta": "#ff00ff",
+	"green":   "#008000",
+	"lime":    "#00ff00",
+	"olive":   "#808000",
+	"yellow":  "#ffff00",
+	"navy":    "#000080",
+	"blue":    "#0000ff",
+	"teal":    "#008080",
+	"aqua":    "#00ffff",
+	"cyan":    "#00ffff",
+	"orange":  "#ffa500",
+}
+
+// Named constructs a color from one of the basic HTML color names,
+// e.g. "red" or "navy", ignoring case. i3bar only accepts hex colors,
+// so names are converted to their hex values.
+func Named(name string) bar.Color {
+	hex, ok := named[strings.ToLower(name)]
+	if !ok {
+		return Empty()
+	}
+	return Hex(hex)
+}
+
 // Scheme gets a color from the user-defined color scheme.
 // Some common names are 'good', 'bad', and 'degraded'.
 func Scheme(name string) bar.Color {
+	schemeMu.RLock()
+	defer schemeMu.RUnlock()
 	color, ok := scheme[name]
 	if !ok {
 		return Empty()
@@ -61,6 +118,59 @@ func Empty() bar.Color {
 // Bar authors can also define arbitrary names, e.g. to load XResource based colours
 // from i3 using the "LoadFromArgs" method.
 var scheme = map[string]bar.Color{}
+var schemeMu sync.RWMutex
+
+// onChange is notified whenever the scheme changes.
+var onChange listeners.Set
+
+// OnChange adds a function that will be called whenever the color scheme
+// changes, and returns a function that removes it. Modules built on base
+// are updated automatically, so that any colors from the scheme are
+// re-rendered.
+func OnChange(f func()) (remove func()) {
+	return onChange.Add(f)
+}
+
+// setAll sets all the given colors in the scheme, removing names with an
+// empty color, and notifies listeners once. If replace is true, all other
+// existing colors are removed.
+func setAll(colors map[string]bar.Color, replace bool) {
+	schemeMu.Lock()
+	if replace {
+		scheme = map[string]bar.Color{}
+	}
+	for name, color := range colors {
+		if color == "" {
+			delete(scheme, name)
+		} else {
+			scheme[name] = color
+		}
+	}
+	schemeMu.Unlock()
+	onChange.Notify()
+}
+
+// Set sets a named color in the scheme, replacing any existing value.
+// Setting an empty color removes the name from the scheme.
+func Set(name string, color bar.Color) {
+	setAll(map[string]bar.Color{name: color}, false)
+}
+
+// Replace replaces the entire color scheme, e.g. to switch between light
+// and dark themes at runtime. Invalid colors are ignored.
+func Replace(s map[string]string) {
+	setAll(parseMap(s), true)
+}
+
+func parseMap(s map[string]string) map[string]bar.Color {
+	colors := map[string]bar.Color{}
+	for name, value := range s {
+		if color := Hex(value); color != "" {
+			colors[name] = color
+		}
+	}
+	return colors
+}
 
 func splitAtLastEqual(s string) (string, string, bool) {
 	idx := strings.LastIndex(s, "=")
@@ -72,22 +182,20 @@ func splitAtLastEqual(s string) (string, string, bool) {
 
 // LoadFromArgs loads a color scheme from command-line arguments of the form name=value.
 func LoadFromArgs(args []string) {
+	loaded := map[string]bar.Color{}
 	for _, arg := range args {
 		if name, value, ok := splitAtLastEqual(arg); ok {
 			if color := Hex(value); color != "" {
-				scheme[name] = color
+				loaded[name] = color
 			}
 		}
 	}
+	setAll(loaded, false)
 }
 
 // LoadFromMap sets the colour scheme from code.
 func LoadFromMap(s map[string]string) {
-	for name, value := range s {
-		if color := Hex(value); color != "" {
-			scheme[name] = color
-		}
-	}
+	setAll(parseMap(s), false)
 }
 
 var fs = afero.NewOsFs()
@@ -100,6 +208,8 @@ func LoadFromConfig(filename string) error {
 		return err
 	}
 	defer f.Close()
+	loaded := map[string]bar.Color{}
+	defer setAll(loaded, false)
 	s := bufio.NewScanner(f)
 	s.Split(bufio.ScanLines)
 	for s.Scan() {
@@ -119,7 +229,7 @@ func LoadFromConfig(filename string) error {
 			value = value[1 : len(value)-1]
 		}
 		if color := Hex(value); color != "" {
-			scheme[name] = color
+			loaded[name] = color
 		}
 	}
 	return nil
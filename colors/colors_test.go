@@ -38,6 +38,10 @@ func TestCreation(t *testing.T) {
 		{"short hex color", Hex("#07f"), "#0077ff"},
 		{"invalid hex color", Hex("#ghi"), ""},
 		{"colorful color from RGB", Colorful(colorful.Color{R: 1, G: 0.5, B: 0}), "#ff8000"},
+		{"8-bit RGB", RGB(255, 128, 0), "#ff8000"},
+		{"named color", Named("navy"), "#000080"},
+		{"named color ignores case", Named("Orange"), "#ffa500"},
+		{"unknown named color", Named("ultraviolet"), ""},
 		{"scheme empty", Scheme("empty"), ""},
 		{"scheme color", Scheme("test"), "#abcdef"},
 		{"scheme non-existent", Scheme("undefined"), ""},
@@ -48,6 +52,22 @@ func TestCreation(t *testing.T) {
 	}
 }
 
+func TestSet(t *testing.T) {
+	scheme = map[string]bar.Color{}
+	Set("good", Hex("#0f0"))
+	Set("bad", Named("red"))
+	assertSchemeEquals(t, map[string]string{
+		"good": "#00ff00",
+		"bad":  "#ff0000",
+	}, "setting colors")
+
+	Set("good", Hex("#00aa00"))
+	Set("bad", Empty())
+	assertSchemeEquals(t, map[string]string{
+		"good": "#00aa00",
+	}, "overriding and removing colors")
+}
+
 func assertSchemeEquals(t *testing.T, expected map[string]string, desc string) {
 	for name, expectedValue := range expected {
 		assert.Equal(t, expectedValue, string(Scheme(name)), desc)
@@ -201,3 +221,17 @@ general {
 		assertSchemeEquals(t, tc.expected, tc.file)
 	}
 }
+
+func TestOnChange(t *testing.T) {
+	changes := 0
+	remove := OnChange(func() { changes++ })
+	Set("good", Hex("#00ff00"))
+	assert.Equal(t, 1, changes, "listener called on change")
+	Replace(map[string]string{"good": "#007700"})
+	assert.Equal(t, 2, changes, "listener called on replace")
+
+	remove()
+	Set("good", Hex("#00ff00"))
+	assert.Equal(t, 2, changes, "removed listener is not called")
+	remove()
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package darkmode switches the color scheme between light and dark themes at
+runtime, following the freedesktop appearance setting from the desktop
+portal (org.freedesktop.appearance color-scheme), or on demand, e.g. from a
+click handler using Toggle. Modules built on base are updated automatically
+when the scheme changes, so any colors from the scheme are re-rendered.
+*/
+package darkmode
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+
+	"github.com/soumya92/barista/colors"
+)
+
+const (
+	portalDest  = "org.freedesktop.portal.Desktop"
+	portalPath  = "/org/freedesktop/portal/desktop"
+	portalIface = "org.freedesktop.portal.Settings"
+	namespace   = "org.freedesktop.appearance"
+	key         = "color-scheme"
+)
+
+// Values for the freedesktop color-scheme setting.
+const (
+	noPreference = 0
+	preferDark   = 1
+)
+
+// setting provides the freedesktop color-scheme setting.
+type setting interface {
+	read() (uint32, error)
+	watch(func(uint32)) error
+}
+
+// portal is the desktop portal setting, replaced in tests.
+var portal setting = dbusPortal{}
+
+var (
+	mu          sync.Mutex
+	light, dark map[string]string
+	isDark      bool
+)
+
+// Watch sets the light and dark color schemes, applies the one matching the
+// current desktop setting, and then switches schemes whenever the setting
+// changes. If the setting cannot be read, the light scheme is applied and
+// an error is returned.
+func Watch(lightScheme, darkScheme map[string]string) error {
+	mu.Lock()
+	light, dark = lightScheme, darkScheme
+	mu.Unlock()
+	value, err := portal.read()
+	if err != nil {
+		Set(false)
+		return err
+	}
+	Set(value == preferDark)
+	go portal.watch(func(value uint32) {
+		Set(value == preferDark)
+	})
+	return nil
+}
+
+// Set applies the dark scheme if dark is true, or the light scheme otherwise.
+func Set(darkMode bool) {
+	mu.Lock()
+	isDark = darkMode
+	s := light
+	if darkMode {
+		s = dark
+	}
+	mu.Unlock()
+	colors.Replace(s)
+}
+
+// Toggle switches between the light and dark schemes.
+func Toggle() {
+	Set(!IsDark())
+}
+
+// IsDark returns true if the dark scheme is currently applied.
+func IsDark() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return isDark
+}
+
+type dbusPortal struct{}
+
+// unwrap returns the uint32 value of a possibly nested variant.
+func unwrap(v interface{}) uint32 {
+	for {
+		variant, ok := v.(dbus.Variant)
+		if !ok {
+			break
+		}
+		v = variant.Value()
+	}
+	value, _ := v.(uint32)
+	return value
+}
+
+func (dbusPortal) read() (uint32, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return noPreference, err
+	}
+	obj := conn.Object(portalDest, portalPath)
+	var v dbus.Variant
+	// ReadOne is only supported by newer portals, while Read wraps the
+	// value in an additional variant.
+	if err := obj.Call(portalIface+".ReadOne", 0, namespace, key).Store(&v); err != nil {
+		if err := obj.Call(portalIface+".Read", 0, namespace, key).Store(&v); err != nil {
+			return noPreference, err
+		}
+	}
+	return unwrap(v), nil
+}
+
+func (dbusPortal) watch(f func(uint32)) error {
+	// A private connection is required since we're using Signal.
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return err
+	}
+	if err := conn.Hello(); err != nil {
+		return err
+	}
+	matchRule := strings.Join([]string{
+		"type='signal'",
+		"interface='" + portalIface + "'",
+		"member='SettingChanged'",
+		"path='" + portalPath + "'",
+	}, ",")
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		return err
+	}
+	c := make(chan *dbus.Signal, 10)
+	conn.Signal(c)
+	for v := range c {
+		if len(v.Body) == 3 && v.Body[0] == namespace && v.Body[1] == key {
+			f(unwrap(v.Body[2]))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package darkmode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/colors"
+)
+
+type testPortal struct {
+	value   uint32
+	err     error
+	changed chan func(uint32)
+}
+
+func (t testPortal) read() (uint32, error) {
+	return t.value, t.err
+}
+
+func (t testPortal) watch(f func(uint32)) error {
+	t.changed <- f
+	return nil
+}
+
+var (
+	lightScheme = map[string]string{"background": "#ffffff", "good": "#007700"}
+	darkScheme  = map[string]string{"background": "#000000", "bad": "#ff0000"}
+)
+
+func TestWatch(t *testing.T) {
+	assert := assert.New(t)
+	p := testPortal{value: preferDark, changed: make(chan func(uint32), 1)}
+	portal = p
+	assert.NoError(Watch(lightScheme, darkScheme))
+	assert.True(IsDark())
+	assert.Equal("#000000", string(colors.Scheme("background")))
+	assert.Equal("", string(colors.Scheme("good")), "scheme is replaced")
+
+	updated := make(chan bool, 10)
+	defer colors.OnChange(func() { updated <- true })()
+	notify := <-p.changed
+	notify(2)
+	<-updated
+	assert.False(IsDark())
+	assert.Equal("#ffffff", string(colors.Scheme("background")))
+	assert.Equal("#007700", string(colors.Scheme("good")))
+
+	Toggle()
+	<-updated
+	assert.True(IsDark())
+	assert.Equal("#ff0000", string(colors.Scheme("bad")))
+
+	notify(noPreference)
+	<-updated
+	assert.False(IsDark(), "no preference uses light scheme")
+}
+
+func TestWatchError(t *testing.T) {
+	assert := assert.New(t)
+	portal = testPortal{err: errors.New("no portal")}
+	Set(true)
+	assert.Error(Watch(lightScheme, darkScheme))
+	assert.False(IsDark())
+	assert.Equal("#ffffff", string(colors.Scheme("background")))
+}
+
+func TestUnwrap(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(uint32(1), unwrap(uint32(1)))
+	assert.Equal(uint32(1), unwrap(dbus.MakeVariant(uint32(1))))
+	assert.Equal(uint32(2), unwrap(dbus.MakeVariant(dbus.MakeVariant(uint32(2)))))
+	assert.Equal(uint32(0), unwrap(dbus.MakeVariant("dark")))
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// Space is a color space used for interpolation.
+type Space int
+
+// Supported interpolation spaces.
+const (
+	// SpaceRGB interpolates each of the red, green, and blue components.
+	// This is the default, but midpoints between distant colors can be
+	// darker and less saturated than either end.
+	SpaceRGB Space = iota
+	// SpaceLab interpolates in the perceptually uniform CIE L*a*b* space.
+	SpaceLab
+	// SpaceHCL interpolates hue, chroma, and lightness in CIE L*C*h° space,
+	// which keeps midpoints saturated, e.g. green to red passes through
+	// yellow instead of brown.
+	SpaceHCL
+)
+
+// Colormap maps values between 0 and 1 to colors, for continuous coloring
+// of values such as percentages, temperatures, or signal strength.
+type Colormap struct {
+	stops []colorful.Color
+	space Space
+}
+
+// Gradient constructs a colormap that interpolates between the given
+// colors, which are evenly spaced between 0 and 1. Invalid colors
+// are ignored.
+func Gradient(stops ...bar.Color) Colormap {
+	var c Colormap
+	for _, s := range stops {
+		if color, err := colorful.Hex(string(s)); err == nil {
+			c.stops = append(c.stops, color)
+		}
+	}
+	return c
+}
+
+// In returns a copy of the colormap that interpolates in the given space.
+func (c Colormap) In(space Space) Colormap {
+	c.space = space
+	return c
+}
+
+func (c Colormap) blend(c1, c2 colorful.Color, t float64) colorful.Color {
+	switch c.space {
+	case SpaceLab:
+		return c1.BlendLab(c2, t).Clamped()
+	case SpaceHCL:
+		return c1.BlendHcl(c2, t).Clamped()
+	}
+	return c1.BlendRgb(c2, t)
+}
+
+// At returns the color at position t, which is clamped between 0 and 1.
+func (c Colormap) At(t float64) bar.Color {
+	switch len(c.stops) {
+	case 0:
+		return Empty()
+	case 1:
+		return Colorful(c.stops[0])
+	}
+	if math.IsNaN(t) {
+		return Empty()
+	}
+	t = math.Max(0, math.Min(1, t))
+	scaled := t * float64(len(c.stops)-1)
+	idx := int(scaled)
+	if idx >= len(c.stops)-1 {
+		return Colorful(c.stops[len(c.stops)-1])
+	}
+	return Colorful(c.blend(c.stops[idx], c.stops[idx+1], scaled-float64(idx)))
+}
+
+// Viridis returns a colormap similar to matplotlib's viridis, from dark
+// purple through blue and green to yellow.
+func Viridis() Colormap {
+	return Gradient(
+		Hex("#440154"),
+		Hex("#3b528b"),
+		Hex("#21918c"),
+		Hex("#5ec962"),
+		Hex("#fde725"),
+	)
+}
+
+// GreenToRed returns a colormap from green through yellow to red, e.g.
+// for values where higher is worse.
+func GreenToRed() Colormap {
+	return Gradient(Hex("#00ff00"), Hex("#ffff00"), Hex("#ff0000"))
+}
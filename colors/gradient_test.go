@@ -0,0 +1,82 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+)
+
+func TestGradient(t *testing.T) {
+	assert := assert.New(t)
+
+	empty := Gradient()
+	assert.Equal(Empty(), empty.At(0.5))
+	assert.Equal(Empty(), Gradient(Hex("invalid")).At(0.5))
+
+	single := Gradient(Hex("#abcdef"))
+	assert.Equal(bar.Color("#abcdef"), single.At(0))
+	assert.Equal(bar.Color("#abcdef"), single.At(1))
+
+	g := Gradient(Hex("#000000"), bar.Color("invalid"), Hex("#ffffff"))
+	assert.Equal(bar.Color("#000000"), g.At(0))
+	assert.Equal(bar.Color("#808080"), g.At(0.5))
+	assert.Equal(bar.Color("#ffffff"), g.At(1))
+	assert.Equal(bar.Color("#000000"), g.At(-1), "clamped")
+	assert.Equal(bar.Color("#ffffff"), g.At(2), "clamped")
+	assert.Equal(Empty(), g.At(math.NaN()))
+
+	r := GreenToRed()
+	assert.Equal(bar.Color("#00ff00"), r.At(0))
+	assert.Equal(bar.Color("#ffff00"), r.At(0.5))
+	assert.Equal(bar.Color("#ff8000"), r.At(0.75))
+	assert.Equal(bar.Color("#ff0000"), r.At(1))
+
+	v := Viridis()
+	assert.Equal(bar.Color("#440154"), v.At(0))
+	assert.Equal(bar.Color("#21918c"), v.At(0.5))
+	assert.Equal(bar.Color("#fde725"), v.At(1))
+
+	lab := Gradient(Hex("#000000"), Hex("#ffffff")).In(SpaceLab)
+	assert.Equal(bar.Color("#000000"), lab.At(0))
+	assert.Equal(bar.Color("#ffffff"), lab.At(1))
+	assert.Equal(bar.Color("#777777"), lab.At(0.5), "perceptual midpoint")
+}
+
+func TestGradientSpaces(t *testing.T) {
+	assert := assert.New(t)
+	g := Gradient(Hex("#00ff00"), Hex("#ff0000"))
+	assert.Equal(bar.Color("#808000"), g.At(0.5), "muddy RGB midpoint")
+	assert.Equal(g.At(0.5), g.In(SpaceRGB).At(0.5))
+
+	hcl := g.In(SpaceHCL)
+	assert.Equal(bar.Color("#00ff00"), hcl.At(0))
+	assert.Equal(bar.Color("#ff0000"), hcl.At(1))
+	assert.Equal(bar.Color("#d7a600"), hcl.At(0.5))
+
+	rgbMid, _ := colorful.Hex(string(g.At(0.5)))
+	hclMid, _ := colorful.Hex(string(hcl.At(0.5)))
+	_, rgbC, rgbL := rgbMid.Hcl()
+	_, hclC, hclL := hclMid.Hcl()
+	assert.True(hclC > rgbC, "more saturated midpoint")
+	assert.True(hclL > rgbL, "brighter midpoint")
+
+	assert.Equal(SpaceRGB, g.space, "In returns a copy")
+}
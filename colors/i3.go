@@ -0,0 +1,164 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// The names of the colors in i3 client.* and bar workspace color lines,
+// in the order they are specified.
+var (
+	clientParts    = []string{"border", "background", "text", "indicator", "child_border"}
+	workspaceParts = []string{"border", "background", "text"}
+)
+
+// workspaceSchemes are the common scheme names that are set from the
+// text colors of the bar's workspace buttons.
+var workspaceSchemes = map[string]string{
+	"focused_workspace":  "focused",
+	"active_workspace":   "visible",
+	"inactive_workspace": "inactive",
+}
+
+// i3Hex parses an i3 color, which may include an alpha component.
+func i3Hex(value string) string {
+	if len(value) == 9 && value[0] == '#' {
+		return value[:7]
+	}
+	return value
+}
+
+// LoadFromI3Config loads a color scheme from an i3 or sway config file.
+//
+// Window colors (client.<class> lines) are loaded as "client.<class>.<part>",
+// e.g. "client.focused.border" or "client.urgent.text". Colors from the bar's
+// colors block are loaded using the same names as i3, e.g. "statusline" or
+// "separator", with workspace buttons loaded as "<type>.<part>", e.g.
+// "focused_workspace.background". The text colors of focused, active, and
+// inactive workspace buttons are also loaded as "focused", "visible", and
+// "inactive", which are used by the workspaces module. Variables defined
+// using "set" are substituted.
+func LoadFromI3Config(filename string) error {
+	f, err := fs.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	vars := map[string]string{}
+	loaded := map[string]bar.Color{}
+	defer setAll(loaded, false)
+	var blocks []string
+	s := bufio.NewScanner(f)
+	s.Split(bufio.ScanLines)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "}" {
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			continue
+		}
+		if fields[len(fields)-1] == "{" {
+			blocks = append(blocks, fields[0])
+			continue
+		}
+		if fields[0] == "set" && len(fields) >= 3 && strings.HasPrefix(fields[1], "$") {
+			vars[fields[1]] = fields[2]
+			continue
+		}
+		for i, field := range fields {
+			if v, ok := vars[field]; ok {
+				fields[i] = v
+			}
+		}
+		inBarColors := len(blocks) == 2 && blocks[0] == "bar" && blocks[1] == "colors"
+		switch {
+		case len(blocks) == 0 && strings.HasPrefix(fields[0], "client."):
+			parts := clientParts
+			if fields[0] == "client.background" {
+				parts = []string{""}
+			}
+			setI3Colors(loaded, fields[0], parts, fields[1:])
+		case inBarColors && strings.HasSuffix(fields[0], "_workspace"),
+			inBarColors && fields[0] == "binding_mode":
+			setI3Colors(loaded, fields[0], workspaceParts, fields[1:])
+			if name, ok := workspaceSchemes[fields[0]]; ok && len(fields) > 3 {
+				if color := Hex(i3Hex(fields[3])); color != "" {
+					loaded[name] = color
+				}
+			}
+		case inBarColors && len(fields) == 2:
+			setI3Colors(loaded, fields[0], []string{""}, fields[1:])
+		}
+	}
+	return s.Err()
+}
+
+// setI3Colors adds colors for each part of an i3 color line.
+func setI3Colors(loaded map[string]bar.Color, prefix string, parts []string, values []string) {
+	for i, part := range parts {
+		if i >= len(values) {
+			return
+		}
+		name := prefix
+		if part != "" {
+			name = prefix + "." + part
+		}
+		if color := Hex(i3Hex(values[i])); color != "" {
+			loaded[name] = color
+		}
+	}
+}
+
+// i3Configs returns the locations in which i3 and sway look for their
+// config files, in order.
+func i3Configs() []string {
+	home := os.Getenv("HOME")
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	return []string{
+		filepath.Join(configHome, "sway", "config"),
+		filepath.Join(home, ".sway", "config"),
+		filepath.Join(configHome, "i3", "config"),
+		filepath.Join(home, ".i3", "config"),
+	}
+}
+
+// LoadFromI3 loads a color scheme from the i3 or sway config file at its
+// default location, using the sway config when running under sway.
+func LoadFromI3() error {
+	configs := i3Configs()
+	if os.Getenv("SWAYSOCK") == "" {
+		configs = configs[2:]
+	}
+	var err error
+	for _, c := range configs {
+		if err = LoadFromI3Config(c); !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return err
+}
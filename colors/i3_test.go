@@ -0,0 +1,138 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+)
+
+const i3Config = `
+# i3 config file (v4)
+set $mod Mod4
+set $bg #285577
+set $urgent #900000ff
+
+font pango:DejaVu Sans Mono 8
+bindsym $mod+Return exec i3-sensible-terminal
+
+# class                 border  backgr. text    indicator child_border
+client.focused          #4c7899 $bg     #ffffff #2e9ef4   #285577
+client.unfocused        #333333 #222222 #888888
+client.urgent           #2f343a $urgent #ffffff #invalid
+client.background       #ffffff
+
+mode "resize" {
+	bindsym h resize shrink width 10 px or 10 ppt
+}
+
+bar {
+	status_command barista
+	colors {
+		background #000000
+		statusline #ffffff
+		separator  #666666cc
+
+		focused_workspace  #4c7899 $bg     #ffffff
+		active_workspace   #333333 #5f676a #eeeeee
+		inactive_workspace #333333 #222222 #888888
+		urgent_workspace   #2f343a $urgent #ffffff
+		binding_mode       #2f343a #900000
+	}
+}
+`
+
+func TestLoadFromI3Config(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "config", []byte(i3Config), 0644)
+	afero.WriteFile(fs, "empty", []byte{}, 0644)
+
+	scheme = map[string]bar.Color{}
+	assert.Error(t, LoadFromI3Config("non-existent"), "non-existent file")
+	assert.NoError(t, LoadFromI3Config("empty"))
+	assertSchemeEquals(t, map[string]string{}, "empty config")
+
+	assert.NoError(t, LoadFromI3Config("config"))
+	assertSchemeEquals(t, map[string]string{
+		"client.focused.border":       "#4c7899",
+		"client.focused.background":   "#285577",
+		"client.focused.text":         "#ffffff",
+		"client.focused.indicator":    "#2e9ef4",
+		"client.focused.child_border": "#285577",
+		"client.unfocused.border":     "#333333",
+		"client.unfocused.background": "#222222",
+		"client.unfocused.text":       "#888888",
+		"client.urgent.border":        "#2f343a",
+		"client.urgent.background":    "#900000",
+		"client.urgent.text":          "#ffffff",
+		"client.background":           "#ffffff",
+
+		"background": "#000000",
+		"statusline": "#ffffff",
+		"separator":  "#666666",
+
+		"focused_workspace.border":      "#4c7899",
+		"focused_workspace.background":  "#285577",
+		"focused_workspace.text":        "#ffffff",
+		"active_workspace.border":       "#333333",
+		"active_workspace.background":   "#5f676a",
+		"active_workspace.text":         "#eeeeee",
+		"inactive_workspace.border":     "#333333",
+		"inactive_workspace.background": "#222222",
+		"inactive_workspace.text":       "#888888",
+		"urgent_workspace.border":       "#2f343a",
+		"urgent_workspace.background":   "#900000",
+		"urgent_workspace.text":         "#ffffff",
+		"binding_mode.border":           "#2f343a",
+		"binding_mode.background":       "#900000",
+
+		"focused":  "#ffffff",
+		"visible":  "#eeeeee",
+		"inactive": "#888888",
+	}, "i3 config")
+}
+
+func TestLoadFromI3(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	os.Setenv("HOME", "/home/user")
+	os.Setenv("XDG_CONFIG_HOME", "")
+	os.Setenv("SWAYSOCK", "")
+
+	scheme = map[string]bar.Color{}
+	assert.True(t, os.IsNotExist(LoadFromI3()), "no config")
+
+	afero.WriteFile(fs, "/home/user/.i3/config",
+		[]byte("client.focused #111111 #222222 #333333"), 0644)
+	afero.WriteFile(fs, "/home/user/.config/sway/config",
+		[]byte("client.focused #aaaaaa #bbbbbb #cccccc"), 0644)
+	assert.NoError(t, LoadFromI3())
+	assert.Equal(t, "#111111", string(Scheme("client.focused.border")))
+
+	afero.WriteFile(fs, "/home/user/.config/i3/config",
+		[]byte("client.focused #444444 #555555 #666666"), 0644)
+	assert.NoError(t, LoadFromI3())
+	assert.Equal(t, "#444444", string(Scheme("client.focused.border")),
+		"prefers XDG config")
+
+	os.Setenv("SWAYSOCK", "/run/sway.sock")
+	assert.NoError(t, LoadFromI3())
+	assert.Equal(t, "#aaaaaa", string(Scheme("client.focused.border")),
+		"uses sway config under sway")
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+// The built-in palettes define the common "good", "degraded", and "bad"
+// colors, and can be loaded when initialising the color scheme, e.g.
+//  colors.LoadFromMap(colors.DeuteranopiaPalette())
+// The colorblind-safe palettes are based on the Okabe-Ito palette, and keep
+// the three states distinguishable by both hue and lightness.
+
+// DefaultPalette returns the i3status default colors: green, yellow, and red.
+func DefaultPalette() map[string]string {
+	return map[string]string{
+		"good":     "#00ff00",
+		"degraded": "#ffff00",
+		"bad":      "#ff0000",
+	}
+}
+
+// DeuteranopiaPalette returns colors that are distinguishable with
+// green-weak or green-blind (deuteranopia) vision: sky blue, yellow,
+// and vermillion.
+func DeuteranopiaPalette() map[string]string {
+	return map[string]string{
+		"good":     "#56b4e9",
+		"degraded": "#f0e442",
+		"bad":      "#d55e00",
+	}
+}
+
+// ProtanopiaPalette returns colors that are distinguishable with red-weak
+// or red-blind (protanopia) vision. Reds appear much darker with protanopia,
+// so a lighter orange is used for "bad" to keep it visible on dark bars.
+func ProtanopiaPalette() map[string]string {
+	return map[string]string{
+		"good":     "#56b4e9",
+		"degraded": "#f0e442",
+		"bad":      "#ff7f0e",
+	}
+}
+
+// TritanopiaPalette returns colors that are distinguishable with
+// blue-yellow colorblindness (tritanopia): bluish green, yellow, and
+// vermillion, which appear as teal, light pink, and red.
+func TritanopiaPalette() map[string]string {
+	return map[string]string{
+		"good":     "#009e73",
+		"degraded": "#f0e442",
+		"bad":      "#d55e00",
+	}
+}
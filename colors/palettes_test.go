@@ -0,0 +1,52 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+)
+
+func TestPalettes(t *testing.T) {
+	for name, palette := range map[string]func() map[string]string{
+		"default":      DefaultPalette,
+		"deuteranopia": DeuteranopiaPalette,
+		"protanopia":   ProtanopiaPalette,
+		"tritanopia":   TritanopiaPalette,
+	} {
+		scheme = map[string]bar.Color{}
+		LoadFromMap(palette())
+		var states []colorful.Color
+		for _, state := range []string{"good", "degraded", "bad"} {
+			c, err := colorful.Hex(string(Scheme(state)))
+			assert.NoError(t, err, "%s: %s", name, state)
+			states = append(states, c)
+		}
+		for i := range states {
+			for j := i + 1; j < len(states); j++ {
+				assert.True(t, states[i].DistanceLab(states[j]) > 0.2,
+					"%s: states %d and %d are distinct", name, i, j)
+			}
+		}
+	}
+
+	p := DeuteranopiaPalette()
+	p["good"] = "#000000"
+	assert.Equal(t, "#56b4e9", DeuteranopiaPalette()["good"], "returns a copy")
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// xrdbQuery returns the X resources loaded into the X server.
+var xrdbQuery = func() ([]byte, error) {
+	return exec.Command("xrdb", "-query").Output()
+}
+
+// xresourceRe matches resources that apply to all clients, e.g.
+// "*.color0", "*foreground", or "background", capturing the name.
+var xresourceRe = regexp.MustCompile(`^\*?\.?(color(?:[0-9]|1[0-5])|foreground|background)$`)
+
+// loadXresources reads X resources of the form name: value, and sets the
+// matching colors in the scheme. Lines starting with "!" are comments, and
+// simple #define macros are substituted in values.
+func loadXresources(r io.Reader) error {
+	defines := map[string]string{}
+	loaded := map[string]bar.Color{}
+	defer setAll(loaded, false)
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#define") {
+			fields := strings.Fields(line)
+			if len(fields) == 3 {
+				defines[fields[1]] = fields[2]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		match := xresourceRe.FindStringSubmatch(strings.TrimSpace(line[:idx]))
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(line[idx+1:])
+		if v, ok := defines[value]; ok {
+			value = v
+		}
+		if color := Hex(value); color != "" {
+			loaded[match[1]] = color
+		}
+	}
+	return s.Err()
+}
+
+// LoadFromXresources loads a color scheme from X resources, using the
+// resources loaded into the X server (from xrdb -query), or ~/.Xresources
+// if xrdb is not available. The terminal colors color0 to color15 are loaded
+// using the same names, along with "foreground" and "background". Only
+// resources that apply to all clients (e.g. "*.color0") are used.
+func LoadFromXresources() error {
+	if out, err := xrdbQuery(); err == nil {
+		return loadXresources(bytes.NewReader(out))
+	}
+	f, err := fs.Open(filepath.Join(os.Getenv("HOME"), ".Xresources"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return loadXresources(f)
+}
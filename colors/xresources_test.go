@@ -0,0 +1,74 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package colors
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+)
+
+func TestLoadFromXresources(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	os.Setenv("HOME", "/home/user")
+	xrdbQuery = func() ([]byte, error) {
+		return []byte(`*.color0:	#282828
+*.color1:	#cc241d
+*color9:	#fb4934
+*.foreground:	#ebdbb2
+*background:	#282828
+URxvt.background:	#000000
+*.color16:	#ffffff
+*.cursorColor:	#ebdbb2
+Xft.dpi:	96
+*.color2:	not-a-color
+`), nil
+	}
+	scheme = map[string]bar.Color{}
+	assert.NoError(t, LoadFromXresources())
+	assertSchemeEquals(t, map[string]string{
+		"color0":     "#282828",
+		"color1":     "#cc241d",
+		"color9":     "#fb4934",
+		"foreground": "#ebdbb2",
+		"background": "#282828",
+	}, "from xrdb")
+
+	xrdbQuery = func() ([]byte, error) {
+		return nil, errors.New("xrdb: Can't open display")
+	}
+	scheme = map[string]bar.Color{}
+	assert.Error(t, LoadFromXresources(), "no xrdb or file")
+
+	afero.WriteFile(fs, "/home/user/.Xresources", []byte(`
+! Gruvbox
+#define red #cc241d
+#define fg #ebdbb2
+
+*.foreground: fg
+*.color1:     red
+! *.color2:   #98971a
+`), 0644)
+	assert.NoError(t, LoadFromXresources())
+	assertSchemeEquals(t, map[string]string{
+		"color1":     "#cc241d",
+		"foreground": "#ebdbb2",
+	}, "from file")
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package builtin registers the modules in this repository with the config
+package, so that they can be used in config files. Import it for its side
+effects:
+
+	import _ "github.com/soumya92/barista/config/builtin"
+
+The registered module types and their options (in addition to the options
+common to all modules, see the config package) are:
+
+	battery: name (default all batteries combined)
+	clock: format (a Go time layout), timezone, granularity
+	counter: format, persist (a key to save the count under)
+	cpuload
+	cputemp: zone (default the first thermal zone)
+	diskspace: path (default /)
+	netspeed: interface
+	shell: command, and interval or tail (default run once), and limits:
+	  timeout, max_output (bytes), cpu_limit (a duration), memory_limit
+	  (bytes), and env (a list of NAME=value, or NAME to copy from the bar)
+	uptime
+	wlan: interface
+*/
+package builtin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/config"
+	"github.com/soumya92/barista/modules/battery"
+	"github.com/soumya92/barista/modules/clock"
+	"github.com/soumya92/barista/modules/counter"
+	"github.com/soumya92/barista/modules/cpuload"
+	"github.com/soumya92/barista/modules/cputemp"
+	"github.com/soumya92/barista/modules/diskspace"
+	"github.com/soumya92/barista/modules/netspeed"
+	"github.com/soumya92/barista/modules/shell"
+	"github.com/soumya92/barista/modules/uptime"
+	"github.com/soumya92/barista/modules/wlan"
+)
+
+func init() {
+	config.Register("battery", func(o *config.Options) (bar.Module, error) {
+		if name := o.String("name", ""); name != "" {
+			return battery.New(name), nil
+		}
+		return battery.Default(), nil
+	})
+	config.Register("clock", newClock)
+	config.Register("counter", func(o *config.Options) (bar.Module, error) {
+		c := counter.New(o.String("format", "%d"))
+		if key := o.String("persist", ""); key != "" {
+			c.Persist(key)
+		}
+		return c, nil
+	})
+	config.Register("cpuload", func(*config.Options) (bar.Module, error) {
+		return cpuload.New(), nil
+	})
+	config.Register("cputemp", func(o *config.Options) (bar.Module, error) {
+		if zone := o.String("zone", ""); zone != "" {
+			return cputemp.Zone(zone), nil
+		}
+		return cputemp.DefaultZone(), nil
+	})
+	config.Register("diskspace", func(o *config.Options) (bar.Module, error) {
+		return diskspace.New(o.String("path", "/")), nil
+	})
+	config.Register("netspeed", func(o *config.Options) (bar.Module, error) {
+		iface := o.String("interface", "")
+		if iface == "" {
+			return nil, errors.New("interface is required")
+		}
+		return netspeed.New(iface), nil
+	})
+	config.Register("shell", newShell)
+	config.Register("uptime", func(*config.Options) (bar.Module, error) {
+		return uptime.New(), nil
+	})
+	config.Register("wlan", func(o *config.Options) (bar.Module, error) {
+		iface := o.String("interface", "")
+		if iface == "" {
+			return nil, errors.New("interface is required")
+		}
+		return wlan.New(iface), nil
+	})
+}
+
+func newClock(o *config.Options) (bar.Module, error) {
+	c := clock.New()
+	if tz := o.String("timezone", ""); tz != "" {
+		// Checked here, since the module only shows an error on the bar.
+		if _, err := time.LoadLocation(tz); err != nil {
+			return nil, err
+		}
+		c.Timezone(tz)
+	}
+	if format := o.String("format", ""); format != "" {
+		c.OutputFormat(format)
+	}
+	if o.Has("granularity") {
+		c.Granularity(o.Duration("granularity", time.Second))
+	}
+	return c, nil
+}
+
+func newShell(o *config.Options) (bar.Module, error) {
+	command := o.Strings("command", nil)
+	if len(command) == 0 {
+		return nil, errors.New("command is required")
+	}
+	tail := o.Bool("tail", false)
+	interval := o.Duration("interval", 0)
+	var m shell.Module
+	switch {
+	case tail && interval > 0:
+		return nil, errors.New("only one of interval and tail can be set")
+	case tail:
+		m = shell.Tail(command[0], command[1:]...)
+	case interval > 0:
+		m = shell.Every(interval, command[0], command[1:]...)
+	default:
+		m = shell.Once(command[0], command[1:]...)
+	}
+	if timeout := o.Duration("timeout", 0); timeout > 0 {
+		m.Timeout(timeout)
+	}
+	if maxOutput := o.Int("max_output", 0); maxOutput > 0 {
+		m.MaxOutput(maxOutput)
+	}
+	if cpu := o.Duration("cpu_limit", 0); cpu > 0 {
+		m.CPULimit(cpu)
+	}
+	if memory := o.Int("memory_limit", 0); memory > 0 {
+		m.MemoryLimit(uint64(memory))
+	}
+	if o.Has("env") {
+		m.Env(o.Strings("env", nil)...)
+	}
+	return m, o.Err()
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builtin
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/config"
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+func TestRegistered(t *testing.T) {
+	assert.Equal(t, []string{
+		"battery", "clock", "counter", "cpuload", "cputemp",
+		"diskspace", "netspeed", "shell", "uptime", "wlan",
+	}, config.Registered())
+}
+
+func TestConfig(t *testing.T) {
+	b := testBar.New(t)
+	err := config.Apply(b.Bar, []byte(`
+modules:
+  - module: shell
+    command: [echo, hello]
+  - module: counter
+    format: "C%d"
+  - module: clock
+    timezone: UTC
+    format: "15:04"
+    granularity: 1m
+  - {module: diskspace, path: /, template: "{{.Total.Bytes}}"}
+  - {module: cpuload, refresh: 5s}
+  - {module: battery, name: BAT0}
+  - {module: cputemp, zone: thermal_zone1}
+  - {module: netspeed, interface: eth0, refresh: 1s}
+  - {module: wlan, interface: wlan0}
+  - {module: uptime, refresh: 1m}
+`))
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	// Commands run when the bar starts, so the first print may not have
+	// the output of the shell module yet.
+	out := b.NextOutput("on start")
+	for i := 0; i < 10 && out[0].Text() != "hello"; i++ {
+		out = b.NextOutput("shell output")
+	}
+	assert.Equal(t, "hello", out[0].Text())
+}
+
+func TestShellLimits(t *testing.T) {
+	b := testBar.New(t)
+	err := config.Apply(b.Bar, []byte(`
+modules:
+  - module: shell
+    command: [sh, -c, 'echo "$FOO$HOME"']
+    env: [FOO=limited]
+    timeout: 5s
+    max_output: 100
+    cpu_limit: 1s
+    memory_limit: 268435456
+`))
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"limited"}, "with restricted environment")
+}
+
+func TestErrors(t *testing.T) {
+	for cfg, message := range map[string]string{
+		"modules: [{module: clock, timezone: Nowhere/Invalid}]":             "unknown time zone",
+		"modules: [{module: netspeed}]":                                     "interface is required",
+		"modules: [{module: wlan}]":                                         "interface is required",
+		"modules: [{module: shell}]":                                        "command is required",
+		"modules: [{module: shell, command: ls, tail: true, interval: 1s}]": "only one of",
+		"modules: [{module: counter, refresh: 1s}]":                         "not supported",
+		"modules: [{module: shell, command: ls, timeout: soon}]":            "option timeout",
+	} {
+		err := config.Apply(testBar.New(t).Bar, []byte(cfg))
+		if assert.Error(t, err, cfg) {
+			assert.Contains(t, err.Error(), message, cfg)
+		}
+	}
+}
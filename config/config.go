@@ -0,0 +1,589 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package config builds a bar from a YAML config file, so that a bar can be
+assembled without writing any code, using the modules registered with the
+package (see the builtin package for the registrations of modules in this
+repository).
+
+A config file lists the modules in the order they appear on the bar, with
+the options for each. Modules can be grouped using any of the groups in
+modules/group. Bar-wide options control the layout ("separators",
+"spacing", "short_text" to derive short text for modules, and "max_width"
+to shorten modules progressively when the bar is wider than that many
+characters), the "order" of modules by id, the name of the "output" that the
+bar is for (see outputs below), and "suppress_signals".
+
+	separators: false
+	spacing: 12
+	short_text: true
+	max_width: 120
+	order: [time]
+	modules:
+	  - module: cpuload
+	    refresh: 5s
+	    template: "{{.Min1}}"
+	  - group: cycling
+	    rotate: 10s
+	    modules:
+	      - module: diskspace
+	        path: /
+	      - module: uptime
+	  - module: clock
+	    id: time
+	    format: "15:04"
+	    outputs: [DP-1]
+
+All modules support the following options, in addition to the options
+supported by their type:
+
+	id: a name for the module, for use in order.
+	outputs: the names of the outputs to show the module on (default all).
+	template: a text template for the output, if the module supports it.
+	pango_template: a pango template for the output, if the module supports it.
+	refresh: the refresh interval, if the module supports it.
+	on_click: a command to run when the module is clicked (see below).
+	tabular: if true, renders digits at a fixed width, so that changing
+	  numbers do not shift the rest of the bar (see reformat.Tabular).
+
+Click commands are run using "sh -c", detached from the bar (see
+click.Command). A single command is run on left clicks, or commands can be
+given for each button (left, middle, right, scroll_up, scroll_down, back,
+forward), either as a command line, or with a working "dir" and additional
+"env" variables:
+
+	modules:
+	  - module: clock
+	    on_click: gsimplecal
+	  - module: volume
+	    on_click:
+	      middle: pavucontrol
+	      right:
+	        command: "$TERMINAL -e alsamixer"
+	        dir: /tmp
+	        env: {TERMINAL: foot}
+
+Groups support "modules", "leading" and "trailing" text to decorate the
+group, "separators", "spacing" and "short_text" for the layout of its
+modules, "id" and "outputs", and type-specific options:
+
+	collapsing: "collapsed", and "button" texts when collapsed and expanded.
+	cycling: "rotate" interval, and "button" text for the next module.
+	switching: "remember" key, and "button" format for the selected index.
+	paged: "size", and "button" format for the current page and page count.
+	following: "revert" timeout.
+
+Third-party modules can be loaded from the bar-wide "plugins" option,
+either as Go plugins, which register their module types when loaded (see
+LoadPlugin), or as commands that serve a module type from a separate
+process (see RegisterCommand and ServePlugin):
+
+	plugins:
+	  - /usr/lib/barista/weather.so
+	  - module: stocks
+	    command: [barista-stocks, --serve]
+	modules:
+	  - module: stocks
+	    symbol: GOOG
+
+Options for modules served by a plugin process, including template and
+refresh, are sent to the process. A new process is started for each
+module, and stopped when the module is removed by a reload.
+
+A Reloader updates a running bar when the config file changes, or on
+SIGHUP, keeping the modules of unchanged entries so that their segments are
+not affected.
+
+Typical usage would be:
+
+	import _ "github.com/soumya92/barista/config/builtin"
+
+	func main() {
+		b, err := config.Load("/path/to/config.yaml")
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Fatal(b.Run())
+	}
+*/
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"reflect"
+	"sort"
+	textTemplate "text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/modules/click"
+	"github.com/soumya92/barista/modules/group"
+	"github.com/soumya92/barista/modules/reformat"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Load reads the config file at the given path, and returns a new bar on
+// standard I/O built from it.
+func Load(path string) (*bar.I3Bar, error) {
+	b := bar.New()
+	if _, err := NewReloader(b, path); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Apply configures an existing bar from the given config, adding all the
+// modules to it. It must be called before the bar is started.
+func Apply(b *bar.I3Bar, data []byte) error {
+	return (&Reloader{bar: b}).configure(data)
+}
+
+// parsed is a parsed config file.
+type parsed struct {
+	suppressSignals bool
+	output          string
+	layout          bar.Layout
+	order           []string
+	entries         []*Options
+	// The canonical form of each entry, used to find the entries that are
+	// unchanged when reloading.
+	keys []string
+}
+
+// parse parses and validates the bar-wide options of a config file.
+func parse(data []byte) (*parsed, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	top := NewOptions(normalize(values).(map[string]interface{}))
+	c := &parsed{
+		suppressSignals: top.Bool("suppress_signals", false),
+		output:          top.String("output", ""),
+		order:           top.Strings("order", nil),
+	}
+	c.layout, _ = layoutOptions(top)
+	c.layout = c.layout.MaxWidth(top.Int("max_width", 0))
+	if err := loadPlugins(top); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.entries, err = entryList(top, "modules"); err != nil {
+		return nil, err
+	}
+	if err := top.Err(); err != nil {
+		return nil, err
+	}
+	if err := top.unknown(); err != nil {
+		return nil, err
+	}
+	for _, entry := range c.entries {
+		key, err := json.Marshal(entry.values)
+		if err != nil {
+			return nil, err
+		}
+		c.keys = append(c.keys, string(key))
+	}
+	return c, nil
+}
+
+// normalize converts the maps decoded by yaml, which can have keys of any
+// type, into maps with string keys.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for key, item := range v {
+			out[fmt.Sprint(key)] = normalize(item)
+		}
+		return out
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	case nil:
+		return map[string]interface{}{}
+	}
+	return value
+}
+
+// entryList returns the list of module or group entries under the key.
+func entryList(o *Options, key string) ([]*Options, error) {
+	value, ok := o.get(key)
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a list of modules", key)
+	}
+	var entries []*Options
+	for i, item := range list {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s[%d]: expected a module, got %v", key, i, item)
+		}
+		entries = append(entries, NewOptions(values))
+	}
+	return entries, nil
+}
+
+// layoutOptions returns the layout from the separators, spacing, and
+// short_text options.
+func layoutOptions(o *Options) (bar.Layout, bool) {
+	layout := bar.Layout{}
+	set := false
+	if o.Has("separators") {
+		layout = layout.Separators(o.Bool("separators", true))
+		set = true
+	}
+	if o.Has("spacing") {
+		layout = layout.Spacing(o.Int("spacing", 0))
+		set = true
+	}
+	if o.Has("short_text") {
+		layout = layout.ShortText(o.Bool("short_text", false))
+		set = true
+	}
+	return layout, set
+}
+
+// placed is a module to add to the bar.
+type placed struct {
+	module  bar.Module
+	outputs []string
+	// The id of the module or group, set on its first module.
+	id string
+	// The module returned by the factory, if it needs to be closed when it
+	// is removed from the bar, e.g. to stop its updates (see base.Close),
+	// or a plugin process.
+	closer io.Closer
+}
+
+// close stops the module, since the bar does not stop removed modules.
+// Modules that cannot be closed are paused instead.
+func (p *placed) close() {
+	if p.closer != nil {
+		p.closer.Close()
+		return
+	}
+	if pausable, ok := p.module.(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// builder builds the modules from the entries in the config.
+type builder struct {
+	placed []*placed
+}
+
+// add builds the module or group for the entry, and adds its modules to
+// the list of modules. Modules are shown on the parent's outputs unless
+// they have their own.
+func (b *builder) add(o *Options, parentOutputs []string) error {
+	id := o.String("id", "")
+	outputNames := o.Strings("outputs", parentOutputs)
+	start := len(b.placed)
+	var err error
+	switch {
+	case o.Has("module") && o.Has("group"):
+		return fmt.Errorf("both module and group set")
+	case o.Has("module"):
+		err = b.module(o, outputNames)
+	case o.Has("group"):
+		err = b.group(o, outputNames)
+	default:
+		return fmt.Errorf("expected module or group")
+	}
+	if err != nil {
+		return err
+	}
+	if id != "" && len(b.placed) > start {
+		b.placed[start].id = id
+	}
+	return nil
+}
+
+func (b *builder) place(m bar.Module, outputNames []string) {
+	b.placed = append(b.placed, &placed{module: m, outputs: outputNames})
+}
+
+// module builds a module using its registered factory, and applies the
+// options common to all modules.
+func (b *builder) module(o *Options, outputNames []string) error {
+	moduleType := o.String("module", "")
+	f, ok := factory(moduleType)
+	if !ok {
+		return fmt.Errorf("unknown module %q", moduleType)
+	}
+	m, err := f(o)
+	closer, _ := m.(io.Closer)
+	tabular := o.Bool("tabular", false)
+	if err == nil {
+		err = o.Err()
+	}
+	if err == nil {
+		err = applyCommon(m, o)
+	}
+	if err == nil && tabular {
+		m = reformat.New(m, reformat.Tabular())
+	}
+	if err == nil {
+		m, err = clickActions(m, o)
+	}
+	if err == nil {
+		err = o.unknown()
+	}
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return fmt.Errorf("%s: %v", moduleType, err)
+	}
+	b.place(m, outputNames)
+	b.placed[len(b.placed)-1].closer = closer
+	return nil
+}
+
+// applyCommon applies the template and refresh options to modules that
+// support them. Each module type has its own Module interface, so the
+// methods are found by name.
+func applyCommon(m bar.Module, o *Options) error {
+	if tpl := o.String("template", ""); tpl != "" {
+		if _, err := textTemplate.New("text").Funcs(outputs.TemplateFuncs()).Parse(tpl); err != nil {
+			return err
+		}
+		if err := call(m, "OutputTemplate", outputs.TextTemplate(tpl)); err != nil {
+			return fmt.Errorf("template: %v", err)
+		}
+	}
+	if tpl := o.String("pango_template", ""); tpl != "" {
+		if _, err := htmlTemplate.New("pango").Funcs(outputs.TemplateFuncs()).Parse(tpl); err != nil {
+			return err
+		}
+		if err := call(m, "OutputTemplate", outputs.PangoTemplate(tpl)); err != nil {
+			return fmt.Errorf("pango_template: %v", err)
+		}
+	}
+	if o.Has("refresh") {
+		if err := call(m, "RefreshInterval", o.Duration("refresh", time.Minute)); err != nil {
+			return fmt.Errorf("refresh: %v", err)
+		}
+	}
+	return o.Err()
+}
+
+// buttons are the names of buttons for on_click.
+var buttons = map[string]bar.Button{
+	"left":        bar.ButtonLeft,
+	"middle":      bar.ButtonMiddle,
+	"right":       bar.ButtonRight,
+	"scroll_up":   bar.ScrollUp,
+	"scroll_down": bar.ScrollDown,
+	"back":        bar.ButtonBack,
+	"forward":     bar.ButtonForward,
+}
+
+// clickActions wraps the module to run the commands given by on_click.
+func clickActions(m bar.Module, o *Options) (bar.Module, error) {
+	value, ok := o.get("on_click")
+	if !ok {
+		return m, nil
+	}
+	actions, ok := value.(map[string]interface{})
+	if !ok {
+		actions = map[string]interface{}{"left": value}
+	}
+	var names []string
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	wrapped := click.Wrap(m)
+	for _, name := range names {
+		button, ok := buttons[name]
+		if !ok {
+			return nil, fmt.Errorf("on_click: unknown button %q", name)
+		}
+		cmd, err := clickCommand(actions[name])
+		if err != nil {
+			return nil, fmt.Errorf("on_click %s: %v", name, err)
+		}
+		wrapped.Run(cmd, button)
+	}
+	return wrapped, nil
+}
+
+// clickCommand returns the command for a command line, or for a map with
+// the command, dir, and env.
+func clickCommand(value interface{}) (click.Command, error) {
+	if commandLine, ok := value.(string); ok {
+		return click.Shell(commandLine), nil
+	}
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return click.Command{}, fmt.Errorf("expected a command, got %v", value)
+	}
+	o := NewOptions(values)
+	commandLine := o.String("command", "")
+	cmd := click.Shell(commandLine).InDir(o.String("dir", ""))
+	if env, ok := o.get("env"); ok {
+		vars, ok := env.(map[string]interface{})
+		if !ok {
+			return click.Command{}, fmt.Errorf("env: expected a map, got %v", env)
+		}
+		var keys []string
+		for key := range vars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			cmd = cmd.WithEnv(fmt.Sprintf("%s=%v", key, vars[key]))
+		}
+	}
+	if err := o.Err(); err != nil {
+		return click.Command{}, err
+	}
+	if commandLine == "" {
+		return click.Command{}, fmt.Errorf("command not set")
+	}
+	return cmd, o.unknown()
+}
+
+// call calls the named method of the module with the argument, converting
+// it to the parameter type, e.g. from outputs.TemplateFunc to a func.
+func call(m bar.Module, method string, arg interface{}) error {
+	fn := reflect.ValueOf(m).MethodByName(method)
+	if !fn.IsValid() || fn.Type().NumIn() != 1 {
+		return fmt.Errorf("not supported by this module")
+	}
+	value := reflect.ValueOf(arg)
+	if !value.Type().ConvertibleTo(fn.Type().In(0)) {
+		return fmt.Errorf("not supported by this module")
+	}
+	fn.Call([]reflect.Value{value.Convert(fn.Type().In(0))})
+	return nil
+}
+
+// group builds a group and its modules.
+func (b *builder) group(o *Options, outputNames []string) error {
+	groupType := o.String("group", "")
+	var g group.Group
+	var button bar.Module
+	switch groupType {
+	case "collapsing":
+		c := group.Collapsing()
+		if o.Bool("collapsed", false) {
+			c.Collapse()
+		}
+		texts := o.Strings("button", []string{"+", "-"})
+		if len(texts) != 2 {
+			return fmt.Errorf("collapsing: button: expected collapsed and expanded texts")
+		}
+		button = c.Button(outputs.Text(texts[0]), outputs.Text(texts[1]))
+		g = c
+	case "cycling":
+		c := group.Cycling()
+		if o.Has("rotate") {
+			c.RotateEvery(o.Duration("rotate", 0))
+		}
+		if o.Has("button") {
+			button = c.Button(outputs.Text(o.String("button", "")))
+		}
+		g = c
+	case "switching":
+		s := group.Switching()
+		if key := o.String("remember", ""); key != "" {
+			s.Remember(key)
+		}
+		format := o.String("button", "%d")
+		button = s.Button(func(i int) bar.Output { return outputs.Textf(format, i+1) })
+		g = s
+	case "paged":
+		p := group.Paged(o.Int("size", 4))
+		format := o.String("button", "%d/%d")
+		button = p.Button(func(page, count int) bar.Output {
+			return outputs.Textf(format, page+1, count)
+		})
+		g = p
+	case "following":
+		f := group.Following()
+		if o.Has("revert") {
+			f.RevertAfter(o.Duration("revert", 0))
+		}
+		g = f
+	default:
+		return fmt.Errorf("unknown group %q", groupType)
+	}
+	if layout, ok := layoutOptions(o); ok {
+		g = group.WithLayout(g, layout)
+	}
+	var decorated group.Decorated
+	if o.Has("leading") || o.Has("trailing") {
+		decorated = group.Decorate(g, text(o, "leading"), text(o, "trailing"))
+		g = decorated
+	}
+	entries, err := entryList(o, "modules")
+	if err == nil {
+		err = o.Err()
+	}
+	if err == nil {
+		err = o.unknown()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", groupType, err)
+	}
+
+	// Build the group's modules separately, so that they can be wrapped.
+	inner := &builder{}
+	for i, entry := range entries {
+		if err := inner.add(entry, outputNames); err != nil {
+			return fmt.Errorf("%s: modules[%d]: %v", groupType, i, err)
+		}
+	}
+	if decorated != nil {
+		b.place(decorated.Leading(), outputNames)
+	}
+	if button != nil {
+		b.place(button, outputNames)
+	}
+	for _, p := range inner.placed {
+		p.module = g.Add(p.module)
+		b.placed = append(b.placed, p)
+	}
+	if decorated != nil {
+		b.place(decorated.Trailing(), outputNames)
+	}
+	return nil
+}
+
+// text returns the output for a text option, or nil if it is not set.
+func text(o *Options, key string) bar.Output {
+	if !o.Has(key) {
+		return nil
+	}
+	return outputs.Text(o.String(key, ""))
+}
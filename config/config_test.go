@@ -0,0 +1,336 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+// textModule is a test module that shows a static text, and supports
+// templates and refresh intervals.
+type textModule struct {
+	*base.Base
+	mutex    sync.Mutex
+	text     string
+	template func(interface{}) bar.Output
+	interval time.Duration
+	updates  int
+}
+
+func (m *textModule) OutputTemplate(template func(interface{}) bar.Output) *textModule {
+	m.mutex.Lock()
+	m.template = template
+	m.mutex.Unlock()
+	m.Update()
+	return m
+}
+
+func (m *textModule) RefreshInterval(interval time.Duration) *textModule {
+	m.mutex.Lock()
+	m.interval = interval
+	m.mutex.Unlock()
+	return m
+}
+
+func (m *textModule) Interval() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.interval
+}
+
+func (m *textModule) Updates() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.updates
+}
+
+var lastText *textModule
+
+func init() {
+	Register("text", func(o *Options) (bar.Module, error) {
+		m := &textModule{Base: base.New(), text: o.String("text", "")}
+		m.OnUpdate(func() {
+			m.mutex.Lock()
+			m.updates++
+			text, template := m.text, m.template
+			m.mutex.Unlock()
+			if template != nil {
+				m.Output(template(text))
+			} else {
+				m.Output(outputs.Text(text))
+			}
+		})
+		lastText = m
+		return m, nil
+	})
+	Register("static", func(o *Options) (bar.Module, error) {
+		if o.Bool("fail", false) {
+			return nil, errors.New("failed")
+		}
+		m := base.New()
+		m.Output(outputs.Text(o.String("text", "")))
+		return m, nil
+	})
+}
+
+func apply(t *testing.T, config string) (*testBar.TestBar, error) {
+	b := testBar.New(t)
+	err := Apply(b.Bar, []byte(config))
+	return b, err
+}
+
+func TestModules(t *testing.T) {
+	b, err := apply(t, `
+modules:
+  - module: text
+    text: foo
+  - module: text
+    text: bar
+    template: "[{{.}}]"
+    refresh: 5s
+  - {module: static, text: baz}
+`)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"foo", "[bar]", "baz"}, "modules in order")
+	assert.Equal(t, 5*time.Second, lastText.Interval(), "refresh interval")
+	assert.Contains(t, Registered(), "text")
+}
+
+func TestBarOptions(t *testing.T) {
+	b, err := apply(t, `
+suppress_signals: true
+separators: false
+spacing: 4
+short_text: true
+max_width: 6
+output: DP-1
+order: [last, first]
+modules:
+  - {module: static, text: a, id: first}
+  - {module: static, text: b, outputs: [HDMI-1]}
+  - {module: static, text: "c: 3 days", outputs: DP-1}
+  - {module: static, text: d, id: last}
+`)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"d", "a", "3d"}, "ordered, filtered by output, and shortened")
+	out := b.LatestOutput()
+	assert.Equal(t, false, out[0]["separator"], "layout")
+	assert.Equal(t, 4.0, out[0]["separator_block_width"], "layout")
+}
+
+func TestGroups(t *testing.T) {
+	b, err := apply(t, `
+modules:
+  - group: collapsing
+    collapsed: true
+    button: [">", "<"]
+    modules:
+      - {module: static, text: hidden}
+  - group: collapsing
+    leading: "("
+    trailing: ")"
+    separators: false
+    modules:
+      - {module: static, text: shown}
+  - group: switching
+    button: "#%d"
+    modules:
+      - {module: static, text: first}
+      - group: following
+        modules:
+          - {module: static, text: second}
+  - group: paged
+    size: 1
+    modules:
+      - {module: static, text: page}
+  - group: cycling
+    rotate: 1m
+    button: next
+    id: cycle
+    modules:
+      - {module: static, text: one}
+      - {module: static, text: two}
+`)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{">", "(", "-", "shown", ")", "#1", "first", "1/1", "page", "next", "one"},
+		"groups with buttons and decorations")
+	b.Click(0)
+	b.AssertText([]string{"<", "hidden", "(", "-", "shown", ")", "#1", "first", "1/1", "page", "next", "one"},
+		"collapsing group expanded")
+}
+
+func TestErrors(t *testing.T) {
+	for config, message := range map[string]string{
+		"modules: [":                "yaml",
+		"modules: foo":              "expected a list of modules",
+		"modules: [foo]":            "expected a module",
+		"modules: [{}]":             "expected module or group",
+		"modules: [{module: nope}]": `unknown module "nope"`,
+		"modules: [{group: nope}]":  `unknown group "nope"`,
+		"modules: [{module: static, group: cycling}]":                 "both module and group",
+		"modules: [{module: static, fail: true}]":                     "failed",
+		"modules: [{module: static, txt: typo}]":                      "unknown options: txt",
+		"modules: [{module: static, template: foo}]":                  "template: not supported",
+		"modules: [{module: text, template: '{{'}]":                   "unclosed action",
+		"modules: [{module: text, refresh: soon}]":                    "expected a duration",
+		"modules: [{module: text, text: [a]}]":                        "expected a string",
+		"spacing: wide":                                               "expected an integer",
+		"modules: [{module: static}]\nfoo: bar":                       "unknown options: foo",
+		"order: [foo]":                                                `no module with id "foo"`,
+		"modules: [{group: cycling, modules: [{}]}]":                  "cycling: modules[0]: expected module or group",
+		"modules: [{group: collapsing, button: [a]}]":                 "expected collapsed and expanded texts",
+		"modules: [{group: paged, size: 1, pages: 2}]":                "paged: unknown options: pages",
+		"modules: [{module: static, id: a}, {module: static, id: a}]": `duplicate id "a"`,
+	} {
+		_, err := apply(t, config)
+		if assert.Error(t, err, config) {
+			assert.Contains(t, err.Error(), message, config)
+		}
+	}
+
+	_, err := Load("/nonexistent/config.yaml")
+	assert.Error(t, err, "missing file")
+}
+
+// waitForFile waits for a click command to write a file, and returns its
+// contents.
+func waitForFile(t *testing.T, path string) string {
+	for i := 0; i < 100; i++ {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "command did not run", path)
+	return ""
+}
+
+func TestClickActions(t *testing.T) {
+	dir := t.TempDir()
+	b, err := apply(t, fmt.Sprintf(`
+modules:
+  - module: static
+    text: left
+    on_click: echo left > %[1]s/left
+  - module: static
+    text: buttons
+    on_click:
+      right: echo $BARISTA_BUTTON > right
+      scroll_up:
+        command: echo $FOO > up
+        dir: %[1]s
+        env: {FOO: bar}
+`, dir))
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"left", "buttons"}, "wrapped modules shown")
+
+	b.Click(0)
+	assert.Equal(t, "left\n", waitForFile(t, filepath.Join(dir, "left")))
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	b.SendEvent(1, bar.Event{Button: bar.ButtonRight})
+	assert.Equal(t, "3\n", waitForFile(t, filepath.Join(home, "right")),
+		"runs in the home directory by default")
+	b.SendEvent(1, bar.Event{Button: bar.ScrollUp})
+	assert.Equal(t, "bar\n", waitForFile(t, filepath.Join(dir, "up")))
+}
+
+func TestTabular(t *testing.T) {
+	b, err := apply(t, `
+modules:
+  - module: static
+    text: "42%"
+    tabular: true
+  - module: static
+    text: "7%"
+`)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"<span font_features='tnum'>42%</span>", "7%"},
+		"only the tabular module is wrapped")
+
+	_, err = apply(t, "modules: [{module: static, tabular: yes please}]")
+	assert.Error(t, err, "invalid tabular option")
+}
+
+func TestClickActionErrors(t *testing.T) {
+	for onClick, message := range map[string]string{
+		"{top: ls}":                           `unknown button "top"`,
+		"[ls]":                                "expected a command",
+		"{left: {dir: /}}":                    "on_click left: command not set",
+		"{left: {command: ls, env: [a]}}":     "env: expected a map",
+		"{left: {command: ls, cwd: /}}":       "unknown options: cwd",
+		"{right: {command: ls, dir: [a, b]}}": "expected a string",
+	} {
+		config := "modules: [{module: static, on_click: " + onClick + "}]"
+		_, err := apply(t, config)
+		if assert.Error(t, err, config) {
+			assert.Contains(t, err.Error(), message, config)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	o := NewOptions(map[string]interface{}{
+		"str": "foo", "num": 42, "float": 1.5, "bool": true,
+		"list": []interface{}{"a", "b"}, "mixed": []interface{}{"a", 1},
+	})
+	assert.Equal(t, "foo", o.String("str", ""))
+	assert.Equal(t, "42", o.String("num", ""), "numbers as strings")
+	assert.Equal(t, "default", o.String("missing", "default"))
+	assert.Equal(t, 42, o.Int("num", 0))
+	assert.Equal(t, 1.5, o.Float("float", 0))
+	assert.Equal(t, 42.0, o.Float("num", 0))
+	assert.Equal(t, true, o.Bool("bool", false))
+	assert.Equal(t, []string{"a", "b"}, o.Strings("list", nil))
+	assert.Equal(t, []string{"foo"}, o.Strings("str", nil))
+	assert.True(t, o.Has("str"))
+	assert.False(t, o.Has("missing"))
+	assert.Nil(t, o.Err())
+	assert.Contains(t, o.unknown().Error(), "unknown options: mixed")
+
+	assert.Equal(t, []string{"x"}, o.Strings("mixed", []string{"x"}))
+	assert.Nil(t, o.unknown())
+	assert.Contains(t, o.Err().Error(), "mixed")
+	assert.Equal(t, 0, o.Int("str", 0))
+	assert.Contains(t, o.Err().Error(), "mixed", "first error is kept")
+
+	o = NewOptions(nil)
+	assert.Equal(t, time.Minute, o.Duration("missing", time.Minute))
+	assert.Nil(t, o.Err())
+}
@@ -0,0 +1,222 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// Factory constructs a module from the options given in the config file.
+// Factories should read all the options they support using the typed
+// getters, and return Options.Err() if any of them were invalid.
+type Factory func(*Options) (bar.Module, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]Factory{}
+)
+
+// Register registers a factory for the modules of the given type, e.g.
+// "clock", which can then be used in config files. Registering the same
+// type again replaces the factory.
+func Register(moduleType string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[moduleType] = factory
+}
+
+// Registered returns the sorted names of all registered module types.
+func Registered() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func factory(moduleType string) (Factory, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	f, ok := registry[moduleType]
+	return f, ok
+}
+
+// Options are the options for a module in the config file. The typed
+// getters return the default if the option is not set, and record an
+// error if it is set to a value of the wrong type. Options that are never
+// read are reported as unknown options, to catch typos in config files.
+type Options struct {
+	values map[string]interface{}
+	used   map[string]bool
+	err    error
+}
+
+// NewOptions creates options from the given values, e.g. for tests of
+// factories.
+func NewOptions(values map[string]interface{}) *Options {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return &Options{values: values, used: map[string]bool{}}
+}
+
+// Has returns true if the option is set.
+func (o *Options) Has(key string) bool {
+	o.used[key] = true
+	_, ok := o.values[key]
+	return ok
+}
+
+// Err returns the first error from reading options, if any.
+func (o *Options) Err() error {
+	return o.err
+}
+
+// unknown returns an error for any options that were set but not read.
+func (o *Options) unknown() error {
+	var keys []string
+	for key := range o.values {
+		if !o.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("unknown options: %s", strings.Join(keys, ", "))
+}
+
+func (o *Options) fail(key string, expected string, value interface{}) {
+	if o.err == nil {
+		o.err = fmt.Errorf("option %s: expected %s, got %v", key, expected, value)
+	}
+}
+
+func (o *Options) get(key string) (interface{}, bool) {
+	o.used[key] = true
+	value, ok := o.values[key]
+	return value, ok && value != nil
+}
+
+// String returns the string value of an option. Numbers and booleans are
+// also accepted, since YAML does not require quotes around strings.
+func (o *Options) String(key string, def string) string {
+	value, ok := o.get(key)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case int, float64, bool:
+		return fmt.Sprint(v)
+	}
+	o.fail(key, "a string", value)
+	return def
+}
+
+// Int returns the integer value of an option.
+func (o *Options) Int(key string, def int) int {
+	value, ok := o.get(key)
+	if !ok {
+		return def
+	}
+	if v, ok := value.(int); ok {
+		return v
+	}
+	o.fail(key, "an integer", value)
+	return def
+}
+
+// Float returns the numeric value of an option.
+func (o *Options) Float(key string, def float64) float64 {
+	value, ok := o.get(key)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	o.fail(key, "a number", value)
+	return def
+}
+
+// Bool returns the boolean value of an option.
+func (o *Options) Bool(key string, def bool) bool {
+	value, ok := o.get(key)
+	if !ok {
+		return def
+	}
+	if v, ok := value.(bool); ok {
+		return v
+	}
+	o.fail(key, "true or false", value)
+	return def
+}
+
+// Duration returns the value of an option as a duration, e.g. "5m" or
+// "1h30m". See time.ParseDuration for the format.
+func (o *Options) Duration(key string, def time.Duration) time.Duration {
+	value, ok := o.get(key)
+	if !ok {
+		return def
+	}
+	if v, ok := value.(string); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	o.fail(key, "a duration (e.g. 30s)", value)
+	return def
+}
+
+// Strings returns the value of an option as a list of strings. A single
+// string is treated as a list with one item.
+func (o *Options) Strings(key string, def []string) []string {
+	value, ok := o.get(key)
+	if !ok {
+		return def
+	}
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				o.fail(key, "a list of strings", value)
+				return def
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	o.fail(key, "a list of strings", value)
+	return def
+}
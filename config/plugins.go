@@ -0,0 +1,350 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/remote"
+)
+
+// LoadPlugin loads a Go plugin (built with -buildmode=plugin), which should
+// register its module types using Register from an init function. Plugins
+// must be built with the same version of Go and of this repository as the
+// bar. Loading the same plugin again has no effect.
+func LoadPlugin(path string) error {
+	_, err := plugin.Open(path)
+	return err
+}
+
+// Environment variables used for the handshake with plugin processes.
+const (
+	pluginEnv        = "BARISTA_PLUGIN"
+	pluginOptionsEnv = "BARISTA_PLUGIN_OPTIONS"
+	pluginVersion    = "1"
+)
+
+// pluginTimeout is how long to wait for a plugin process to start serving,
+// and for it to exit after being closed.
+var pluginTimeout = 10 * time.Second
+
+// pluginOptions are the common options that are handled by the plugin
+// process rather than the bar, since templates cannot be sent to it.
+var pluginOptions = []string{"template", "pango_template", "refresh"}
+
+// RegisterCommand registers a module type that runs in a separate process,
+// so that third-party modules can be used without rebuilding the bar. The
+// command is started for each module of the type, and should call
+// ServePlugin. If the process exits, the module shows an error.
+func RegisterCommand(moduleType string, command ...string) {
+	Register(moduleType, func(o *Options) (bar.Module, error) {
+		values := map[string]interface{}{}
+		for key, value := range o.values {
+			if !commonOption(key) {
+				o.used[key] = true
+				values[key] = value
+			}
+		}
+		for _, key := range pluginOptions {
+			if value, ok := o.values[key]; ok {
+				values[key] = value
+				delete(o.values, key)
+			}
+		}
+		return startPlugin(command, values)
+	})
+}
+
+// commonOption returns true for options handled by the bar for all modules.
+func commonOption(key string) bool {
+	switch key {
+	case "module", "id", "outputs", "on_click", "tabular":
+		return true
+	}
+	return false
+}
+
+// pluginModule is a module served by a plugin process.
+type pluginModule struct {
+	base.Module
+	name  string
+	cmd   *exec.Cmd
+	stdin io.Closer
+	// exited is closed when the plugin process exits, after err is set to
+	// its exit status.
+	exited chan struct{}
+	err    error
+	// closed is closed when the module is removed from the bar, so that the
+	// exit of the plugin process is expected.
+	closed chan struct{}
+}
+
+// wait waits for the plugin process to exit.
+func (p *pluginModule) wait() {
+	p.err = p.cmd.Wait()
+	close(p.exited)
+}
+
+// Stream passes through the output from the plugin process, until it exits
+// and the exit status is shown instead.
+func (p *pluginModule) Stream() <-chan bar.Output {
+	out := make(chan bar.Output)
+	go p.pipe(p.Module.Stream(), out)
+	return out
+}
+
+func (p *pluginModule) pipe(in <-chan bar.Output, out chan<- bar.Output) {
+	for {
+		select {
+		case o := <-in:
+			out <- o
+		case <-p.exited:
+			select {
+			case <-p.closed:
+			default:
+				err := p.err
+				if err == nil {
+					err = errors.New("exited")
+				}
+				out <- outputs.Errorf("plugin %s: %v", p.name, err)
+			}
+			// Connection errors from the remote module are not shown
+			// since the process is gone.
+			for range in {
+			}
+			return
+		}
+	}
+}
+
+// Close stops the plugin process when the module is removed from the bar.
+// Plugins exit when their standard input is closed, and are killed if they
+// do not exit in time.
+func (p *pluginModule) Close() error {
+	close(p.closed)
+	p.stdin.Close()
+	go func() {
+		select {
+		case <-p.exited:
+		case <-time.After(pluginTimeout):
+			p.cmd.Process.Kill()
+		}
+	}()
+	return nil
+}
+
+// startPlugin starts a plugin process, and connects to the module it serves.
+func startPlugin(command []string, values map[string]interface{}) (bar.Module, error) {
+	if len(command) == 0 {
+		return nil, errors.New("plugin: no command")
+	}
+	options, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(),
+		pluginEnv+"="+pluginVersion,
+		pluginOptionsEnv+"="+string(options))
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	network, address, err := handshake(stdout)
+	if err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("plugin %s: %v", command[0], err)
+	}
+	// Anything else the plugin prints is discarded, so that it does not
+	// block on a full pipe.
+	go io.Copy(io.Discard, stdout)
+	p := &pluginModule{
+		Module: remote.Dial(network, address),
+		name:   command[0],
+		cmd:    cmd,
+		stdin:  stdin,
+		exited: make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go p.wait()
+	return p, nil
+}
+
+// handshake reads the first line printed by a plugin process, which is
+// "barista-plugin|<version>|<network>|<address>", or
+// "barista-plugin|<version>|error|<message>" if the module could not be built.
+func handshake(stdout io.Reader) (network, address string, err error) {
+	line := make(chan string, 1)
+	failed := make(chan error, 1)
+	go func() {
+		l, err := bufio.NewReader(stdout).ReadString('\n')
+		if err != nil {
+			failed <- err
+			return
+		}
+		line <- l
+	}()
+	var l string
+	select {
+	case l = <-line:
+	case err := <-failed:
+		return "", "", fmt.Errorf("no handshake: %v", err)
+	case <-time.After(pluginTimeout):
+		return "", "", errors.New("timed out waiting for handshake")
+	}
+	parts := strings.SplitN(strings.TrimSpace(l), "|", 4)
+	if len(parts) != 4 || parts[0] != "barista-plugin" {
+		return "", "", fmt.Errorf("invalid handshake %q", l)
+	}
+	if parts[1] != pluginVersion {
+		return "", "", fmt.Errorf("unsupported plugin version %s", parts[1])
+	}
+	if parts[2] == "error" {
+		return "", "", errors.New(parts[3])
+	}
+	return parts[2], parts[3], nil
+}
+
+// ServePlugin serves a module built by the factory, for a plugin process
+// started by a bar using RegisterCommand. The factory receives the options
+// from the bar's config file, and the common template and refresh options
+// are applied to the module. It returns once the bar closes the plugin, or
+// an error if the process was not started by a bar.
+//
+//	func main() {
+//		err := config.ServePlugin(func(o *config.Options) (bar.Module, error) {
+//			return mymodule.New(o.String("name", "")), o.Err()
+//		})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func ServePlugin(factory Factory) error {
+	if os.Getenv(pluginEnv) != pluginVersion {
+		return errors.New("plugin: not started by a bar")
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(os.Getenv(pluginOptionsEnv)), &values); err != nil {
+		return fmt.Errorf("plugin: invalid options: %v", err)
+	}
+	o := NewOptions(values)
+	m, err := factory(o)
+	if err == nil {
+		err = o.Err()
+	}
+	if err == nil {
+		err = applyCommon(m, o)
+	}
+	if err == nil {
+		err = o.unknown()
+	}
+	var dir string
+	if err == nil {
+		dir, err = os.MkdirTemp("", "barista-plugin")
+	}
+	var l net.Listener
+	if err == nil {
+		defer os.RemoveAll(dir)
+		l, err = net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	}
+	if err != nil {
+		// Report the error to the bar, so that it is shown in the config
+		// error instead of a missing handshake.
+		msg := strings.Replace(err.Error(), "\n", " ", -1)
+		fmt.Fprintf(pluginStdout, "barista-plugin|%s|error|%s\n", pluginVersion, msg)
+		return err
+	}
+	fmt.Fprintf(pluginStdout, "barista-plugin|%s|unix|%s\n", pluginVersion, l.Addr())
+	go func() {
+		// The bar closes standard input to stop the plugin.
+		io.Copy(io.Discard, pluginStdin)
+		l.Close()
+	}()
+	remote.Serve(l, m)
+	return nil
+}
+
+// pluginStdin and pluginStdout are used for the handshake by ServePlugin,
+// and can be replaced in tests.
+var pluginStdin io.Reader = os.Stdin
+var pluginStdout io.Writer = os.Stdout
+
+// loadPlugins loads the plugins listed in the bar-wide "plugins" option,
+// which are either paths to Go plugins, or module types with the command
+// for a plugin process:
+//
+//	plugins:
+//	  - /usr/lib/barista/weather.so
+//	  - module: stocks
+//	    command: [barista-stocks, --serve]
+func loadPlugins(top *Options) error {
+	value, ok := top.get("plugins")
+	if !ok {
+		return nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("plugins: expected a list of plugins")
+	}
+	for i, item := range list {
+		switch v := item.(type) {
+		case string:
+			if err := LoadPlugin(v); err != nil {
+				return fmt.Errorf("plugins[%d]: %v", i, err)
+			}
+		case map[string]interface{}:
+			o := NewOptions(v)
+			moduleType := o.String("module", "")
+			command := o.Strings("command", nil)
+			if err := o.Err(); err != nil {
+				return fmt.Errorf("plugins[%d]: %v", i, err)
+			}
+			if err := o.unknown(); err != nil {
+				return fmt.Errorf("plugins[%d]: %v", i, err)
+			}
+			if moduleType == "" || len(command) == 0 {
+				return fmt.Errorf("plugins[%d]: expected module and command", i)
+			}
+			RegisterCommand(moduleType, command...)
+		default:
+			return fmt.Errorf("plugins[%d]: expected a path or a command, got %v", i, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+// TestPluginProcess is not a real test, but serves the text module when the
+// test binary is started as a plugin process.
+func TestPluginProcess(t *testing.T) {
+	if os.Getenv(pluginEnv) == "" {
+		return
+	}
+	f, _ := factory("text")
+	if os.Getenv("CONFIG_TEST_PLUGIN_CRASH") != "" {
+		go ServePlugin(f)
+		time.Sleep(500 * time.Millisecond)
+		os.Exit(3)
+	}
+	err := ServePlugin(f)
+	if exited := os.Getenv("CONFIG_TEST_PLUGIN_EXITED"); exited != "" {
+		os.WriteFile(exited, []byte(fmt.Sprint(err)), 0644)
+	}
+	os.Exit(0)
+}
+
+func pluginCommand() string {
+	return fmt.Sprintf("[%q, -test.run=TestPluginProcess]", os.Args[0])
+}
+
+func TestPlugins(t *testing.T) {
+	exited := filepath.Join(t.TempDir(), "exited")
+	t.Setenv("CONFIG_TEST_PLUGIN_EXITED", exited)
+	path, cleanup := tempConfig(t, `
+plugins:
+  - module: remote-text
+    command: `+pluginCommand()+`
+modules:
+  - {module: remote-text, text: a, template: "<{{.}}>", id: x}
+`)
+	defer cleanup()
+	b := testBar.New(t)
+	r, err := NewReloader(b.Bar, path)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"<a>"}, "output from plugin process")
+	assert.Contains(t, Registered(), "remote-text")
+
+	writeConfig(t, path, `modules: []`)
+	assert.Nil(t, r.Reload())
+	b.AssertText([]string{}, "plugin module removed")
+	for i := 0; i < 100; i++ {
+		if result, _ := os.ReadFile(exited); len(result) > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	result, err := os.ReadFile(exited)
+	assert.Nil(t, err, "plugin process exits when removed")
+	assert.Equal(t, "<nil>", string(result))
+}
+
+func TestPluginExit(t *testing.T) {
+	t.Setenv("CONFIG_TEST_PLUGIN_CRASH", "1")
+	b := testBar.New(t)
+	assert.Nil(t, Apply(b.Bar, []byte(`
+plugins:
+  - module: crashing-text
+    command: `+pluginCommand()+`
+modules:
+  - {module: crashing-text, text: a}
+`)))
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"a"}, "output from plugin process")
+	b.AssertText([]string{fmt.Sprintf("plugin %s: exit status 3", os.Args[0])},
+		"exit status shown when the plugin exits")
+}
+
+func TestPluginErrors(t *testing.T) {
+	for _, tc := range []struct{ desc, config, err string }{
+		{"not a list", `plugins: foo`, "expected a list"},
+		{"invalid plugin", `plugins: [1]`, "expected a path or a command"},
+		{"missing file", `plugins: [/does/not/exist.so]`, "plugins[0]"},
+		{"missing command", `plugins: [{module: foo}]`, "expected module and command"},
+		{"unknown options", `plugins: [{module: foo, command: x, args: y}]`, "unknown options: args"},
+		{"no handshake", `
+plugins: [{module: bad-plugin, command: "true"}]
+modules: [{module: bad-plugin}]`, "no handshake"},
+		{"invalid handshake", `
+plugins: [{module: bad-plugin, command: [echo, hello]}]
+modules: [{module: bad-plugin}]`, `invalid handshake "hello\n"`},
+		{"plugin error", `
+plugins: [{module: bad-plugin, command: ` + pluginCommand() + `}]
+modules: [{module: bad-plugin, foo: bar}]`, "unknown options: foo"},
+	} {
+		err := Apply(testBar.New(t).Bar, []byte(tc.config))
+		if assert.Error(t, err, tc.desc) {
+			assert.Contains(t, err.Error(), tc.err, tc.desc)
+		}
+	}
+	assert.Error(t, ServePlugin(nil), "when not started by a bar")
+}
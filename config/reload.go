@@ -0,0 +1,310 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("config")
+
+// Reloader rebuilds a bar from its config file when the file changes, while
+// the bar is running. Entries that are unchanged keep their modules, so only
+// the segments of added, removed, or changed entries are affected. The
+// "output" and "suppress_signals" options only take effect on restart.
+type Reloader struct {
+	bar  *bar.I3Bar
+	path string
+
+	mutex  sync.Mutex
+	layout bar.Layout
+	// The modules built for each top-level entry of the config.
+	entries []*entry
+	// The modules in the order they are shown on the bar.
+	shown []*placed
+}
+
+// entry is a top-level entry in the config, and the modules built for it.
+type entry struct {
+	key    string
+	placed []*placed
+}
+
+// NewReloader configures an existing bar from the config file at the given
+// path, like Apply, and returns a Reloader that can update the bar when the
+// file changes.
+func NewReloader(b *bar.I3Bar, path string) (*Reloader, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reloader{bar: b, path: path}
+	if err := r.configure(data); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// configure applies the config to the bar, including the options that can
+// only be set before the bar is started.
+func (r *Reloader) configure(data []byte) error {
+	c, err := parse(data)
+	if err != nil {
+		return err
+	}
+	r.bar.SuppressSignals(c.suppressSignals)
+	if c.output != "" {
+		r.bar.Output(c.output)
+	}
+	return r.apply(c)
+}
+
+// Reload reads the config file again, and updates the bar to match it. If
+// the config file has any errors, the bar is left unchanged.
+func (r *Reloader) Reload() error {
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	c, err := parse(data)
+	if err == nil {
+		err = r.apply(c)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", r.path, err)
+	}
+	return nil
+}
+
+// reloadDelay is how long to wait after the config file changes before
+// reloading it, since editors often save files in multiple steps.
+var reloadDelay = 100 * time.Millisecond
+
+// Watch reloads the config file whenever it changes, or the process receives
+// SIGHUP, until the returned stop function is called. The result of each
+// reload is passed to onReload, if not nil, e.g. to log errors. The stop
+// function must not be called from onReload.
+func (r *Reloader) Watch(onReload func(error)) (stop func(), err error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory, since editors often replace the file instead of
+	// writing to it, which would end a watch on the file itself.
+	if err := w.Add(filepath.Dir(r.path)); err != nil {
+		w.Close()
+		return nil, err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	delay := reloadDelay
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	reload := func() {
+		err := r.Reload()
+		if err != nil {
+			log.Error("reload failed", "error", err)
+		}
+		if onReload != nil {
+			onReload(err)
+		}
+	}
+	go func() {
+		defer close(stopped)
+		defer w.Close()
+		defer signal.Stop(signals)
+		var pending <-chan time.Time
+		for {
+			select {
+			case e := <-w.Events:
+				if filepath.Clean(e.Name) == filepath.Clean(r.path) &&
+					e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					pending = time.After(delay)
+				}
+			case <-pending:
+				pending = nil
+				reload()
+			case <-signals:
+				reload()
+			case err := <-w.Errors:
+				if onReload != nil {
+					onReload(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}, nil
+}
+
+// apply updates the bar to match the config, reusing the modules of any
+// entries that have not changed since the last time it was applied. All the
+// modules are built before the bar is changed, so that the bar is left
+// unchanged if any entry has errors.
+func (r *Reloader) apply(c *parsed) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	unused := append([]*entry(nil), r.entries...)
+	var entries []*entry
+	var all, built []*placed
+	// Modules built for a config that is not applied are closed, e.g. to
+	// stop any plugin processes started for them.
+	discard := func() {
+		for _, p := range built {
+			p.close()
+		}
+	}
+	for i, options := range c.entries {
+		e := take(&unused, c.keys[i])
+		if e == nil {
+			builder := &builder{}
+			err := builder.add(options, nil)
+			built = append(built, builder.placed...)
+			if err != nil {
+				discard()
+				return fmt.Errorf("modules[%d]: %v", i, err)
+			}
+			e = &entry{key: c.keys[i], placed: builder.placed}
+		}
+		entries = append(entries, e)
+		all = append(all, e.placed...)
+	}
+	target, err := arrange(all, c.order)
+	if err != nil {
+		discard()
+		return err
+	}
+
+	for _, e := range unused {
+		for _, p := range e.placed {
+			r.bar.Remove(p.module)
+			p.close()
+		}
+	}
+	index := map[*placed]int{}
+	for i, p := range all {
+		index[p] = i
+	}
+	var current []int
+	for _, p := range r.shown {
+		if i, ok := index[p]; ok {
+			current = append(current, i)
+			delete(index, p)
+		}
+	}
+	// Anything left in the index is new, and is added at the end of the bar.
+	for i, p := range all {
+		if _, added := index[p]; !added {
+			continue
+		}
+		if len(p.outputs) > 0 {
+			r.bar.AddTo(p.outputs, p.module)
+		} else {
+			r.bar.Add(p.module)
+		}
+		current = append(current, i)
+	}
+	if c.layout != r.layout {
+		r.bar.Layout(c.layout)
+	}
+	// Only move the modules that are out of place, so that the bar is not
+	// reprinted needlessly.
+	for position, i := range target {
+		if current[position] != i {
+			r.bar.Move(all[i].module, position)
+			current = move(current, i, position)
+		}
+	}
+
+	log.Info("applied", "modules", len(all), "unchanged", len(r.entries)-len(unused))
+	r.layout = c.layout
+	r.entries = entries
+	r.shown = nil
+	for _, i := range target {
+		r.shown = append(r.shown, all[i])
+	}
+	return nil
+}
+
+// take removes and returns the first entry with the given key from the list,
+// or nil if there is no such entry.
+func take(entries *[]*entry, key string) *entry {
+	for i, e := range *entries {
+		if e.key == key {
+			*entries = append((*entries)[:i], (*entries)[i+1:]...)
+			return e
+		}
+	}
+	return nil
+}
+
+// arrange returns the order in which the modules are shown on the bar, as
+// indexes into the list of modules, after moving the modules with the ids
+// in order to the front.
+func arrange(all []*placed, order []string) ([]int, error) {
+	positions := make([]int, len(all))
+	ids := map[string]int{}
+	for i, p := range all {
+		positions[i] = i
+		if p.id == "" {
+			continue
+		}
+		if _, exists := ids[p.id]; exists {
+			return nil, fmt.Errorf("duplicate id %q", p.id)
+		}
+		ids[p.id] = i
+	}
+	for position, id := range order {
+		i, ok := ids[id]
+		if !ok {
+			return nil, fmt.Errorf("order: no module with id %q", id)
+		}
+		positions = move(positions, i, position)
+	}
+	return positions, nil
+}
+
+// move moves the value to the given position in the list, the same way
+// bar.Move moves modules.
+func move(list []int, value, position int) []int {
+	for i, v := range list {
+		if v == value {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if position > len(list) {
+		position = len(list)
+	}
+	return append(list[:position], append([]int{value}, list[position:]...)...)
+}
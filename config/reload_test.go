@@ -0,0 +1,172 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+func writeConfig(t *testing.T, path, config string) {
+	assert.Nil(t, ioutil.WriteFile(path, []byte(config), 0644))
+}
+
+func tempConfig(t *testing.T, config string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, "config.yaml")
+	writeConfig(t, path, config)
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// drain consumes updates until the bar stops printing, since the bar is
+// reprinted asynchronously after each change.
+func drain(b *testBar.TestBar) {
+	for {
+		time.Sleep(10 * time.Millisecond)
+		if _, ok := b.PendingOutput(); !ok {
+			return
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	path, cleanup := tempConfig(t, `
+modules:
+  - {module: static, text: a}
+  - {module: text, text: b}
+  - {module: static, text: c}
+`)
+	defer cleanup()
+	b := testBar.New(t)
+	r, err := NewReloader(b.Bar, path)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"a", "b", "c"}, "initial config")
+	text := lastText
+
+	drain(b)
+	assert.Nil(t, r.Reload(), "reloading an unchanged config")
+	b.AssertNoOutput("unchanged config does not reprint the bar")
+
+	writeConfig(t, path, `
+separators: false
+modules:
+  - {module: static, text: e}
+  - {module: text, text: b}
+  - {module: static, text: d}
+`)
+	assert.Nil(t, r.Reload())
+	b.AssertText([]string{"e", "b", "d"}, "modules added, removed, and changed")
+	assert.True(t, text == lastText, "unchanged module is not rebuilt")
+	assert.Equal(t, false, b.LatestOutput()[0]["separator"], "layout changed")
+
+	writeConfig(t, path, `
+order: [first]
+modules:
+  - {module: text, text: b}
+  - {module: static, text: d}
+  - {module: static, text: f, id: first}
+`)
+	assert.Nil(t, r.Reload())
+	b.AssertText([]string{"f", "b", "d"}, "reordered and changed")
+	assert.True(t, text == lastText, "moved module is not rebuilt")
+	assert.Nil(t, b.LatestOutput()[0]["separator"], "layout reset")
+
+	writeConfig(t, path, `
+modules:
+  - {module: static, text: d}
+`)
+	assert.Nil(t, r.Reload())
+	b.AssertText([]string{"d"}, "module removed")
+	updates := text.Updates()
+	text.Update()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, updates, text.Updates(), "removed module is stopped")
+
+	drain(b)
+	writeConfig(t, path, "modules: [{module: nope}]")
+	err = r.Reload()
+	if assert.Error(t, err, "invalid config") {
+		assert.Contains(t, err.Error(), path, "error includes the path")
+	}
+	b.AssertNoOutput("bar unchanged by invalid config")
+
+	os.Remove(path)
+	assert.Error(t, r.Reload(), "missing config")
+
+	_, err = NewReloader(b.Bar, path)
+	assert.Error(t, err, "missing config")
+}
+
+func TestWatch(t *testing.T) {
+	oldDelay := reloadDelay
+	defer func() { reloadDelay = oldDelay }()
+	reloadDelay = time.Millisecond
+
+	path, cleanup := tempConfig(t, "modules: [{module: static, text: a}]")
+	defer cleanup()
+	b := testBar.New(t)
+	r, err := NewReloader(b.Bar, path)
+	assert.Nil(t, err)
+	b.Start()
+	defer b.Close()
+	b.AssertText([]string{"a"}, "initial config")
+
+	reloads := make(chan error, 10)
+	stop, err := r.Watch(func(err error) { reloads <- err })
+	assert.Nil(t, err)
+	defer stop()
+
+	writeConfig(t, path, "modules: [{module: static, text: b}]")
+	select {
+	case err := <-reloads:
+		assert.Nil(t, err, "reloaded on change")
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected a reload when the file changes")
+	}
+	b.AssertText([]string{"b"}, "reloaded on change")
+
+	// Write the file without a change notification, e.g. over a network
+	// file system, and reload using the signal instead.
+	stop()
+	stop, err = r.Watch(func(err error) { reloads <- err })
+	assert.Nil(t, err)
+	for len(reloads) > 0 {
+		<-reloads
+	}
+	writeConfig(t, path, "modules: [{module: static, text: c}]")
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	select {
+	case err := <-reloads:
+		assert.Nil(t, err, "reloaded on SIGHUP")
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected a reload on SIGHUP")
+	}
+	b.AssertText([]string{"c"}, "reloaded on SIGHUP")
+	stop()
+
+	_, err = (&Reloader{path: "/nonexistent/config.yaml"}).Watch(nil)
+	assert.Error(t, err, "watching a missing directory")
+}
@@ -0,0 +1,429 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package dbusapi publishes the state of a bar on the session bus, so that
+scripts, rofi menus, and other desktop tools can read module outputs and
+click or refresh modules.
+
+The service is exported at /org/barista/Bar with the org.barista.Bar
+interface, which has:
+
+	Outputs (property, a{ss}): the text of each module, by name.
+	Order (property, as): the names of the modules, in display order.
+	Modules() -> a(sss): the name, go type, and text of each module.
+	Output(name) -> s: the i3bar JSON for a module's last output.
+	Click(name, instance, button): clicks a module, as if in i3bar.
+	Refresh(name): asks a module to update its output.
+	FocusNext(), FocusPrevious(): moves the focus mode highlight.
+	Activate(button): clicks the highlighted segment.
+	Unfocus(): leaves focus mode.
+
+Changes to the properties are announced using PropertiesChanged.
+
+Typical usage would be:
+
+	b := bar.New().Add(modules...)
+	if _, err := dbusapi.Export(b, dbusapi.DefaultName); err != nil {
+	    log.Println(err)
+	}
+	b.Run()
+
+Then this left-clicks the module named "3":
+
+	busctl --user call org.barista.Bar /org/barista/Bar org.barista.Bar \
+	    Click ssi 3 "" 1
+
+and these navigate the bar from the keyboard, e.g. in i3 key bindings:
+
+	busctl --user call org.barista.Bar /org/barista/Bar org.barista.Bar FocusNext
+	busctl --user call org.barista.Bar /org/barista/Bar org.barista.Bar Activate i 1
+*/
+package dbusapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+)
+
+// DefaultName is the default bus name for the service. Bars that run more
+// than one instance should use a distinct name for each.
+const DefaultName = "org.barista.Bar"
+
+// Constants for the d-bus API.
+const (
+	objectPath      = dbus.ObjectPath("/org/barista/Bar")
+	barIface        = "org.barista.Bar"
+	propsIface      = "org.freedesktop.DBus.Properties"
+	propsChanged    = propsIface + ".PropertiesChanged"
+	introspectIface = "org.freedesktop.DBus.Introspectable"
+	errUnknown      = barIface + ".UnknownModule"
+	errUnsupported  = barIface + ".NotSupported"
+)
+
+var log = logging.New("dbusapi")
+
+// conn is the part of a d-bus connection used by the service, so that tests
+// can use a fake bus.
+type conn interface {
+	Export(v interface{}, path dbus.ObjectPath, iface string) error
+	Emit(path dbus.ObjectPath, name string, values ...interface{}) error
+	RequestName(name string, flags dbus.RequestNameFlags) (dbus.RequestNameReply, error)
+	Close() error
+}
+
+// dial opens a new connection to the session bus, replaced in tests.
+var dial = func() (conn, error) {
+	c, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := c.Auth(nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.Hello(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Service is a bar published on the session bus.
+type Service struct {
+	bar  *bar.I3Bar
+	conn conn
+	stop func()
+	done chan struct{}
+
+	// The last published state, guarded by mutex.
+	mutex   sync.Mutex
+	outputs map[string]string
+	order   []string
+}
+
+// Module describes a module on the bar, as returned by Modules.
+type Module struct {
+	Name string
+	Type string
+	Text string
+}
+
+// Export publishes the bar on the session bus under the given name, which
+// must not already be taken.
+func Export(b *bar.I3Bar, name string) (*Service, error) {
+	c, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{bar: b, conn: c, done: make(chan struct{})}
+	s.outputs, s.order = s.state()
+	if err := s.export(name); err != nil {
+		c.Close()
+		return nil, err
+	}
+	updates, stop := b.Subscribe()
+	s.stop = stop
+	go s.watch(updates)
+	return s, nil
+}
+
+func (s *Service) export(name string) error {
+	if err := s.conn.Export(methods{s}, objectPath, barIface); err != nil {
+		return err
+	}
+	if err := s.conn.Export(properties{s}, objectPath, propsIface); err != nil {
+		return err
+	}
+	err := s.conn.Export(introspect.NewIntrospectable(introspection), objectPath, introspectIface)
+	if err != nil {
+		return err
+	}
+	reply, err := s.conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("dbusapi: name %q is already taken", name)
+	}
+	return nil
+}
+
+// Close removes the bar from the session bus.
+func (s *Service) Close() error {
+	s.stop()
+	close(s.done)
+	return s.conn.Close()
+}
+
+// watch publishes changes to the outputs and order after each print.
+func (s *Service) watch(updates <-chan struct{}) {
+	for {
+		select {
+		case <-updates:
+			// Prints queued before Close must not emit signals.
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.update()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// update emits PropertiesChanged for any properties that have changed.
+func (s *Service) update() {
+	outputs, order := s.state()
+	changed := map[string]dbus.Variant{}
+	s.mutex.Lock()
+	if !equalMaps(outputs, s.outputs) {
+		changed["Outputs"] = dbus.MakeVariant(outputs)
+	}
+	if strings.Join(order, ",") != strings.Join(s.order, ",") {
+		changed["Order"] = dbus.MakeVariant(order)
+	}
+	s.outputs, s.order = outputs, order
+	s.mutex.Unlock()
+	if len(changed) == 0 {
+		return
+	}
+	err := s.conn.Emit(objectPath, propsChanged, barIface, changed, []string{})
+	if err != nil {
+		log.Error("failed to emit properties", "err", err)
+	}
+}
+
+// state returns the current text of each module, and the display order.
+func (s *Service) state() (map[string]string, []string) {
+	outputs := map[string]string{}
+	order := []string{}
+	for _, m := range s.bar.Stats().Modules {
+		outputs[m.Name] = text(m.Output)
+		order = append(order, m.Name)
+	}
+	return outputs, order
+}
+
+// text returns the text of all segments of an output.
+func text(o bar.Output) string {
+	var texts []string
+	for _, segment := range o {
+		texts = append(texts, segment.Text())
+	}
+	return strings.Join(texts, " ")
+}
+
+func equalMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// methods are the methods of the org.barista.Bar interface. They are kept
+// separate from Service so that only these are exported on the bus.
+type methods struct{ s *Service }
+
+func (m methods) Modules() ([]Module, *dbus.Error) {
+	var modules []Module
+	for _, stats := range m.s.bar.Stats().Modules {
+		modules = append(modules, Module{stats.Name, stats.Type, text(stats.Output)})
+	}
+	return modules, nil
+}
+
+func (m methods) Output(name string) (string, *dbus.Error) {
+	for _, stats := range m.s.bar.Stats().Modules {
+		if stats.Name == name {
+			out, err := json.Marshal(stats.Output)
+			if err != nil {
+				return "", dbus.MakeFailedError(err)
+			}
+			return string(out), nil
+		}
+	}
+	return "", unknownModule(name)
+}
+
+func (m methods) Click(name, instance string, button int32) *dbus.Error {
+	e := bar.Event{Button: bar.Button(button), Instance: instance}
+	if m.s.bar.Click(name, e) {
+		return nil
+	}
+	if !m.s.known(name) {
+		return unknownModule(name)
+	}
+	return dbus.NewError(errUnsupported, []interface{}{"module does not handle clicks"})
+}
+
+func (m methods) Refresh(name string) *dbus.Error {
+	if m.s.bar.Refresh(name) {
+		return nil
+	}
+	if !m.s.known(name) {
+		return unknownModule(name)
+	}
+	return dbus.NewError(errUnsupported, []interface{}{"module cannot be refreshed"})
+}
+
+func (m methods) FocusNext() *dbus.Error {
+	m.s.bar.FocusNext()
+	return nil
+}
+
+func (m methods) FocusPrevious() *dbus.Error {
+	m.s.bar.FocusPrevious()
+	return nil
+}
+
+func (m methods) Activate(button int32) *dbus.Error {
+	if m.s.bar.Activate(bar.Button(button)) {
+		return nil
+	}
+	if _, _, ok := m.s.bar.Focused(); !ok {
+		return dbus.NewError(errUnsupported, []interface{}{"bar is not in focus mode"})
+	}
+	return dbus.NewError(errUnsupported, []interface{}{"module does not handle clicks"})
+}
+
+func (m methods) Unfocus() *dbus.Error {
+	m.s.bar.Unfocus()
+	return nil
+}
+
+// known returns true if a module with the given name is on the bar.
+func (s *Service) known(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.outputs[name]
+	return ok
+}
+
+func unknownModule(name string) *dbus.Error {
+	return dbus.NewError(errUnknown, []interface{}{fmt.Sprintf("no module named %q", name)})
+}
+
+// properties implements org.freedesktop.DBus.Properties for the service.
+type properties struct{ s *Service }
+
+func (p properties) Get(iface, property string) (dbus.Variant, *dbus.Error) {
+	all, err := p.GetAll(iface)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	value, ok := all[property]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty",
+			[]interface{}{property})
+	}
+	return value, nil
+}
+
+func (p properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != barIface {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface",
+			[]interface{}{iface})
+	}
+	p.s.mutex.Lock()
+	defer p.s.mutex.Unlock()
+	return map[string]dbus.Variant{
+		"Outputs": dbus.MakeVariant(p.s.outputs),
+		"Order":   dbus.MakeVariant(p.s.order),
+	}, nil
+}
+
+func (p properties) Set(iface, property string, value dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{property})
+}
+
+// introspection describes the service for tools like busctl and d-feet.
+var introspection = &introspect.Node{
+	Name: string(objectPath),
+	Interfaces: []introspect.Interface{
+		introspect.IntrospectData,
+		{
+			Name: propsIface,
+			Methods: []introspect.Method{
+				{Name: "Get", Args: []introspect.Arg{
+					{Name: "interface", Type: "s", Direction: "in"},
+					{Name: "property", Type: "s", Direction: "in"},
+					{Name: "value", Type: "v", Direction: "out"},
+				}},
+				{Name: "GetAll", Args: []introspect.Arg{
+					{Name: "interface", Type: "s", Direction: "in"},
+					{Name: "properties", Type: "a{sv}", Direction: "out"},
+				}},
+				{Name: "Set", Args: []introspect.Arg{
+					{Name: "interface", Type: "s", Direction: "in"},
+					{Name: "property", Type: "s", Direction: "in"},
+					{Name: "value", Type: "v", Direction: "in"},
+				}},
+			},
+			Signals: []introspect.Signal{
+				{Name: "PropertiesChanged", Args: []introspect.Arg{
+					{Name: "interface", Type: "s"},
+					{Name: "changed", Type: "a{sv}"},
+					{Name: "invalidated", Type: "as"},
+				}},
+			},
+		},
+		{
+			Name: barIface,
+			Methods: []introspect.Method{
+				{Name: "Modules", Args: []introspect.Arg{
+					{Name: "modules", Type: "a(sss)", Direction: "out"},
+				}},
+				{Name: "Output", Args: []introspect.Arg{
+					{Name: "name", Type: "s", Direction: "in"},
+					{Name: "output", Type: "s", Direction: "out"},
+				}},
+				{Name: "Click", Args: []introspect.Arg{
+					{Name: "name", Type: "s", Direction: "in"},
+					{Name: "instance", Type: "s", Direction: "in"},
+					{Name: "button", Type: "i", Direction: "in"},
+				}},
+				{Name: "Refresh", Args: []introspect.Arg{
+					{Name: "name", Type: "s", Direction: "in"},
+				}},
+				{Name: "FocusNext"},
+				{Name: "FocusPrevious"},
+				{Name: "Activate", Args: []introspect.Arg{
+					{Name: "button", Type: "i", Direction: "in"},
+				}},
+				{Name: "Unfocus"},
+			},
+			Properties: []introspect.Property{
+				{Name: "Outputs", Type: "a{ss}", Access: "read"},
+				{Name: "Order", Type: "as", Access: "read"},
+			},
+		},
+	},
+}
@@ -0,0 +1,220 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbusapi
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/testing/mockio"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+// fakeConn records exported objects and emitted signals.
+type fakeConn struct {
+	sync.Mutex
+	exports map[string]interface{}
+	names   map[string]bool
+	signals chan []interface{}
+	closed  bool
+}
+
+func (f *fakeConn) Export(v interface{}, path dbus.ObjectPath, iface string) error {
+	f.Lock()
+	defer f.Unlock()
+	f.exports[iface] = v
+	return nil
+}
+
+func (f *fakeConn) Emit(path dbus.ObjectPath, name string, values ...interface{}) error {
+	f.signals <- append([]interface{}{name}, values...)
+	return nil
+}
+
+func (f *fakeConn) RequestName(name string, flags dbus.RequestNameFlags) (dbus.RequestNameReply, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.names[name] {
+		return dbus.RequestNameReplyExists, nil
+	}
+	f.names[name] = true
+	return dbus.RequestNameReplyPrimaryOwner, nil
+}
+
+func (f *fakeConn) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	f.closed = true
+	return nil
+}
+
+func setupFake(t *testing.T) *fakeConn {
+	f := &fakeConn{
+		exports: map[string]interface{}{},
+		names:   map[string]bool{},
+		signals: make(chan []interface{}, 10),
+	}
+	old := dial
+	t.Cleanup(func() { dial = old })
+	dial = func() (conn, error) { return f, nil }
+	return f
+}
+
+func (f *fakeConn) methods() methods {
+	f.Lock()
+	defer f.Unlock()
+	return f.exports[barIface].(methods)
+}
+
+func (f *fakeConn) properties() properties {
+	f.Lock()
+	defer f.Unlock()
+	return f.exports[propsIface].(properties)
+}
+
+func (f *fakeConn) nextSignal(t *testing.T) map[string]dbus.Variant {
+	select {
+	case s := <-f.signals:
+		assert.Equal(t, propsChanged, s[0])
+		assert.Equal(t, barIface, s[1])
+		return s[2].(map[string]dbus.Variant)
+	case <-time.After(time.Second):
+		assert.Fail(t, "no signal emitted")
+	}
+	return nil
+}
+
+// sliceModule is a module that neither handles clicks nor refreshes.
+type sliceModule []bar.Output
+
+func (s sliceModule) Stream() <-chan bar.Output {
+	ch := make(chan bar.Output, len(s))
+	for _, o := range s {
+		ch <- o
+	}
+	return ch
+}
+
+func TestService(t *testing.T) {
+	assert := assert.New(t)
+	fake := setupFake(t)
+	stdout := mockio.Stdout()
+	module1 := testModule.New(t)
+	module2 := sliceModule{outputs.Text("b")}
+	b := bar.NewOnIo(mockio.Stdin(), stdout).Add(module1, module2)
+
+	s, err := Export(b, DefaultName)
+	assert.NoError(err)
+	assert.NotNil(fake.exports[introspectIface], "introspectable")
+
+	go b.Run()
+	module1.Output(outputs.Text("a"))
+	var texts interface{}
+	for i := 0; i < 5 && texts == nil; i++ {
+		changed := fake.nextSignal(t)
+		// The modules can be printed separately, so wait for both.
+		if o, ok := changed["Outputs"]; ok {
+			if v := o.Value().(map[string]string); v["0"] == "a" && v["1"] == "b" {
+				texts = v
+			}
+		}
+	}
+	assert.Equal(map[string]string{"0": "a", "1": "b"}, texts)
+
+	props, dbusErr := fake.properties().GetAll(barIface)
+	assert.Nil(dbusErr)
+	assert.Equal(texts, props["Outputs"].Value())
+	order, dbusErr := fake.properties().Get(barIface, "Order")
+	assert.Nil(dbusErr)
+	assert.Equal([]string{"0", "1"}, order.Value())
+	_, dbusErr = fake.properties().Get(barIface, "Other")
+	assert.NotNil(dbusErr, "unknown property")
+	_, dbusErr = fake.properties().GetAll("org.example.Other")
+	assert.NotNil(dbusErr, "unknown interface")
+	assert.NotNil(fake.properties().Set(barIface, "Order", dbus.MakeVariant("")), "read-only")
+
+	b.MoveAt(1, 0)
+	changed := fake.nextSignal(t)
+	assert.Equal([]string{"1", "0"}, changed["Order"].Value())
+	assert.NotContains(changed, "Outputs", "unchanged properties not emitted")
+
+	m := fake.methods()
+	modules, dbusErr := m.Modules()
+	assert.Nil(dbusErr)
+	assert.Equal([]Module{
+		{"1", "dbusapi.sliceModule", "b"},
+		{"0", "*module.TestModule", "a"},
+	}, modules)
+
+	out, dbusErr := m.Output("0")
+	assert.Nil(dbusErr)
+	assert.Contains(out, `"full_text":"a"`)
+	_, dbusErr = m.Output("7")
+	assert.Equal(errUnknown, dbusErr.Name)
+
+	assert.Nil(m.Click("0", "inst", int32(bar.ButtonRight)))
+	e := module1.AssertClicked("click over d-bus")
+	assert.Equal(bar.ButtonRight, e.Button)
+	assert.Equal("inst", e.Instance)
+	assert.Nil(m.Refresh("0"))
+	module1.AssertUpdated("refresh over d-bus")
+
+	assert.Equal(errUnsupported, m.Click("1", "", 1).Name)
+	assert.Equal(errUnsupported, m.Refresh("1").Name)
+	assert.Equal(errUnknown, m.Click("9", "", 1).Name)
+	assert.Equal(errUnknown, m.Refresh("9").Name)
+
+	assert.Equal(errUnsupported, m.Activate(1).Name, "not in focus mode")
+	assert.Nil(m.FocusNext())
+	assert.Nil(m.FocusPrevious())
+	assert.Nil(m.FocusNext())
+	assert.Nil(m.Activate(int32(bar.ButtonMiddle)))
+	e = module1.AssertClicked("activate over d-bus")
+	assert.Equal(bar.ButtonMiddle, e.Button)
+	assert.Nil(m.Unfocus())
+	assert.Equal(errUnsupported, m.Activate(1).Name, "after unfocus")
+
+	assert.NoError(s.Close())
+	assert.True(fake.closed)
+	module1.Output(outputs.Text("c"))
+	select {
+	case <-fake.signals:
+		assert.Fail("signal emitted after close")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNameTaken(t *testing.T) {
+	fake := setupFake(t)
+	fake.names[DefaultName] = true
+	_, err := Export(bar.NewOnIo(mockio.Stdin(), mockio.Stdout()), DefaultName)
+	assert.Error(t, err)
+	assert.True(t, fake.closed, "connection closed on error")
+}
+
+func TestDialError(t *testing.T) {
+	old := dial
+	defer func() { dial = old }()
+	dial = func() (conn, error) { return nil, errors.New("no bus") }
+	_, err := Export(bar.NewOnIo(mockio.Stdin(), mockio.Stdout()), DefaultName)
+	assert.Error(t, err)
+}
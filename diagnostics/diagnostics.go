@@ -0,0 +1,240 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package diagnostics serves an HTTP endpoint for debugging a running bar,
+e.g. to find out which module is using up the CPU. The endpoint only
+listens on loopback addresses, and provides:
+
+	/debug/pprof/: the standard go profiles, see net/http/pprof.
+	/debug/vars: expvar variables, including "barista" with the bar's stats.
+	/outputs: the current output of each module, and its update statistics.
+	/metrics: the bar's statistics for prometheus (see the metrics package).
+	/profile: the cost of module refreshes, if profiling is enabled (see
+	    the profiling package).
+	/dump: a dump of the bar (see Dump), which is also written to the log.
+
+Typical usage would be:
+
+	b := bar.New().Add(modules...)
+	if _, err := diagnostics.Listen(b, "localhost:6060"); err != nil {
+	    log.Println(err)
+	}
+	b.Run()
+
+Then "go tool pprof http://localhost:6060/debug/pprof/profile" profiles the
+bar, and "curl localhost:6060/outputs" shows how often each module updates.
+
+For bars that have stopped responding, DumpOnSignal writes the same dump
+to the log on SIGQUIT, e.g. "pkill -QUIT mybar".
+*/
+package diagnostics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/metrics"
+	"github.com/soumya92/barista/profiling"
+)
+
+// Server is a running diagnostics endpoint.
+type Server struct {
+	listener net.Listener
+	bar      *bar.I3Bar
+}
+
+// Addr returns the address the endpoint is listening on, e.g. to find the
+// port when listening on port 0.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops serving the endpoint.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+var (
+	// expvar variables cannot be unpublished, so the "barista" variable
+	// reports the stats of the most recently served bar.
+	publishOnce sync.Once
+	current     *Server
+	currentMu   sync.Mutex
+)
+
+// Listen serves the diagnostics endpoint for the bar on the given address,
+// which must be a loopback address, e.g. "localhost:6060" or "127.0.0.1:0",
+// since profiles and outputs should not be exposed to the network.
+func Listen(b *bar.I3Bar, addr string) (*Server, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("diagnostics: %q is not a loopback address", host)
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: l, bar: b}
+	currentMu.Lock()
+	current = s
+	currentMu.Unlock()
+	publishOnce.Do(func() {
+		expvar.Publish("barista", expvar.Func(func() interface{} {
+			currentMu.Lock()
+			defer currentMu.Unlock()
+			return current.vars()
+		}))
+	})
+	go http.Serve(l, s.handler())
+	return s, nil
+}
+
+// isLoopback returns true if the host only resolves to loopback addresses.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handler returns the handler for all the diagnostics pages.
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/outputs", s.outputs)
+	mux.Handle("/metrics", metrics.Handler(s.bar))
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if !profiling.Enabled() {
+			fmt.Fprintln(w, "profiling is disabled, see profiling.Enable")
+		}
+		profiling.Write(w)
+	})
+	mux.HandleFunc("/dump", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		Dump(io.MultiWriter(w, logging.Writer()), s.bar)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<a href="/debug/pprof/">pprof</a><br>`)
+		fmt.Fprintln(w, `<a href="/debug/vars">expvar</a><br>`)
+		fmt.Fprintln(w, `<a href="/outputs">outputs</a><br>`)
+		fmt.Fprintln(w, `<a href="/metrics">metrics</a><br>`)
+		fmt.Fprintln(w, `<a href="/profile">profile</a><br>`)
+		fmt.Fprintln(w, `<a href="/dump">dump</a>`)
+	})
+	return mux
+}
+
+// moduleVars are the stats for a module, with rates and latencies averaged
+// over the time since the bar was started.
+type moduleVars struct {
+	Name       string
+	Type       string
+	Updates    int64
+	PerMinute  float64
+	LastUpdate time.Time
+	// The average time that each output waited for the bar.
+	AvgLatency string
+	Output     bar.Output `json:",omitempty"`
+}
+
+// barVars are the stats for the bar.
+type barVars struct {
+	Uptime       string
+	Prints       int64
+	AvgPrintTime string
+	Modules      []moduleVars
+}
+
+// vars returns the current stats for the bar as expvar variables, without
+// the outputs, which are only included on the outputs page.
+func (s *Server) vars() barVars {
+	v := s.stats()
+	for i := range v.Modules {
+		v.Modules[i].Output = nil
+	}
+	return v
+}
+
+func (s *Server) stats() barVars {
+	stats := s.bar.Stats()
+	var uptime time.Duration
+	if !stats.Started.IsZero() {
+		uptime = time.Since(stats.Started)
+	}
+	v := barVars{
+		Uptime:       uptime.String(),
+		Prints:       stats.Prints,
+		AvgPrintTime: average(stats.PrintTime, stats.Prints).String(),
+	}
+	for _, m := range stats.Modules {
+		v.Modules = append(v.Modules, moduleVars{
+			Name:       m.Name,
+			Type:       m.Type,
+			Updates:    m.Updates,
+			PerMinute:  perMinute(m.Updates, uptime),
+			LastUpdate: m.LastUpdate,
+			AvgLatency: average(m.Latency, m.Updates).String(),
+			Output:     m.Output,
+		})
+	}
+	return v
+}
+
+// perMinute returns the rate of count events over the duration.
+func perMinute(count int64, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(count) / duration.Minutes()
+}
+
+// average returns the average duration of count events.
+func average(total time.Duration, count int64) time.Duration {
+	if count == 0 {
+		return 0
+	}
+	return total / time.Duration(count)
+}
+
+// outputs serves the stats and current outputs of all modules as json.
+func (s *Server) outputs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(s.stats())
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func get(t *testing.T, s *Server, path string) (int, string) {
+	resp, err := http.Get("http://" + s.Addr() + path)
+	if !assert.Nil(t, err, path) {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err, path)
+	return resp.StatusCode, string(body)
+}
+
+func TestDiagnostics(t *testing.T) {
+	module := testModule.New(t)
+	b := testBar.New(t)
+	b.Bar.Add(module)
+	s, err := Listen(b.Bar, "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s.Close()
+	b.Start()
+	defer b.Close()
+	module.Output(outputs.Text("hello"))
+	b.AssertText([]string{"hello"}, "module output")
+
+	code, body := get(t, s, "/outputs")
+	assert.Equal(t, http.StatusOK, code)
+	var stats struct {
+		Prints  int64
+		Modules []struct {
+			Type    string
+			Updates int64
+			Output  []map[string]interface{}
+		}
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &stats), "outputs are json")
+	assert.True(t, stats.Prints > 0)
+	if assert.Equal(t, 1, len(stats.Modules)) {
+		assert.Equal(t, "*module.TestModule", stats.Modules[0].Type)
+		assert.Equal(t, int64(1), stats.Modules[0].Updates)
+		assert.Equal(t, "hello", stats.Modules[0].Output[0]["full_text"])
+	}
+
+	code, body = get(t, s, "/debug/vars")
+	assert.Equal(t, http.StatusOK, code)
+	var vars map[string]json.RawMessage
+	assert.Nil(t, json.Unmarshal([]byte(body), &vars), "expvar is json")
+	assert.Contains(t, string(vars["barista"]), "TestModule", "bar stats in expvar")
+	assert.NotContains(t, string(vars["barista"]), "hello", "outputs not in expvar")
+
+	code, body = get(t, s, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body,
+		`barista_module_updates_total{module="0",type="*module.TestModule"} 1`)
+
+	code, body = get(t, s, "/profile")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "profiling is disabled")
+	assert.Contains(t, body, "avg cpu", "includes report header")
+
+	code, body = get(t, s, "/debug/pprof/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "goroutine")
+
+	logging.SetOutput(ioutil.Discard)
+	defer logging.SetOutput(os.Stderr)
+	code, body = get(t, s, "/dump")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `output: "hello"`)
+
+	code, body = get(t, s, "/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "/outputs")
+	code, _ = get(t, s, "/nope")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	b := testBar.New(t)
+	for _, addr := range []string{"0.0.0.0:6060", ":6060", "example.com:80", "nope"} {
+		_, err := Listen(b.Bar, addr)
+		assert.Error(t, err, addr)
+	}
+	s, err := Listen(b.Bar, "localhost:0")
+	if assert.Nil(t, err, "localhost") {
+		s.Close()
+	}
+}
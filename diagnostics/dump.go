@@ -0,0 +1,97 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/logging"
+)
+
+// Dump writes the state of the bar for a bug report: the statistics, last
+// output, and last error of each module, the state of all schedulers, and
+// the stacks of all goroutines.
+func Dump(w io.Writer, b *bar.I3Bar) {
+	fmt.Fprintf(w, "=== barista dump at %s ===\n", time.Now().Format(time.RFC3339))
+	stats := b.Stats()
+	fmt.Fprintf(w, "bar: started %s, %d prints, %s per print\n",
+		formatTime(stats.Started), stats.Prints, average(stats.PrintTime, stats.Prints))
+	for _, m := range stats.Modules {
+		fmt.Fprintf(w, "module %s (%s): %d updates, last %s, %s latency\n",
+			m.Name, m.Type, m.Updates, formatTime(m.LastUpdate), average(m.Latency, m.Updates))
+		if m.Error != nil {
+			fmt.Fprintf(w, "\terror: %v\n", m.Error)
+		}
+		for _, segment := range m.Output {
+			fmt.Fprintf(w, "\toutput: %q\n", segment.Text())
+		}
+	}
+	state := scheduler.CurrentState()
+	fmt.Fprintf(w, "schedulers: paused %v, %d waiting\n", state.Paused, state.Waiting)
+	for _, s := range state.Schedulers {
+		fmt.Fprintf(w, "\t%s: next %s, last %s", s.Func, formatTime(s.Next), formatTime(s.Last))
+		if s.Interval > 0 {
+			fmt.Fprintf(w, ", every %s", s.Interval)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "goroutines:")
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+	fmt.Fprintln(w, "=== end of barista dump ===")
+}
+
+// formatTime formats a time for the dump, with the zero time as "never".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("15:04:05.000")
+}
+
+// DumpOnSignal writes a dump of the bar to the log (see the logging
+// package) whenever the process receives SIGQUIT, until the returned stop
+// function is called. This replaces go's default handling of SIGQUIT, which
+// writes the goroutine stacks to standard error and exits.
+func DumpOnSignal(b *bar.I3Bar) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGQUIT)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				Dump(logging.Writer(), b)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostics
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+func TestDump(t *testing.T) {
+	ok := base.New()
+	failing := base.New()
+	b := testBar.New(t)
+	b.Bar.Add(ok, failing)
+	b.Start()
+	defer b.Close()
+	ok.Output(outputs.Text("fine"))
+	failing.Error(errors.New("something broke"))
+	b.AssertText([]string{"fine", "something broke"}, "outputs")
+	ok.Schedule().Every(time.Minute)
+	defer ok.Schedule().Stop()
+
+	var buf bytes.Buffer
+	Dump(&buf, b.Bar)
+	dump := buf.String()
+	assert.Contains(t, dump, "module 0 (*base.Base): 1 updates")
+	assert.Contains(t, dump, `output: "fine"`)
+	assert.Contains(t, dump, "error: something broke")
+	assert.Contains(t, dump, "every 1m0s", "scheduler state")
+	assert.Contains(t, dump, "goroutine", "goroutine stacks")
+	assert.Contains(t, dump, "TestDump", "goroutine stacks")
+}
+
+// chanWriter sends each write on a channel.
+type chanWriter chan string
+
+func (c chanWriter) Write(p []byte) (int, error) {
+	c <- string(p)
+	return len(p), nil
+}
+
+func TestDumpOnSignal(t *testing.T) {
+	writes := make(chanWriter, 100)
+	logging.SetOutput(writes)
+	defer logging.SetOutput(os.Stderr)
+
+	b := testBar.New(t)
+	stop := DumpOnSignal(b.Bar)
+	defer stop()
+	syscall.Kill(os.Getpid(), syscall.SIGQUIT)
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case w := <-writes:
+			if strings.Contains(w, "end of barista dump") {
+				return
+			}
+		case <-timeout:
+			assert.Fail(t, "expected a dump on SIGQUIT")
+			return
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package format renders values with units consistently across modules:
+sizes in decimal (SI) or binary (IEC) units, bits, temperatures, and
+compact durations. Numbers and unit labels follow the current locale (see
+the locale package).
+
+Scaled values use a consistent precision: one decimal place below 10, and
+none from 10 up, e.g. "1.5 GiB", "83 MB", "12 Mb".
+
+Typical usage would be:
+
+	format.IBytes(1536)                        // "1.5 KiB"
+	format.Bytes(82854982)                     // "83 MB"
+	format.Bits(12345678)                      // "12 Mb"
+	format.Compact(1234)                       // "1.2k"
+	format.Celsius(45.2)                       // "45°C"
+	format.Fahrenheit(45.2)                    // "113°F"
+	format.Duration(76*time.Hour, time.Minute) // "3d 4h"
+
+The same functions are available in templates created using
+outputs.TextTemplate and outputs.PangoTemplate, e.g. {{.Used | ibytes}},
+{{.Temp | celsius}}, or {{.Remaining | duration}}, with Compact available
+as {{.Count | humanizeNumber}}.
+*/
+package format
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/soumya92/barista/locale"
+)
+
+var (
+	siBytes  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	iecBytes = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siBits   = []string{"b", "kb", "Mb", "Gb", "Tb", "Pb", "Eb"}
+	// SI prefixes are used for counts, since they do not depend on the
+	// language, unlike e.g. "B" for billion.
+	siCounts = []string{"", "k", "M", "G", "T", "P", "E"}
+)
+
+// Bytes formats a size in decimal units, e.g. "83 MB".
+func Bytes(b uint64) string {
+	return scaled(float64(b), 1000, siBytes)
+}
+
+// IBytes formats a size in binary units, e.g. "79 MiB".
+func IBytes(b uint64) string {
+	return scaled(float64(b), 1024, iecBytes)
+}
+
+// Bits formats a number of bits in decimal units, e.g. "12 Mb". Append
+// "/s" for rates, or multiply a size in bytes by 8 to show it in bits.
+func Bits(b uint64) string {
+	return scaled(float64(b), 1000, siBits)
+}
+
+// scaled formats the value in the largest unit that keeps it at least 1.
+func scaled(v, base float64, units []string) string {
+	if v < 10 {
+		return locale.Current().FormatInt(int64(v)) + " " + locale.Current().Unit(units[0])
+	}
+	e := int(math.Floor(math.Log(v) / math.Log(base)))
+	if e >= len(units) {
+		e = len(units) - 1
+	}
+	scaled := v / math.Pow(base, float64(e))
+	if math.Floor(scaled+0.5) >= base && e < len(units)-1 {
+		// Rounding would show e.g. "1000 kB" for 999,999 bytes.
+		e, scaled = e+1, scaled/base
+	}
+	return Unit(scaled, units[e])
+}
+
+// Compact formats a count using SI prefixes from 1000 up, e.g. "950",
+// "1.2k", "34k", or "5.6M".
+func Compact(v float64) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	if math.Floor(v+0.5) < 1000 {
+		return sign + locale.Current().FormatFloat(math.Floor(v+0.5), 0)
+	}
+	e := int(math.Floor(math.Log10(v) / 3))
+	if e >= len(siCounts) {
+		e = len(siCounts) - 1
+	}
+	scaled := v / math.Pow(1000, float64(e))
+	if scaled >= 999.5 && e < len(siCounts)-1 {
+		// Rounding would show e.g. "1000k" for 999,999.
+		e, scaled = e+1, scaled/1000
+	}
+	return sign + Number(scaled) + locale.Current().Unit(siCounts[e])
+}
+
+// Number formats a number with one decimal place below 10, and none from
+// 10 up, e.g. "1.5", "83".
+func Number(v float64) string {
+	precision := 0
+	if math.Abs(v) < 10 {
+		precision = 1
+	}
+	// Round half up once, at the precision used for display.
+	scale := math.Pow10(precision)
+	v = math.Floor(v*scale+0.5) / scale
+	if math.Abs(v) >= 10 {
+		// Rounding may have carried into another digit, e.g. 9.96 to 10.
+		precision = 0
+	}
+	return locale.Current().FormatFloat(v, precision)
+}
+
+// Unit formats a value with a unit, e.g. "1.5 KiB", using the label for
+// the unit in the current locale.
+func Unit(v float64, unit string) string {
+	return Number(v) + " " + locale.Current().Unit(unit)
+}
+
+// Celsius formats a temperature in degrees Celsius, e.g. "45°C".
+func Celsius(c float64) string {
+	return locale.Current().FormatFloat(c, 0) + "°C"
+}
+
+// Fahrenheit formats a temperature given in degrees Celsius in degrees
+// Fahrenheit, e.g. "113°F" for 45°C.
+func Fahrenheit(c float64) string {
+	return locale.Current().FormatFloat(c*9/5+32, 0) + "°F"
+}
+
+// durationUnits are the units for compact durations, largest first.
+var durationUnits = []struct {
+	unit  time.Duration
+	label string
+}{
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+}
+
+// Duration formats a duration using the two largest units, from days down
+// to the smallest unit given, e.g. "3d 4h", "4h 0m", or "12m" for 12m5s
+// with a smallest unit of time.Minute.
+func Duration(d time.Duration, smallest time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	var parts []string
+	for _, u := range durationUnits {
+		if u.unit < smallest {
+			break
+		}
+		value := d / u.unit
+		d -= value * u.unit
+		if len(parts) == 0 && value == 0 && u.unit > smallest {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d%s", value, u.label))
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		// Smaller than a second, or than the smallest unit if it is not one
+		// of the duration units.
+		parts = append(parts, "0"+durationUnits[len(durationUnits)-1].label)
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// figureSpace is as wide as a digit in fonts that support it, so padding
+// with it keeps numbers aligned.
+const figureSpace = "\u2007"
+
+// Pad right-aligns s to at least width characters using figure spaces, so
+// that values like "7%" and "42%" take up the same space on the bar. Use
+// it with pango.TabularNumbers for fonts with proportional digits.
+func Pad(s string, width int) string {
+	if n := width - utf8.RuneCountInString(s); n > 0 {
+		return strings.Repeat(figureSpace, n) + s
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/locale"
+)
+
+func TestSizes(t *testing.T) {
+	assert := assert.New(t)
+	defer locale.SetLocale(locale.English)
+	assert.Equal("5 B", Bytes(5))
+	assert.Equal("5 B", IBytes(5))
+	assert.Equal("10 B", IBytes(10))
+	assert.Equal("1.0 KiB", IBytes(1024))
+	assert.Equal("1.5 KiB", IBytes(1536))
+	assert.Equal("1.5 kB", Bytes(1536))
+	assert.Equal("9.9 MB", Bytes(9940000))
+	assert.Equal("10 MB", Bytes(9960000), "rounding carries into another digit")
+	assert.Equal("13 MB", Bytes(12500000), "rounds half up")
+	assert.Equal("83 MB", Bytes(82854982))
+	assert.Equal("79 MiB", IBytes(82854982))
+	assert.Equal("12 Mb", Bits(12345678))
+	assert.Equal("16 EiB", IBytes(1<<64-1))
+	assert.Equal("999 kB", Bytes(999499))
+	assert.Equal("1.0 MB", Bytes(999999), "rounding carries into the next unit")
+	assert.Equal("1.0 MiB", IBytes(1048575), "rounding carries into the next unit")
+	assert.Equal("1023 KiB", IBytes(1023*1024))
+	assert.Equal("1.0 Gb", Bits(999999999))
+
+	locale.Set("fr")
+	assert.Equal("1,5 Kio", IBytes(1536))
+	assert.Equal("83 Mo", Bytes(82854982))
+	assert.Equal("5 o", Bytes(5))
+	assert.Equal("12 Mb", Bits(12345678), "no localized bit units")
+}
+
+func TestCompact(t *testing.T) {
+	defer locale.SetLocale(locale.English)
+	for v, expected := range map[float64]string{
+		0:      "0",
+		950:    "950",
+		999.6:  "1.0k",
+		1234:   "1.2k",
+		34567:  "35k",
+		999999: "1.0M",
+		5.6e6:  "5.6M",
+		-1500:  "-1.5k",
+		7.2e9:  "7.2G",
+		1e21:   "1000E",
+		12.4:   "12",
+	} {
+		assert.Equal(t, expected, Compact(v), "%v", v)
+	}
+	locale.Set("de")
+	assert.Equal(t, "1,2k", Compact(1234))
+}
+
+func TestTemperatures(t *testing.T) {
+	defer locale.SetLocale(locale.English)
+	assert.Equal(t, "45°C", Celsius(45.2))
+	assert.Equal(t, "-3°C", Celsius(-3))
+	assert.Equal(t, "113°F", Fahrenheit(45.2))
+	assert.Equal(t, "32°F", Fahrenheit(0))
+}
+
+func TestDuration(t *testing.T) {
+	for _, tc := range []struct {
+		duration time.Duration
+		smallest time.Duration
+		expected string
+	}{
+		{76 * time.Hour, time.Minute, "3d 4h"},
+		{76*time.Hour + 5*time.Minute, time.Second, "3d 4h"},
+		{4 * time.Hour, time.Minute, "4h 0m"},
+		{12*time.Minute + 5*time.Second, time.Minute, "12m"},
+		{12*time.Minute + 5*time.Second, time.Second, "12m 5s"},
+		{30 * time.Second, time.Minute, "0m"},
+		{45 * time.Second, time.Second, "45s"},
+		{500 * time.Millisecond, time.Second, "0s"},
+		{500 * time.Millisecond, time.Millisecond, "0s"},
+		{48 * time.Hour, 24 * time.Hour, "2d"},
+		{-90 * time.Second, time.Second, "-1m 30s"},
+	} {
+		assert.Equal(t, tc.expected, Duration(tc.duration, tc.smallest),
+			"%v with smallest unit %v", tc.duration, tc.smallest)
+	}
+}
+
+func TestPad(t *testing.T) {
+	assert.Equal(t, "\u2007\u20077%", Pad("7%", 4))
+	assert.Equal(t, "\u200742%", Pad("42%", 4))
+	assert.Equal(t, "100%", Pad("100%", 4))
+	assert.Equal(t, "1000%", Pad("1000%", 4), "never truncates")
+	assert.Equal(t, "\u2007°C", Pad("°C", 3), "counts characters, not bytes")
+}
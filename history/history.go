@@ -0,0 +1,228 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package history records every output that each module on a bar produced, and
+replays recorded sessions, to make intermittent rendering bugs reproducible.
+
+A Recorder keeps the most recent outputs in memory, and can also write every
+output to a file as a line of JSON:
+
+	f, _ := os.Create("/tmp/barista-history.json")
+	rec := history.Record(b, 1000).To(f)
+
+The recorded session can then be replayed on a bar with the same timing, e.g.
+from a test or a separate binary, without any of the original modules:
+
+	entries, _ := history.Read(f)
+	bar.Run(history.Replay(entries)...)
+*/
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/timing"
+)
+
+var log = logging.New("history")
+
+// Entry is an output recorded from a module.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// The name of the module, as in the bar's Stats.
+	Module string     `json:"module"`
+	Output bar.Output `json:"output"`
+}
+
+// Recorder records the outputs of all modules on a bar.
+type Recorder struct {
+	mutex   sync.Mutex
+	size    int
+	entries []Entry
+	encoder *json.Encoder
+	stopped bool
+}
+
+// Record starts recording every output from the modules on the bar,
+// keeping the given number of most recent entries in memory.
+func Record(b *bar.I3Bar, size int) *Recorder {
+	r := &Recorder{size: size}
+	b.Observe(r.add)
+	return r
+}
+
+// To also writes every entry to w as a line of JSON, e.g. to keep a
+// whole session in a file. Entries written this way can be read back
+// using Read.
+func (r *Recorder) To(w io.Writer) *Recorder {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.encoder = json.NewEncoder(w)
+	return r
+}
+
+// Stop stops recording. Recorded entries are kept.
+func (r *Recorder) Stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stopped = true
+}
+
+func (r *Recorder) add(name string, o bar.Output) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stopped {
+		return
+	}
+	e := Entry{Time: scheduler.Now(), Module: name, Output: o}
+	r.entries = append(r.entries, e)
+	if len(r.entries) > r.size {
+		r.entries = append([]Entry(nil), r.entries[len(r.entries)-r.size:]...)
+	}
+	if r.encoder != nil {
+		if err := r.encoder.Encode(e); err != nil {
+			log.Error("failed to write entry", "err", err)
+			r.encoder = nil
+		}
+	}
+}
+
+// Entries returns the entries in memory, oldest first.
+func (r *Recorder) Entries() []Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Write writes the entries in memory to w as lines of JSON, in the same
+// format as To, e.g. to save the outputs leading up to a bug.
+func (r *Recorder) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, e := range r.Entries() {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read reads entries written by a Recorder.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	// Outputs can be much longer than the default token size.
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// Replay returns modules that output the recorded entries, one for each
+// recorded module in the order of their names, with the same delays
+// between outputs as when they were recorded. The delays are measured
+// from when the first module is streamed, using the timing package, so
+// replays also run in virtual time in tests.
+func Replay(entries []Entry) []bar.Module {
+	if len(entries) == 0 {
+		return nil
+	}
+	p := &player{start: entries[0].Time}
+	byName := map[string]*replayModule{}
+	var modules []*replayModule
+	for _, e := range entries {
+		if e.Time.Before(p.start) {
+			p.start = e.Time
+		}
+		m, ok := byName[e.Module]
+		if !ok {
+			m = &replayModule{player: p, name: e.Module, scheduler: timing.NewScheduler()}
+			byName[e.Module] = m
+			modules = append(modules, m)
+		}
+		m.entries = append(m.entries, e)
+	}
+	sort.SliceStable(modules, func(i, j int) bool {
+		return lessName(modules[i].name, modules[j].name)
+	})
+	var out []bar.Module
+	for _, m := range modules {
+		out = append(out, m)
+	}
+	return out
+}
+
+// lessName orders module names numerically where possible, since the bar
+// names modules by position.
+func lessName(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// player keeps the time at which a replay started, shared by all modules.
+type player struct {
+	once  sync.Once
+	start time.Time
+	begun time.Time
+}
+
+// at returns the replay time for a recorded time.
+func (p *player) at(recorded time.Time) time.Time {
+	p.once.Do(func() { p.begun = timing.Now() })
+	return p.begun.Add(recorded.Sub(p.start))
+}
+
+// replayModule outputs the recorded entries for a single module.
+type replayModule struct {
+	player    *player
+	name      string
+	entries   []Entry
+	scheduler *timing.Scheduler
+}
+
+func (m *replayModule) Stream() <-chan bar.Output {
+	ch := make(chan bar.Output)
+	go func() {
+		for _, e := range m.entries {
+			if when := m.player.at(e.Time); when.After(timing.Now()) {
+				m.scheduler.At(when)
+				<-m.scheduler.C
+			}
+			ch <- e.Output
+		}
+		log.Fine("replay finished", "module", m.name)
+	}()
+	return ch
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func texts(entries []Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Module+":"+e.Output[0].Text())
+	}
+	return out
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	b := testBar.New(t)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	b.AdvanceTo(start)
+	m1, m2 := testModule.New(t), testModule.New(t)
+	b.Bar.Add(m1, m2)
+	var file bytes.Buffer
+	rec := Record(b.Bar, 3).To(&file)
+	b.Start()
+	defer b.Close()
+
+	m1.Output(outputs.Text("a"))
+	b.AssertText([]string{"a"}, "first output")
+	b.AdvanceBy(time.Second)
+	m2.Output(outputs.Text("<b>b</b>").Markup(bar.MarkupPango))
+	b.AssertText([]string{"a", "<b>b</b>"}, "second output")
+	b.AdvanceBy(2 * time.Second)
+	m1.Output(outputs.Text("c"))
+	b.AssertText([]string{"c", "<b>b</b>"}, "third output")
+	m1.Output(outputs.Text("d"))
+	b.AssertText([]string{"d", "<b>b</b>"}, "fourth output")
+	rec.Stop()
+	m2.Output(outputs.Text("e"))
+	b.AssertText([]string{"d", "e"}, "after stop")
+
+	assert.Equal(t, []string{"1:<b>b</b>", "0:c", "0:d"}, texts(rec.Entries()),
+		"most recent entries in memory")
+	assert.Equal(t, start.Add(time.Second), rec.Entries()[0].Time)
+
+	entries, err := Read(&file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0:a", "1:<b>b</b>", "0:c", "0:d"}, texts(entries),
+		"all entries written to file")
+	assert.Equal(t, "pango", entries[1].Output[0]["markup"])
+
+	var saved bytes.Buffer
+	assert.Nil(t, rec.Write(&saved))
+	entries, err = Read(&saved)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1:<b>b</b>", "0:c", "0:d"}, texts(entries), "saved entries")
+
+	_, err = Read(strings.NewReader("{\"module\": \"0\"}\n\nnot json\n"))
+	assert.Error(t, err, "invalid entry")
+}
+
+func TestReplay(t *testing.T) {
+	assert.Empty(t, Replay(nil))
+
+	recorded := time.Date(2017, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{recorded, "10", outputs.Text("late module")},
+		{recorded, "2", outputs.Text("a")},
+		{recorded.Add(time.Second), "10", outputs.Text("b")},
+		{recorded.Add(5 * time.Second), "2", outputs.Text("c")},
+	}
+	b := testBar.New(t)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	b.AdvanceTo(start)
+	b.Bar.Add(Replay(entries)...)
+	b.Start()
+	defer b.Close()
+
+	b.AssertText([]string{"a", "late module"}, "modules in order of names")
+	b.AdvanceBy(time.Second)
+	b.AssertText([]string{"a", "b"}, "after a second")
+	b.AdvanceBy(3 * time.Second)
+	assert.Equal(t, start.Add(5*time.Second), b.NextTick(), "same delays as recorded")
+	b.AssertText([]string{"c", "b"}, "after five seconds")
+}
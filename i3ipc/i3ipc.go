@@ -0,0 +1,260 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package i3ipc provides a minimal client for the i3 IPC protocol, which is also
+used by sway. It is shared by modules that show window manager state.
+
+Each message is the magic string "i3-ipc", followed by the payload length and
+message type as 32-bit integers in native (little-endian) byte order, and then
+the JSON payload. Replies use the type of the request, while events have the
+high bit set.
+*/
+package i3ipc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const magic = "i3-ipc"
+
+// Message types for requests and replies.
+const (
+	RunCommand      = 0
+	GetWorkspaces   = 1
+	Subscribe       = 2
+	GetTree         = 4
+	GetBindingState = 12
+	// GetInputs is only supported by sway.
+	GetInputs = 100
+)
+
+// Message types for events.
+const (
+	WorkspaceEvent = 0x80000000
+	ModeEvent      = 0x80000002
+	WindowEvent    = 0x80000003
+	BindingEvent   = 0x80000005
+	// InputEvent is only supported by sway.
+	InputEvent = 0x80000015
+)
+
+// Client sends messages to a window manager over its IPC socket.
+// A new connection is made for each request or subscription.
+type Client struct {
+	socket func() (string, error)
+}
+
+// i3Socket returns the path of the i3 IPC socket.
+var i3Socket = func() (string, error) {
+	if socket := os.Getenv("I3SOCK"); socket != "" {
+		return socket, nil
+	}
+	out, err := exec.Command("i3", "--get-socketpath").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// swaySocket returns the path of the sway IPC socket.
+func swaySocket() (string, error) {
+	if socket := os.Getenv("SWAYSOCK"); socket != "" {
+		return socket, nil
+	}
+	return "", errors.New("SWAYSOCK is not set")
+}
+
+// I3 returns a client for the running i3 instance.
+func I3() Client {
+	return Client{i3Socket}
+}
+
+// Sway returns a client for the running sway instance.
+func Sway() Client {
+	return Client{swaySocket}
+}
+
+// Socket returns a client that uses the IPC socket at the given path.
+func Socket(path string) Client {
+	return Client{func() (string, error) { return path, nil }}
+}
+
+func (c Client) dial() (net.Conn, error) {
+	socket, err := c.socket()
+	if err != nil {
+		return nil, err
+	}
+	return net.Dial("unix", socket)
+}
+
+// WriteMessage writes a single IPC message.
+func WriteMessage(w io.Writer, msgType uint32, payload string) error {
+	buf := make([]byte, len(magic)+8+len(payload))
+	copy(buf, magic)
+	binary.LittleEndian.PutUint32(buf[len(magic):], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[len(magic)+4:], msgType)
+	copy(buf[len(magic)+8:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadMessage reads a single IPC message.
+func ReadMessage(r io.Reader) (msgType uint32, payload []byte, err error) {
+	header := make([]byte, len(magic)+8)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return
+	}
+	if string(header[:len(magic)]) != magic {
+		err = fmt.Errorf("invalid IPC magic: %q", header[:len(magic)])
+		return
+	}
+	length := binary.LittleEndian.Uint32(header[len(magic):])
+	msgType = binary.LittleEndian.Uint32(header[len(magic)+4:])
+	payload = make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	return
+}
+
+// Call sends a single message and decodes the reply into out.
+func (c Client) Call(msgType uint32, payload string, out interface{}) error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := WriteMessage(conn, msgType, payload); err != nil {
+		return err
+	}
+	_, reply, err := ReadMessage(conn)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(reply, out)
+}
+
+// Command runs a command, and returns the first error if it fails.
+func (c Client) Command(cmd string) error {
+	var results []struct {
+		Success bool
+		Error   string
+	}
+	if err := c.Call(RunCommand, cmd, &results); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if !r.Success {
+			return errors.New(r.Error)
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to the given events, and calls the function with
+// the type and payload of each event. It only returns on error.
+func (c Client) Subscribe(events []string, f func(uint32, []byte)) error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	payload, _ := json.Marshal(events)
+	if err := WriteMessage(conn, Subscribe, string(payload)); err != nil {
+		return err
+	}
+	_, reply, err := ReadMessage(conn)
+	if err != nil {
+		return err
+	}
+	var result struct{ Success bool }
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("failed to subscribe to %v", events)
+	}
+	for {
+		msgType, payload, err := ReadMessage(conn)
+		if err != nil {
+			return err
+		}
+		f(msgType, payload)
+	}
+}
+
+// Node is a container in the layout tree, as returned by GET_TREE. Only the
+// fields used by modules are included.
+type Node struct {
+	ID               int64  `json:"id"`
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	Focused          bool   `json:"focused"`
+	AppID            string `json:"app_id"`
+	WindowProperties struct {
+		Class    string `json:"class"`
+		Instance string `json:"instance"`
+	} `json:"window_properties"`
+	Nodes         []Node `json:"nodes"`
+	FloatingNodes []Node `json:"floating_nodes"`
+}
+
+// Children returns the tiling and floating children of the node.
+func (n Node) Children() []Node {
+	return append(append([]Node(nil), n.Nodes...), n.FloatingNodes...)
+}
+
+// Find returns the first node in the tree (including the node itself) that
+// matches the given function, in depth-first order, and false if none match.
+func (n Node) Find(match func(Node) bool) (Node, bool) {
+	if match(n) {
+		return n, true
+	}
+	for _, c := range n.Children() {
+		if f, ok := c.Find(match); ok {
+			return f, true
+		}
+	}
+	return Node{}, false
+}
+
+// Windows returns all the leaf containers under the node.
+func (n Node) Windows() []Node {
+	children := n.Children()
+	if len(children) == 0 {
+		if n.Type == "con" || n.Type == "floating_con" {
+			return []Node{n}
+		}
+		return nil
+	}
+	var windows []Node
+	for _, c := range children {
+		windows = append(windows, c.Windows()...)
+	}
+	return windows
+}
+
+// Tree returns the layout tree.
+func (c Client) Tree() (Node, error) {
+	var tree Node
+	err := c.Call(GetTree, "", &tree)
+	return tree, err
+}
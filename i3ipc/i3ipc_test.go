@@ -0,0 +1,116 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i3ipc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestMessages(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(WriteMessage(&buf, GetTree, `{"a": 1}`))
+	assert.Equal("i3-ipc\x08\x00\x00\x00\x04\x00\x00\x00{\"a\": 1}", buf.String())
+
+	msgType, payload, err := ReadMessage(&buf)
+	assert.NoError(err)
+	assert.Equal(uint32(GetTree), msgType)
+	assert.Equal(`{"a": 1}`, string(payload))
+
+	_, _, err = ReadMessage(bytes.NewBufferString("i3-pc\x00\x00\x00\x00\x00\x00\x00\x00"))
+	assert.Error(err, "invalid magic")
+	_, _, err = ReadMessage(bytes.NewBufferString("i3-ipc\x08\x00\x00\x00\x04\x00\x00\x00{}"))
+	assert.Error(err, "truncated payload")
+}
+
+// serve starts a fake IPC server that replies to each message with the
+// given function, and returns the socket path.
+func serve(t *testing.T, reply func(uint32, []byte) string) (string, func()) {
+	dir, err := ioutil.TempDir("", "i3ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "ipc.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					msgType, payload, err := ReadMessage(conn)
+					if err != nil {
+						return
+					}
+					WriteMessage(conn, msgType, reply(msgType, payload))
+				}
+			}()
+		}
+	}()
+	return sock, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+	sock, cleanup := serve(t, func(msgType uint32, payload []byte) string {
+		switch msgType {
+		case RunCommand:
+			if string(payload) == "nop" {
+				return `[{"success": true}]`
+			}
+			return `[{"success": false, "error": "unknown command"}]`
+		case GetBindingState:
+			return `{"name": "resize"}`
+		case Subscribe:
+			return `{"success": false}`
+		}
+		return `{}`
+	})
+	defer cleanup()
+	c := Socket(sock)
+
+	var mode struct{ Name string }
+	assert.NoError(c.Call(GetBindingState, "", &mode))
+	assert.Equal("resize", mode.Name)
+
+	assert.NoError(c.Command("nop"))
+	assert.EqualError(c.Command("frobnicate"), "unknown command")
+
+	assert.Error(c.Subscribe([]string{"window"}, func(uint32, []byte) {}),
+		"failed subscription")
+
+	assert.Error(Socket(sock+".missing").Command("nop"), "missing socket")
+
+	os.Setenv("SWAYSOCK", "")
+	assert.Error(Sway().Command("nop"), "without sway socket")
+	os.Setenv("I3SOCK", sock)
+	assert.NoError(I3().Command("nop"))
+}
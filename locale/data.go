@@ -0,0 +1,179 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locale
+
+// English is the default locale.
+var English = &Locale{
+	Tag: "en",
+	Months: [12]string{"January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"},
+	ShortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun",
+		"Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	Days:      [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	ShortDays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	Decimal:   ".",
+	Group:     ",",
+
+	OrdinalSuffix: englishOrdinal,
+}
+
+var locales = map[string]*Locale{
+	"en": English,
+	"de": {
+		Tag: "de",
+		Months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun",
+			"Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		Days:      [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays: [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		Decimal:   ",",
+		Group:     ".",
+	},
+	"es": {
+		Tag: "es",
+		Months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio",
+			"julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun",
+			"jul", "ago", "sept", "oct", "nov", "dic"},
+		Days:      [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays: [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		Decimal:   ",",
+		Group:     ".",
+
+		OrdinalSuffix: masculineOrdinal,
+	},
+	"fr": {
+		Tag: "fr",
+		Months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin",
+			"juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:      [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays: [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		Decimal:   ",",
+		Group:     "\u202f",
+		// Sizes are measured in octets.
+		Units: map[string]string{
+			"B": "o", "kB": "ko", "MB": "Mo", "GB": "Go", "TB": "To", "PB": "Po", "EB": "Eo",
+			"KiB": "Kio", "MiB": "Mio", "GiB": "Gio", "TiB": "Tio", "PiB": "Pio", "EiB": "Eio",
+		},
+		PluralFromTwo: true,
+		OrdinalSuffix: frenchOrdinal,
+	},
+	"it": {
+		Tag: "it",
+		Months: [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno",
+			"luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		ShortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu",
+			"lug", "ago", "set", "ott", "nov", "dic"},
+		Days:      [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		ShortDays: [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		Decimal:   ",",
+		Group:     ".",
+
+		OrdinalSuffix: masculineOrdinal,
+	},
+	"nl": {
+		Tag: "nl",
+		Months: [12]string{"januari", "februari", "maart", "april", "mei", "juni",
+			"juli", "augustus", "september", "oktober", "november", "december"},
+		ShortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun",
+			"jul", "aug", "sep", "okt", "nov", "dec"},
+		Days:      [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		ShortDays: [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		Decimal:   ",",
+		Group:     ".",
+
+		OrdinalSuffix: dutchOrdinal,
+	},
+	"pt": {
+		Tag: "pt",
+		Months: [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho",
+			"julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		ShortMonths: [12]string{"jan", "fev", "mar", "abr", "mai", "jun",
+			"jul", "ago", "set", "out", "nov", "dez"},
+		Days: [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira",
+			"quinta-feira", "sexta-feira", "sábado"},
+		ShortDays: [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+		Decimal:   ",",
+		Group:     ".",
+
+		OrdinalSuffix: masculineOrdinal,
+	},
+	"sv": {
+		Tag: "sv",
+		Months: [12]string{"januari", "februari", "mars", "april", "maj", "juni",
+			"juli", "augusti", "september", "oktober", "november", "december"},
+		ShortMonths: [12]string{"jan", "feb", "mars", "apr", "maj", "juni",
+			"juli", "aug", "sep", "okt", "nov", "dec"},
+		Days:      [7]string{"söndag", "måndag", "tisdag", "onsdag", "torsdag", "fredag", "lördag"},
+		ShortDays: [7]string{"sön", "mån", "tis", "ons", "tors", "fre", "lör"},
+		Decimal:   ",",
+		Group:     "\u00a0",
+
+		OrdinalSuffix: swedishOrdinal,
+	},
+}
+
+// englishOrdinal returns "st", "nd", "rd", or "th" for an ordinal number.
+func englishOrdinal(n int64) string {
+	if n < 0 {
+		n = -n
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		return "th"
+	}
+	switch n % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
+
+// masculineOrdinal returns the masculine ordinal indicator, as used in
+// Spanish, Italian, and Portuguese.
+func masculineOrdinal(n int64) string {
+	return "º"
+}
+
+// frenchOrdinal returns "er" for the first, and "e" for others.
+func frenchOrdinal(n int64) string {
+	if n == 1 {
+		return "er"
+	}
+	return "e"
+}
+
+// dutchOrdinal returns "e" for all ordinal numbers.
+func dutchOrdinal(n int64) string {
+	return "e"
+}
+
+// swedishOrdinal returns ":a" for ordinal numbers ending in 1 or 2, except
+// for 11 and 12, and ":e" for others.
+func swedishOrdinal(n int64) string {
+	if n < 0 {
+		n = -n
+	}
+	if (n%10 == 1 || n%10 == 2) && n%100 != 11 && n%100 != 12 {
+		return ":a"
+	}
+	return ":e"
+}
@@ -0,0 +1,242 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package locale formats dates, numbers, and units for the user's locale, so
+that modules can show e.g. "Montag, 3. Juni" or "1,5 GiB" instead of
+hardcoded English formatting.
+
+The locale is read from the LC_ALL, LC_TIME, or LANG environment variables
+(in that order), and can be changed using Set. Locales that are not known
+fall back to just the language (e.g. "de" for "de_AT.UTF-8"), and then to
+English.
+
+Typical usage would be:
+
+	l := locale.Current()
+	l.FormatTime(now, "Monday, 2 January") // "Montag, 2 Januar" for de.
+	l.FormatFloat(1.5, 2)                  // "1,50" for de.
+	l.Unit("KiB")                          // "Kio" for fr.
+
+Templates created using outputs.TextTemplate and outputs.PangoTemplate
+can use the "number", "date", "plural", and "ordinal" functions for
+localized formatting, e.g. {{.Min1 | number 2}}, {{.Now | date "Mon 2 Jan"}},
+or {{.Count | plural "update" "updates"}}. See the format package
+for values with units, such as sizes and temperatures.
+*/
+package locale
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Locale has the names and separators used to format values for a locale.
+type Locale struct {
+	// The tag of the locale, e.g. "de" or "pt_br".
+	Tag string
+	// Month and day names, starting with January and Sunday.
+	Months      [12]string
+	ShortMonths [12]string
+	Days        [7]string
+	ShortDays   [7]string
+	// The decimal separator, and the separator between groups of thousands.
+	Decimal string
+	Group   string
+	// Translations of unit labels, e.g. "MB" to "Mo" in French. Units that
+	// are not translated are shown as is.
+	Units map[string]string
+	// Whether only counts of 2 or more take the plural form, as in French,
+	// rather than any count other than 1, as in English.
+	PluralFromTwo bool
+	// OrdinalSuffix returns the suffix for an ordinal number, e.g. "nd" for
+	// 2 in English. If nil, ordinals end with a ".", as in German.
+	OrdinalSuffix func(n int64) string
+}
+
+var (
+	mu      sync.Mutex
+	current *Locale
+)
+
+// Current returns the current locale.
+func Current() *Locale {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		current = fromEnv()
+	}
+	return current
+}
+
+// fromEnv returns the locale set in the environment, or English.
+func fromEnv() *Locale {
+	for _, env := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if tag := os.Getenv(env); tag != "" {
+			if l, ok := Get(tag); ok {
+				return l
+			}
+			break
+		}
+	}
+	return English
+}
+
+// Set sets the current locale by tag, e.g. "de", "fr_FR", or "pt-BR.UTF-8",
+// and returns false if the locale (and its language) is not known, in which
+// case the current locale is not changed.
+func Set(tag string) bool {
+	l, ok := Get(tag)
+	if ok {
+		SetLocale(l)
+	}
+	return ok
+}
+
+// SetLocale sets the current locale, e.g. to a custom locale.
+func SetLocale(l *Locale) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = l
+}
+
+// Get returns the locale for the tag, falling back to its language, and
+// returns false if neither is known. The POSIX "C" locale is English.
+func Get(tag string) (*Locale, bool) {
+	tag = strings.ToLower(tag)
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	tag = strings.Replace(tag, "-", "_", -1)
+	if tag == "c" || tag == "posix" {
+		return English, true
+	}
+	if l, ok := locales[tag]; ok {
+		return l, true
+	}
+	if i := strings.Index(tag, "_"); i >= 0 {
+		l, ok := locales[tag[:i]]
+		return l, ok
+	}
+	return nil, false
+}
+
+// Known returns the tags of all known locales.
+func Known() []string {
+	var tags []string
+	for tag := range locales {
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// nameTokens are the parts of a time layout that are replaced with names
+// from the locale, longest first so that "January" is not read as "Jan".
+var nameTokens = []string{"January", "Monday", "Jan", "Mon"}
+
+// FormatTime formats the time like time.Format, but with the month and day
+// names of the locale.
+func (l *Locale) FormatTime(t time.Time, layout string) string {
+	var out []string
+	start := 0
+	for i := 0; i < len(layout); {
+		token := ""
+		for _, n := range nameTokens {
+			if strings.HasPrefix(layout[i:], n) {
+				token = n
+				break
+			}
+		}
+		if token == "" {
+			i++
+			continue
+		}
+		if start < i {
+			out = append(out, t.Format(layout[start:i]))
+		}
+		out = append(out, l.name(t, token))
+		i += len(token)
+		start = i
+	}
+	if start < len(layout) {
+		out = append(out, t.Format(layout[start:]))
+	}
+	return strings.Join(out, "")
+}
+
+func (l *Locale) name(t time.Time, token string) string {
+	switch token {
+	case "January":
+		return l.Months[t.Month()-1]
+	case "Jan":
+		return l.ShortMonths[t.Month()-1]
+	case "Monday":
+		return l.Days[t.Weekday()]
+	default:
+		return l.ShortDays[t.Weekday()]
+	}
+}
+
+// FormatFloat formats the number with the given number of decimal places,
+// using the locale's decimal separator.
+func (l *Locale) FormatFloat(v float64, precision int) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', precision, 64), ".", l.Decimal, 1)
+}
+
+// FormatInt formats the integer with the locale's separator between groups
+// of thousands, e.g. "1,234,567" in English.
+func (l *Locale) FormatInt(v int64) string {
+	digits := strconv.FormatInt(v, 10)
+	sign := ""
+	if v < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var groups []string
+	for len(digits) > 3 {
+		groups = append([]string{digits[len(digits)-3:]}, groups...)
+		digits = digits[:len(digits)-3]
+	}
+	groups = append([]string{digits}, groups...)
+	return sign + strings.Join(groups, l.Group)
+}
+
+// Plural returns the singular or plural form of a word for the count, e.g.
+// Plural(3, "update", "updates") is "updates".
+func (l *Locale) Plural(n float64, one, other string) string {
+	if n == 1 || (l.PluralFromTwo && n >= 0 && n < 2) {
+		return one
+	}
+	return other
+}
+
+// FormatOrdinal formats the integer as an ordinal number, e.g. "2nd" in
+// English, or "2." in German.
+func (l *Locale) FormatOrdinal(n int64) string {
+	suffix := "."
+	if l.OrdinalSuffix != nil {
+		suffix = l.OrdinalSuffix(n)
+	}
+	return l.FormatInt(n) + suffix
+}
+
+// Unit returns the label for the unit in the locale.
+func (l *Locale) Unit(unit string) string {
+	if label, ok := l.Units[unit]; ok {
+		return label
+	}
+	return unit
+}
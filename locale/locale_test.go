@@ -0,0 +1,144 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locale
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"de":          "de",
+		"de_DE.UTF-8": "de",
+		"fr-CA":       "fr",
+		"PT_br":       "pt",
+		"sv_SE@euro":  "sv",
+		"C":           "en",
+		"POSIX":       "en",
+		"en_US.UTF-8": "en",
+	} {
+		l, ok := Get(tag)
+		if assert.True(t, ok, tag) {
+			assert.Equal(t, expected, l.Tag, tag)
+		}
+	}
+	_, ok := Get("xx_YY")
+	assert.False(t, ok, "unknown locale")
+	assert.Contains(t, Known(), "de")
+}
+
+func TestCurrent(t *testing.T) {
+	defer SetLocale(English)
+
+	assert.True(t, Set("de_DE"))
+	assert.Equal(t, "de", Current().Tag)
+	assert.False(t, Set("xx"), "unknown locale")
+	assert.Equal(t, "de", Current().Tag, "unchanged by unknown locale")
+
+	for _, env := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	assert.Equal(t, English, fromEnv(), "English by default")
+	os.Setenv("LANG", "fr_FR.UTF-8")
+	assert.Equal(t, "fr", fromEnv().Tag, "from LANG")
+	os.Setenv("LC_TIME", "it_IT.UTF-8")
+	assert.Equal(t, "it", fromEnv().Tag, "LC_TIME overrides LANG")
+	os.Setenv("LC_ALL", "xx_XX")
+	assert.Equal(t, English, fromEnv(), "unknown LC_ALL is not skipped")
+}
+
+func TestFormatTime(t *testing.T) {
+	when := time.Date(2017, time.March, 5, 14, 30, 0, 0, time.UTC)
+	de, _ := Get("de")
+	fr, _ := Get("fr")
+	for _, tc := range []struct {
+		locale   *Locale
+		layout   string
+		expected string
+	}{
+		{English, "Monday, 2 January 2006 15:04", "Sunday, 5 March 2017 14:30"},
+		{de, "Monday, 2. January 2006 15:04", "Sonntag, 5. März 2017 14:30"},
+		{de, "Mon 02 Jan", "So 05 Mär"},
+		{fr, "Mon 2 Jan", "dim. 5 mars"},
+		{fr, "15:04", "14:30"},
+		{fr, "Janet", "marset"},
+		{fr, "", ""},
+	} {
+		assert.Equal(t, tc.expected, tc.locale.FormatTime(when, tc.layout),
+			"%s: %q", tc.locale.Tag, tc.layout)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	de, _ := Get("de")
+	fr, _ := Get("fr")
+	assert.Equal(t, "3.14", English.FormatFloat(3.14159, 2))
+	assert.Equal(t, "3,14", de.FormatFloat(3.14159, 2))
+	assert.Equal(t, "3", de.FormatFloat(3.14159, 0))
+	assert.Equal(t, "1,234,567", English.FormatInt(1234567))
+	assert.Equal(t, "-1.234", de.FormatInt(-1234))
+	assert.Equal(t, "123", de.FormatInt(123))
+	assert.Equal(t, "1\u202f000", fr.FormatInt(1000))
+}
+
+func TestUnits(t *testing.T) {
+	fr, _ := Get("fr")
+	assert.Equal(t, "KiB", English.Unit("KiB"))
+	assert.Equal(t, "Kio", fr.Unit("KiB"))
+	assert.Equal(t, "Mo", fr.Unit("MB"))
+	assert.Equal(t, "RPM", fr.Unit("RPM"), "untranslated units")
+}
+
+func TestPlural(t *testing.T) {
+	fr, _ := Get("fr")
+	for n, expected := range map[float64]string{
+		0: "updates", 1: "update", 1.5: "updates", 2: "updates", -1: "updates",
+	} {
+		assert.Equal(t, expected, English.Plural(n, "update", "updates"), "en %v", n)
+	}
+	for n, expected := range map[float64]string{
+		0: "heure", 1: "heure", 1.5: "heure", 2: "heures", -1: "heures",
+	} {
+		assert.Equal(t, expected, fr.Plural(n, "heure", "heures"), "fr %v", n)
+	}
+}
+
+func TestOrdinals(t *testing.T) {
+	for n, expected := range map[int64]string{
+		1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th",
+		13: "13th", 21: "21st", 102: "102nd", 111: "111th", 1001: "1,001st",
+	} {
+		assert.Equal(t, expected, English.FormatOrdinal(n))
+	}
+	for tag, expected := range map[string][]string{
+		"de": {"1.", "2.", "11."},
+		"es": {"1º", "2º", "11º"},
+		"fr": {"1er", "2e", "11e"},
+		"nl": {"1e", "2e", "11e"},
+		"sv": {"1:a", "2:a", "11:e"},
+	} {
+		l, _ := Get(tag)
+		for i, n := range []int64{1, 2, 11} {
+			assert.Equal(t, expected[i], l.FormatOrdinal(n), "%s %d", tag, n)
+		}
+	}
+	custom := &Locale{Group: ","}
+	assert.Equal(t, "3.", custom.FormatOrdinal(3), "default suffix")
+}
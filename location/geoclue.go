@@ -0,0 +1,120 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package location
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	geoclueDest    = "org.freedesktop.GeoClue2"
+	managerPath    = "/org/freedesktop/GeoClue2/Manager"
+	managerIface   = geoclueDest + ".Manager"
+	clientIface    = geoclueDest + ".Client"
+	locationIface  = geoclueDest + ".Location"
+	propsIface     = "org.freedesktop.DBus.Properties"
+	locationSignal = clientIface + ".LocationUpdated"
+)
+
+// GeoClue accuracy levels. City level is accurate enough for weather and
+// solar times, and does not need GPS or wifi-based positioning.
+const accuracyCity = uint32(4)
+
+// distanceThreshold is the distance in metres that the location needs to
+// change by before GeoClue sends an update.
+const distanceThreshold = uint32(1000)
+
+// desktopID identifies the bar to GeoClue, which may use it to decide
+// whether to allow access to the location.
+const desktopID = "barista"
+
+// watchGeoClue calls update with each location provided by GeoClue.
+// It only returns on error. Replaced in tests.
+var watchGeoClue = func(update func(lat, lng float64)) error {
+	// A private connection is required since we're using Signal.
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return err
+	}
+	if err := conn.Hello(); err != nil {
+		return err
+	}
+	var client dbus.ObjectPath
+	err = conn.Object(geoclueDest, managerPath).
+		Call(managerIface+".GetClient", 0).Store(&client)
+	if err != nil {
+		return err
+	}
+	obj := conn.Object(geoclueDest, client)
+	for _, prop := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"DesktopId", desktopID},
+		{"RequestedAccuracyLevel", accuracyCity},
+		{"DistanceThreshold", distanceThreshold},
+	} {
+		err := obj.Call(propsIface+".Set", 0,
+			clientIface, prop.name, dbus.MakeVariant(prop.value)).Err
+		if err != nil {
+			return err
+		}
+	}
+	matchRule := strings.Join([]string{
+		"type='signal'",
+		"interface='" + clientIface + "'",
+		"member='LocationUpdated'",
+		"path='" + string(client) + "'",
+	}, ",")
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		return err
+	}
+	c := make(chan *dbus.Signal, 10)
+	conn.Signal(c)
+	if err := obj.Call(clientIface+".Start", 0).Err; err != nil {
+		return err
+	}
+	defer obj.Call(clientIface+".Stop", 0)
+	for sig := range c {
+		if sig.Name != locationSignal || len(sig.Body) < 2 {
+			continue
+		}
+		// LocationUpdated has the old and new location objects.
+		path, ok := sig.Body[1].(dbus.ObjectPath)
+		if !ok {
+			continue
+		}
+		var props map[string]dbus.Variant
+		err := conn.Object(geoclueDest, path).
+			Call(propsIface+".GetAll", 0, locationIface).Store(&props)
+		if err != nil {
+			return err
+		}
+		lat, latOk := props["Latitude"].Value().(float64)
+		lng, lngOk := props["Longitude"].Value().(float64)
+		if latOk && lngOk {
+			update(lat, lng)
+		}
+	}
+	return errors.New("geoclue connection closed")
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package location provides the current location to modules that need it, such
+as weather, sunrise and sunset schedules, and prayer times, so that the
+location only needs to be configured once for the bar.
+
+The location can be set manually, and can also be provided by GeoClue, in
+which case it is updated automatically, e.g. while travelling. The manual
+location is used as a fallback until GeoClue provides a location, or if
+GeoClue is not available.
+
+Typical usage would be:
+
+	location.Set(40.7128, -74.0060)
+	location.UseGeoClue()
+	...
+	weather.New(openweathermap.Here().APIKey(key).Build())
+	prayer.Here()
+*/
+package location
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/base/listeners"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("location")
+
+// ErrUnknown is returned by lookups that need the current location before it
+// is known, e.g. while waiting for GeoClue.
+var ErrUnknown = errors.New("location is not known yet")
+
+// coords is a position in degrees, with north and east positive.
+type coords struct {
+	lat, lng float64
+}
+
+var (
+	mu sync.Mutex
+	// manual is the location set using Set, and auto is the location
+	// provided by GeoClue, which takes precedence.
+	manual, auto *coords
+	geoclue      bool
+)
+
+// onChange is notified whenever the location changes.
+var onChange listeners.Set
+
+// OnChange adds a function that will be called whenever the current location
+// changes, and returns a function that removes it.
+func OnChange(f func()) (remove func()) {
+	return onChange.Add(f)
+}
+
+// Get returns the current latitude and longitude, in degrees with north and
+// east positive. ok is false if the location is not known yet.
+func Get() (lat, lng float64, ok bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	c := current()
+	if c == nil {
+		return 0, 0, false
+	}
+	return c.lat, c.lng, true
+}
+
+// current returns the location in use, and must be called with mu held.
+func current() *coords {
+	if auto != nil {
+		return auto
+	}
+	return manual
+}
+
+// Set sets the location manually, given by latitude and longitude in degrees
+// (north and east are positive). If GeoClue is in use, this location is only
+// used until GeoClue provides one.
+func Set(lat, lng float64) {
+	update(&manual, lat, lng)
+}
+
+// update sets one of the locations, and notifies listeners if the current
+// location changed as a result.
+func update(which **coords, lat, lng float64) {
+	mu.Lock()
+	before := current()
+	*which = &coords{lat, lng}
+	changed := before == nil || *before != *current()
+	mu.Unlock()
+	if !changed {
+		return
+	}
+	onChange.Notify()
+}
+
+// retryDelay is how long to wait before reconnecting to GeoClue after an
+// error, e.g. if it is not running yet.
+var retryDelay = time.Minute
+
+// UseGeoClue starts updating the location from GeoClue, in the background.
+// Errors are logged, and GeoClue is retried periodically, with the last
+// known location (or the manual location) used in the meantime. Calling
+// UseGeoClue again has no effect.
+func UseGeoClue() {
+	mu.Lock()
+	defer mu.Unlock()
+	if geoclue {
+		return
+	}
+	geoclue = true
+	go func() {
+		for {
+			err := watchGeoClue(func(lat, lng float64) {
+				log.Fine("location updated", "lat", lat, "lng", lng)
+				update(&auto, lat, lng)
+			})
+			log.Error("geoclue failed", "error", err)
+			time.Sleep(retryDelay)
+		}
+	}()
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package location
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func init() {
+	retryDelay = time.Millisecond
+}
+
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	manual, auto = nil, nil
+	geoclue = false
+}
+
+func assertLocation(t *testing.T, lat, lng float64, message string) {
+	gotLat, gotLng, ok := Get()
+	assert.True(t, ok, message)
+	assert.Equal(t, lat, gotLat, message)
+	assert.Equal(t, lng, gotLng, message)
+}
+
+func TestManual(t *testing.T) {
+	reset()
+	_, _, ok := Get()
+	assert.False(t, ok, "location unknown by default")
+
+	changes := 0
+	remove := OnChange(func() { changes++ })
+	Set(51.5, -0.12)
+	assertLocation(t, 51.5, -0.12, "manual location")
+	assert.Equal(t, 1, changes)
+
+	Set(51.5, -0.12)
+	assert.Equal(t, 1, changes, "not notified when unchanged")
+	Set(48.85, 2.35)
+	assertLocation(t, 48.85, 2.35, "manual location updated")
+	assert.Equal(t, 2, changes)
+
+	remove()
+	Set(40.7, -74)
+	assert.Equal(t, 2, changes, "not notified after removing")
+}
+
+func TestGeoClue(t *testing.T) {
+	reset()
+	Set(51.5, -0.12)
+
+	var attempts int32
+	updates := make(chan [2]float64)
+	watchGeoClue = func(update func(lat, lng float64)) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return errors.New("geoclue is not running")
+		}
+		for u := range updates {
+			update(u[0], u[1])
+		}
+		return errors.New("closed")
+	}
+	changes := make(chan struct{}, 10)
+	defer OnChange(func() { changes <- struct{}{} })()
+
+	UseGeoClue()
+	UseGeoClue()
+	assertLocation(t, 51.5, -0.12, "manual location until geoclue provides one")
+
+	updates <- [2]float64{48.85, 2.35}
+	<-changes
+	assertLocation(t, 48.85, 2.35, "location from geoclue")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts),
+		"retried after error, and started only once")
+
+	Set(40.7, -74)
+	assertLocation(t, 48.85, 2.35, "geoclue location takes precedence")
+	select {
+	case <-changes:
+		assert.Fail(t, "notified when current location is unchanged")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	updates <- [2]float64{52.52, 13.4}
+	<-changes
+	assertLocation(t, 52.52, 13.4, "location updated by geoclue")
+}
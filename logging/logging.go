@@ -0,0 +1,346 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package logging provides named loggers for modules, with the verbosity
+controlled separately for each logger, and logs written to a file (or any
+io.Writer) instead of standard output, which is used by the bar protocol.
+
+Typical usage would be:
+
+	var log = logging.New("modules/clock")
+
+	func (m *module) update() {
+	    log.Fine("updating", "format", m.format)
+	    if err := doSomething(); err != nil {
+	        log.Error("update failed", "error", err)
+	    }
+	}
+
+Each entry is a message followed by key-value pairs, written on a single
+line with the time, level, and logger name, e.g.
+
+	2017-06-01 12:34:56.789 E [modules/clock] update failed error="timed out"
+
+Only errors are logged by default. The verbosity is set using rules of the
+form "pattern=level", separated by commas, where the pattern matches logger
+names as in path.Match, and later rules override earlier ones, e.g.
+
+	*=info,modules/*=fine,modules/clock=error
+
+The rules and the log file are read from the BARISTA_LOG and
+BARISTA_LOG_FILE environment variables, can be set from flags using
+Configure and SetFile, and can be changed while the bar is running using
+Listen, e.g. "echo 'modules/*=fine' | socat - UNIX-CONNECT:/path/to/socket".
+Logs are written to standard error if no log file is set.
+*/
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the verbosity of a log entry.
+type Level int
+
+// Log levels, from least to most verbose.
+const (
+	Error Level = iota
+	Info
+	Fine
+)
+
+var levelNames = map[Level]string{Error: "error", Info: "info", Fine: "fine"}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// rule sets the verbosity for loggers with names that match the pattern.
+type rule struct {
+	pattern string
+	level   Level
+}
+
+var (
+	mu     sync.Mutex
+	rules  []rule
+	output io.Writer
+	file   *os.File
+	// Used to read the environment variables before the first entry is
+	// logged or the configuration is changed.
+	once sync.Once
+)
+
+// setup reads the configuration from the environment. Must be called with
+// mu held.
+func setup() {
+	once.Do(func() {
+		output = os.Stderr
+		if spec := os.Getenv("BARISTA_LOG"); spec != "" {
+			if r, err := parseRules(spec); err == nil {
+				rules = r
+			}
+		}
+		if path := os.Getenv("BARISTA_LOG_FILE"); path != "" {
+			setFile(path)
+		}
+	})
+}
+
+// Configure replaces the verbosity rules, e.g. from a command line flag.
+// See the package documentation for the format.
+func Configure(spec string) error {
+	r, err := parseRules(spec)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	setup()
+	rules = r
+	return nil
+}
+
+// parseRules parses comma separated verbosity rules.
+func parseRules(spec string) ([]rule, error) {
+	var parsed []rule
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rule %q, expected pattern=level", item)
+		}
+		pattern := strings.TrimSpace(parts[0])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		level, err := parseLevel(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, rule{pattern, level})
+	}
+	return parsed, nil
+}
+
+// parseLevel parses a level by name, or number.
+func parseLevel(name string) (Level, error) {
+	for level, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return level, nil
+		}
+	}
+	if v, err := strconv.Atoi(name); err == nil && v >= 0 {
+		return Level(v), nil
+	}
+	return Error, fmt.Errorf("unknown level %q", name)
+}
+
+// SetOutput sets the writer that log entries are written to.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	setup()
+	closeFile()
+	output = w
+}
+
+// Writer returns a writer that writes directly to the log output, e.g. for
+// multi-line diagnostics that do not fit in a log entry.
+func Writer() io.Writer {
+	return writer{}
+}
+
+type writer struct{}
+
+func (writer) Write(p []byte) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	setup()
+	return output.Write(p)
+}
+
+// SetFile appends log entries to the file at the given path, creating it
+// if necessary.
+func SetFile(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	setup()
+	return setFile(path)
+}
+
+// setFile opens the log file. Must be called with mu held.
+func setFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	closeFile()
+	file, output = f, f
+	return nil
+}
+
+// closeFile closes the log file, if any. Must be called with mu held.
+func closeFile() {
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+}
+
+// levelFor returns the verbosity for the named logger, as set by the last
+// matching rule. Must be called with mu held.
+func levelFor(name string) Level {
+	level := Error
+	for _, r := range rules {
+		if ok, _ := path.Match(r.pattern, name); ok {
+			level = r.level
+		}
+	}
+	return level
+}
+
+// Logger writes log entries for a module or package.
+type Logger struct {
+	name string
+}
+
+// New creates a logger with the given name, which is used to set its
+// verbosity, and is included in each entry.
+func New(name string) *Logger {
+	return &Logger{name: name}
+}
+
+// Name returns the name of the logger.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Enabled returns true if entries at the given level are logged, e.g. to
+// skip computing expensive values that are only logged at Fine.
+func (l *Logger) Enabled(level Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	setup()
+	return level <= levelFor(l.name)
+}
+
+// Log writes an entry with the message and key-value pairs if the logger's
+// verbosity includes the given level.
+func (l *Logger) Log(level Level, message string, keyvals ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	setup()
+	if level > levelFor(l.name) {
+		return
+	}
+	fmt.Fprintln(output, format(time.Now(), level, l.name, message, keyvals))
+}
+
+// Error logs an entry at the Error level.
+func (l *Logger) Error(message string, keyvals ...interface{}) {
+	l.Log(Error, message, keyvals...)
+}
+
+// Info logs an entry at the Info level.
+func (l *Logger) Info(message string, keyvals ...interface{}) {
+	l.Log(Info, message, keyvals...)
+}
+
+// Fine logs an entry at the Fine level.
+func (l *Logger) Fine(message string, keyvals ...interface{}) {
+	l.Log(Fine, message, keyvals...)
+}
+
+// format formats an entry as a single line.
+func format(now time.Time, level Level, name, message string, keyvals []interface{}) string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s %s [%s] %s",
+		now.Format("2006-01-02 15:04:05.000"),
+		strings.ToUpper(level.String()[:1]), name, quote(message, false))
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		value := "(missing)"
+		if i+1 < len(keyvals) {
+			value = fmt.Sprint(keyvals[i+1])
+		}
+		fmt.Fprintf(&out, " %s=%s", key, quote(value, true))
+	}
+	return out.String()
+}
+
+// quote quotes a string if it would otherwise be ambiguous, i.e. if it
+// spans multiple lines, or if it is a value that contains spaces, quotes,
+// or equals signs.
+func quote(s string, value bool) string {
+	special := "\n\r"
+	if value {
+		special += " \t\"="
+	}
+	if strings.ContainsAny(s, special) || (value && s == "") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Listen accepts verbosity rules on a unix socket, one set of rules per
+// line, so that verbosity can be changed while the bar is running. Each
+// line receives "ok" or an error in response.
+func Listen(socket string) error {
+	os.Remove(socket)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return nil
+}
+
+// serve applies the rules from each line received on the connection.
+func serve(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		response := "ok"
+		if err := Configure(scanner.Text()); err != nil {
+			response = err.Error()
+		}
+		fmt.Fprintln(conn, response)
+	}
+}
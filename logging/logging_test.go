@@ -0,0 +1,167 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func capture(t *testing.T, spec string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	SetOutput(buf)
+	assert.Nil(t, Configure(spec))
+	return buf
+}
+
+func lines(buf *bytes.Buffer) []string {
+	var out []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		// Strip the timestamp.
+		out = append(out, strings.SplitN(line, " ", 3)[2])
+	}
+	buf.Reset()
+	return out
+}
+
+func TestLevels(t *testing.T) {
+	buf := capture(t, "")
+	clock := New("modules/clock")
+	assert.Equal(t, "modules/clock", clock.Name())
+	clock.Error("failed")
+	clock.Info("info")
+	clock.Fine("fine")
+	assert.Equal(t, []string{"E [modules/clock] failed"}, lines(buf),
+		"only errors logged by default")
+
+	assert.Nil(t, Configure("*=info, modules/*=fine,modules/clock=error"))
+	bar := New("bar")
+	cpu := New("modules/cpuload")
+	for _, l := range []*Logger{bar, cpu, clock} {
+		l.Info("info")
+		l.Fine("fine")
+	}
+	assert.Equal(t, []string{
+		"I [bar] info",
+		"I [modules/cpuload] info",
+		"F [modules/cpuload] fine",
+	}, lines(buf), "later rules override earlier ones")
+	assert.True(t, cpu.Enabled(Fine))
+	assert.False(t, clock.Enabled(Info))
+
+	assert.Nil(t, Configure("bar=2"), "numeric level")
+	assert.True(t, bar.Enabled(Fine))
+	assert.Equal(t, "Level(5)", Level(5).String())
+}
+
+func TestInvalidRules(t *testing.T) {
+	capture(t, "*=fine")
+	for spec, message := range map[string]string{
+		"foo":       "expected pattern=level",
+		"foo=loud":  `unknown level "loud"`,
+		"[=fine":    "invalid pattern",
+		"*=fine,a=": `unknown level ""`,
+	} {
+		err := Configure(spec)
+		if assert.Error(t, err, spec) {
+			assert.Contains(t, err.Error(), message, spec)
+		}
+	}
+	assert.True(t, New("foo").Enabled(Fine), "rules unchanged on error")
+}
+
+func TestFormat(t *testing.T) {
+	when := time.Date(2017, time.June, 1, 12, 34, 56, 789000000, time.UTC)
+	assert.Equal(t,
+		`2017-06-01 12:34:56.789 E [clock] update failed error="timed out" empty="" n=3 eq="a=b"`,
+		format(when, Error, "clock", "update failed",
+			[]interface{}{"error", "timed out", "empty", "", "n", 3, "eq", "a=b"}))
+	assert.Equal(t,
+		`2017-06-01 12:34:56.789 I [x] "two\nlines" odd=(missing)`,
+		format(when, Info, "x", "two\nlines", []interface{}{"odd"}))
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "barista.log")
+
+	capture(t, "test=info")
+	assert.Nil(t, SetFile(path))
+	New("test").Info("to file", "n", 1)
+	New("test").Fine("not logged")
+	assert.Nil(t, SetFile(path), "reopening appends")
+	New("test").Info("again")
+	SetOutput(ioutil.Discard)
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"I [test] to file n=1", "I [test] again"},
+		lines(bytes.NewBuffer(data)))
+
+	assert.Error(t, SetFile(filepath.Join(dir, "missing", "barista.log")))
+}
+
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "log.sock")
+
+	capture(t, "")
+	assert.Nil(t, Listen(socket))
+	conn, err := net.Dial("unix", socket)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+	responses := bufio.NewScanner(conn)
+
+	conn.Write([]byte("modules/*=fine\n"))
+	assert.True(t, responses.Scan())
+	assert.Equal(t, "ok", responses.Text())
+	assert.True(t, New("modules/clock").Enabled(Fine), "verbosity changed over socket")
+
+	conn.Write([]byte("oops\n"))
+	assert.True(t, responses.Scan())
+	assert.Contains(t, responses.Text(), "expected pattern=level")
+	assert.True(t, New("modules/clock").Enabled(Fine), "unchanged by invalid rules")
+
+	assert.Error(t, Listen(filepath.Join(dir, "missing", "log.sock")))
+}
+
+func TestWriter(t *testing.T) {
+	buf := capture(t, "")
+	w := Writer()
+	w.Write([]byte("raw\n"))
+	other := &bytes.Buffer{}
+	SetOutput(other)
+	w.Write([]byte("follows output\n"))
+	assert.Equal(t, "raw\n", buf.String())
+	assert.Equal(t, "follows output\n", other.String())
+}
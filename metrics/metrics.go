@@ -0,0 +1,247 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package metrics exports the bar's internal statistics, and values chosen by
+the user, in the Prometheus text format, so that a machine can be monitored
+using the data the bar already collects.
+
+The exported metrics are:
+
+	barista_start_time_seconds: when the bar was started.
+	barista_prints_total, barista_print_seconds_total: bar prints, and the
+	    time spent printing.
+	barista_module_updates_total: outputs sent by each module.
+	barista_module_update_latency_seconds_total: time that outputs waited
+	    for the bar.
+	barista_module_events_total: click events sent to each module.
+	barista_module_error: 1 if the module is showing an error, 0 otherwise.
+
+Module metrics have "module" (the name in the i3bar protocol) and "type"
+labels. Any gauges created using NewGauge are exported as well, e.g. to
+export the battery level from an output function:
+
+	var batteryLevel = metrics.NewGauge("battery_percent", "Remaining battery.")
+	...
+	battery.Default().OutputFunc(func(i battery.Info) bar.Output {
+		batteryLevel.Set(i.Remaining() * 100)
+		...
+	})
+
+The metrics are served at /metrics by the diagnostics endpoint, so they can
+be scraped from e.g. "localhost:6060/metrics" (see the diagnostics package),
+or Handler can be used to serve them elsewhere.
+*/
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("metrics")
+
+// Gauge is a value exported as a prometheus gauge, optionally with labels.
+type Gauge struct {
+	name   string
+	help   string
+	labels []string
+
+	mutex  sync.Mutex
+	values map[string]sample
+}
+
+// sample is a single value of a gauge, for a set of label values.
+type sample struct {
+	labels []string
+	value  float64
+}
+
+var (
+	gaugesMutex sync.Mutex
+	gauges      = map[string]*Gauge{}
+)
+
+// NewGauge creates a gauge with the given name, help text, and label names.
+// Creating a gauge with the name of an existing gauge returns the existing
+// gauge, so that reloaded modules can keep exporting the same values.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	gaugesMutex.Lock()
+	defer gaugesMutex.Unlock()
+	if g, ok := gauges[name]; ok {
+		return g
+	}
+	g := &Gauge{name: name, help: help, labels: labels, values: map[string]sample{}}
+	gauges[name] = g
+	return g
+}
+
+// Set sets the value of the gauge for the given label values, which must
+// match the label names given when creating the gauge.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	if len(labelValues) != len(g.labels) {
+		log.Error("wrong number of labels", "gauge", g.name,
+			"expected", len(g.labels), "got", len(labelValues))
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.values[strings.Join(labelValues, "\x00")] = sample{labelValues, value}
+}
+
+// Delete removes the value of the gauge for the given label values, e.g.
+// when a disk is unmounted.
+func (g *Gauge) Delete(labelValues ...string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.values, strings.Join(labelValues, "\x00"))
+}
+
+// errorReporter is implemented by modules that can report their last error.
+type errorReporter interface {
+	LastError() error
+}
+
+// Handler returns an HTTP handler that serves the metrics for the bar.
+func Handler(b *bar.I3Bar) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w, b)
+	})
+}
+
+// Write writes the metrics for the bar in the prometheus text format.
+func Write(w io.Writer, b *bar.I3Bar) error {
+	out := bufio.NewWriter(w)
+	stats := b.Stats()
+	if !stats.Started.IsZero() {
+		metric(out, "barista_start_time_seconds", "gauge",
+			"The time the bar was started, in seconds since the epoch.")
+		value(out, "barista_start_time_seconds", nil,
+			float64(stats.Started.UnixNano())/1e9)
+	}
+	metric(out, "barista_prints_total", "counter", "The number of times the bar was printed.")
+	value(out, "barista_prints_total", nil, float64(stats.Prints))
+	metric(out, "barista_print_seconds_total", "counter", "The time spent printing the bar.")
+	value(out, "barista_print_seconds_total", nil, stats.PrintTime.Seconds())
+
+	moduleMetrics := []struct {
+		name, kind, help string
+		value            func(bar.ModuleStats) float64
+	}{
+		{"barista_module_updates_total", "counter", "The number of outputs sent by the module.",
+			func(m bar.ModuleStats) float64 { return float64(m.Updates) }},
+		{"barista_module_update_latency_seconds_total", "counter",
+			"The total time that outputs from the module waited for the bar.",
+			func(m bar.ModuleStats) float64 { return m.Latency.Seconds() }},
+		{"barista_module_events_total", "counter", "The number of click events sent to the module.",
+			func(m bar.ModuleStats) float64 { return float64(m.Events) }},
+		{"barista_module_error", "gauge", "Whether the module is showing an error.",
+			func(m bar.ModuleStats) float64 {
+				if m.Error != nil {
+					return 1
+				}
+				return 0
+			}},
+	}
+	for _, mm := range moduleMetrics {
+		metric(out, mm.name, mm.kind, mm.help)
+		for _, m := range stats.Modules {
+			value(out, mm.name, []string{"module", m.Name, "type", m.Type}, mm.value(m))
+		}
+	}
+
+	gaugesMutex.Lock()
+	var names []string
+	for name := range gauges {
+		names = append(names, name)
+	}
+	gaugesMutex.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		gaugesMutex.Lock()
+		g := gauges[name]
+		gaugesMutex.Unlock()
+		g.write(out)
+	}
+	return out.Flush()
+}
+
+// write writes all values of the gauge, sorted by their label values.
+func (g *Gauge) write(out io.Writer) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	metric(out, g.name, "gauge", g.help)
+	var keys []string
+	for key := range g.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := g.values[key]
+		var labels []string
+		for i, name := range g.labels {
+			labels = append(labels, name, s.labels[i])
+		}
+		value(out, g.name, labels, s.value)
+	}
+}
+
+// metric writes the HELP and TYPE lines for a metric.
+func metric(out io.Writer, name, kind, help string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// value writes a single value, with labels given as name, value pairs.
+func value(out io.Writer, name string, labels []string, v float64) {
+	io.WriteString(out, name)
+	if len(labels) > 0 {
+		var pairs []string
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, labels[i]+`="`+escape(labels[i+1])+`"`)
+		}
+		io.WriteString(out, "{"+strings.Join(pairs, ",")+"}")
+	}
+	fmt.Fprintf(out, " %s\n", formatValue(v))
+}
+
+// escape escapes a label value for the text format.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatValue formats a value, using the text format's names for special
+// values.
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
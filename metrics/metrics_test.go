@@ -0,0 +1,89 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func lines(t *testing.T, b *bar.I3Bar) []string {
+	var out strings.Builder
+	assert.NoError(t, Write(&out, b))
+	return strings.Split(out.String(), "\n")
+}
+
+func TestBarMetrics(t *testing.T) {
+	module := testModule.New(t)
+	failing := base.New()
+	b := testBar.New(t)
+	b.Bar.Add(module, failing)
+	b.Start()
+	defer b.Close()
+	module.Output(outputs.Text("hello"))
+	b.NextOutput("module output")
+	failing.Error(errors.New("oops"))
+	b.NextOutput("error output")
+	b.Click(0)
+	module.AssertClicked("click")
+
+	out := lines(t, b.Bar)
+	assert.Contains(t, out, "# TYPE barista_prints_total counter")
+	assert.Contains(t, out, "# TYPE barista_start_time_seconds gauge")
+	assert.Contains(t, out, `barista_module_updates_total{module="0",type="*module.TestModule"} 1`)
+	assert.Contains(t, out, `barista_module_events_total{module="0",type="*module.TestModule"} 1`)
+	assert.Contains(t, out, `barista_module_events_total{module="1",type="*base.Base"} 0`)
+	assert.Contains(t, out, `barista_module_error{module="0",type="*module.TestModule"} 0`)
+	assert.Contains(t, out, `barista_module_error{module="1",type="*base.Base"} 1`)
+}
+
+func TestGauges(t *testing.T) {
+	b := testBar.New(t)
+	plain := NewGauge("test_plain", "A plain gauge.")
+	plain.Set(1.5)
+	assert.Equal(t, plain, NewGauge("test_plain", "Another gauge."), "existing gauge returned")
+
+	labelled := NewGauge("test_disk_free_bytes", "Free space\nby disk.", "path")
+	labelled.Set(1e12, "/")
+	labelled.Set(math.Inf(1), `/mnt/"quoted"`)
+	labelled.Set(3, "/tmp")
+	labelled.Set(4, "/tmp", "extra")
+	labelled.Delete("/tmp")
+
+	out := lines(t, b.Bar)
+	assert.Contains(t, out, "# HELP test_plain A plain gauge.")
+	assert.Contains(t, out, "test_plain 1.5")
+	assert.Contains(t, out, `# HELP test_disk_free_bytes Free space\nby disk.`)
+	assert.Contains(t, out, `test_disk_free_bytes{path="/"} 1e+12`)
+	assert.Contains(t, out, `test_disk_free_bytes{path="/mnt/\"quoted\""} +Inf`)
+	assert.NotContains(t, strings.Join(out, "\n"), "/tmp", "deleted and invalid values")
+	assert.NotContains(t, out, "# TYPE barista_start_time_seconds gauge", "bar not started")
+
+	w := httptest.NewRecorder()
+	Handler(b.Bar).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), "test_plain 1.5")
+}
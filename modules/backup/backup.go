@@ -0,0 +1,170 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package backup provides an i3bar module that shows how long ago the last
+successful backup ran.
+
+The time of the last backup is read from a Source, either by querying a restic
+or borg repository for the latest snapshot, or using the modification time of a
+status file that is touched by a backup script on success. The module becomes
+urgent when the last backup is older than a threshold.
+*/
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Source provides the time of the last successful backup.
+type Source interface {
+	// LastBackup returns the time of the last successful backup,
+	// or zero if there are no backups.
+	LastBackup() (time.Time, error)
+}
+
+// Info represents the freshness of the last backup.
+type Info struct {
+	// Last is the time of the last successful backup, or zero if none.
+	Last time.Time
+	// Now is the time at which the backup was checked.
+	Now time.Time
+	// Threshold is the maximum age of a backup before it is stale.
+	Threshold time.Duration
+}
+
+// Age returns the time since the last backup.
+func (i Info) Age() time.Duration {
+	return i.Now.Sub(i.Last)
+}
+
+// Stale returns true if there are no backups, or the last backup
+// is older than the threshold.
+func (i Info) Stale() bool {
+	return i.Last.IsZero() || i.Age() > i.Threshold
+}
+
+// Ago returns the age of the last backup as a short string, e.g. "3d",
+// "5h", or "12m", or "never" if there are no backups.
+func (i Info) Ago() string {
+	if i.Last.IsZero() {
+		return "never"
+	}
+	age := i.Age()
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age/(24*time.Hour)))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	}
+	return fmt.Sprintf("%dm", int(age/time.Minute))
+}
+
+// Module is the public interface for a backup module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// Threshold sets the maximum age of a backup before it is stale.
+	Threshold(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	source     Source
+	threshold  time.Duration
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the backup module using the given source.
+func New(source Source) Module {
+	m := &module{
+		Base:      base.New(),
+		source:    source,
+		threshold: 24 * time.Hour,
+	}
+	// Querying a repository can be slow, and backups are infrequent.
+	m.RefreshInterval(15 * time.Minute)
+	// Default output template is the age of the last backup.
+	m.OutputTemplate(outputs.TextTemplate(`backup {{.Ago}}`))
+	m.UrgentWhen(Info.Stale)
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) Threshold(threshold time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.threshold = threshold
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+func (m *module) update() {
+	last, err := m.source.LastBackup()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	info := Info{Last: last, Now: scheduler.Now(), Threshold: m.threshold}
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
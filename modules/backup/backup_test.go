@@ -0,0 +1,170 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func fakeOutput(out string, err error) *[]string {
+	var args []string
+	output = func(a ...string) ([]byte, error) {
+		args = a
+		return []byte(out), err
+	}
+	return &args
+}
+
+func TestRestic(t *testing.T) {
+	assert := assert.New(t)
+	args := fakeOutput(`[
+		{"time": "2018-01-04T02:00:00.123456+01:00", "hostname": "desktop"},
+		{"time": "2018-01-05T02:00:00.5+01:00", "hostname": "laptop"}]`, nil)
+	last, err := Restic("/mnt/backup", "--host", "laptop").LastBackup()
+	assert.NoError(err)
+	assert.True(time.Date(2018, 1, 5, 1, 0, 0, 500000000, time.UTC).Equal(last))
+	assert.Equal("restic --repo /mnt/backup snapshots --json --latest 1 --host laptop",
+		strings.Join(*args, " "))
+
+	fakeOutput(`[]`, nil)
+	last, err = Restic("/mnt/backup").LastBackup()
+	assert.NoError(err)
+	assert.True(last.IsZero(), "no snapshots")
+
+	fakeOutput(`Fatal: wrong password`, errors.New("exit status 1"))
+	_, err = Restic("/mnt/backup").LastBackup()
+	assert.Error(err)
+
+	fakeOutput(`not json`, nil)
+	_, err = Restic("/mnt/backup").LastBackup()
+	assert.Error(err)
+}
+
+func TestBorg(t *testing.T) {
+	assert := assert.New(t)
+	args := fakeOutput(`{"archives": [{"name": "laptop-2018-01-05", "time": "2018-01-05T02:00:00.000000"}]}`, nil)
+	last, err := Borg("ssh://backup/./repo").LastBackup()
+	assert.NoError(err)
+	assert.Equal(time.Date(2018, 1, 5, 2, 0, 0, 0, time.Local), last)
+	assert.Equal("borg list --json --last 1 ssh://backup/./repo", strings.Join(*args, " "))
+
+	fakeOutput(`{"archives": []}`, nil)
+	last, err = Borg("repo").LastBackup()
+	assert.NoError(err)
+	assert.True(last.IsZero(), "no archives")
+
+	fakeOutput(`{"archives": [{"time": "yesterday"}]}`, nil)
+	_, err = Borg("repo").LastBackup()
+	assert.Error(err)
+
+	fakeOutput(``, errors.New("exit status 2"))
+	_, err = Borg("repo").LastBackup()
+	assert.Error(err)
+}
+
+func TestStatusFile(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "last-backup")
+
+	last, err := StatusFile(file).LastBackup()
+	assert.NoError(err)
+	assert.True(last.IsZero(), "missing file")
+
+	ioutil.WriteFile(file, nil, 0644)
+	mtime := time.Date(2018, 1, 5, 2, 0, 0, 0, time.UTC)
+	os.Chtimes(file, mtime, mtime)
+	last, err = StatusFile(file).LastBackup()
+	assert.NoError(err)
+	assert.True(mtime.Equal(last))
+}
+
+type testSource struct {
+	last time.Time
+	err  error
+}
+
+func (t *testSource) LastBackup() (time.Time, error) { return t.last, t.err }
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	now := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	scheduler.AdvanceTo(now)
+
+	s := &testSource{last: now.Add(-5 * time.Hour)}
+	b := New(s)
+	tester := testModule.NewOutputTester(t, b)
+	out := tester.AssertOutput("on start")
+	assert.Equal("backup 5h", out[0].Text())
+	assert.Equal(false, out[0]["urgent"])
+
+	scheduler.AdvanceBy(15 * time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("backup 5h", out[0].Text())
+
+	b.Threshold(4 * time.Hour)
+	out = tester.AssertOutput("on threshold change")
+	assert.Equal(true, out[0]["urgent"], "stale backup")
+
+	s.last = now.Add(-10 * time.Minute)
+	scheduler.AdvanceBy(15 * time.Minute)
+	out = tester.AssertOutput("on new backup")
+	assert.Equal("backup 40m", out[0].Text())
+	assert.Equal(false, out[0]["urgent"])
+
+	s.last = now.Add(-50 * time.Hour)
+	scheduler.AdvanceBy(15 * time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("backup 2d", out[0].Text())
+
+	s.last = time.Time{}
+	var info Info
+	b.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Text(i.Ago())
+	})
+	out = tester.AssertOutput("on output func change")
+	assert.Equal("never", out[0].Text())
+	assert.Equal(true, out[0]["urgent"], "no backups")
+	assert.True(info.Stale())
+	assert.Equal(4*time.Hour, info.Threshold)
+
+	b.UrgentWhen(nil)
+	out = tester.AssertOutput("on urgent func change")
+	_, ok := out[0]["urgent"]
+	assert.False(ok)
+
+	s.err = errors.New("repository locked")
+	scheduler.AdvanceBy(15 * time.Minute)
+	tester.AssertError("on source error")
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// output runs a command and returns its output.
+var output = func(args ...string) ([]byte, error) {
+	return exec.Command(args[0], args[1:]...).Output()
+}
+
+type restic []string
+
+// Restic returns a source that uses the latest snapshot in a restic
+// repository. The password must be provided to restic using the usual
+// environment variables (e.g. RESTIC_PASSWORD_FILE), and additional
+// arguments (e.g. "--host", "laptop") can be given to filter snapshots.
+func Restic(repo string, args ...string) Source {
+	return restic(append([]string{"restic", "--repo", repo, "snapshots", "--json", "--latest", "1"}, args...))
+}
+
+func (r restic) LastBackup() (time.Time, error) {
+	out, err := output(r...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var snapshots []struct{ Time time.Time }
+	if err := json.Unmarshal(out, &snapshots); err != nil {
+		return time.Time{}, err
+	}
+	// Snapshots are sorted oldest first, and with --latest 1 there is
+	// one snapshot per host and path set.
+	var last time.Time
+	for _, s := range snapshots {
+		if s.Time.After(last) {
+			last = s.Time
+		}
+	}
+	return last, nil
+}
+
+type borg []string
+
+// Borg returns a source that uses the latest archive in a borg repository.
+// The passphrase must be provided to borg using the usual environment
+// variables (e.g. BORG_PASSCOMMAND).
+func Borg(repo string) Source {
+	return borg{"borg", "list", "--json", "--last", "1", repo}
+}
+
+// borgTime is the format of archive times, which are in local time.
+const borgTime = "2006-01-02T15:04:05.000000"
+
+func (b borg) LastBackup() (time.Time, error) {
+	out, err := output(b...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var list struct {
+		Archives []struct{ Time string }
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return time.Time{}, err
+	}
+	if len(list.Archives) == 0 {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(borgTime, list.Archives[len(list.Archives)-1].Time, time.Local)
+}
+
+type statusFile string
+
+// StatusFile returns a source that uses the modification time of a file,
+// which should be touched by the backup script after a successful backup.
+// A missing file means there have been no backups.
+func StatusFile(path string) Source {
+	return statusFile(path)
+}
+
+func (s statusFile) LastBackup() (time.Time, error) {
+	stat, err := os.Stat(string(s))
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return stat.ModTime(), nil
+}
@@ -23,10 +23,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/spf13/afero"
-
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -211,7 +210,13 @@ func fromMicroStr(str string) float64 {
 	return float64(uValue) / math.Pow(10, 6 /* micros */)
 }
 
-var fs = afero.NewOsFs()
+var fs = hostfs.Fs()
+
+// Get returns the current information for the named battery, e.g. "BAT0",
+// for code that needs the battery state without a module.
+func Get(name string) Info {
+	return batteryInfo(name)
+}
 
 func batteryPath(name string) string {
 	return fmt.Sprintf("/sys/class/power_supply/%s/uevent", name)
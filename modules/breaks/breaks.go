@@ -0,0 +1,204 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package breaks provides an i3bar module that reminds the user to take breaks.
+
+By default it implements the 20-20-20 rule: after 20 minutes of continuous
+activity, look at something 20 feet away for 20 seconds. Activity is tracked
+using the user's idle time (from xprintidle by default), so any idle period
+longer than the break length counts as a break and resets the timer.
+*/
+package breaks
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the current activity state.
+type Info struct {
+	// Active is the duration of continuous activity since the last break.
+	Active time.Duration
+	// Interval is the amount of continuous activity after which a break is due.
+	Interval time.Duration
+	// Idle is the current idle time, as reported by the idle source.
+	Idle time.Duration
+}
+
+// Due returns true if a break is due.
+func (i Info) Due() bool {
+	return i.Active >= i.Interval
+}
+
+// Remaining returns the time until the next break is due.
+func (i Info) Remaining() time.Duration {
+	if i.Due() {
+		return 0
+	}
+	return i.Interval - i.Active
+}
+
+// Module represents a break reminder bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// Interval sets the amount of continuous activity after which a break is due.
+	Interval(time.Duration) Module
+
+	// BreakLength sets the minimum idle time that counts as a break.
+	BreakLength(time.Duration) Module
+
+	// RefreshInterval configures the polling frequency for idle time.
+	RefreshInterval(time.Duration) Module
+
+	// IdleSource sets the function used to get the user's current idle time.
+	IdleSource(func() (time.Duration, error)) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function. By default, the output is urgent when a break is due.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	interval    time.Duration
+	breakLength time.Duration
+	idleFunc    func() (time.Duration, error)
+	outputFunc  func(Info) bar.Output
+	urgentFunc  func(Info) bool
+	// activeSince is the start of the current continuous activity,
+	// or zero if the user is currently on a break.
+	activeSince time.Time
+}
+
+// New constructs a new break reminder module using the 20-20-20 rule.
+func New() Module {
+	m := &module{
+		Base:        base.New(),
+		interval:    20 * time.Minute,
+		breakLength: 20 * time.Second,
+		idleFunc:    XPrintIdle,
+		activeSince: scheduler.Now(),
+	}
+	// Poll often enough to reliably detect breaks.
+	m.RefreshInterval(5 * time.Second)
+	m.UrgentWhen(Info.Due)
+	// Default output is empty unless a break is due.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Due}}Take a break{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+// XPrintIdle returns the X11 idle time using the xprintidle command.
+func XPrintIdle() (time.Duration, error) {
+	out, err := exec.Command("xprintidle").Output()
+	if err != nil {
+		return 0, err
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (m *module) Interval(interval time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.interval = interval
+	return m
+}
+
+func (m *module) BreakLength(breakLength time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.breakLength = breakLength
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) IdleSource(idleFunc func() (time.Duration, error)) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.idleFunc = idleFunc
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+func (m *module) update() {
+	m.Lock()
+	idleFunc := m.idleFunc
+	m.Unlock()
+	idle, err := idleFunc()
+	if m.Error(err) {
+		return
+	}
+	now := scheduler.Now()
+	m.Lock()
+	info := Info{Interval: m.interval, Idle: idle}
+	switch {
+	case idle >= m.breakLength:
+		// Long enough idle period, the user is on a break.
+		m.activeSince = time.Time{}
+	case m.activeSince.IsZero():
+		// Activity resumed after a break.
+		m.activeSince = now.Add(-idle)
+		fallthrough
+	default:
+		info.Active = now.Sub(m.activeSince)
+	}
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breaks
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+	"github.com/soumya92/barista/testing/output"
+)
+
+type fakeIdle struct {
+	sync.Mutex
+	idle time.Duration
+	err  error
+}
+
+func (f *fakeIdle) set(idle time.Duration, err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.idle = idle
+	f.err = err
+}
+
+func (f *fakeIdle) get() (time.Duration, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.idle, f.err
+}
+
+func TestBreaks(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2017, time.March, 1, 0, 0, 0, 0, time.Local))
+
+	idle := &fakeIdle{}
+	var lastInfo Info
+	b := New().
+		IdleSource(idle.get).
+		Interval(time.Minute).
+		BreakLength(10 * time.Second).
+		RefreshInterval(5 * time.Second).
+		OutputFunc(func(i Info) bar.Output {
+			lastInfo = i
+			return bar.Output{bar.NewSegment(i.Remaining().String())}
+		})
+	tester := testModule.NewOutputTester(t, b)
+
+	out := tester.AssertOutput("on start")
+	output.New(t, out, "on start").Text("1m0s").Has("urgent").Urgent(false)
+
+	for i := 0; i < 6; i++ {
+		scheduler.NextTick()
+		out = tester.AssertOutput("on tick")
+	}
+	assert.Equal("30s", out[0].Text())
+
+	idle.set(6*time.Second, nil)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on short idle")
+	assert.Equal("25s", out[0].Text(), "short idle is not a break")
+
+	for i := 0; i < 5; i++ {
+		scheduler.NextTick()
+		out = tester.AssertOutput("on tick")
+	}
+	assert.True(lastInfo.Due())
+	output.New(t, out, "when break is due").Segment(0).Urgent(true)
+
+	idle.set(12*time.Second, nil)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on break")
+	assert.False(lastInfo.Due())
+	output.New(t, out, "during break").Text("1m0s").Has("urgent").Urgent(false)
+
+	idle.set(2*time.Second, nil)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on activity after break")
+	assert.Equal("58s", out[0].Text(), "active time counts from end of idle")
+
+	idle.set(0, errors.New("no idle"))
+	scheduler.NextTick()
+	tester.AssertError("on idle source error")
+}
@@ -0,0 +1,222 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package brightness provides an i3bar module that shows the backlight brightness.
+
+The brightness is read from /sys/class/backlight, and the module uses inotify
+to update as soon as the brightness changes, instead of polling. The default
+click handler raises or lowers the brightness on scroll, by writing to sysfs
+directly if the user has permission to do so, or using brightnessctl otherwise.
+
+External monitors can be controlled using DDC/CI, which uses ddcutil to read
+and set the brightness. Since DDC/CI does not provide change notifications,
+the brightness is polled at a configurable interval for external monitors.
+*/
+package brightness
+
+import (
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the current backlight brightness.
+type Info struct {
+	Brightness, Max int
+}
+
+// Frac returns the current brightness as a fraction of the maximum.
+func (i Info) Frac() float64 {
+	if i.Max == 0 {
+		return 0
+	}
+	return float64(i.Brightness) / float64(i.Max)
+}
+
+// Pct returns the current brightness in the range 0-100.
+func (i Info) Pct() int {
+	return int(i.Frac()*100 + 0.5)
+}
+
+// Controller provides an interface to change the brightness from the click handler.
+type Controller interface {
+	// SetBrightness sets the brightness, clamped to the range [0, max].
+	SetBrightness(int)
+}
+
+// Module is the public interface for the brightness module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control the brightness.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency for backends that
+	// do not support change notifications, i.e. DDC/CI.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// backend reads and sets the brightness of a specific display.
+type backend interface {
+	read() (Info, error)
+	write(int) error
+}
+
+// watcher is implemented by backends that support change notifications.
+type watcher interface {
+	watch() (*fsnotify.Watcher, error)
+}
+
+type module struct {
+	*base.Base
+	backend    backend
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+func newModule(b backend) *module {
+	m := &module{
+		Base:    base.New(),
+		backend: b,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is just the brightness percentage.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Pct}}%`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler raises or lowers the brightness by 5% on scroll.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	step := i.Max / 20
+	if step == 0 {
+		step = 1
+	}
+	switch e.Button {
+	case bar.ScrollUp, bar.ScrollRight:
+		c.SetBrightness(i.Brightness + step)
+	case bar.ScrollDown, bar.ScrollLeft:
+		c.SetBrightness(i.Brightness - step)
+	}
+}
+
+func (m *module) SetBrightness(value int) {
+	m.Lock()
+	max := m.info.Max
+	m.Unlock()
+	if value > max {
+		value = max
+	}
+	if value < 0 {
+		value = 0
+	}
+	if m.Error(m.backend.write(value)) {
+		return
+	}
+	// Backends with change notifications will update automatically.
+	if _, ok := m.backend.(watcher); !ok {
+		m.Update()
+	}
+}
+
+// Stream starts watching for brightness changes and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	bw, ok := m.backend.(watcher)
+	if !ok {
+		return ch
+	}
+	w, err := bw.watch()
+	if m.Error(err) {
+		return ch
+	}
+	go m.listen(w)
+	return ch
+}
+
+// listen triggers an update whenever the brightness changes.
+func (m *module) listen(w *fsnotify.Watcher) {
+	defer w.Close()
+	for {
+		select {
+		case _, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			m.Update()
+		case err := <-w.Errors:
+			m.Error(err)
+			return
+		}
+	}
+}
+
+func (m *module) update() {
+	info, err := m.backend.read()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
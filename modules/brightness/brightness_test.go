@@ -0,0 +1,140 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brightness
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/testing/event"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func writeFile(t *testing.T, name, value string) {
+	err := ioutil.WriteFile(filepath.Join(hostfs.Path(sysfsRoot), "intel_backlight", name), []byte(value+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	bytes, err := ioutil.ReadFile(filepath.Join(hostfs.Path(sysfsRoot), "intel_backlight", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bytes)
+}
+
+func TestBrightness(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "backlight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hostfs.SetRoot(dir)
+	defer hostfs.SetRoot("")
+	os.MkdirAll(filepath.Join(dir, sysfsRoot, "intel_backlight"), 0755)
+	writeFile(t, "max_brightness", "1000")
+	writeFile(t, "brightness", "500")
+	writeFile(t, "actual_brightness", "500")
+
+	b := Default()
+	tester := testModule.NewOutputTester(t, b)
+	out := tester.AssertOutput("on start")
+	assert.Equal("50%", out[0].Text())
+
+	writeFile(t, "actual_brightness", "333")
+	out = tester.AssertOutput("when brightness changes")
+	assert.Equal("33%", out[0].Text())
+
+	event.ScrollUp.On(b)
+	assert.Equal("383", readFile(t, "brightness"), "scroll up raises brightness")
+	tester.AssertOutput("on brightness write")
+
+	var set []int
+	brightnessctl = func(device string, value int) error {
+		assert.Equal("intel_backlight", device)
+		set = append(set, value)
+		return nil
+	}
+	os.Chmod(filepath.Join(dir, "intel_backlight", "brightness"), 0444)
+	event.ScrollDown.On(b)
+	if os.Getuid() == 0 {
+		// Permissions are not enforced for root.
+		tester.AssertOutput("on brightness write")
+	} else {
+		assert.Equal([]int{283}, set, "uses brightnessctl without write permission")
+	}
+
+	b.OnClick(func(i Info, c Controller, e bar.Event) {
+		c.SetBrightness(i.Max * 2)
+	})
+	os.Chmod(filepath.Join(dir, "intel_backlight", "brightness"), 0644)
+	event.Left.On(b)
+	assert.Equal("1000", readFile(t, "brightness"), "brightness is clamped")
+	tester.AssertOutput("on brightness write")
+}
+
+func TestDDC(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	current := 70
+	var calls []string
+	ddcutil = func(display int, args ...string) (string, error) {
+		assert.Equal(2, display)
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "setvcp" {
+			current, _ = strconv.Atoi(args[2])
+			return "", nil
+		}
+		if current < 0 {
+			return "", errors.New("no monitor")
+		}
+		return fmt.Sprintf("VCP 10 C %d 100\n", current), nil
+	}
+
+	d := DDC(2)
+	tester := testModule.NewOutputTester(t, d)
+	out := tester.AssertOutput("on start")
+	assert.Equal("70%", out[0].Text())
+	assert.Equal([]string{"--brief getvcp 10"}, calls)
+
+	current = 40
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("40%", out[0].Text())
+
+	calls = nil
+	event.ScrollUp.On(d)
+	out = tester.AssertOutput("after setting brightness")
+	assert.Equal("45%", out[0].Text())
+	assert.Equal([]string{"setvcp 10 45", "--brief getvcp 10"}, calls)
+
+	current = -1
+	scheduler.NextTick()
+	tester.AssertError("on ddcutil error")
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brightness
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VCP feature code for brightness (luminance) in the MCCS spec.
+const vcpBrightness = "10"
+
+type ddcBackend struct {
+	display int
+}
+
+// DDC constructs an instance of the brightness module for an external
+// monitor using DDC/CI. The display is the ddcutil display number, as
+// shown by `ddcutil detect`, starting at 1.
+func DDC(display int) Module {
+	m := newModule(&ddcBackend{display})
+	// DDC/CI is slow, so don't poll too often.
+	m.RefreshInterval(10 * time.Second)
+	return m
+}
+
+// ddcutil runs ddcutil with the given arguments for the given display.
+var ddcutil = func(display int, args ...string) (string, error) {
+	args = append([]string{"--display", strconv.Itoa(display)}, args...)
+	out, err := exec.Command("ddcutil", args...).Output()
+	return string(out), err
+}
+
+func (d *ddcBackend) read() (Info, error) {
+	out, err := ddcutil(d.display, "--brief", "getvcp", vcpBrightness)
+	if err != nil {
+		return Info{}, err
+	}
+	// Brief output for continuous features is "VCP 10 C <current> <max>".
+	fields := strings.Fields(out)
+	if len(fields) < 5 || fields[0] != "VCP" || fields[2] != "C" {
+		return Info{}, fmt.Errorf("unexpected ddcutil output: %q", out)
+	}
+	var info Info
+	if info.Brightness, err = strconv.Atoi(fields[3]); err != nil {
+		return Info{}, err
+	}
+	if info.Max, err = strconv.Atoi(fields[4]); err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
+
+func (d *ddcBackend) write(value int) error {
+	_, err := ddcutil(d.display, "setvcp", vcpBrightness, strconv.Itoa(value))
+	return err
+}
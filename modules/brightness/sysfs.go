@@ -0,0 +1,111 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brightness
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/soumya92/barista/base/hostfs"
+)
+
+// sysfsRoot is the directory containing all backlight devices, relative to
+// the host root.
+var sysfsRoot = "/sys/class/backlight"
+
+type sysfsBackend struct {
+	device string
+	path   string
+}
+
+// New constructs an instance of the brightness module
+// for the given backlight device, e.g. "intel_backlight".
+func New(device string) Module {
+	return newModule(&sysfsBackend{
+		device: device,
+		path:   filepath.Join(hostfs.Path(sysfsRoot), device),
+	})
+}
+
+// Default constructs an instance of the brightness module
+// for the first backlight device found.
+func Default() Module {
+	device := ""
+	if devices, err := ioutil.ReadDir(hostfs.Path(sysfsRoot)); err == nil && len(devices) > 0 {
+		device = devices[0].Name()
+	}
+	return New(device)
+}
+
+// brightnessctl sets the brightness using brightnessctl, which is used when
+// the user does not have permission to write to sysfs directly.
+var brightnessctl = func(device string, value int) error {
+	return exec.Command("brightnessctl", "--device="+device, "set", strconv.Itoa(value)).Run()
+}
+
+func (s *sysfsBackend) file(name string) string {
+	return filepath.Join(s.path, name)
+}
+
+func (s *sysfsBackend) readInt(name string) (int, error) {
+	bytes, err := ioutil.ReadFile(s.file(name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(bytes)))
+}
+
+func (s *sysfsBackend) read() (info Info, err error) {
+	if info.Brightness, err = s.readInt("actual_brightness"); err != nil {
+		return
+	}
+	info.Max, err = s.readInt("max_brightness")
+	return
+}
+
+func (s *sysfsBackend) write(value int) error {
+	err := ioutil.WriteFile(s.file("brightness"), []byte(strconv.Itoa(value)), 0644)
+	if os.IsPermission(err) {
+		err = brightnessctl(s.device, value)
+	}
+	return err
+}
+
+// watch uses inotify to watch for brightness changes.
+// Userspace writes modify "brightness", while changes from the kernel
+// (e.g. hardware keys) notify on "actual_brightness".
+func (s *sysfsBackend) watch() (*fsnotify.Watcher, error) {
+	if s.device == "" {
+		return nil, fmt.Errorf("no backlight devices in %s", sysfsRoot)
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"brightness", "actual_brightness"} {
+		if err := w.Add(s.file(name)); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
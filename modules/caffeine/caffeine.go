@@ -0,0 +1,182 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package caffeine provides an i3bar module that toggles idle inhibition.
+
+While inhibited, the module keeps an inhibitor command running, which prevents
+the screen saver (or suspend, depending on the command) from activating. The
+default command uses systemd-inhibit, but any long-running command that
+inhibits idle until it exits can be used, e.g. a Wayland idle-inhibit client,
+or a loop that periodically calls `xdg-screensaver reset`.
+*/
+package caffeine
+
+import (
+	"os/exec"
+	"syscall"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the current inhibition state.
+type Info struct {
+	Inhibited bool
+}
+
+// Controller provides an interface to control idle inhibition from the click handler.
+type Controller interface {
+	// SetInhibited starts or stops inhibiting idle.
+	SetInhibited(bool)
+	// Toggle toggles idle inhibition.
+	Toggle()
+}
+
+// Module is the public interface for a caffeine module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control idle inhibition.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	command    []string
+	outputFunc func(Info) bar.Output
+	// cmd is the running inhibitor command, nil if not inhibited.
+	cmd *exec.Cmd
+}
+
+// New constructs an instance of the caffeine module that uses systemd-inhibit
+// to inhibit idle.
+func New() Module {
+	return Command("systemd-inhibit",
+		"--what=idle", "--who=barista", "--why=Caffeine", "--mode=block",
+		"sleep", "infinity")
+}
+
+// Command constructs an instance of the caffeine module that inhibits idle
+// by running the given command, until it exits or inhibition is stopped.
+func Command(name string, args ...string) Module {
+	m := &module{
+		Base:    base.New(),
+		command: append([]string{name}, args...),
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template shows the inhibition state.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{if .Inhibited}}CAFFEINE{{else}}caffeine{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		f(m.info(), m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler toggles idle inhibition on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Toggle()
+	}
+}
+
+func (m *module) info() Info {
+	m.Lock()
+	defer m.Unlock()
+	return Info{Inhibited: m.cmd != nil}
+}
+
+func (m *module) Toggle() {
+	m.SetInhibited(!m.info().Inhibited)
+}
+
+func (m *module) SetInhibited(inhibited bool) {
+	m.Lock()
+	if inhibited == (m.cmd != nil) {
+		m.Unlock()
+		return
+	}
+	if !inhibited {
+		m.cmd.Process.Kill()
+		m.cmd = nil
+		m.UnlockAndUpdate()
+		return
+	}
+	cmd := exec.Command(m.command[0], m.command[1:]...)
+	// Make sure the inhibitor does not outlive the bar.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+	err := cmd.Start()
+	if err == nil {
+		m.cmd = cmd
+		go m.wait(cmd)
+	}
+	m.Unlock()
+	if !m.Error(err) {
+		m.Update()
+	}
+}
+
+// wait waits for the inhibitor command to exit, and updates the module
+// if it exited on its own, e.g. if it was killed externally.
+func (m *module) wait(cmd *exec.Cmd) {
+	cmd.Wait()
+	m.Lock()
+	if m.cmd != cmd {
+		m.Unlock()
+		return
+	}
+	m.cmd = nil
+	m.UnlockAndUpdate()
+}
+
+func (m *module) update() {
+	info := m.info()
+	m.Lock()
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
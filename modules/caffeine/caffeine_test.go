@@ -0,0 +1,60 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package caffeine
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestCaffeine(t *testing.T) {
+	assert := assert.New(t)
+	c := Command("sleep", "60").(*module)
+	tester := testModule.NewOutputTester(t, c)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("caffeine", out[0].Text())
+
+	c.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click")
+	assert.Equal("CAFFEINE", out[0].Text())
+
+	c.SetInhibited(true)
+	tester.AssertNoOutput("when already inhibited")
+
+	// Killing the command externally should stop inhibition.
+	c.Lock()
+	c.cmd.Process.Kill()
+	c.Unlock()
+	out = tester.AssertOutput("when command exits")
+	assert.Equal("caffeine", out[0].Text())
+
+	c.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertOutput("on click")
+	c.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click")
+	assert.Equal("caffeine", out[0].Text())
+	tester.AssertNoOutput("when stopped by the module")
+
+	c = Command("this-command-does-not-exist").(*module)
+	tester = testModule.NewOutputTester(t, c)
+	tester.AssertOutput("on start")
+	c.Toggle()
+	tester.AssertError("when command cannot be started")
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package click provides a module that "wraps" an existing module, and adds
+click handlers to it, e.g. to show the date when the clock is clicked:
+
+	c := click.OnClick(clock.New(), func(bar.Event) {
+	  showDate = !showDate
+	})
+
+Handlers can be restricted to specific buttons, and events that are not
+handled are passed through to the wrapped module, so handlers can also be
+layered on top of a module's own click handling.
+
+Commands can be run on click without writing exec code, e.g. to open a
+calendar from the clock, or a mixer from the volume module. Commands are
+detached from the bar (see Command):
+
+	c := click.Wrap(clock.New()).Run(click.Cmd("gsimplecal"))
+	v := click.Wrap(volume.DefaultMixer()).
+	  Run(click.Cmd("pavucontrol"), bar.ButtonMiddle)
+*/
+package click
+
+import (
+	"sync"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// Module represents a module with additional click handlers, which
+// forwards unhandled clicks and pause/resume events to the wrapped module.
+type Module interface {
+	bar.Module
+	bar.Clickable
+	bar.Pausable
+
+	// On adds a handler for events from the given buttons, or from all
+	// buttons if none are given. Handlers added later take precedence.
+	On(handler func(bar.Event), buttons ...bar.Button) Module
+
+	// Left adds a handler for left clicks.
+	Left(func()) Module
+
+	// Middle adds a handler for middle clicks.
+	Middle(func()) Module
+
+	// Right adds a handler for right clicks.
+	Right(func()) Module
+
+	// Scroll adds handlers for scrolling up and down. Either can be nil.
+	Scroll(up, down func()) Module
+
+	// Run adds a handler that starts a command on clicks from the given
+	// buttons, or from all buttons if none are given.
+	Run(cmd Command, buttons ...bar.Button) Module
+}
+
+// handler is a click handler for a set of buttons.
+type handler struct {
+	handle  func(bar.Event)
+	buttons []bar.Button
+}
+
+// matches returns true if the handler handles the given button.
+func (h handler) matches(button bar.Button) bool {
+	if len(h.buttons) == 0 {
+		return true
+	}
+	for _, b := range h.buttons {
+		if b == button {
+			return true
+		}
+	}
+	return false
+}
+
+type module struct {
+	bar.Module
+	mutex    sync.Mutex
+	handlers []handler
+}
+
+// Wrap wraps an existing module, so that click handlers can be added.
+func Wrap(original bar.Module) Module {
+	return &module{Module: original}
+}
+
+// OnClick wraps an existing module, and handles all clicks using the
+// given handler.
+func OnClick(original bar.Module, handler func(bar.Event)) Module {
+	return Wrap(original).On(handler)
+}
+
+func (m *module) On(h func(bar.Event), buttons ...bar.Button) Module {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handlers = append(m.handlers, handler{h, buttons})
+	return m
+}
+
+// discardEvent adapts a function that does not need the event details.
+func discardEvent(f func()) func(bar.Event) {
+	return func(bar.Event) { f() }
+}
+
+func (m *module) Left(f func()) Module {
+	return m.On(discardEvent(f), bar.ButtonLeft)
+}
+
+func (m *module) Middle(f func()) Module {
+	return m.On(discardEvent(f), bar.ButtonMiddle)
+}
+
+func (m *module) Right(f func()) Module {
+	return m.On(discardEvent(f), bar.ButtonRight)
+}
+
+func (m *module) Scroll(up, down func()) Module {
+	if up != nil {
+		m.On(discardEvent(up), bar.ScrollUp)
+	}
+	if down != nil {
+		m.On(discardEvent(down), bar.ScrollDown)
+	}
+	return m
+}
+
+// Click calls the most recently added handler for the button, or passes
+// through the click event if no handler matches and the wrapped module
+// supports it.
+func (m *module) Click(e bar.Event) {
+	m.mutex.Lock()
+	var handle func(bar.Event)
+	for i := len(m.handlers) - 1; i >= 0; i-- {
+		if m.handlers[i].matches(e.Button) {
+			handle = m.handlers[i].handle
+			break
+		}
+	}
+	m.mutex.Unlock()
+	if handle != nil {
+		handle(e)
+		return
+	}
+	if clickable, ok := m.Module.(bar.Clickable); ok {
+		clickable.Click(e)
+	}
+}
+
+// Pause passes through the pause event if supported by the wrapped module.
+func (m *module) Pause() {
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// Resume passes through the resume event if supported by the wrapped module.
+func (m *module) Resume() {
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Resume()
+	}
+}
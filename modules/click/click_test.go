@@ -0,0 +1,87 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package click
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestOnClick(t *testing.T) {
+	original := testModule.New(t)
+	var events []bar.Event
+	m := OnClick(original, func(e bar.Event) { events = append(events, e) })
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("test"))
+	out := tester.AssertOutput("output passed through")
+	assert.Equal(t, "test", out[0].Text())
+
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	m.Click(bar.Event{Button: bar.ScrollUp, X: 2})
+	assert.Equal(t,
+		[]bar.Event{{Button: bar.ButtonLeft}, {Button: bar.ScrollUp, X: 2}},
+		events, "all events handled")
+	original.AssertNotClicked("when handled")
+
+	m.Pause()
+	original.AssertPaused("pause passed through")
+	m.Resume()
+	original.AssertResumed("resume passed through")
+}
+
+func TestButtonFiltering(t *testing.T) {
+	original := testModule.New(t)
+	var calls []string
+	record := func(name string) func() {
+		return func() { calls = append(calls, name) }
+	}
+	m := Wrap(original).
+		Left(record("left")).
+		Right(record("right")).
+		Scroll(record("up"), nil).
+		On(func(bar.Event) { calls = append(calls, "back/fwd") },
+			bar.ButtonBack, bar.ButtonForward)
+
+	for _, b := range []bar.Button{
+		bar.ButtonLeft, bar.ButtonRight, bar.ScrollUp,
+		bar.ButtonBack, bar.ButtonForward,
+	} {
+		m.Click(bar.Event{Button: b})
+	}
+	assert.Equal(t,
+		[]string{"left", "right", "up", "back/fwd", "back/fwd"},
+		calls, "events routed by button")
+	original.AssertNotClicked("when handled")
+
+	m.Click(bar.Event{Button: bar.ScrollDown})
+	assert.Equal(t, bar.ScrollDown,
+		original.AssertClicked("unhandled events passed through").Button)
+	m.Click(bar.Event{Button: bar.ButtonMiddle})
+	original.AssertClicked("unhandled events passed through")
+
+	m.Middle(record("middle"))
+	m.Left(record("new left"))
+	m.Click(bar.Event{Button: bar.ButtonMiddle})
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal(t, []string{"middle", "new left"}, calls[5:],
+		"later handlers take precedence")
+	original.AssertNotClicked("when handled")
+}
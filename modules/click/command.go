@@ -0,0 +1,103 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package click
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("modules/click")
+
+// Command is a command to run when a module is clicked, e.g. to open a
+// mixer from the volume module. Commands are detached from the bar: they run
+// in a new session with no standard input or output, so they keep running
+// when the bar restarts, and do not receive signals sent to the bar.
+//
+// Commands inherit the bar's environment, with the details of the click in
+// BARISTA_BUTTON (the button number) and BARISTA_INSTANCE (the instance of
+// the clicked segment), which is useful for scripts.
+type Command struct {
+	// The program and its arguments. The program is found using $PATH.
+	Args []string
+	// Additional environment variables, as "KEY=value".
+	Env []string
+	// The working directory, or the home directory if empty.
+	Dir string
+}
+
+// Cmd returns a command that runs the program with the given arguments.
+func Cmd(name string, args ...string) Command {
+	return Command{Args: append([]string{name}, args...)}
+}
+
+// Shell returns a command that runs the command line using "sh -c", for
+// pipelines, redirection, or expanding environment variables.
+func Shell(commandLine string) Command {
+	return Cmd("sh", "-c", commandLine)
+}
+
+// WithEnv returns a copy of the command with additional environment
+// variables, given as "KEY=value".
+func (c Command) WithEnv(env ...string) Command {
+	c.Env = append(append([]string(nil), c.Env...), env...)
+	return c
+}
+
+// InDir returns a copy of the command that runs in the given directory.
+func (c Command) InDir(dir string) Command {
+	c.Dir = dir
+	return c
+}
+
+// Start starts the command for a click event, without waiting for it to
+// finish. The command is reaped when it exits, and failures are logged.
+func (c Command) Start(e bar.Event) error {
+	cmd := exec.Command(c.Args[0], c.Args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"BARISTA_BUTTON="+strconv.Itoa(int(e.Button)),
+		"BARISTA_INSTANCE="+e.Instance)
+	cmd.Env = append(cmd.Env, c.Env...)
+	cmd.Dir = c.Dir
+	if cmd.Dir == "" {
+		cmd.Dir, _ = os.UserHomeDir()
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		log.Error("failed to start command", "args", c.Args, "err", err)
+		return err
+	}
+	log.Fine("started command", "args", c.Args, "pid", cmd.Process.Pid)
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Info("command failed", "args", c.Args, "err", err)
+		}
+	}()
+	return nil
+}
+
+// Handler returns a click handler that starts the command.
+func (c Command) Handler() func(bar.Event) {
+	return func(e bar.Event) { c.Start(e) }
+}
+
+func (m *module) Run(c Command, buttons ...bar.Button) Module {
+	return m.On(c.Handler(), buttons...)
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package click
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+// waitForFile waits for a command to write a file, and returns its contents.
+func waitForFile(t *testing.T, path string) string {
+	for i := 0; i < 100; i++ {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "command did not run", path)
+	return ""
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	cmd := Shell(`echo "$BARISTA_BUTTON $BARISTA_INSTANCE $FOO $(pwd)" > out.tmp && mv out.tmp out`).
+		WithEnv("FOO=bar").InDir(dir)
+	assert.NoError(t, cmd.Start(bar.Event{Button: bar.ButtonMiddle, Instance: "inst"}))
+	assert.Equal(t, "2 inst bar "+dir+"\n", waitForFile(t, out))
+
+	assert.Error(t, Cmd("/this/does/not/exist").Start(bar.Event{}))
+	assert.NotPanics(t, func() { Cmd("/this/does/not/exist").Handler()(bar.Event{}) })
+
+	withFoo := cmd.WithEnv("BAZ=1")
+	assert.Equal(t, []string{"FOO=bar"}, cmd.Env, "WithEnv does not modify the original")
+	assert.Equal(t, []string{"FOO=bar", "BAZ=1"}, withFoo.Env)
+}
+
+func TestCommandDefaultDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	Shell("pwd > out.tmp && mv out.tmp out").Start(bar.Event{})
+	assert.Equal(t, home+"\n", waitForFile(t, filepath.Join(home, "out")))
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	original := testModule.New(t)
+	m := Wrap(original).Run(Shell("echo $BARISTA_BUTTON > out").InDir(dir), bar.ButtonRight)
+	m.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Equal(t, "3\n", waitForFile(t, filepath.Join(dir, "out")))
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	original.AssertClicked("other buttons passed through")
+}
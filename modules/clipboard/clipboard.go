@@ -0,0 +1,178 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package clipboard provides an i3bar module that previews the clipboard.
+
+On X11, the module uses XFixes selection events to detect changes, and reads
+the selection directly. On wayland, it uses wl-paste --watch from wl-clipboard.
+Either the clipboard or the primary selection can be shown. By default, the
+module shows a truncated, single-line preview of the text, and clicking it
+clears the selection.
+
+CopyOnClick adds a click action to the whole bar that copies the text of the
+clicked segment to the clipboard, e.g. on shift+middle click.
+*/
+package clipboard
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Selection represents an X11 (or wayland) selection.
+type Selection int
+
+const (
+	// Clipboard is the selection used for explicit copy and paste.
+	Clipboard Selection = iota
+	// Primary is the selection set by selecting text, and pasted
+	// using the middle mouse button.
+	Primary
+)
+
+// Info represents the current contents of the selection.
+type Info struct {
+	// Text is the full text of the selection, or empty if the
+	// selection is empty or does not contain text.
+	Text string
+}
+
+// Truncated returns a single-line preview of the text, with whitespace
+// collapsed, truncated to at most the given number of characters.
+func (i Info) Truncated(length int) string {
+	preview := strings.Join(strings.Fields(i.Text), " ")
+	if utf8.RuneCountInString(preview) <= length {
+		return preview
+	}
+	if length < 1 {
+		return ""
+	}
+	runes := []rune(preview)
+	return string(runes[:length-1]) + "…"
+}
+
+// Controller provides an interface to clear the selection from the click handler.
+type Controller interface {
+	// Clear empties the selection.
+	Clear()
+}
+
+// Module is the public interface for a clipboard module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to clear the selection.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// backend watches and clears a selection.
+type backend interface {
+	// watch calls the given function with the text of the selection
+	// whenever it changes. It only returns on error.
+	watch(func(string)) error
+	clear() error
+}
+
+type module struct {
+	*base.Base
+	backend    backend
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+func newModule(b backend) *module {
+	m := &module{
+		Base:    base.New(),
+		backend: b,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is a short preview of the text.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Truncated 20}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler clears the selection on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Clear()
+	}
+}
+
+func (m *module) Clear() {
+	// The backend will report the empty selection through watch.
+	m.Error(m.backend.clear())
+}
+
+// Stream starts watching the selection, and then returns
+// the output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go func() { m.Error(m.backend.watch(m.setText)) }()
+	return ch
+}
+
+func (m *module) setText(text string) {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.info = Info{Text: text}
+}
+
+func (m *module) update() {
+	m.Lock()
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clipboard
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestTruncated(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("", Info{}.Truncated(10))
+	assert.Equal("hello world", Info{Text: " hello\n\tworld\n"}.Truncated(11))
+	assert.Equal("hello wo…", Info{Text: "hello world"}.Truncated(9))
+	assert.Equal("日本…", Info{Text: "日本語のテキスト"}.Truncated(3))
+	assert.Equal("", Info{Text: "text"}.Truncated(0))
+}
+
+func TestWayland(t *testing.T) {
+	assert := assert.New(t)
+	watchOut, watchIn := io.Pipe()
+	var mu sync.Mutex
+	var commands []string
+	contents := "copied text"
+	startWatch = func(args ...string) (io.Reader, error) {
+		assert.Equal([]string{"--watch", "echo", "--primary"}, args)
+		return watchOut, nil
+	}
+	run = func(name string, args ...string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if name == "wl-copy" {
+			contents = ""
+			return "", nil
+		}
+		if contents == "" {
+			return "", errors.New("Nothing is copied")
+		}
+		return contents, nil
+	}
+
+	c := Wayland(Primary)
+	tester := testModule.NewOutputTester(t, c)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text())
+
+	watchIn.Write([]byte("\n"))
+	out = tester.AssertOutput("on change")
+	assert.Equal("copied text", out[0].Text())
+
+	c.Click(bar.Event{Button: bar.ButtonLeft})
+	watchIn.Write([]byte("\n"))
+	out = tester.AssertOutput("after clear")
+	assert.Equal("", out[0].Text())
+	mu.Lock()
+	assert.Equal([]string{
+		"wl-paste --no-newline --type text --primary",
+		"wl-copy --clear --primary",
+		"wl-paste --no-newline --type text --primary",
+	}, commands)
+	mu.Unlock()
+
+	watchIn.CloseWithError(errors.New("wl-paste crashed"))
+	tester.AssertError("when watch fails")
+}
+
+type testBackend struct {
+	texts   chan string
+	cleared chan bool
+}
+
+func (t *testBackend) watch(f func(string)) error {
+	for text := range t.texts {
+		f(text)
+	}
+	return errors.New("closed")
+}
+
+func (t *testBackend) clear() error {
+	t.cleared <- true
+	return nil
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	b := &testBackend{texts: make(chan string), cleared: make(chan bool, 1)}
+	c := newModule(b)
+	tester := testModule.NewOutputTester(t, c)
+	tester.AssertOutput("on start")
+
+	b.texts <- "a very long line of text that does not fit"
+	out := tester.AssertOutput("on change")
+	assert.Equal("a very long line of…", out[0].Text())
+
+	var info Info
+	c.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return bar.Output{}
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal("a very long line of text that does not fit", info.Text)
+
+	c.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Empty(b.cleared, "only left click clears")
+	c.Click(bar.Event{Button: bar.ButtonLeft})
+	<-b.cleared
+	tester.AssertNoOutput("clear does not update directly")
+
+	close(b.texts)
+	tester.AssertError("when watch fails")
+}
+
+func TestCopyOnClick(t *testing.T) {
+	copied := make(chan string, 10)
+	copyText = func(text string, name string, args ...string) error {
+		copied <- name + " " + strings.Join(args, " ") + ": " + text
+		return nil
+	}
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	m := testModule.New(t)
+	b := testBar.New(t)
+	b.Bar.Add(m)
+	CopyOnClick(b.Bar, bar.ButtonMiddle, bar.ShiftKey)
+	b.Start()
+	defer b.Close()
+
+	m.AssertStarted("on bar start")
+	m.Output(bar.Output{
+		bar.NewSegment("<b>10.0.0.2</b> &amp; \ue1a4 wired").
+			Markup(bar.MarkupPango).Instance("ip"),
+		bar.NewSegment("song title").Instance("song"),
+	})
+	b.NextOutput("module output")
+	assertCopied := func(expected, message string) {
+		select {
+		case text := <-copied:
+			assert.Equal(t, expected, text, message)
+		case <-time.After(time.Second):
+			assert.Fail(t, "nothing copied", message)
+		}
+	}
+
+	b.SendEvent(0, bar.Event{Button: bar.ButtonMiddle, Modifiers: bar.ShiftKey | bar.Mod2Key})
+	assertCopied("xclip -selection clipboard: 10.0.0.2 & wired",
+		"copies plain text of clicked segment")
+	m.AssertNotClicked("copy click is not sent to module")
+
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	b.SendEvent(1, bar.Event{Button: bar.ButtonMiddle, Modifiers: bar.ShiftKey})
+	assertCopied("wl-copy : song title", "uses wl-copy on wayland")
+
+	b.SendEvent(1, bar.Event{Button: bar.ButtonMiddle})
+	m.AssertClicked("click without modifier")
+	b.SendEvent(1, bar.Event{Button: bar.ButtonLeft, Modifiers: bar.ShiftKey})
+	m.AssertClicked("click with another button")
+	assert.Empty(t, copied, "nothing else copied")
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/pango"
+)
+
+var log = logging.New("modules/clipboard")
+
+// copyText runs the command with the text as its input.
+var copyText = func(text string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// Copy sets the clipboard to the text, using wl-copy on wayland (if
+// WAYLAND_DISPLAY is set), and xclip otherwise.
+func Copy(text string) error {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return copyText(text, "wl-copy")
+	}
+	return copyText(text, "xclip", "-selection", "clipboard")
+}
+
+// CopyOnClick makes clicks with the given button and modifiers copy the
+// full text of the clicked segment, without markup or icons, to the
+// clipboard instead of clicking the module, e.g. to copy an IP address,
+// song title, or error message from the bar:
+//
+//	clipboard.CopyOnClick(b, bar.ButtonMiddle, bar.ShiftKey)
+//
+// Other modifiers may also be held, so that e.g. num lock does not stop
+// the action from working. If segments of a module do not have distinct
+// instances, the text of all such segments is copied.
+func CopyOnClick(b *bar.I3Bar, button bar.Button, modifiers bar.Modifiers) {
+	b.Intercept(func(o bar.Output, e bar.Event) bool {
+		if e.Button != button || !e.Modifiers.Has(modifiers) {
+			return false
+		}
+		text := segmentText(o)
+		if text == "" {
+			return false
+		}
+		go func() {
+			if err := Copy(text); err != nil {
+				log.Error("failed to copy", "err", err)
+			}
+		}()
+		return true
+	})
+}
+
+// segmentText returns the text of the segments without markup, and with
+// icons (which use private use characters) and redundant whitespace
+// removed.
+func segmentText(o bar.Output) string {
+	var words []string
+	for _, s := range o {
+		text := s.Text()
+		if markup, _ := s["markup"].(bar.Markup); markup == bar.MarkupPango {
+			text = pango.PlainText(text)
+		}
+		words = append(words, strings.FieldsFunc(text, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.In(r, unicode.Co)
+		})...)
+	}
+	return strings.Join(words, " ")
+}
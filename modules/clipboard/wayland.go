@@ -0,0 +1,81 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clipboard
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+)
+
+type wayland struct {
+	selection Selection
+}
+
+// Wayland constructs an instance of the clipboard module for the given
+// selection using wl-clipboard.
+func Wayland(selection Selection) Module {
+	return newModule(&wayland{selection})
+}
+
+// args returns the arguments to select the configured selection.
+func (w *wayland) args(args ...string) []string {
+	if w.selection == Primary {
+		args = append(args, "--primary")
+	}
+	return args
+}
+
+// startWatch starts wl-paste --watch, which runs a command whenever the
+// selection changes, and returns the command's output.
+var startWatch = func(args ...string) (io.Reader, error) {
+	cmd := exec.Command("wl-paste", args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	return out, cmd.Start()
+}
+
+// run runs a wl-clipboard command and returns its output.
+var run = func(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return string(out), err
+}
+
+func (w *wayland) watch(f func(string)) error {
+	// The watch command just prints a line on every change,
+	// and the contents are read separately.
+	out, err := startWatch(w.args("--watch", "echo")...)
+	if err != nil {
+		return err
+	}
+	s := bufio.NewScanner(out)
+	for s.Scan() {
+		// wl-paste exits with an error if the selection is empty,
+		// or does not contain text.
+		text, err := run("wl-paste", w.args("--no-newline", "--type", "text")...)
+		if err != nil {
+			text = ""
+		}
+		f(text)
+	}
+	return s.Err()
+}
+
+func (w *wayland) clear() error {
+	_, err := run("wl-copy", w.args("--clear")...)
+	return err
+}
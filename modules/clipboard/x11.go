@@ -0,0 +1,159 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clipboard
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xfixes"
+	"github.com/jezek/xgb/xproto"
+)
+
+// maxLength is the maximum length of selection text read, in 4-byte units.
+// Selections larger than this are truncated, which is fine for a preview.
+const maxLength = 16 * 1024
+
+type x11 struct {
+	selection Selection
+	sync.Mutex
+	conn   *xgb.Conn
+	window xproto.Window
+	atom   xproto.Atom
+}
+
+// X11 constructs an instance of the clipboard module for the given
+// selection on the X11 display.
+func X11(selection Selection) Module {
+	return newModule(&x11{selection: selection})
+}
+
+func internAtom(c *xgb.Conn, name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(c, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+	return reply.Atom, nil
+}
+
+func (x *x11) watch(f func(string)) error {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := xfixes.Init(conn); err != nil {
+		return err
+	}
+	if _, err := xfixes.QueryVersion(conn, 5, 0).Reply(); err != nil {
+		return err
+	}
+	name := "CLIPBOARD"
+	if x.selection == Primary {
+		name = "PRIMARY"
+	}
+	atoms := map[string]xproto.Atom{}
+	for _, n := range []string{name, "UTF8_STRING", "BARISTA_SELECTION"} {
+		if atoms[n], err = internAtom(conn, n); err != nil {
+			return err
+		}
+	}
+	sel, utf8, prop := atoms[name], atoms["UTF8_STRING"], atoms["BARISTA_SELECTION"]
+
+	// An unmapped window is needed to receive the selection contents,
+	// and to own the selection when clearing it.
+	window, err := xproto.NewWindowId(conn)
+	if err != nil {
+		return err
+	}
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	err = xproto.CreateWindowChecked(conn, 0, window, root, 0, 0, 1, 1, 0,
+		xproto.WindowClassInputOnly, 0, 0, nil).Check()
+	if err != nil {
+		return err
+	}
+	err = xfixes.SelectSelectionInputChecked(conn, window, sel,
+		xfixes.SelectionEventMaskSetSelectionOwner|
+			xfixes.SelectionEventMaskSelectionWindowDestroy|
+			xfixes.SelectionEventMaskSelectionClientClose).Check()
+	if err != nil {
+		return err
+	}
+	x.Lock()
+	x.conn, x.window, x.atom = conn, window, sel
+	x.Unlock()
+
+	convert := func() {
+		xproto.ConvertSelection(conn, window, sel, utf8, prop, xproto.TimeCurrentTime)
+	}
+	convert()
+	for {
+		ev, err := conn.WaitForEvent()
+		if ev == nil && err == nil {
+			return errors.New("X connection closed")
+		}
+		if err != nil {
+			continue
+		}
+		switch e := ev.(type) {
+		case xfixes.SelectionNotifyEvent:
+			// The selection changed, request the new contents.
+			convert()
+		case xproto.SelectionNotifyEvent:
+			// None means the owner could not convert the selection
+			// to text, or there is no owner.
+			if e.Property == xproto.AtomNone {
+				f("")
+				continue
+			}
+			reply, err := xproto.GetProperty(conn, true, window, prop,
+				xproto.GetPropertyTypeAny, 0, maxLength).Reply()
+			if err != nil {
+				f("")
+				continue
+			}
+			f(string(reply.Value))
+		case xproto.SelectionRequestEvent:
+			// Only received after clearing, when we own the selection.
+			// Refuse all requests, so the selection appears empty.
+			refuse(conn, e)
+		}
+	}
+}
+
+// refuse responds to a selection request without providing any data.
+func refuse(c *xgb.Conn, req xproto.SelectionRequestEvent) {
+	ev := xproto.SelectionNotifyEvent{
+		Time:      req.Time,
+		Requestor: req.Requestor,
+		Selection: req.Selection,
+		Target:    req.Target,
+		Property:  xproto.AtomNone,
+	}
+	xproto.SendEvent(c, false, req.Requestor, xproto.EventMaskNoEvent, string(ev.Bytes()))
+}
+
+func (x *x11) clear() error {
+	x.Lock()
+	conn, window, sel := x.conn, x.window, x.atom
+	x.Unlock()
+	if conn == nil {
+		return errors.New("X connection not ready")
+	}
+	// Taking ownership of the selection (and refusing all requests)
+	// replaces the existing contents.
+	return xproto.SetSelectionOwnerChecked(conn, window, sel, xproto.TimeCurrentTime).Check()
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/locale"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -32,6 +33,9 @@ type Module interface {
 	// OutputFunc configures a module to display the output of a user-defined function.
 	OutputFunc(func(time.Time) bar.Output) Module
 
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
 	// OutputFormat configures a module to display the time in a given format.
 	OutputFormat(string) Module
 
@@ -74,9 +78,15 @@ func (m *module) OutputFunc(outputFunc func(time.Time) bar.Output) Module {
 	return m
 }
 
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(now time.Time) bar.Output {
+		return template(now)
+	})
+}
+
 func (m *module) OutputFormat(format string) Module {
 	return m.OutputFunc(func(now time.Time) bar.Output {
-		return outputs.Text(now.Format(format))
+		return outputs.Text(locale.Current().FormatTime(now, format))
 	})
 }
 
@@ -22,6 +22,8 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/locale"
+	"github.com/soumya92/barista/outputs"
 	testModule "github.com/soumya92/barista/testing/module"
 )
 
@@ -54,6 +56,10 @@ func TestSimple(t *testing.T) {
 	local.Granularity(time.Minute)
 	tester.AssertOutput("on granularity change")
 
+	local.OutputTemplate(outputs.TextTemplate(`{{.Format "15h04"}}`))
+	out = tester.AssertOutput("on output template change")
+	assert.Equal(bar.NewSegment("00h00"), out[0])
+
 	local.OutputFormat("15:04:05")
 	out = tester.AssertOutput("on output format change")
 	assert.Equal(bar.NewSegment("00:00:02"), out[0])
@@ -66,6 +72,12 @@ func TestSimple(t *testing.T) {
 
 	tester.AssertNoOutput("when time is frozen")
 	// This also serves as a check to make sure we've consumed all outputs.
+
+	locale.Set("de")
+	defer locale.SetLocale(locale.English)
+	local.OutputFormat("Mon 2 January")
+	out = tester.AssertOutput("on output format change")
+	assert.Equal(bar.NewSegment("Mi 1 März"), out[0], "localized names")
 }
 
 func TestZones(t *testing.T) {
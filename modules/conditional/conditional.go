@@ -0,0 +1,181 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package conditional provides a module that "wraps" an existing module, and
+only shows it when a condition is met.
+
+The condition can depend on the module's own output, e.g. hiding a module
+that shows "0" when there is nothing to report:
+
+	u := updates.New(updates.Pacman())
+	c := conditional.HideWhen(u, func(o bar.Output) bool {
+	  return len(o) == 0 || o[0].Text() == "0"
+	})
+
+or on some external state, in which case Refresh should be called when it
+might have changed:
+
+	c := conditional.ShowIf(cpuload.New(), onBattery)
+	scheduler.Do(c.Refresh).Every(time.Minute)
+
+Modules that provide an output function can also hide themselves by
+returning an empty output, which is preferable when the module's info
+already includes the condition. Templates often leave blank text instead,
+which still takes up a block on the bar; AutoHide removes blank segments
+so that such modules collapse completely:
+
+	c := conditional.AutoHide(media.New("spotify"))
+*/
+package conditional
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Module is a wrapped module that is only shown when its condition is met.
+// It forwards clicks and pause/resume calls to the wrapped module.
+type Module interface {
+	bar.Module
+	bar.Clickable
+	bar.Pausable
+
+	// Refresh re-evaluates the condition for the last output, e.g. after
+	// external state used by the condition changes.
+	Refresh()
+}
+
+// module stores the original module and the condition for showing it.
+type module struct {
+	bar.Module
+	sync.Mutex
+	// filter, if set, transforms each output before checking the condition.
+	filter     func(bar.Output) bar.Output
+	show       func(bar.Output) bool
+	channel    chan bar.Output
+	lastOutput bar.Output
+	hidden     bool
+}
+
+// ShowWhen wraps an existing module, and only shows it when the condition
+// returns true for its output.
+func ShowWhen(original bar.Module, condition func(bar.Output) bool) Module {
+	return &module{Module: original, show: condition}
+}
+
+// HideWhen wraps an existing module, and hides it whenever the condition
+// returns true for its output.
+func HideWhen(original bar.Module, condition func(bar.Output) bool) Module {
+	return ShowWhen(original, func(o bar.Output) bool { return !condition(o) })
+}
+
+// ShowIf wraps an existing module, and only shows it while the condition
+// returns true. The condition is checked on each output from the module,
+// and when Refresh is called.
+func ShowIf(original bar.Module, condition func() bool) Module {
+	return ShowWhen(original, func(bar.Output) bool { return condition() })
+}
+
+// AutoHide wraps an existing module, and removes any segments without text
+// from its output. A module that outputs only blank text is then hidden
+// completely, instead of leaving an empty block with padding and separators
+// on the bar, and is shown again as soon as it has some text.
+func AutoHide(original bar.Module) Module {
+	return &module{
+		Module: original,
+		filter: removeBlank,
+		show:   func(o bar.Output) bool { return len(o) > 0 },
+	}
+}
+
+// removeBlank removes segments that only contain whitespace.
+func removeBlank(o bar.Output) bar.Output {
+	var out bar.Output
+	for _, s := range o {
+		if text, _ := s["full_text"].(string); strings.TrimSpace(text) != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Stream sets up the output pipeline to hide outputs when the condition
+// is not met.
+func (m *module) Stream() <-chan bar.Output {
+	m.Lock()
+	m.channel = make(chan bar.Output, 10)
+	m.Unlock()
+	go m.pipe(m.Module.Stream())
+	return m.channel
+}
+
+// Click passes through the click event if supported by the wrapped module.
+func (m *module) Click(e bar.Event) {
+	if clickable, ok := m.Module.(bar.Clickable); ok {
+		clickable.Click(e)
+	}
+}
+
+// Pause passes through the pause event if supported by the wrapped module.
+func (m *module) Pause() {
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// Resume passes through the resume event if supported by the wrapped module.
+func (m *module) Resume() {
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Resume()
+	}
+}
+
+func (m *module) Refresh() {
+	m.Lock()
+	defer m.Unlock()
+	if m.lastOutput != nil {
+		m.send()
+	}
+}
+
+func (m *module) pipe(input <-chan bar.Output) {
+	for out := range input {
+		if m.filter != nil {
+			out = m.filter(out)
+		}
+		m.Lock()
+		m.lastOutput = out
+		m.send()
+		m.Unlock()
+	}
+}
+
+// send sends the last output, or an empty output if the condition is not
+// met, and must be called with the mutex held. Repeated empty outputs are
+// not sent, to avoid needlessly updating the bar.
+func (m *module) send() {
+	if m.show(m.lastOutput) {
+		m.hidden = false
+		m.channel <- m.lastOutput
+		return
+	}
+	if !m.hidden {
+		m.hidden = true
+		m.channel <- outputs.Empty()
+	}
+}
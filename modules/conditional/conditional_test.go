@@ -0,0 +1,119 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditional
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestHideWhen(t *testing.T) {
+	original := testModule.New(t)
+	m := HideWhen(original, func(o bar.Output) bool {
+		return o[0].Text() == "0"
+	})
+	original.AssertNotStarted("on construction of wrapped module")
+	tester := testModule.NewOutputTester(t, m)
+	original.AssertStarted("on stream of wrapped module")
+
+	original.Output(outputs.Text("3"))
+	out := tester.AssertOutput("when condition is not met")
+	assert.Equal(t, "3", out[0].Text())
+
+	original.Output(outputs.Text("0"))
+	tester.AssertEmpty("when condition is met")
+	original.Output(outputs.Text("0"))
+	tester.AssertNoOutput("while hidden")
+
+	original.Output(outputs.Text("1"))
+	out = tester.AssertOutput("when condition is no longer met")
+	assert.Equal(t, "1", out[0].Text())
+
+	m.Pause()
+	original.AssertPaused("when wrapped module is paused")
+	m.Resume()
+	original.AssertResumed("when wrapped module is resumed")
+
+	evt := bar.Event{Y: 1}
+	m.Click(evt)
+	recvEvt := original.AssertClicked("click events propagated")
+	assert.Equal(t, evt, recvEvt, "click events passed through unchanged")
+}
+
+func TestShowIf(t *testing.T) {
+	var connected int32
+	original := testModule.New(t)
+	m := ShowIf(original, func() bool { return atomic.LoadInt32(&connected) == 1 })
+	tester := testModule.NewOutputTester(t, m)
+
+	m.Refresh()
+	tester.AssertNoOutput("refresh before any output")
+
+	original.Output(outputs.Text("vpn"))
+	tester.AssertEmpty("when condition is false")
+
+	atomic.StoreInt32(&connected, 1)
+	tester.AssertNoOutput("until refreshed")
+	m.Refresh()
+	out := tester.AssertOutput("on refresh")
+	assert.Equal(t, "vpn", out[0].Text())
+
+	atomic.StoreInt32(&connected, 0)
+	m.Refresh()
+	tester.AssertEmpty("on refresh")
+	m.Refresh()
+	tester.AssertNoOutput("when still hidden")
+
+	atomic.StoreInt32(&connected, 1)
+	original.Output(outputs.Text("vpn: up"))
+	out = tester.AssertOutput("on output when condition is true")
+	assert.Equal(t, "vpn: up", out[0].Text())
+}
+
+func TestAutoHide(t *testing.T) {
+	original := testModule.New(t)
+	m := AutoHide(original)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("  "))
+	tester.AssertEmpty("when output is blank")
+
+	original.Output(outputs.Empty())
+	tester.AssertNoOutput("when still hidden")
+
+	original.Output(outputs.Text("3 updates"))
+	out := tester.AssertOutput("when output has text")
+	assert.Equal(t, "3 updates", out[0].Text())
+
+	original.Output(outputs.Multi().
+		AddText("1", "").
+		AddText("2", "a").
+		AddText("3", " ").
+		AddText("4", "b").
+		Build())
+	out = tester.AssertOutput("with some blank segments")
+	assert.Equal(t, 2, len(out), "blank segments are removed")
+	assert.Equal(t, "a", out[0].Text())
+	assert.Equal(t, "b", out[1].Text())
+
+	original.Output(outputs.Multi().AddText("1", "").AddText("2", "").Build())
+	tester.AssertEmpty("when all segments are blank")
+}
@@ -20,33 +20,88 @@ package counter
 import (
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/state"
 	"github.com/soumya92/barista/outputs"
 )
 
+// Module represents a counter bar module. It supports setting the output
+// format of the count.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(int) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// Persist saves the count under the given key whenever it changes, and
+	// restores the previously saved count, if any.
+	Persist(key string) Module
+}
+
 type module struct {
 	*base.Base
-	count  int
-	format string
+	count      int
+	outputFunc func(int) bar.Output
+	key        string
 }
 
-// New constructs a new counter module.
-func New(format string) bar.Module {
-	m := &module{
-		Base:   base.New(),
-		count:  0,
-		format: format,
-	}
-	m.Output(outputs.Textf(format, 0))
+// New constructs a new counter module, which displays the count using the
+// given format string.
+func New(format string) Module {
+	m := &module{Base: base.New()}
+	m.OutputFunc(func(count int) bar.Output {
+		return outputs.Textf(format, count)
+	})
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(int) bar.Output) Module {
+	m.Lock()
+	m.outputFunc = outputFunc
+	m.Unlock()
+	m.output()
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(count int) bar.Output {
+		return template(count)
+	})
+}
+
+func (m *module) Persist(key string) Module {
+	m.Lock()
+	m.key = key
+	state.Load(key, &m.count)
+	m.Unlock()
+	m.output()
 	return m
 }
 
 func (m *module) Click(e bar.Event) {
+	m.Lock()
 	switch e.Button {
 	case bar.ButtonLeft, bar.ScrollDown, bar.ScrollLeft, bar.ButtonBack:
 		m.count--
 	case bar.ButtonRight, bar.ScrollUp, bar.ScrollRight, bar.ButtonForward:
 		m.count++
 	}
-	m.Output(outputs.Textf(m.format, m.count))
+	key, count := m.key, m.count
+	m.Unlock()
+	if key != "" {
+		// Errors are ignored, since persisting the count is only a convenience.
+		state.Save(key, count)
+	}
+	m.output()
 	m.Base.Click(e)
 }
+
+// output displays the current count using the output func.
+func (m *module) output() {
+	m.Lock()
+	out := m.outputFunc(m.count)
+	m.Unlock()
+	m.Output(out)
+}
@@ -15,12 +15,16 @@
 package counter
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchrcom/testify/assert"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
 	testModule "github.com/soumya92/barista/testing/module"
+	"github.com/soumya92/barista/testing/stress"
 )
 
 func TestCounter(t *testing.T) {
@@ -49,3 +53,50 @@ func TestCounter(t *testing.T) {
 	out = tester.AssertOutput("on click")
 	assert.Equal(bar.NewSegment("C:-1"), out[0])
 }
+
+func TestOutputTemplate(t *testing.T) {
+	ctr := New("%d")
+	tester := testModule.NewOutputTester(t, ctr)
+	tester.AssertOutput("on start")
+
+	ctr.OutputTemplate(outputs.TextTemplate(`count: {{.}}`))
+	out := tester.AssertOutput("on template change")
+	assert.Equal(t, "count: 0", out[0].Text())
+
+	ctr.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on click")
+	assert.Equal(t, "count: 1", out[0].Text())
+}
+
+func TestPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_STATE_HOME", dir)
+	defer os.Unsetenv("XDG_STATE_HOME")
+
+	ctr := New("%d").Persist("test")
+	tester := testModule.NewOutputTester(t, ctr)
+	out := tester.AssertOutput("on start")
+	assert.Equal(t, "0", out[0].Text(), "nothing saved yet")
+
+	ctr.Click(bar.Event{Button: bar.ScrollUp})
+	tester.AssertOutput("on click")
+	ctr.Click(bar.Event{Button: bar.ScrollUp})
+	tester.AssertOutput("on click")
+
+	ctr = New("%d").Persist("test")
+	tester = testModule.NewOutputTester(t, ctr)
+	out = tester.AssertOutput("on start")
+	assert.Equal(t, "2", out[0].Text(), "count restored")
+
+	tester = testModule.NewOutputTester(t, New("%d").Persist("other"))
+	out = tester.AssertOutput("on start")
+	assert.Equal(t, "0", out[0].Text(), "counts saved per key")
+}
+
+func TestStress(t *testing.T) {
+	stress.New(t).Module(New("%d"))
+}
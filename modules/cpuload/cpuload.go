@@ -83,7 +83,7 @@ func New() Module {
 	// Default is to refresh every 3s, matching the behaviour of top.
 	m.RefreshInterval(3 * time.Second)
 	// Construct a simple template that's just 2 decimals of the 1-minute load average.
-	m.OutputTemplate(outputs.TextTemplate(`{{.Min1 | printf "%.2f"}}`))
+	m.OutputTemplate(outputs.TextTemplate(`{{.Min1 | number 2}}`))
 	// Update load average when asked.
 	m.OnUpdate(m.update)
 	return m
@@ -26,6 +26,7 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -90,7 +91,7 @@ func Zone(thermalZone string) Module {
 	// Default is to refresh every 3s, matching the behaviour of top.
 	m.RefreshInterval(3 * time.Second)
 	// Default output template, if no template/function was specified.
-	m.OutputTemplate(outputs.TextTemplate(`{{.C}}℃`))
+	m.OutputTemplate(outputs.TextTemplate(`{{.C | celsius}}`))
 	// Update temperature when asked.
 	m.OnUpdate(m.update)
 	return m
@@ -133,7 +134,7 @@ func (m *module) UrgentWhen(urgentFunc func(Temperature) bool) Module {
 	return m
 }
 
-var fs = afero.NewOsFs()
+var fs = hostfs.Fs()
 
 func (m *module) update() {
 	bytes, err := afero.ReadFile(fs, m.thermalFile)
@@ -0,0 +1,231 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package crypto provides an i3bar module that shows cryptocurrency prices.
+
+Prices for the configured pairs are polled from an exchange API using a
+Provider. Providers are available for CoinGecko and Binance. The default
+output shows one pair at a time, coloured by the 24 hour change, and
+scrolling cycles through the pairs.
+*/
+package crypto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Pair identifies a cryptocurrency and the currency to price it in.
+// The identifiers used depend on the provider, e.g. CoinGecko uses coin ids
+// such as "bitcoin" and currencies such as "usd", while Binance uses symbols
+// such as "BTC" and "USDT".
+type Pair struct {
+	Coin     string
+	Currency string
+}
+
+// Quote represents the price of a pair.
+type Quote struct {
+	Pair
+	Price float64
+	// Change is the percentage change in price over the last 24 hours.
+	Change float64
+}
+
+// Up returns true if the price has not gone down over the last 24 hours.
+func (q Quote) Up() bool {
+	return q.Change >= 0
+}
+
+// Provider gets prices from an exchange or aggregator.
+type Provider interface {
+	// Quotes returns the prices of the given pairs, in the same order.
+	Quotes([]Pair) ([]Quote, error)
+}
+
+// Info represents the prices of all configured pairs, and the pair
+// currently being shown.
+type Info struct {
+	Quotes  []Quote
+	Current int
+}
+
+// Quote returns the quote currently being shown.
+func (i Info) Quote() Quote {
+	if i.Current < 0 || i.Current >= len(i.Quotes) {
+		return Quote{}
+	}
+	return i.Quotes[i.Current]
+}
+
+// Controller provides an interface to cycle through the pairs from the
+// click handler.
+type Controller interface {
+	// Next shows the next pair, cycling back to the first.
+	Next()
+	// Previous shows the previous pair, cycling to the last.
+	Previous()
+}
+
+// Module is the public interface for a crypto module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to cycle through the pairs.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	provider   Provider
+	pairs      []Pair
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the crypto module that shows the prices of
+// the given pairs using the given provider.
+func New(provider Provider, pairs ...Pair) Module {
+	m := &module{
+		Base:     base.New(),
+		provider: provider,
+		pairs:    pairs,
+	}
+	// Public APIs are rate limited, and prices are only shown to a few
+	// significant figures anyway.
+	m.RefreshInterval(5 * time.Minute)
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows the current pair's price and 24 hour change,
+// using the 'good' colour from the scheme if the price has gone up,
+// and 'bad' if it has gone down.
+func DefaultOutput(i Info) bar.Output {
+	if len(i.Quotes) == 0 {
+		return outputs.Empty()
+	}
+	q := i.Quote()
+	color := colors.Scheme("bad")
+	if q.Up() {
+		color = colors.Scheme("good")
+	}
+	return outputs.Textf("%s %s %+.1f%%", q.Coin, formatPrice(q.Price), q.Change).Color(color)
+}
+
+// formatPrice formats a price with 2 decimal places, or more for
+// coins that are worth less than 1 unit of the currency.
+func formatPrice(price float64) string {
+	if price < 1 {
+		return fmt.Sprintf("%.4g", price)
+	}
+	return fmt.Sprintf("%.2f", price)
+}
+
+// DefaultClickHandler cycles through the pairs on scroll.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ScrollDown, bar.ScrollRight, bar.ButtonForward:
+		c.Next()
+	case bar.ScrollUp, bar.ScrollLeft, bar.ButtonBack:
+		c.Previous()
+	}
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+func (m *module) Next() {
+	m.moveBy(1)
+}
+
+func (m *module) Previous() {
+	m.moveBy(-1)
+}
+
+func (m *module) moveBy(delta int) {
+	m.Lock()
+	count := len(m.info.Quotes)
+	if count > 0 {
+		m.info.Current = ((m.info.Current+delta)%count + count) % count
+	}
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
+
+func (m *module) update() {
+	quotes, err := m.provider.Quotes(m.pairs)
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	// Keep showing the same pair across refreshes.
+	m.info.Quotes = quotes
+	if m.info.Current >= len(quotes) {
+		m.info.Current = 0
+	}
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func init() {
+	// Retrying failed requests would only slow down the error tests.
+	client.Retry(0, 0)
+}
+
+func TestCoinGecko(t *testing.T) {
+	assert := assert.New(t)
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/simple/price", r.URL.Path)
+		query = r.URL.Query()
+		w.Write([]byte(`{
+			"bitcoin": {"usd": 43210.5, "usd_24h_change": 2.5, "eur": 40000},
+			"ethereum": {"usd": 2300, "eur": 2100.25, "eur_24h_change": -1.25}}`))
+	}))
+	defer srv.Close()
+	coinGeckoAPI = srv.URL
+
+	quotes, err := CoinGecko().Quotes([]Pair{{"bitcoin", "USD"}, {"ethereum", "eur"}})
+	assert.NoError(err)
+	assert.Equal([]Quote{
+		{Pair{"bitcoin", "USD"}, 43210.5, 2.5},
+		{Pair{"ethereum", "eur"}, 2100.25, -1.25},
+	}, quotes)
+	assert.Equal("bitcoin,ethereum", query.Get("ids"))
+	assert.Equal("usd,eur", query.Get("vs_currencies"))
+
+	_, err = CoinGecko().Quotes([]Pair{{"bitcoin", "usd"}, {"dogecoin", "usd"}})
+	assert.Error(err, "missing coin")
+	assert.Equal("usd", query.Get("vs_currencies"), "currencies are not repeated")
+
+	srv.Close()
+	_, err = CoinGecko().Quotes([]Pair{{"bitcoin", "usd"}})
+	assert.Error(err, "server down")
+}
+
+func TestBinance(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/v3/ticker/24hr", r.URL.Path)
+		switch r.URL.Query().Get("symbol") {
+		case "BTCUSDT":
+			w.Write([]byte(`{"symbol": "BTCUSDT", "lastPrice": "43210.50000000", "priceChangePercent": "-0.300"}`))
+		case "ETHBTC":
+			w.Write([]byte(`{"symbol": "ETHBTC", "lastPrice": "0.05321000", "priceChangePercent": "1.000"}`))
+		case "BADBTC":
+			w.Write([]byte(`{"symbol": "BADBTC", "lastPrice": "", "priceChangePercent": "1.000"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": -1121, "msg": "Invalid symbol."}`))
+		}
+	}))
+	defer srv.Close()
+	binanceAPI = srv.URL
+
+	quotes, err := Binance().Quotes([]Pair{{"BTC", "USDT"}, {"eth", "btc"}})
+	assert.NoError(err)
+	assert.Equal([]Quote{
+		{Pair{"BTC", "USDT"}, 43210.5, -0.3},
+		{Pair{"eth", "btc"}, 0.05321, 1},
+	}, quotes)
+
+	_, err = Binance().Quotes([]Pair{{"BTC", "USDT"}, {"XYZ", "USDT"}})
+	assert.Error(err, "invalid symbol")
+
+	_, err = Binance().Quotes([]Pair{{"BAD", "BTC"}})
+	assert.Error(err, "invalid price")
+}
+
+type testProvider struct {
+	quotes []Quote
+	err    error
+}
+
+func (t *testProvider) Quotes([]Pair) ([]Quote, error) { return t.quotes, t.err }
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	colors.LoadFromMap(map[string]string{"good": "#00ff00", "bad": "#ff0000"})
+
+	p := &testProvider{quotes: []Quote{
+		{Pair{"BTC", "USD"}, 43210.5, 2.5},
+		{Pair{"ETH", "USD"}, 2300, -1.25},
+		{Pair{"DOGE", "USD"}, 0.0812345, 0},
+	}}
+	c := New(p)
+	tester := testModule.NewOutputTester(t, c)
+	out := tester.AssertOutput("on start")
+	assert.Equal("BTC 43210.50 +2.5%", out[0].Text())
+	assert.Equal(colors.Hex("#00ff00"), out[0]["color"], "price up")
+
+	c.Click(bar.Event{Button: bar.ScrollDown})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("ETH 2300.00 -1.2%", out[0].Text())
+	assert.Equal(colors.Hex("#ff0000"), out[0]["color"], "price down")
+
+	c.Click(bar.Event{Button: bar.ScrollUp})
+	tester.AssertOutput("on scroll")
+	c.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("DOGE 0.08123 +0.0%", out[0].Text(), "cycles to last pair")
+
+	p.quotes = p.quotes[:1]
+	scheduler.AdvanceBy(5 * time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("BTC 43210.50 +2.5%", out[0].Text(), "resets when pair is gone")
+
+	c.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertNoOutput("on left click")
+
+	p.quotes = nil
+	c.RefreshInterval(time.Minute)
+	scheduler.AdvanceBy(time.Minute)
+	out = tester.AssertOutput("on refresh with no quotes")
+	assert.Empty(out)
+
+	p.err = errors.New("rate limited")
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertError("on provider error")
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/soumya92/barista/base/httpclient"
+)
+
+// API endpoints, overridden in tests.
+var (
+	coinGeckoAPI = "https://api.coingecko.com/api/v3"
+	binanceAPI   = "https://api.binance.com"
+)
+
+// client is shared by all providers, e.g. to reuse connections.
+var client = httpclient.New()
+
+type coinGecko struct{}
+
+// CoinGecko returns a provider that gets prices from the CoinGecko API.
+// Pairs use CoinGecko coin ids (e.g. "bitcoin", "ethereum") and currency
+// codes (e.g. "usd", "eur"). All pairs are fetched in a single request.
+func CoinGecko() Provider {
+	return coinGecko{}
+}
+
+func (coinGecko) Quotes(pairs []Pair) ([]Quote, error) {
+	var coins, currencies []string
+	for _, p := range pairs {
+		coins = appendUnique(coins, strings.ToLower(p.Coin))
+		currencies = appendUnique(currencies, strings.ToLower(p.Currency))
+	}
+	qp := url.Values{}
+	qp.Add("ids", strings.Join(coins, ","))
+	qp.Add("vs_currencies", strings.Join(currencies, ","))
+	qp.Add("include_24hr_change", "true")
+	// The response is {coin: {currency: price, currency_24h_change: change}}.
+	var prices map[string]map[string]float64
+	if err := client.GetJSON(coinGeckoAPI+"/simple/price?"+qp.Encode(), &prices); err != nil {
+		return nil, err
+	}
+	var quotes []Quote
+	for _, p := range pairs {
+		coin, currency := strings.ToLower(p.Coin), strings.ToLower(p.Currency)
+		price, ok := prices[coin][currency]
+		if !ok {
+			return nil, fmt.Errorf("no price for %s/%s", coin, currency)
+		}
+		quotes = append(quotes, Quote{
+			Pair:   p,
+			Price:  price,
+			Change: prices[coin][currency+"_24h_change"],
+		})
+	}
+	return quotes, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+type binance struct{}
+
+// Binance returns a provider that gets prices from the Binance API.
+// Pairs use symbols (e.g. "BTC", "USDT"), which are combined into the Binance
+// trading pair (e.g. "BTCUSDT"). Each pair is fetched in a separate request.
+func Binance() Provider {
+	return binance{}
+}
+
+func (binance) Quotes(pairs []Pair) ([]Quote, error) {
+	var quotes []Quote
+	for _, p := range pairs {
+		symbol := strings.ToUpper(p.Coin + p.Currency)
+		// Binance returns numbers as strings to avoid losing precision.
+		var ticker struct {
+			LastPrice          string
+			PriceChangePercent string
+		}
+		err := client.GetJSON(binanceAPI+"/api/v3/ticker/24hr?symbol="+url.QueryEscape(symbol), &ticker)
+		if err != nil {
+			return nil, err
+		}
+		price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+		if err != nil {
+			return nil, err
+		}
+		change, err := strconv.ParseFloat(ticker.PriceChangePercent, 64)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, Quote{Pair: p, Price: price, Change: change})
+	}
+	return quotes, nil
+}
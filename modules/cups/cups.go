@@ -0,0 +1,266 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package cups provides an i3bar module that shows the print queue.
+
+It speaks IPP directly to the CUPS server, so no CUPS client libraries are
+needed. The module shows the number of queued jobs, and any printers that are
+stopped or reporting errors. By default, nothing is shown when all queues are
+empty and no printer has an error.
+*/
+package cups
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Job represents a print job that has not yet completed.
+type Job struct {
+	ID      int
+	Name    string
+	Printer string
+	// State is the IPP job state, e.g. "pending", "processing", "held".
+	State string
+}
+
+// Printer represents a printer (or print queue) known to CUPS.
+type Printer struct {
+	Name string
+	// State is the IPP printer state: "idle", "processing", or "stopped".
+	State string
+	// Reasons are the IPP printer-state-reasons keywords, e.g.
+	// "media-empty-error" or "toner-low-warning".
+	Reasons []string
+	// Message is a human-readable description of the printer state.
+	Message string
+}
+
+// Error returns true if the printer is stopped, or reports an error.
+func (p Printer) Error() bool {
+	if p.State == "stopped" {
+		return true
+	}
+	for _, r := range p.Reasons {
+		if strings.HasSuffix(r, "-error") {
+			return true
+		}
+	}
+	return false
+}
+
+// Info represents the state of all print queues.
+type Info struct {
+	Jobs     []Job
+	Printers []Printer
+}
+
+// Count returns the number of queued jobs.
+func (i Info) Count() int {
+	return len(i.Jobs)
+}
+
+// Errors returns the printers that are stopped or report an error.
+func (i Info) Errors() []Printer {
+	var errors []Printer
+	for _, p := range i.Printers {
+		if p.Error() {
+			errors = append(errors, p)
+		}
+	}
+	return errors
+}
+
+// Module represents a CUPS bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency for the print queue.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	server     string
+	outputFunc func(Info) bar.Output
+}
+
+// DefaultServer is the address of the local CUPS server.
+const DefaultServer = "http://localhost:631"
+
+// New constructs an instance of the cups module using the local server.
+func New() Module {
+	return Server(DefaultServer)
+}
+
+// Server constructs an instance of the cups module using the given server,
+// as an http(s) URL, e.g. "http://printserver:631".
+func Server(server string) Module {
+	m := &module{
+		Base:   base.New(),
+		server: strings.TrimSuffix(server, "/"),
+	}
+	m.RefreshInterval(10 * time.Second)
+	// Default output template shows errors, then jobs, and nothing otherwise.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{with .Errors}}{{(index . 0).Name}}: error{{else}}{{with .Count}}{{.}} jobs{{end}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+var requestID uint32
+
+// request sends an IPP request with the given operation attributes,
+// and returns the response groups with the given tag.
+func (m *module) request(op uint16, tag byte, attrs ...attribute) ([]*group, error) {
+	attrs = append([]attribute{
+		stringAttr(tagCharset, "attributes-charset", "utf-8"),
+		stringAttr(tagLanguage, "attributes-natural-language", "en"),
+	}, attrs...)
+	req := message{
+		code:      op,
+		requestID: atomic.AddUint32(&requestID, 1),
+		groups:    []*group{newGroup(tagOperation, attrs...)},
+	}
+	resp, err := client.Post(m.server+"/", "application/ipp", bytes.NewReader(req.encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CUPS: HTTP %s", resp.Status)
+	}
+	res, err := decode(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// CUPS returns not-found if there are no jobs or printers.
+	if res.code == statusNotFound {
+		return nil, nil
+	}
+	if res.code > statusOKMax {
+		return nil, fmt.Errorf("CUPS: IPP status 0x%04x", res.code)
+	}
+	var groups []*group
+	for _, g := range res.groups {
+		if g.tag == tag {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+var jobStates = map[int]string{
+	3: "pending", 4: "held", 5: "processing", 6: "stopped",
+	7: "canceled", 8: "aborted", 9: "completed",
+}
+
+var printerStates = map[int]string{3: "idle", 4: "processing", 5: "stopped"}
+
+func (m *module) jobs() ([]Job, error) {
+	groups, err := m.request(opGetJobs, tagJob,
+		stringAttr(tagURI, "printer-uri", "ipp://localhost/"),
+		stringAttr(tagKeyword, "which-jobs", "not-completed"),
+		stringAttr(tagKeyword, "requested-attributes",
+			"job-id", "job-name", "job-state", "job-printer-uri"))
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Job
+	for _, g := range groups {
+		jobs = append(jobs, Job{
+			ID:      g.int("job-id"),
+			Name:    g.string("job-name"),
+			Printer: path.Base(g.string("job-printer-uri")),
+			State:   jobStates[g.int("job-state")],
+		})
+	}
+	return jobs, nil
+}
+
+func (m *module) printers() ([]Printer, error) {
+	groups, err := m.request(opGetPrinters, tagPrinter,
+		stringAttr(tagKeyword, "requested-attributes",
+			"printer-name", "printer-state", "printer-state-reasons", "printer-state-message"))
+	if err != nil {
+		return nil, err
+	}
+	var printers []Printer
+	for _, g := range groups {
+		p := Printer{
+			Name:    g.string("printer-name"),
+			State:   printerStates[g.int("printer-state")],
+			Message: g.string("printer-state-message"),
+		}
+		for _, r := range g.strings("printer-state-reasons") {
+			if r != "none" {
+				p.Reasons = append(p.Reasons, r)
+			}
+		}
+		printers = append(printers, p)
+	}
+	return printers, nil
+}
+
+func (m *module) update() {
+	jobs, err := m.jobs()
+	if m.Error(err) {
+		return
+	}
+	printers, err := m.printers()
+	if m.Error(err) {
+		return
+	}
+	info := Info{Jobs: jobs, Printers: printers}
+	m.Lock()
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cups
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	assert := assert.New(t)
+	msg := message{
+		code:      opGetJobs,
+		requestID: 42,
+		groups: []*group{newGroup(tagPrinter,
+			stringAttr(tagName, "printer-name", "office"),
+			intAttr(tagEnum, "printer-state", 5),
+			stringAttr(tagKeyword, "printer-state-reasons", "media-empty-error", "toner-low-warning"),
+		)},
+	}
+	decoded, err := decode(bytes.NewReader(msg.encode()))
+	assert.NoError(err)
+	assert.Equal(uint16(opGetJobs), decoded.code)
+	assert.Equal(uint32(42), decoded.requestID)
+	assert.Equal(1, len(decoded.groups))
+	g := decoded.groups[0]
+	assert.Equal("office", g.string("printer-name"))
+	assert.Equal(5, g.int("printer-state"))
+	assert.Equal([]string{"media-empty-error", "toner-low-warning"},
+		g.strings("printer-state-reasons"))
+
+	encoded := msg.encode()
+	_, err = decode(bytes.NewReader(encoded[:len(encoded)-5]))
+	assert.Error(err, "truncated message")
+}
+
+type fakeCups struct {
+	sync.Mutex
+	jobs     []*group
+	printers []*group
+	status   uint16
+}
+
+func (f *fakeCups) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := decode(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	res := message{code: f.status, requestID: req.requestID,
+		groups: []*group{newGroup(tagOperation,
+			stringAttr(tagCharset, "attributes-charset", "utf-8"))}}
+	switch req.code {
+	case opGetJobs:
+		if len(f.jobs) == 0 && f.status == 0 {
+			res.code = statusNotFound
+		}
+		res.groups = append(res.groups, f.jobs...)
+	case opGetPrinters:
+		res.groups = append(res.groups, f.printers...)
+	}
+	w.Header().Set("Content-Type", "application/ipp")
+	w.Write(res.encode())
+}
+
+func printer(name string, state int, reasons ...string) *group {
+	return newGroup(tagPrinter,
+		stringAttr(tagName, "printer-name", name),
+		intAttr(tagEnum, "printer-state", state),
+		stringAttr(tagKeyword, "printer-state-reasons", reasons...))
+}
+
+func job(id int, name string) *group {
+	return newGroup(tagJob,
+		intAttr(tagInteger, "job-id", id),
+		stringAttr(tagName, "job-name", name),
+		intAttr(tagEnum, "job-state", 3),
+		stringAttr(tagURI, "job-printer-uri", "ipp://localhost/printers/office"))
+}
+
+func TestCups(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	cups := &fakeCups{printers: []*group{printer("office", 3, "none")}}
+	server := httptest.NewServer(cups)
+	defer server.Close()
+
+	c := Server(server.URL)
+	tester := testModule.NewOutputTester(t, c)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "hidden when queues are empty")
+
+	cups.Lock()
+	cups.jobs = []*group{job(12, "report.pdf"), job(13, "photo.jpg")}
+	cups.Unlock()
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("2 jobs", out[0].Text())
+
+	cups.Lock()
+	cups.printers = append(cups.printers, printer("label", 5, "paused"))
+	cups.Unlock()
+	scheduler.NextTick()
+	out = tester.AssertOutput("on printer error")
+	assert.Equal("label: error", out[0].Text())
+
+	var info Info
+	c.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(Job{ID: 12, Name: "report.pdf", Printer: "office", State: "pending"}, info.Jobs[0])
+	assert.Equal([]Printer{
+		{Name: "office", State: "idle"},
+		{Name: "label", State: "stopped", Reasons: []string{"paused"}},
+	}, info.Printers)
+
+	cups.Lock()
+	cups.status = 0x0500
+	cups.Unlock()
+	scheduler.NextTick()
+	tester.AssertError("on server error")
+}
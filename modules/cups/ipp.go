@@ -0,0 +1,206 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cups
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The subset of IPP (RFC 8010, 8011) needed to list jobs and printers.
+
+const (
+	opGetJobs      = 0x000A
+	opGetPrinters  = 0x4002 // CUPS-Get-Printers
+	tagOperation   = 0x01
+	tagJob         = 0x02
+	tagEnd         = 0x03
+	tagPrinter     = 0x04
+	tagInteger     = 0x21
+	tagEnum        = 0x23
+	tagName        = 0x42
+	tagKeyword     = 0x44
+	tagURI         = 0x45
+	tagCharset     = 0x47
+	tagLanguage    = 0x48
+	statusOKMax    = 0x00FF
+	statusNotFound = 0x0406
+)
+
+// attribute is a single, possibly multi-valued, IPP attribute.
+type attribute struct {
+	tag    byte
+	name   string
+	values [][]byte
+}
+
+func stringAttr(tag byte, name string, values ...string) attribute {
+	a := attribute{tag: tag, name: name}
+	for _, v := range values {
+		a.values = append(a.values, []byte(v))
+	}
+	return a
+}
+
+func intAttr(tag byte, name string, value int) attribute {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint32(v, uint32(value))
+	return attribute{tag: tag, name: name, values: [][]byte{v}}
+}
+
+// group is a group of attributes, e.g. for each job or printer.
+type group struct {
+	tag   byte
+	attrs map[string]attribute
+	// order preserves the attribute order for encoding.
+	order []string
+}
+
+func newGroup(tag byte, attrs ...attribute) *group {
+	g := &group{tag: tag, attrs: map[string]attribute{}}
+	for _, a := range attrs {
+		g.add(a)
+	}
+	return g
+}
+
+func (g *group) add(a attribute) {
+	if _, ok := g.attrs[a.name]; !ok {
+		g.order = append(g.order, a.name)
+	}
+	g.attrs[a.name] = a
+}
+
+// string returns the first value of a text attribute.
+func (g *group) string(name string) string {
+	if a, ok := g.attrs[name]; ok && len(a.values) > 0 {
+		return string(a.values[0])
+	}
+	return ""
+}
+
+// strings returns all values of a text attribute.
+func (g *group) strings(name string) []string {
+	var values []string
+	for _, v := range g.attrs[name].values {
+		values = append(values, string(v))
+	}
+	return values
+}
+
+// int returns the first value of an integer or enum attribute.
+func (g *group) int(name string) int {
+	if a, ok := g.attrs[name]; ok && len(a.values) > 0 && len(a.values[0]) == 4 {
+		return int(int32(binary.BigEndian.Uint32(a.values[0])))
+	}
+	return 0
+}
+
+// message is an IPP request or response. The code is the
+// operation for requests, and the status for responses.
+type message struct {
+	code      uint16
+	requestID uint32
+	groups    []*group
+}
+
+func (m message) encode() []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{2, 0})
+	binary.Write(&buf, binary.BigEndian, m.code)
+	binary.Write(&buf, binary.BigEndian, m.requestID)
+	for _, g := range m.groups {
+		buf.WriteByte(g.tag)
+		for _, name := range g.order {
+			a := g.attrs[name]
+			for i, v := range a.values {
+				buf.WriteByte(a.tag)
+				// Additional values of an attribute have an empty name.
+				n := a.name
+				if i > 0 {
+					n = ""
+				}
+				binary.Write(&buf, binary.BigEndian, uint16(len(n)))
+				buf.WriteString(n)
+				binary.Write(&buf, binary.BigEndian, uint16(len(v)))
+				buf.Write(v)
+			}
+		}
+	}
+	buf.WriteByte(tagEnd)
+	return buf.Bytes()
+}
+
+var errTruncated = errors.New("truncated IPP message")
+
+func decode(r io.Reader) (message, error) {
+	var m message
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return m, err
+	}
+	if header[0] < 1 || header[0] > 2 {
+		return m, fmt.Errorf("unsupported IPP version %d.%d", header[0], header[1])
+	}
+	m.code = binary.BigEndian.Uint16(header[2:4])
+	m.requestID = binary.BigEndian.Uint32(header[4:8])
+	var current *group
+	var last attribute
+	readField := func() ([]byte, error) {
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, errTruncated
+		}
+		field := make([]byte, length)
+		if _, err := io.ReadFull(r, field); err != nil {
+			return nil, errTruncated
+		}
+		return field, nil
+	}
+	for {
+		var tag [1]byte
+		if _, err := io.ReadFull(r, tag[:]); err != nil {
+			return m, errTruncated
+		}
+		if tag[0] == tagEnd {
+			return m, nil
+		}
+		// Tags below 0x10 are delimiters that start a new group.
+		if tag[0] < 0x10 {
+			current = newGroup(tag[0])
+			m.groups = append(m.groups, current)
+			continue
+		}
+		if current == nil {
+			return m, errors.New("IPP attribute outside group")
+		}
+		name, err := readField()
+		if err != nil {
+			return m, err
+		}
+		value, err := readField()
+		if err != nil {
+			return m, err
+		}
+		if len(name) > 0 {
+			last = attribute{tag: tag[0], name: string(name)}
+		}
+		last.values = append(last.values, value)
+		current.add(last)
+	}
+}
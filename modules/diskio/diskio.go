@@ -17,7 +17,6 @@ package diskio
 
 import (
 	"bufio"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -26,8 +25,10 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
 	"github.com/soumya92/barista/base/multi"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/format"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -45,12 +46,12 @@ func (r Rate) In(unit string) float64 {
 
 // IEC returns the rate formatted in base 2.
 func (r Rate) IEC() string {
-	return humanize.IBytes(uint64(r))
+	return format.IBytes(uint64(r))
 }
 
 // SI returns the rate formatted in base 10.
 func (r Rate) SI() string {
-	return humanize.Bytes(uint64(r))
+	return format.Bytes(uint64(r))
 }
 
 // IO represents input and output rates for a disk.
@@ -152,9 +153,11 @@ func (i *io) Update(in, out uint64) (inRate, outRate int) {
 	return // inRate, outRate
 }
 
+var fs = hostfs.Fs()
+
 func (m *Module) update() {
 	var err error
-	f, err := os.Open("/proc/diskstats")
+	f, err := fs.Open("/proc/diskstats")
 	if m.moduleSet.Error(err) {
 		return
 	}
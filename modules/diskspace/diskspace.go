@@ -23,6 +23,7 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/format"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -72,12 +73,12 @@ func (b Bytes) In(unit string) float64 {
 
 // IEC returns the size formatted in base 2.
 func (b Bytes) IEC() string {
-	return humanize.IBytes(uint64(b))
+	return format.IBytes(uint64(b))
 }
 
 // SI returns the size formatted in base 10.
 func (b Bytes) SI() string {
-	return humanize.Bytes(uint64(b))
+	return format.Bytes(uint64(b))
 }
 
 // Module represents a diskspace bar module. It supports setting the output
@@ -122,7 +123,7 @@ func New(path string) Module {
 	// Default is to refresh every 3s, matching the behaviour of top.
 	m.Schedule().Every(3 * time.Second)
 	// Construct a simple template that's just 2 decimals of the used disk space.
-	m.OutputTemplate(outputs.TextTemplate(`{{.Used.In "GB" | printf "%.2f"}} GB`))
+	m.OutputTemplate(outputs.TextTemplate(`{{.Used.In "GB" | number 2}} GB`))
 	// Update disk information when asked.
 	m.OnUpdate(m.update)
 	return m
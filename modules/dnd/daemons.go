@@ -0,0 +1,155 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnd
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	dunstDest  = "org.freedesktop.Notifications"
+	dunstPath  = "/org/freedesktop/Notifications"
+	dunstIface = "org.dunstproject.cmd0"
+	propsIface = "org.freedesktop.DBus.Properties"
+)
+
+type dunst struct {
+	sync.Mutex
+	obj dbus.BusObject
+}
+
+// Dunst constructs an instance of the do-not-disturb module for dunst.
+func Dunst() Module {
+	return newModule(&dunst{})
+}
+
+// object returns the dunst d-bus object, connecting to the session bus
+// if necessary.
+func (d *dunst) object() (dbus.BusObject, error) {
+	d.Lock()
+	defer d.Unlock()
+	if d.obj != nil {
+		return d.obj, nil
+	}
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+	d.obj = conn.Object(dunstDest, dunstPath)
+	return d.obj, nil
+}
+
+func (d *dunst) enabled() (bool, error) {
+	obj, err := d.object()
+	if err != nil {
+		return false, err
+	}
+	v, err := obj.GetProperty(dunstIface + ".paused")
+	if err != nil {
+		return false, err
+	}
+	paused, _ := v.Value().(bool)
+	return paused, nil
+}
+
+func (d *dunst) setEnabled(enabled bool) error {
+	obj, err := d.object()
+	if err != nil {
+		return err
+	}
+	return obj.Call(propsIface+".Set", 0,
+		dunstIface, "paused", dbus.MakeVariant(enabled)).Err
+}
+
+func (d *dunst) watch(f func()) error {
+	// A private connection is required since we're using Signal.
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return err
+	}
+	if err := conn.Hello(); err != nil {
+		return err
+	}
+	matchRule := strings.Join([]string{
+		"type='signal'",
+		"interface='" + propsIface + "'",
+		"member='PropertiesChanged'",
+		"path='" + dunstPath + "'",
+	}, ",")
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		return err
+	}
+	c := make(chan *dbus.Signal, 10)
+	conn.Signal(c)
+	for v := range c {
+		if len(v.Body) > 0 && v.Body[0] == dunstIface {
+			f()
+		}
+	}
+	return nil
+}
+
+type mako struct {
+	mode string
+}
+
+// DefaultMakoMode is the mode used for do-not-disturb by the mako module.
+const DefaultMakoMode = "do-not-disturb"
+
+// Mako constructs an instance of the do-not-disturb module for mako,
+// using the given mako mode for do-not-disturb.
+func Mako(mode string) Module {
+	m := newModule(&mako{mode})
+	m.RefreshInterval(5 * time.Second)
+	return m
+}
+
+// makoctl runs makoctl with the given arguments.
+var makoctl = func(args ...string) (string, error) {
+	out, err := exec.Command("makoctl", args...).Output()
+	return string(out), err
+}
+
+func (m *mako) enabled() (bool, error) {
+	out, err := makoctl("mode")
+	if err != nil {
+		return false, err
+	}
+	for _, mode := range strings.Split(out, "\n") {
+		if strings.TrimSpace(mode) == m.mode {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mako) setEnabled(enabled bool) error {
+	flag := "-r"
+	if enabled {
+		flag = "-a"
+	}
+	_, err := makoctl("mode", flag, m.mode)
+	return err
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package dnd provides an i3bar module that shows and toggles do-not-disturb
+for the notification daemon.
+
+For dunst, the paused state is read and set using dunst's d-bus interface,
+and changes are picked up immediately from its PropertiesChanged signals.
+For mako, do-not-disturb is implemented using a mode (by default
+"do-not-disturb", which should hide notifications in the mako config), and
+the modes are read and set using makoctl. Since mako does not emit signals
+when modes change, the mode is polled at a configurable interval.
+*/
+package dnd
+
+import (
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the do-not-disturb state.
+type Info struct {
+	Enabled bool
+}
+
+// Controller provides an interface to control do-not-disturb from the click handler.
+type Controller interface {
+	// SetEnabled enables or disables do-not-disturb.
+	SetEnabled(bool)
+	// Toggle toggles do-not-disturb.
+	Toggle()
+}
+
+// Module is the public interface for a do-not-disturb module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control do-not-disturb.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency, for daemons that
+	// do not notify on changes, i.e. mako.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// daemon reads and sets the do-not-disturb state of a notification daemon.
+type daemon interface {
+	enabled() (bool, error)
+	setEnabled(bool) error
+}
+
+// watcher is implemented by daemons that notify when the state changes.
+type watcher interface {
+	// watch calls the given function whenever the state changes.
+	// It only returns on error.
+	watch(func()) error
+}
+
+type module struct {
+	*base.Base
+	daemon     daemon
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+func newModule(d daemon) *module {
+	m := &module{
+		Base:   base.New(),
+		daemon: d,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template only shows when do-not-disturb is enabled.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Enabled}}DND{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler toggles do-not-disturb on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Toggle()
+	}
+}
+
+func (m *module) SetEnabled(enabled bool) {
+	if m.Error(m.daemon.setEnabled(enabled)) {
+		return
+	}
+	// Daemons that notify on changes will update automatically.
+	if _, ok := m.daemon.(watcher); !ok {
+		m.Update()
+	}
+}
+
+func (m *module) Toggle() {
+	m.Lock()
+	enabled := m.info.Enabled
+	m.Unlock()
+	m.SetEnabled(!enabled)
+}
+
+// Stream starts watching for changes if supported by the daemon, and then
+// returns the output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	if w, ok := m.daemon.(watcher); ok {
+		go func() { m.Error(w.watch(m.Update)) }()
+	}
+	return ch
+}
+
+func (m *module) update() {
+	enabled, err := m.daemon.enabled()
+	if m.Error(err) {
+		return
+	}
+	info := Info{Enabled: enabled}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnd
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestMako(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+
+	var mu sync.Mutex
+	modes := []string{"default"}
+	var fail error
+	makoctl = func(args ...string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail != nil {
+			return "", fail
+		}
+		switch {
+		case len(args) == 1:
+			return strings.Join(modes, "\n") + "\n", nil
+		case args[1] == "-a":
+			modes = append(modes, args[2])
+		case args[1] == "-r":
+			modes = modes[:1]
+		}
+		return "", nil
+	}
+
+	d := Mako(DefaultMakoMode)
+	tester := testModule.NewOutputTester(t, d)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text())
+
+	d.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click")
+	assert.Equal("DND", out[0].Text())
+	assert.Equal([]string{"default", "do-not-disturb"}, modes)
+
+	mu.Lock()
+	modes = modes[:1]
+	mu.Unlock()
+	scheduler.AdvanceBy(5 * time.Second)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("", out[0].Text(), "picks up external changes")
+
+	d.OutputTemplate(func(i interface{}) bar.Output {
+		if i.(Info).Enabled {
+			return bar.Output{bar.NewSegment("quiet")}
+		}
+		return bar.Output{bar.NewSegment("noisy")}
+	})
+	out = tester.AssertOutput("on template change")
+	assert.Equal("noisy", out[0].Text())
+
+	mu.Lock()
+	fail = errors.New("mako not running")
+	mu.Unlock()
+	d.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertError("when makoctl fails")
+}
@@ -0,0 +1,277 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package docker provides an i3bar module that shows docker container counts.
+
+It uses the docker engine API over the docker socket, and subscribes to the
+container event stream so that the output is updated as soon as containers
+are started, stopped, or change health state.
+*/
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Container represents a single docker container.
+type Container struct {
+	ID    string
+	Name  string
+	Image string
+	// State is the container state, e.g. "running", "exited".
+	State string
+	// Status is the human-readable status, e.g. "Up 2 hours (healthy)".
+	Status string
+}
+
+// Running returns true if the container is running.
+func (c Container) Running() bool {
+	return c.State == "running"
+}
+
+// Unhealthy returns true if the container's health check is failing.
+func (c Container) Unhealthy() bool {
+	return strings.Contains(c.Status, "(unhealthy)")
+}
+
+// Info represents the list of containers known to the docker engine.
+type Info []Container
+
+// Running returns the number of running containers.
+func (i Info) Running() int {
+	return i.count(Container.Running)
+}
+
+// Unhealthy returns the number of unhealthy containers.
+func (i Info) Unhealthy() int {
+	return i.count(Container.Unhealthy)
+}
+
+// Stopped returns the number of containers that are not running.
+func (i Info) Stopped() int {
+	return len(i) - i.Running()
+}
+
+func (i Info) count(pred func(Container) bool) int {
+	count := 0
+	for _, c := range i {
+		if pred(c) {
+			count++
+		}
+	}
+	return count
+}
+
+// Module represents a docker bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// Label restricts the module to containers with the given label,
+	// either "key" or "key=value".
+	Label(string) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	client     *http.Client
+	labels     []string
+	outputFunc func(Info) bar.Output
+}
+
+// DefaultSocket is the default location for the docker engine API.
+const DefaultSocket = "/var/run/docker.sock"
+
+// New constructs an instance of the docker module
+// using the docker engine API at the default socket.
+func New() Module {
+	return Socket(DefaultSocket)
+}
+
+// Socket constructs an instance of the docker module
+// using the docker engine API at the given unix socket.
+func Socket(path string) Module {
+	m := &module{
+		Base: base.New(),
+		client: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}},
+	}
+	// Default output template is the number of running containers,
+	// along with the number of unhealthy ones if any.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{.Running}} running{{with .Unhealthy}}, {{.}} unhealthy{{end}}`))
+	return m
+}
+
+func (m *module) Label(label string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.labels = append(m.labels, label)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) Stream() <-chan bar.Output {
+	m.OnUpdate(m.update)
+	go m.watchEvents()
+	return m.Base.Stream()
+}
+
+// filters returns the JSON-encoded filters for the docker API.
+func (m *module) filters(extra map[string][]string) string {
+	m.Lock()
+	defer m.Unlock()
+	f := map[string][]string{}
+	for k, v := range extra {
+		f[k] = v
+	}
+	if len(m.labels) > 0 {
+		f["label"] = m.labels
+	}
+	enc, _ := json.Marshal(f)
+	return string(enc)
+}
+
+// get performs a GET request against the docker engine API. Responses other
+// than 200 OK are converted to errors, using the message from the daemon.
+func (m *module) get(path string, query url.Values) (*http.Response, error) {
+	// The host is ignored since all connections use the unix socket.
+	u := url.URL{Scheme: "http", Host: "docker", Path: path, RawQuery: query.Encode()}
+	resp, err := m.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Message != "" {
+		return nil, errors.New(apiErr.Message)
+	}
+	return nil, fmt.Errorf("docker: %s", resp.Status)
+}
+
+// retryDelay and maxRetryDelay control how long to wait before resubscribing
+// to the event stream after an error. The delay doubles with each consecutive
+// error, and is reset once the stream is established.
+var retryDelay = 10 * time.Second
+var maxRetryDelay = 5 * time.Minute
+
+// watchEvents subscribes to container events and triggers an update on each
+// event. If the connection is lost, it waits a little and tries again.
+func (m *module) watchEvents() {
+	delay := retryDelay
+	for {
+		resp, err := m.get("/events", url.Values{
+			"filters": {m.filters(map[string][]string{"type": {"container"}})},
+		})
+		if m.Error(err) {
+			// Clicking on the error will trigger an update, which refreshes
+			// the container list but does not restart the event stream,
+			// so keep retrying here.
+			time.Sleep(delay)
+			if delay *= 2; delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			continue
+		}
+		delay = retryDelay
+		s := bufio.NewScanner(resp.Body)
+		for s.Scan() {
+			m.Update()
+		}
+		resp.Body.Close()
+		time.Sleep(time.Second)
+	}
+}
+
+// apiContainer is the container as returned by the docker API.
+type apiContainer struct {
+	ID     string   `json:"Id"`
+	Names  []string `json:"Names"`
+	Image  string   `json:"Image"`
+	State  string   `json:"State"`
+	Status string   `json:"Status"`
+}
+
+func (m *module) update() {
+	resp, err := m.get("/containers/json", url.Values{
+		"all":     {"1"},
+		"filters": {m.filters(nil)},
+	})
+	if m.Error(err) {
+		return
+	}
+	defer resp.Body.Close()
+	var containers []apiContainer
+	if m.Error(json.NewDecoder(resp.Body).Decode(&containers)) {
+		return
+	}
+	info := Info{}
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		info = append(info, Container{
+			ID:     c.ID,
+			Name:   name,
+			Image:  c.Image,
+			State:  c.State,
+			Status: c.Status,
+		})
+	}
+	m.Lock()
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+const containersJSON = `[
+ {"Id": "1", "Names": ["/web"], "Image": "nginx", "State": "running", "Status": "Up 2 hours (healthy)"},
+ {"Id": "2", "Names": ["/db"], "Image": "postgres", "State": "running", "Status": "Up 3 minutes (unhealthy)"},
+ {"Id": "3", "Names": ["/old"], "Image": "alpine", "State": "exited", "Status": "Exited (0) 2 days ago"}
+]`
+
+func init() {
+	retryDelay, maxRetryDelay = 10*time.Millisecond, 40*time.Millisecond
+}
+
+func TestDocker(t *testing.T) {
+	assert := assert.New(t)
+	events := make(chan string)
+	filters := make(chan string, 10)
+	sock, cleanup := listen(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			filters <- r.URL.Query().Get("filters")
+			fmt.Fprint(w, containersJSON)
+		case "/events":
+			w.(http.Flusher).Flush()
+			for e := range events {
+				fmt.Fprintln(w, e)
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	defer cleanup()
+	defer close(events)
+
+	d := Socket(sock).Label("env=test")
+	tester := testModule.NewOutputTester(t, d)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("2 running, 1 unhealthy", out[0].Text())
+	assert.Equal(`{"label":["env=test"]}`, <-filters, "label filter is passed")
+
+	events <- `{"status": "start", "id": "4"}`
+	out = tester.AssertOutput("on event")
+	assert.Equal("2 running, 1 unhealthy", out[0].Text())
+
+	var info Info
+	d.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Textf("%d", i.Stopped())
+	})
+	out = tester.AssertOutput("on output func change")
+	assert.Equal("1", out[0].Text())
+	assert.Equal("db", info[1].Name, "names are cleaned up")
+	assert.True(info[1].Unhealthy())
+	assert.False(info[0].Unhealthy())
+}
+
+func listen(t *testing.T, h http.HandlerFunc) (sock string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock = filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(h)
+	srv.Listener = l
+	srv.Start()
+	return sock, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	assert := assert.New(t)
+	sock, cleanup := listen(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message": "permission denied"}`)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	defer cleanup()
+	m := Socket(sock).(*module)
+
+	_, err := m.get("/containers/json", nil)
+	assert.EqualError(err, "permission denied", "uses message from daemon")
+	_, err = m.get("/events", nil)
+	assert.EqualError(err, "docker: 503 Service Unavailable",
+		"falls back to http status without message")
+}
+
+func TestEventsBackoff(t *testing.T) {
+	assert := assert.New(t)
+	requests := make(chan time.Time, 10)
+	var count int32
+	done := make(chan struct{})
+	sock, cleanup := listen(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			fmt.Fprint(w, containersJSON)
+		case "/events":
+			requests <- time.Now()
+			if atomic.AddInt32(&count, 1) < 5 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.(http.Flusher).Flush()
+			<-done
+		}
+	})
+	defer cleanup()
+	defer close(done)
+
+	outs := Socket(sock).Stream()
+	go func() {
+		for range outs {
+		}
+	}()
+
+	last := <-requests
+	for _, expected := range []time.Duration{10, 20, 40, 40} {
+		select {
+		case next := <-requests:
+			assert.True(next.Sub(last) >= expected*time.Millisecond,
+				"waits at least %dms before retrying", expected)
+			last = next
+		case <-time.After(time.Second):
+			assert.Fail("expected a retry after %dms", expected)
+		}
+	}
+}
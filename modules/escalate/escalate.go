@@ -0,0 +1,280 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package escalate provides a module that "wraps" an existing module, and makes
+urgent output harder to miss the longer it stays urgent, e.g. for a critical
+battery that would otherwise go unnoticed:
+
+	b := escalate.New(battery.All()).
+		Blink(time.Minute, time.Second).
+		Widen(2*time.Minute, 300).
+		Notify(5*time.Minute, "Battery critical")
+
+Each policy starts once any segment of the output has been urgent for the
+given duration, and stops as soon as the output is no longer urgent:
+
+Blink alternates the urgent segments between urgent and normal colors.
+Widen sets a minimum width (in pixels) for the urgent segments, centering
+their text. Notify sends a desktop notification with the text of the
+urgent segments, once for each time the module becomes urgent.
+*/
+package escalate
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/notify"
+	"github.com/soumya92/barista/pango"
+)
+
+// Module represents an escalating module, which forwards clicks and
+// pause/resume events to the wrapped module.
+type Module interface {
+	bar.Module
+	bar.Clickable
+	bar.Pausable
+
+	// Blink alternates urgent segments between urgent and normal colors
+	// at the given interval, once the output has been urgent for a while.
+	Blink(after, interval time.Duration) Module
+
+	// Widen sets the minimum width of urgent segments, in pixels, once the
+	// output has been urgent for a while.
+	Widen(after time.Duration, minWidth int) Module
+
+	// Notify sends a critical desktop notification with the given summary
+	// and the text of the urgent segments, once the output has been urgent
+	// for a while.
+	Notify(after time.Duration, summary string) Module
+}
+
+// sendNotification sends a notification, and can be replaced in tests.
+var sendNotification = (*notify.Notifier).Notify
+
+type module struct {
+	bar.Module
+	scheduler scheduler.Scheduler
+	notifier  *notify.Notifier
+	mutex     sync.Mutex
+	channel   chan bar.Output
+	// Zero durations disable the corresponding policy.
+	blinkAfter    time.Duration
+	blinkInterval time.Duration
+	widenAfter    time.Duration
+	minWidth      int
+	notifyAfter   time.Duration
+	summary       string
+	// The last output from the wrapped module, the time it became urgent
+	// (zero if it is not urgent), and whether a notification was sent.
+	lastOutput  bar.Output
+	urgentSince time.Time
+	notified    bool
+}
+
+// New wraps an existing module, so that escalation policies can be added.
+// Without any policies, the output is passed through unchanged.
+func New(original bar.Module) Module {
+	m := &module{Module: original, notifier: notify.New("barista")}
+	m.scheduler = scheduler.Do(m.escalate)
+	return m
+}
+
+func (m *module) Blink(after, interval time.Duration) Module {
+	m.mutex.Lock()
+	m.blinkAfter, m.blinkInterval = after, interval
+	m.mutex.Unlock()
+	m.escalate()
+	return m
+}
+
+func (m *module) Widen(after time.Duration, minWidth int) Module {
+	m.mutex.Lock()
+	m.widenAfter, m.minWidth = after, minWidth
+	m.mutex.Unlock()
+	m.escalate()
+	return m
+}
+
+func (m *module) Notify(after time.Duration, summary string) Module {
+	m.mutex.Lock()
+	m.notifyAfter, m.summary = after, summary
+	m.mutex.Unlock()
+	m.escalate()
+	return m
+}
+
+// Stream sets up the output pipeline, and returns the escalated outputs.
+func (m *module) Stream() <-chan bar.Output {
+	m.mutex.Lock()
+	m.channel = make(chan bar.Output, 10)
+	m.mutex.Unlock()
+	go m.pipe(m.Module.Stream())
+	return m.channel
+}
+
+func (m *module) pipe(input <-chan bar.Output) {
+	for out := range input {
+		m.mutex.Lock()
+		m.lastOutput = out
+		switch {
+		case !isUrgent(out):
+			m.urgentSince = time.Time{}
+		case m.urgentSince.IsZero():
+			m.urgentSince = scheduler.Now()
+			m.notified = false
+		}
+		m.mutex.Unlock()
+		m.escalate()
+	}
+}
+
+// isUrgent returns true if any segment of the output is urgent.
+func isUrgent(o bar.Output) bool {
+	for _, s := range o {
+		if urgent, _ := s["urgent"].(bool); urgent {
+			return true
+		}
+	}
+	return false
+}
+
+// escalate outputs the last output with the policies that apply to it,
+// sends a notification if one is due, and schedules the next change.
+func (m *module) escalate() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.channel == nil || m.lastOutput == nil {
+		return
+	}
+	if m.urgentSince.IsZero() {
+		m.scheduler.Stop()
+		m.channel <- m.lastOutput
+		return
+	}
+	now := scheduler.Now()
+	urgentFor := now.Sub(m.urgentSince)
+	if m.notifyAfter > 0 && !m.notified && urgentFor >= m.notifyAfter {
+		m.notified = true
+		go sendNotification(m.notifier, notify.Notification{
+			Summary: m.summary,
+			Body:    urgentText(m.lastOutput),
+			Urgency: notify.Critical,
+		})
+	}
+	m.channel <- m.styled(urgentFor)
+	if next, ok := m.nextChange(urgentFor); ok {
+		m.scheduler.At(m.urgentSince.Add(next))
+	} else {
+		m.scheduler.Stop()
+	}
+}
+
+// styled returns a copy of the last output with the blink and widen
+// policies applied to its urgent segments.
+func (m *module) styled(urgentFor time.Duration) bar.Output {
+	blinkOff := m.blinkInterval > 0 && urgentFor >= m.blinkAfter &&
+		((urgentFor-m.blinkAfter)/m.blinkInterval)%2 == 1
+	widen := m.minWidth > 0 && urgentFor >= m.widenAfter
+	out := make(bar.Output, 0, len(m.lastOutput))
+	for _, s := range m.lastOutput {
+		if urgent, _ := s["urgent"].(bool); !urgent || !(blinkOff || widen) {
+			out = append(out, s)
+			continue
+		}
+		// Copy the segment, since it may still be in use by the bar.
+		segment := bar.Segment{}
+		for k, v := range s {
+			segment[k] = v
+		}
+		if blinkOff {
+			segment.Urgent(false)
+		}
+		if widen {
+			segment.MinWidth(m.minWidth).Align(bar.AlignCenter)
+		}
+		out = append(out, segment)
+	}
+	return out
+}
+
+// nextChange returns how long after becoming urgent the output next
+// changes, if any policy is still to take effect.
+func (m *module) nextChange(urgentFor time.Duration) (time.Duration, bool) {
+	var next []time.Duration
+	if m.blinkInterval > 0 {
+		toggle := m.blinkAfter
+		if urgentFor >= m.blinkAfter {
+			toggle += ((urgentFor-m.blinkAfter)/m.blinkInterval + 1) * m.blinkInterval
+		}
+		next = append(next, toggle)
+	}
+	if m.minWidth > 0 && urgentFor < m.widenAfter {
+		next = append(next, m.widenAfter)
+	}
+	if m.notifyAfter > 0 && !m.notified {
+		next = append(next, m.notifyAfter)
+	}
+	if len(next) == 0 {
+		return 0, false
+	}
+	earliest := next[0]
+	for _, n := range next[1:] {
+		if n < earliest {
+			earliest = n
+		}
+	}
+	return earliest, true
+}
+
+// urgentText returns the plain text of the urgent segments.
+func urgentText(o bar.Output) string {
+	var texts []string
+	for _, s := range o {
+		if urgent, _ := s["urgent"].(bool); !urgent {
+			continue
+		}
+		text := s.Text()
+		if markup, _ := s["markup"].(bar.Markup); markup == bar.MarkupPango {
+			text = pango.PlainText(text)
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, " ")
+}
+
+// Click passes through the click event if supported by the wrapped module.
+func (m *module) Click(e bar.Event) {
+	if clickable, ok := m.Module.(bar.Clickable); ok {
+		clickable.Click(e)
+	}
+}
+
+// Pause passes through the pause event if supported by the wrapped module.
+func (m *module) Pause() {
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// Resume passes through the resume event if supported by the wrapped module.
+func (m *module) Resume() {
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Resume()
+	}
+}
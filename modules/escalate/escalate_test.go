@@ -0,0 +1,152 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escalate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/notify"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func urgentOutput(text string) bar.Output {
+	return bar.Output{
+		bar.NewSegment("icon"),
+		bar.NewSegment(text).Urgent(true),
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	original := testModule.New(t)
+	m := New(original)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(urgentOutput("5%"))
+	out := tester.AssertOutput("passes through output")
+	assert.Equal(t, true, out[1]["urgent"])
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("no policies")
+
+	m.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Equal(t, bar.ButtonRight, original.AssertClicked("click passed through").Button)
+}
+
+func TestBlinkAndWiden(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	original := testModule.New(t)
+	m := New(original).Blink(time.Minute, time.Second).Widen(90*time.Second, 300)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("85%"))
+	tester.AssertOutput("not urgent")
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("not urgent")
+
+	original.Output(urgentOutput("5%"))
+	out := tester.AssertOutput("on urgent output")
+	assert.Equal(t, true, out[1]["urgent"], "not blinking yet")
+	assert.Nil(t, out[1]["min_width"], "not widened yet")
+
+	assert.Equal(t, scheduler.Now().Add(time.Minute), scheduler.NextTick())
+	scheduler.NextTick()
+	out = tester.AssertOutput("blink starts")
+	assert.Equal(t, true, out[1]["urgent"], "blink starts on")
+	scheduler.AdvanceBy(time.Second)
+	out = tester.AssertOutput("blink off")
+	assert.Equal(t, false, out[1]["urgent"], "blink off")
+	assert.Nil(t, out[0]["urgent"], "non-urgent segments are not changed")
+	scheduler.AdvanceBy(time.Second)
+	out = tester.AssertOutput("blink on")
+	assert.Equal(t, true, out[1]["urgent"], "blink on")
+
+	for i := 0; i < 28; i++ {
+		scheduler.AdvanceBy(time.Second)
+		out = tester.AssertOutput("blinking")
+	}
+	assert.Equal(t, true, out[1]["urgent"], "blink on after 30s")
+	assert.Equal(t, 300, out[1]["min_width"], "widened after 90s")
+	assert.Equal(t, bar.AlignCenter, out[1]["align"])
+	assert.Nil(t, out[0]["min_width"], "non-urgent segments are not changed")
+
+	original.Output(urgentOutput("4%"))
+	out = tester.AssertOutput("urgent output updated")
+	assert.Equal(t, "4%", out[1].Text())
+	assert.Equal(t, 300, out[1]["min_width"], "still urgent")
+
+	original.Output(outputs.Text("charging"))
+	out = tester.AssertOutput("no longer urgent")
+	assert.Nil(t, out[0]["min_width"])
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("stops when not urgent")
+
+	original.Output(urgentOutput("3%"))
+	out = tester.AssertOutput("urgent again")
+	assert.Nil(t, out[1]["min_width"], "escalation starts over")
+}
+
+func TestNotify(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	notes := make(chan notify.Notification, 10)
+	sendNotification = func(n *notify.Notifier, note notify.Notification) error {
+		notes <- note
+		return nil
+	}
+	original := testModule.New(t)
+	m := New(original).Notify(5*time.Minute, "Battery critical")
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(bar.Output{
+		bar.NewSegment("icon"),
+		bar.NewSegment("<b>5%</b> left").Markup(bar.MarkupPango).Urgent(true),
+	})
+	tester.AssertOutput("on urgent output")
+	scheduler.AdvanceBy(4 * time.Minute)
+	assert.Empty(t, notes, "not urgent for long enough")
+	scheduler.AdvanceBy(time.Minute)
+	select {
+	case note := <-notes:
+		assert.Equal(t, "Battery critical", note.Summary)
+		assert.Equal(t, "5% left", note.Body, "text of urgent segments")
+		assert.Equal(t, notify.Critical, note.Urgency)
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected a notification")
+	}
+	scheduler.AdvanceBy(time.Hour)
+	assert.Empty(t, notes, "only notified once")
+
+	original.Output(outputs.Text("charging"))
+	tester.AssertOutput("no longer urgent")
+	original.Output(urgentOutput("2%"))
+	tester.AssertOutput("urgent again")
+	scheduler.AdvanceBy(5 * time.Minute)
+	select {
+	case note := <-notes:
+		assert.Equal(t, "2%", note.Body, "notified again when urgent again")
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected a notification")
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package exchange provides an i3bar module that shows currency exchange rates.
+
+Rates are fetched using a Provider, with providers available for the European
+Central Bank's reference rates and exchangerate.host. Since reference rates
+only change once a day, rates are refreshed a few times a day, and cached in
+the user's cache directory so that restarting the bar does not fetch them
+again.
+*/
+package exchange
+
+import (
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/cache"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Provider gets exchange rates from a data source.
+type Provider interface {
+	// Rates returns the value of one unit of the base currency in each of
+	// the given currencies, keyed by currency code.
+	Rates(base string, currencies []string) (map[string]float64, error)
+}
+
+// Rate represents the exchange rate from the base currency to a currency.
+type Rate struct {
+	Currency string
+	// Rate is the value of one unit of the base currency in this currency.
+	Rate float64
+}
+
+// Info represents the exchange rates for all configured currencies.
+type Info struct {
+	Base  string
+	Rates []Rate
+	// Updated is the time at which the rates were fetched.
+	Updated time.Time
+}
+
+// Rate returns the exchange rate to the given currency, and false if the
+// currency is not available.
+func (i Info) Rate(currency string) (float64, bool) {
+	for _, r := range i.Rates {
+		if r.Currency == currency {
+			return r.Rate, true
+		}
+	}
+	return 0, false
+}
+
+// Module is the public interface for an exchange rate module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	provider   Provider
+	base       string
+	currencies []string
+	interval   time.Duration
+	outputFunc func(Info) bar.Output
+	cached     *rates
+}
+
+// New constructs an instance of the exchange rate module that shows the
+// rates from one currency to each of the other given currencies.
+func New(provider Provider, from string, to ...string) Module {
+	m := &module{
+		Base:     base.New(),
+		provider: provider,
+		base:     strings.ToUpper(from),
+		// Reference rates are published once per working day.
+		interval: 6 * time.Hour,
+	}
+	for _, c := range to {
+		m.currencies = append(m.currencies, strings.ToUpper(c))
+	}
+	// Default output template is each currency and its rate.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{range $i, $r := .Rates}}{{if $i}} {{end}}{{$r.Currency}} {{printf "%.4f" $r.Rate}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.interval = interval
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+// rates is the result of the last fetch for a base currency.
+type rates struct {
+	Fetched time.Time
+	Rates   map[string]float64
+}
+
+// cacheKey returns the key under which rates for a base currency are cached.
+func cacheKey(base string) string {
+	return "exchange-" + strings.ToLower(base)
+}
+
+// loadRates returns the cached rates for a base currency, or nil if there
+// are none.
+func loadRates(base string) *rates {
+	r := &rates{}
+	fetched, ok := cache.Load(cacheKey(base), &r.Rates)
+	if !ok {
+		return nil
+	}
+	r.Fetched = fetched
+	return r
+}
+
+// hasAll returns true if there are rates for all the given currencies.
+func (r *rates) hasAll(currencies []string) bool {
+	for _, cur := range currencies {
+		if _, ok := r.Rates[cur]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *module) update() {
+	m.Lock()
+	if m.cached == nil {
+		// Load the rates from the cache on the first update.
+		m.cached = loadRates(m.base)
+	}
+	cached := m.cached
+	interval := m.interval
+	m.Unlock()
+	now := scheduler.Now()
+	if cached != nil && cached.hasAll(m.currencies) && now.Before(cached.Fetched.Add(interval)) {
+		m.Schedule().At(cached.Fetched.Add(interval))
+	} else {
+		// Schedule the next refresh before fetching, so that errors
+		// are retried.
+		m.Schedule().After(interval)
+		fetched, err := m.provider.Rates(m.base, m.currencies)
+		if m.Error(err) {
+			return
+		}
+		cached = &rates{Fetched: now, Rates: fetched}
+		cache.Save(cacheKey(m.base), fetched)
+	}
+	info := Info{Base: m.base, Updated: cached.Fetched}
+	for _, cur := range m.currencies {
+		if rate, ok := cached.Rates[cur]; ok {
+			info.Rates = append(info.Rates, Rate{cur, rate})
+		}
+	}
+	m.Lock()
+	m.cached = cached
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exchange
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+const ecbXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2018-01-05">
+			<Cube currency="USD" rate="1.2"/>
+			<Cube currency="GBP" rate="0.9"/>
+			<Cube currency="JPY" rate="135.0"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func init() {
+	// Retrying failed requests would only slow down the error tests.
+	client.Retry(0, 0)
+}
+
+func TestECB(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ecbXML))
+	}))
+	defer srv.Close()
+	ecbURL = srv.URL
+
+	rates, err := ECB().Rates("EUR", []string{"USD", "JPY"})
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"USD": 1.2, "JPY": 135}, rates)
+
+	rates, err = ECB().Rates("USD", []string{"EUR", "GBP"})
+	assert.NoError(err)
+	assert.InDelta(1/1.2, rates["EUR"], 1e-9, "cross rate")
+	assert.InDelta(0.75, rates["GBP"], 1e-9, "cross rate")
+
+	_, err = ECB().Rates("XYZ", []string{"USD"})
+	assert.Error(err, "unknown base")
+	_, err = ECB().Rates("EUR", []string{"XYZ"})
+	assert.Error(err, "unknown currency")
+
+	srv.Close()
+	_, err = ECB().Rates("EUR", []string{"USD"})
+	assert.Error(err, "server down")
+}
+
+func TestExchangeRateHost(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/latest", r.URL.Path)
+		switch r.URL.Query().Get("base") {
+		case "USD":
+			assert.Contains(r.URL.Query().Get("symbols"), "EUR,GBP")
+			w.Write([]byte(`{"success": true, "base": "USD", "rates": {"EUR": 0.83, "GBP": 0.74}}`))
+		case "XYZ":
+			w.Write([]byte(`{"success": false}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	exchangeRateHostAPI = srv.URL
+
+	rates, err := ExchangeRateHost().Rates("USD", []string{"EUR", "GBP"})
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"EUR": 0.83, "GBP": 0.74}, rates)
+
+	_, err = ExchangeRateHost().Rates("USD", []string{"EUR", "GBP", "JPY"})
+	assert.Error(err, "missing currency")
+	_, err = ExchangeRateHost().Rates("XYZ", []string{"EUR"})
+	assert.Error(err, "unsuccessful response")
+	_, err = ExchangeRateHost().Rates("EUR", []string{"USD"})
+	assert.Error(err, "server error")
+}
+
+type testProvider struct {
+	sync.Mutex
+	rates map[string]float64
+	err   error
+	calls int
+}
+
+func (t *testProvider) Rates(base string, currencies []string) (map[string]float64, error) {
+	t.Lock()
+	defer t.Unlock()
+	t.calls++
+	return t.rates, t.err
+}
+
+func (t *testProvider) set(rates map[string]float64, err error) {
+	t.Lock()
+	defer t.Unlock()
+	t.rates = rates
+	t.err = err
+}
+
+func (t *testProvider) callCount() int {
+	t.Lock()
+	defer t.Unlock()
+	return t.calls
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	dir, err := ioutil.TempDir("", "exchange")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", dir)
+	defer func() {
+		os.Unsetenv("XDG_CACHE_HOME")
+		os.RemoveAll(dir)
+	}()
+	cacheFile := filepath.Join(dir, "barista", "exchange-usd")
+
+	p := &testProvider{rates: map[string]float64{"EUR": 0.83, "GBP": 0.74}}
+	e := New(p, "usd", "eur", "GBP")
+	tester := testModule.NewOutputTester(t, e)
+	out := tester.AssertOutput("on start")
+	assert.Equal("EUR 0.8300 GBP 0.7400", out[0].Text())
+	assert.Equal(1, p.callCount())
+
+	_, err = os.Stat(cacheFile)
+	assert.NoError(err, "rates are cached")
+
+	p.set(map[string]float64{"EUR": 0.8, "GBP": 0.7}, nil)
+	scheduler.AdvanceBy(6 * time.Hour)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("EUR 0.8000 GBP 0.7000", out[0].Text())
+	assert.Equal(2, p.callCount())
+
+	// A new module uses the cached rates until they are stale.
+	p2 := &testProvider{rates: map[string]float64{"EUR": 0.9, "GBP": 0.8}}
+	var info Info
+	e2 := New(p2, "USD", "EUR", "GBP").OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester2 := testModule.NewOutputTester(t, e2)
+	tester2.AssertOutput("on start")
+	assert.Equal(0, p2.callCount(), "cached rates are used")
+	rate, ok := info.Rate("EUR")
+	assert.True(ok)
+	assert.Equal(0.8, rate)
+	_, ok = info.Rate("JPY")
+	assert.False(ok)
+	assert.Equal(time.Time{}.Add(6*time.Hour), info.Updated)
+
+	scheduler.AdvanceBy(3 * time.Hour)
+	tester2.AssertNoOutput("while cache is fresh")
+	scheduler.AdvanceBy(3 * time.Hour)
+	tester2.AssertOutput("when cache expires")
+	assert.Equal(1, p2.callCount())
+	rate, _ = info.Rate("EUR")
+	assert.Equal(0.9, rate)
+	// The first module is also refreshed at this time.
+	tester.AssertOutput("on refresh")
+
+	// Currencies missing from the cache cause a refresh.
+	p3 := &testProvider{rates: map[string]float64{"JPY": 110}}
+	tester3 := testModule.NewOutputTester(t, New(p3, "USD", "JPY"))
+	out = tester3.AssertOutput("on start")
+	assert.Equal("JPY 110.0000", out[0].Text())
+	assert.Equal(1, p3.callCount())
+
+	p.set(nil, errors.New("offline"))
+	scheduler.AdvanceBy(6 * time.Hour)
+	tester.AssertError("on provider error")
+	tester2.AssertOutput("on refresh")
+	tester3.AssertOutput("on refresh")
+
+	ioutil.WriteFile(cacheFile, []byte("not json"), 0644)
+	p4 := &testProvider{rates: map[string]float64{"EUR": 1}}
+	tester4 := testModule.NewOutputTester(t, New(p4, "USD", "EUR"))
+	tester4.AssertOutput("on start with invalid cache")
+	assert.Equal(1, p4.callCount())
+}
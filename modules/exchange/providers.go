@@ -0,0 +1,112 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exchange
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/soumya92/barista/base/httpclient"
+)
+
+// API endpoints, overridden in tests.
+var (
+	ecbURL              = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	exchangeRateHostAPI = "https://api.exchangerate.host"
+)
+
+// client is shared by all providers, e.g. to reuse connections.
+var client = httpclient.New()
+
+type ecb struct{}
+
+// ECB returns a provider that uses the euro foreign exchange reference rates
+// published by the European Central Bank. Rates for other base currencies
+// are computed from the euro rates.
+func ECB() Provider {
+	return ecb{}
+}
+
+// ecbRates represents the ECB daily reference rates XML.
+type ecbRates struct {
+	Cube struct {
+		Cube struct {
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func (ecb) Rates(base string, currencies []string) (map[string]float64, error) {
+	body, err := client.Get(ecbURL)
+	if err != nil {
+		return nil, err
+	}
+	r := ecbRates{}
+	if err := xml.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+	euroRates := map[string]float64{"EUR": 1}
+	for _, rate := range r.Cube.Cube.Rates {
+		euroRates[rate.Currency] = rate.Rate
+	}
+	baseRate, ok := euroRates[base]
+	if !ok {
+		return nil, fmt.Errorf("no ECB rate for %s", base)
+	}
+	rates := map[string]float64{}
+	for _, c := range currencies {
+		rate, ok := euroRates[c]
+		if !ok {
+			return nil, fmt.Errorf("no ECB rate for %s", c)
+		}
+		rates[c] = rate / baseRate
+	}
+	return rates, nil
+}
+
+type exchangeRateHost struct{}
+
+// ExchangeRateHost returns a provider that gets rates from exchangerate.host.
+func ExchangeRateHost() Provider {
+	return exchangeRateHost{}
+}
+
+func (exchangeRateHost) Rates(base string, currencies []string) (map[string]float64, error) {
+	qp := url.Values{}
+	qp.Add("base", base)
+	qp.Add("symbols", strings.Join(currencies, ","))
+	var r struct {
+		Success bool
+		Rates   map[string]float64
+	}
+	err := client.GetJSON(exchangeRateHostAPI+"/latest?"+qp.Encode(), &r)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Success {
+		return nil, fmt.Errorf("failed to get rates for %s", base)
+	}
+	for _, c := range currencies {
+		if _, ok := r.Rates[c]; !ok {
+			return nil, fmt.Errorf("no rate for %s", c)
+		}
+	}
+	return r.Rates, nil
+}
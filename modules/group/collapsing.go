@@ -41,7 +41,8 @@ type Collapsable interface {
 
 	// Button returns a button with the given output for the
 	// collapsed and expanded states respectively that toggles
-	// the group when clicked.
+	// the group when clicked. The button's output also changes
+	// when the group is collapsed or expanded programmatically.
 	Button(collapsed, expanded bar.Output) Button
 }
 
@@ -55,6 +56,7 @@ func Collapsing() Collapsable {
 type collapsable struct {
 	sync.Mutex
 	modules   []*module
+	buttons   []func()
 	collapsed bool
 }
 
@@ -101,17 +103,23 @@ func (g *collapsable) Button(collapsed, expanded bar.Output) Button {
 	b.OnClick(func(e bar.Event) {
 		if e.Button == bar.ButtonLeft {
 			g.Toggle()
-			b.Output(outputFunc())
 		}
 	})
+	g.Lock()
+	g.buttons = append(g.buttons, func() { b.Output(outputFunc()) })
+	g.Unlock()
 	return b
 }
 
 func (g *collapsable) setCollapsed(collapsed bool) {
 	g.Lock()
-	defer g.Unlock()
 	g.collapsed = collapsed
 	for _, m := range g.modules {
 		m.SetVisible(!g.collapsed)
 	}
+	buttons := g.buttons
+	g.Unlock()
+	for _, update := range buttons {
+		update()
+	}
 }
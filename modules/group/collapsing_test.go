@@ -113,3 +113,34 @@ func TestCollapsingButton(t *testing.T) {
 
 	buttonTester.AssertNoOutput("no output without interaction")
 }
+
+func TestCollapsedBeforeStart(t *testing.T) {
+	group := Collapsing()
+	module := testModule.New(t)
+	wrapped := group.Add(module)
+	group.Collapse()
+
+	tester := testModule.NewOutputTester(t, wrapped)
+	module.Output(bar.Output{bar.NewSegment("hidden")})
+	tester.AssertNoOutput("hidden by default")
+
+	group.Expand()
+	out := tester.AssertOutput("on expand")
+	assert.Equal(t, bar.Output{bar.NewSegment("hidden")}, out)
+}
+
+func TestCollapsingButtonUpdates(t *testing.T) {
+	group := Collapsing()
+	col := bar.Output{bar.NewSegment("col")}
+	exp := bar.Output{bar.NewSegment("exp")}
+	buttonTester := testModule.NewOutputTester(t, group.Button(col, exp))
+	buttonTester.AssertOutput("initial output")
+
+	group.Collapse()
+	out := buttonTester.AssertOutput("when collapsed directly")
+	assert.Equal(t, col, out, "collapsed")
+
+	group.Toggle()
+	out = buttonTester.AssertOutput("when toggled directly")
+	assert.Equal(t, exp, out, "expanded")
+}
@@ -16,9 +16,11 @@ package group
 
 import (
 	"sync"
+	"time"
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
 )
 
 // Cyclic is a group that supports cyclic between its modules.
@@ -42,6 +44,12 @@ type Cyclic interface {
 	// Count returns the number of modules in this group
 	Count() int
 
+	// RotateEvery switches to the next module automatically at the
+	// given interval, so that several modules can share a single slot
+	// on the bar. Switching modules manually restarts the interval.
+	// An interval of 0 disables automatic rotation.
+	RotateEvery(time.Duration)
+
 	// Button returns a button with the given output that switches
 	// to the next module in the group when clicked.
 	Button(bar.Output) Button
@@ -49,15 +57,19 @@ type Cyclic interface {
 
 // Cycling returns a new cyclic group.
 func Cycling() Cyclic {
-	return &cyclic{}
+	g := &cyclic{}
+	g.scheduler = scheduler.Do(g.Next)
+	return g
 }
 
 // cyclic implements the Cyclic group. It stores a list
 // of modules and the index of the currently visible one.
 type cyclic struct {
 	sync.Mutex
-	modules []*module
-	current int
+	modules   []*module
+	current   int
+	scheduler scheduler.Scheduler
+	interval  time.Duration
 }
 
 // Add adds a module to the cyclic group. The returned module
@@ -102,6 +114,20 @@ func (g *cyclic) Show(index int) {
 		m.SetVisible(idx == index)
 	}
 	g.current = index
+	if g.interval > 0 {
+		g.scheduler.Every(g.interval)
+	}
+}
+
+func (g *cyclic) RotateEvery(interval time.Duration) {
+	g.Lock()
+	defer g.Unlock()
+	g.interval = interval
+	if interval > 0 {
+		g.scheduler.Every(interval)
+	} else {
+		g.scheduler.Stop()
+	}
 }
 
 func (g *cyclic) Count() int {
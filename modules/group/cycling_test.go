@@ -16,10 +16,12 @@ package group
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchrcom/testify/assert"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
 	testModule "github.com/soumya92/barista/testing/module"
 )
 
@@ -110,3 +112,44 @@ func TestCyclingButton(t *testing.T) {
 	button.Click(scrollUp)
 	assert.Equal(t, 3, group.Visible(), "wraps around to last at beginning")
 }
+
+func TestCyclingRotation(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	group := Cycling()
+
+	module1 := testModule.New(t)
+	tester1 := testModule.NewOutputTester(t, group.Add(module1))
+	module1.Output(bar.Output{bar.NewSegment("1")})
+	tester1.AssertOutput("first module starts visible")
+	module2 := testModule.New(t)
+	tester2 := testModule.NewOutputTester(t, group.Add(module2))
+	module2.Output(bar.Output{bar.NewSegment("2")})
+
+	scheduler.AdvanceBy(time.Minute)
+	tester2.AssertNoOutput("without rotation")
+	assert.Equal(t, 0, group.Visible())
+
+	group.RotateEvery(10 * time.Second)
+	scheduler.AdvanceBy(10 * time.Second)
+	tester1.AssertEmpty("on rotation")
+	tester2.AssertOutput("on rotation")
+	scheduler.AdvanceBy(10 * time.Second)
+	tester2.AssertEmpty("on rotation")
+	tester1.AssertOutput("wraps around on rotation")
+
+	scheduler.AdvanceBy(5 * time.Second)
+	group.Next()
+	tester1.AssertEmpty("on manual switch")
+	tester2.AssertOutput("on manual switch")
+	scheduler.AdvanceBy(5 * time.Second)
+	tester1.AssertNoOutput("manual switch restarts interval")
+	scheduler.AdvanceBy(5 * time.Second)
+	tester2.AssertEmpty("on rotation")
+	tester1.AssertOutput("on rotation")
+
+	group.RotateEvery(0)
+	scheduler.AdvanceBy(time.Minute)
+	tester1.AssertNoOutput("rotation stopped")
+	tester2.AssertNoOutput("rotation stopped")
+}
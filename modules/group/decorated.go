@@ -0,0 +1,131 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"sync"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+)
+
+// Decorated is a group with decorative segments around its modules, e.g. a
+// label, bracket glyphs, or powerline-style separators, so that related
+// modules are visually clustered. The decorations are only shown while at
+// least one module in the group has some output.
+type Decorated interface {
+	Group
+
+	// Leading returns a module that shows the leading segments, which
+	// should be added to the bar before the group's modules.
+	Leading() bar.Module
+
+	// Trailing returns a module that shows the trailing segments, which
+	// should be added to the bar after the group's modules.
+	Trailing() bar.Module
+}
+
+// Decorate returns a group that adds modules to the given group, with the
+// leading and trailing segments shown around them. Either can be nil.
+func Decorate(g Group, leading, trailing bar.Output) Decorated {
+	d := &decorated{
+		Group:          g,
+		leadingOutput:  leading,
+		trailingOutput: trailing,
+		leading:        base.New(),
+		trailing:       base.New(),
+	}
+	d.update()
+	return d
+}
+
+// decorated implements the Decorated group. It tracks which of the
+// group's modules have output, to show or hide the decorations.
+type decorated struct {
+	Group
+	sync.Mutex
+	members        []*decoratedModule
+	shown          bool
+	updated        bool
+	leadingOutput  bar.Output
+	trailingOutput bar.Output
+	leading        *base.Base
+	trailing       *base.Base
+}
+
+// decoratedModule wraps a module from the group, and records whether its
+// last output was empty.
+type decoratedModule struct {
+	WrappedModule
+	group     *decorated
+	hasOutput bool
+}
+
+func (d *decorated) Add(original bar.Module) WrappedModule {
+	m := &decoratedModule{WrappedModule: d.Group.Add(original), group: d}
+	d.Lock()
+	d.members = append(d.members, m)
+	d.Unlock()
+	return m
+}
+
+func (d *decorated) Leading() bar.Module {
+	return d.leading
+}
+
+func (d *decorated) Trailing() bar.Module {
+	return d.trailing
+}
+
+// update shows or hides the decorations when the group changes between
+// having some output and having none.
+func (d *decorated) update() {
+	d.Lock()
+	shown := false
+	for _, m := range d.members {
+		shown = shown || m.hasOutput
+	}
+	if d.updated && shown == d.shown {
+		d.Unlock()
+		return
+	}
+	d.updated = true
+	d.shown = shown
+	d.Unlock()
+	if shown {
+		d.leading.Output(d.leadingOutput)
+		d.trailing.Output(d.trailingOutput)
+	} else {
+		d.leading.Clear()
+		d.trailing.Clear()
+	}
+}
+
+// Stream records whether each output is empty, and updates the
+// decorations after passing it through.
+func (m *decoratedModule) Stream() <-chan bar.Output {
+	input := m.WrappedModule.Stream()
+	output := make(chan bar.Output, 10)
+	go func() {
+		for out := range input {
+			m.group.Lock()
+			m.hasOutput = len(out) > 0
+			m.group.Unlock()
+			output <- out
+			m.group.update()
+		}
+	}()
+	return output
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestDecorated(t *testing.T) {
+	collapsing := Collapsing()
+	group := Decorate(collapsing, outputs.Text("["), outputs.Text("]"))
+	leading := testModule.NewOutputTester(t, group.Leading())
+	trailing := testModule.NewOutputTester(t, group.Trailing())
+	leading.AssertEmpty("no modules with output")
+	trailing.AssertEmpty("no modules with output")
+
+	module1 := testModule.New(t)
+	wrapped1 := group.Add(module1)
+	tester1 := testModule.NewOutputTester(t, wrapped1)
+	module1.AssertStarted("when wrapping module is started")
+	module2 := testModule.New(t)
+	tester2 := testModule.NewOutputTester(t, group.Add(module2))
+
+	module1.Output(outputs.Text("1"))
+	tester1.AssertOutput("passes thru")
+	out := leading.AssertOutput("when a module has output")
+	assert.Equal(t, "[", out[0].Text())
+	out = trailing.AssertOutput("when a module has output")
+	assert.Equal(t, "]", out[0].Text())
+
+	module2.Output(outputs.Text("2"))
+	tester2.AssertOutput("passes thru")
+	leading.AssertNoOutput("already shown")
+
+	module1.Output(outputs.Empty())
+	tester1.AssertEmpty("passes thru")
+	leading.AssertNoOutput("other module still has output")
+
+	collapsing.Collapse()
+	tester2.AssertEmpty("on collapse")
+	tester1.AssertEmpty("on collapse")
+	leading.AssertEmpty("when group is collapsed")
+	trailing.AssertEmpty("when group is collapsed")
+
+	collapsing.Expand()
+	tester2.AssertOutput("on expand")
+	tester1.AssertEmpty("on expand")
+	leading.AssertOutput("when group is expanded")
+	trailing.AssertOutput("when group is expanded")
+
+	evt := bar.Event{X: 1}
+	wrapped1.Click(evt)
+	module1.AssertClicked("click passed through")
+}
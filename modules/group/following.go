@@ -0,0 +1,109 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+// Follower is a group that shows one module at a time, switching to
+// whichever module updated most recently. This works well for modules that
+// only update on changes, e.g. showing the volume for a few seconds after
+// it changes, and the time otherwise.
+type Follower interface {
+	Group
+
+	// Visible returns the index of the currently visible module.
+	Visible() int
+
+	// RevertAfter switches back to the first module if the visible module
+	// does not update for the given duration. The first module should be
+	// one that updates regularly, e.g. a clock. A duration of 0 (the
+	// default) disables this, and the most recently updated module stays
+	// visible until another module updates.
+	RevertAfter(time.Duration) Follower
+}
+
+// Following returns a new following group.
+func Following() Follower {
+	g := &follower{}
+	g.scheduler = scheduler.Do(g.revert)
+	return g
+}
+
+// follower implements the Follower group. It stores a list of modules
+// and the index of the currently visible one.
+type follower struct {
+	sync.Mutex
+	modules   []*module
+	current   int
+	timeout   time.Duration
+	scheduler scheduler.Scheduler
+}
+
+// Add adds a module to the following group. The returned module will
+// only output anything while it's the most recently updated module.
+func (g *follower) Add(original bar.Module) WrappedModule {
+	g.Lock()
+	defer g.Unlock()
+	index := len(g.modules)
+	m := &module{
+		Module:  original,
+		visible: g.current == index,
+	}
+	m.onOutput = func() { g.show(index) }
+	g.modules = append(g.modules, m)
+	return m
+}
+
+func (g *follower) Visible() int {
+	g.Lock()
+	defer g.Unlock()
+	return g.current
+}
+
+func (g *follower) RevertAfter(timeout time.Duration) Follower {
+	g.Lock()
+	defer g.Unlock()
+	g.timeout = timeout
+	if timeout == 0 {
+		g.scheduler.Stop()
+	}
+	return g
+}
+
+// show makes the module at the given index visible, and restarts the
+// timeout to revert to the first module.
+func (g *follower) show(index int) {
+	g.Lock()
+	defer g.Unlock()
+	for idx, m := range g.modules {
+		m.SetVisible(idx == index)
+	}
+	g.current = index
+	if g.timeout > 0 && index > 0 {
+		g.scheduler.After(g.timeout)
+	} else {
+		g.scheduler.Stop()
+	}
+}
+
+func (g *follower) revert() {
+	g.show(0)
+}
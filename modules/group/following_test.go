@@ -0,0 +1,87 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestFollowing(t *testing.T) {
+	scheduler.TestMode(true)
+	group := Following()
+	assert.Equal(t, 0, group.Visible(), "first module visible at start")
+
+	clock := testModule.New(t)
+	clockTester := testModule.NewOutputTester(t, group.Add(clock))
+	volume := testModule.New(t)
+	volumeTester := testModule.NewOutputTester(t, group.Add(volume))
+
+	clock.Output(bar.Output{bar.NewSegment("10:00")})
+	clockTester.AssertOutput("first module visible at start")
+
+	volume.Output(bar.Output{bar.NewSegment("50%")})
+	clockTester.AssertEmpty("on update of another module")
+	out := volumeTester.AssertOutput("on update")
+	assert.Equal(t, "50%", out[0].Text())
+	assert.Equal(t, 1, group.Visible())
+
+	volume.Output(bar.Output{bar.NewSegment("60%")})
+	out = volumeTester.AssertOutput("on update while visible")
+	assert.Equal(t, "60%", out[0].Text())
+	clockTester.AssertNoOutput("while hidden")
+
+	clock.Output(bar.Output{bar.NewSegment("10:01")})
+	volumeTester.AssertEmpty("on update of another module")
+	out = clockTester.AssertOutput("on update")
+	assert.Equal(t, "10:01", out[0].Text())
+
+	scheduler.AdvanceBy(time.Hour)
+	clockTester.AssertNoOutput("no revert without timeout")
+}
+
+func TestFollowingRevert(t *testing.T) {
+	scheduler.TestMode(true)
+	group := Following().RevertAfter(3 * time.Second)
+
+	clock := testModule.New(t)
+	clockTester := testModule.NewOutputTester(t, group.Add(clock))
+	volume := testModule.New(t)
+	volumeTester := testModule.NewOutputTester(t, group.Add(volume))
+	clock.Output(bar.Output{bar.NewSegment("10:00")})
+	clockTester.AssertOutput("first module visible at start")
+
+	volume.Output(bar.Output{bar.NewSegment("50%")})
+	clockTester.AssertEmpty("on update of another module")
+	volumeTester.AssertOutput("on update")
+
+	scheduler.AdvanceBy(2 * time.Second)
+	volume.Output(bar.Output{bar.NewSegment("60%")})
+	volumeTester.AssertOutput("on update")
+	scheduler.AdvanceBy(2 * time.Second)
+	clockTester.AssertNoOutput("update restarts timeout")
+
+	scheduler.AdvanceBy(time.Second)
+	volumeTester.AssertEmpty("on timeout")
+	out := clockTester.AssertOutput("reverts to first module")
+	assert.Equal(t, "10:00", out[0].Text())
+	assert.Equal(t, 0, group.Visible())
+}
@@ -22,12 +22,70 @@ which is toggle for collapsing, and next for cycling. Or add a static text
 module and use the click handler to get more fined grain control over the
 group.
 
+To keep rarely used modules hidden until the button is clicked, collapse
+the group before running the bar:
+
  g := group.Collapsing()
+ g.Collapse()
  bar.Run(
    g.Add(localtime.New(...)),
    g.Add(shell.Every(...)),
    g.Button(outputs.Text("+"), outputs.Text("-")),
  )
+
+Cycling groups can also rotate through their modules automatically, so that
+several low-priority modules can share a single slot on the bar:
+
+ g := group.Cycling()
+ g.RotateEvery(10 * time.Second)
+
+Switching groups also show one module at a time, but are controlled by a
+switcher button that shows the current selection, which can be remembered
+across restarts:
+
+ g := group.Switching().Remember("sensors")
+ bar.Run(
+   g.Button(func(i int) bar.Output { return outputs.Textf("[%d]", i+1) }),
+   g.Add(cputemp.DefaultZone()),
+   g.Add(diskspace.New("/")),
+ )
+
+Paged groups split many modules into pages with a fixed number of slots,
+and scrolling on the pager button flips between pages:
+
+ g := group.Paged(4)
+ bar.Run(
+   g.Button(func(p, n int) bar.Output { return outputs.Textf("%d/%d", p+1, n) }),
+   g.Add(cputemp.DefaultZone()),
+   g.Add(diskspace.New("/")),
+   ...
+ )
+
+Following groups show whichever module updated most recently, optionally
+reverting to the first module after a timeout:
+
+ g := group.Following().RevertAfter(3 * time.Second)
+ bar.Run(g.Add(localtime.New(...)), g.Add(volume.DefaultMixer()))
+
+Any group can be decorated with leading and trailing segments, which are
+shown around its modules while any of them have output:
+
+ g := group.Decorate(group.Collapsing(), outputs.Text("["), outputs.Text("]"))
+ bar.Run(g.Leading(), g.Add(cputemp.DefaultZone()), g.Trailing())
+
+Any group can also control the separators and spacing after its modules,
+overriding the bar's layout, e.g. to pack related modules closely:
+
+ g := group.WithLayout(group.Collapsing(), bar.Layout{}.Separators(false))
+
+Modal groups show different modules depending on the i3 or sway binding
+mode:
+
+ g := group.Modes(i3ipc.I3())
+ bar.Run(
+   g.Mode("default").Add(localtime.New(...)),
+   g.Mode("resize").Add(title.I3()),
+ )
 */
 package group
 
@@ -59,6 +117,11 @@ type module struct {
 	channel    chan bar.Output
 	lastOutput bar.Output
 	visible    bool
+	// onOutput, if set, is called on each output from the wrapped module,
+	// before it is sent to the bar.
+	onOutput func()
+	// onStream, if set, is called when the module is started.
+	onStream func()
 }
 
 // WrappedModule implements bar.Module, Clickable, and Pausable.
@@ -71,7 +134,12 @@ type WrappedModule interface {
 
 // Stream sets up the output pipeline to filter outputs when hidden.
 func (m *module) Stream() <-chan bar.Output {
+	if m.onStream != nil {
+		m.onStream()
+	}
+	m.Lock()
 	m.channel = make(chan bar.Output, 10)
+	m.Unlock()
 	go m.pipeWhenVisible(m.Module.Stream(), m.channel)
 	return m.channel
 }
@@ -105,6 +173,10 @@ func (m *module) SetVisible(visible bool) {
 		return
 	}
 	m.visible = visible
+	if m.channel == nil {
+		// Not streaming yet, so there is no output to update.
+		return
+	}
 	if visible {
 		m.channel <- m.lastOutput
 	} else {
@@ -118,6 +190,9 @@ func (m *module) pipeWhenVisible(input <-chan bar.Output, output chan<- bar.Outp
 		m.lastOutput = out
 		visible := m.visible
 		m.Unlock()
+		if m.onOutput != nil {
+			m.onOutput()
+		}
 		if visible {
 			output <- out
 		}
@@ -0,0 +1,56 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import "github.com/soumya92/barista/bar"
+
+// WithLayout returns a group that adds modules to the given group, with the
+// separators and spacing after each module controlled by the layout. Group
+// layouts take precedence over the bar's layout, so related modules can be
+// packed closely together, e.g.
+//
+//	g := group.WithLayout(group.Collapsing(), bar.Layout{}.Separators(false).Spacing(4))
+func WithLayout(g Group, layout bar.Layout) Group {
+	return &laidOut{Group: g, layout: layout}
+}
+
+// laidOut implements a group that applies a layout to its modules.
+type laidOut struct {
+	Group
+	layout bar.Layout
+}
+
+// laidOutModule wraps a module from the group, and applies the group's
+// layout to each output.
+type laidOutModule struct {
+	WrappedModule
+	layout bar.Layout
+}
+
+func (g *laidOut) Add(original bar.Module) WrappedModule {
+	return &laidOutModule{WrappedModule: g.Group.Add(original), layout: g.layout}
+}
+
+// Stream applies the layout to each output of the wrapped module.
+func (m *laidOutModule) Stream() <-chan bar.Output {
+	input := m.WrappedModule.Stream()
+	output := make(chan bar.Output, 10)
+	go func() {
+		for out := range input {
+			output <- m.layout.Apply(out)
+		}
+	}()
+	return output
+}
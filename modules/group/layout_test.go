@@ -0,0 +1,45 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestLayout(t *testing.T) {
+	group := WithLayout(Collapsing(), bar.Layout{}.Separators(false).Spacing(2))
+	module := testModule.New(t)
+	wrapped := group.Add(module)
+	tester := testModule.NewOutputTester(t, wrapped)
+
+	module.Output(bar.Output{bar.NewSegment("a"), bar.NewSegment("b")})
+	out := tester.AssertOutput("on output")
+	assert.Nil(t, out[0]["separator"], "inner segments are not affected")
+	assert.Equal(t, false, out[1]["separator"], "layout applied")
+	assert.Equal(t, 2, out[1]["separator_block_width"], "layout applied")
+
+	module.Output(bar.Output{bar.NewSegment("c").Separator(true)})
+	out = tester.AssertOutput("on output")
+	assert.Equal(t, true, out[0]["separator"], "module's separator is kept")
+
+	evt := bar.Event{X: 1}
+	wrapped.Click(evt)
+	assert.Equal(t, evt, module.AssertClicked("click passed through"))
+}
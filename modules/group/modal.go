@@ -0,0 +1,146 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/i3ipc"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("modules/group")
+
+// Modal is a group that shows a different set of modules for each binding
+// mode of i3 or sway, e.g. window information in a "resize" mode, or media
+// controls in a "media" mode. Modules are added to a mode using Mode(name),
+// and are hidden whenever a different binding mode is active.
+type Modal interface {
+	// Mode returns a group for the given binding mode. Modules added to it
+	// are only visible while the binding mode is active. The normal mode
+	// is named "default".
+	Mode(name string) Group
+
+	// Current returns the name of the active binding mode.
+	Current() string
+}
+
+// Modes returns a new modal group that follows the binding mode of the
+// window manager at the other end of the IPC client, e.g. i3ipc.I3() or
+// i3ipc.Sway(). The binding mode is tracked once any module in the group
+// is started.
+func Modes(c i3ipc.Client) Modal {
+	return &modal{ipc: c, current: "default"}
+}
+
+// modal implements the Modal group. It stores the modules for each
+// binding mode, and the name of the active mode.
+type modal struct {
+	sync.Mutex
+	ipc     i3ipc.Client
+	modules map[string][]*module
+	current string
+	once    sync.Once
+}
+
+// modeGroup is the group of modules for a single binding mode.
+type modeGroup struct {
+	*modal
+	name string
+}
+
+func (g *modal) Mode(name string) Group {
+	return modeGroup{g, name}
+}
+
+// Add adds a module to the binding mode's group. The returned module will
+// not output anything unless the binding mode is active.
+func (g modeGroup) Add(original bar.Module) WrappedModule {
+	g.Lock()
+	defer g.Unlock()
+	if g.modules == nil {
+		g.modules = map[string][]*module{}
+	}
+	m := &module{
+		Module:   original,
+		visible:  g.current == g.name,
+		onStream: g.start,
+	}
+	g.modules[g.name] = append(g.modules[g.name], m)
+	return m
+}
+
+func (g *modal) Current() string {
+	g.Lock()
+	defer g.Unlock()
+	return g.current
+}
+
+// start gets the current binding mode and subscribes to mode changes,
+// the first time it is called.
+func (g *modal) start() {
+	g.once.Do(func() {
+		g.fetchMode()
+		go g.subscribe()
+	})
+}
+
+// fetchMode gets the current binding mode. Older versions of i3 do not
+// support querying the binding mode, so errors are ignored, and the mode
+// will be updated by events.
+func (g *modal) fetchMode() {
+	var state struct{ Name string }
+	if err := g.ipc.Call(i3ipc.GetBindingState, "", &state); err == nil && state.Name != "" {
+		g.setMode(state.Name)
+	}
+}
+
+// retryDelay is how long to wait before subscribing to mode changes again,
+// e.g. after i3 is restarted.
+var retryDelay = 10 * time.Second
+
+func (g *modal) subscribe() {
+	for {
+		err := g.ipc.Subscribe([]string{"mode"}, g.handleEvent)
+		log.Error("mode events failed", "error", err)
+		time.Sleep(retryDelay)
+		// The mode may have changed while the subscription was lost.
+		g.fetchMode()
+	}
+}
+
+func (g *modal) handleEvent(msgType uint32, payload []byte) {
+	if msgType != i3ipc.ModeEvent {
+		return
+	}
+	var event struct{ Change string }
+	if json.Unmarshal(payload, &event) == nil {
+		g.setMode(event.Change)
+	}
+}
+
+func (g *modal) setMode(mode string) {
+	g.Lock()
+	defer g.Unlock()
+	g.current = mode
+	for name, modules := range g.modules {
+		for _, m := range modules {
+			m.SetVisible(name == mode)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/i3ipc"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+// fakeIPC starts a fake i3 IPC server that reports the given binding mode,
+// and sends mode events from the channel to subscribers. The "disconnect"
+// event closes the subscription instead.
+func fakeIPC(t *testing.T, mode string, events <-chan string) (i3ipc.Client, func()) {
+	dir, err := ioutil.TempDir("", "i3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "ipc.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		for {
+			msgType, _, err := i3ipc.ReadMessage(conn)
+			if err != nil {
+				return
+			}
+			switch msgType {
+			case i3ipc.Subscribe:
+				i3ipc.WriteMessage(conn, i3ipc.Subscribe, `{"success": true}`)
+				for e := range events {
+					if e == "disconnect" {
+						return
+					}
+					i3ipc.WriteMessage(conn, i3ipc.ModeEvent, `{"change": "`+e+`"}`)
+				}
+				return
+			case i3ipc.GetBindingState:
+				i3ipc.WriteMessage(conn, i3ipc.GetBindingState, `{"name": "`+mode+`"}`)
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return i3ipc.Socket(sock), func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func init() {
+	retryDelay = 10 * time.Millisecond
+}
+
+func TestModal(t *testing.T) {
+	events := make(chan string)
+	defer close(events)
+	ipc, cleanup := fakeIPC(t, "resize", events)
+	defer cleanup()
+
+	group := Modes(ipc)
+	assert.Equal(t, "default", group.Current(), "default mode before starting")
+
+	clock := testModule.New(t)
+	clockTester := testModule.NewOutputTester(t, group.Mode("default").Add(clock))
+	assert.Equal(t, "resize", group.Current(), "initial mode fetched on start")
+
+	window := testModule.New(t)
+	windowTester := testModule.NewOutputTester(t, group.Mode("resize").Add(window))
+	media := testModule.New(t)
+	mediaTester := testModule.NewOutputTester(t, group.Mode("media").Add(media))
+
+	clock.Output(bar.Output{bar.NewSegment("10:00")})
+	clockTester.AssertNoOutput("hidden in another mode")
+	window.Output(bar.Output{bar.NewSegment("800x600")})
+	windowTester.AssertOutput("visible in active mode")
+	media.Output(bar.Output{bar.NewSegment("playing")})
+	mediaTester.AssertNoOutput("hidden in another mode")
+
+	events <- "media"
+	windowTester.AssertEmpty("on mode change")
+	out := mediaTester.AssertOutput("on mode change")
+	assert.Equal(t, "playing", out[0].Text())
+	clockTester.AssertNoOutput("hidden in another mode")
+	assert.Equal(t, "media", group.Current())
+
+	events <- "default"
+	mediaTester.AssertEmpty("on mode change")
+	out = clockTester.AssertOutput("on mode change")
+	assert.Equal(t, "10:00", out[0].Text())
+	windowTester.AssertNoOutput("already hidden")
+}
+
+func TestModalResubscribe(t *testing.T) {
+	events := make(chan string)
+	defer close(events)
+	ipc, cleanup := fakeIPC(t, "resize", events)
+	defer cleanup()
+
+	group := Modes(ipc)
+	testModule.NewOutputTester(t, group.Mode("media").Add(testModule.New(t)))
+	assert.Equal(t, "resize", group.Current())
+
+	events <- "media"
+	waitForMode(t, group, "media", "on mode change")
+
+	events <- "disconnect"
+	waitForMode(t, group, "resize", "mode fetched again after resubscribing")
+
+	events <- "media"
+	waitForMode(t, group, "media", "events received after resubscribing")
+}
+
+func waitForMode(t *testing.T, group Modal, mode, message string) {
+	for i := 0; i < 100 && group.Current() != mode; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, mode, group.Current(), message)
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"sync"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+)
+
+// Pager is a group that splits its modules into pages of a fixed number
+// of slots, and shows one page at a time. This keeps the bar usable on
+// narrow screens when there are many modules.
+type Pager interface {
+	Group
+
+	// Page returns the index of the currently visible page.
+	Page() int
+
+	// PageCount returns the number of pages in this group.
+	PageCount() int
+
+	// Previous switches to the previous page.
+	Previous()
+
+	// Next switches to the next page.
+	Next()
+
+	// Show switches to the page at the given index.
+	Show(int)
+
+	// Button returns a pager button that displays the output of the given
+	// function for the current page and the number of pages. Scrolling
+	// down or left clicking switches to the next page, and scrolling up or
+	// right clicking to the previous page.
+	Button(func(page, count int) bar.Output) Button
+}
+
+// Paged returns a new paged group, with the given number of modules on
+// each page.
+func Paged(size int) Pager {
+	if size < 1 {
+		size = 1
+	}
+	return &pager{size: size}
+}
+
+// pager implements the Pager group. It stores a list of modules
+// and the index of the currently visible page.
+type pager struct {
+	sync.Mutex
+	modules []*module
+	size    int
+	current int
+	buttons []func()
+}
+
+// Add adds a module to the paged group. The returned module
+// will not output anything unless its page is visible.
+func (g *pager) Add(original bar.Module) WrappedModule {
+	g.Lock()
+	index := len(g.modules)
+	m := &module{
+		Module:  original,
+		visible: index/g.size == g.current,
+	}
+	g.modules = append(g.modules, m)
+	buttons := g.buttons
+	g.Unlock()
+	if index > 0 && index%g.size == 0 {
+		// Added a new page, so the page count has changed.
+		for _, update := range buttons {
+			update()
+		}
+	}
+	return m
+}
+
+func (g *pager) Page() int {
+	g.Lock()
+	defer g.Unlock()
+	return g.current
+}
+
+func (g *pager) PageCount() int {
+	g.Lock()
+	defer g.Unlock()
+	return g.pageCount()
+}
+
+// pageCount returns the number of pages, which is at least one even if
+// the group has no modules. It must be called with the lock held.
+func (g *pager) pageCount() int {
+	count := (len(g.modules) + g.size - 1) / g.size
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+func (g *pager) Previous() {
+	g.Show(g.Page() - 1)
+}
+
+func (g *pager) Next() {
+	g.Show(g.Page() + 1)
+}
+
+func (g *pager) Show(page int) {
+	g.Lock()
+	count := g.pageCount()
+	// Handle wrap around on either side.
+	page = (page%count + count) % count
+	// Hide modules before showing others, to avoid briefly showing
+	// two pages at once.
+	for idx, m := range g.modules {
+		if idx/g.size != page {
+			m.SetVisible(false)
+		}
+	}
+	for idx, m := range g.modules {
+		if idx/g.size == page {
+			m.SetVisible(true)
+		}
+	}
+	g.current = page
+	buttons := g.buttons
+	g.Unlock()
+	for _, update := range buttons {
+		update()
+	}
+}
+
+func (g *pager) Button(outputFunc func(page, count int) bar.Output) Button {
+	b := base.New()
+	update := func() {
+		g.Lock()
+		page, count := g.current, g.pageCount()
+		g.Unlock()
+		b.Output(outputFunc(page, count))
+	}
+	update()
+	b.OnClick(func(e bar.Event) {
+		switch e.Button {
+		case bar.ButtonLeft, bar.ScrollDown, bar.ScrollRight, bar.ButtonForward:
+			g.Next()
+		case bar.ButtonRight, bar.ScrollUp, bar.ScrollLeft, bar.ButtonBack:
+			g.Previous()
+		}
+	})
+	g.Lock()
+	g.buttons = append(g.buttons, update)
+	g.Unlock()
+	return b
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func pagerOutput(page, count int) bar.Output {
+	return bar.Output{bar.NewSegment(fmt.Sprintf("%d/%d", page+1, count))}
+}
+
+func TestPaged(t *testing.T) {
+	group := Paged(2)
+	pager := group.Button(pagerOutput)
+	button := testModule.NewOutputTester(t, pager)
+	out := button.AssertOutput("initial output")
+	assert.Equal(t, "1/1", out[0].Text())
+	assert.Equal(t, 1, group.PageCount(), "empty group has one page")
+
+	var modules []*testModule.TestModule
+	var testers []*testModule.OutputTester
+	for i := 0; i < 5; i++ {
+		m := testModule.New(t)
+		modules = append(modules, m)
+		testers = append(testers, testModule.NewOutputTester(t, group.Add(m)))
+		m.Output(bar.Output{bar.NewSegment(fmt.Sprintf("%d", i))})
+	}
+	assert.Equal(t, 3, group.PageCount())
+	out = button.AssertOutput("when adding pages")
+	assert.Equal(t, "1/2", out[0].Text())
+	out = button.AssertOutput("when adding pages")
+	assert.Equal(t, "1/3", out[0].Text())
+	button.AssertNoOutput("when adding to an existing page")
+
+	testers[0].AssertOutput("first page is visible")
+	testers[1].AssertOutput("first page is visible")
+	for i := 2; i < 5; i++ {
+		testers[i].AssertNoOutput("other pages are hidden")
+	}
+
+	pager.Click(bar.Event{Button: bar.ScrollDown})
+	assert.Equal(t, 1, group.Page(), "scrolling switches to the next page")
+	out = button.AssertOutput("on page switch")
+	assert.Equal(t, "2/3", out[0].Text())
+	testers[0].AssertEmpty("hidden on page switch")
+	testers[1].AssertEmpty("hidden on page switch")
+	testers[2].AssertOutput("shown on page switch")
+	testers[3].AssertOutput("shown on page switch")
+	testers[4].AssertNoOutput("other page")
+
+	group.Next()
+	assert.Equal(t, 2, group.Page())
+	button.AssertOutput("on page switch")
+	testers[4].AssertOutput("partial last page")
+	modules[4].Output(bar.Output{bar.NewSegment("new")})
+	out = testers[4].AssertOutput("updates on visible page")
+	assert.Equal(t, "new", out[0].Text())
+	modules[0].Output(bar.Output{bar.NewSegment("new")})
+	testers[0].AssertNoOutput("updates on hidden page")
+
+	group.Next()
+	assert.Equal(t, 0, group.Page(), "wraps around")
+	button.AssertOutput("on page switch")
+	pager.Click(bar.Event{Button: bar.ScrollUp})
+	assert.Equal(t, 2, group.Page(), "scrolling up wraps around")
+	out = button.AssertOutput("on page switch")
+	assert.Equal(t, "3/3", out[0].Text())
+
+	group.Show(1)
+	assert.Equal(t, 1, group.Page())
+	group.Show(-1)
+	assert.Equal(t, 2, group.Page(), "negative index wraps around")
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"sync"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/state"
+)
+
+// Switchable is a group that shows one module at a time, chosen using a
+// switcher button that shows which module is selected. Unlike cyclic
+// groups, the selection can be remembered across restarts of the bar.
+type Switchable interface {
+	Group
+
+	// Visible returns the index of the currently selected module.
+	Visible() int
+
+	// Previous selects the previous module.
+	Previous()
+
+	// Next selects the next module.
+	Next()
+
+	// Show selects the module at the given index.
+	Show(int)
+
+	// Count returns the number of modules in this group.
+	Count() int
+
+	// Remember saves the selection under the given name, and restores
+	// the previously saved selection, if any. Call this before adding
+	// modules, to avoid briefly showing the first module on startup.
+	Remember(name string) Switchable
+
+	// Button returns a switcher button that displays the output of the
+	// given function for the selected index. Left click or scroll down
+	// selects the next module, and right click or scroll up the previous.
+	Button(func(int) bar.Output) Button
+}
+
+// Switching returns a new switchable group.
+func Switching() Switchable {
+	return &switchable{cyclic: Cycling().(*cyclic)}
+}
+
+// switchable implements the Switchable group, using a cyclic group to
+// track the modules and the selection.
+type switchable struct {
+	cyclic  *cyclic
+	mutex   sync.Mutex
+	name    string
+	buttons []func()
+}
+
+// stateKey returns the key that stores the selection for a named group.
+func stateKey(name string) string {
+	return "group-" + name
+}
+
+func (g *switchable) Add(original bar.Module) WrappedModule {
+	return g.cyclic.Add(original)
+}
+
+func (g *switchable) Visible() int {
+	return g.cyclic.Visible()
+}
+
+func (g *switchable) Previous() {
+	g.Show(g.Visible() - 1)
+}
+
+func (g *switchable) Next() {
+	g.Show(g.Visible() + 1)
+}
+
+func (g *switchable) Show(index int) {
+	g.cyclic.Show(index)
+	index = g.cyclic.Visible()
+	g.mutex.Lock()
+	name := g.name
+	buttons := g.buttons
+	g.mutex.Unlock()
+	if name != "" {
+		// Errors are ignored, since the selection is only a convenience.
+		state.Save(stateKey(name), index)
+	}
+	for _, update := range buttons {
+		update()
+	}
+}
+
+func (g *switchable) Count() int {
+	return g.cyclic.Count()
+}
+
+func (g *switchable) Remember(name string) Switchable {
+	g.mutex.Lock()
+	g.name = name
+	g.mutex.Unlock()
+	var index int
+	if !state.Load(stateKey(name), &index) || index < 0 {
+		return g
+	}
+	if g.Count() == 0 {
+		// Modules added later will use the saved selection.
+		g.cyclic.Lock()
+		g.cyclic.current = index
+		g.cyclic.Unlock()
+		return g
+	}
+	if index < g.Count() {
+		g.Show(index)
+	}
+	return g
+}
+
+func (g *switchable) Button(outputFunc func(int) bar.Output) Button {
+	b := base.New()
+	b.Output(outputFunc(g.Visible()))
+	b.OnClick(func(e bar.Event) {
+		switch e.Button {
+		case bar.ButtonLeft, bar.ScrollDown, bar.ScrollRight, bar.ButtonForward:
+			g.Next()
+		case bar.ButtonRight, bar.ScrollUp, bar.ScrollLeft, bar.ButtonBack:
+			g.Previous()
+		}
+	})
+	g.mutex.Lock()
+	g.buttons = append(g.buttons, func() { b.Output(outputFunc(g.Visible())) })
+	g.mutex.Unlock()
+	return b
+}
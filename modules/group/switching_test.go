@@ -0,0 +1,112 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func switcherOutput(index int) bar.Output {
+	return bar.Output{bar.NewSegment(string('A' + rune(index)))}
+}
+
+func TestSwitching(t *testing.T) {
+	group := Switching()
+	switcher := group.Button(switcherOutput)
+	button := testModule.NewOutputTester(t, switcher)
+	out := button.AssertOutput("initial output")
+	assert.Equal(t, "A", out[0].Text())
+
+	module1 := testModule.New(t)
+	tester1 := testModule.NewOutputTester(t, group.Add(module1))
+	module1.Output(bar.Output{bar.NewSegment("1")})
+	tester1.AssertOutput("first module starts visible")
+	module2 := testModule.New(t)
+	tester2 := testModule.NewOutputTester(t, group.Add(module2))
+	module2.Output(bar.Output{bar.NewSegment("2")})
+	tester2.AssertNoOutput("other modules start hidden")
+	assert.Equal(t, 2, group.Count())
+
+	switcher.Click(bar.Event{Button: bar.ScrollDown})
+	assert.Equal(t, 1, group.Visible(), "scrolling selects the next module")
+	tester1.AssertEmpty("on switch")
+	tester2.AssertOutput("on switch")
+	out = button.AssertOutput("on switch")
+	assert.Equal(t, "B", out[0].Text())
+
+	group.Next()
+	assert.Equal(t, 0, group.Visible(), "wraps around")
+	button.AssertOutput("on switch")
+	group.Previous()
+	assert.Equal(t, 1, group.Visible(), "wraps around")
+	button.AssertOutput("on switch")
+
+	switcher.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Equal(t, 0, group.Visible(), "right click selects the previous module")
+	out = button.AssertOutput("on switch")
+	assert.Equal(t, "A", out[0].Text())
+}
+
+func TestSwitchingRemember(t *testing.T) {
+	dir, err := ioutil.TempDir("", "group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_STATE_HOME", dir)
+	defer os.Unsetenv("XDG_STATE_HOME")
+
+	group := Switching().Remember("info")
+	assert.Equal(t, 0, group.Visible(), "no saved selection")
+	group.Add(testModule.New(t))
+	group.Add(testModule.New(t))
+	group.Add(testModule.New(t))
+	group.Show(2)
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "barista", "group-info"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(contents), "selection saved")
+
+	group = Switching().Remember("info")
+	assert.Equal(t, 2, group.Visible(), "selection restored before adding")
+	module1 := testModule.New(t)
+	tester1 := testModule.NewOutputTester(t, group.Add(module1))
+	group.Add(testModule.New(t))
+	module3 := testModule.New(t)
+	tester3 := testModule.NewOutputTester(t, group.Add(module3))
+	module1.Output(bar.Output{bar.NewSegment("1")})
+	tester1.AssertNoOutput("not selected")
+	module3.Output(bar.Output{bar.NewSegment("3")})
+	tester3.AssertOutput("restored selection is visible")
+
+	group = Switching()
+	group.Add(testModule.New(t))
+	group.Add(testModule.New(t))
+	group.Remember("info")
+	assert.Equal(t, 0, group.Visible(), "out of range selection ignored")
+	group.Add(testModule.New(t))
+	group.Remember("info")
+	assert.Equal(t, 2, group.Visible(), "selection restored after adding")
+
+	ioutil.WriteFile(filepath.Join(dir, "barista", "group-other"), []byte("invalid"), 0644)
+	assert.Equal(t, 0, Switching().Remember("other").Visible(), "invalid selection ignored")
+}
@@ -0,0 +1,434 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package homeassistant provides an i3bar module that shows the state of
+Home Assistant entities.
+
+The module connects to the Home Assistant WebSocket API using a long-lived
+access token (created from the user profile in Home Assistant), and updates
+immediately whenever the state of any of the selected entities changes.
+By default, each entity is shown as a separate segment, e.g. "Kitchen: on" or
+"Living Room: 21.5 °C", and clicking an entity that can be toggled (lights,
+switches, fans, covers etc.) toggles it. Click handlers can call any service,
+e.g. to lock a door or open a cover.
+*/
+package homeassistant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/websocket"
+)
+
+// Entity represents the state of a Home Assistant entity.
+type Entity struct {
+	ID string `json:"entity_id"`
+	// State is the entity state, e.g. "on", "locked", or "21.5".
+	State       string                 `json:"state"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	LastChanged time.Time              `json:"last_changed"`
+}
+
+// Domain returns the domain of the entity, e.g. "light".
+func (e Entity) Domain() string {
+	return strings.SplitN(e.ID, ".", 2)[0]
+}
+
+// Name returns the friendly name of the entity, or its ID if it does not
+// have a friendly name.
+func (e Entity) Name() string {
+	if name, ok := e.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return e.ID
+}
+
+// Unit returns the unit of measurement of the entity, if any.
+func (e Entity) Unit() string {
+	unit, _ := e.Attributes["unit_of_measurement"].(string)
+	return unit
+}
+
+// On returns true if the entity is on, open, or unlocked.
+func (e Entity) On() bool {
+	switch e.State {
+	case "on", "open", "opening", "unlocked", "home", "playing":
+		return true
+	}
+	return false
+}
+
+// Available returns true if Home Assistant knows the state of the entity.
+func (e Entity) Available() bool {
+	return e.State != "unavailable" && e.State != "unknown"
+}
+
+// Info represents the state of the selected entities.
+type Info struct {
+	// Entities are the selected entities, in the order given to New.
+	// Entities that do not exist in Home Assistant are not included.
+	Entities []Entity
+}
+
+// Get returns the entity with the given ID, and false if it does not exist.
+func (i Info) Get(id string) (Entity, bool) {
+	for _, e := range i.Entities {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Entity{}, false
+}
+
+// Controller provides an interface to control entities from the click handler.
+type Controller interface {
+	// CallService calls a service for an entity, e.g.
+	// CallService("cover", "open_cover", "cover.garage").
+	CallService(domain, service, entityID string)
+	// Toggle toggles an entity that supports it, e.g. a light or switch.
+	Toggle(entityID string)
+}
+
+// Module is the public interface for a homeassistant module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to call services.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	wsURL      string
+	token      secret.Secret
+	ids        []string
+	outputFunc func(Info) bar.Output
+	entities   map[string]Entity
+	info       Info
+	ws         *websocket.Conn
+	nextID     int
+	connErr    error
+}
+
+// New constructs an instance of the homeassistant module, for a server
+// given by its URL (e.g. "http://homeassistant.local:8123"), using a
+// long-lived access token, that shows the given entities.
+func New(server string, token secret.Secret, entities ...string) Module {
+	wsURL := strings.TrimSuffix(server, "/") + "/api/websocket"
+	wsURL = strings.Replace(wsURL, "http", "ws", 1)
+	m := &module{
+		Base:     base.New(),
+		wsURL:    wsURL,
+		token:    token,
+		ids:      entities,
+		entities: map[string]Entity{},
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows each entity as a segment, using the entity ID as the
+// instance, so click handlers can identify the clicked entity.
+func DefaultOutput(i Info) bar.Output {
+	out := outputs.Multi()
+	for _, e := range i.Entities {
+		text := e.Name() + ": " + e.State
+		if unit := e.Unit(); unit != "" {
+			text += " " + unit
+		}
+		out.Add(e.ID, outputs.Text(text))
+	}
+	return out.KeepSeparators(true).Build()
+}
+
+// toggleable are the domains that support the toggle service.
+var toggleable = map[string]bool{
+	"light":         true,
+	"switch":        true,
+	"fan":           true,
+	"cover":         true,
+	"input_boolean": true,
+	"automation":    true,
+	"media_player":  true,
+}
+
+// DefaultClickHandler toggles the clicked entity on left click,
+// if it can be toggled.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button != bar.ButtonLeft {
+		return
+	}
+	if entity, ok := i.Get(e.Instance); ok && toggleable[entity.Domain()] {
+		c.Toggle(entity.ID)
+	}
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// message is a message sent to or received from Home Assistant.
+// Only the fields used by the module are included.
+type message struct {
+	ID          int             `json:"id,omitempty"`
+	Type        string          `json:"type"`
+	AccessToken string          `json:"access_token,omitempty"`
+	EventType   string          `json:"event_type,omitempty"`
+	Domain      string          `json:"domain,omitempty"`
+	Service     string          `json:"service,omitempty"`
+	ServiceData *serviceData    `json:"service_data,omitempty"`
+	Success     bool            `json:"success,omitempty"`
+	Message     string          `json:"message,omitempty"`
+	Error       *haError        `json:"error,omitempty"`
+	Event       *stateChange    `json:"event,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+}
+
+type serviceData struct {
+	EntityID string `json:"entity_id,omitempty"`
+}
+
+type haError struct {
+	Message string `json:"message"`
+}
+
+type stateChange struct {
+	Data struct {
+		EntityID string  `json:"entity_id"`
+		NewState *Entity `json:"new_state"`
+	} `json:"data"`
+}
+
+// Message IDs for the initial requests. Service calls use later IDs.
+const (
+	subscribeID = 1
+	statesID    = 2
+)
+
+func (m *module) CallService(domain, service, entityID string) {
+	m.Lock()
+	ws := m.ws
+	m.nextID++
+	id := m.nextID
+	m.Unlock()
+	if ws == nil {
+		m.Error(errors.New("not connected to home assistant"))
+		return
+	}
+	// The state_changed event will update the module.
+	m.Error(ws.WriteJSON(message{
+		ID:          id,
+		Type:        "call_service",
+		Domain:      domain,
+		Service:     service,
+		ServiceData: &serviceData{EntityID: entityID},
+	}))
+}
+
+func (m *module) Toggle(entityID string) {
+	// homeassistant.toggle dispatches to the entity's own domain.
+	m.CallService("homeassistant", "toggle", entityID)
+}
+
+// Stream connects to Home Assistant, and then returns the output channel
+// from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go m.listen()
+	return ch
+}
+
+// retryDelay is how long to wait before reconnecting to Home Assistant.
+var retryDelay = 10 * time.Second
+
+func (m *module) listen() {
+	for {
+		err := m.receive()
+		// Show the error until the connection is re-established.
+		m.Lock()
+		m.connErr = err
+		m.ws = nil
+		m.Unlock()
+		m.Update()
+		time.Sleep(retryDelay)
+	}
+}
+
+func (m *module) authenticate(ws *websocket.Conn) error {
+	var msg message
+	if err := ws.ReadJSON(&msg); err != nil {
+		return err
+	}
+	if msg.Type != "auth_required" {
+		return fmt.Errorf("home assistant: unexpected %q message", msg.Type)
+	}
+	token, err := m.token.Get()
+	if err != nil {
+		return err
+	}
+	if err := ws.WriteJSON(message{Type: "auth", AccessToken: token}); err != nil {
+		return err
+	}
+	msg = message{}
+	if err := ws.ReadJSON(&msg); err != nil {
+		return err
+	}
+	if msg.Type != "auth_ok" {
+		return fmt.Errorf("home assistant: authentication failed: %s", msg.Message)
+	}
+	return nil
+}
+
+// receive connects to Home Assistant and updates the module for each
+// change to the selected entities, until the connection fails.
+func (m *module) receive() error {
+	ws, err := websocket.Dial(m.wsURL, nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	if err := m.authenticate(ws); err != nil {
+		return err
+	}
+	// Subscribe before getting the initial states, so that no changes are missed.
+	if err := ws.WriteJSON(message{ID: subscribeID, Type: "subscribe_events", EventType: "state_changed"}); err != nil {
+		return err
+	}
+	if err := ws.WriteJSON(message{ID: statesID, Type: "get_states"}); err != nil {
+		return err
+	}
+	m.Lock()
+	m.ws = ws
+	m.nextID = statesID
+	m.Unlock()
+	for {
+		var msg message
+		if err := ws.ReadJSON(&msg); err != nil {
+			return err
+		}
+		switch {
+		case msg.Type == "event" && msg.Event != nil:
+			m.setState(msg.Event.Data.EntityID, msg.Event.Data.NewState)
+		case msg.Type == "result" && !msg.Success:
+			errMsg := "request failed"
+			if msg.Error != nil {
+				errMsg = msg.Error.Message
+			}
+			m.Error(fmt.Errorf("home assistant: %s", errMsg))
+		case msg.Type == "result" && msg.ID == statesID:
+			var states []Entity
+			if err := json.Unmarshal(msg.Result, &states); err != nil {
+				return err
+			}
+			m.setStates(states)
+		}
+	}
+}
+
+func (m *module) wanted(id string) bool {
+	for _, w := range m.ids {
+		if w == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *module) setStates(states []Entity) {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.connErr = nil
+	m.entities = map[string]Entity{}
+	for _, e := range states {
+		if m.wanted(e.ID) {
+			m.entities[e.ID] = e
+		}
+	}
+}
+
+func (m *module) setState(id string, state *Entity) {
+	if !m.wanted(id) {
+		return
+	}
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	if state == nil {
+		// The entity was removed.
+		delete(m.entities, id)
+	} else {
+		m.entities[id] = *state
+	}
+}
+
+func (m *module) update() {
+	m.Lock()
+	if err := m.connErr; err != nil {
+		m.Unlock()
+		m.Error(err)
+		return
+	}
+	info := Info{}
+	for _, id := range m.ids {
+		if e, ok := m.entities[id]; ok {
+			info.Entities = append(info.Entities, e)
+		}
+	}
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
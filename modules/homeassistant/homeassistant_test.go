@@ -0,0 +1,197 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package homeassistant
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/secret"
+	testModule "github.com/soumya92/barista/testing/module"
+	"github.com/soumya92/barista/websocket"
+)
+
+// fakeHA is a fake Home Assistant websocket server.
+type fakeHA struct {
+	received chan message
+	conns    chan *serverConn
+}
+
+type serverConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (s *serverConn) send(payload string) {
+	websocket.WriteFrame(s.conn, websocket.OpText, []byte(payload), false)
+}
+
+func newFakeHA(t *testing.T) (*fakeHA, *httptest.Server) {
+	f := &fakeHA{received: make(chan message, 10), conns: make(chan *serverConn, 10)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/websocket" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		conn, reader, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		s := &serverConn{conn, reader}
+		f.conns <- s
+		for {
+			_, opcode, payload, err := websocket.ReadFrame(s.reader)
+			if err != nil || opcode == websocket.OpClose {
+				conn.Close()
+				return
+			}
+			if opcode != websocket.OpText {
+				continue
+			}
+			var msg message
+			json.Unmarshal(payload, &msg)
+			f.received <- msg
+		}
+	}))
+	return f, server
+}
+
+// connect performs the initial handshake, and returns the connection and
+// the client's requests.
+func (f *fakeHA) connect(t *testing.T, states string) *serverConn {
+	s := <-f.conns
+	s.send(`{"type": "auth_required", "ha_version": "2023.1.0"}`)
+	auth := <-f.received
+	assert.Equal(t, message{Type: "auth", AccessToken: "token"}, auth)
+	s.send(`{"type": "auth_ok", "ha_version": "2023.1.0"}`)
+	assert.Equal(t, message{ID: 1, Type: "subscribe_events", EventType: "state_changed"}, <-f.received)
+	assert.Equal(t, message{ID: 2, Type: "get_states"}, <-f.received)
+	s.send(`{"id": 1, "type": "result", "success": true, "result": null}`)
+	s.send(`{"id": 2, "type": "result", "success": true, "result": ` + states + `}`)
+	return s
+}
+
+const states = `[
+	{"entity_id": "light.kitchen", "state": "off",
+	 "attributes": {"friendly_name": "Kitchen"}, "last_changed": "2018-01-05T10:00:00+00:00"},
+	{"entity_id": "sensor.temperature", "state": "21.5",
+	 "attributes": {"friendly_name": "Living Room", "unit_of_measurement": "°C"}},
+	{"entity_id": "lock.front_door", "state": "locked", "attributes": {}},
+	{"entity_id": "light.garage", "state": "on", "attributes": {}}
+]`
+
+func stateChanged(id, state string) string {
+	return `{"id": 1, "type": "event", "event": {"event_type": "state_changed",
+		"data": {"entity_id": "` + id + `", "new_state": ` + state + `}}}`
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	retryDelay = 10 * time.Millisecond
+	f, server := newFakeHA(t)
+	defer server.Close()
+
+	ha := New(server.URL+"/", secret.Value("token"), "sensor.temperature", "light.kitchen", "lock.front_door")
+	tester := testModule.NewOutputTester(t, ha)
+	out := tester.AssertOutput("on start")
+	assert.Empty(out, "not connected yet")
+
+	s := f.connect(t, states)
+	out = tester.AssertOutput("on initial states")
+	assert.Equal(3, len(out))
+	assert.Equal(bar.NewSegment("Living Room: 21.5 °C").Instance("sensor.temperature"), out[0])
+	assert.Equal("Kitchen: off", out[1].Text())
+	assert.Equal("lock.front_door: locked", out[2].Text())
+
+	s.send(stateChanged("light.garage", `{"entity_id": "light.garage", "state": "off"}`))
+	tester.AssertNoOutput("on unrelated state change")
+
+	s.send(stateChanged("light.kitchen",
+		`{"entity_id": "light.kitchen", "state": "on", "attributes": {"friendly_name": "Kitchen"}}`))
+	out = tester.AssertOutput("on state change")
+	assert.Equal("Kitchen: on", out[1].Text())
+
+	ha.Click(bar.Event{Button: bar.ButtonLeft, Instance: "light.kitchen"})
+	call := <-f.received
+	assert.Equal("call_service", call.Type)
+	assert.Equal("homeassistant", call.Domain)
+	assert.Equal("toggle", call.Service)
+	assert.Equal(&serviceData{EntityID: "light.kitchen"}, call.ServiceData)
+	assert.True(call.ID > 2, "unique message id")
+
+	ha.Click(bar.Event{Button: bar.ButtonLeft, Instance: "lock.front_door"})
+	ha.Click(bar.Event{Button: bar.ButtonLeft, Instance: "sensor.temperature"})
+	ha.Click(bar.Event{Button: bar.ButtonRight, Instance: "light.kitchen"})
+	select {
+	case msg := <-f.received:
+		assert.Fail("unexpected message", "%+v", msg)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	var info Info
+	ha.OnClick(func(i Info, c Controller, e bar.Event) {
+		info = i
+		c.CallService("lock", "unlock", "lock.front_door")
+	})
+	ha.Click(bar.Event{Button: bar.ButtonLeft})
+	call = <-f.received
+	assert.Equal("lock", call.Domain)
+	assert.Equal("unlock", call.Service)
+	entity, ok := info.Get("light.kitchen")
+	assert.True(ok)
+	assert.True(entity.On())
+	assert.True(entity.Available())
+	assert.Equal("light", entity.Domain())
+	entity, _ = info.Get("sensor.temperature")
+	assert.Equal("°C", entity.Unit())
+	_, ok = info.Get("light.garage")
+	assert.False(ok)
+
+	s.send(stateChanged("lock.front_door", `null`))
+	out = tester.AssertOutput("on entity removed")
+	assert.Equal(2, len(out))
+
+	s.send(`{"id": 4, "type": "result", "success": false,
+		"error": {"code": "not_found", "message": "Service not found."}}`)
+	err := tester.AssertError("on service error")
+	assert.Contains(err, "Service not found.")
+
+	s.conn.Close()
+	tester.AssertError("on disconnect")
+	f.connect(t, states)
+	out = tester.AssertOutput("on reconnect")
+	assert.Equal(3, len(out))
+}
+
+func TestAuthInvalid(t *testing.T) {
+	f, server := newFakeHA(t)
+	defer server.Close()
+	tester := testModule.NewOutputTester(t, New(server.URL, secret.Value("token"), "light.kitchen"))
+	tester.AssertOutput("on start")
+	s := <-f.conns
+	s.send(`{"type": "auth_required"}`)
+	<-f.received
+	s.send(`{"type": "auth_invalid", "message": "Invalid access token or password"}`)
+	err := tester.AssertError("on invalid token")
+	assert.Contains(t, err, "Invalid access token")
+}
@@ -0,0 +1,245 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package httpjson provides an i3bar module that shows a value from a JSON API.
+
+The module periodically fetches a URL, decodes the response as JSON, and
+extracts a value using a simple path expression, which makes it easy to show
+data from small REST APIs without writing a custom module. Paths use dots for
+object keys and brackets for array indices, with an optional leading "$",
+e.g. "$.current.temperature" or "items[0].name". For example,
+
+	httpjson.New("https://api.example.com/status").
+		Path("services[0].state").
+		OutputTemplate(outputs.TextTemplate(`API: {{.Text}}`))
+
+Templates can also access any part of the response using .Get.
+*/
+package httpjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the fetched JSON response.
+type Info struct {
+	// Data is the decoded response, using the types from encoding/json.
+	Data interface{}
+	// Value is the value at the configured path, or nil if the path
+	// does not exist in the response.
+	Value interface{}
+}
+
+// Get returns the value at a path in the response, or nil if it does not exist.
+func (i Info) Get(path string) interface{} {
+	value, _ := Extract(i.Data, path)
+	return value
+}
+
+// Text returns the value formatted as text. Strings are returned as-is,
+// numbers use the shortest representation, and objects and arrays are
+// formatted as JSON. Missing and null values are returned as "".
+func (i Info) Text() string {
+	return format(i.Value)
+}
+
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// Extract returns the value at a path in decoded JSON, and false if the
+// path does not exist. An empty path, "$", or "." returns the root value.
+func Extract(data interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.Replace(path, "[", ".[", -1)
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+		if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+			arr, ok := data.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			idx, err := strconv.Atoi(part[1 : len(part)-1])
+			if err != nil {
+				return nil, false
+			}
+			if idx < 0 {
+				// Negative indices count from the end.
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			data = arr[idx]
+			continue
+		}
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if data, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// Module is the public interface for an httpjson module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// Path sets the path of the value to extract from the response.
+	Path(string) Module
+
+	// Header adds a header to the request, e.g. for authentication.
+	Header(name, value string) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	url        string
+	path       string
+	header     http.Header
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the httpjson module that fetches the given URL.
+func New(url string) Module {
+	m := &module{
+		Base:   base.New(),
+		url:    url,
+		header: http.Header{},
+	}
+	m.RefreshInterval(5 * time.Minute)
+	// Default output template is the extracted value.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Text}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) Path(path string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.path = path
+	return m
+}
+
+func (m *module) Header(name, value string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.header.Add(name, value)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+func (m *module) fetch() (interface{}, error) {
+	req, err := http.NewRequest("GET", m.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	m.Lock()
+	for name, values := range m.header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	m.Unlock()
+	req.Header.Set("Accept", "application/json")
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", m.url, response.Status)
+	}
+	var data interface{}
+	err = json.NewDecoder(response.Body).Decode(&data)
+	return data, err
+}
+
+func (m *module) update() {
+	data, err := m.fetch()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	info := Info{Data: data}
+	info.Value, _ = Extract(data, m.path)
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+const doc = `{
+	"status": "ok",
+	"current": {"temperature": 21.5, "raining": false},
+	"items": [{"name": "first"}, {"name": "second", "tags": ["a", "b"]}],
+	"empty": null
+}`
+
+func TestExtract(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	json.Unmarshal([]byte(doc), &data)
+
+	for path, expected := range map[string]interface{}{
+		"status":              "ok",
+		"$.status":            "ok",
+		"current.temperature": 21.5,
+		"$.items[1].name":     "second",
+		"items[1].tags[0]":    "a",
+		"items[-1].tags[-1]":  "b",
+		"items.[0].name":      "first",
+		"empty":               nil,
+	} {
+		value, ok := Extract(data, path)
+		assert.True(ok, path)
+		assert.Equal(expected, value, path)
+	}
+	for _, path := range []string{"", "$", "."} {
+		value, ok := Extract(data, path)
+		assert.True(ok, path)
+		assert.Equal(data, value, "root for %q", path)
+	}
+	for _, path := range []string{"missing", "status.length", "items[2]", "items[x]", "current[0]", "items.name"} {
+		_, ok := Extract(data, path)
+		assert.False(ok, path)
+	}
+
+	assert.Equal("", Info{}.Text())
+	assert.Equal("21.5", Info{Value: 21.5}.Text())
+	assert.Equal("1000000", Info{Value: 1e6}.Text())
+	assert.Equal("false", Info{Value: false}.Text())
+	assert.Equal(`["a","b"]`, Info{Value: []interface{}{"a", "b"}}.Text())
+}
+
+type fakeAPI struct {
+	sync.Mutex
+	status  int
+	body    string
+	headers http.Header
+}
+
+func (f *fakeAPI) set(status int, body string) {
+	f.Lock()
+	defer f.Unlock()
+	f.status, f.body = status, body
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.headers = r.Header
+	w.WriteHeader(f.status)
+	w.Write([]byte(f.body))
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	f := &fakeAPI{}
+	f.set(200, doc)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	j := New(server.URL).Path("current.temperature").Header("Authorization", "Bearer abc")
+	tester := testModule.NewOutputTester(t, j)
+	out := tester.AssertOutput("on start")
+	assert.Equal("21.5", out[0].Text())
+	f.Lock()
+	assert.Equal("Bearer abc", f.headers.Get("Authorization"))
+	assert.Equal("application/json", f.headers.Get("Accept"))
+	f.Unlock()
+
+	j.OutputTemplate(outputs.TextTemplate(`{{.Get "status"}}: {{.Text}}°C, {{.Get "items[0].name"}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal("ok: 21.5°C, first", out[0].Text())
+
+	j.Path("missing")
+	out = tester.AssertOutput("on path change")
+	assert.Equal("ok: °C, first", out[0].Text())
+
+	var info Info
+	j.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Text(i.Text())
+	})
+	j.UrgentWhen(func(i Info) bool { return i.Get("status") != "ok" })
+	tester.AssertOutput("on output func change")
+	tester.AssertOutput("on urgent func change")
+	assert.Nil(info.Value)
+	assert.Equal(false, info.Get("current.raining"))
+
+	f.set(200, `{"status": "down"}`)
+	j.Path("status")
+	out = tester.AssertOutput("on path change")
+	assert.Equal("down", out[0].Text())
+	assert.Equal(true, out[0]["urgent"])
+
+	f.set(500, `{"error": "oops"}`)
+	scheduler.NextTick()
+	tester.AssertError("on server error")
+
+	f.set(200, `not json`)
+	j.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertEmpty("on restart")
+	tester.AssertError("on invalid json")
+}
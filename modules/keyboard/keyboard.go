@@ -0,0 +1,217 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package keyboard provides an i3bar module that shows the current keyboard layout.
+
+Two backends are available: X11 uses the XKB extension, and receives events
+from the X server whenever the layout group changes; Sway uses the sway IPC
+socket, and subscribes to input events. Both backends support switching
+between the configured layouts, which the default click handler does on
+click or scroll.
+
+The package also provides a Caps Lock / Num Lock indicator module, which
+uses XKB state notifications, and by default only appears when Caps Lock is on.
+*/
+package keyboard
+
+import (
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/i3ipc"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Layout represents a single configured keyboard layout.
+type Layout struct {
+	// Name is the name of the layout. For X11, this is the layout code
+	// (e.g. "us"), while sway only reports descriptive names
+	// (e.g. "English (US)").
+	Name string
+	// Variant is the layout variant (e.g. "dvorak"), if any.
+	// Only available for X11.
+	Variant string
+}
+
+// Info represents the configured keyboard layouts and the active one.
+type Info struct {
+	Layouts []Layout
+	// Current is the index of the active layout in Layouts.
+	Current int
+}
+
+// Layout returns the active layout.
+func (i Info) Layout() Layout {
+	if i.Current < 0 || i.Current >= len(i.Layouts) {
+		return Layout{}
+	}
+	return i.Layouts[i.Current]
+}
+
+// Controller provides an interface to switch keyboard layouts,
+// used in the click handler.
+type Controller interface {
+	// Next switches to the next configured layout.
+	Next()
+	// Previous switches to the previous configured layout.
+	Previous()
+	// SetLayout switches to the layout at the given index.
+	SetLayout(int)
+}
+
+// Module is the public interface for a keyboard layout module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to switch layouts.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// backend provides the keyboard layout information from, and allows
+// switching layouts using, a specific window system.
+type backend interface {
+	// watch calls the given function with the current layout information,
+	// and again each time it changes. It only returns on error.
+	watch(func(Info)) error
+	// setLayout switches to the layout at the given index.
+	setLayout(int) error
+}
+
+type module struct {
+	*base.Base
+	backend    backend
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// X11 constructs an instance of the keyboard layout module
+// that uses the XKB extension of the X server.
+func X11() Module {
+	return newModule(&xkbBackend{})
+}
+
+// Sway constructs an instance of the keyboard layout module
+// that uses sway's IPC socket.
+func Sway() Module {
+	return newModule(&swayBackend{i3ipc.Sway()})
+}
+
+func newModule(b backend) Module {
+	m := &module{
+		Base:    base.New(),
+		backend: b,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is just the layout name.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Layout.Name}}`))
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler cycles through the configured layouts on click or scroll.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ButtonLeft, bar.ScrollDown, bar.ScrollRight, bar.ButtonForward:
+		c.Next()
+	case bar.ButtonRight, bar.ScrollUp, bar.ScrollLeft, bar.ButtonBack:
+		c.Previous()
+	}
+}
+
+func (m *module) Next() {
+	m.moveBy(1)
+}
+
+func (m *module) Previous() {
+	m.moveBy(-1)
+}
+
+func (m *module) moveBy(delta int) {
+	m.Lock()
+	count := len(m.info.Layouts)
+	current := m.info.Current
+	m.Unlock()
+	if count == 0 {
+		return
+	}
+	m.SetLayout(((current+delta)%count + count) % count)
+}
+
+func (m *module) SetLayout(index int) {
+	m.Error(m.backend.setLayout(index))
+}
+
+// Stream starts watching for layout changes and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	m.OnUpdate(m.update)
+	go func() {
+		m.Error(m.backend.watch(func(i Info) {
+			m.Lock()
+			m.info = i
+			m.Unlock()
+			m.Update()
+		}))
+	}()
+	return ch
+}
+
+func (m *module) update() {
+	m.Lock()
+	info := m.info
+	outputFunc := m.outputFunc
+	m.Unlock()
+	// Nothing to show until the backend has reported the layouts.
+	if len(info.Layouts) == 0 {
+		m.Clear()
+		return
+	}
+	m.Output(outputFunc(info))
+}
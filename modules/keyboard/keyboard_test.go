@@ -0,0 +1,138 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyboard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/i3ipc"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestParseRulesNames(t *testing.T) {
+	assert := assert.New(t)
+	assert.Empty(parseRulesNames(""))
+	assert.Equal([]Layout{{"us", ""}},
+		parseRulesNames("evdev\x00pc105\x00us\x00\x00\x00"))
+	assert.Equal([]Layout{{"us", ""}, {"de", "nodeadkeys"}},
+		parseRulesNames("evdev\x00pc105\x00us,de\x00,nodeadkeys\x00grp:alt_shift_toggle\x00"))
+	assert.Equal([]Layout{{"us", ""}, {"fr", ""}},
+		parseRulesNames("evdev\x00pc105\x00us,fr"), "missing variants")
+}
+
+// fakeSway is a minimal sway IPC server for testing.
+type fakeSway struct {
+	sync.Mutex
+	current  int
+	events   chan string
+	commands chan string
+}
+
+func (f *fakeSway) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msgType, payload, err := i3ipc.ReadMessage(conn)
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case i3ipc.Subscribe:
+			i3ipc.WriteMessage(conn, i3ipc.Subscribe, `{"success": true}`)
+			for e := range f.events {
+				i3ipc.WriteMessage(conn, i3ipc.InputEvent, e)
+			}
+			return
+		case i3ipc.GetInputs:
+			f.Lock()
+			current := f.current
+			f.Unlock()
+			i3ipc.WriteMessage(conn, i3ipc.GetInputs, fmt.Sprintf(`[
+				{"identifier": "0:0:mouse", "type": "pointer"},
+				{"identifier": "1:1:keyboard", "type": "keyboard",
+				 "xkb_layout_names": ["English (US)", "German"],
+				 "xkb_active_layout_index": %d}]`, current))
+		case i3ipc.RunCommand:
+			f.commands <- string(payload)
+			i3ipc.WriteMessage(conn, i3ipc.RunCommand, `[{"success": true}]`)
+		}
+	}
+}
+
+func TestSway(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "sway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "sway.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	os.Setenv("SWAYSOCK", sock)
+
+	sway := &fakeSway{
+		events:   make(chan string),
+		commands: make(chan string, 10),
+	}
+	defer close(sway.events)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go sway.serve(conn)
+		}
+	}()
+
+	k := Sway()
+	tester := testModule.NewOutputTester(t, k)
+	out := tester.AssertOutput("on start")
+	assert.Equal("English (US)", out[0].Text())
+
+	sway.Lock()
+	sway.current = 1
+	sway.Unlock()
+	sway.events <- `{"change": "xkb_layout"}`
+	out = tester.AssertOutput("on layout change")
+	assert.Equal("German", out[0].Text())
+
+	sway.events <- `{"change": "added"}`
+	tester.AssertNoOutput("on unrelated input event")
+
+	k.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal("input type:keyboard xkb_switch_layout 0", <-sway.commands,
+		"next layout wraps around")
+	k.Click(bar.Event{Button: bar.ScrollUp})
+	assert.Equal("input type:keyboard xkb_switch_layout 0", <-sway.commands)
+
+	k.OnClick(func(i Info, c Controller, e bar.Event) {
+		c.SetLayout(len(i.Layouts) - 1)
+	})
+	k.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal("input type:keyboard xkb_switch_layout 1", <-sway.commands)
+}
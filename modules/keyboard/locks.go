@@ -0,0 +1,131 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyboard
+
+import (
+	"errors"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Modifier masks for the lock keys. Caps Lock always uses the Lock
+// modifier, and Num Lock is conventionally mapped to Mod2.
+const (
+	capsLockMask = 1 << 1
+	numLockMask  = 1 << 4
+)
+
+// LockInfo represents the state of the lock keys.
+type LockInfo struct {
+	Caps bool
+	Num  bool
+}
+
+// LockModule represents a lock key indicator bar module.
+type LockModule interface {
+	base.WithClickHandler
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(LockInfo) bar.Output) LockModule
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) LockModule
+}
+
+type lockModule struct {
+	*base.Base
+	outputFunc func(LockInfo) bar.Output
+	info       LockInfo
+}
+
+// Locks constructs an instance of the lock key indicator module, which
+// uses XKB state notifications to track the locked modifiers.
+func Locks() LockModule {
+	m := &lockModule{Base: base.New()}
+	// Default output template only shows caps lock, and only when it's on.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Caps}}CAPS{{end}}`))
+	return m
+}
+
+func (m *lockModule) OutputFunc(outputFunc func(LockInfo) bar.Output) LockModule {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *lockModule) OutputTemplate(template func(interface{}) bar.Output) LockModule {
+	return m.OutputFunc(func(i LockInfo) bar.Output {
+		return template(i)
+	})
+}
+
+// Stream starts watching for lock state changes and then returns the output
+// channel from the base module.
+func (m *lockModule) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	m.OnUpdate(m.update)
+	go func() { m.Error(watchLocks(m.setLocked)) }()
+	return ch
+}
+
+func (m *lockModule) setLocked(mods byte) {
+	m.Lock()
+	m.info = LockInfo{
+		Caps: mods&capsLockMask != 0,
+		Num:  mods&numLockMask != 0,
+	}
+	m.Unlock()
+	m.Update()
+}
+
+// watchLocks calls setLocked with the locked modifiers on start and on
+// each change, and only returns on error.
+var watchLocks = func(setLocked func(mods byte)) error {
+	c, err := connectXkb()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := selectStateNotify(c, xkbModLockMask); err != nil {
+		return err
+	}
+	state, err := getState(c)
+	if err != nil {
+		return err
+	}
+	setLocked(state[11])
+	for {
+		ev, xerr := c.WaitForEvent()
+		if ev == nil && xerr == nil {
+			return errors.New("X connection closed")
+		}
+		if xerr != nil {
+			return xerr
+		}
+		if e, ok := ev.(xkbEvent); ok && e[1] == xkbStateNotify {
+			setLocked(e[12])
+		}
+	}
+}
+
+func (m *lockModule) update() {
+	m.Lock()
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyboard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestLocks(t *testing.T) {
+	assert := assert.New(t)
+	mods := make(chan byte)
+	errs := make(chan error)
+	watchLocks = func(setLocked func(byte)) error {
+		for {
+			select {
+			case m := <-mods:
+				setLocked(m)
+			case err := <-errs:
+				return err
+			}
+		}
+	}
+
+	l := Locks()
+	tester := testModule.NewOutputTester(t, l)
+	mods <- 0
+	assert.Equal("", tester.AssertOutput("on initial state")[0].Text(), "caps lock off")
+
+	mods <- capsLockMask
+	assert.Equal("CAPS", tester.AssertOutput("caps lock on")[0].Text())
+
+	mods <- capsLockMask | numLockMask
+	tester.AssertOutput("num lock on")
+
+	l.OutputFunc(func(i LockInfo) bar.Output {
+		if i.Num {
+			return outputs.Text("NUM")
+		}
+		return nil
+	})
+	assert.Equal("NUM", tester.AssertOutput("output func changed")[0].Text())
+
+	mods <- capsLockMask
+	tester.AssertEmpty("num lock off")
+
+	errs <- errors.New("X connection closed")
+	tester.AssertError("on watch error")
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyboard
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/soumya92/barista/i3ipc"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("modules/keyboard")
+
+type swayBackend struct {
+	ipc i3ipc.Client
+}
+
+// swayInput represents the relevant fields of an input device from sway.
+type swayInput struct {
+	Identifier  string   `json:"identifier"`
+	Type        string   `json:"type"`
+	LayoutNames []string `json:"xkb_layout_names"`
+	LayoutIndex int      `json:"xkb_active_layout_index"`
+}
+
+// getInfo returns the layouts of the first keyboard that has any.
+func (s swayBackend) getInfo() (Info, error) {
+	var inputs []swayInput
+	if err := s.ipc.Call(i3ipc.GetInputs, "", &inputs); err != nil {
+		return Info{}, err
+	}
+	info := Info{}
+	for _, in := range inputs {
+		if in.Type != "keyboard" || len(in.LayoutNames) == 0 {
+			continue
+		}
+		for _, name := range in.LayoutNames {
+			info.Layouts = append(info.Layouts, Layout{Name: name})
+		}
+		info.Current = in.LayoutIndex
+		break
+	}
+	return info, nil
+}
+
+func (s swayBackend) watch(f func(Info)) error {
+	info, err := s.getInfo()
+	if err != nil {
+		return err
+	}
+	f(info)
+	return s.ipc.Subscribe([]string{"input"}, func(msgType uint32, payload []byte) {
+		if msgType != i3ipc.InputEvent {
+			return
+		}
+		var event struct{ Change string }
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Error("invalid input event", "error", err)
+			return
+		}
+		if event.Change != "xkb_layout" && event.Change != "xkb_keymap" {
+			return
+		}
+		// The subscription cannot be interrupted from here, so failures
+		// are logged and the layouts are queried again on the next event.
+		info, err := s.getInfo()
+		if err != nil {
+			log.Error("failed to get layouts", "error", err)
+			return
+		}
+		f(info)
+	})
+}
+
+func (s swayBackend) setLayout(index int) error {
+	return s.ipc.Command(fmt.Sprintf("input type:keyboard xkb_switch_layout %d", index))
+}
@@ -0,0 +1,261 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyboard
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// xgb does not include bindings for the XKEYBOARD extension, but only a
+// handful of simple requests are needed to track the keyboard state,
+// so they are encoded by hand here, following the XKB protocol spec.
+const (
+	xkbExtension = "XKEYBOARD"
+
+	xkbUseExtension   = 0
+	xkbSelectEvents   = 1
+	xkbGetState       = 4
+	xkbLatchLockState = 5
+
+	xkbUseCoreKbd      = 0x100
+	xkbStateNotify     = 2
+	xkbStateNotifyMask = 1 << xkbStateNotify
+	xkbModLockMask     = 1 << 3
+	xkbGroupStateMask  = 1 << 4
+
+	// The layouts are read from the root window property set by setxkbmap.
+	rulesNamesProp = "_XKB_RULES_NAMES"
+)
+
+type xkbBackend struct {
+	sync.Mutex
+	conn *xgb.Conn
+}
+
+// xkbEvent is a raw XKB event. All XKB events share a single event code,
+// with the actual XKB event type in the second byte.
+type xkbEvent []byte
+
+func (e xkbEvent) Bytes() []byte  { return e }
+func (e xkbEvent) String() string { return "XkbEvent" }
+
+// xkbRequest builds an XKB request with the given minor opcode and body.
+func xkbRequest(c *xgb.Conn, opcode byte, body ...byte) []byte {
+	buf := make([]byte, 4+len(body))
+	c.ExtLock.RLock()
+	buf[0] = c.Extensions[xkbExtension]
+	c.ExtLock.RUnlock()
+	buf[1] = opcode
+	xgb.Put16(buf[2:], uint16(len(buf)/4))
+	copy(buf[4:], body)
+	return buf
+}
+
+// sendXkb sends an XKB request, and returns the reply if one is expected.
+func sendXkb(c *xgb.Conn, reply bool, opcode byte, body ...byte) ([]byte, error) {
+	cookie := c.NewCookie(true, reply)
+	c.NewRequest(xkbRequest(c, opcode, body...), cookie)
+	if reply {
+		return cookie.Reply()
+	}
+	return nil, cookie.Check()
+}
+
+// connectXkb connects to the X server and initialises the XKEYBOARD
+// extension on the connection.
+func connectXkb() (*xgb.Conn, error) {
+	c, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	if err := initXkb(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+var registerEvents sync.Once
+
+func initXkb(c *xgb.Conn) error {
+	ext, err := xproto.QueryExtension(c, uint16(len(xkbExtension)), xkbExtension).Reply()
+	if err != nil {
+		return err
+	}
+	if !ext.Present {
+		return errors.New("X server does not support XKB")
+	}
+	c.ExtLock.Lock()
+	c.Extensions[xkbExtension] = ext.MajorOpcode
+	c.ExtLock.Unlock()
+	// NewEventFuncs is shared by all connections, and is not synchronised,
+	// so only register the event constructor once. All connections are to
+	// the same X server, so the event code is the same for all of them.
+	registerEvents.Do(func() {
+		xgb.NewEventFuncs[int(ext.FirstEvent)] = func(buf []byte) xgb.Event {
+			return xkbEvent(buf)
+		}
+	})
+	// Wanted version 1.0.
+	rep, err := sendXkb(c, true, xkbUseExtension, 1, 0, 0, 0)
+	if err != nil {
+		return err
+	}
+	if rep[1] == 0 {
+		return errors.New("X server does not support XKB 1.0")
+	}
+	return nil
+}
+
+// selectStateNotify selects state notifications for changes to the
+// given parts of the keyboard state.
+func selectStateNotify(c *xgb.Conn, parts byte) error {
+	_, err := sendXkb(c, false, xkbSelectEvents,
+		xkbUseCoreKbd&0xff, xkbUseCoreKbd>>8, // deviceSpec
+		xkbStateNotifyMask, 0, // affectWhich
+		0, 0, // clear
+		0, 0, // selectAll
+		0, 0, // affectMap
+		0, 0, // map
+		parts, 0, // affectState
+		parts, 0, // stateDetails
+	)
+	return err
+}
+
+// getState returns the raw reply to a GetState request for the core keyboard.
+func getState(c *xgb.Conn) ([]byte, error) {
+	return sendXkb(c, true, xkbGetState, xkbUseCoreKbd&0xff, xkbUseCoreKbd>>8, 0, 0)
+}
+
+// parseRulesNames parses the layouts and variants from the value of the
+// _XKB_RULES_NAMES property, which is a sequence of null-terminated strings:
+// rules, model, layouts, variants, options.
+func parseRulesNames(value string) []Layout {
+	parts := strings.Split(value, "\x00")
+	if len(parts) < 3 || parts[2] == "" {
+		return nil
+	}
+	var variants []string
+	if len(parts) > 3 {
+		variants = strings.Split(parts[3], ",")
+	}
+	var layouts []Layout
+	for idx, name := range strings.Split(parts[2], ",") {
+		l := Layout{Name: name}
+		if idx < len(variants) {
+			l.Variant = variants[idx]
+		}
+		layouts = append(layouts, l)
+	}
+	return layouts
+}
+
+func (x *xkbBackend) watch(f func(Info)) error {
+	c, err := connectXkb()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	x.Lock()
+	x.conn = c
+	x.Unlock()
+
+	root := xproto.Setup(c).DefaultScreen(c).Root
+	prop, err := xproto.InternAtom(c, false, uint16(len(rulesNamesProp)), rulesNamesProp).Reply()
+	if err != nil {
+		return err
+	}
+	// Watch the root window properties, to pick up changes to the
+	// configured layouts, e.g. from setxkbmap.
+	err = xproto.ChangeWindowAttributesChecked(c, root, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange}).Check()
+	if err != nil {
+		return err
+	}
+	// Select state notifications only for changes to the layout group.
+	if err := selectStateNotify(c, xkbGroupStateMask); err != nil {
+		return err
+	}
+
+	info := Info{}
+	readLayouts := func() error {
+		rep, err := xproto.GetProperty(c, false, root, prop.Atom,
+			xproto.AtomString, 0, 1024).Reply()
+		if err != nil {
+			return err
+		}
+		info.Layouts = parseRulesNames(string(rep.Value))
+		return nil
+	}
+	if err := readLayouts(); err != nil {
+		return err
+	}
+	state, err := getState(c)
+	if err != nil {
+		return err
+	}
+	info.Current = int(state[12])
+	f(info)
+
+	for {
+		ev, xerr := c.WaitForEvent()
+		if ev == nil && xerr == nil {
+			return errors.New("X connection closed")
+		}
+		if xerr != nil {
+			return xerr
+		}
+		switch e := ev.(type) {
+		case xkbEvent:
+			if e[1] != xkbStateNotify {
+				continue
+			}
+			info.Current = int(e[13])
+		case xproto.PropertyNotifyEvent:
+			if e.Atom != prop.Atom {
+				continue
+			}
+			if err := readLayouts(); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		f(info)
+	}
+}
+
+func (x *xkbBackend) setLayout(index int) error {
+	x.Lock()
+	c := x.conn
+	x.Unlock()
+	if c == nil {
+		return errors.New("not connected to X server")
+	}
+	_, err := sendXkb(c, false, xkbLatchLockState,
+		xkbUseCoreKbd&0xff, xkbUseCoreKbd>>8, // deviceSpec
+		0, 0, // affectModLocks, modLocks
+		1, byte(index), // lockGroup, groupLock
+		0, 0, 0, // affectModLatches, padding
+		0, 0, 0, // latchGroup, groupLatch
+	)
+	return err
+}
@@ -0,0 +1,267 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package kubernetes provides an i3bar module that shows the current kubectl context.
+
+The context and namespace are read from the kubeconfig file, which is watched
+using inotify, so switching contexts with kubectl (or kubectx) is reflected on
+the bar immediately. Optionally, the module can also use
+kubectl to check whether the cluster is reachable and count pending pods.
+*/
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/watchers/file"
+)
+
+// Info represents the current kubernetes context.
+type Info struct {
+	// Context is the name of the current context.
+	Context string
+	// Cluster is the name of the cluster for the current context.
+	Cluster string
+	// User is the name of the user for the current context.
+	User string
+	// Namespace is the namespace for the current context.
+	Namespace string
+	// Checked is true if the cluster status has been checked,
+	// which is only done if enabled using ClusterStatus.
+	Checked bool
+	// Reachable is true if the cluster responded to the last status check.
+	Reachable bool
+	// PendingPods is the number of pods in the Pending phase in the
+	// namespace, as of the last status check.
+	PendingPods int
+}
+
+// Module represents a kubernetes context bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// ClusterStatus enables checking the cluster's reachability and the
+	// number of pending pods (using kubectl) at the given interval.
+	// An interval of zero disables cluster status checks.
+	ClusterStatus(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	configFile     string
+	outputFunc     func(Info) bar.Output
+	statusInterval time.Duration
+	lastChecked    time.Time
+	info           Info
+}
+
+// New constructs an instance of the kubernetes module using the kubeconfig
+// file from $KUBECONFIG, falling back to ~/.kube/config.
+func New() Module {
+	config := os.Getenv("KUBECONFIG")
+	if config != "" {
+		// Only the first file is used if multiple files are specified.
+		config = filepath.SplitList(config)[0]
+	} else {
+		config = filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	}
+	return Config(config)
+}
+
+// Config constructs an instance of the kubernetes module using the given
+// kubeconfig file.
+func Config(configFile string) Module {
+	m := &module{
+		Base:       base.New(),
+		configFile: configFile,
+	}
+	// Default output template is the context and namespace.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{.Context}}{{with .Namespace}}/{{.}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) ClusterStatus(interval time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.statusInterval = interval
+	m.lastChecked = time.Time{}
+	// Config changes are handled by the watcher, so the scheduler is
+	// only needed for cluster status checks.
+	if interval > 0 {
+		m.Schedule().Every(interval)
+	} else {
+		m.Schedule().Stop()
+	}
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+// kubeconfig represents the relevant parts of a kubeconfig file.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// readConfig reads the current context information from the kubeconfig file.
+func readConfig(configFile string) (Info, error) {
+	bytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return Info{}, err
+	}
+	var conf kubeconfig
+	if err := yaml.Unmarshal(bytes, &conf); err != nil {
+		return Info{}, err
+	}
+	info := Info{Context: conf.CurrentContext, Namespace: "default"}
+	for _, c := range conf.Contexts {
+		if c.Name != conf.CurrentContext {
+			continue
+		}
+		info.Cluster = c.Context.Cluster
+		info.User = c.Context.User
+		if c.Context.Namespace != "" {
+			info.Namespace = c.Context.Namespace
+		}
+	}
+	return info, nil
+}
+
+// kubectl runs kubectl with the given arguments against the given context,
+// with a timeout to prevent an unreachable cluster from blocking updates.
+var kubectl = func(ctx string, args ...string) (string, error) {
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	args = append([]string{"--context", ctx}, args...)
+	out, err := exec.CommandContext(c, "kubectl", args...).Output()
+	return string(out), err
+}
+
+// checkStatus updates the cluster status fields of info using kubectl.
+func checkStatus(info *Info) {
+	info.Checked = true
+	info.Reachable = false
+	info.PendingPods = 0
+	if _, err := kubectl(info.Context, "get", "--raw", "/healthz"); err != nil {
+		return
+	}
+	info.Reachable = true
+	out, err := kubectl(info.Context,
+		"--namespace", info.Namespace,
+		"get", "pods", "--field-selector", "status.phase=Pending", "-o", "name")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			info.PendingPods++
+		}
+	}
+}
+
+// Stream starts watching the kubeconfig file for changes, and then
+// returns the output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	w, err := file.Watch(m.configFile)
+	if m.Error(err) {
+		return ch
+	}
+	go m.listen(w)
+	return ch
+}
+
+// listen triggers an update whenever the kubeconfig file changes.
+func (m *module) listen(w *file.Watcher) {
+	defer w.Close()
+	for {
+		select {
+		case <-w.Updates:
+			m.Update()
+		case err := <-w.Errors:
+			m.Error(err)
+			return
+		}
+	}
+}
+
+func (m *module) update() {
+	info, err := readConfig(m.configFile)
+	if m.Error(err) {
+		return
+	}
+	now := scheduler.Now()
+	m.Lock()
+	last := m.info
+	contextChanged := info.Context != last.Context || info.Namespace != last.Namespace
+	statusDue := m.statusInterval > 0 &&
+		(contextChanged || !last.Checked || now.Sub(m.lastChecked) >= m.statusInterval)
+	if m.statusInterval > 0 && !statusDue {
+		// Keep the last status until the next check is due.
+		info.Checked = last.Checked
+		info.Reachable = last.Reachable
+		info.PendingPods = last.PendingPods
+	}
+	m.Unlock()
+	if statusDue {
+		checkStatus(&info)
+	}
+	m.Lock()
+	if statusDue {
+		m.lastChecked = now
+	}
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
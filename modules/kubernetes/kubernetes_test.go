@@ -0,0 +1,139 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+// writeConfig replaces the kubeconfig file, the same way kubectx does.
+func writeConfig(configFile, current string) {
+	ioutil.WriteFile(configFile+".tmp", []byte(`
+apiVersion: v1
+kind: Config
+current-context: `+current+`
+contexts:
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: admin
+    namespace: web
+- name: dev
+  context:
+    cluster: minikube
+    user: minikube
+`), 0644)
+	os.Rename(configFile+".tmp", configFile)
+}
+
+func tempConfig(t *testing.T) (configFile string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "config"), func() { os.RemoveAll(dir) }
+}
+
+func TestContext(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	configFile, cleanup := tempConfig(t)
+	defer cleanup()
+
+	k := Config(configFile)
+	tester := testModule.NewOutputTester(t, k)
+	tester.AssertError("on start with missing config")
+
+	writeConfig(configFile, "prod")
+	out := tester.AssertOutput("when config is created")
+	assert.Equal("prod/web", out[0].Text())
+
+	ioutil.WriteFile(filepath.Join(filepath.Dir(configFile), "other"), nil, 0644)
+	tester.AssertNoOutput("on other file change")
+
+	writeConfig(configFile, "dev")
+	out = tester.AssertOutput("when config is changed")
+	assert.Equal("dev/default", out[0].Text(), "default namespace")
+
+	k.OutputTemplate(outputs.TextTemplate(`{{.Cluster}} {{.User}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal("minikube minikube", out[0].Text())
+}
+
+func TestClusterStatus(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	start := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	scheduler.AdvanceTo(start)
+	configFile, cleanup := tempConfig(t)
+	defer cleanup()
+	writeConfig(configFile, "prod")
+
+	calls := make(chan string, 10)
+	reachable := true
+	kubectl = func(ctx string, args ...string) (string, error) {
+		calls <- ctx + " " + strings.Join(args, " ")
+		if !reachable {
+			return "", errors.New("unreachable")
+		}
+		if args[0] == "get" {
+			return "ok", nil
+		}
+		return "pod/a\npod/b\n", nil
+	}
+
+	k := Config(configFile).
+		ClusterStatus(time.Minute).
+		OutputTemplate(outputs.TextTemplate(
+			`{{if .Reachable}}{{.PendingPods}} pending{{else}}down{{end}}`))
+	tester := testModule.NewOutputTester(t, k)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("2 pending", out[0].Text())
+	assert.Equal("prod get --raw /healthz", <-calls)
+	assert.Equal("prod --namespace web get pods --field-selector status.phase=Pending -o name", <-calls)
+
+	scheduler.AdvanceBy(30 * time.Second)
+	tester.AssertNoOutput("before status interval elapses")
+
+	writeConfig(configFile, "prod")
+	out = tester.AssertOutput("on config change")
+	assert.Equal("2 pending", out[0].Text(), "status is kept for the same context")
+	assert.Empty(calls, "status not checked again for the same context")
+
+	reachable = false
+	scheduler.AdvanceBy(31 * time.Second)
+	out = tester.AssertOutput("after status interval elapses")
+	assert.Equal("down", out[0].Text())
+	<-calls
+
+	k.ClusterStatus(0)
+	tester.AssertOutput("on disabling status checks")
+	scheduler.AdvanceBy(10 * time.Minute)
+	tester.AssertNoOutput("when status checks are disabled")
+	assert.Empty(calls)
+}
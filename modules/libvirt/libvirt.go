@@ -0,0 +1,257 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package libvirt provides an i3bar module that shows running libvirt domains.
+
+It speaks the libvirt RPC protocol directly over the libvirt socket, so no
+libvirt client libraries are needed. The default output shows each running
+domain as a separate segment, which allows the click handler to determine
+which domain was clicked, e.g. to shut it down:
+
+	libvirt.New().OnClick(libvirt.ShutdownOnRightClick)
+*/
+package libvirt
+
+import (
+	"net"
+	"os/exec"
+	"sort"
+	"time"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Domain represents a single running libvirt domain (virtual machine).
+type Domain struct {
+	Name string
+	ID   int
+}
+
+// Info represents the list of running domains, sorted by name.
+type Info []Domain
+
+// Count returns the number of running domains.
+func (i Info) Count() int {
+	return len(i)
+}
+
+// Names returns the names of all running domains.
+func (i Info) Names() []string {
+	var names []string
+	for _, d := range i {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+// Controller provides an interface to control libvirt domains,
+// used in the click handler.
+type Controller interface {
+	// Shutdown gracefully shuts down the named domain.
+	Shutdown(name string)
+
+	// OpenManager launches virt-manager.
+	OpenManager()
+}
+
+// Module is the public interface for a libvirt module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control the domains.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency for the domain list.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// client lists and controls libvirt domains.
+type client interface {
+	// domains returns the running domains, in any order.
+	domains() (Info, error)
+	// shutdown gracefully shuts down the named domain.
+	shutdown(name string) error
+}
+
+type module struct {
+	*base.Base
+	client     client
+	outputFunc func(Info) bar.Output
+	lastInfo   Info
+}
+
+// DefaultSocket is the default location of the system libvirt socket.
+const DefaultSocket = "/var/run/libvirt/libvirt-sock"
+
+// New constructs an instance of the libvirt module using the system socket.
+func New() Module {
+	return Socket(DefaultSocket)
+}
+
+// Socket constructs an instance of the libvirt module using the given socket.
+func Socket(socket string) Module {
+	return newModule(&socketClient{socket})
+}
+
+func newModule(c client) *module {
+	m := &module{
+		Base:   base.New(),
+		client: c,
+	}
+	m.RefreshInterval(5 * time.Second)
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows each running domain as a segment, using the domain
+// name as the instance, so click handlers can identify the clicked domain.
+func DefaultOutput(i Info) bar.Output {
+	out := outputs.Multi()
+	for _, d := range i {
+		out.AddText(d.Name, d.Name)
+	}
+	return out.Build()
+}
+
+// DefaultClickHandler opens virt-manager on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.OpenManager()
+	}
+}
+
+// ShutdownOnRightClick is a click handler that opens virt-manager on left
+// click, and shuts down the clicked domain on right click. Since shutting
+// down a domain is disruptive, it is not the default click handler.
+func ShutdownOnRightClick(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonRight && e.Instance != "" {
+		c.Shutdown(e.Instance)
+		return
+	}
+	DefaultClickHandler(i, c, e)
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.lastInfo
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+func (m *module) OpenManager() {
+	go exec.Command("virt-manager").Run()
+}
+
+func (m *module) Shutdown(name string) {
+	if !m.Error(m.client.shutdown(name)) {
+		m.Update()
+	}
+}
+
+// socketClient connects to libvirt over a unix socket for each request.
+type socketClient struct {
+	socket string
+}
+
+// withConnection connects to libvirt, calls the given function, and
+// disconnects afterwards.
+func (s *socketClient) withConnection(f func(*golibvirt.Libvirt) error) error {
+	conn, err := net.DialTimeout("unix", s.socket, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	l := golibvirt.New(conn)
+	if err := l.Connect(); err != nil {
+		conn.Close()
+		return err
+	}
+	defer l.Disconnect()
+	return f(l)
+}
+
+func (s *socketClient) domains() (Info, error) {
+	var info Info
+	err := s.withConnection(func(l *golibvirt.Libvirt) error {
+		domains, _, err := l.ConnectListAllDomains(1, golibvirt.ConnectListDomainsActive)
+		for _, d := range domains {
+			info = append(info, Domain{Name: d.Name, ID: int(d.ID)})
+		}
+		return err
+	})
+	return info, err
+}
+
+func (s *socketClient) shutdown(name string) error {
+	return s.withConnection(func(l *golibvirt.Libvirt) error {
+		dom, err := l.DomainLookupByName(name)
+		if err != nil {
+			return err
+		}
+		return l.DomainShutdown(dom)
+	})
+}
+
+func (m *module) update() {
+	info, err := m.client.domains()
+	if m.Error(err) {
+		return
+	}
+	sort.Slice(info, func(a, b int) bool { return info[a].Name < info[b].Name })
+	m.Lock()
+	m.lastInfo = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeClient struct {
+	sync.Mutex
+	running   map[string]int
+	err       error
+	shutdowns chan string
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		running:   map[string]int{},
+		shutdowns: make(chan string, 10),
+	}
+}
+
+func (f *fakeClient) domains() (Info, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	var info Info
+	for name, id := range f.running {
+		info = append(info, Domain{Name: name, ID: id})
+	}
+	return info, nil
+}
+
+func (f *fakeClient) shutdown(name string) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.shutdowns <- name
+	delete(f.running, name)
+	return nil
+}
+
+func (f *fakeClient) start(name string, id int) {
+	f.Lock()
+	defer f.Unlock()
+	f.running[name] = id
+}
+
+func (f *fakeClient) shouldError(err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.err = err
+}
+
+func TestDomains(t *testing.T) {
+	scheduler.TestMode(true)
+	c := newFakeClient()
+	c.start("windows", 3)
+	c.start("arch", 1)
+	c.start("debian", 2)
+
+	m := newModule(c)
+	tester := testModule.NewOutputTester(t, m)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal(t, 3, len(out), "one segment per domain")
+	for i, name := range []string{"arch", "debian", "windows"} {
+		assert.Equal(t, name, out[i].Text(), "domains sorted by name")
+		assert.Equal(t, name, out[i]["instance"], "domain name as instance")
+	}
+
+	m.OutputTemplate(outputs.TextTemplate(`{{.Count}}: {{index .Names 0}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal(t, "3: arch", out[0].Text())
+
+	c.start("alpine", 4)
+	tester.AssertNoOutput("until refresh")
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal(t, "4: alpine", out[0].Text())
+
+	c.shouldError(errors.New("connection refused"))
+	scheduler.NextTick()
+	tester.AssertError("on error")
+}
+
+func TestShutdown(t *testing.T) {
+	scheduler.TestMode(true)
+	c := newFakeClient()
+	c.start("arch", 1)
+	c.start("debian", 2)
+
+	m := newModule(c)
+	tester := testModule.NewOutputTester(t, m)
+	tester.AssertOutput("on start")
+
+	m.Click(bar.Event{Button: bar.ButtonRight, Instance: "arch"})
+	assert.Empty(t, c.shutdowns, "default handler does not shut down")
+	tester.AssertNoOutput("on right click with default handler")
+
+	m.OnClick(ShutdownOnRightClick)
+	m.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Empty(t, c.shutdowns, "no shutdown without instance")
+
+	m.Click(bar.Event{Button: bar.ButtonRight, Instance: "arch"})
+	assert.Equal(t, "arch", <-c.shutdowns, "shuts down clicked domain")
+	out := tester.AssertOutput("after shutdown")
+	assert.Equal(t, 1, len(out), "shut down domain is removed")
+	assert.Equal(t, "debian", out[0].Text())
+
+	c.shouldError(errors.New("domain not found"))
+	m.Click(bar.Event{Button: bar.ButtonRight, Instance: "debian"})
+	assert.Empty(t, c.shutdowns, "no shutdown on error")
+	tester.AssertError("on shutdown error")
+}
@@ -0,0 +1,281 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package mdstat provides an i3bar module that shows the status of Linux
+software RAID (mdadm) arrays.
+
+The status is read from /proc/mdstat, which does not generate inotify events,
+so it is polled periodically. By default the module shows the member status
+of each array, e.g. "md0 [UU_]", along with the progress of any running
+recovery, resync, or check. The module is urgent when any array is degraded.
+*/
+package mdstat
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Array represents the state of a single md array.
+type Array struct {
+	Name string
+	// State is "active" or "inactive", with an optional qualifier,
+	// e.g. "active (auto-read-only)".
+	State string
+	// Level is the RAID level, e.g. "raid1".
+	Level string
+	// Devices are the names of the member devices.
+	Devices []string
+	// Failed are the names of member devices marked as faulty.
+	Failed []string
+	// Total is the number of devices the array should have,
+	// and Active is the number of devices currently in use.
+	Total, Active int
+	// Status is the state of each member, e.g. "UU_", where "_" marks a
+	// missing or failed member.
+	Status string
+	// Action is the running sync action, e.g. "recovery", "resync",
+	// "check", or "reshape", or empty if the array is idle.
+	Action string
+	// Progress is the progress of the running action as a percentage,
+	// and Finish is the estimated time to completion.
+	Progress float64
+	Finish   time.Duration
+}
+
+// Degraded returns true if the array is missing any devices.
+func (a Array) Degraded() bool {
+	return a.Active < a.Total || len(a.Failed) > 0
+}
+
+// Syncing returns true if the array is running a sync action.
+func (a Array) Syncing() bool {
+	return a.Action != ""
+}
+
+// Info represents the state of all monitored arrays.
+type Info struct {
+	Arrays []Array
+}
+
+// Degraded returns true if any array is degraded.
+func (i Info) Degraded() bool {
+	for _, a := range i.Arrays {
+		if a.Degraded() {
+			return true
+		}
+	}
+	return false
+}
+
+// Syncing returns true if any array is running a sync action.
+func (i Info) Syncing() bool {
+	for _, a := range i.Arrays {
+		if a.Syncing() {
+			return true
+		}
+	}
+	return false
+}
+
+// Module is the public interface for an mdstat module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	arrays     []string
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the mdstat module for the given arrays
+// (e.g. "md0"), or all arrays if none are given.
+func New(arrays ...string) Module {
+	m := &module{Base: base.New(), arrays: arrays}
+	m.RefreshInterval(5 * time.Second)
+	// Default output template is the member status of each array (or its
+	// state if inactive), and the progress of any running sync action.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{range $i, $a := .Arrays}}{{if $i}} {{end}}{{$a.Name}}` +
+			`{{with $a.Status}} [{{.}}]{{else}} {{$a.State}}{{end}}` +
+			`{{if $a.Syncing}} {{$a.Action}} {{$a.Progress | number 1}}%{{end}}{{end}}`))
+	// Degraded arrays need attention by default.
+	m.UrgentWhen(Info.Degraded)
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+var fs = hostfs.Fs()
+
+var (
+	// e.g. "1953382400 blocks super 1.2 [2/1] [U_]"
+	statusRegexp = regexp.MustCompile(`\[(\d+)/(\d+)\] \[([U_]+)\]`)
+	// e.g. "[==>....]  recovery = 12.6% (37043392/293039104) finish=127.5min speed=33440K/sec"
+	actionRegexp = regexp.MustCompile(`(\w+) = *([0-9.]+)%.*finish=([0-9.]+)min`)
+	// e.g. "resync=DELAYED" or "resync=PENDING"
+	pendingRegexp = regexp.MustCompile(`(\w+)=(DELAYED|PENDING)`)
+)
+
+// parse parses the contents of /proc/mdstat.
+func parse(data []byte) []Array {
+	var arrays []Array
+	var a *Array
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			a = nil
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			a = nil
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[1] != ":" || !strings.HasPrefix(fields[0], "md") {
+				continue
+			}
+			arrays = append(arrays, parseHeader(fields[0], fields[2:]))
+			a = &arrays[len(arrays)-1]
+			continue
+		}
+		if a == nil {
+			continue
+		}
+		if match := statusRegexp.FindStringSubmatch(line); match != nil {
+			a.Total, _ = strconv.Atoi(match[1])
+			a.Active, _ = strconv.Atoi(match[2])
+			a.Status = match[3]
+		} else if match := actionRegexp.FindStringSubmatch(line); match != nil {
+			a.Action = match[1]
+			a.Progress, _ = strconv.ParseFloat(match[2], 64)
+			minutes, _ := strconv.ParseFloat(match[3], 64)
+			a.Finish = time.Duration(minutes * float64(time.Minute))
+		} else if match := pendingRegexp.FindStringSubmatch(line); match != nil {
+			a.Action = match[1]
+		}
+	}
+	return arrays
+}
+
+// parseHeader parses the first line of an array, e.g.
+// "md1 : active raid5 sdc1[2] sdb1[1](F) sda1[0]", after the colon.
+func parseHeader(name string, fields []string) Array {
+	a := Array{Name: name, State: fields[0]}
+	fields = fields[1:]
+	// Qualifiers like "(auto-read-only)" are part of the state.
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "(") {
+		a.State += " " + fields[0]
+		fields = fields[1:]
+	}
+	// Inactive arrays do not list a level.
+	if len(fields) > 0 && !strings.Contains(fields[0], "[") {
+		a.Level = fields[0]
+		fields = fields[1:]
+	}
+	for _, f := range fields {
+		idx := strings.Index(f, "[")
+		if idx < 0 {
+			continue
+		}
+		dev := f[:idx]
+		a.Devices = append(a.Devices, dev)
+		if strings.HasSuffix(f, "(F)") {
+			a.Failed = append(a.Failed, dev)
+		}
+	}
+	return a
+}
+
+func (m *module) update() {
+	data, err := afero.ReadFile(fs, "/proc/mdstat")
+	if m.Error(err) {
+		return
+	}
+	info := Info{}
+	for _, a := range parse(data) {
+		if m.includes(a.Name) {
+			info.Arrays = append(info.Arrays, a)
+		}
+	}
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
+
+func (m *module) includes(name string) bool {
+	if len(m.arrays) == 0 {
+		return true
+	}
+	for _, a := range m.arrays {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mdstat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+const healthy = `Personalities : [raid1] [raid6] [raid5] [raid4]
+md0 : active raid1 sdb1[1] sda1[0]
+      1953382400 blocks super 1.2 [2/2] [UU]
+      bitmap: 0/15 pages [0KB], 65536KB chunk
+
+md1 : active raid5 sde1[2] sdd1[1] sdc1[0]
+      586078208 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/3] [UUU]
+
+unused devices: <none>
+`
+
+const degraded = `Personalities : [raid1] [raid6] [raid5] [raid4]
+md0 : active raid1 sdb1[1] sda1[0]
+      1953382400 blocks super 1.2 [2/2] [UU]
+      [=====>...............]  check = 25.3% (494240128/1953382400) finish=120.5min speed=201776K/sec
+      bitmap: 0/15 pages [0KB], 65536KB chunk
+
+md1 : active raid5 sdf1[3] sde1[2] sdd1[1](F) sdc1[0]
+      586078208 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [U_U]
+      [==>..................]  recovery = 12.6% (37043392/293039104) finish=127.5min speed=33440K/sec
+
+md2 : inactive sdg1[0](S)
+      976630488 blocks super 1.2
+
+md3 : active (auto-read-only) raid1 sdh1[0] sdi1[1]
+      976630488 blocks super 1.2 [2/2] [UU]
+      	resync=PENDING
+
+unused devices: <none>
+`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	arrays := parse([]byte(degraded))
+	assert.Equal([]Array{
+		{Name: "md0", State: "active", Level: "raid1", Devices: []string{"sdb1", "sda1"},
+			Total: 2, Active: 2, Status: "UU",
+			Action: "check", Progress: 25.3, Finish: 120*time.Minute + 30*time.Second},
+		{Name: "md1", State: "active", Level: "raid5",
+			Devices: []string{"sdf1", "sde1", "sdd1", "sdc1"}, Failed: []string{"sdd1"},
+			Total: 3, Active: 2, Status: "U_U",
+			Action: "recovery", Progress: 12.6, Finish: 127*time.Minute + 30*time.Second},
+		{Name: "md2", State: "inactive", Devices: []string{"sdg1"}},
+		{Name: "md3", State: "active (auto-read-only)", Level: "raid1",
+			Devices: []string{"sdh1", "sdi1"}, Total: 2, Active: 2, Status: "UU",
+			Action: "resync"},
+	}, arrays)
+	assert.False(arrays[0].Degraded())
+	assert.True(arrays[1].Degraded())
+	assert.True(arrays[0].Syncing())
+
+	assert.Empty(parse([]byte("Personalities : \nunused devices: <none>\n")))
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "/proc/mdstat", []byte(healthy), 0444)
+
+	m := New()
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("on start")
+	assert.Equal("md0 [UU] md1 [UUU]", out[0].Text())
+	assert.Equal(false, out[0]["urgent"])
+
+	afero.WriteFile(fs, "/proc/mdstat", []byte(degraded), 0444)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("md0 [UU] check 25.3% md1 [U_U] recovery 12.6% md2 inactive md3 [UU] resync 0.0%",
+		out[0].Text())
+	assert.Equal(true, out[0]["urgent"], "degraded array")
+
+	var info Info
+	m.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Text(i.Arrays[0].Status)
+	})
+	out = tester.AssertOutput("on output func change")
+	assert.Equal("UU", out[0].Text())
+	assert.True(info.Degraded())
+	assert.True(info.Syncing())
+
+	m.UrgentWhen(nil)
+	out = tester.AssertOutput("on urgent func change")
+	_, ok := out[0]["urgent"]
+	assert.False(ok)
+
+	tester = testModule.NewOutputTester(t, New("md0"))
+	out = tester.AssertOutput("on start")
+	assert.Equal("md0 [UU] check 25.3%", out[0].Text())
+	assert.Equal(false, out[0]["urgent"], "other arrays are not monitored")
+
+	fs.Remove("/proc/mdstat")
+	scheduler.NextTick()
+	tester.AssertError("without /proc/mdstat")
+}
@@ -186,7 +186,7 @@ func New(player string) Module {
 	// Set default click handler in New(), can be overridden later.
 	m.OnClick(DefaultClickHandler)
 	// Default output template that's just the currently playing track.
-	m.OutputTemplate(outputs.TextTemplate(`{{if .Connected}}{{.Title}}{{end}}`))
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Connected}}{{.Title | isolate}}{{end}}`))
 	// Set the position scheduler to call update when triggered.
 	m.positionScheduler = scheduler.Do(m.Update)
 	return m
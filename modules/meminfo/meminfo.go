@@ -17,7 +17,6 @@ package meminfo
 
 import (
 	"bufio"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -26,8 +25,10 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
 	"github.com/soumya92/barista/base/multi"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/format"
 )
 
 // Info wraps meminfo output.
@@ -71,12 +72,12 @@ func (b Bytes) In(unit string) float64 {
 
 // IEC returns the size formatted in base 2.
 func (b Bytes) IEC() string {
-	return humanize.IBytes(uint64(b))
+	return format.IBytes(uint64(b))
 }
 
 // SI returns the size formatted in base 10.
 func (b Bytes) SI() string {
-	return humanize.Bytes(uint64(b))
+	return format.Bytes(uint64(b))
 }
 
 // Module represents a meminfo multi-module, and provides an interface
@@ -119,9 +120,11 @@ func (m *Module) OutputTemplate(template func(interface{}) bar.Output) base.With
 	return m.OutputFunc(func(i Info) bar.Output { return template(i) })
 }
 
+var fs = hostfs.Fs()
+
 func (m *Module) update() {
 	i := make(Info)
-	f, err := os.Open("/proc/meminfo")
+	f, err := fs.Open("/proc/meminfo")
 	if m.moduleSet.Error(err) {
 		return
 	}
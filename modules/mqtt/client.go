@@ -0,0 +1,291 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soumya92/barista/timing"
+)
+
+// Packet types, in the high nibble of the fixed header.
+const (
+	connect   = 0x10
+	connack   = 0x20
+	publish   = 0x30
+	puback    = 0x40
+	subscribe = 0x82 // includes the required flags.
+	suback    = 0x90
+	pingreq   = 0xC0
+	pingresp  = 0xD0
+)
+
+// keepAlive is the interval at which the broker expects packets from the
+// client. Pings are sent at half this interval.
+var keepAlive = 60 * time.Second
+
+// clientCount distinguishes multiple modules in the same process,
+// since brokers disconnect clients with duplicate IDs.
+var clientCount int32
+
+// writePacket writes an MQTT packet with the given fixed header byte.
+func writePacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	// The remaining length is encoded 7 bits at a time, least significant first.
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if length == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// readPacket reads an MQTT packet, returning the fixed header byte and body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7F) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+// appendString appends a length-prefixed string.
+func appendString(b []byte, s string) []byte {
+	return append(append(b, byte(len(s)>>8), byte(len(s))), s...)
+}
+
+// readString reads a length-prefixed string, and returns it along with
+// the remaining bytes.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("mqtt: short packet")
+	}
+	length := int(b[0])<<8 | int(b[1])
+	if len(b) < 2+length {
+		return "", nil, errors.New("mqtt: short packet")
+	}
+	return string(b[2 : 2+length]), b[2+length:], nil
+}
+
+// connectErrors are the reasons for a refused connection, by return code.
+var connectErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// client is a minimal MQTT 3.1.1 client, which only supports subscribing.
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// writes from the ping goroutine and acknowledgements must not interleave.
+	writeMu sync.Mutex
+	done    chan struct{}
+}
+
+// dial connects to an MQTT broker, given as "host:port", "tcp://host:port",
+// or "ssl://host:port" for a TLS connection.
+func dial(broker, username, password string) (*client, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		u = &url.URL{Scheme: "tcp", Host: broker}
+	}
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = net.Dial("tcp", hostPort(u.Host, "1883"))
+	case "ssl", "tls", "mqtts":
+		conn, err = tls.Dial("tcp", hostPort(u.Host, "8883"), nil)
+	default:
+		return nil, fmt.Errorf("mqtt: unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &client{conn: conn, reader: bufio.NewReader(conn), done: make(chan struct{})}
+	if err := c.connect(username, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.ping()
+	return c, nil
+}
+
+func hostPort(host, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, defaultPort)
+}
+
+func (c *client) connect(username, password string) error {
+	body := appendString(nil, "MQTT")
+	// Protocol level 4 is MQTT 3.1.1. Always use a clean session, since
+	// only the latest messages are of interest.
+	flags := byte(0x02)
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+	secs := int(keepAlive / time.Second)
+	body = append(body, 4, flags, byte(secs>>8), byte(secs))
+	id := fmt.Sprintf("barista-%d-%d", os.Getpid(), atomic.AddInt32(&clientCount, 1))
+	body = appendString(body, id)
+	if username != "" {
+		body = appendString(body, username)
+	}
+	if password != "" {
+		body = appendString(body, password)
+	}
+	if err := c.write(connect, body); err != nil {
+		return err
+	}
+	header, resp, err := c.read()
+	if err != nil {
+		return err
+	}
+	if header&0xF0 != connack || len(resp) != 2 {
+		return errors.New("mqtt: expected CONNACK")
+	}
+	if code := resp[1]; code != 0 {
+		if reason, ok := connectErrors[code]; ok {
+			return fmt.Errorf("mqtt: connection refused: %s", reason)
+		}
+		return fmt.Errorf("mqtt: connection refused: code %d", code)
+	}
+	return nil
+}
+
+// subscribe subscribes to the given topics, which may include wildcards.
+// The SUBACK is handled by next, so that retained messages that arrive
+// first are not lost.
+func (c *client) subscribe(topics []string) error {
+	// Packet identifier, which must be non-zero.
+	body := []byte{0, 1}
+	for _, t := range topics {
+		// Only the latest value matters, so QoS 0 is sufficient.
+		body = append(appendString(body, t), 0)
+	}
+	return c.write(subscribe, body)
+}
+
+// next returns the topic and payload of the next published message.
+func (c *client) next() (string, []byte, error) {
+	for {
+		header, body, err := c.read()
+		if err != nil {
+			return "", nil, err
+		}
+		switch header & 0xF0 {
+		case publish:
+			topic, rest, err := readString(body)
+			if err != nil {
+				return "", nil, err
+			}
+			if qos := (header >> 1) & 3; qos > 0 {
+				if len(rest) < 2 {
+					return "", nil, errors.New("mqtt: short packet")
+				}
+				// Brokers only downgrade, but acknowledge anyway to be safe.
+				if qos == 1 {
+					c.write(puback, rest[:2])
+				}
+				rest = rest[2:]
+			}
+			return topic, rest, nil
+		case suback:
+			if len(body) < 2 {
+				return "", nil, errors.New("mqtt: short packet")
+			}
+			for _, code := range body[2:] {
+				if code == 0x80 {
+					return "", nil, errors.New("mqtt: subscription rejected")
+				}
+			}
+		}
+	}
+}
+
+func (c *client) read() (byte, []byte, error) {
+	// The broker responds to pings, so a connection that is silent for
+	// longer than the keep alive interval is dead.
+	c.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+	return readPacket(c.reader)
+}
+
+func (c *client) write(header byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writePacket(c.conn, header, body)
+}
+
+func (c *client) ping() {
+	t := timing.Repeat(keepAlive / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if c.write(pingreq, nil) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *client) close() {
+	close(c.done)
+	c.conn.Close()
+}
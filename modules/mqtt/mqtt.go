@@ -0,0 +1,217 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package mqtt provides an i3bar module that shows messages from MQTT topics.
+
+The module connects to an MQTT broker, subscribes to the given topics (which
+may include the "+" and "#" wildcards), and keeps the latest message on each
+topic, which is useful for showing sensor data or home automation state on the
+bar. By default the payload of the latest message is shown, but templates can
+show the payload of specific topics, e.g.
+
+	{{.Get "home/livingroom/temperature"}}°C
+
+and JSON payloads can be accessed using e.g. {{.Latest.JSON.temperature}}.
+The connection is re-established automatically if it is lost.
+*/
+package mqtt
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Message represents a message published to a topic.
+type Message struct {
+	Topic    string
+	Payload  string
+	Received time.Time
+}
+
+// JSON returns the payload decoded as JSON, or nil if it is not valid JSON.
+func (m Message) JSON() interface{} {
+	var value interface{}
+	if json.Unmarshal([]byte(m.Payload), &value) != nil {
+		return nil
+	}
+	return value
+}
+
+// Info represents the latest messages received.
+type Info struct {
+	// Latest is the most recently received message on any topic.
+	Latest Message
+	// Messages are the latest messages by topic. For wildcard subscriptions,
+	// the topics are those of the messages received, not the wildcards.
+	Messages map[string]Message
+}
+
+// Get returns the payload of the latest message on a topic, or an empty
+// string if no messages have been received on that topic.
+func (i Info) Get(topic string) string {
+	return i.Messages[topic].Payload
+}
+
+// Module is the public interface for an mqtt module.
+type Module interface {
+	base.WithClickHandler
+
+	// Auth sets the credentials used to connect to the broker, with a nil
+	// password for brokers that only require a user name.
+	// It must be called before the module is started.
+	Auth(username string, password secret.Secret) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	broker     string
+	topics     []string
+	username   string
+	password   secret.Secret
+	outputFunc func(Info) bar.Output
+	latest     Message
+	messages   map[string]Message
+	connErr    error
+}
+
+// New constructs an instance of the mqtt module that subscribes to the given
+// topics on a broker, given as "host[:port]", "tcp://host[:port]", or
+// "ssl://host[:port]" for a TLS connection.
+func New(broker string, topics ...string) Module {
+	m := &module{
+		Base:     base.New(),
+		broker:   broker,
+		topics:   topics,
+		messages: map[string]Message{},
+	}
+	// Default output template is the payload of the latest message.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Latest.Payload}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) Auth(username string, password secret.Secret) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.username = username
+	m.password = password
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+// Stream connects to the broker, and then returns the output channel
+// from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go m.listen()
+	return ch
+}
+
+// retryDelay is how long to wait before reconnecting to the broker.
+var retryDelay = 10 * time.Second
+
+func (m *module) listen() {
+	for {
+		err := m.receive()
+		// Show the error until the connection is re-established.
+		m.Lock()
+		m.connErr = err
+		m.Unlock()
+		m.Update()
+		time.Sleep(retryDelay)
+	}
+}
+
+// receive connects to the broker and updates the module for each message
+// received, until the connection fails.
+func (m *module) receive() error {
+	m.Lock()
+	username, passwordSecret := m.username, m.password
+	m.Unlock()
+	var password string
+	if passwordSecret != nil {
+		var err error
+		if password, err = passwordSecret.Get(); err != nil {
+			return err
+		}
+	}
+	c, err := dial(m.broker, username, password)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	if err := c.subscribe(m.topics); err != nil {
+		return err
+	}
+	m.Lock()
+	hadErr := m.connErr != nil
+	m.connErr = nil
+	m.Unlock()
+	if hadErr {
+		m.Update()
+	}
+	for {
+		topic, payload, err := c.next()
+		if err != nil {
+			return err
+		}
+		msg := Message{Topic: topic, Payload: string(payload), Received: scheduler.Now()}
+		m.Lock()
+		m.latest = msg
+		m.messages[topic] = msg
+		m.Unlock()
+		m.Update()
+	}
+}
+
+func (m *module) update() {
+	m.Lock()
+	if err := m.connErr; err != nil {
+		m.Unlock()
+		m.Error(err)
+		return
+	}
+	info := Info{Latest: m.latest, Messages: map[string]Message{}}
+	for topic, msg := range m.messages {
+		info.Messages[topic] = msg
+	}
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeBroker struct {
+	listener net.Listener
+	// connects receives the CONNECT body, and subscribes the SUBSCRIBE body.
+	connects   chan []byte
+	subscribes chan []byte
+	// returnCode is sent in the CONNACK.
+	returnCode byte
+	// conns receives each accepted connection, for sending messages.
+	conns chan net.Conn
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{
+		listener:   l,
+		connects:   make(chan []byte, 10),
+		subscribes: make(chan []byte, 10),
+		conns:      make(chan net.Conn, 10),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	_, body, err := readPacket(r)
+	if err != nil {
+		return
+	}
+	b.connects <- body
+	writePacket(conn, connack, []byte{0, b.returnCode})
+	if b.returnCode != 0 {
+		conn.Close()
+		return
+	}
+	_, body, err = readPacket(r)
+	if err != nil {
+		return
+	}
+	b.subscribes <- body
+	writePacket(conn, suback, []byte{0, 1, 0})
+	b.conns <- conn
+	for {
+		if _, _, err := readPacket(r); err != nil {
+			return
+		}
+	}
+}
+
+func publishTo(conn net.Conn, topic, payload string) {
+	writePacket(conn, publish, append(appendString(nil, topic), payload...))
+}
+
+func TestPackets(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	body := bytes.Repeat([]byte("x"), 200)
+	assert.NoError(writePacket(&buf, publish, body))
+	assert.Equal([]byte{publish, 0xC8, 0x01}, buf.Bytes()[:3], "multi-byte length")
+	header, read, err := readPacket(bufio.NewReader(&buf))
+	assert.NoError(err)
+	assert.Equal(byte(publish), header)
+	assert.Equal(body, read)
+
+	_, _, err = readPacket(bufio.NewReader(bytes.NewReader([]byte{publish, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})))
+	assert.Error(err, "malformed length")
+	_, _, err = readPacket(bufio.NewReader(bytes.NewReader([]byte{publish, 0x05, 0x01})))
+	assert.Error(err, "truncated body")
+
+	s, rest, err := readString(appendString([]byte{}, "topic/a"))
+	assert.NoError(err)
+	assert.Equal("topic/a", s)
+	assert.Empty(rest)
+	_, _, err = readString([]byte{0, 10, 'a'})
+	assert.Error(err)
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	retryDelay = 10 * time.Millisecond
+	b := newFakeBroker(t)
+	defer b.listener.Close()
+
+	m := New(b.listener.Addr().String(), "home/+/temperature", "home/door").
+		Auth("user", secret.Value("secret"))
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "no messages yet")
+
+	connect := <-b.connects
+	proto, rest, _ := readString(connect)
+	assert.Equal("MQTT", proto)
+	assert.Equal([]byte{4, 0xC2, 0, 60}, rest[:4], "level, flags, keep alive")
+	id, rest, _ := readString(rest[4:])
+	assert.Contains(id, "barista-")
+	user, rest, _ := readString(rest)
+	pass, _, _ := readString(rest)
+	assert.Equal("user", user)
+	assert.Equal("secret", pass)
+	assert.Equal(append(append(appendString([]byte{0, 1}, "home/+/temperature"), 0),
+		append(appendString(nil, "home/door"), 0)...), <-b.subscribes)
+
+	conn := <-b.conns
+	publishTo(conn, "home/kitchen/temperature", "21.5")
+	out = tester.AssertOutput("on message")
+	assert.Equal("21.5", out[0].Text())
+
+	// QoS 1, with a packet identifier.
+	writePacket(conn, publish|0x02, append(append(appendString(nil, "home/door"), 0, 7), "open"...))
+	out = tester.AssertOutput("on message")
+	assert.Equal("open", out[0].Text())
+
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{.Get "home/kitchen/temperature"}}°C {{.Get "home/door"}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal("21.5°C open", out[0].Text())
+
+	var mu sync.Mutex
+	var info Info
+	m.OutputFunc(func(i Info) bar.Output {
+		mu.Lock()
+		defer mu.Unlock()
+		info = i
+		return outputs.Text(i.Latest.Topic)
+	})
+	publishTo(conn, "home/garden/temperature", `{"temperature": 12.5, "battery": 80}`)
+	tester.AssertOutput("on output func change")
+	out = tester.AssertOutput("on message")
+	assert.Equal("home/garden/temperature", out[0].Text())
+	mu.Lock()
+	assert.Equal(3, len(info.Messages))
+	assert.Equal(12.5, info.Latest.JSON().(map[string]interface{})["temperature"])
+	assert.Nil(info.Messages["home/door"].JSON(), "not json")
+	assert.Equal("", info.Get("home/other"))
+	mu.Unlock()
+
+	conn.Close()
+	tester.AssertError("on disconnect")
+	<-b.connects
+	<-b.subscribes
+	conn = <-b.conns
+	tester.AssertOutput("on reconnect")
+	publishTo(conn, "home/door", "closed")
+	out = tester.AssertOutput("on message after reconnect")
+	assert.Equal("home/door", out[0].Text())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal("closed", info.Get("home/door"))
+	assert.Equal("21.5", info.Get("home/kitchen/temperature"), "messages are kept")
+}
+
+func TestConnectionRefused(t *testing.T) {
+	assert := assert.New(t)
+	b := newFakeBroker(t)
+	defer b.listener.Close()
+	b.returnCode = 4
+
+	tester := testModule.NewOutputTester(t, New("tcp://"+b.listener.Addr().String(), "a"))
+	tester.AssertOutput("on start")
+	connect := <-b.connects
+	assert.Equal(byte(0x02), connect[7], "no credentials")
+	tester.AssertError("on connection refused")
+
+	tester = testModule.NewOutputTester(t, New("ws://localhost", "a"))
+	tester.AssertOutput("on start")
+	tester.AssertError("unsupported scheme")
+}
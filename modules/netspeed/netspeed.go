@@ -23,6 +23,7 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/format"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -40,12 +41,12 @@ func (s Speed) In(unit string) float64 {
 
 // IEC returns the speed formatted in base 2.
 func (s Speed) IEC() string {
-	return humanize.IBytes(uint64(s))
+	return format.IBytes(uint64(s))
 }
 
 // SI returns the speed formatted in base 10.
 func (s Speed) SI() string {
-	return humanize.Bytes(uint64(s))
+	return format.Bytes(uint64(s))
 }
 
 // Speeds represents bidirectional network traffic.
@@ -0,0 +1,249 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package nightlight provides an i3bar module that shows the status of a screen
+color temperature shifter, either redshift or gammastep.
+
+The running shifter is found by looking through /proc, and the current period
+and color temperature are obtained using the shifter's print mode (-p), which
+computes the values without changing the screen. Clicking the module sends
+SIGUSR1 to the shifter, which toggles it on and off. Gammastep is a fork of
+redshift, and supports the same signal.
+
+Shifters do not report whether they have been toggled off, so the module
+assumes that a newly started shifter is enabled, and keeps track of the
+toggles it sends. Toggling the shifter by other means will not be reflected.
+*/
+package nightlight
+
+import (
+	"bufio"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Programs is the list of supported shifters, in order of preference.
+var Programs = []string{"redshift", "gammastep"}
+
+// Info represents the status of the color temperature shifter.
+type Info struct {
+	// Program is the name of the running shifter, if any.
+	Program string
+	// Enabled is true unless the shifter has been toggled off.
+	Enabled bool
+	// Period is the current period, e.g. "Daytime", "Night", "Transition".
+	Period string
+	// Temperature is the current color temperature, in Kelvin.
+	Temperature int
+}
+
+// Running returns true if a shifter is running.
+func (i Info) Running() bool {
+	return i.Program != ""
+}
+
+// Active returns true if a shifter is running and enabled.
+func (i Info) Active() bool {
+	return i.Running() && i.Enabled
+}
+
+// Controller provides an interface to control the shifter from the click handler.
+type Controller interface {
+	// Toggle enables or disables the running shifter.
+	Toggle()
+}
+
+// Module is the public interface for a night light module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to toggle the shifter.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc func(Info) bar.Output
+	pid        int
+	info       Info
+}
+
+// New constructs an instance of the night light module.
+func New() Module {
+	m := &module{Base: base.New()}
+	m.RefreshInterval(time.Minute)
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template shows the temperature when active.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{if .Active}}{{.Temperature}}K{{else if .Running}}off{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler toggles the shifter on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Toggle()
+	}
+}
+
+var fs = hostfs.Fs()
+
+// signal sends a signal to the process with the given pid.
+var signal = func(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// printMode runs the shifter in print mode, which only computes
+// and prints the current period and temperature.
+var printMode = func(program string) (string, error) {
+	out, err := exec.Command(program, "-p").Output()
+	return string(out), err
+}
+
+func (m *module) Toggle() {
+	m.Lock()
+	pid := m.pid
+	m.Unlock()
+	if pid == 0 {
+		return
+	}
+	if m.Error(signal(pid, syscall.SIGUSR1)) {
+		return
+	}
+	m.Lock()
+	m.info.Enabled = !m.info.Enabled
+	m.Unlock()
+	m.Update()
+}
+
+// findShifter returns the pid and name of the running shifter, by looking
+// at the command names of all processes.
+func findShifter() (int, string) {
+	comms, _ := afero.Glob(fs, "/proc/*/comm")
+	for _, program := range Programs {
+		for _, file := range comms {
+			comm, err := afero.ReadFile(fs, file)
+			if err != nil || strings.TrimSpace(string(comm)) != program {
+				continue
+			}
+			pid, err := strconv.Atoi(filepath.Base(filepath.Dir(file)))
+			if err == nil {
+				return pid, program
+			}
+		}
+	}
+	return 0, ""
+}
+
+// parsePrintMode parses the output of print mode into the info.
+func parsePrintMode(out string, info *Info) {
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		parts := strings.SplitN(s.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch parts[0] {
+		case "Period":
+			// "Transition (54.64% day)" is reported as "Transition".
+			if fields := strings.Fields(value); len(fields) > 0 {
+				info.Period = fields[0]
+			}
+		case "Color temperature":
+			info.Temperature, _ = strconv.Atoi(strings.TrimSuffix(value, "K"))
+		}
+	}
+}
+
+func (m *module) update() {
+	pid, program := findShifter()
+	m.Lock()
+	info := m.info
+	if pid != m.pid {
+		// Newly started shifters are always enabled.
+		info = Info{Program: program, Enabled: true}
+		m.pid = pid
+	}
+	m.Unlock()
+	if info.Running() {
+		out, err := printMode(program)
+		if m.Error(err) {
+			return
+		}
+		parsePrintMode(out, &info)
+	}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nightlight
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestParsePrintMode(t *testing.T) {
+	var info Info
+	parsePrintMode(`Location: 52.37 N, 4.90 E
+Period: Transition (54.64% day)
+Color temperature: 4812K
+Brightness: 1.00
+`, &info)
+	assert.Equal(t, Info{Period: "Transition", Temperature: 4812}, info)
+}
+
+func TestNightLight(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "/proc/1/comm", []byte("init\n"), 0644)
+	afero.WriteFile(fs, "/proc/42/comm", []byte("bash\n"), 0644)
+
+	temperature := "3500K"
+	printMode = func(program string) (string, error) {
+		return "Period: Night\nColor temperature: " + temperature + "\n", nil
+	}
+	signals := make(chan int, 10)
+	signal = func(pid int, sig syscall.Signal) error {
+		assert.Equal(syscall.SIGUSR1, sig)
+		signals <- pid
+		return nil
+	}
+
+	n := New()
+	tester := testModule.NewOutputTester(t, n)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "when no shifter is running")
+
+	afero.WriteFile(fs, "/proc/100/comm", []byte("gammastep\n"), 0644)
+	scheduler.NextTick()
+	out = tester.AssertOutput("when shifter starts")
+	assert.Equal("3500K", out[0].Text())
+
+	n.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal(100, <-signals)
+	out = tester.AssertOutput("on toggle")
+	assert.Equal("off", out[0].Text())
+
+	temperature = "3200K"
+	n.OutputTemplate(func(i interface{}) bar.Output {
+		info := i.(Info)
+		return bar.Output{bar.NewSegment(info.Program + " " + info.Period)}
+	})
+	out = tester.AssertOutput("on template change")
+	assert.Equal("gammastep Night", out[0].Text())
+
+	fs.Remove("/proc/100/comm")
+	afero.WriteFile(fs, "/proc/200/comm", []byte("redshift\n"), 0644)
+	n.OutputTemplate(func(i interface{}) bar.Output {
+		info := i.(Info)
+		if !info.Active() {
+			return bar.Output{bar.NewSegment("inactive")}
+		}
+		return bar.Output{bar.NewSegment(info.Program)}
+	})
+	out = tester.AssertOutput("when shifter restarts")
+	assert.Equal("redshift", out[0].Text(), "new shifter is enabled")
+
+	n.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal(200, <-signals)
+	tester.AssertOutput("on toggle")
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertOutput("on refresh")
+}
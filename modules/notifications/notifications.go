@@ -0,0 +1,232 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package notifications provides an i3bar module that counts desktop notifications.
+
+It monitors the session bus for calls to org.freedesktop.Notifications.Notify,
+so it works with any notification daemon. By default all notifications are
+counted, but a condition can be provided to only count notifications received,
+for example, while do-not-disturb is enabled or while away from the computer.
+The default click handler clears the count.
+*/
+package notifications
+
+import (
+	"github.com/godbus/dbus"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Notification represents a single desktop notification.
+type Notification struct {
+	AppName string
+	Summary string
+	Body    string
+}
+
+// Info represents the notifications counted since the last clear.
+type Info struct {
+	Count int
+	// Last is the most recently counted notification.
+	Last Notification
+}
+
+// Controller provides an interface to clear the count from the click handler.
+type Controller interface {
+	// Clear resets the notification count.
+	Clear()
+}
+
+// Module is the public interface for a notifications module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to clear the count.
+type Module interface {
+	base.Module
+
+	// CountWhen sets a condition for counting notifications. Notifications
+	// received while the condition returns false are ignored.
+	CountWhen(func() bool) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	countWhen  func() bool
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the notifications module.
+func New() Module {
+	m := &module{Base: base.New()}
+	m.CountWhen(func() bool { return true })
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the count, only if there are notifications.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Count}}{{.Count}} new{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) CountWhen(countWhen func() bool) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.countWhen = countWhen
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler clears the count on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Clear()
+	}
+}
+
+func (m *module) Clear() {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.info = Info{}
+}
+
+const (
+	notifyIface  = "org.freedesktop.Notifications"
+	notifyMember = "Notify"
+	matchRule    = "type='method_call',interface='" + notifyIface + "',member='" + notifyMember + "'"
+)
+
+// monitor sets up a private session bus connection that
+// receives all calls to the notification daemon's Notify method.
+var monitor = func() (<-chan *dbus.Message, error) {
+	// Monitoring turns the connection into a receive-only connection,
+	// so a private connection is required.
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		return nil, err
+	}
+	// Prefer BecomeMonitor, but fall back to the deprecated eavesdropping
+	// for older buses that don't support monitoring.
+	err = conn.BusObject().Call("org.freedesktop.DBus.Monitoring.BecomeMonitor", 0,
+		[]string{matchRule}, uint32(0)).Err
+	if err != nil {
+		err = conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0,
+			matchRule+",eavesdrop='true'").Err
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan *dbus.Message, 10)
+	conn.Eavesdrop(c)
+	return c, nil
+}
+
+// Stream sets up d-bus monitoring and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	c, err := monitor()
+	if m.Error(err) {
+		return ch
+	}
+	go m.listen(c)
+	return ch
+}
+
+// listen counts notifications from the monitored messages.
+func (m *module) listen(c <-chan *dbus.Message) {
+	for msg := range c {
+		m.handle(msg)
+	}
+}
+
+func (m *module) handle(msg *dbus.Message) {
+	if msg.Type != dbus.TypeMethodCall {
+		return
+	}
+	if iface, _ := msg.Headers[dbus.FieldInterface].Value().(string); iface != notifyIface {
+		return
+	}
+	if member, _ := msg.Headers[dbus.FieldMember].Value().(string); member != notifyMember {
+		return
+	}
+	// Notify(app_name, replaces_id, app_icon, summary, body, ...).
+	if len(msg.Body) < 5 {
+		return
+	}
+	n := Notification{}
+	n.AppName, _ = msg.Body[0].(string)
+	n.Summary, _ = msg.Body[3].(string)
+	n.Body, _ = msg.Body[4].(string)
+	m.Lock()
+	countWhen := m.countWhen
+	m.Unlock()
+	if !countWhen() {
+		return
+	}
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.info.Count++
+	m.info.Last = n
+}
+
+func (m *module) update() {
+	m.Lock()
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
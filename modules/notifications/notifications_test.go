@@ -0,0 +1,86 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func notify(member string, body ...interface{}) *dbus.Message {
+	return &dbus.Message{
+		Type: dbus.TypeMethodCall,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldInterface: dbus.MakeVariant(notifyIface),
+			dbus.FieldMember:    dbus.MakeVariant(member),
+		},
+		Body: body,
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	assert := assert.New(t)
+	messages := make(chan *dbus.Message)
+	monitor = func() (<-chan *dbus.Message, error) {
+		return messages, nil
+	}
+	n := New()
+	tester := testModule.NewOutputTester(t, n)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text())
+
+	messages <- notify("Notify", "mail", uint32(0), "", "New mail", "Hello")
+	out = tester.AssertOutput("on notification")
+	assert.Equal("1 new", out[0].Text())
+
+	messages <- notify("CloseNotification", uint32(1))
+	messages <- notify("Notify", "short")
+	tester.AssertNoOutput("on other method calls")
+
+	away := true
+	n.CountWhen(func() bool { return away })
+	messages <- notify("Notify", "chat", uint32(0), "", "Ping", "Are you there?")
+	tester.AssertOutput("on notification")
+
+	away = false
+	messages <- notify("Notify", "chat", uint32(0), "", "Ping", "Hello?")
+	tester.AssertNoOutput("when condition is false")
+
+	var info Info
+	n.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(2, info.Count)
+	assert.Equal(Notification{"chat", "Ping", "Are you there?"}, info.Last)
+
+	n.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertOutput("on click")
+	assert.Equal(0, info.Count, "click clears the count")
+
+	monitor = func() (<-chan *dbus.Message, error) {
+		return nil, errors.New("no session bus")
+	}
+	tester = testModule.NewOutputTester(t, New())
+	tester.AssertError("on monitoring error")
+}
@@ -0,0 +1,201 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package peripherals provides an i3bar module that shows the battery levels of
+wireless peripherals, such as mice, keyboards, headsets, and styluses.
+
+Devices are enumerated from UPower on the system bus, and changes (including
+devices connecting and disconnecting) are picked up immediately from signals.
+System batteries and line power are not included (see the battery module), and
+devices that do not report their charge are hidden. By default, each device is
+shown as a separate segment, e.g. "MX Master 3 45%", which is urgent when the
+device is low on charge.
+*/
+package peripherals
+
+import (
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// DeviceType is the type of a device, as reported by UPower.
+type DeviceType uint32
+
+// Device types of interest. UPower defines more types, which are
+// shown as "device".
+const (
+	Mouse      DeviceType = 5
+	Keyboard   DeviceType = 6
+	Phone      DeviceType = 8
+	Tablet     DeviceType = 10
+	Gamepad    DeviceType = 12
+	Pen        DeviceType = 13
+	Touchpad   DeviceType = 14
+	Headset    DeviceType = 17
+	Speakers   DeviceType = 18
+	Headphones DeviceType = 19
+	Remote     DeviceType = 22
+	Wearable   DeviceType = 26
+)
+
+var typeNames = map[DeviceType]string{
+	Mouse:      "mouse",
+	Keyboard:   "keyboard",
+	Phone:      "phone",
+	Tablet:     "tablet",
+	Gamepad:    "gamepad",
+	Pen:        "pen",
+	Touchpad:   "touchpad",
+	Headset:    "headset",
+	Speakers:   "speakers",
+	Headphones: "headphones",
+	Remote:     "remote",
+	Wearable:   "wearable",
+}
+
+func (t DeviceType) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "device"
+}
+
+// Device represents a peripheral with a battery.
+type Device struct {
+	// ID is the UPower object path of the device.
+	ID     string
+	Model  string
+	Vendor string
+	Type   DeviceType
+	// Percentage is the remaining charge. Devices that only report coarse
+	// levels (e.g. "low", "high") are given an approximate percentage.
+	Percentage float64
+	// Charging is true while the device is charging or fully charged.
+	Charging bool
+}
+
+// Name returns the model of the device, or its type if the model is unknown.
+func (d Device) Name() string {
+	if d.Model != "" {
+		return d.Model
+	}
+	return d.Type.String()
+}
+
+// Low returns true if the device has 10% charge or less, and is not charging.
+func (d Device) Low() bool {
+	return d.Percentage <= 10 && !d.Charging
+}
+
+// Info represents the peripherals with batteries, sorted by name.
+type Info []Device
+
+// Low returns true if any device is low on charge.
+func (i Info) Low() bool {
+	for _, d := range i {
+		if d.Low() {
+			return true
+		}
+	}
+	return false
+}
+
+// Module is the public interface for a peripherals module.
+type Module interface {
+	base.WithClickHandler
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+// backend lists peripheral devices.
+type backend interface {
+	devices() ([]Device, error)
+	// watch calls the given function whenever the devices change.
+	// It only returns on error.
+	watch(func()) error
+}
+
+type module struct {
+	*base.Base
+	backend    backend
+	outputFunc func(Info) bar.Output
+}
+
+// New constructs an instance of the peripherals module.
+func New() Module {
+	return newModule(&upower{})
+}
+
+func newModule(b backend) *module {
+	m := &module{
+		Base:    base.New(),
+		backend: b,
+	}
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows each device as a segment with its charge, using the
+// device ID as the instance. Devices low on charge are marked urgent.
+func DefaultOutput(i Info) bar.Output {
+	out := outputs.Multi()
+	for _, d := range i {
+		segment := outputs.Textf("%s %.0f%%", d.Name(), d.Percentage)
+		if d.Low() {
+			segment.Urgent(true)
+		}
+		out.Add(d.ID, segment)
+	}
+	return out.KeepSeparators(true).Build()
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+// Stream starts watching for changes, and then returns the output channel
+// from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go func() { m.Error(m.backend.watch(m.Update)) }()
+	return ch
+}
+
+func (m *module) update() {
+	devices, err := m.backend.devices()
+	if m.Error(err) {
+		return
+	}
+	info := Info(devices)
+	m.Lock()
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
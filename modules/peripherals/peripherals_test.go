@@ -0,0 +1,140 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peripherals
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestDeviceFromProps(t *testing.T) {
+	assert := assert.New(t)
+	v := dbus.MakeVariant
+
+	d, ok := deviceFromProps("/org/freedesktop/UPower/devices/mouse_hidpp_battery_0",
+		map[string]dbus.Variant{
+			"Model": v("MX Master 3"), "Vendor": v("Logitech"), "Type": v(uint32(5)),
+			"PowerSupply": v(false), "Percentage": v(45.0), "State": v(uint32(2)),
+			"BatteryLevel": v(uint32(1)),
+		})
+	assert.True(ok)
+	assert.Equal(Device{
+		ID:         "/org/freedesktop/UPower/devices/mouse_hidpp_battery_0",
+		Model:      "MX Master 3",
+		Vendor:     "Logitech",
+		Type:       Mouse,
+		Percentage: 45,
+	}, d)
+
+	d, ok = deviceFromProps("/org/freedesktop/UPower/devices/keyboard_0",
+		map[string]dbus.Variant{
+			"Type": v(uint32(6)), "Percentage": v(0.0), "BatteryLevel": v(uint32(7)),
+			"State": v(uint32(1)),
+		})
+	assert.True(ok, "coarse battery level")
+	assert.Equal(80.0, d.Percentage)
+	assert.True(d.Charging)
+	assert.Equal("keyboard", d.Name())
+
+	_, ok = deviceFromProps("/org/freedesktop/UPower/devices/battery_BAT0",
+		map[string]dbus.Variant{"Type": v(uint32(2)), "PowerSupply": v(true), "Percentage": v(80.0)})
+	assert.False(ok, "system battery")
+	_, ok = deviceFromProps("/org/freedesktop/UPower/devices/line_power_AC",
+		map[string]dbus.Variant{"Type": v(uint32(1)), "PowerSupply": v(true)})
+	assert.False(ok, "line power")
+	_, ok = deviceFromProps("/org/freedesktop/UPower/devices/headset_dev_00",
+		map[string]dbus.Variant{"Type": v(uint32(17)), "Percentage": v(0.0)})
+	assert.False(ok, "no charge reported")
+
+	assert.Equal("device", DeviceType(28).String())
+	assert.Equal("headset", Headset.String())
+}
+
+type fakeBackend struct {
+	sync.Mutex
+	list    []Device
+	err     error
+	changes chan struct{}
+}
+
+func (f *fakeBackend) devices() ([]Device, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.list, f.err
+}
+
+func (f *fakeBackend) watch(update func()) error {
+	for range f.changes {
+		update()
+	}
+	return errors.New("upower stopped")
+}
+
+func (f *fakeBackend) set(devices ...Device) {
+	f.Lock()
+	f.list = devices
+	f.Unlock()
+	f.changes <- struct{}{}
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	b := &fakeBackend{changes: make(chan struct{}, 10)}
+	b.list = []Device{
+		{ID: "/mouse", Model: "MX Master 3", Type: Mouse, Percentage: 45},
+		{ID: "/pen", Type: Pen, Percentage: 8},
+	}
+	p := newModule(b)
+	tester := testModule.NewOutputTester(t, p)
+	out := tester.AssertOutput("on start")
+	assert.Equal(2, len(out))
+	assert.Equal(bar.NewSegment("MX Master 3 45%").Instance("/mouse"), out[0])
+	assert.Equal(bar.NewSegment("pen 8%").Instance("/pen").Urgent(true), out[1])
+
+	b.set(Device{ID: "/pen", Type: Pen, Percentage: 8, Charging: true})
+	out = tester.AssertOutput("on device change")
+	assert.Equal(1, len(out))
+	assert.Equal(bar.NewSegment("pen 8%").Instance("/pen"), out[0], "not urgent while charging")
+
+	var info Info
+	p.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Textf("%d", len(i))
+	})
+	tester.AssertOutput("on output func change")
+	assert.False(info.Low())
+	assert.True(Info{{Percentage: 5}}.Low())
+
+	b.set()
+	out = tester.AssertOutput("on all devices removed")
+	assert.Equal("0", out[0].Text())
+
+	b.Lock()
+	b.err = errors.New("upower not running")
+	b.Unlock()
+	b.changes <- struct{}{}
+	tester.AssertError("on backend error")
+
+	close(b.changes)
+	tester.AssertError("on watch error")
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peripherals
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	upowerDest  = "org.freedesktop.UPower"
+	upowerPath  = "/org/freedesktop/UPower"
+	deviceIface = upowerDest + ".Device"
+	propsIface  = "org.freedesktop.DBus.Properties"
+)
+
+// UPower device types and states that are handled specially.
+const (
+	typeLinePower = 1
+	stateCharging = 1
+	stateFull     = 4
+)
+
+// coarseLevels are approximate percentages for devices that report a
+// BatteryLevel instead of a percentage. Level 1 means the device reports
+// a percentage, and levels 0 (unknown) and 2 (deprecated) are ignored.
+var coarseLevels = map[uint32]float64{
+	3: 10,  // low
+	4: 5,   // critical
+	6: 55,  // normal
+	7: 80,  // high
+	8: 100, // full
+}
+
+type upower struct {
+	sync.Mutex
+	conn *dbus.Conn
+}
+
+// connection returns the system bus connection, connecting if necessary.
+func (u *upower) connection() (*dbus.Conn, error) {
+	u.Lock()
+	defer u.Unlock()
+	if u.conn != nil {
+		return u.conn, nil
+	}
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	u.conn = conn
+	return conn, nil
+}
+
+func (u *upower) devices() ([]Device, error) {
+	conn, err := u.connection()
+	if err != nil {
+		return nil, err
+	}
+	var paths []dbus.ObjectPath
+	err = conn.Object(upowerDest, upowerPath).
+		Call(upowerDest+".EnumerateDevices", 0).Store(&paths)
+	if err != nil {
+		return nil, err
+	}
+	var devices []Device
+	for _, path := range paths {
+		var props map[string]dbus.Variant
+		err := conn.Object(upowerDest, path).
+			Call(propsIface+".GetAll", 0, deviceIface).Store(&props)
+		if err != nil {
+			// The device may have been removed since it was enumerated.
+			continue
+		}
+		if d, ok := deviceFromProps(path, props); ok {
+			devices = append(devices, d)
+		}
+	}
+	sort.Slice(devices, func(a, b int) bool {
+		if devices[a].Name() != devices[b].Name() {
+			return devices[a].Name() < devices[b].Name()
+		}
+		return devices[a].ID < devices[b].ID
+	})
+	return devices, nil
+}
+
+// deviceFromProps returns the peripheral for a UPower device, and false if
+// the device is not a peripheral or does not report its charge.
+func deviceFromProps(path dbus.ObjectPath, props map[string]dbus.Variant) (Device, bool) {
+	// PowerSupply is set for batteries that power the system.
+	if supply, _ := props["PowerSupply"].Value().(bool); supply {
+		return Device{}, false
+	}
+	typ, _ := props["Type"].Value().(uint32)
+	if typ == typeLinePower {
+		return Device{}, false
+	}
+	d := Device{
+		ID:     string(path),
+		Model:  strings.TrimSpace(stringProp(props, "Model")),
+		Vendor: strings.TrimSpace(stringProp(props, "Vendor")),
+		Type:   DeviceType(typ),
+	}
+	level, _ := props["BatteryLevel"].Value().(uint32)
+	if approx, ok := coarseLevels[level]; ok {
+		d.Percentage = approx
+	} else {
+		d.Percentage, _ = props["Percentage"].Value().(float64)
+		// Disconnected devices and devices without batteries report 0%.
+		if d.Percentage == 0 {
+			return Device{}, false
+		}
+	}
+	state, _ := props["State"].Value().(uint32)
+	d.Charging = state == stateCharging || state == stateFull
+	return d, true
+}
+
+func stringProp(props map[string]dbus.Variant, name string) string {
+	val, _ := props[name].Value().(string)
+	return val
+}
+
+func (u *upower) watch(f func()) error {
+	// A private connection is required since we're using Signal.
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return err
+	}
+	if err := conn.Hello(); err != nil {
+		return err
+	}
+	// Devices being added or removed are signalled by UPower, while
+	// charge changes are signalled as property changes on each device.
+	matchRule := strings.Join([]string{
+		"type='signal'",
+		"path_namespace='" + upowerPath + "'",
+	}, ",")
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		return err
+	}
+	c := make(chan *dbus.Signal, 10)
+	conn.Signal(c)
+	for range c {
+		f()
+	}
+	return nil
+}
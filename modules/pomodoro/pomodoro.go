@@ -0,0 +1,408 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package pomodoro provides an i3bar module that implements the pomodoro technique.
+
+The module alternates between work and break phases, with a long break after
+a configurable number of work phases. Clicking it starts or stops the current
+phase, and right-clicking skips to the next phase. When a phase ends, the next
+phase is selected but not started, and the output is marked urgent until the
+user starts it.
+*/
+package pomodoro
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Phase represents a phase of the pomodoro cycle.
+type Phase int
+
+// Possible pomodoro phases.
+const (
+	Work Phase = iota
+	ShortBreak
+	LongBreak
+)
+
+func (p Phase) String() string {
+	switch p {
+	case Work:
+		return "Work"
+	case ShortBreak:
+		return "Break"
+	case LongBreak:
+		return "Long Break"
+	}
+	return "Unknown"
+}
+
+// IsBreak returns true for both short and long breaks.
+func (p Phase) IsBreak() bool {
+	return p == ShortBreak || p == LongBreak
+}
+
+// Info represents the current state of the pomodoro timer.
+type Info struct {
+	// Phase is the current phase.
+	Phase Phase
+	// Remaining is the time left in the current phase.
+	Remaining time.Duration
+	// Running is true if the current phase is in progress.
+	Running bool
+	// Waiting is true if a phase just ended and the next one
+	// has not yet been started.
+	Waiting bool
+	// Completed is the number of work phases completed so far.
+	Completed int
+}
+
+// Controller provides an interface to control the pomodoro timer,
+// used in the click handler.
+type Controller interface {
+	// Start starts (or resumes) the current phase.
+	Start()
+
+	// Stop stops the current phase, retaining the remaining time.
+	Stop()
+
+	// Toggle toggles between running and stopped.
+	Toggle()
+
+	// Skip ends the current phase immediately and moves to the next one.
+	// Skipped work phases do not count towards completed pomodoros.
+	Skip()
+
+	// Reset stops the timer and returns to the start of a work phase,
+	// clearing the count of completed pomodoros.
+	Reset()
+}
+
+// Module is the public interface for a pomodoro module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control the timer.
+type Module interface {
+	base.Module
+
+	// Durations sets the duration of work phases, short breaks, and long breaks.
+	Durations(work, shortBreak, longBreak time.Duration) Module
+
+	// LongBreakEvery sets the number of work phases before the long break.
+	LongBreakEvery(int) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OutputColor configures a module to change the colour of its output based on a
+	// user-defined function. By default, work phases use the 'bad' color and
+	// breaks use the 'good' color from the color scheme.
+	OutputColor(func(Info) bar.Color) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+
+	// OnPhaseEnd sets a function that will be called whenever a phase ends
+	// naturally (i.e. not skipped), with the phase that just ended.
+	// This can be used to send notifications or play sounds.
+	OnPhaseEnd(func(Phase)) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc    func(Info) bar.Output
+	colorFunc     func(Info) bar.Color
+	phaseEndFunc  func(Phase)
+	durations     map[Phase]time.Duration
+	longBreakFreq int
+	// Timer state, guarded by its own mutex since controller
+	// methods can be called from click handlers at any time.
+	stateMu   sync.Mutex
+	phase     Phase
+	elapsed   time.Duration
+	startedAt time.Time
+	running   bool
+	waiting   bool
+	completed int
+}
+
+// New constructs a new pomodoro module with the traditional
+// 25 minute work phase, 5 minute breaks, and a 15 minute long break
+// after every 4 work phases.
+func New() Module {
+	m := &module{
+		Base: base.New(),
+		durations: map[Phase]time.Duration{
+			Work:       25 * time.Minute,
+			ShortBreak: 5 * time.Minute,
+			LongBreak:  15 * time.Minute,
+		},
+		longBreakFreq: 4,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputColor(DefaultColors)
+	// Default output template is the phase and the remaining time.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Phase}} {{.Remaining}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) Durations(work, shortBreak, longBreak time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.durations = map[Phase]time.Duration{
+		Work:       work,
+		ShortBreak: shortBreak,
+		LongBreak:  longBreak,
+	}
+	return m
+}
+
+func (m *module) LongBreakEvery(count int) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.longBreakFreq = count
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OutputColor(colorFunc func(Info) bar.Color) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.colorFunc = colorFunc
+	return m
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		f(m.info(), m, e)
+	})
+	return m
+}
+
+func (m *module) OnPhaseEnd(phaseEndFunc func(Phase)) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.phaseEndFunc = phaseEndFunc
+	return m
+}
+
+// DefaultClickHandler provides useful behaviour out of the box,
+// Click to start/stop, right click to skip, and back to reset.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ButtonLeft:
+		c.Toggle()
+	case bar.ButtonRight:
+		c.Skip()
+	case bar.ButtonBack:
+		c.Reset()
+	}
+}
+
+// DefaultColors colours work phases using the 'bad' color from the scheme,
+// and breaks using the 'good' color.
+func DefaultColors(i Info) bar.Color {
+	if i.Phase.IsBreak() {
+		return colors.Scheme("good")
+	}
+	return colors.Scheme("bad")
+}
+
+func (m *module) Start() {
+	m.stateMu.Lock()
+	if !m.running {
+		m.running = true
+		m.waiting = false
+		m.startedAt = scheduler.Now()
+	}
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+func (m *module) Stop() {
+	m.stateMu.Lock()
+	if m.running {
+		m.elapsed += scheduler.Now().Sub(m.startedAt)
+		m.running = false
+	}
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+func (m *module) Toggle() {
+	m.stateMu.Lock()
+	running := m.running
+	m.stateMu.Unlock()
+	if running {
+		m.Stop()
+	} else {
+		m.Start()
+	}
+}
+
+func (m *module) Skip() {
+	m.stateMu.Lock()
+	m.advance(false)
+	m.waiting = false
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+func (m *module) Reset() {
+	m.stateMu.Lock()
+	m.phase = Work
+	m.elapsed = 0
+	m.running = false
+	m.waiting = false
+	m.completed = 0
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+// advance moves to the next phase, and must be called with stateMu held.
+func (m *module) advance(completed bool) {
+	if m.phase == Work {
+		if completed {
+			m.completed++
+		}
+		m.phase = ShortBreak
+		m.Lock()
+		freq := m.longBreakFreq
+		m.Unlock()
+		if completed && freq > 0 && m.completed%freq == 0 {
+			m.phase = LongBreak
+		}
+	} else {
+		m.phase = Work
+	}
+	m.elapsed = 0
+	m.running = false
+}
+
+func (m *module) duration(p Phase) time.Duration {
+	m.Lock()
+	defer m.Unlock()
+	return m.durations[p]
+}
+
+// info returns a snapshot of the current pomodoro state.
+func (m *module) info() Info {
+	duration := m.duration(m.currentPhase())
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	elapsed := m.elapsed
+	if m.running {
+		elapsed += scheduler.Now().Sub(m.startedAt)
+	}
+	remaining := duration - elapsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Info{
+		Phase: m.phase,
+		// Round up so that the remaining time shows 0 only when finished.
+		Remaining: (remaining + time.Second - 1) / time.Second * time.Second,
+		Running:   m.running,
+		Waiting:   m.waiting,
+		Completed: m.completed,
+	}
+}
+
+func (m *module) currentPhase() Phase {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	return m.phase
+}
+
+// checkPhaseEnd moves to the next phase if the current phase has ended,
+// and returns the phase that ended (and true), or false if it has not.
+func (m *module) checkPhaseEnd() (Phase, bool) {
+	duration := m.duration(m.currentPhase())
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if !m.running || m.elapsed+scheduler.Now().Sub(m.startedAt) < duration {
+		return m.phase, false
+	}
+	ended := m.phase
+	m.advance(true)
+	m.waiting = true
+	return ended, true
+}
+
+func (m *module) update() {
+	ended, phaseEnded := m.checkPhaseEnd()
+	info := m.info()
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.colorFunc != nil {
+		out.Color(m.colorFunc(info))
+	}
+	phaseEndFunc := m.phaseEndFunc
+	m.Unlock()
+	if info.Waiting {
+		out.Urgent(true)
+	}
+	// Schedule the next update first, so that it is already set once the
+	// output is seen, e.g. by a test advancing to the next tick.
+	if !info.Running {
+		m.Schedule().Stop()
+	} else {
+		// Remaining is rounded up to the second, so the next update is due
+		// when the actual remaining time drops to the next lower second.
+		remaining := m.duration(info.Phase) - m.elapsedNow()
+		m.Schedule().After(remaining - info.Remaining + time.Second)
+	}
+	m.Output(out)
+	if phaseEnded && phaseEndFunc != nil {
+		go phaseEndFunc(ended)
+	}
+}
+
+func (m *module) elapsedNow() time.Duration {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	elapsed := m.elapsed
+	if m.running {
+		elapsed += scheduler.Now().Sub(m.startedAt)
+	}
+	return elapsed
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pomodoro
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestPomodoro(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2017, time.March, 1, 0, 0, 0, 0, time.Local))
+	colors.LoadFromMap(map[string]string{"good": "#00ff00", "bad": "#ff0000"})
+
+	ended := make(chan Phase, 10)
+	p := New().
+		Durations(3*time.Second, time.Second, 2*time.Second).
+		LongBreakEvery(2).
+		OnPhaseEnd(func(p Phase) { ended <- p })
+	tester := testModule.NewOutputTester(t, p)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("Work 3s", out[0].Text())
+	assert.Equal(colors.Hex("#ff0000"), out[0]["color"], "work color")
+
+	p.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertOutput("on click to start")
+
+	scheduler.NextTick()
+	out = tester.AssertOutput("on tick")
+	assert.Equal("Work 2s", out[0].Text())
+
+	scheduler.NextTick()
+	tester.AssertOutput("on tick")
+	scheduler.NextTick()
+	out = tester.AssertOutput("on phase end")
+	assert.Equal("Break 1s", out[0].Text())
+	assert.Equal(true, out[0]["urgent"], "urgent when waiting")
+	assert.Equal(colors.Hex("#00ff00"), out[0]["color"], "break color")
+	assert.Equal(Work, <-ended, "phase end hook called")
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("while waiting for next phase")
+
+	p.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on starting break")
+	assert.NotContains(out[0], "urgent")
+
+	scheduler.NextTick()
+	out = tester.AssertOutput("on break end")
+	assert.Equal("Work 3s", out[0].Text())
+	assert.Equal(ShortBreak, <-ended, "phase end hook called")
+
+	p.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertOutput("on starting work")
+	for i := 0; i < 3; i++ {
+		scheduler.NextTick()
+		out = tester.AssertOutput("on tick")
+	}
+	assert.Equal("Long Break 2s", out[0].Text(), "long break after 2 work phases")
+	assert.Equal(Work, <-ended, "phase end hook called")
+
+	p.Click(bar.Event{Button: bar.ButtonRight})
+	out = tester.AssertOutput("on skip")
+	assert.Equal("Work 3s", out[0].Text())
+	assert.NotContains(out[0], "urgent")
+
+	p.Click(bar.Event{Button: bar.ButtonRight})
+	out = tester.AssertOutput("on skip")
+	assert.Equal("Break 1s", out[0].Text(), "skipped work does not count")
+
+	p.Click(bar.Event{Button: bar.ButtonBack})
+	out = tester.AssertOutput("on reset")
+	assert.Equal("Work 3s", out[0].Text())
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("when not running")
+	select {
+	case <-ended:
+		assert.Fail("phase end hook called on skip or reset")
+	default:
+	}
+}
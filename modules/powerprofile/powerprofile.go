@@ -0,0 +1,196 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package powerprofile provides an i3bar module that shows and changes the
+active power profile of power-profiles-daemon.
+
+The profile is read and set using the daemon's d-bus interface on the system
+bus, and changes made elsewhere (e.g. by the desktop environment) are picked
+up immediately from PropertiesChanged signals. By default, the active profile
+is shown, and clicking or scrolling cycles between the available profiles,
+usually "power-saver", "balanced", and "performance".
+*/
+package powerprofile
+
+import (
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the power profile state.
+type Info struct {
+	// Profile is the active profile, e.g. "balanced".
+	Profile string
+	// Profiles are the available profiles, from lowest to highest power.
+	Profiles []string
+	// Degraded is the reason the performance profile is running in a
+	// degraded mode, e.g. "lap-detected", or empty if it is not.
+	Degraded string
+}
+
+// next returns the profile offset by delta from the active profile,
+// wrapping around at either end.
+func (i Info) next(delta int) string {
+	n := len(i.Profiles)
+	for idx, p := range i.Profiles {
+		if p == i.Profile {
+			return i.Profiles[((idx+delta)%n+n)%n]
+		}
+	}
+	if n > 0 {
+		return i.Profiles[0]
+	}
+	return i.Profile
+}
+
+// Controller provides an interface to change the profile from the click handler.
+type Controller interface {
+	// Set activates the given profile.
+	Set(profile string)
+	// Next activates the next higher power profile, wrapping around to
+	// the lowest power profile.
+	Next()
+	// Previous activates the next lower power profile, wrapping around to
+	// the highest power profile.
+	Previous()
+}
+
+// Module is the public interface for a power profile module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to change the active profile.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// daemon reads and sets the power profile, and notifies on changes.
+type daemon interface {
+	info() (Info, error)
+	setProfile(string) error
+	// watch calls the given function whenever the state changes.
+	// It only returns on error.
+	watch(func()) error
+}
+
+type module struct {
+	*base.Base
+	daemon     daemon
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the power profile module.
+func New() Module {
+	return newModule(&ppd{})
+}
+
+func newModule(d daemon) *module {
+	m := &module{
+		Base:   base.New(),
+		daemon: d,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the active profile.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Profile}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler cycles to the next profile on left click or scroll up,
+// and to the previous profile on right click or scroll down.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ButtonLeft, bar.ScrollUp, bar.ScrollRight:
+		c.Next()
+	case bar.ButtonRight, bar.ScrollDown, bar.ScrollLeft:
+		c.Previous()
+	}
+}
+
+func (m *module) Set(profile string) {
+	// The PropertiesChanged signal will update the module.
+	m.Error(m.daemon.setProfile(profile))
+}
+
+func (m *module) Next() {
+	m.Lock()
+	profile := m.info.next(1)
+	m.Unlock()
+	m.Set(profile)
+}
+
+func (m *module) Previous() {
+	m.Lock()
+	profile := m.info.next(-1)
+	m.Unlock()
+	m.Set(profile)
+}
+
+// Stream starts watching for changes, and then returns the output channel
+// from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go func() { m.Error(m.daemon.watch(m.Update)) }()
+	return ch
+}
+
+func (m *module) update() {
+	info, err := m.daemon.info()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
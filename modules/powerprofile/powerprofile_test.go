@@ -0,0 +1,120 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powerprofile
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeDaemon struct {
+	sync.Mutex
+	state   Info
+	err     error
+	changes chan struct{}
+}
+
+func (f *fakeDaemon) info() (Info, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.state, f.err
+}
+
+func (f *fakeDaemon) setProfile(profile string) error {
+	f.Lock()
+	f.state.Profile = profile
+	f.Unlock()
+	// Simulate the PropertiesChanged signal.
+	f.changes <- struct{}{}
+	return nil
+}
+
+func (f *fakeDaemon) watch(update func()) error {
+	for range f.changes {
+		update()
+	}
+	return errors.New("daemon stopped")
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	d := &fakeDaemon{
+		state: Info{
+			Profile:  "balanced",
+			Profiles: []string{"power-saver", "balanced", "performance"},
+		},
+		changes: make(chan struct{}, 10),
+	}
+	p := newModule(d)
+	tester := testModule.NewOutputTester(t, p)
+	out := tester.AssertOutput("on start")
+	assert.Equal("balanced", out[0].Text())
+
+	p.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click")
+	assert.Equal("performance", out[0].Text())
+
+	p.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("power-saver", out[0].Text(), "wraps around")
+
+	p.Click(bar.Event{Button: bar.ScrollDown})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("performance", out[0].Text(), "wraps around")
+
+	p.Click(bar.Event{Button: bar.ButtonRight})
+	out = tester.AssertOutput("on right click")
+	assert.Equal("balanced", out[0].Text())
+
+	d.Lock()
+	d.state.Profile = "performance"
+	d.state.Degraded = "lap-detected"
+	d.Unlock()
+	d.changes <- struct{}{}
+	out = tester.AssertOutput("on external change")
+	assert.Equal("performance", out[0].Text())
+
+	var info Info
+	p.OnClick(func(i Info, c Controller, e bar.Event) {
+		info = i
+		c.Set("power-saver")
+	})
+	p.OutputTemplate(outputs.TextTemplate(`{{.Profile}}{{with .Degraded}} ({{.}}){{end}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal("performance (lap-detected)", out[0].Text())
+	p.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on custom click")
+	assert.Equal("power-saver (lap-detected)", out[0].Text())
+	assert.Equal("lap-detected", info.Degraded)
+
+	assert.Equal("power-saver", Info{Profile: "custom", Profiles: []string{"power-saver"}}.next(1))
+	assert.Equal("custom", Info{Profile: "custom"}.next(-1))
+
+	d.Lock()
+	d.err = errors.New("no daemon")
+	d.Unlock()
+	d.changes <- struct{}{}
+	tester.AssertError("on daemon error")
+
+	close(d.changes)
+	tester.AssertError("on watch error")
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powerprofile
+
+import (
+	"sync"
+
+	godbus "github.com/godbus/dbus"
+
+	"github.com/soumya92/barista/watchers/dbus"
+)
+
+const (
+	ppdDest  = "net.hadess.PowerProfiles"
+	ppdPath  = "/net/hadess/PowerProfiles"
+	ppdIface = "net.hadess.PowerProfiles"
+)
+
+// ppd is the d-bus client for power-profiles-daemon.
+type ppd struct {
+	once    sync.Once
+	watcher *dbus.PropertiesWatcher
+}
+
+// properties returns the watcher for the power-profiles-daemon properties,
+// starting it if necessary.
+func (p *ppd) properties() *dbus.PropertiesWatcher {
+	p.once.Do(func() {
+		p.watcher = dbus.WatchProperties(dbus.System, ppdDest, ppdPath, ppdIface)
+	})
+	return p.watcher
+}
+
+func (p *ppd) info() (Info, error) {
+	props, err := p.properties().Get()
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{}
+	info.Profile, _ = props["ActiveProfile"].(string)
+	info.Degraded, _ = props["PerformanceDegraded"].(string)
+	profiles, _ := props["Profiles"].([]map[string]godbus.Variant)
+	for _, profile := range profiles {
+		if name, ok := profile["Profile"].Value().(string); ok {
+			info.Profiles = append(info.Profiles, name)
+		}
+	}
+	return info, nil
+}
+
+func (p *ppd) setProfile(profile string) error {
+	return p.properties().Set("ActiveProfile", profile)
+}
+
+func (p *ppd) watch(f func()) error {
+	for range p.properties().Updates {
+		f()
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prayer
+
+import (
+	"math"
+	"time"
+)
+
+// Method is a calculation method, which defines the sun angles used for
+// Fajr and Isha. Some methods use a fixed interval after Maghrib for Isha.
+type Method struct {
+	// Fajr is the angle of the sun below the horizon at Fajr, in degrees.
+	Fajr float64
+	// Isha is the angle of the sun below the horizon at Isha, in degrees,
+	// used if IshaInterval is zero.
+	Isha float64
+	// IshaInterval is the time from Maghrib to Isha, if non-zero.
+	IshaInterval time.Duration
+}
+
+// Common calculation methods.
+var (
+	// MWL is the Muslim World League method.
+	MWL = Method{Fajr: 18, Isha: 17}
+	// ISNA is the Islamic Society of North America method.
+	ISNA = Method{Fajr: 15, Isha: 15}
+	// Egypt is the Egyptian General Authority of Survey method.
+	Egypt = Method{Fajr: 19.5, Isha: 17.5}
+	// Makkah is the Umm al-Qura University, Makkah method.
+	Makkah = Method{Fajr: 18.5, IshaInterval: 90 * time.Minute}
+	// Karachi is the University of Islamic Sciences, Karachi method.
+	Karachi = Method{Fajr: 18, Isha: 18}
+)
+
+// Juristic is the juristic method used to compute the time of Asr.
+type Juristic int
+
+const (
+	// Standard uses a shadow length equal to the object (Shafi'i,
+	// Maliki, Hanbali).
+	Standard Juristic = 1
+	// Hanafi uses a shadow length twice the object.
+	Hanafi Juristic = 2
+)
+
+// sunriseAngle accounts for refraction and the radius of the sun.
+const sunriseAngle = 0.833
+
+func sin(d float64) float64  { return math.Sin(d * math.Pi / 180) }
+func cos(d float64) float64  { return math.Cos(d * math.Pi / 180) }
+func tan(d float64) float64  { return math.Tan(d * math.Pi / 180) }
+func asin(x float64) float64 { return math.Asin(x) * 180 / math.Pi }
+func acos(x float64) float64 { return math.Acos(x) * 180 / math.Pi }
+func atan(x float64) float64 { return math.Atan(x) * 180 / math.Pi }
+
+func atan2(y, x float64) float64 { return math.Atan2(y, x) * 180 / math.Pi }
+
+// fix wraps a value into [0, max).
+func fix(a, max float64) float64 {
+	a = math.Mod(a, max)
+	if a < 0 {
+		a += max
+	}
+	return a
+}
+
+// calculator computes prayer times for a single day and location.
+type calculator struct {
+	lat, lng float64
+	// jd is the julian date at local midnight, adjusted for longitude.
+	jd float64
+}
+
+func newCalculator(date time.Time, lat, lng float64) calculator {
+	y, m, d := date.Date()
+	if m <= 2 {
+		y--
+		m += 12
+	}
+	a := math.Floor(float64(y) / 100)
+	b := 2 - a + math.Floor(a/4)
+	jd := math.Floor(365.25*float64(y+4716)) + math.Floor(30.6001*float64(m+1)) +
+		float64(d) + b - 1524.5
+	return calculator{lat: lat, lng: lng, jd: jd - lng/(15*24)}
+}
+
+// sunPosition returns the declination of the sun and the equation of time
+// at the given fraction of the day.
+func (c calculator) sunPosition(dayFraction float64) (decl, eqt float64) {
+	d := c.jd + dayFraction - 2451545.0
+	g := fix(357.529+0.98560028*d, 360)
+	q := fix(280.459+0.98564736*d, 360)
+	l := fix(q+1.915*sin(g)+0.020*sin(2*g), 360)
+	e := 23.439 - 0.00000036*d
+	ra := fix(atan2(cos(e)*sin(l), cos(l))/15, 24)
+	return asin(sin(e) * sin(l)), q/15 - ra
+}
+
+// midDay returns the time of solar noon, in hours of local solar time.
+func (c calculator) midDay(hours float64) float64 {
+	_, eqt := c.sunPosition(hours / 24)
+	return fix(12-eqt, 24)
+}
+
+// sunAngleTime returns the time at which the sun is the given angle below
+// the horizon, before noon if ccw is true. It returns NaN if the sun does
+// not reach that angle, e.g. near the poles.
+func (c calculator) sunAngleTime(angle, hours float64, ccw bool) float64 {
+	decl, _ := c.sunPosition(hours / 24)
+	noon := c.midDay(hours)
+	t := acos((-sin(angle)-sin(decl)*sin(c.lat))/(cos(decl)*cos(c.lat))) / 15
+	if ccw {
+		return noon - t
+	}
+	return noon + t
+}
+
+// asrTime returns the time of Asr for the given shadow factor.
+func (c calculator) asrTime(factor, hours float64) float64 {
+	decl, _ := c.sunPosition(hours / 24)
+	angle := -atan(1 / (factor + tan(math.Abs(c.lat-decl))))
+	return c.sunAngleTime(angle, hours, false)
+}
+
+// times computes prayer times for the day, in hours of local solar time,
+// starting from approximate times and refining once.
+func (c calculator) times(method Method, asr Juristic) [numPrayers]float64 {
+	t := [numPrayers]float64{5, 6, 12, 13, 18, 18}
+	for i := 0; i < 2; i++ {
+		t = [numPrayers]float64{
+			Fajr:    c.sunAngleTime(method.Fajr, t[Fajr], true),
+			Sunrise: c.sunAngleTime(sunriseAngle, t[Sunrise], true),
+			Dhuhr:   c.midDay(t[Dhuhr]),
+			Asr:     c.asrTime(float64(asr), t[Asr]),
+			Maghrib: c.sunAngleTime(sunriseAngle, t[Maghrib], false),
+			Isha:    c.sunAngleTime(method.Isha, t[Isha], false),
+		}
+	}
+	if method.IshaInterval > 0 {
+		t[Isha] = t[Maghrib] + method.IshaInterval.Hours()
+	}
+	// At high latitudes, the sun may not reach the Fajr or Isha angles.
+	// Use a portion of the night proportional to the angle instead.
+	night := 24 - (t[Maghrib] - t[Sunrise])
+	if math.IsNaN(t[Fajr]) {
+		t[Fajr] = t[Sunrise] - method.Fajr/60*night
+	}
+	if math.IsNaN(t[Isha]) {
+		t[Isha] = t[Maghrib] + method.Isha/60*night
+	}
+	return t
+}
+
+// Times computes the prayer times on the given date, for a location.
+// The times are in the location of the date, and are zero if they do not
+// occur on that date, e.g. during polar night.
+func Times(date time.Time, lat, lng float64, method Method, asr Juristic) [numPrayers]time.Time {
+	c := newCalculator(date, lat, lng)
+	hours := c.times(method, asr)
+	y, m, d := date.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	var times [numPrayers]time.Time
+	for i, h := range hours {
+		if math.IsNaN(h) {
+			// e.g. no sunrise during polar night.
+			continue
+		}
+		// Convert from local solar time to UTC.
+		utc := h - c.lng/15
+		times[i] = midnight.Add(time.Duration(utc * float64(time.Hour))).
+			Round(time.Minute).In(date.Location())
+	}
+	return times
+}
@@ -0,0 +1,264 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package prayer provides an i3bar module that shows the time until the next
+Islamic prayer.
+
+Prayer times are computed locally from the coordinates of the location, using
+the position of the sun and a calculation method (by default, the Muslim World
+League method), so no network access is needed. By default, the next prayer
+and the time remaining until it are shown, e.g. "Asr 1h23m", and the module is
+urgent within 15 minutes of a prayer.
+
+The module can also use the current location from the location package using
+Here, in which case the times follow the location as it changes.
+*/
+package prayer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/location"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Prayer identifies one of the daily prayers, or sunrise.
+type Prayer int
+
+// Daily prayers, in order. Sunrise is not a prayer, but marks the end of
+// the time for Fajr.
+const (
+	Fajr Prayer = iota
+	Sunrise
+	Dhuhr
+	Asr
+	Maghrib
+	Isha
+	numPrayers
+)
+
+var prayerNames = [numPrayers]string{"Fajr", "Sunrise", "Dhuhr", "Asr", "Maghrib", "Isha"}
+
+func (p Prayer) String() string {
+	if p < 0 || p >= numPrayers {
+		return fmt.Sprintf("Prayer(%d)", int(p))
+	}
+	return prayerNames[p]
+}
+
+// Info represents the prayer times for the current day.
+type Info struct {
+	// Times are the times of each prayer today, indexed by Prayer.
+	Times [numPrayers]time.Time
+	// Next is the next prayer, and NextTime its time, which is
+	// tomorrow's Fajr after Isha.
+	Next     Prayer
+	NextTime time.Time
+	Now      time.Time
+}
+
+// Time returns the time of a prayer today.
+func (i Info) Time(p Prayer) time.Time {
+	return i.Times[p]
+}
+
+// Remaining returns the time remaining until the next prayer.
+func (i Info) Remaining() time.Duration {
+	return i.NextTime.Sub(i.Now)
+}
+
+// Countdown returns the time remaining until the next prayer in a compact
+// format, e.g. "1h23m" or "12m", or an empty string if the time of the
+// next prayer is not known, e.g. near the poles.
+func (i Info) Countdown() string {
+	if i.NextTime.IsZero() {
+		return ""
+	}
+	// Round up, so that "0m" is never shown before the prayer.
+	d := i.Remaining() + time.Minute - 1
+	hours := int(d / time.Hour)
+	minutes := int(d/time.Minute) % 60
+	if hours > 0 {
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// Module is the public interface for a prayer times module.
+type Module interface {
+	base.WithClickHandler
+
+	// Method sets the calculation method, which defines the angles used
+	// for Fajr and Isha.
+	Method(Method) Module
+
+	// Asr sets the juristic method used for Asr.
+	Asr(Juristic) Module
+
+	// SkipSunrise excludes sunrise from the next prayer, since it is
+	// not a prayer time.
+	SkipSunrise(bool) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	lat, lng    float64
+	here        bool
+	location    *time.Location
+	method      Method
+	asr         Juristic
+	skipSunrise bool
+	outputFunc  func(Info) bar.Output
+	urgentFunc  func(Info) bool
+}
+
+// New constructs an instance of the prayer times module for a location,
+// given by latitude and longitude in degrees (north and east are positive).
+// Times are shown in the local timezone.
+func New(lat, lng float64) Module {
+	return NewWithLocation(lat, lng, time.Local)
+}
+
+// NewWithLocation constructs an instance of the prayer times module for a
+// location, showing times in the given timezone.
+func NewWithLocation(lat, lng float64, location *time.Location) Module {
+	return newModule(lat, lng, location)
+}
+
+// Here constructs an instance of the prayer times module for the current
+// location, as provided by the location package. Times are shown in the
+// local timezone, and nothing is shown while the location is unknown.
+func Here() Module {
+	return HereWithLocation(time.Local)
+}
+
+// HereWithLocation constructs an instance of the prayer times module for the
+// current location, showing times in the given timezone.
+func HereWithLocation(tz *time.Location) Module {
+	m := newModule(0, 0, tz)
+	m.here = true
+	location.OnChange(m.Update)
+	return m
+}
+
+func newModule(lat, lng float64, location *time.Location) *module {
+	m := &module{
+		Base:        base.New(),
+		lat:         lat,
+		lng:         lng,
+		location:    location,
+		method:      MWL,
+		asr:         Standard,
+		skipSunrise: true,
+	}
+	// Update at the start of every minute for the countdown.
+	m.Schedule().EveryAligned(time.Minute)
+	// Default output template is the next prayer and the countdown.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Next}} {{.Countdown}}`))
+	// Prayers within 15 minutes are urgent by default.
+	m.UrgentWhen(func(i Info) bool { return i.Remaining() <= 15*time.Minute })
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) Method(method Method) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.method = method
+	return m
+}
+
+func (m *module) Asr(asr Juristic) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.asr = asr
+	return m
+}
+
+func (m *module) SkipSunrise(skip bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.skipSunrise = skip
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+func (m *module) update() {
+	m.Lock()
+	lat, lng := m.lat, m.lng
+	if m.here {
+		var ok bool
+		if lat, lng, ok = location.Get(); !ok {
+			m.Unlock()
+			m.Clear()
+			return
+		}
+	}
+	now := scheduler.Now().In(m.location)
+	info := Info{
+		Now:   now,
+		Times: Times(now, lat, lng, m.method, m.asr),
+		Next:  Fajr,
+	}
+	info.NextTime = Times(now.AddDate(0, 0, 1), lat, lng, m.method, m.asr)[Fajr]
+	for p := Fajr; p < numPrayers; p++ {
+		if p == Sunrise && m.skipSunrise {
+			continue
+		}
+		if t := info.Times[p]; !t.IsZero() && t.After(now) {
+			info.Next, info.NextTime = p, t
+			break
+		}
+	}
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/location"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func clock(times [numPrayers]time.Time) []string {
+	var out []string
+	for _, t := range times {
+		if t.IsZero() {
+			out = append(out, "-")
+		} else {
+			out = append(out, t.Format("15:04"))
+		}
+	}
+	return out
+}
+
+func TestTimes(t *testing.T) {
+	assert := assert.New(t)
+	london := time.FixedZone("GMT", 0)
+	assert.Equal(
+		[]string{"06:03", "08:05", "12:06", "13:49", "16:07", "18:03"},
+		clock(Times(time.Date(2018, 1, 5, 12, 0, 0, 0, london), 51.5074, -0.1278, MWL, Standard)))
+
+	newYork := time.FixedZone("EDT", -4*60*60)
+	assert.Equal(
+		[]string{"03:45", "05:25", "12:58", "18:12", "20:31", "22:11"},
+		clock(Times(time.Date(2018, 6, 21, 12, 0, 0, 0, newYork), 40.7128, -74.0060, ISNA, Hanafi)))
+
+	makkah := time.FixedZone("AST", 3*60*60)
+	times := Times(time.Date(2018, 3, 1, 12, 0, 0, 0, makkah), 21.4225, 39.8262, Makkah, Standard)
+	assert.Equal(90*time.Minute, times[Isha].Sub(times[Maghrib]), "fixed isha interval")
+
+	times = Times(time.Date(2018, 6, 21, 12, 0, 0, 0, time.UTC), 69.65, 18.96, MWL, Standard)
+	assert.True(times[Sunrise].IsZero(), "no sunrise during midnight sun")
+	assert.False(times[Dhuhr].IsZero())
+
+	assert.Equal("Maghrib", Maghrib.String())
+	assert.Equal("Prayer(7)", Prayer(7).String())
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	london := time.FixedZone("GMT", 0)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 7, 0, 0, 0, london))
+
+	p := NewWithLocation(51.5074, -0.1278, london)
+	tester := testModule.NewOutputTester(t, p)
+	out := tester.AssertOutput("on start")
+	assert.Equal("Dhuhr 5h6m", out[0].Text(), "skips sunrise")
+	assert.Equal(false, out[0]["urgent"])
+
+	p.SkipSunrise(false)
+	out = tester.AssertOutput("on skip sunrise change")
+	assert.Equal("Sunrise 1h5m", out[0].Text())
+
+	scheduler.AdvanceBy(time.Minute)
+	out = tester.AssertOutput("on tick")
+	assert.Equal("Sunrise 1h4m", out[0].Text())
+
+	var info Info
+	p.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Text(i.Time(Asr).Format("15:04"))
+	})
+	out = tester.AssertOutput("on output func change")
+	assert.Equal("13:49", out[0].Text())
+	assert.Equal(Sunrise, info.Next)
+
+	p.Asr(Hanafi)
+	out = tester.AssertOutput("on asr change")
+	assert.Equal("14:20", out[0].Text())
+
+	p.Method(ISNA)
+	tester.AssertOutput("on method change")
+	assert.Equal(time.Date(2018, 1, 5, 6, 23, 0, 0, london), info.Time(Fajr))
+
+	p.UrgentWhen(nil)
+	out = tester.AssertOutput("on urgent func change")
+	_, ok := out[0]["urgent"]
+	assert.False(ok)
+
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 11, 55, 30, 0, london))
+	tester = testModule.NewOutputTester(t, NewWithLocation(51.5074, -0.1278, london))
+	out = tester.AssertOutput("on start")
+	assert.Equal("Dhuhr 11m", out[0].Text(), "rounds up")
+	assert.Equal(true, out[0]["urgent"])
+
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 20, 0, 0, 0, london))
+	var next Info
+	p = NewWithLocation(51.5074, -0.1278, london)
+	p.OutputFunc(func(i Info) bar.Output {
+		next = i
+		return outputs.Text(i.Countdown())
+	})
+	tester = testModule.NewOutputTester(t, p)
+	out = tester.AssertOutput("after isha")
+	assert.Equal("10h2m", out[0].Text())
+	assert.Equal(Fajr, next.Next)
+	assert.Equal(time.Date(2018, 1, 6, 6, 2, 0, 0, london), next.NextTime, "tomorrow's fajr")
+
+	assert.Equal("", Info{}.Countdown())
+}
+
+func TestHere(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	gmt := time.FixedZone("GMT", 0)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 7, 0, 0, 0, gmt))
+
+	tester := testModule.NewOutputTester(t, HereWithLocation(gmt))
+	if _, _, ok := location.Get(); !ok {
+		tester.AssertEmpty("while location is unknown")
+	} else {
+		tester.AssertOutput("on start")
+	}
+
+	location.Set(51.5074, -0.1278)
+	out := tester.AssertOutput("on location change")
+	assert.Equal("Dhuhr 5h6m", out[0].Text())
+
+	// It is 16:00 in Tokyo, shortly before sunset.
+	location.Set(35.6762, 139.6503)
+	out = tester.AssertOutput("on location change")
+	assert.Equal("Maghrib 42m", out[0].Text())
+}
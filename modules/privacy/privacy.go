@@ -0,0 +1,224 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package privacy provides an i3bar module that warns when the camera or
+microphone is in use.
+
+The camera is detected as in use when any process has a /dev/video* device
+open, like fuser. Capture streams are read from PipeWire using pw-dump, which
+detects applications recording from the microphone, as well as cameras used
+through PipeWire. If pw-dump is not installed, only video devices are checked.
+By default, nothing is shown unless the camera or microphone is in use, in
+which case an urgent indicator is shown.
+*/
+package privacy
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/outputs"
+)
+
+// App represents an application using the camera or microphone.
+type App struct {
+	PID  int
+	Name string
+}
+
+// Info represents the applications currently capturing audio or video.
+type Info struct {
+	Camera     []App
+	Microphone []App
+}
+
+// Active returns true if either the camera or the microphone is in use.
+func (i Info) Active() bool {
+	return len(i.Camera) > 0 || len(i.Microphone) > 0
+}
+
+// Module represents a privacy indicator bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the privacy indicator module.
+func New() Module {
+	m := &module{Base: base.New()}
+	m.RefreshInterval(2 * time.Second)
+	// Default output template only shows devices that are in use.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{if .Camera}}CAM{{end}}{{if and .Camera .Microphone}} {{end}}{{if .Microphone}}MIC{{end}}`))
+	// Capture should be prominent by default.
+	m.UrgentWhen(Info.Active)
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+var fs = hostfs.Fs()
+
+// readlink is used to resolve file descriptors, since afero does not
+// support symlinks.
+var readlink = func(name string) (string, error) {
+	return os.Readlink(hostfs.Path(name))
+}
+
+// videoUsers returns the processes that have a video device open.
+func videoUsers() []App {
+	fds, _ := afero.Glob(fs, "/proc/*/fd/*")
+	seen := map[int]bool{}
+	var apps []App
+	for _, fd := range fds {
+		target, err := readlink(fd)
+		if err != nil || !strings.HasPrefix(target, "/dev/video") {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(filepath.Dir(fd))))
+		if err != nil || seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		comm, _ := afero.ReadFile(fs, filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+		apps = append(apps, App{PID: pid, Name: strings.TrimSpace(string(comm))})
+	}
+	sort.Slice(apps, func(a, b int) bool { return apps[a].PID < apps[b].PID })
+	return apps
+}
+
+// pwDump returns the output of pw-dump, which describes all PipeWire objects as JSON.
+var pwDump = func() ([]byte, error) {
+	return exec.Command("pw-dump").Output()
+}
+
+// pwObject is the subset of a PipeWire object from pw-dump that is needed
+// to find capture streams.
+type pwObject struct {
+	Type string `json:"type"`
+	Info struct {
+		State string                 `json:"state"`
+		Props map[string]interface{} `json:"props"`
+	} `json:"info"`
+}
+
+// captureStreams returns the applications with running PipeWire capture
+// streams, for audio and video.
+func captureStreams() (audio, video []App, err error) {
+	out, err := pwDump()
+	if err != nil {
+		// PipeWire is optional, so only report other errors.
+		if execErr, ok := err.(*exec.Error); ok && execErr.Err == exec.ErrNotFound {
+			err = nil
+		}
+		return nil, nil, err
+	}
+	var objects []pwObject
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, nil, err
+	}
+	for _, o := range objects {
+		if o.Type != "PipeWire:Interface:Node" || o.Info.State != "running" {
+			continue
+		}
+		class, _ := o.Info.Props["media.class"].(string)
+		app := App{}
+		app.Name, _ = o.Info.Props["application.name"].(string)
+		// JSON numbers are decoded as float64.
+		if pid, ok := o.Info.Props["application.process.id"].(float64); ok {
+			app.PID = int(pid)
+		}
+		switch class {
+		case "Stream/Input/Audio":
+			audio = append(audio, app)
+		case "Stream/Input/Video":
+			video = append(video, app)
+		}
+	}
+	return audio, video, nil
+}
+
+func (m *module) update() {
+	audio, video, err := captureStreams()
+	if m.Error(err) {
+		return
+	}
+	info := Info{Camera: videoUsers(), Microphone: audio}
+	// Cameras used through PipeWire are opened by the PipeWire daemon,
+	// so show the application using the stream instead.
+	if len(video) > 0 {
+		info.Camera = video
+	}
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privacy
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+const pwDumpOutput = `[
+  {"id": 30, "type": "PipeWire:Interface:Node", "info": {"state": "running",
+    "props": {"media.class": "Audio/Source", "node.name": "alsa_input.pci"}}},
+  {"id": 81, "type": "PipeWire:Interface:Node", "info": {"state": "running",
+    "props": {"media.class": "Stream/Input/Audio", "application.name": "Firefox",
+      "application.process.id": 4242}}},
+  {"id": 82, "type": "PipeWire:Interface:Node", "info": {"state": "suspended",
+    "props": {"media.class": "Stream/Input/Audio", "application.name": "Zoom"}}},
+  {"id": 90, "type": "PipeWire:Interface:Link", "info": {"state": "active"}}
+]`
+
+func TestPrivacy(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "/proc/100/comm", []byte("cheese\n"), 0644)
+	afero.WriteFile(fs, "/proc/100/fd/0", nil, 0644)
+	afero.WriteFile(fs, "/proc/100/fd/7", nil, 0644)
+	afero.WriteFile(fs, "/proc/100/fd/8", nil, 0644)
+
+	var mu sync.Mutex
+	links := map[string]string{"/proc/100/fd/0": "/dev/null"}
+	readlink = func(name string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if target, ok := links[name]; ok {
+			return target, nil
+		}
+		return "", os.ErrNotExist
+	}
+	dump := pwDumpOutput
+	var dumpErr error = &exec.Error{Name: "pw-dump", Err: exec.ErrNotFound}
+	pwDump = func() ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []byte(dump), dumpErr
+	}
+
+	p := New()
+	tester := testModule.NewOutputTester(t, p)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "nothing shown when not capturing")
+
+	mu.Lock()
+	links["/proc/100/fd/7"] = "/dev/video0"
+	links["/proc/100/fd/8"] = "/dev/video1"
+	mu.Unlock()
+	scheduler.NextTick()
+	out = tester.AssertOutput("when camera is opened")
+	assert.Equal(bar.NewSegment("CAM").Urgent(true), out[0])
+
+	var info Info
+	p.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return bar.Output{}
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal([]App{{PID: 100, Name: "cheese"}}, info.Camera, "once per process")
+
+	mu.Lock()
+	dumpErr = nil
+	delete(links, "/proc/100/fd/7")
+	delete(links, "/proc/100/fd/8")
+	mu.Unlock()
+	scheduler.NextTick()
+	tester.AssertOutput("when pipewire is available")
+	assert.Empty(info.Camera)
+	assert.Equal([]App{{PID: 4242, Name: "Firefox"}}, info.Microphone)
+
+	mu.Lock()
+	dumpErr = errors.New("pipewire not running")
+	mu.Unlock()
+	scheduler.NextTick()
+	tester.AssertError("on pw-dump error")
+}
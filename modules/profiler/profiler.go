@@ -0,0 +1,155 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package profiler provides an i3bar module that shows the modules that use
+the most CPU time, using the profiling package. Adding the module to the bar
+enables profiling.
+
+By default, the module shows the three modules with the most CPU time since
+profiling was enabled, e.g. "weather 1.2s, cpuload 310ms, clock 80ms". Left
+clicking the module writes the full report to the log, and right clicking it
+resets the recorded stats.
+*/
+package profiler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/profiling"
+)
+
+// Info contains the recorded stats of all modules, using the most CPU time
+// first.
+type Info []profiling.Stats
+
+// Top returns the stats of the n modules that used the most CPU time.
+func (i Info) Top(n int) Info {
+	if len(i) > n {
+		return i[:n]
+	}
+	return i
+}
+
+// Summary returns a short summary of the module names and their CPU time,
+// e.g. "weather 1.2s, clock 80ms".
+func (i Info) Summary() string {
+	var parts []string
+	for _, s := range i {
+		parts = append(parts, fmt.Sprintf("%s %v", ShortName(s.Name), roundCPU(s.CPU)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ShortName returns the name of a module without the "modules/" prefix of
+// built-in modules.
+func ShortName(name string) string {
+	return strings.TrimPrefix(name, "modules/")
+}
+
+// roundCPU rounds CPU times to a readable precision.
+func roundCPU(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// Module represents a profiler bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc func(Info) bar.Output
+}
+
+// New constructs an instance of the profiler module, and enables profiling.
+func New() Module {
+	profiling.Enable(true)
+	m := &module{Base: base.New()}
+	m.RefreshInterval(10 * time.Second)
+	m.OutputFunc(DefaultOutput)
+	m.OnClick(DefaultClickHandler)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows a summary of the three modules with the most CPU
+// time, or nothing if no modules have been profiled yet.
+func DefaultOutput(i Info) bar.Output {
+	if len(i) == 0 {
+		return nil
+	}
+	return outputs.Text(i.Top(3).Summary())
+}
+
+var log = logging.New("modules/profiler")
+
+// DefaultClickHandler writes the full report to the log on left click, and
+// resets the recorded stats on right click.
+func DefaultClickHandler(e bar.Event) {
+	switch e.Button {
+	case bar.ButtonLeft:
+		profiling.Write(logging.Writer())
+	case bar.ButtonRight:
+		log.Info("resetting profile")
+		profiling.Reset()
+	}
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) update() {
+	info := Info(profiling.Report())
+	m.Lock()
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
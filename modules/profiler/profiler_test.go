@@ -0,0 +1,65 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/profiling"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestInfo(t *testing.T) {
+	i := Info{
+		{Name: "modules/weather", CPU: 1234 * time.Millisecond},
+		{Name: "modules/cpuload", CPU: 310400 * time.Microsecond},
+		{Name: "main", CPU: 1500 * time.Nanosecond},
+		{Name: "modules/clock", CPU: time.Microsecond},
+	}
+	assert.Len(t, i.Top(3), 3)
+	assert.Len(t, i.Top(10), 4)
+	assert.Equal(t, "weather 1.2s, cpuload 310ms, main 2µs", i.Top(3).Summary())
+	assert.Equal(t, "", Info{}.Summary())
+	assert.Nil(t, DefaultOutput(Info{}), "nothing profiled yet")
+}
+
+func TestProfiler(t *testing.T) {
+	scheduler.TestMode(true)
+	defer profiling.Enable(false)
+	m := New()
+	assert.True(t, profiling.Enabled(), "enabled by adding the module")
+	m.OutputFunc(func(i Info) bar.Output {
+		return outputs.Text(i.Summary())
+	})
+	tester := testModule.NewOutputTester(t, m)
+	tester.AssertOutput("on start")
+
+	p := profiling.New("modules/fake")
+	p.Start()()
+	scheduler.AdvanceBy(10 * time.Second)
+	out := tester.AssertOutput("on refresh")
+	assert.Contains(t, out[0].Text(), "fake")
+
+	profiling.Reset()
+	scheduler.AdvanceBy(10 * time.Second)
+	out = tester.AssertOutput("after reset")
+	assert.NotContains(t, out[0].Text(), "fake")
+}
@@ -0,0 +1,233 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package prometheus provides an i3bar module that shows the result of a
+Prometheus query.
+
+The module periodically evaluates a PromQL instant query using the HTTP API,
+so any metric already collected by Prometheus can be shown on the bar, e.g.
+
+	prometheus.New("http://localhost:9090",
+		`sum(rate(node_network_receive_bytes_total[1m]))`)
+
+By default the value of the first sample is shown, and nothing is shown if the
+query returns no samples. Queries that return multiple time series can be
+formatted using the labels of each sample.
+*/
+package prometheus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Sample represents a single sample returned by a query.
+type Sample struct {
+	// Metric contains the labels of the time series, including the metric
+	// name as "__name__". It is empty for scalar results.
+	Metric map[string]string
+	Value  float64
+	Time   time.Time
+}
+
+// Label returns the value of a label, or an empty string if not set.
+func (s Sample) Label(name string) string {
+	return s.Metric[name]
+}
+
+// Info represents the result of a query.
+type Info struct {
+	Samples []Sample
+}
+
+// Value returns the value of the first sample, or NaN if the query
+// did not return any samples.
+func (i Info) Value() float64 {
+	if len(i.Samples) == 0 {
+		return math.NaN()
+	}
+	return i.Samples[0].Value
+}
+
+// Module is the public interface for a prometheus module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	server     string
+	query      string
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the prometheus module that evaluates the
+// given query against a Prometheus server, e.g. "http://localhost:9090".
+func New(server, query string) Module {
+	m := &module{
+		Base:   base.New(),
+		server: strings.TrimSuffix(server, "/"),
+		query:  query,
+	}
+	// Prometheus usually scrapes every 15 seconds or more.
+	m.RefreshInterval(30 * time.Second)
+	// Default output template is the value of the first sample, if any.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Samples}}{{printf "%.4g" .Value}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+// queryResponse is the response from the /api/v1/query endpoint.
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// point is a [timestamp, "value"] pair.
+type point [2]interface{}
+
+func (p point) sample(metric map[string]string) (Sample, error) {
+	secs, ok := p[0].(float64)
+	str, ok2 := p[1].(string)
+	if !ok || !ok2 {
+		return Sample{}, fmt.Errorf("prometheus: malformed sample %v", p)
+	}
+	// Prometheus formats special values as e.g. "+Inf", which ParseFloat handles.
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Sample{}, err
+	}
+	t := time.Unix(0, int64(secs*float64(time.Second)))
+	return Sample{Metric: metric, Value: value, Time: t}, nil
+}
+
+func (m *module) evaluate() ([]Sample, error) {
+	u := m.server + "/api/v1/query?" + url.Values{"query": {m.query}}.Encode()
+	response, err := http.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var r queryResponse
+	// Errors also have a JSON body with a useful message.
+	if err := json.NewDecoder(response.Body).Decode(&r); err != nil {
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", u, response.Status)
+		}
+		return nil, err
+	}
+	if r.Status != "success" {
+		return nil, errors.New("prometheus: " + r.Error)
+	}
+	switch r.Data.ResultType {
+	case "scalar":
+		var p point
+		if err := json.Unmarshal(r.Data.Result, &p); err != nil {
+			return nil, err
+		}
+		s, err := p.sample(map[string]string{})
+		if err != nil {
+			return nil, err
+		}
+		return []Sample{s}, nil
+	case "vector":
+		var series []struct {
+			Metric map[string]string `json:"metric"`
+			Value  point             `json:"value"`
+		}
+		if err := json.Unmarshal(r.Data.Result, &series); err != nil {
+			return nil, err
+		}
+		var samples []Sample
+		for _, s := range series {
+			sample, err := s.Value.sample(s.Metric)
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, sample)
+		}
+		return samples, nil
+	}
+	return nil, fmt.Errorf("prometheus: unsupported result type %q", r.Data.ResultType)
+}
+
+func (m *module) update() {
+	samples, err := m.evaluate()
+	if m.Error(err) {
+		return
+	}
+	info := Info{Samples: samples}
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
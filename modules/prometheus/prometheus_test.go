@@ -0,0 +1,134 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeServer struct {
+	sync.Mutex
+	status   int
+	response string
+	query    string
+}
+
+func (f *fakeServer) set(status int, response string) {
+	f.Lock()
+	defer f.Unlock()
+	f.status, f.response = status, response
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if r.URL.Path != "/api/v1/query" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.query = r.URL.Query().Get("query")
+	w.WriteHeader(f.status)
+	w.Write([]byte(f.response))
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	f := &fakeServer{}
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	f.set(200, `{"status": "success", "data": {"resultType": "vector", "result": [
+		{"metric": {"__name__": "up", "job": "node"}, "value": [1515150000.5, "1"]},
+		{"metric": {"__name__": "up", "job": "prometheus"}, "value": [1515150000.5, "0"]}]}}`)
+	p := New(server.URL+"/", `up{job=~"node|prometheus"}`)
+	tester := testModule.NewOutputTester(t, p)
+	out := tester.AssertOutput("on start")
+	assert.Equal("1", out[0].Text())
+	f.Lock()
+	assert.Equal(`up{job=~"node|prometheus"}`, f.query)
+	f.Unlock()
+
+	var info Info
+	p.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Textf("%d", len(i.Samples))
+	})
+	out = tester.AssertOutput("on output func change")
+	assert.Equal("2", out[0].Text())
+	assert.Equal(Sample{
+		Metric: map[string]string{"__name__": "up", "job": "prometheus"},
+		Value:  0,
+		Time:   time.Unix(1515150000, 500000000),
+	}, info.Samples[1])
+	assert.Equal("node", info.Samples[0].Label("job"))
+	assert.Equal("", info.Samples[0].Label("instance"))
+
+	p.OutputTemplate(outputs.TextTemplate(`{{if .Samples}}{{printf "%.4g" .Value}}{{end}}`))
+	p.UrgentWhen(func(i Info) bool { return i.Value() > 100 })
+	tester.AssertOutput("on template change")
+	tester.AssertOutput("on urgent func change")
+
+	f.set(200, `{"status": "success", "data": {"resultType": "scalar", "result": [1515150000, "123.456"]}}`)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("123.5", out[0].Text())
+	assert.Equal(true, out[0]["urgent"])
+
+	f.set(200, `{"status": "success", "data": {"resultType": "vector", "result": []}}`)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on empty result")
+	assert.Equal("", out[0].Text())
+	assert.True(math.IsNaN(Info{}.Value()))
+
+	f.set(200, `{"status": "success", "data": {"resultType": "vector", "result": [
+		{"metric": {}, "value": [1515150000, "+Inf"]}]}}`)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on infinite value")
+	assert.Equal("+Inf", out[0].Text())
+
+	f.set(400, `{"status": "error", "errorType": "bad_data", "error": "parse error at char 3"}`)
+	scheduler.NextTick()
+	err := tester.AssertError("on bad query")
+	assert.Contains(err, "parse error at char 3")
+
+	f.set(502, `<html>Bad Gateway</html>`)
+	p.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertEmpty("on restart")
+	err = tester.AssertError("on server error")
+	assert.Contains(err, "502")
+
+	f.set(200, `{"status": "success", "data": {"resultType": "matrix", "result": []}}`)
+	p.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertEmpty("on restart")
+	tester.AssertError("on range query")
+
+	f.set(200, `{"status": "success", "data": {"resultType": "scalar", "result": [1515150000, "abc"]}}`)
+	p.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertEmpty("on restart")
+	tester.AssertError("on malformed value")
+}
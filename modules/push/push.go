@@ -0,0 +1,288 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package push provides an i3bar module that shows messages pushed by a server.
+
+The module keeps a connection open to either a websocket (ws:// or wss:// URLs)
+or a server-sent events stream (http:// or https:// URLs), and updates its
+output whenever a message is received, instead of polling. By default the
+latest message is shown as-is, and JSON messages can be formatted using e.g.
+{{.JSON.status}} in templates. The connection is re-established automatically
+if it is lost, and server-sent event streams are resumed from the last event
+ID received.
+*/
+package push
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/websocket"
+)
+
+// Info represents the latest message received.
+type Info struct {
+	Message string
+	// Event is the event type for server-sent events, "message" by default.
+	// It is always empty for websockets.
+	Event string
+	// Received is the time the latest message was received,
+	// and Count is the number of messages received.
+	Received time.Time
+	Count    int
+}
+
+// JSON returns the message decoded as JSON, or nil if it is not valid JSON.
+func (i Info) JSON() interface{} {
+	var value interface{}
+	if json.Unmarshal([]byte(i.Message), &value) != nil {
+		return nil
+	}
+	return value
+}
+
+// Module is the public interface for a push module.
+type Module interface {
+	base.WithClickHandler
+
+	// Header adds a header to the request used to connect,
+	// e.g. for authentication. It must be called before the module is started.
+	Header(name, value string) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	url         string
+	header      http.Header
+	outputFunc  func(Info) bar.Output
+	urgentFunc  func(Info) bool
+	info        Info
+	lastEventID string
+	connErr     error
+}
+
+// New constructs an instance of the push module that connects to the given
+// websocket or server-sent events URL.
+func New(url string) Module {
+	m := &module{
+		Base:   base.New(),
+		url:    url,
+		header: http.Header{},
+	}
+	// Default output template is the latest message.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Message}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) Header(name, value string) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.header.Add(name, value)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+// Stream connects to the server, and then returns the output channel
+// from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go m.listen()
+	return ch
+}
+
+// retryDelay is how long to wait before reconnecting to the server.
+var retryDelay = 10 * time.Second
+
+func (m *module) listen() {
+	receive := m.receiveEvents
+	if strings.HasPrefix(m.url, "ws://") || strings.HasPrefix(m.url, "wss://") {
+		receive = m.receiveMessages
+	}
+	for {
+		err := receive()
+		// Show the error until the connection is re-established.
+		m.Lock()
+		m.connErr = err
+		m.Unlock()
+		m.Update()
+		time.Sleep(retryDelay)
+	}
+}
+
+// connected clears any error from a previous connection.
+func (m *module) connected() {
+	m.Lock()
+	hadErr := m.connErr != nil
+	m.connErr = nil
+	m.Unlock()
+	if hadErr {
+		m.Update()
+	}
+}
+
+func (m *module) received(event, message string) {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.info = Info{
+		Message:  message,
+		Event:    event,
+		Received: scheduler.Now(),
+		Count:    m.info.Count + 1,
+	}
+}
+
+// receiveMessages receives messages from a websocket until the
+// connection fails.
+func (m *module) receiveMessages() error {
+	m.Lock()
+	header := cloneHeader(m.header)
+	m.Unlock()
+	conn, err := websocket.Dial(m.url, header)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	m.connected()
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		m.received("", string(message))
+	}
+}
+
+// receiveEvents receives server-sent events until the connection fails.
+func (m *module) receiveEvents() error {
+	req, err := http.NewRequest("GET", m.url, nil)
+	if err != nil {
+		return err
+	}
+	m.Lock()
+	req.Header = cloneHeader(m.header)
+	if m.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", m.lastEventID)
+	}
+	m.Unlock()
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", m.url, response.Status)
+	}
+	m.connected()
+	var event string
+	var data []string
+	s := bufio.NewScanner(response.Body)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			// A blank line dispatches the event, if it has any data.
+			if data != nil {
+				if event == "" {
+					event = "message"
+				}
+				m.received(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+			continue
+		}
+		field, value := line, ""
+		if idx := strings.Index(line, ":"); idx >= 0 {
+			field, value = line[:idx], strings.TrimPrefix(line[idx+1:], " ")
+		}
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			m.Lock()
+			m.lastEventID = value
+			m.Unlock()
+		}
+		// Lines starting with ":" are comments, often used as keep-alives.
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s: stream closed", m.url)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	clone := http.Header{}
+	for name, values := range h {
+		clone[name] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+func (m *module) update() {
+	m.Lock()
+	if err := m.connErr; err != nil {
+		m.Unlock()
+		m.Error(err)
+		return
+	}
+	info := m.info
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+	"github.com/soumya92/barista/websocket"
+)
+
+func init() {
+	retryDelay = 10 * time.Millisecond
+}
+
+func TestWebsocket(t *testing.T) {
+	assert := assert.New(t)
+	messages := make(chan string)
+	headers := make(chan http.Header, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		conn, _, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for msg := range messages {
+			if msg == "" {
+				// Simulate a dropped connection.
+				return
+			}
+			websocket.WriteFrame(conn, websocket.OpText, []byte(msg), false)
+		}
+	}))
+	defer server.Close()
+	defer close(messages)
+
+	p := New(strings.Replace(server.URL, "http", "ws", 1)).Header("Authorization", "Bearer abc")
+	tester := testModule.NewOutputTester(t, p)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "no messages yet")
+	assert.Equal("Bearer abc", (<-headers).Get("Authorization"))
+
+	messages <- "hello"
+	out = tester.AssertOutput("on message")
+	assert.Equal("hello", out[0].Text())
+
+	var info Info
+	p.OutputTemplate(outputs.TextTemplate(`{{.JSON.status}} ({{.Count}})`))
+	messages <- `{"status": "green"}`
+	tester.AssertOutput("on template change")
+	out = tester.AssertOutput("on message")
+	assert.Equal("green (2)", out[0].Text())
+
+	var mu sync.Mutex
+	p.OutputFunc(func(i Info) bar.Output {
+		mu.Lock()
+		defer mu.Unlock()
+		info = i
+		return outputs.Text(i.Message)
+	})
+	p.UrgentWhen(func(i Info) bool { return i.Message == "alert" })
+	tester.AssertOutput("on output func change")
+	tester.AssertOutput("on urgent func change")
+	mu.Lock()
+	assert.Equal("", info.Event)
+	mu.Unlock()
+	assert.Nil(Info{Message: "not json"}.JSON())
+
+	messages <- ""
+	tester.AssertError("on disconnect")
+	<-headers
+	tester.AssertOutput("on reconnect")
+	messages <- "alert"
+	out = tester.AssertOutput("on message after reconnect")
+	assert.Equal("alert", out[0].Text())
+	assert.Equal(true, out[0]["urgent"])
+}
+
+func TestServerSentEvents(t *testing.T) {
+	assert := assert.New(t)
+	events := make(chan string)
+	headers := make(chan http.Header, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		headers <- r.Header
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for e := range events {
+			if e == "" {
+				return
+			}
+			io.WriteString(w, e)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+	defer close(events)
+
+	p := New(server.URL + "/events")
+	tester := testModule.NewOutputTester(t, p)
+	tester.AssertOutput("on start")
+	h := <-headers
+	assert.Equal("text/event-stream", h.Get("Accept"))
+	assert.Equal("", h.Get("Last-Event-ID"))
+
+	var mu sync.Mutex
+	var info Info
+	p.OutputFunc(func(i Info) bar.Output {
+		mu.Lock()
+		defer mu.Unlock()
+		info = i
+		return outputs.Text(i.Message)
+	})
+	tester.AssertOutput("on output func change")
+
+	events <- ": keep-alive\n\ndata: first\n\n"
+	out := tester.AssertOutput("on event")
+	assert.Equal("first", out[0].Text())
+	mu.Lock()
+	assert.Equal("message", info.Event)
+	mu.Unlock()
+
+	events <- "event: status\nid: 42\ndata: line one\ndata:line two\n\n"
+	out = tester.AssertOutput("on multi-line event")
+	assert.Equal("line one\nline two", out[0].Text())
+	mu.Lock()
+	assert.Equal("status", info.Event)
+	assert.Equal(2, info.Count)
+	mu.Unlock()
+
+	events <- "event: ping\n\n"
+	tester.AssertNoOutput("on event without data")
+
+	events <- ""
+	tester.AssertError("on stream closed")
+	assert.Equal("42", (<-headers).Get("Last-Event-ID"), "resumes from last id")
+	tester.AssertOutput("on reconnect")
+
+	tester = testModule.NewOutputTester(t, New(server.URL+"/missing"))
+	tester.AssertOutput("on start")
+	tester.AssertError("on not found")
+}
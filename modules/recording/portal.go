@@ -0,0 +1,189 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	portalPrefix   = "org.freedesktop.portal."
+	screenCast     = portalPrefix + "ScreenCast"
+	sessionIface   = portalPrefix + "Session"
+	requestIface   = portalPrefix + "Request"
+	requestPrefix  = "/org/freedesktop/portal/desktop/request/"
+	dbusIface      = "org.freedesktop.DBus"
+	nameOwnerEvent = "NameOwnerChanged"
+)
+
+// matchRules select the portal calls and signals needed to track the
+// lifecycle of screencast sessions.
+var matchRules = []string{
+	"type='method_call',interface='" + screenCast + "',member='Start'",
+	"type='method_call',interface='" + sessionIface + "',member='Close'",
+	"type='signal',interface='" + sessionIface + "',member='Closed'",
+	"type='signal',interface='" + requestIface + "',member='Response'",
+	"type='signal',interface='" + dbusIface + "',member='" + nameOwnerEvent + "'",
+}
+
+// monitor sets up a private session bus connection that receives
+// all the messages matched by matchRules.
+var monitor = func() (<-chan *dbus.Message, error) {
+	// Monitoring turns the connection into a receive-only connection,
+	// so a private connection is required.
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		return nil, err
+	}
+	// Prefer BecomeMonitor, but fall back to the deprecated eavesdropping
+	// for older buses that don't support monitoring.
+	err = conn.BusObject().Call(dbusIface+".Monitoring.BecomeMonitor", 0,
+		matchRules, uint32(0)).Err
+	if err != nil {
+		for _, rule := range matchRules {
+			err = conn.BusObject().Call(dbusIface+".AddMatch", 0,
+				rule+",eavesdrop='true'").Err
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	c := make(chan *dbus.Message, 10)
+	conn.Eavesdrop(c)
+	return c, nil
+}
+
+// sessions tracks active screencast sessions, and the bus names
+// of the clients that own them.
+type sessions struct {
+	sync.Mutex
+	// pending maps request paths to the sessions being started.
+	pending map[dbus.ObjectPath]session
+	active  map[dbus.ObjectPath]string
+}
+
+type session struct {
+	path   dbus.ObjectPath
+	client string
+}
+
+func newSessions() *sessions {
+	return &sessions{
+		pending: map[dbus.ObjectPath]session{},
+		active:  map[dbus.ObjectPath]string{},
+	}
+}
+
+func (s *sessions) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.active)
+}
+
+// requestPath returns the path of the request object the portal creates
+// for a call from the given client, using the given handle token.
+func requestPath(client, token string) dbus.ObjectPath {
+	sender := strings.Replace(strings.TrimPrefix(client, ":"), ".", "_", -1)
+	return dbus.ObjectPath(requestPrefix + sender + "/" + token)
+}
+
+func header(msg *dbus.Message, field dbus.HeaderField) string {
+	val := msg.Headers[field].Value()
+	switch v := val.(type) {
+	case string:
+		return v
+	case dbus.ObjectPath:
+		return string(v)
+	}
+	return ""
+}
+
+// handle updates the sessions from a monitored message,
+// and returns true if the number of active sessions changed.
+func (s *sessions) handle(msg *dbus.Message) bool {
+	s.Lock()
+	defer s.Unlock()
+	before := len(s.active)
+	path := dbus.ObjectPath(header(msg, dbus.FieldPath))
+	switch header(msg, dbus.FieldInterface) + "." + header(msg, dbus.FieldMember) {
+	case screenCast + ".Start":
+		// Start(session_handle, parent_window, options).
+		if msg.Type != dbus.TypeMethodCall || len(msg.Body) < 3 {
+			break
+		}
+		sessionPath, _ := msg.Body[0].(dbus.ObjectPath)
+		client := header(msg, dbus.FieldSender)
+		sess := session{sessionPath, client}
+		options, _ := msg.Body[2].(map[string]dbus.Variant)
+		token, _ := options["handle_token"].Value().(string)
+		if token == "" {
+			// Without a token, the request path cannot be predicted,
+			// so assume that the screencast was started.
+			s.active[sessionPath] = client
+			break
+		}
+		s.pending[requestPath(client, token)] = sess
+	case requestIface + ".Response":
+		sess, ok := s.pending[path]
+		if !ok {
+			break
+		}
+		delete(s.pending, path)
+		// Response(response, results), where 0 indicates success.
+		if len(msg.Body) > 0 {
+			if code, _ := msg.Body[0].(uint32); code == 0 {
+				s.active[sess.path] = sess.client
+			}
+		}
+	case sessionIface + ".Close", sessionIface + ".Closed":
+		delete(s.active, path)
+	case dbusIface + "." + nameOwnerEvent:
+		// NameOwnerChanged(name, old_owner, new_owner), where an empty
+		// new owner means the client has disconnected.
+		if len(msg.Body) < 3 {
+			break
+		}
+		name, _ := msg.Body[0].(string)
+		newOwner, _ := msg.Body[2].(string)
+		if newOwner != "" {
+			break
+		}
+		for path, client := range s.active {
+			if client == name {
+				delete(s.active, path)
+			}
+		}
+	}
+	return len(s.active) != before
+}
+
+// listen tracks screencast sessions from the monitored messages.
+func (m *module) listen(c <-chan *dbus.Message) {
+	for msg := range c {
+		if m.sessions.handle(msg) {
+			m.Update()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package recording provides an i3bar module that indicates active screen recording.
+
+Recording is detected in two ways: by looking for known screen recorder
+processes (e.g. wf-recorder), and by monitoring the session bus for PipeWire
+screencast sessions created through the xdg-desktop-portal ScreenCast
+interface, which is used by browsers and video conferencing apps to share the
+screen on wayland. By default, nothing is shown unless recording is active,
+in which case an urgent indicator is shown.
+*/
+package recording
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Recorders are the names of screen recorder processes that are detected.
+var Recorders = []string{
+	"wf-recorder",
+	"wl-screenrec",
+	"gpu-screen-recorder",
+	"simplescreenrecorder",
+	"kazam",
+	"peek",
+}
+
+// Info represents the active screen recordings.
+type Info struct {
+	// Recorders are the names of running screen recorder processes.
+	Recorders []string
+	// Screencasts is the number of active portal screencast sessions.
+	Screencasts int
+}
+
+// Active returns true if the screen is being recorded or shared.
+func (i Info) Active() bool {
+	return len(i.Recorders) > 0 || i.Screencasts > 0
+}
+
+// Module represents a screen recording bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency for recorder processes.
+	// Screencast sessions are always detected immediately.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+	sessions   *sessions
+}
+
+// New constructs an instance of the screen recording module.
+func New() Module {
+	m := &module{
+		Base:     base.New(),
+		sessions: newSessions(),
+	}
+	m.RefreshInterval(2 * time.Second)
+	// Default output template is a recording indicator, only while recording.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Active}}REC{{end}}`))
+	// Recording should be prominent by default.
+	m.UrgentWhen(Info.Active)
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+// Stream sets up screencast monitoring and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	c, err := monitor()
+	if m.Error(err) {
+		return ch
+	}
+	go m.listen(c)
+	return ch
+}
+
+var fs = hostfs.Fs()
+
+// findRecorders returns the names of all running screen recorders.
+func findRecorders() []string {
+	comms, _ := afero.Glob(fs, "/proc/*/comm")
+	found := map[string]bool{}
+	for _, file := range comms {
+		comm, err := afero.ReadFile(fs, file)
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSpace(string(comm))
+		for _, r := range Recorders {
+			if name == r {
+				found[r] = true
+			}
+		}
+	}
+	var recorders []string
+	for r := range found {
+		recorders = append(recorders, r)
+	}
+	sort.Strings(recorders)
+	return recorders
+}
+
+func (m *module) update() {
+	info := Info{
+		Recorders:   findRecorders(),
+		Screencasts: m.sessions.count(),
+	}
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,118 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func message(typ dbus.Type, sender, path, iface, member string, body ...interface{}) *dbus.Message {
+	return &dbus.Message{
+		Type: typ,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldSender:    dbus.MakeVariant(sender),
+			dbus.FieldPath:      dbus.MakeVariant(dbus.ObjectPath(path)),
+			dbus.FieldInterface: dbus.MakeVariant(iface),
+			dbus.FieldMember:    dbus.MakeVariant(member),
+		},
+		Body: body,
+	}
+}
+
+const sessionPath = "/org/freedesktop/portal/desktop/session/1_42/s1"
+
+func start(client, token string) *dbus.Message {
+	options := map[string]dbus.Variant{}
+	if token != "" {
+		options["handle_token"] = dbus.MakeVariant(token)
+	}
+	return message(dbus.TypeMethodCall, client, "/org/freedesktop/portal/desktop",
+		screenCast, "Start", dbus.ObjectPath(sessionPath), "", options)
+}
+
+func response(path string, code uint32) *dbus.Message {
+	return message(dbus.TypeSignal, ":1.7", path, requestIface, "Response",
+		code, map[string]dbus.Variant{})
+}
+
+func TestSessions(t *testing.T) {
+	assert := assert.New(t)
+	s := newSessions()
+	reqPath := "/org/freedesktop/portal/desktop/request/1_42/t1"
+
+	assert.False(s.handle(start(":1.42", "t1")), "pending until response")
+	assert.False(s.handle(response(reqPath+"x", 0)), "unrelated response")
+	assert.True(s.handle(response(reqPath, 0)))
+	assert.Equal(1, s.count())
+
+	assert.True(s.handle(message(dbus.TypeMethodCall, ":1.42", sessionPath,
+		sessionIface, "Close")))
+	assert.Equal(0, s.count())
+
+	s.handle(start(":1.42", "t1"))
+	assert.False(s.handle(response(reqPath, 1)), "cancelled by user")
+	assert.Equal(0, s.count())
+
+	assert.True(s.handle(start(":1.42", "")), "assumed started without token")
+	assert.False(s.handle(message(dbus.TypeSignal, "org.freedesktop.DBus", "/org/freedesktop/DBus",
+		dbusIface, nameOwnerEvent, ":1.43", ":1.43", "")), "other client disconnected")
+	assert.True(s.handle(message(dbus.TypeSignal, "org.freedesktop.DBus", "/org/freedesktop/DBus",
+		dbusIface, nameOwnerEvent, ":1.42", ":1.42", "")), "client disconnected")
+	assert.Equal(0, s.count())
+}
+
+func TestRecording(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "/proc/1/comm", []byte("init\n"), 0644)
+	messages := make(chan *dbus.Message, 10)
+	monitor = func() (<-chan *dbus.Message, error) { return messages, nil }
+
+	r := New()
+	tester := testModule.NewOutputTester(t, r)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "nothing shown when not recording")
+
+	afero.WriteFile(fs, "/proc/100/comm", []byte("wf-recorder\n"), 0644)
+	scheduler.NextTick()
+	out = tester.AssertOutput("when recorder starts")
+	assert.Equal(bar.NewSegment("REC").Urgent(true), out[0])
+
+	var info Info
+	r.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Text("x")
+	})
+	fs.Remove("/proc/100/comm")
+	messages <- start(":1.42", "")
+	tester.AssertOutput("on output func change")
+	tester.AssertOutput("on screencast start")
+	assert.Equal(Info{Screencasts: 1}, info)
+
+	messages <- message(dbus.TypeSignal, ":1.7", sessionPath, sessionIface, "Closed",
+		map[string]dbus.Variant{})
+	tester.AssertOutput("on screencast end")
+	assert.False(info.Active())
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reformat
+
+import (
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/pango"
+)
+
+// Chain returns a format function that applies the given format functions
+// in order, e.g. to truncate an output and then add a prefix.
+func Chain(formatFuncs ...FormatFunc) FormatFunc {
+	return func(o bar.Output) bar.Output {
+		for _, f := range formatFuncs {
+			o = f(o)
+		}
+		return o
+	}
+}
+
+// Prefix returns a format function that adds the given segments, e.g. an
+// icon, before the module's output, without a separator between them.
+// Empty outputs are not changed, so hidden modules stay hidden.
+func Prefix(prefix bar.Output) FormatFunc {
+	return func(o bar.Output) bar.Output {
+		if len(o) == 0 || len(prefix) == 0 {
+			return o
+		}
+		out := make(bar.Output, 0, len(prefix)+len(o))
+		for _, s := range prefix {
+			out = append(out, copySegment(s))
+		}
+		out.Separator(false)
+		return append(out, o...)
+	}
+}
+
+// Truncate returns a format function that shortens the full and short text
+// of each segment to at most the given number of characters, ending with an
+// ellipsis. Segments using pango markup are not changed, since truncating
+// the markup could leave it invalid. The original output is not modified.
+func Truncate(length int) FormatFunc {
+	return func(o bar.Output) bar.Output {
+		out := make(bar.Output, 0, len(o))
+		for _, s := range o {
+			s = copySegment(s)
+			if s["markup"] != bar.MarkupPango {
+				for _, key := range []string{"full_text", "short_text"} {
+					if text, ok := s[key].(string); ok {
+						s[key] = truncate(text, length)
+					}
+				}
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+}
+
+// Tabular returns a format function that renders the full and short text
+// of each segment with tabular (fixed-width) digits, so that numbers which
+// change every refresh do not shift the rest of the bar. Plain text segments
+// are converted to pango markup. The font must support the OpenType 'tnum'
+// feature; see also format.Pad. The original output is not modified.
+func Tabular() FormatFunc {
+	return func(o bar.Output) bar.Output {
+		out := make(bar.Output, 0, len(o))
+		for _, s := range o {
+			s = copySegment(s)
+			isPango := s["markup"] == bar.MarkupPango
+			for _, key := range []string{"full_text", "short_text"} {
+				if text, ok := s[key].(string); ok {
+					var node pango.Node = pango.Text(text)
+					if isPango {
+						node = markup(text)
+					}
+					s[key] = pango.Span(pango.TabularNumbers, node).Pango()
+				}
+			}
+			out = append(out, s.Markup(bar.MarkupPango))
+		}
+		return out
+	}
+}
+
+// markup is a pango node for text that is already pango markup.
+type markup string
+
+func (m markup) Pango() string {
+	return string(m)
+}
+
+// truncate shortens text to at most length characters, ending with an
+// ellipsis if any characters were removed.
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length || length <= 0 {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+// copySegment returns a copy of the segment, so that format functions do not
+// modify static outputs or the original module's output.
+func copySegment(s bar.Segment) bar.Segment {
+	c := bar.Segment{}
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
@@ -23,6 +23,13 @@ which don't allow for colours or borders. You can add those using reformat:
  r := reformat.New(t, func(o bar.Output) bar.Output {
    return o.Background("red").SeparatorWidth(20)
  })
+
+Some common transformations are provided, which can be combined using Chain:
+
+ m := reformat.New(media.New("spotify"), reformat.Chain(
+   reformat.Truncate(30),
+   reformat.Prefix(outputs.Pango(icon)),
+ ))
 */
 package reformat
 
@@ -52,3 +52,51 @@ func TestReformat(t *testing.T) {
 	original.AssertNoPauseResume("when reformatted module is not paused/resumed")
 	original.AssertNotClicked("when reformatted module is not clicked")
 }
+
+func TestFormatFuncs(t *testing.T) {
+	assert.Equal(t, "a long t…",
+		Truncate(9)(outputs.Text("a long title"))[0].Text(), "truncates long text")
+	assert.Equal(t, "short",
+		Truncate(9)(outputs.Text("short"))[0].Text(), "short text is unchanged")
+	assert.Equal(t, "ünïcödé …",
+		Truncate(9)(outputs.Text("ünïcödé text"))[0].Text(), "counts characters")
+	pango := outputs.PangoUnsafe("<b>a long title</b>")
+	assert.Equal(t, "<b>a long title</b>", Truncate(5)(pango)[0].Text(),
+		"pango markup is not truncated")
+	original := outputs.Text("a long title")
+	original[0].ShortText("short title")
+	out := Truncate(6)(original)
+	assert.Equal(t, "a lon…", out[0].Text())
+	assert.Equal(t, "short…", out[0]["short_text"], "truncates short text")
+	assert.Equal(t, "a long title", original[0].Text(), "original is not modified")
+	assert.Equal(t, "short title", original[0]["short_text"], "original is not modified")
+
+	prefix := outputs.Text("icon")
+	out = Prefix(prefix)(outputs.Text("text"))
+	assert.Equal(t, 2, len(out))
+	assert.Equal(t, "icon", out[0].Text())
+	assert.Equal(t, false, out[0]["separator"], "no separator after prefix")
+	assert.Equal(t, "text", out[1].Text())
+	assert.Nil(t, prefix[0]["separator"], "prefix is not modified")
+	assert.Empty(t, Prefix(prefix)(outputs.Empty()), "empty output stays empty")
+
+	out = Chain(Truncate(3), Prefix(prefix))(outputs.Text("text"))
+	assert.Equal(t, "te…", out[1].Text(), "chained format functions")
+	assert.Equal(t, "icon", out[0].Text(), "applied in order")
+}
+
+func TestTabular(t *testing.T) {
+	original := outputs.Text("7% <cpu>")
+	original[0].ShortText("7%")
+	out := Tabular()(original)
+	assert.Equal(t, "<span font_features='tnum'>7% &lt;cpu&gt;</span>", out[0].Text())
+	assert.Equal(t, "<span font_features='tnum'>7%</span>", out[0]["short_text"])
+	assert.Equal(t, bar.MarkupPango, out[0]["markup"], "plain text is escaped to pango")
+	assert.Equal(t, "7% <cpu>", original[0].Text(), "original is not modified")
+	assert.Nil(t, original[0]["markup"], "original is not modified")
+
+	out = Tabular()(outputs.PangoUnsafe("<b>42</b>%"))
+	assert.Equal(t, "<span font_features='tnum'><b>42</b>%</span>", out[0].Text(),
+		"pango markup is wrapped")
+	assert.Empty(t, Tabular()(outputs.Empty()), "empty output stays empty")
+}
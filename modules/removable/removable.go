@@ -0,0 +1,316 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package removable provides an i3bar module that shows removable drives.
+
+Drives are listed using UDisks2 over the system bus, and the module updates
+whenever UDisks2 signals a change, e.g. when a drive is attached or a volume
+is mounted. Each filesystem on a removable drive is shown as a volume, with
+its mount state and, if mounted, the free space. The default output shows each
+volume as a separate segment, which allows the click handler to mount, unmount,
+or eject the clicked volume.
+*/
+package removable
+
+import (
+	"syscall"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/format"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Bytes represents a size in bytes.
+type Bytes uint64
+
+// In gets the size in a specific unit, e.g. "b" or "MB".
+func (b Bytes) In(unit string) float64 {
+	base, err := humanize.ParseBytes("1" + unit)
+	if err != nil {
+		base = 1
+	}
+	return float64(b) / float64(base)
+}
+
+// IEC returns the size formatted in base 2.
+func (b Bytes) IEC() string {
+	return format.IBytes(uint64(b))
+}
+
+// SI returns the size formatted in base 10.
+func (b Bytes) SI() string {
+	return format.Bytes(uint64(b))
+}
+
+// Volume represents a filesystem on a removable drive.
+type Volume struct {
+	// ID uniquely identifies the volume, and is used as the segment
+	// instance in the default output.
+	ID string
+	// Drive is the vendor and model of the drive.
+	Drive  string
+	Label  string
+	Device string
+	// MountPoint is where the volume is mounted, or empty if not mounted.
+	MountPoint string
+	Size       Bytes
+	// Free is the space available on the volume, only set if mounted.
+	Free Bytes
+	// driveID identifies the drive, which is needed to eject it.
+	driveID string
+}
+
+// Mounted returns true if the volume is mounted.
+func (v Volume) Mounted() bool {
+	return v.MountPoint != ""
+}
+
+// Name returns the label of the volume if available,
+// falling back to the device name.
+func (v Volume) Name() string {
+	if v.Label != "" {
+		return v.Label
+	}
+	return v.Device
+}
+
+// Info represents the volumes on all removable drives, sorted by device.
+type Info []Volume
+
+// Count returns the number of volumes.
+func (i Info) Count() int {
+	return len(i)
+}
+
+// Find returns the volume with the given ID, and false if there is no such volume.
+func (i Info) Find(id string) (Volume, bool) {
+	for _, v := range i {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return Volume{}, false
+}
+
+// Controller provides an interface to control volumes from the click handler.
+type Controller interface {
+	// Mount mounts the volume with the given ID.
+	Mount(id string)
+	// Unmount unmounts the volume with the given ID.
+	Unmount(id string)
+	// Eject unmounts all volumes on the same drive as the volume with
+	// the given ID, and then ejects the drive.
+	Eject(id string)
+}
+
+// Module is the public interface for a removable drives module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to mount, unmount, and eject volumes.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// backend lists and controls volumes on removable drives.
+type backend interface {
+	volumes() ([]Volume, error)
+	mount(Volume) error
+	unmount(Volume) error
+	eject(driveID string) error
+	// watch calls the given function whenever the volumes change.
+	// It only returns on error.
+	watch(func()) error
+}
+
+type module struct {
+	*base.Base
+	backend    backend
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the removable drives module.
+func New() Module {
+	return newModule(&udisks{})
+}
+
+func newModule(b backend) *module {
+	m := &module{
+		Base:    base.New(),
+		backend: b,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows each volume as a segment, with the free space if
+// mounted, using the volume ID as the instance, so click handlers can
+// identify the clicked volume.
+func DefaultOutput(i Info) bar.Output {
+	out := outputs.Multi()
+	for _, v := range i {
+		if v.Mounted() {
+			out.AddTextf(v.ID, "%s %s", v.Name(), v.Free.IEC())
+		} else {
+			out.AddText(v.ID, v.Name())
+		}
+	}
+	return out.Build()
+}
+
+// DefaultClickHandler mounts or unmounts the clicked volume on left click,
+// and ejects its drive on right click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	v, ok := i.Find(e.Instance)
+	if !ok {
+		return
+	}
+	switch e.Button {
+	case bar.ButtonLeft:
+		if v.Mounted() {
+			c.Unmount(v.ID)
+		} else {
+			c.Mount(v.ID)
+		}
+	case bar.ButtonRight:
+		c.Eject(v.ID)
+	}
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// volume returns the current volume with the given ID.
+func (m *module) volume(id string) (Volume, bool) {
+	m.Lock()
+	defer m.Unlock()
+	return m.info.Find(id)
+}
+
+// Mounting and ejecting can take a while, and UDisks2 signals the changes,
+// so all controller operations are asynchronous and do not update directly.
+
+func (m *module) Mount(id string) {
+	if v, ok := m.volume(id); ok {
+		go func() { m.Error(m.backend.mount(v)) }()
+	}
+}
+
+func (m *module) Unmount(id string) {
+	if v, ok := m.volume(id); ok {
+		go func() { m.Error(m.backend.unmount(v)) }()
+	}
+}
+
+func (m *module) Eject(id string) {
+	v, ok := m.volume(id)
+	if !ok {
+		return
+	}
+	m.Lock()
+	var mounted []Volume
+	for _, other := range m.info {
+		if other.driveID == v.driveID && other.Mounted() {
+			mounted = append(mounted, other)
+		}
+	}
+	m.Unlock()
+	go func() {
+		for _, other := range mounted {
+			if m.Error(m.backend.unmount(other)) {
+				return
+			}
+		}
+		m.Error(m.backend.eject(v.driveID))
+	}()
+}
+
+// Stream starts watching for changes, and then returns
+// the output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go func() { m.Error(m.backend.watch(m.Update)) }()
+	return ch
+}
+
+// statfs returns the space available on the filesystem at the given path.
+var statfs = func(path string) (Bytes, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return Bytes(st.Bavail * uint64(st.Bsize)), nil
+}
+
+func (m *module) update() {
+	volumes, err := m.backend.volumes()
+	if m.Error(err) {
+		return
+	}
+	info := Info(volumes)
+	for idx, v := range info {
+		if !v.Mounted() {
+			continue
+		}
+		// The volume might be unmounted before it can be checked,
+		// so errors here are not fatal.
+		if free, err := statfs(v.MountPoint); err == nil {
+			info[idx].Free = free
+		}
+	}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
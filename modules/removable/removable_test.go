@@ -0,0 +1,148 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package removable
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestVolumesFromObjects(t *testing.T) {
+	v := dbus.MakeVariant
+	objects := managedObjects{
+		"/org/freedesktop/UDisks2/drives/SanDisk_Cruzer": {
+			driveIface: {"Vendor": v("SanDisk"), "Model": v("Cruzer"), "Removable": v(true)},
+		},
+		"/org/freedesktop/UDisks2/drives/Samsung_SSD": {
+			driveIface: {"Vendor": v(""), "Model": v("Samsung SSD"), "Removable": v(false)},
+		},
+		"/org/freedesktop/UDisks2/block_devices/sdb": {
+			blockIface: {"Drive": v(dbus.ObjectPath("/org/freedesktop/UDisks2/drives/SanDisk_Cruzer"))},
+		},
+		"/org/freedesktop/UDisks2/block_devices/sdb1": {
+			blockIface: {
+				"Drive":   v(dbus.ObjectPath("/org/freedesktop/UDisks2/drives/SanDisk_Cruzer")),
+				"Device":  v([]byte("/dev/sdb1\x00")),
+				"IdLabel": v("BACKUP"),
+				"Size":    v(uint64(16000000000)),
+			},
+			fsIface: {"MountPoints": v([][]byte{[]byte("/run/media/user/BACKUP\x00")})},
+		},
+		"/org/freedesktop/UDisks2/block_devices/sda1": {
+			blockIface: {
+				"Drive":  v(dbus.ObjectPath("/org/freedesktop/UDisks2/drives/Samsung_SSD")),
+				"Device": v([]byte("/dev/sda1\x00")),
+			},
+			fsIface: {"MountPoints": v([][]byte{[]byte("/\x00")})},
+		},
+	}
+	assert.Equal(t, []Volume{{
+		ID:         "/org/freedesktop/UDisks2/block_devices/sdb1",
+		Drive:      "SanDisk Cruzer",
+		Label:      "BACKUP",
+		Device:     "/dev/sdb1",
+		MountPoint: "/run/media/user/BACKUP",
+		Size:       16000000000,
+		driveID:    "/org/freedesktop/UDisks2/drives/SanDisk_Cruzer",
+	}}, volumesFromObjects(objects), "only filesystems on removable drives")
+}
+
+type testBackend struct {
+	sync.Mutex
+	vols    []Volume
+	err     error
+	changed chan func()
+	actions chan string
+}
+
+func (t *testBackend) volumes() ([]Volume, error) {
+	t.Lock()
+	defer t.Unlock()
+	return append([]Volume(nil), t.vols...), t.err
+}
+
+func (t *testBackend) mount(v Volume) error {
+	t.actions <- "mount " + v.ID
+	return nil
+}
+
+func (t *testBackend) unmount(v Volume) error {
+	t.actions <- "unmount " + v.ID
+	return nil
+}
+
+func (t *testBackend) eject(driveID string) error {
+	t.actions <- "eject " + driveID
+	return nil
+}
+
+func (t *testBackend) watch(f func()) error {
+	t.changed <- f
+	select {}
+}
+
+func TestRemovable(t *testing.T) {
+	assert := assert.New(t)
+	statfs = func(path string) (Bytes, error) {
+		if path == "/media/b" {
+			return 0, errors.New("not mounted")
+		}
+		return 1536 * 1024 * 1024, nil
+	}
+	b := &testBackend{changed: make(chan func(), 1), actions: make(chan string, 10)}
+	m := newModule(b)
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("on start")
+	assert.Empty(out, "no volumes")
+	notify := <-b.changed
+
+	b.Lock()
+	b.vols = []Volume{
+		{ID: "a1", Device: "/dev/sdb1", Label: "DATA", MountPoint: "/media/a", driveID: "a"},
+		{ID: "a2", Device: "/dev/sdb2", driveID: "a"},
+		{ID: "b1", Device: "/dev/sdc1", MountPoint: "/media/b", driveID: "b"},
+	}
+	b.Unlock()
+	notify()
+	out = tester.AssertOutput("on change")
+	assert.Equal(3, len(out))
+	assert.Equal("DATA 1.5 GiB", out[0].Text())
+	assert.Equal("/dev/sdb2", out[1].Text())
+	assert.Equal("/dev/sdc1 0 B", out[2].Text(), "statfs error")
+
+	m.Click(bar.Event{Button: bar.ButtonLeft, Instance: "a1"})
+	assert.Equal("unmount a1", <-b.actions)
+	m.Click(bar.Event{Button: bar.ButtonLeft, Instance: "a2"})
+	assert.Equal("mount a2", <-b.actions)
+	m.Click(bar.Event{Button: bar.ButtonRight, Instance: "a2"})
+	assert.Equal("unmount a1", <-b.actions, "unmounts other volumes on drive")
+	assert.Equal("eject a", <-b.actions)
+	m.Click(bar.Event{Button: bar.ButtonLeft, Instance: "unknown"})
+	assert.Empty(b.actions)
+	tester.AssertNoOutput("actions do not update directly")
+
+	b.Lock()
+	b.err = errors.New("udisks not running")
+	b.Unlock()
+	notify()
+	tester.AssertError("on backend error")
+}
@@ -0,0 +1,176 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package removable
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	udisksDest = "org.freedesktop.UDisks2"
+	udisksPath = "/org/freedesktop/UDisks2"
+	driveIface = udisksDest + ".Drive"
+	blockIface = udisksDest + ".Block"
+	fsIface    = udisksDest + ".Filesystem"
+)
+
+// managedObjects is the result of ObjectManager.GetManagedObjects,
+// a map of object path to interface name to properties.
+type managedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+type udisks struct {
+	sync.Mutex
+	conn *dbus.Conn
+}
+
+// connection returns the system bus connection, connecting if necessary.
+func (u *udisks) connection() (*dbus.Conn, error) {
+	u.Lock()
+	defer u.Unlock()
+	if u.conn != nil {
+		return u.conn, nil
+	}
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	u.conn = conn
+	return conn, nil
+}
+
+func (u *udisks) call(path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	conn, err := u.connection()
+	if err != nil {
+		return &dbus.Call{Err: err}
+	}
+	return conn.Object(udisksDest, path).Call(method, 0, args...)
+}
+
+func (u *udisks) volumes() ([]Volume, error) {
+	var objects managedObjects
+	err := u.call(udisksPath, "org.freedesktop.DBus.ObjectManager.GetManagedObjects").
+		Store(&objects)
+	if err != nil {
+		return nil, err
+	}
+	volumes := volumesFromObjects(objects)
+	sort.Slice(volumes, func(a, b int) bool { return volumes[a].Device < volumes[b].Device })
+	return volumes, nil
+}
+
+// volumesFromObjects returns the filesystems on removable drives
+// from the UDisks2 managed objects.
+func volumesFromObjects(objects managedObjects) []Volume {
+	var volumes []Volume
+	for path, ifaces := range objects {
+		block, ok := ifaces[blockIface]
+		if !ok {
+			continue
+		}
+		fs, ok := ifaces[fsIface]
+		if !ok {
+			continue
+		}
+		if boolProp(block, "HintIgnore") {
+			continue
+		}
+		drivePath, _ := block["Drive"].Value().(dbus.ObjectPath)
+		drive, ok := objects[drivePath][driveIface]
+		if !ok {
+			continue
+		}
+		if !boolProp(drive, "Removable") && !boolProp(drive, "MediaRemovable") {
+			continue
+		}
+		v := Volume{
+			ID:      string(path),
+			Drive:   strings.TrimSpace(stringProp(drive, "Vendor") + " " + stringProp(drive, "Model")),
+			Label:   stringProp(block, "IdLabel"),
+			Device:  byteString(block["Device"]),
+			driveID: string(drivePath),
+		}
+		size, _ := block["Size"].Value().(uint64)
+		v.Size = Bytes(size)
+		if mountPoints, _ := fs["MountPoints"].Value().([][]byte); len(mountPoints) > 0 {
+			v.MountPoint = strings.TrimRight(string(mountPoints[0]), "\x00")
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes
+}
+
+func boolProp(props map[string]dbus.Variant, name string) bool {
+	val, _ := props[name].Value().(bool)
+	return val
+}
+
+func stringProp(props map[string]dbus.Variant, name string) string {
+	val, _ := props[name].Value().(string)
+	return val
+}
+
+// byteString converts a null-terminated byte array property to a string.
+func byteString(v dbus.Variant) string {
+	val, _ := v.Value().([]byte)
+	return strings.TrimRight(string(val), "\x00")
+}
+
+func (u *udisks) mount(v Volume) error {
+	return u.call(dbus.ObjectPath(v.ID), fsIface+".Mount", map[string]dbus.Variant{}).Err
+}
+
+func (u *udisks) unmount(v Volume) error {
+	return u.call(dbus.ObjectPath(v.ID), fsIface+".Unmount", map[string]dbus.Variant{}).Err
+}
+
+func (u *udisks) eject(driveID string) error {
+	return u.call(dbus.ObjectPath(driveID), driveIface+".Eject", map[string]dbus.Variant{}).Err
+}
+
+func (u *udisks) watch(f func()) error {
+	// A private connection is required since we're using Signal.
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return err
+	}
+	if err := conn.Hello(); err != nil {
+		return err
+	}
+	// Drives being added or removed are signalled by the object manager,
+	// while mounts and unmounts are signalled as property changes.
+	matchRule := strings.Join([]string{
+		"type='signal'",
+		"sender='" + udisksDest + "'",
+		"path_namespace='" + udisksPath + "'",
+	}, ",")
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		return err
+	}
+	c := make(chan *dbus.Signal, 10)
+	conn.Signal(c)
+	for range c {
+		f()
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package retry provides a module that "wraps" an existing module, and retries
+it with exponential backoff while it shows an error, e.g. for modules that
+fetch data over the network, which often fail while connecting to wifi or
+resuming from suspend.
+
+The error is shown until a retry succeeds, and left clicking the error
+retries immediately:
+
+	w := retry.New(weather.New(provider)).Backoff(10*time.Second, 10*time.Minute)
+*/
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+// Updatable is a module that can be updated on demand, such as any module
+// built on base.
+type Updatable interface {
+	bar.Module
+	Update()
+}
+
+// Module represents a retrying module, which forwards clicks and
+// pause/resume events to the wrapped module.
+type Module interface {
+	bar.Module
+	bar.Clickable
+	bar.Pausable
+
+	// Backoff sets the delay before the first retry, and the maximum delay
+	// between retries. The delay doubles after each failed retry.
+	Backoff(initial, max time.Duration) Module
+}
+
+type module struct {
+	original  Updatable
+	scheduler scheduler.Scheduler
+	mutex     sync.Mutex
+	initial   time.Duration
+	max       time.Duration
+	// delay is the delay before the pending retry, or zero if the last
+	// output was not an error.
+	delay time.Duration
+}
+
+// New wraps an existing module, and retries it while it shows an error,
+// initially after 5 seconds, backing off to a retry every 5 minutes.
+func New(original Updatable) Module {
+	m := &module{
+		original: original,
+		initial:  5 * time.Second,
+		max:      5 * time.Minute,
+	}
+	m.scheduler = scheduler.Do(original.Update)
+	return m
+}
+
+func (m *module) Backoff(initial, max time.Duration) Module {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.initial = initial
+	m.max = max
+	return m
+}
+
+// isError returns true if the output was constructed using outputs.Error.
+func isError(out bar.Output) bool {
+	if len(out) != 1 {
+		return false
+	}
+	urgent, _ := out[0]["urgent"].(bool)
+	return urgent && out[0]["short_text"] == "Error"
+}
+
+// Stream sets up the output pipeline, which schedules retries on errors.
+func (m *module) Stream() <-chan bar.Output {
+	output := make(chan bar.Output, 10)
+	go m.pipe(m.original.Stream(), output)
+	return output
+}
+
+func (m *module) pipe(input <-chan bar.Output, output chan<- bar.Output) {
+	for out := range input {
+		m.mutex.Lock()
+		if isError(out) {
+			if m.delay == 0 {
+				m.delay = m.initial
+			} else {
+				m.delay *= 2
+			}
+			if m.delay > m.max {
+				m.delay = m.max
+			}
+			m.scheduler.After(m.delay)
+		} else if m.delay > 0 {
+			m.delay = 0
+			m.scheduler.Stop()
+		}
+		m.mutex.Unlock()
+		output <- out
+	}
+}
+
+// Click retries immediately on left click while the module shows an error,
+// and otherwise passes through the click event if supported by the wrapped
+// module.
+func (m *module) Click(e bar.Event) {
+	m.mutex.Lock()
+	failed := m.delay > 0
+	m.mutex.Unlock()
+	if failed && e.Button == bar.ButtonLeft {
+		m.scheduler.Stop()
+		m.original.Update()
+		return
+	}
+	if clickable, ok := m.original.(bar.Clickable); ok {
+		clickable.Click(e)
+	}
+}
+
+// Pause passes through the pause event if supported by the wrapped module.
+func (m *module) Pause() {
+	if pausable, ok := m.original.(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// Resume passes through the resume event if supported by the wrapped module.
+func (m *module) Resume() {
+	if pausable, ok := m.original.(bar.Pausable); ok {
+		pausable.Resume()
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestRetry(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	original := testModule.New(t)
+	m := New(original).Backoff(time.Second, 5*time.Second)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("ok"))
+	tester.AssertOutput("passes through output")
+	scheduler.AdvanceBy(time.Hour)
+	original.AssertNotUpdated("without errors")
+
+	original.Output(outputs.Error(errors.New("oops")))
+	assert.Equal(t, "oops", tester.AssertError("error is shown"))
+	scheduler.AdvanceBy(999 * time.Millisecond)
+	original.AssertNotUpdated("before initial delay")
+	scheduler.AdvanceBy(time.Millisecond)
+	original.AssertUpdated("after initial delay")
+
+	for _, delay := range []time.Duration{2, 4, 5, 5} {
+		original.Output(outputs.Error(errors.New("oops")))
+		tester.AssertError("on repeated errors")
+		scheduler.AdvanceBy(delay*time.Second - time.Millisecond)
+		original.AssertNotUpdated("before backoff delay")
+		scheduler.AdvanceBy(time.Millisecond)
+		original.AssertUpdated("after backoff delay")
+	}
+
+	original.Output(outputs.Text("ok"))
+	tester.AssertOutput("on success")
+	scheduler.AdvanceBy(time.Hour)
+	original.AssertNotUpdated("after success")
+
+	original.Output(outputs.Error(errors.New("oops")))
+	tester.AssertError("error after success")
+	scheduler.AdvanceBy(time.Second)
+	original.AssertUpdated("backoff is reset after success")
+}
+
+func TestClickToRetry(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	original := testModule.New(t)
+	m := New(original)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("ok"))
+	tester.AssertOutput("passes through output")
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	original.AssertClicked("click passed through without error")
+	original.AssertNotUpdated("click without error")
+
+	original.Output(outputs.Error(errors.New("oops")))
+	tester.AssertError("error is shown")
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	original.AssertUpdated("left click on error retries")
+	original.AssertNotClicked("left click on error is not passed through")
+	scheduler.AdvanceBy(time.Hour)
+	original.AssertNotUpdated("pending retry cancelled")
+
+	m.Click(bar.Event{Button: bar.ButtonRight})
+	original.AssertClicked("other clicks passed through")
+
+	m.Pause()
+	original.AssertPaused("pause passed through")
+	m.Resume()
+	original.AssertResumed("resume passed through")
+}
@@ -0,0 +1,233 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package scratchpad provides an i3bar module that shows the windows in the
+i3 or sway scratchpad.
+
+The scratchpad is read from the layout tree whenever a window event is
+received. By default the module shows the number of windows, and nothing when
+the scratchpad is empty. Clicking the module expands it to list the window
+titles, and clicking a title shows that window.
+*/
+package scratchpad
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/i3ipc"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Window represents a window in the scratchpad.
+type Window struct {
+	ID    int64
+	Title string
+	// AppID is the wayland app_id, and Class the X11 window class.
+	AppID string
+	Class string
+}
+
+// Info represents the contents of the scratchpad.
+type Info struct {
+	Windows []Window
+	// Expanded is true if the window titles should be listed.
+	Expanded bool
+}
+
+// Count returns the number of windows in the scratchpad.
+func (i Info) Count() int {
+	return len(i.Windows)
+}
+
+// Controller provides an interface to control the scratchpad from the
+// click handler.
+type Controller interface {
+	// Toggle expands or collapses the list of window titles.
+	Toggle()
+	// Show shows the scratchpad window with the given ID.
+	Show(id int64)
+}
+
+// Module is the public interface for a scratchpad module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to list and show windows.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	ipc        i3ipc.Client
+	outputFunc func(Info) bar.Output
+	expanded   bool
+	info       Info
+}
+
+// I3 constructs an instance of the scratchpad module for i3.
+func I3() Module {
+	return newModule(i3ipc.I3())
+}
+
+// Sway constructs an instance of the scratchpad module for sway.
+func Sway() Module {
+	return newModule(i3ipc.Sway())
+}
+
+func newModule(c i3ipc.Client) *module {
+	m := &module{
+		Base: base.New(),
+		ipc:  c,
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows the number of windows in the scratchpad, or nothing if
+// it is empty. When expanded, each window title is shown as a segment, using
+// the window ID as the instance, so click handlers can identify the window.
+func DefaultOutput(i Info) bar.Output {
+	if i.Count() == 0 {
+		return outputs.Empty()
+	}
+	out := outputs.Multi()
+	out.AddTextf("", "scratch: %d", i.Count())
+	if i.Expanded {
+		for _, w := range i.Windows {
+			out.AddText(strconv.FormatInt(w.ID, 10), w.Title)
+		}
+	}
+	return out.Build()
+}
+
+// DefaultClickHandler shows the clicked window on left click if the list of
+// titles is expanded, and otherwise toggles the list.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button != bar.ButtonLeft {
+		return
+	}
+	if id, err := strconv.ParseInt(e.Instance, 10, 64); err == nil && i.Expanded {
+		c.Show(id)
+		return
+	}
+	c.Toggle()
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+func (m *module) Toggle() {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.expanded = !m.expanded
+}
+
+func (m *module) Show(id int64) {
+	// The window event will update the module.
+	m.Error(m.ipc.Command(fmt.Sprintf("[con_id=%d] scratchpad show", id)))
+}
+
+// Stream subscribes to window events, and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go func() {
+		m.Error(m.ipc.Subscribe([]string{"window"}, m.handleEvent))
+	}()
+	return ch
+}
+
+func (m *module) handleEvent(msgType uint32, payload []byte) {
+	// Windows are moved to and from the scratchpad with "move" and
+	// "focus" events, but titles can also change, so any window event
+	// could change the output.
+	if msgType == i3ipc.WindowEvent {
+		m.Update()
+	}
+}
+
+// scratchpadName is the name of the hidden workspace that holds
+// scratchpad windows, in both i3 and sway.
+const scratchpadName = "__i3_scratch"
+
+func (m *module) update() {
+	tree, err := m.ipc.Tree()
+	if m.Error(err) {
+		return
+	}
+	var windows []Window
+	scratch, ok := tree.Find(func(n i3ipc.Node) bool {
+		return n.Type == "workspace" && n.Name == scratchpadName
+	})
+	if ok {
+		for _, n := range scratch.Windows() {
+			windows = append(windows, Window{
+				ID:    n.ID,
+				Title: n.Name,
+				AppID: n.AppID,
+				Class: n.WindowProperties.Class,
+			})
+		}
+	}
+	m.Lock()
+	if len(windows) == 0 {
+		// Collapse the list when the scratchpad is emptied, so that
+		// it doesn't expand again when a window is added.
+		m.expanded = false
+	}
+	info := Info{Windows: windows, Expanded: m.expanded}
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
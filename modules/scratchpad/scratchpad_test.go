@@ -0,0 +1,220 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scratchpad
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/i3ipc"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeI3 struct {
+	sync.Mutex
+	tree     string
+	events   chan string
+	commands chan string
+}
+
+func (f *fakeI3) setTree(tree string) {
+	f.Lock()
+	defer f.Unlock()
+	f.tree = tree
+}
+
+func (f *fakeI3) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msgType, payload, err := i3ipc.ReadMessage(conn)
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case i3ipc.Subscribe:
+			i3ipc.WriteMessage(conn, i3ipc.Subscribe, `{"success": true}`)
+			for e := range f.events {
+				i3ipc.WriteMessage(conn, i3ipc.WindowEvent, e)
+			}
+			return
+		case i3ipc.GetTree:
+			f.Lock()
+			tree := f.tree
+			f.Unlock()
+			i3ipc.WriteMessage(conn, i3ipc.GetTree, tree)
+		case i3ipc.RunCommand:
+			f.commands <- string(payload)
+			i3ipc.WriteMessage(conn, i3ipc.RunCommand, `[{"success": true}]`)
+		}
+	}
+}
+
+func (f *fakeI3) listen(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "i3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "ipc.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return sock, func() {
+		close(f.events)
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// i3 wraps scratchpad windows in floating containers.
+const i3Tree = `{"type": "root", "nodes": [
+	{"type": "output", "name": "__i3", "nodes": [
+		{"type": "con", "name": "content", "nodes": [
+			{"type": "workspace", "name": "__i3_scratch", "floating_nodes": [
+				{"type": "floating_con", "id": 10, "nodes": [
+					{"type": "con", "id": 11, "name": "htop",
+						"window_properties": {"class": "URxvt"}}]},
+				{"type": "floating_con", "id": 20, "nodes": [
+					{"type": "con", "id": 21, "name": "Music",
+						"window_properties": {"class": "Spotify"}}]}
+			]}
+		]}
+	]},
+	{"type": "output", "name": "DP-1", "nodes": [
+		{"type": "workspace", "name": "1", "nodes": [
+			{"type": "con", "id": 30, "name": "vim", "focused": true}]}
+	]}
+]}`
+
+func TestI3(t *testing.T) {
+	assert := assert.New(t)
+	i3 := &fakeI3{
+		tree:     i3Tree,
+		events:   make(chan string),
+		commands: make(chan string, 10),
+	}
+	sock, cleanup := i3.listen(t)
+	defer cleanup()
+	os.Setenv("I3SOCK", sock)
+
+	s := I3()
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal(1, len(out))
+	assert.Equal("scratch: 2", out[0].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on expand")
+	assert.Equal(3, len(out))
+	assert.Equal("htop", out[1].Text())
+	assert.Equal(bar.NewSegment("Music").Instance("21"), out[2])
+
+	s.Click(bar.Event{Button: bar.ButtonLeft, Instance: "21"})
+	assert.Equal("[con_id=21] scratchpad show", <-i3.commands)
+	tester.AssertNoOutput("until window event")
+
+	i3.setTree(`{"type": "root", "nodes": [
+		{"type": "workspace", "name": "__i3_scratch", "floating_nodes": [
+			{"type": "floating_con", "id": 10, "nodes": [
+				{"type": "con", "id": 11, "name": "htop"}]}]}]}`)
+	i3.events <- `{"change": "move"}`
+	out = tester.AssertOutput("on window event")
+	assert.Equal(2, len(out), "still expanded")
+	assert.Equal("htop", out[1].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on collapse")
+	assert.Equal(1, len(out))
+
+	s.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertNoOutput("on right click")
+
+	var info Info
+	s.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(Info{Windows: []Window{{ID: 11, Title: "htop"}}}, info)
+
+	i3.setTree(`{"type": "root", "nodes": []}`)
+	i3.events <- `{"change": "move"}`
+	tester.AssertOutput("on window event")
+	assert.Equal(0, info.Count())
+}
+
+func TestSway(t *testing.T) {
+	assert := assert.New(t)
+	sway := &fakeI3{
+		// sway puts scratchpad windows directly in the workspace.
+		tree: `{"type": "root", "nodes": [
+			{"type": "output", "name": "__i3", "nodes": [
+				{"type": "workspace", "name": "__i3_scratch", "floating_nodes": [
+					{"type": "floating_con", "id": 4, "name": "foot", "app_id": "foot"}]}]}]}`,
+		events:   make(chan string),
+		commands: make(chan string, 10),
+	}
+	sock, cleanup := sway.listen(t)
+	defer cleanup()
+	os.Setenv("SWAYSOCK", sock)
+
+	s := Sway()
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal("scratch: 1", out[0].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on expand")
+	assert.Equal("foot", out[1].Text())
+
+	sway.setTree(`{"type": "root", "nodes": [
+		{"type": "output", "name": "__i3", "nodes": [
+			{"type": "workspace", "name": "__i3_scratch"}]}]}`)
+	sway.events <- `{"change": "move"}`
+	out = tester.AssertOutput("on empty scratchpad")
+	assert.Empty(out, "nothing shown when empty")
+
+	sway.setTree(`{"type": "root", "nodes": [
+		{"type": "output", "name": "__i3", "nodes": [
+			{"type": "workspace", "name": "__i3_scratch", "floating_nodes": [
+				{"type": "floating_con", "id": 4, "name": "foot", "app_id": "foot"}]}]}]}`)
+	sway.events <- `{"change": "move"}`
+	out = tester.AssertOutput("on window moved to scratchpad")
+	assert.Equal(1, len(out), "collapsed after scratchpad was emptied")
+
+	sway.setTree(`{"bad json`)
+	sway.events <- `{"change": "title"}`
+	tester.AssertError("on invalid tree")
+
+	os.Setenv("SWAYSOCK", "")
+	tester = testModule.NewOutputTester(t, Sway())
+	tester.AssertError("without sway socket")
+}
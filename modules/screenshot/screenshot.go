@@ -0,0 +1,246 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package screenshot provides an i3bar module that takes screenshots when clicked.
+
+Screenshots are taken using a configurable tool, with commands for capturing
+the full screen, a selected region, or the active window. Tools are provided
+for maim (X11), grim (wayland), and flameshot. By default, left click captures
+a region, middle click the active window, and right click the full screen.
+After a screenshot is saved, the module briefly shows a confirmation.
+*/
+package screenshot
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Mode represents the area of the screen to capture.
+type Mode int
+
+const (
+	// FullScreen captures all screens.
+	FullScreen Mode = iota
+	// Region captures an area selected by the user.
+	Region
+	// Window captures the active window.
+	Window
+)
+
+// Tool maps each capture mode to a shell command that saves a screenshot
+// to the file in the $FILE environment variable.
+type Tool map[Mode]string
+
+// Maim returns a tool that uses maim, with xdotool to find the active window.
+func Maim() Tool {
+	return Tool{
+		FullScreen: `maim "$FILE"`,
+		Region:     `maim -s "$FILE"`,
+		Window:     `maim -i "$(xdotool getactivewindow)" "$FILE"`,
+	}
+}
+
+// Grim returns a tool that uses grim, with slurp to select a region,
+// and swaymsg and jq to find the focused window.
+func Grim() Tool {
+	return Tool{
+		FullScreen: `grim "$FILE"`,
+		Region:     `grim -g "$(slurp)" "$FILE"`,
+		Window: `grim -g "$(swaymsg -t get_tree | jq -r '.. | select(.focused?) | .rect | ` +
+			`"\(.x),\(.y) \(.width)x\(.height)"')" "$FILE"`,
+	}
+}
+
+// Flameshot returns a tool that uses flameshot. Since flameshot cannot
+// capture a single window, the window mode captures the current screen.
+func Flameshot() Tool {
+	return Tool{
+		FullScreen: `flameshot full --raw > "$FILE"`,
+		Region:     `flameshot gui --raw > "$FILE"`,
+		Window:     `flameshot screen --raw > "$FILE"`,
+	}
+}
+
+// Info represents the state of the screenshot module.
+type Info struct {
+	// File is the path of the last saved screenshot.
+	File string
+	// Saved is true for a short time after a screenshot is saved.
+	Saved bool
+}
+
+// Controller provides an interface to take screenshots from the click handler.
+type Controller interface {
+	// Capture takes a screenshot using the given mode.
+	Capture(Mode)
+}
+
+// Module is the public interface for a screenshot module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to take screenshots.
+type Module interface {
+	base.Module
+
+	// Directory sets the directory where screenshots are saved.
+	Directory(string) Module
+
+	// ConfirmFor sets how long the confirmation is shown after saving.
+	ConfirmFor(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	tool       Tool
+	dir        string
+	confirmFor time.Duration
+	outputFunc func(Info) bar.Output
+	file       string
+	savedUntil time.Time
+}
+
+// New constructs an instance of the screenshot module using the given tool.
+func New(tool Tool) Module {
+	m := &module{
+		Base: base.New(),
+		tool: tool,
+		dir:  filepath.Join(os.Getenv("HOME"), "Pictures"),
+	}
+	m.ConfirmFor(2 * time.Second)
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is a short label, and a confirmation once saved.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Saved}}saved{{else}}screenshot{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) Directory(dir string) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.dir = dir
+	return m
+}
+
+func (m *module) ConfirmFor(duration time.Duration) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.confirmFor = duration
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info()
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler captures a region on left click, the active window on
+// middle click, and the full screen on right click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ButtonLeft:
+		c.Capture(Region)
+	case bar.ButtonMiddle:
+		c.Capture(Window)
+	case bar.ButtonRight:
+		c.Capture(FullScreen)
+	}
+}
+
+// runCommand runs a shell command with $FILE set to the given file.
+var runCommand = func(command, file string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "FILE="+file)
+	return cmd.Run()
+}
+
+func (m *module) Capture(mode Mode) {
+	m.Lock()
+	command, ok := m.tool[mode]
+	dir := m.dir
+	m.Unlock()
+	if !ok {
+		return
+	}
+	file := filepath.Join(dir, scheduler.Now().Format("screenshot-2006-01-02-150405.png"))
+	go func() {
+		if m.Error(os.MkdirAll(dir, 0755)) {
+			return
+		}
+		if m.Error(runCommand(command, file)) {
+			return
+		}
+		m.Lock()
+		m.file = file
+		m.savedUntil = scheduler.Now().Add(m.confirmFor)
+		// Update again when the confirmation should be removed.
+		m.Schedule().After(m.confirmFor)
+		m.Unlock()
+		m.Update()
+	}()
+}
+
+// info returns the current state; it must be called with the lock held.
+func (m *module) info() Info {
+	return Info{
+		File:  m.file,
+		Saved: scheduler.Now().Before(m.savedUntil),
+	}
+}
+
+func (m *module) update() {
+	m.Lock()
+	out := m.outputFunc(m.info())
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package screenshot
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestScreenshot(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2017, time.December, 1, 13, 14, 15, 0, time.UTC))
+	tmpDir, err := ioutil.TempDir("", "screenshot")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpDir)
+	dir := filepath.Join(tmpDir, "shots")
+
+	type capture struct{ command, file string }
+	captures := make(chan capture, 10)
+	var captureErr error
+	runCommand = func(command, file string) error {
+		captures <- capture{command, file}
+		return captureErr
+	}
+
+	s := New(Tool{FullScreen: "full", Region: "region"}).Directory(dir)
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal("screenshot", out[0].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	c := <-captures
+	assert.Equal("region", c.command)
+	assert.Equal(filepath.Join(dir, "screenshot-2017-12-01-131415.png"), c.file)
+	out = tester.AssertOutput("after saving")
+	assert.Equal("saved", out[0].Text())
+	_, err = os.Stat(dir)
+	assert.NoError(err, "directory is created")
+
+	scheduler.NextTick()
+	out = tester.AssertOutput("after confirmation")
+	assert.Equal("screenshot", out[0].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonMiddle})
+	tester.AssertOutput("middle click updates")
+	assert.Empty(captures, "no command for window capture")
+
+	var info Info
+	s.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return bar.Output{bar.NewSegment("x")}
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(c.file, info.File)
+	assert.False(info.Saved)
+
+	captureErr = errors.New("cancelled")
+	s.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Equal("full", (<-captures).command)
+	tester.AssertError("when screenshot fails")
+}
@@ -0,0 +1,281 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package sensors provides an i3bar module that shows hardware sensor readings.
+
+Sensors are read from the hwmon devices in /sys/class/hwmon, the same source
+used by lm-sensors. Each sensor is identified by the chip name and its label,
+e.g. "coretemp/Package id 0", "nct6775/fan2", or "amdgpu/vddgfx" (the sensor
+name is used if the driver does not provide a label). Sensors are selected
+using path.Match patterns against the "chip/label" string, so any supported
+hardware can be displayed without a dedicated module.
+*/
+package sensors
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/format"
+	"github.com/soumya92/barista/locale"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Type represents the kind of measurement made by a sensor.
+type Type string
+
+// Sensor types supported by hwmon, named after the sysfs file prefixes.
+const (
+	Voltage     Type = "in"
+	Temperature Type = "temp"
+	Fan         Type = "fan"
+	Power       Type = "power"
+	Current     Type = "curr"
+)
+
+// scale converts the raw sysfs values to volts, degrees celsius,
+// rpm, watts, and amps respectively.
+var scale = map[Type]float64{
+	Voltage:     1e-3,
+	Temperature: 1e-3,
+	Fan:         1,
+	Power:       1e-6,
+	Current:     1e-3,
+}
+
+// Sensor represents a single sensor reading.
+type Sensor struct {
+	Chip  string
+	Label string
+	Type  Type
+	// Value is the reading, in volts, degrees celsius, rpm, watts,
+	// or amps, depending on the type of sensor.
+	Value float64
+}
+
+// Name returns the "chip/label" name used to match the sensor.
+func (s Sensor) Name() string {
+	return s.Chip + "/" + s.Label
+}
+
+// String returns the reading formatted with its unit.
+func (s Sensor) String() string {
+	l := locale.Current()
+	switch s.Type {
+	case Voltage:
+		return l.FormatFloat(s.Value, 2) + "V"
+	case Temperature:
+		return format.Celsius(s.Value)
+	case Fan:
+		return l.FormatFloat(s.Value, 0) + " " + l.Unit("RPM")
+	case Power:
+		return l.FormatFloat(s.Value, 1) + "W"
+	case Current:
+		return l.FormatFloat(s.Value, 2) + "A"
+	}
+	return fmt.Sprintf("%g", s.Value)
+}
+
+// Info represents the readings of all selected sensors,
+// ordered by the pattern that matched them.
+type Info []Sensor
+
+// Find returns the first sensor matching the given pattern,
+// and false if there is no matching sensor.
+func (i Info) Find(pattern string) (Sensor, bool) {
+	for _, s := range i {
+		if ok, _ := path.Match(pattern, s.Name()); ok {
+			return s, true
+		}
+	}
+	return Sensor{}, false
+}
+
+// Module represents a sensors bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency for sensors.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	patterns   []string
+	outputFunc func(Info) bar.Output
+}
+
+// New constructs an instance of the sensors module that shows all sensors
+// whose "chip/label" matches any of the given patterns, or all sensors
+// if no patterns are given.
+func New(patterns ...string) Module {
+	if len(patterns) == 0 {
+		patterns = []string{"*/*"}
+	}
+	m := &module{
+		Base:     base.New(),
+		patterns: patterns,
+	}
+	// Default is to refresh every 3s, matching the behaviour of top.
+	m.RefreshInterval(3 * time.Second)
+	// Default output template is all readings separated by spaces.
+	m.OutputTemplate(outputs.TextTemplate(`{{range $i, $s := .}}{{if $i}} {{end}}{{$s}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+var fs = hostfs.Fs()
+
+const hwmonRoot = "/sys/class/hwmon"
+
+var inputFile = regexp.MustCompile(`^(in|temp|fan|power|curr)(\d+)_input$`)
+
+// readFile returns the trimmed contents of a sysfs file.
+func readFile(name string) (string, error) {
+	bytes, err := afero.ReadFile(fs, name)
+	return strings.TrimSpace(string(bytes)), err
+}
+
+// readSensors reads all sensors from all hwmon devices.
+func readSensors() ([]Sensor, error) {
+	devices, err := afero.ReadDir(fs, hwmonRoot)
+	if err != nil {
+		return nil, err
+	}
+	var sensors []Sensor
+	for _, device := range devices {
+		dir := path.Join(hwmonRoot, device.Name())
+		chip, err := readFile(path.Join(dir, "name"))
+		if err != nil {
+			continue
+		}
+		files, err := afero.ReadDir(fs, dir)
+		if err != nil {
+			continue
+		}
+		var chipSensors []Sensor
+		var numbers []int
+		for _, file := range files {
+			match := inputFile.FindStringSubmatch(file.Name())
+			if match == nil {
+				continue
+			}
+			// Some drivers expose inputs that fail to read,
+			// e.g. for disconnected fans, so those are skipped.
+			raw, err := readFile(path.Join(dir, file.Name()))
+			if err != nil {
+				continue
+			}
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			name := match[1] + match[2]
+			label, err := readFile(path.Join(dir, name+"_label"))
+			if err != nil || label == "" {
+				label = name
+			}
+			typ := Type(match[1])
+			number, _ := strconv.Atoi(match[2])
+			chipSensors = append(chipSensors, Sensor{
+				Chip:  chip,
+				Label: label,
+				Type:  typ,
+				Value: value * scale[typ],
+			})
+			numbers = append(numbers, number)
+		}
+		// Directory listings are sorted by name, which puts e.g. temp10
+		// before temp2, so sort by type and sensor number instead.
+		sort.Sort(byNumber{chipSensors, numbers})
+		sensors = append(sensors, chipSensors...)
+	}
+	return sensors, nil
+}
+
+// byNumber sorts a chip's sensors by type, then by sensor number.
+type byNumber struct {
+	sensors []Sensor
+	numbers []int
+}
+
+func (b byNumber) Len() int { return len(b.sensors) }
+
+func (b byNumber) Less(i, j int) bool {
+	if b.sensors[i].Type != b.sensors[j].Type {
+		return b.sensors[i].Type < b.sensors[j].Type
+	}
+	return b.numbers[i] < b.numbers[j]
+}
+
+func (b byNumber) Swap(i, j int) {
+	b.sensors[i], b.sensors[j] = b.sensors[j], b.sensors[i]
+	b.numbers[i], b.numbers[j] = b.numbers[j], b.numbers[i]
+}
+
+func (m *module) update() {
+	sensors, err := readSensors()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	var info Info
+	added := make([]bool, len(sensors))
+	for _, pattern := range m.patterns {
+		for idx, s := range sensors {
+			if ok, _ := path.Match(pattern, s.Name()); ok && !added[idx] {
+				info = append(info, s)
+				added[idx] = true
+			}
+		}
+	}
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
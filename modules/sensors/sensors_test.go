@@ -0,0 +1,98 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sensors
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/testing/hostfs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func setupHwmon(t *testing.T) *hostfs.Fixture {
+	f := hostfs.New(t)
+	f.Hwmon(0, "coretemp").
+		Sensor("temp1", 52000, "Package id 0").
+		Sensor("temp2", 48000, "Core 0").
+		Sensor("temp10", 47000, "Core 8")
+	f.Hwmon(1, "nct6775").
+		Sensor("in0", 1216, "").
+		Sensor("fan2", 1150, "").
+		File("fan2_min", "300").
+		Sensor("fan3", "not a number", "").
+		Sensor("power1", 12500000, "")
+	return f
+}
+
+func TestReadSensors(t *testing.T) {
+	assert := assert.New(t)
+	f := setupHwmon(t)
+	sensors, err := readSensors()
+	assert.NoError(err)
+	var names, values []string
+	for _, s := range sensors {
+		names = append(names, s.Name())
+		values = append(values, s.String())
+	}
+	assert.Equal([]string{
+		"coretemp/Package id 0", "coretemp/Core 0", "coretemp/Core 8",
+		"nct6775/fan2", "nct6775/in0", "nct6775/power1",
+	}, names)
+	assert.Equal([]string{
+		"52°C", "48°C", "47°C", "1150 RPM", "1.22V", "12.5W",
+	}, values)
+
+	f.Close()
+	f = hostfs.New(t)
+	defer f.Close()
+	_, err = readSensors()
+	assert.Error(err, "without hwmon")
+}
+
+func TestSensors(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	f := setupHwmon(t)
+	defer f.Close()
+
+	s := New("nct6775/fan*", "coretemp/Package*", "*/fan2")
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal("1150 RPM 52°C", out[0].Text(), "ordered by pattern, without duplicates")
+
+	f.Hwmon(1, "nct6775").Sensor("fan2", 1320, "")
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("1320 RPM 52°C", out[0].Text())
+
+	s.OutputTemplate(func(i interface{}) bar.Output {
+		info := i.(Info)
+		sensor, ok := info.Find("coretemp/*")
+		assert.True(ok)
+		_, ok = info.Find("nct6775/in*")
+		assert.False(ok, "sensor not selected")
+		return bar.Output{bar.NewSegment(sensor.Label)}
+	})
+	out = tester.AssertOutput("on template change")
+	assert.Equal("Package id 0", out[0].Text())
+
+	fs.RemoveAll(hwmonRoot)
+	scheduler.NextTick()
+	tester.AssertError("when hwmon is missing")
+}
@@ -17,76 +17,304 @@ Package shell provides modules to display the output of shell commands.
 It supports both long-running commands, where the output is the last line,
 e.g. dmesg or tail -f /var/log/some.log, and repeatedly running commands,
 e.g. whoami, date +%s.
+
+Commands can be constrained, so that a misbehaving script cannot hang the
+module or exhaust the machine's resources:
+
+	shell.Every(time.Minute, "check-mail.sh").
+		Timeout(10 * time.Second).
+		MaxOutput(4096).
+		CPULimit(2 * time.Second).
+		MemoryLimit(64 * 1024 * 1024).
+		Env("PATH", "HOME", "LANG=C")
 */
 package shell
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
 	"github.com/soumya92/barista/outputs"
 )
 
-type tailModule struct {
+// Module represents a shell bar module. It supports setting the click
+// handler, and formatting the output of the command.
+type Module interface {
+	base.WithClickHandler
+
+	// OutputFunc configures a module to display the output of a user-defined
+	// function, given the output of the command.
+	OutputFunc(func(string) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// Timeout kills the command, and shows an error, if a run takes longer
+	// than the given duration. It has no effect on long-running commands.
+	Timeout(time.Duration) Module
+
+	// MaxOutput kills the command, and shows an error, if it prints more
+	// than the given number of bytes, or for long-running commands, a line
+	// longer than that.
+	MaxOutput(bytes int) Module
+
+	// CPULimit limits the CPU time of each run of the command, and
+	// MemoryLimit limits its address space, using rlimits that are applied
+	// as soon as the command starts, and inherited by its children.
+	CPULimit(time.Duration) Module
+	MemoryLimit(bytes uint64) Module
+
+	// Env runs the command with only the given environment variables,
+	// either as "NAME=value", or "NAME" to copy the value from the bar's
+	// environment, instead of the bar's entire environment.
+	Env(vars ...string) Module
+}
+
+// limits constrain each run of the command.
+type limits struct {
+	timeout   time.Duration
+	maxOutput int
+	cpu       time.Duration
+	memory    uint64
+	// The environment of the command, or nil to use the bar's environment.
+	env []string
+}
+
+type module struct {
 	*base.Base
-	cmd  string
-	args []string
+	limits     limits
+	outputFunc func(string) bar.Output
+	// The last output of the command, to re-render when the output func
+	// changes, if hasText is set.
+	text    string
+	hasText bool
+	// For tail modules, start is called when the module is streamed.
+	start func()
+	// For once modules, run is called before the module is first streamed.
+	run func()
 }
 
-// Tail constructs a module that displays the last line of output from
-// a long running command. Use the reformat module to adjust the output
-// if necessary.
-func Tail(cmd string, args ...string) base.WithClickHandler {
-	return &tailModule{
-		Base: base.New(),
-		cmd:  cmd,
-		args: args,
+func newModule() *module {
+	return &module{
+		Base:       base.New(),
+		outputFunc: outputs.Text,
 	}
 }
 
-func (m *tailModule) Stream() <-chan bar.Output {
-	go m.worker()
+func (m *module) Stream() <-chan bar.Output {
+	m.Lock()
+	run := m.run
+	m.run = nil
+	m.Unlock()
+	if run != nil {
+		run()
+	}
+	if m.start != nil {
+		go m.start()
+	}
 	return m.Base.Stream()
 }
 
-func (m *tailModule) worker() {
-	cmd := exec.Command(m.cmd, m.args...)
+func (m *module) OutputFunc(outputFunc func(string) bar.Output) Module {
+	m.Lock()
+	m.outputFunc = outputFunc
+	text, hasText := m.text, m.hasText
+	m.Unlock()
+	if hasText {
+		m.Output(outputFunc(text))
+	}
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(text string) bar.Output {
+		return template(text)
+	})
+}
+
+func (m *module) Timeout(timeout time.Duration) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.limits.timeout = timeout
+	return m
+}
+
+func (m *module) MaxOutput(bytes int) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.limits.maxOutput = bytes
+	return m
+}
+
+func (m *module) CPULimit(cpu time.Duration) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.limits.cpu = cpu
+	return m
+}
+
+func (m *module) MemoryLimit(bytes uint64) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.limits.memory = bytes
+	return m
+}
+
+func (m *module) Env(vars ...string) Module {
+	env := []string{}
+	for _, v := range vars {
+		if strings.Contains(v, "=") {
+			env = append(env, v)
+		} else if value, ok := os.LookupEnv(v); ok {
+			env = append(env, v+"="+value)
+		}
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.limits.env = env
+	return m
+}
+
+// command creates a command with the module's limits, which must be started
+// using start.
+func (m *module) command(name string, args ...string) (*exec.Cmd, limits) {
+	m.Lock()
+	l := m.limits
+	m.Unlock()
+	cmd := exec.Command(name, args...)
 	// Prevent SIGUSR for bar pause/resume from propagating to the
 	// child process. Some commands don't play nice with signals.
+	// This also allows the whole process group to be killed.
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 		Pgid:    0,
 	}
+	cmd.Env = l.env
+	return cmd, l
+}
+
+// start starts the command, and applies the CPU and memory limits.
+func start(cmd *exec.Cmd, l limits) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pid := cmd.Process.Pid
+	var err error
+	if l.cpu > 0 {
+		secs := uint64((l.cpu + time.Second - 1) / time.Second)
+		err = unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: secs, Max: secs}, nil)
+	}
+	if err == nil && l.memory > 0 {
+		err = unix.Prlimit(pid, unix.RLIMIT_AS, &unix.Rlimit{Cur: l.memory, Max: l.memory}, nil)
+	}
+	if err != nil {
+		kill(cmd)
+		cmd.Wait()
+	}
+	return err
+}
+
+// kill kills the command and any processes it started.
+func kill(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// limitedBuffer collects the output of a command, and kills it if the
+// output is too long.
+type limitedBuffer struct {
+	// Not embedded, so that io.Copy cannot bypass Write using ReadFrom.
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+	cmd      *exec.Cmd
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		b.exceeded = true
+		kill(b.cmd)
+		return 0, io.ErrShortWrite
+	}
+	return b.buf.Write(p)
+}
+
+// output displays the command's output using the output func.
+func (m *module) output(text string) {
+	m.Lock()
+	m.text = text
+	m.hasText = true
+	outputFunc := m.outputFunc
+	m.Unlock()
+	m.Output(outputFunc(text))
+}
+
+// error displays an error, if not nil, and returns true if it did.
+func (m *module) error(err error) bool {
+	if err == nil {
+		return false
+	}
+	m.Lock()
+	m.hasText = false
+	m.Unlock()
+	return m.Error(err)
+}
+
+// Tail constructs a module that displays the last line of output from
+// a long running command.
+func Tail(cmd string, args ...string) Module {
+	m := newModule()
+	m.start = func() { m.tail(cmd, args...) }
+	return m
+}
+
+func (m *module) tail(command string, args ...string) {
+	cmd, l := m.command(command, args...)
 	stdout, err := cmd.StdoutPipe()
-	if m.Error(err) {
+	if m.error(err) {
 		return
 	}
-	if m.Error(cmd.Start()) {
+	if m.error(start(cmd, l)) {
 		return
 	}
 	m.OnUpdate(func() {})
 	scanner := bufio.NewScanner(stdout)
+	if l.maxOutput > 0 {
+		scanner.Buffer(make([]byte, 0, l.maxOutput), l.maxOutput)
+	}
 	for scanner.Scan() {
-		m.Output(outputs.Text(scanner.Text()))
+		m.output(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		kill(cmd)
+		cmd.Wait()
+		m.error(fmt.Errorf("%s: %v", command, err))
+	} else {
+		m.error(cmd.Wait())
 	}
-	m.Error(cmd.Wait())
 	// If the process died, the next update should restart it.
 	// Since we clear onUpdate when the process starts successfully,
 	// updates while the process is running are no-ops.
-	m.OnUpdate(m.worker)
+	m.OnUpdate(func() { m.tail(command, args...) })
 }
 
 // Every constructs a module that runs the given command with the
 // specified interval and displays the commands output in the bar.
-func Every(interval time.Duration, cmd string, args ...string) base.WithClickHandler {
-	m := base.New()
+func Every(interval time.Duration, cmd string, args ...string) Module {
+	m := newModule()
 	m.OnUpdate(func() {
-		commandOutputToModule(m, cmd, args...)
+		m.runCommand(cmd, args...)
 	})
 	m.Schedule().Every(interval)
 	return m
@@ -94,19 +322,39 @@ func Every(interval time.Duration, cmd string, args ...string) base.WithClickHan
 
 // Once constructs a static module that displays the output of
 // the given command in the bar.
-func Once(cmd string, args ...string) base.WithClickHandler {
-	m := base.New()
-	commandOutputToModule(m, cmd, args...)
+func Once(cmd string, args ...string) Module {
+	m := newModule()
+	// Run when streamed, so that any limits have been set.
+	m.run = func() { m.runCommand(cmd, args...) }
 	return m
 }
 
-// commandOutputToModule runs the command and sends the output or error
-// as appropriate to the base module.
-func commandOutputToModule(module *base.Base, cmd string, args ...string) {
-	out, err := exec.Command(cmd, args...).Output()
-	if module.Error(err) {
+// runCommand runs the command and displays the output or error
+// as appropriate.
+func (m *module) runCommand(name string, args ...string) {
+	cmd, l := m.command(name, args...)
+	out := &limitedBuffer{max: l.maxOutput, cmd: cmd}
+	cmd.Stdout = out
+	if m.error(start(cmd, l)) {
+		return
+	}
+	var timedOut int32
+	if l.timeout > 0 {
+		timer := time.AfterFunc(l.timeout, func() {
+			atomic.StoreInt32(&timedOut, 1)
+			kill(cmd)
+		})
+		defer timer.Stop()
+	}
+	err := cmd.Wait()
+	switch {
+	case atomic.LoadInt32(&timedOut) == 1:
+		err = fmt.Errorf("%s: timed out after %v", name, l.timeout)
+	case out.exceeded:
+		err = fmt.Errorf("%s: output exceeds %d bytes", name, l.maxOutput)
+	}
+	if m.error(err) {
 		return
 	}
-	strOut := strings.TrimSpace(string(out))
-	module.Output(outputs.Text(strOut))
+	m.output(strings.TrimSpace(out.buf.String()))
 }
@@ -0,0 +1,100 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestOnce(t *testing.T) {
+	m := Once("echo", "  hello world  ")
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("on start")
+	assert.Equal(t, "hello world", out[0].Text(), "trims output")
+
+	m.OutputFunc(func(s string) bar.Output { return outputs.Textf("<%s>", s) })
+	out = tester.AssertOutput("on output func change")
+	assert.Equal(t, "<hello world>", out[0].Text(), "re-renders last output")
+
+	m.OutputTemplate(outputs.TextTemplate(`{{len .}}`))
+	out = tester.AssertOutput("on output template change")
+	assert.Equal(t, "11", out[0].Text())
+
+	errored := Once("false")
+	tester = testModule.NewOutputTester(t, errored)
+	tester.AssertError("when command fails")
+	errored.OutputTemplate(outputs.TextTemplate(`{{.}}`))
+	tester.AssertNoOutput("error is not replaced by output func change")
+}
+
+func TestTail(t *testing.T) {
+	m := Tail("echo", "line").OutputTemplate(outputs.TextTemplate(`[{{.}}]`))
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("on start")
+	assert.Equal(t, "[line]", out[0].Text())
+}
+
+func TestTimeout(t *testing.T) {
+	m := Once("sleep", "10").Timeout(50 * time.Millisecond)
+	tester := testModule.NewOutputTester(t, m)
+	assert.Contains(t, tester.AssertError("when command times out"), "timed out")
+
+	m = Every(time.Hour, "echo", "fast").Timeout(time.Second)
+	tester = testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("within timeout")
+	assert.Equal(t, "fast", out[0].Text())
+}
+
+func TestMaxOutput(t *testing.T) {
+	m := Once("echo", "hello world").MaxOutput(5)
+	tester := testModule.NewOutputTester(t, m)
+	assert.Contains(t, tester.AssertError("when output is too long"), "output exceeds 5 bytes")
+
+	m = Once("echo", "hi").MaxOutput(5)
+	tester = testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("when output fits")
+	assert.Equal(t, "hi", out[0].Text())
+
+	m = Tail("sh", "-c", "echo short; echo a-very-long-line; sleep 10").MaxOutput(10)
+	tester = testModule.NewOutputTester(t, m)
+	out = tester.AssertOutput("short line")
+	assert.Equal(t, "short", out[0].Text())
+	assert.Contains(t, tester.AssertError("when a line is too long"), "too long")
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("SHELL_TEST_VAR", "copied")
+	m := Once("sh", "-c", `echo "$FOO,$SHELL_TEST_VAR,$HOME"`).
+		Env("FOO=bar", "SHELL_TEST_VAR", "SHELL_TEST_MISSING")
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("with restricted environment")
+	assert.Equal(t, "bar,copied,", out[0].Text())
+}
+
+func TestResourceLimits(t *testing.T) {
+	m := Once("sh", "-c", "sleep 0.1; ulimit -t; ulimit -v").
+		CPULimit(1500 * time.Millisecond).
+		MemoryLimit(256 * 1024 * 1024)
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("with limits")
+	assert.Equal(t, "2\n262144", out[0].Text())
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package speedtest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// cloudflareURL is the speed test server, overridden in tests.
+var cloudflareURL = "https://speed.cloudflare.com"
+
+// latencySamples is the number of requests used to measure latency.
+const latencySamples = 5
+
+type cloudflare struct {
+	downloadBytes, uploadBytes int64
+}
+
+// Cloudflare returns a tester that uses the Cloudflare speed test servers,
+// transferring the given number of bytes in each direction. Larger transfers
+// are more accurate on fast connections, but take longer on slow ones.
+func Cloudflare(downloadBytes, uploadBytes int64) Tester {
+	return cloudflare{downloadBytes, uploadBytes}
+}
+
+func (c cloudflare) get(bytes int64) (*http.Response, error) {
+	response, err := http.Get(fmt.Sprintf("%s/__down?bytes=%d", cloudflareURL, bytes))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("speed test: %s", response.Status)
+	}
+	return response, nil
+}
+
+// Latency returns the median time to first byte of empty downloads.
+// The first request also sets up the connection, which is reused
+// for the others.
+func (c cloudflare) Latency() (time.Duration, error) {
+	var samples []time.Duration
+	for i := 0; i < latencySamples; i++ {
+		start := time.Now()
+		response, err := c.get(0)
+		if err != nil {
+			return 0, err
+		}
+		samples = append(samples, time.Since(start))
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[len(samples)/2], nil
+}
+
+// progressReader reports the progress of reading a known number of bytes.
+type progressReader struct {
+	io.Reader
+	read, total int64
+	progress    func(float64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.read += int64(n)
+	p.progress(float64(p.read) / float64(p.total))
+	return n, err
+}
+
+func (c cloudflare) Download(progress func(float64)) (Speed, error) {
+	start := time.Now()
+	response, err := c.get(c.downloadBytes)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	n, err := io.Copy(ioutil.Discard,
+		&progressReader{Reader: response.Body, total: c.downloadBytes, progress: progress})
+	if err != nil {
+		return 0, err
+	}
+	return Speed(float64(n) / time.Since(start).Seconds()), nil
+}
+
+// zeros is an infinite reader of zero bytes, used as the upload body.
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+func (c cloudflare) Upload(progress func(float64)) (Speed, error) {
+	body := &progressReader{
+		Reader:   io.LimitReader(zeros{}, c.uploadBytes),
+		total:    c.uploadBytes,
+		progress: progress,
+	}
+	req, err := http.NewRequest("POST", cloudflareURL+"/__up", body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = c.uploadBytes
+	req.Header.Set("Content-Type", "application/octet-stream")
+	start := time.Now()
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("speed test: %s", response.Status)
+	}
+	return Speed(float64(c.uploadBytes) / time.Since(start).Seconds()), nil
+}
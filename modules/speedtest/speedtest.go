@@ -0,0 +1,274 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package speedtest provides an i3bar module that runs a network speed test.
+
+Since a speed test uses a lot of bandwidth, tests are only run when the module
+is clicked, and never automatically. The module shows the progress of each
+phase (latency, download, and upload) while the test is running, and then
+shows the results until the next test.
+*/
+package speedtest
+
+import (
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/format"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Speed represents a transfer speed in bytes per second.
+type Speed float64
+
+// In gets the speed in a specific unit, e.g. "b" or "MB".
+func (s Speed) In(unit string) float64 {
+	base, err := humanize.ParseBytes("1" + unit)
+	if err != nil {
+		base = 1
+	}
+	return float64(s) / float64(base)
+}
+
+// IEC returns the speed formatted in base 2.
+func (s Speed) IEC() string {
+	return format.IBytes(uint64(s))
+}
+
+// SI returns the speed formatted in base 10.
+func (s Speed) SI() string {
+	return format.Bytes(uint64(s))
+}
+
+// Mbps returns the speed in megabits per second, the unit most commonly
+// used for internet connections.
+func (s Speed) Mbps() float64 {
+	return float64(s) * 8 / 1e6
+}
+
+// Phase represents a phase of the speed test.
+type Phase int
+
+// Phases of a speed test, in the order they are run.
+const (
+	Latency Phase = iota
+	Download
+	Upload
+)
+
+func (p Phase) String() string {
+	switch p {
+	case Latency:
+		return "latency"
+	case Download:
+		return "download"
+	case Upload:
+		return "upload"
+	}
+	return "unknown"
+}
+
+// Tester runs the phases of a speed test against a server.
+type Tester interface {
+	// Latency returns the round-trip time to the server.
+	Latency() (time.Duration, error)
+	// Download and Upload return the transfer speed, and call the
+	// progress function with the fraction of the transfer completed.
+	Download(progress func(float64)) (Speed, error)
+	Upload(progress func(float64)) (Speed, error)
+}
+
+// Info represents the state of the speed test, and the last results.
+type Info struct {
+	// Running is true while a test is in progress, and Phase and
+	// Progress are the current phase and its progress from 0 to 1.
+	Running  bool
+	Phase    Phase
+	Progress float64
+	// Results of the last completed test.
+	Latency  time.Duration
+	Download Speed
+	Upload   Speed
+	// Tested is the time the last test completed, or zero if no test
+	// has been completed.
+	Tested time.Time
+}
+
+// Percent returns the progress of the current phase as a percentage.
+func (i Info) Percent() int {
+	return int(i.Progress * 100)
+}
+
+// Controller provides an interface to start a test from the click handler.
+type Controller interface {
+	// Start starts a speed test, unless one is already running.
+	Start()
+}
+
+// Module is the public interface for a speed test module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to start a test.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	tester     Tester
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the speed test module using the given tester.
+func New(tester Tester) Module {
+	m := &module{Base: base.New(), tester: tester}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the progress while running, and the
+	// download and upload speeds in Mbps once complete.
+	m.OutputTemplate(outputs.TextTemplate(`{{if .Running}}{{.Phase}} {{.Percent}}%` +
+		`{{else if .Tested.IsZero}}speed test` +
+		`{{else}}{{.Download.Mbps | number 1}}/{{.Upload.Mbps | number 1}} Mbps{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler starts a speed test on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Start()
+	}
+}
+
+func (m *module) Start() {
+	m.Lock()
+	if m.info.Running {
+		m.Unlock()
+		return
+	}
+	m.info.Running = true
+	m.setPhase(Latency)
+	m.UnlockAndUpdate()
+	go m.run()
+}
+
+// setPhase sets the current phase and resets the progress.
+// It must be called with the lock held.
+func (m *module) setPhase(phase Phase) {
+	m.info.Phase = phase
+	m.info.Progress = 0
+}
+
+func (m *module) run() {
+	latency, err := m.tester.Latency()
+	if m.failed(err) {
+		return
+	}
+	m.nextPhase(Download)
+	download, err := m.tester.Download(m.progress)
+	if m.failed(err) {
+		return
+	}
+	m.nextPhase(Upload)
+	upload, err := m.tester.Upload(m.progress)
+	if m.failed(err) {
+		return
+	}
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.info = Info{
+		Latency:  latency,
+		Download: download,
+		Upload:   upload,
+		Tested:   scheduler.Now(),
+	}
+}
+
+func (m *module) nextPhase(phase Phase) {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.setPhase(phase)
+}
+
+// failed stops the test and shows the error, if any.
+func (m *module) failed(err error) bool {
+	if err == nil {
+		return false
+	}
+	m.Lock()
+	m.info.Running = false
+	m.Unlock()
+	return m.Error(err)
+}
+
+// progress updates the progress of the current phase, but only updates
+// the module when the percentage changes, since transfers report progress
+// very frequently.
+func (m *module) progress(progress float64) {
+	m.Lock()
+	if int(progress*100) == m.info.Percent() {
+		m.Unlock()
+		return
+	}
+	m.info.Progress = progress
+	m.UnlockAndUpdate()
+}
+
+func (m *module) update() {
+	m.Lock()
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
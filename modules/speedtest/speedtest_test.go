@@ -0,0 +1,200 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package speedtest
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestCloudflare(t *testing.T) {
+	assert := assert.New(t)
+	uploaded := make(chan int64, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/__down":
+			bytes, _ := strconv.Atoi(r.URL.Query().Get("bytes"))
+			w.Write([]byte(strings.Repeat("x", bytes)))
+		case "/__up":
+			n, _ := io.Copy(ioutil.Discard, r.Body)
+			uploaded <- n
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	cloudflareURL = srv.URL
+
+	c := Cloudflare(100000, 50000)
+	latency, err := c.Latency()
+	assert.NoError(err)
+	assert.True(latency > 0)
+
+	var progress []float64
+	speed, err := c.Download(func(p float64) { progress = append(progress, p) })
+	assert.NoError(err)
+	assert.True(speed > 0)
+	assert.Equal(1.0, progress[len(progress)-1], "download completes")
+
+	progress = nil
+	speed, err = c.Upload(func(p float64) { progress = append(progress, p) })
+	assert.NoError(err)
+	assert.True(speed > 0)
+	assert.Equal(int64(50000), <-uploaded)
+	assert.Equal(1.0, progress[len(progress)-1], "upload completes")
+
+	cloudflareURL = srv.URL + "/missing"
+	_, err = c.Latency()
+	assert.Error(err)
+	_, err = c.Download(func(float64) {})
+	assert.Error(err)
+	_, err = c.Upload(func(float64) {})
+	assert.Error(err)
+}
+
+func TestSpeed(t *testing.T) {
+	assert := assert.New(t)
+	s := Speed(12500000)
+	assert.Equal(100.0, s.Mbps())
+	assert.Equal(12.5, s.In("MB"))
+	assert.Equal("13 MB", s.SI())
+	assert.Equal("12 MiB", s.IEC())
+	assert.Equal("download", Download.String())
+	assert.Equal("unknown", Phase(-1).String())
+}
+
+// testTester runs each phase when a value is sent on its channel.
+type testTester struct {
+	latency  chan time.Duration
+	progress chan float64
+	speed    chan Speed
+	err      chan error
+}
+
+func (t *testTester) Latency() (time.Duration, error) {
+	select {
+	case l := <-t.latency:
+		return l, nil
+	case err := <-t.err:
+		return 0, err
+	}
+}
+
+func (t *testTester) transfer(progress func(float64)) (Speed, error) {
+	for {
+		select {
+		case p := <-t.progress:
+			progress(p)
+		case s := <-t.speed:
+			return s, nil
+		case err := <-t.err:
+			return 0, err
+		}
+	}
+}
+
+func (t *testTester) Download(progress func(float64)) (Speed, error) {
+	return t.transfer(progress)
+}
+
+func (t *testTester) Upload(progress func(float64)) (Speed, error) {
+	return t.transfer(progress)
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	tt := &testTester{
+		latency:  make(chan time.Duration),
+		progress: make(chan float64),
+		speed:    make(chan Speed),
+		err:      make(chan error),
+	}
+	s := New(tt)
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal("speed test", out[0].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertNoOutput("on right click")
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on test start")
+	assert.Equal("latency 0%", out[0].Text())
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertNoOutput("when already running")
+
+	tt.latency <- 15 * time.Millisecond
+	out = tester.AssertOutput("on download start")
+	assert.Equal("download 0%", out[0].Text())
+
+	tt.progress <- 0.25
+	out = tester.AssertOutput("on download progress")
+	assert.Equal("download 25%", out[0].Text())
+	tt.progress <- 0.251
+	tester.AssertNoOutput("when percentage is unchanged")
+	tt.progress <- 0.5
+	out = tester.AssertOutput("on download progress")
+	assert.Equal("download 50%", out[0].Text())
+
+	tt.speed <- 12500000
+	out = tester.AssertOutput("on upload start")
+	assert.Equal("upload 0%", out[0].Text())
+
+	scheduler.AdvanceBy(time.Minute)
+	tt.speed <- 2500000
+	out = tester.AssertOutput("on test complete")
+	assert.Equal("100.0/20.0 Mbps", out[0].Text())
+
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("results are kept until the next test")
+
+	var info Info
+	s.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.False(info.Running)
+	assert.Equal(15*time.Millisecond, info.Latency)
+	assert.Equal(Speed(12500000), info.Download)
+	assert.Equal(time.Time{}.Add(time.Minute), info.Tested)
+
+	s.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertOutput("on test start")
+	assert.True(info.Running)
+	tt.err <- errors.New("network unreachable")
+	tester.AssertError("on test error")
+
+	s.OnClick(DefaultClickHandler)
+	s.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertOutput("clears error")
+	tester.AssertOutput("on restart after error")
+	assert.False(info.Running)
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stocks
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/soumya92/barista/base/httpclient"
+	"github.com/soumya92/barista/base/secret"
+)
+
+// API endpoints, overridden in tests.
+var (
+	yahooAPI        = "https://query1.finance.yahoo.com"
+	finnhubAPI      = "https://finnhub.io/api/v1"
+	alphaVantageAPI = "https://www.alphavantage.co"
+)
+
+// client is shared by all providers, e.g. to reuse connections.
+var client = httpclient.New()
+
+type yahoo struct{}
+
+// Yahoo returns a provider that gets quotes from Yahoo Finance.
+// All symbols are fetched in a single request.
+func Yahoo() Provider {
+	return yahoo{}
+}
+
+func (yahoo) Quotes(symbols []string) ([]Quote, error) {
+	var r struct {
+		QuoteResponse struct {
+			Result []struct {
+				Symbol                     string
+				RegularMarketPrice         float64
+				RegularMarketChange        float64
+				RegularMarketChangePercent float64
+			}
+			Error *struct{ Description string }
+		}
+	}
+	u := yahooAPI + "/v7/finance/quote?symbols=" + url.QueryEscape(strings.Join(symbols, ","))
+	if err := client.GetJSON(u, &r); err != nil {
+		return nil, err
+	}
+	if r.QuoteResponse.Error != nil {
+		return nil, errors.New(r.QuoteResponse.Error.Description)
+	}
+	// Results may be in a different order, and omit unknown symbols.
+	bySymbol := map[string]Quote{}
+	for _, q := range r.QuoteResponse.Result {
+		bySymbol[q.Symbol] = Quote{
+			Symbol:        q.Symbol,
+			Price:         q.RegularMarketPrice,
+			Change:        q.RegularMarketChange,
+			ChangePercent: q.RegularMarketChangePercent,
+		}
+	}
+	var quotes []Quote
+	for _, s := range symbols {
+		q, ok := bySymbol[s]
+		if !ok {
+			return nil, fmt.Errorf("no quote for %s", s)
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, nil
+}
+
+type finnhub struct{ apiKey secret.Secret }
+
+// Finnhub returns a provider that gets quotes from Finnhub using the given
+// API key. Each symbol is fetched in a separate request.
+func Finnhub(apiKey secret.Secret) Provider {
+	return finnhub{apiKey}
+}
+
+func (f finnhub) Quotes(symbols []string) ([]Quote, error) {
+	key, err := f.apiKey.Get()
+	if err != nil {
+		return nil, err
+	}
+	var quotes []Quote
+	for _, s := range symbols {
+		// c is the current price, d the change, and dp the percent change.
+		var r struct {
+			C  float64
+			D  *float64
+			Dp float64
+		}
+		qp := url.Values{}
+		qp.Add("symbol", s)
+		qp.Add("token", key)
+		if err := client.GetJSON(finnhubAPI+"/quote?"+qp.Encode(), &r); err != nil {
+			return nil, err
+		}
+		// Unknown symbols return all zeros, with a null change.
+		if r.D == nil {
+			return nil, fmt.Errorf("no quote for %s", s)
+		}
+		quotes = append(quotes, Quote{
+			Symbol:        s,
+			Price:         r.C,
+			Change:        *r.D,
+			ChangePercent: r.Dp,
+		})
+	}
+	return quotes, nil
+}
+
+type alphaVantage struct{ apiKey secret.Secret }
+
+// AlphaVantage returns a provider that gets quotes from Alpha Vantage using
+// the given API key. Each symbol is fetched in a separate request, and free
+// API keys have a low daily limit, so use a long refresh interval.
+func AlphaVantage(apiKey secret.Secret) Provider {
+	return alphaVantage{apiKey}
+}
+
+func (a alphaVantage) Quotes(symbols []string) ([]Quote, error) {
+	key, err := a.apiKey.Get()
+	if err != nil {
+		return nil, err
+	}
+	var quotes []Quote
+	for _, s := range symbols {
+		// Alpha Vantage returns numbers as strings, with numbered keys.
+		var r struct {
+			Quote struct {
+				Symbol        string `json:"01. symbol"`
+				Price         string `json:"05. price"`
+				Change        string `json:"09. change"`
+				ChangePercent string `json:"10. change percent"`
+			} `json:"Global Quote"`
+			// Rate limit and API key errors are returned with a 200 status.
+			Note        string
+			Information string
+			Error       string `json:"Error Message"`
+		}
+		qp := url.Values{}
+		qp.Add("function", "GLOBAL_QUOTE")
+		qp.Add("symbol", s)
+		qp.Add("apikey", key)
+		if err := client.GetJSON(alphaVantageAPI+"/query?"+qp.Encode(), &r); err != nil {
+			return nil, err
+		}
+		for _, msg := range []string{r.Error, r.Note, r.Information} {
+			if msg != "" {
+				return nil, errors.New(msg)
+			}
+		}
+		if r.Quote.Symbol == "" {
+			return nil, fmt.Errorf("no quote for %s", s)
+		}
+		price, err := strconv.ParseFloat(r.Quote.Price, 64)
+		if err != nil {
+			return nil, err
+		}
+		change, err := strconv.ParseFloat(r.Quote.Change, 64)
+		if err != nil {
+			return nil, err
+		}
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(r.Quote.ChangePercent, "%"), 64)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, Quote{
+			Symbol:        s,
+			Price:         price,
+			Change:        change,
+			ChangePercent: pct,
+		})
+	}
+	return quotes, nil
+}
@@ -0,0 +1,407 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package stocks provides an i3bar module that shows stock quotes.
+
+Quotes for the configured symbols are fetched using a Provider. Providers are
+available for Yahoo Finance, Finnhub, and Alpha Vantage. Quotes are only
+polled while the market is open, with one final refresh after it closes to get
+the closing prices. The default output shows one symbol at a time, coloured by
+the change since the previous close, and scrolling cycles through the symbols.
+Quotes can also be cached, so that restarting the bar does not use up requests
+on rate limited plans.
+*/
+package stocks
+
+import (
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/cache"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/base/state"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Quote represents the price of a stock.
+type Quote struct {
+	Symbol string
+	Price  float64
+	// Change and ChangePercent are the change in price since the
+	// previous close.
+	Change        float64
+	ChangePercent float64
+}
+
+// Up returns true if the price has not gone down since the previous close.
+func (q Quote) Up() bool {
+	return q.Change >= 0
+}
+
+// Provider gets stock quotes from a market data service.
+type Provider interface {
+	// Quotes returns the quotes for the given symbols, in the same order.
+	Quotes([]string) ([]Quote, error)
+}
+
+// Market represents the trading hours of a stock market. Markets are assumed
+// to be open on all weekdays between the opening and closing times, since
+// holidays vary between markets and years. A market with the same opening
+// and closing times is always open.
+type Market struct {
+	// Location is the timezone of the market. Nil means UTC.
+	Location *time.Location
+	// Open and Close are the times of day (since midnight in the market's
+	// timezone) at which trading starts and ends.
+	Open, Close time.Duration
+}
+
+// USMarket returns the regular trading hours of the NYSE and NASDAQ.
+func USMarket() Market {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		// Without timezone data, use standard time. The hours will
+		// be off by one during daylight saving time.
+		loc = time.FixedZone("EST", -5*60*60)
+	}
+	return Market{
+		Location: loc,
+		Open:     9*time.Hour + 30*time.Minute,
+		Close:    16 * time.Hour,
+	}
+}
+
+func (k Market) midnight(t time.Time) time.Time {
+	loc := k.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+// IsOpen returns true if the market is open at the given time.
+func (k Market) IsOpen(t time.Time) bool {
+	if k.Open == k.Close {
+		return true
+	}
+	midnight := k.midnight(t)
+	if isWeekend(midnight) {
+		return false
+	}
+	sinceMidnight := t.Sub(midnight)
+	return sinceMidnight >= k.Open && sinceMidnight < k.Close
+}
+
+// NextOpen returns the next time after the given time at which the market opens.
+func (k Market) NextOpen(t time.Time) time.Time {
+	midnight := k.midnight(t)
+	for {
+		open := midnight.Add(k.Open)
+		if !isWeekend(midnight) && open.After(t) {
+			return open
+		}
+		midnight = midnight.AddDate(0, 0, 1)
+	}
+}
+
+// Info represents the quotes for all configured symbols, and the symbol
+// currently being shown.
+type Info struct {
+	Quotes  []Quote
+	Current int
+	// Open is true if the market was open at the last refresh.
+	Open bool
+}
+
+// Quote returns the quote currently being shown.
+func (i Info) Quote() Quote {
+	if i.Current < 0 || i.Current >= len(i.Quotes) {
+		return Quote{}
+	}
+	return i.Quotes[i.Current]
+}
+
+// Controller provides an interface to cycle through the symbols from the
+// click handler.
+type Controller interface {
+	// Next shows the next symbol, cycling back to the first.
+	Next()
+	// Previous shows the previous symbol, cycling to the last.
+	Previous()
+}
+
+// Module is the public interface for a stocks module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to cycle through the symbols.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency while the market is open.
+	RefreshInterval(time.Duration) Module
+
+	// Market sets the trading hours, outside of which quotes are not polled.
+	Market(Market) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+
+	// Persist saves the symbol being shown under the given key whenever it
+	// changes, and restores the previously saved symbol, if any.
+	Persist(key string) Module
+
+	// Cache saves the quotes under the given key after every fetch, and uses
+	// the saved quotes on start if they are still current, i.e. they are
+	// newer than the refresh interval, or the market has not opened since
+	// they were fetched after it closed.
+	Cache(key string) Module
+}
+
+type module struct {
+	*base.Base
+	provider   Provider
+	symbols    []string
+	interval   time.Duration
+	market     Market
+	outputFunc func(Info) bar.Output
+	info       Info
+	key        string
+	cacheKey   string
+	loaded     bool
+}
+
+// cachedQuotes is the result of the last fetch, saved to the cache.
+type cachedQuotes struct {
+	Symbols []string
+	Quotes  []Quote
+	Open    bool
+}
+
+// current returns true if the cached quotes, saved at the given time, are
+// for the given symbols, and do not need to be refreshed yet. If so, it also
+// returns the time of the next refresh.
+func (c cachedQuotes) current(saved time.Time, symbols []string, market Market, interval time.Duration) (time.Time, bool) {
+	if len(c.Symbols) != len(symbols) || len(c.Quotes) != len(symbols) {
+		return time.Time{}, false
+	}
+	for i, s := range symbols {
+		if c.Symbols[i] != s {
+			return time.Time{}, false
+		}
+	}
+	now := scheduler.Now()
+	if c.Open {
+		next := saved.Add(interval)
+		return next, now.Before(next)
+	}
+	next := market.NextOpen(saved)
+	return next, now.Before(next)
+}
+
+// New constructs an instance of the stocks module that shows quotes for the
+// given symbols using the given provider.
+func New(provider Provider, symbols ...string) Module {
+	m := &module{
+		Base:     base.New(),
+		provider: provider,
+		symbols:  symbols,
+		// Free API plans are heavily rate limited.
+		interval: 5 * time.Minute,
+		market:   USMarket(),
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows the current symbol's price and percent change,
+// using the 'good' colour from the scheme if the price has gone up,
+// and 'bad' if it has gone down.
+func DefaultOutput(i Info) bar.Output {
+	if len(i.Quotes) == 0 {
+		return outputs.Empty()
+	}
+	q := i.Quote()
+	color := colors.Scheme("bad")
+	if q.Up() {
+		color = colors.Scheme("good")
+	}
+	return outputs.Textf("%s %.2f %+.2f%%", q.Symbol, q.Price, q.ChangePercent).Color(color)
+}
+
+// DefaultClickHandler cycles through the symbols on scroll.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ScrollDown, bar.ScrollRight, bar.ButtonForward:
+		c.Next()
+	case bar.ScrollUp, bar.ScrollLeft, bar.ButtonBack:
+		c.Previous()
+	}
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.interval = interval
+	return m
+}
+
+func (m *module) Market(market Market) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.market = market
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+func (m *module) Persist(key string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.key = key
+	var symbol string
+	if state.Load(key, &symbol) {
+		for i, s := range m.symbols {
+			if s == symbol {
+				m.info.Current = i
+			}
+		}
+	}
+	return m
+}
+
+func (m *module) Cache(key string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.cacheKey = key
+	m.loaded = false
+	return m
+}
+
+func (m *module) Next() {
+	m.moveBy(1)
+}
+
+func (m *module) Previous() {
+	m.moveBy(-1)
+}
+
+func (m *module) moveBy(delta int) {
+	m.Lock()
+	count := len(m.info.Quotes)
+	if count > 0 {
+		m.info.Current = ((m.info.Current+delta)%count + count) % count
+	}
+	key, symbol := m.key, m.info.Quote().Symbol
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+	if key != "" && symbol != "" {
+		// Errors are ignored, since the selection is only a convenience.
+		state.Save(key, symbol)
+	}
+}
+
+func (m *module) update() {
+	m.Lock()
+	market := m.market
+	interval := m.interval
+	key := m.cacheKey
+	load := key != "" && !m.loaded
+	m.loaded = true
+	m.Unlock()
+	if load {
+		var c cachedQuotes
+		if saved, ok := cache.Load(key, &c); ok {
+			if next, ok := c.current(saved, m.symbols, market, interval); ok {
+				m.Schedule().At(next)
+				m.setQuotes(c.Quotes, c.Open)
+				return
+			}
+		}
+	}
+	// Schedule the next refresh before fetching, so that errors are retried.
+	// While the market is open, quotes are refreshed at the interval. Once it
+	// closes, the next refresh gets the closing prices, and then polling
+	// stops until the market opens again.
+	now := scheduler.Now()
+	open := market.IsOpen(now)
+	if open {
+		m.Schedule().After(interval)
+	} else {
+		m.Schedule().At(market.NextOpen(now))
+	}
+	quotes, err := m.provider.Quotes(m.symbols)
+	if m.Error(err) {
+		return
+	}
+	if key != "" {
+		cache.Save(key, cachedQuotes{m.symbols, quotes, open})
+	}
+	m.setQuotes(quotes, open)
+}
+
+// setQuotes updates the quotes being shown, and outputs them.
+func (m *module) setQuotes(quotes []Quote, open bool) {
+	m.Lock()
+	// Keep showing the same symbol across refreshes.
+	m.info.Quotes = quotes
+	m.info.Open = open
+	if m.info.Current >= len(quotes) {
+		m.info.Current = 0
+	}
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
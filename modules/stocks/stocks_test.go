@@ -0,0 +1,342 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stocks
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/cache"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/colors"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestMarket(t *testing.T) {
+	assert := assert.New(t)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	k := Market{Location: tokyo, Open: 9 * time.Hour, Close: 15 * time.Hour}
+
+	// 2018-01-05 is a Friday.
+	friday := time.Date(2018, 1, 5, 0, 0, 0, 0, tokyo)
+	assert.False(k.IsOpen(friday.Add(8 * time.Hour)))
+	assert.True(k.IsOpen(friday.Add(9 * time.Hour)))
+	assert.True(k.IsOpen(friday.Add(14*time.Hour + 59*time.Minute)))
+	assert.False(k.IsOpen(friday.Add(15 * time.Hour)))
+	assert.False(k.IsOpen(friday.AddDate(0, 0, 1).Add(12*time.Hour)), "saturday")
+	assert.True(k.IsOpen(time.Date(2018, 1, 5, 1, 0, 0, 0, time.UTC)),
+		"10am in Tokyo, in UTC")
+
+	morning := friday.Add(8 * time.Hour)
+	assert.Equal(friday.Add(9*time.Hour), k.NextOpen(morning))
+	monday := friday.AddDate(0, 0, 3)
+	assert.Equal(monday.Add(9*time.Hour), k.NextOpen(friday.Add(9*time.Hour)),
+		"skips weekend")
+	assert.Equal(monday.Add(9*time.Hour), k.NextOpen(friday.Add(20*time.Hour)))
+
+	alwaysOpen := Market{}
+	assert.True(alwaysOpen.IsOpen(friday.AddDate(0, 0, 1)))
+
+	us := USMarket()
+	assert.Equal(9*time.Hour+30*time.Minute, us.Open)
+	assert.Equal(16*time.Hour, us.Close)
+}
+
+func TestYahoo(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/v7/finance/quote", r.URL.Path)
+		if r.URL.Query().Get("symbols") == "BAD" {
+			w.Write([]byte(`{"quoteResponse": {"result": null, "error": {"description": "Invalid"}}}`))
+			return
+		}
+		w.Write([]byte(`{"quoteResponse": {"result": [
+			{"symbol": "MSFT", "regularMarketPrice": 88.5, "regularMarketChange": -0.5,
+				"regularMarketChangePercent": -0.56},
+			{"symbol": "AAPL", "regularMarketPrice": 170.25, "regularMarketChange": 1.25,
+				"regularMarketChangePercent": 0.74}]}}`))
+	}))
+	defer srv.Close()
+	yahooAPI = srv.URL
+
+	quotes, err := Yahoo().Quotes([]string{"AAPL", "MSFT"})
+	assert.NoError(err)
+	assert.Equal([]Quote{
+		{"AAPL", 170.25, 1.25, 0.74},
+		{"MSFT", 88.5, -0.5, -0.56},
+	}, quotes)
+
+	_, err = Yahoo().Quotes([]string{"AAPL", "GOOG"})
+	assert.Error(err, "missing symbol")
+	_, err = Yahoo().Quotes([]string{"BAD"})
+	assert.Error(err, "error response")
+}
+
+func TestFinnhub(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/quote", r.URL.Path)
+		if r.URL.Query().Get("token") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("symbol") {
+		case "AAPL":
+			w.Write([]byte(`{"c": 170.25, "d": 1.25, "dp": 0.74, "pc": 169}`))
+		default:
+			w.Write([]byte(`{"c": 0, "d": null, "dp": null, "pc": 0}`))
+		}
+	}))
+	defer srv.Close()
+	finnhubAPI = srv.URL
+
+	quotes, err := Finnhub(secret.Value("key")).Quotes([]string{"AAPL"})
+	assert.NoError(err)
+	assert.Equal([]Quote{{"AAPL", 170.25, 1.25, 0.74}}, quotes)
+
+	_, err = Finnhub(secret.Value("key")).Quotes([]string{"AAPL", "NOPE"})
+	assert.Error(err, "unknown symbol")
+	_, err = Finnhub(secret.Value("wrong")).Quotes([]string{"AAPL"})
+	assert.Error(err, "wrong api key")
+	_, err = Finnhub(secret.Value("")).Quotes([]string{"AAPL"})
+	assert.Error(err, "missing api key")
+}
+
+func TestAlphaVantage(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/query", r.URL.Path)
+		assert.Equal("GLOBAL_QUOTE", r.URL.Query().Get("function"))
+		switch r.URL.Query().Get("symbol") {
+		case "IBM":
+			w.Write([]byte(`{"Global Quote": {"01. symbol": "IBM", "05. price": "154.2500",
+				"09. change": "-1.1000", "10. change percent": "-0.7081%"}}`))
+		case "LIMIT":
+			w.Write([]byte(`{"Note": "API call frequency exceeded"}`))
+		case "BAD":
+			w.Write([]byte(`{"Global Quote": {"01. symbol": "BAD", "05. price": "?"}}`))
+		default:
+			w.Write([]byte(`{"Global Quote": {}}`))
+		}
+	}))
+	defer srv.Close()
+	alphaVantageAPI = srv.URL
+
+	quotes, err := AlphaVantage(secret.Value("key")).Quotes([]string{"IBM"})
+	assert.NoError(err)
+	assert.Equal([]Quote{{"IBM", 154.25, -1.1, -0.7081}}, quotes)
+
+	_, err = AlphaVantage(secret.Value("key")).Quotes([]string{"LIMIT"})
+	assert.EqualError(err, "API call frequency exceeded")
+	_, err = AlphaVantage(secret.Value("key")).Quotes([]string{"NOPE"})
+	assert.Error(err, "unknown symbol")
+	_, err = AlphaVantage(secret.Value("key")).Quotes([]string{"BAD"})
+	assert.Error(err, "invalid price")
+}
+
+type testProvider struct {
+	quotes []Quote
+	err    error
+	calls  int32
+}
+
+func (t *testProvider) Quotes([]string) ([]Quote, error) {
+	atomic.AddInt32(&t.calls, 1)
+	return t.quotes, t.err
+}
+
+func (t *testProvider) callCount() int {
+	return int(atomic.LoadInt32(&t.calls))
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	colors.LoadFromMap(map[string]string{"good": "#00ff00", "bad": "#ff0000"})
+
+	p := &testProvider{quotes: []Quote{
+		{"AAPL", 170.25, 1.25, 0.74},
+		{"MSFT", 88.5, -0.5, -0.56},
+	}}
+	// Test time starts at midnight on a Monday, in UTC.
+	s := New(p, "AAPL", "MSFT").Market(Market{Open: 9 * time.Hour, Close: 17 * time.Hour})
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal("AAPL 170.25 +0.74%", out[0].Text())
+	assert.Equal(colors.Hex("#00ff00"), out[0]["color"], "price up")
+
+	scheduler.AdvanceBy(8 * time.Hour)
+	tester.AssertNoOutput("while market is closed")
+
+	p.quotes[0].Price = 171
+	scheduler.AdvanceBy(time.Hour)
+	out = tester.AssertOutput("when market opens")
+	assert.Equal("AAPL 171.00 +0.74%", out[0].Text())
+
+	assert.Equal(time.Time{}.Add(9*time.Hour+5*time.Minute), scheduler.NextTick())
+	tester.AssertOutput("on refresh while open")
+
+	s.RefreshInterval(time.Hour)
+	tester.AssertOutput("on refresh interval change")
+	assert.Equal(time.Time{}.Add(10*time.Hour+5*time.Minute), scheduler.NextTick())
+	tester.AssertOutput("on refresh while open")
+
+	s.Click(bar.Event{Button: bar.ScrollDown})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("MSFT 88.50 -0.56%", out[0].Text())
+	assert.Equal(colors.Hex("#ff0000"), out[0]["color"], "price down")
+	s.Click(bar.Event{Button: bar.ScrollUp})
+	tester.AssertOutput("on scroll")
+	s.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("MSFT 88.50 -0.56%", out[0].Text(), "cycles to last symbol")
+
+	var info Info
+	s.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.True(info.Open)
+	assert.Equal(1, info.Current)
+
+	for i := 0; i < 6; i++ {
+		scheduler.NextTick()
+		tester.AssertOutput("on refresh while open")
+	}
+	assert.Equal(time.Time{}.Add(17*time.Hour+5*time.Minute), scheduler.NextTick())
+	tester.AssertOutput("on refresh after close")
+	assert.False(info.Open)
+	assert.Equal(time.Time{}.Add(33*time.Hour), scheduler.NextTick(),
+		"next refresh when market opens")
+	tester.AssertOutput("on market open")
+
+	p.err = errors.New("rate limited")
+	scheduler.NextTick()
+	tester.AssertError("on provider error")
+}
+
+func TestPersist(t *testing.T) {
+	scheduler.TestMode(true)
+	dir, err := ioutil.TempDir("", "stocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_STATE_HOME", dir)
+	defer os.Unsetenv("XDG_STATE_HOME")
+
+	p := &testProvider{quotes: []Quote{
+		{"AAPL", 170.25, 1.25, 0.74},
+		{"MSFT", 88.5, -0.5, -0.56},
+		{"GOOG", 1100, 10, 0.92},
+	}}
+	alwaysOpen := Market{}
+	s := New(p, "AAPL", "MSFT", "GOOG").Market(alwaysOpen).Persist("stocks")
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal(t, "AAPL 170.25 +0.74%", out[0].Text(), "nothing saved yet")
+
+	s.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal(t, "GOOG 1100.00 +0.92%", out[0].Text())
+
+	s = New(p, "AAPL", "MSFT", "GOOG").Market(alwaysOpen).Persist("stocks")
+	tester = testModule.NewOutputTester(t, s)
+	out = tester.AssertOutput("on start")
+	assert.Equal(t, "GOOG 1100.00 +0.92%", out[0].Text(), "selected symbol restored")
+
+	p.quotes = p.quotes[:2]
+	s = New(p, "AAPL", "MSFT").Market(alwaysOpen).Persist("stocks")
+	tester = testModule.NewOutputTester(t, s)
+	out = tester.AssertOutput("on start")
+	assert.Equal(t, "AAPL 170.25 +0.74%", out[0].Text(), "removed symbol ignored")
+}
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	dir, err := ioutil.TempDir("", "stocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_CACHE_HOME", dir)
+	defer os.Unsetenv("XDG_CACHE_HOME")
+	// Modules from previous runs of this test are still refreshing, so use
+	// keys that are unique to this run.
+	key := filepath.Base(dir)
+
+	quotes := []Quote{
+		{"AAPL", 170.25, 1.25, 0.74},
+		{"MSFT", 88.5, -0.5, -0.56},
+	}
+	market := Market{Open: 9 * time.Hour, Close: 17 * time.Hour}
+	scheduler.AdvanceBy(9 * time.Hour)
+
+	p := &testProvider{quotes: quotes}
+	s := New(p, "AAPL", "MSFT").Market(market).Cache(key)
+	out := testModule.NewOutputTester(t, s).AssertOutput("on start")
+	assert.Equal("AAPL 170.25 +0.74%", out[0].Text())
+	assert.Equal(1, p.callCount())
+
+	// A new module uses the cached quotes until the next refresh.
+	scheduler.AdvanceBy(2 * time.Minute)
+	p2 := &testProvider{quotes: []Quote{{"AAPL", 171, 2, 1.18}, {"MSFT", 89, 0, 0}}}
+	s2 := New(p2, "AAPL", "MSFT").Market(market).Cache(key)
+	tester2 := testModule.NewOutputTester(t, s2)
+	out = tester2.AssertOutput("on start")
+	assert.Equal("AAPL 170.25 +0.74%", out[0].Text(), "cached quotes are used")
+	assert.Equal(0, p2.callCount())
+
+	scheduler.AdvanceBy(2 * time.Minute)
+	tester2.AssertNoOutput("while cache is current")
+	scheduler.AdvanceBy(time.Minute)
+	out = tester2.AssertOutput("when cache expires")
+	assert.Equal("AAPL 171.00 +1.18%", out[0].Text())
+	assert.Equal(1, p2.callCount())
+
+	// Closing prices are used until the market opens again.
+	scheduler.AdvanceTo(time.Time{}.Add(17*time.Hour + 30*time.Minute))
+	cache.Save(key, cachedQuotes{[]string{"AAPL", "MSFT"}, quotes, false})
+	scheduler.AdvanceBy(4 * time.Hour)
+	p3 := &testProvider{quotes: quotes}
+	s3 := New(p3, "AAPL", "MSFT").Market(market).Cache(key)
+	tester3 := testModule.NewOutputTester(t, s3)
+	tester3.AssertOutput("on start")
+	assert.Equal(0, p3.callCount(), "closing prices are used")
+	scheduler.AdvanceTo(time.Time{}.Add(33*time.Hour - time.Second))
+	tester3.AssertNoOutput("while market is closed")
+	scheduler.AdvanceBy(time.Second)
+	tester3.AssertOutput("on market open")
+	assert.Equal(1, p3.callCount())
+
+	// Cached quotes for different symbols are not used.
+	p4 := &testProvider{quotes: quotes[:1]}
+	s4 := New(p4, "AAPL").Market(market).Cache(key)
+	testModule.NewOutputTester(t, s4).AssertOutput("on start")
+	assert.Equal(1, p4.callCount(), "symbols changed")
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncthing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/soumya92/barista/base/secret"
+)
+
+// api is a minimal client for the Syncthing REST API.
+type api struct {
+	url    string
+	apiKey secret.Secret
+	client *http.Client
+}
+
+func newAPI(url string, apiKey secret.Secret) *api {
+	// The timeout must be longer than the events long-poll timeout.
+	return &api{url, apiKey, &http.Client{Timeout: 2 * eventTimeout}}
+}
+
+func (a *api) get(path string, query url.Values, out interface{}) error {
+	apiKey, err := a.apiKey.Get()
+	if err != nil {
+		return err
+	}
+	u := a.url + path
+	if query != nil {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", apiKey)
+	response, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+func (a *api) info() (Info, error) {
+	var connections struct {
+		Connections map[string]struct{ Connected bool }
+	}
+	if err := a.get("/rest/system/connections", nil, &connections); err != nil {
+		return Info{}, err
+	}
+	info := Info{Devices: len(connections.Connections)}
+	for _, c := range connections.Connections {
+		if c.Connected {
+			info.Connected++
+		}
+	}
+	var folders []struct {
+		ID     string
+		Label  string
+		Paused bool
+	}
+	if err := a.get("/rest/config/folders", nil, &folders); err != nil {
+		return Info{}, err
+	}
+	for _, f := range folders {
+		if f.Paused {
+			continue
+		}
+		var status struct {
+			State       string
+			NeedBytes   int64
+			GlobalBytes int64
+			Errors      int
+			Error       string
+		}
+		if err := a.get("/rest/db/status", url.Values{"folder": {f.ID}}, &status); err != nil {
+			return Info{}, err
+		}
+		info.Folders = append(info.Folders, Folder{
+			ID:          f.ID,
+			Label:       f.Label,
+			State:       status.State,
+			NeedBytes:   status.NeedBytes,
+			GlobalBytes: status.GlobalBytes,
+			Errors:      status.Errors,
+			Error:       status.Error,
+		})
+	}
+	return info, nil
+}
+
+// eventTimeout is how long each events request waits for new events.
+var eventTimeout = time.Minute
+
+// retryDelay is how long to wait before listening for events again,
+// if the events request fails (e.g. if Syncthing is restarting).
+var retryDelay = 10 * time.Second
+
+// events are the types of events that change the module's output.
+const events = "StateChanged,FolderCompletion,FolderErrors,FolderSummary," +
+	"DeviceConnected,DeviceDisconnected,ConfigSaved"
+
+// listen calls the update function whenever relevant events occur.
+// It never returns.
+func (a *api) listen(update func()) {
+	var since int
+	for {
+		var received []struct{ ID int }
+		err := a.get("/rest/events", url.Values{
+			"since":   {fmt.Sprintf("%d", since)},
+			"timeout": {fmt.Sprintf("%d", int(eventTimeout.Seconds()))},
+			"events":  {events},
+		}, &received)
+		if err != nil {
+			// Polling will show the error, if it persists. Event IDs
+			// start from 1 again when Syncthing is restarted.
+			since = 0
+			time.Sleep(retryDelay)
+			continue
+		}
+		if len(received) == 0 {
+			continue
+		}
+		since = received[len(received)-1].ID
+		update()
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package syncthing provides an i3bar module that shows the status of Syncthing.
+
+The module uses the Syncthing REST API to get the state of each folder and the
+number of connected devices, and the events API to update as soon as anything
+changes. The API key can be found in the Syncthing GUI settings. By default the
+module shows the sync progress while syncing, and is urgent if any folder has
+errors.
+*/
+package syncthing
+
+import (
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Folder represents the state of a synced folder.
+type Folder struct {
+	ID    string
+	Label string
+	// State is the folder state, e.g. "idle", "scanning", "syncing",
+	// or "error".
+	State string
+	// NeedBytes is the amount of data to be synced, out of GlobalBytes.
+	NeedBytes   int64
+	GlobalBytes int64
+	// Errors is the number of files that failed to sync, and Error is
+	// the reason the folder is stopped, if any.
+	Errors int
+	Error  string
+}
+
+// Info represents the state of Syncthing.
+type Info struct {
+	Folders []Folder
+	// Connected is the number of connected devices, out of Devices.
+	Connected int
+	Devices   int
+}
+
+// Syncing returns true if any folder is syncing.
+func (i Info) Syncing() bool {
+	for _, f := range i.Folders {
+		if f.State == "syncing" || f.State == "sync-preparing" {
+			return true
+		}
+	}
+	return false
+}
+
+// Completion returns the percentage of data in sync across all folders.
+func (i Info) Completion() float64 {
+	var need, global int64
+	for _, f := range i.Folders {
+		need += f.NeedBytes
+		global += f.GlobalBytes
+	}
+	if global == 0 {
+		return 100
+	}
+	return 100 * float64(global-need) / float64(global)
+}
+
+// Errors returns the number of folder and file errors across all folders.
+func (i Info) Errors() int {
+	count := 0
+	for _, f := range i.Folders {
+		count += f.Errors
+		if f.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// HasErrors returns true if there are any errors.
+func (i Info) HasErrors() bool {
+	return i.Errors() > 0
+}
+
+// Module is the public interface for a syncthing module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency, in addition to
+	// updates from the events API.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	api        *api
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the syncthing module for the local
+// Syncthing instance, using the given API key.
+func New(apiKey secret.Secret) Module {
+	return Server("http://localhost:8384", apiKey)
+}
+
+// Server constructs an instance of the syncthing module for the Syncthing
+// instance at the given URL, using the given API key.
+func Server(url string, apiKey secret.Secret) Module {
+	m := &module{
+		Base: base.New(),
+		api:  newAPI(url, apiKey),
+	}
+	// Events trigger updates, so polling is only a fallback.
+	m.RefreshInterval(time.Minute)
+	m.OutputFunc(DefaultOutput)
+	m.UrgentWhen(Info.HasErrors)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows the number of errors if there are any, the sync
+// progress while syncing, and otherwise the number of connected devices.
+func DefaultOutput(i Info) bar.Output {
+	switch {
+	case i.HasErrors():
+		return outputs.Textf("sync: %d errors", i.Errors())
+	case i.Syncing():
+		return outputs.Textf("sync: %.0f%%", i.Completion())
+	}
+	return outputs.Textf("sync: %d/%d", i.Connected, i.Devices)
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+// Stream starts listening for events, and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go m.api.listen(m.Update)
+	return ch
+}
+
+func (m *module) update() {
+	info, err := m.api.info()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncthing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/base/secret"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeSyncthing struct {
+	sync.Mutex
+	status map[string]string
+	events chan string
+	since  chan string
+}
+
+func (f *fakeSyncthing) setStatus(folder, status string) {
+	f.Lock()
+	defer f.Unlock()
+	f.status[folder] = status
+}
+
+func (f *fakeSyncthing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-API-Key") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/rest/system/connections":
+		w.Write([]byte(`{"connections": {
+			"AAAA": {"connected": true}, "BBBB": {"connected": false}, "CCCC": {"connected": true}}}`))
+	case "/rest/config/folders":
+		w.Write([]byte(`[{"id": "docs", "label": "Documents"}, {"id": "music", "label": "Music"},
+			{"id": "old", "label": "Old", "paused": true}]`))
+	case "/rest/db/status":
+		f.Lock()
+		status, ok := f.status[r.URL.Query().Get("folder")]
+		f.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(status))
+	case "/rest/events":
+		select {
+		case f.since <- r.URL.Query().Get("since"):
+		default:
+		}
+		select {
+		case e := <-f.events:
+			w.Write([]byte(e))
+		case <-time.After(50 * time.Millisecond):
+			w.Write([]byte(`[]`))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	f := &fakeSyncthing{
+		status: map[string]string{
+			"docs":  `{"state": "idle", "needBytes": 0, "globalBytes": 1000}`,
+			"music": `{"state": "idle", "needBytes": 0, "globalBytes": 3000}`,
+		},
+		events: make(chan string),
+		since:  make(chan string, 100),
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	s := Server(srv.URL, secret.Value("secret"))
+	tester := testModule.NewOutputTester(t, s)
+	out := tester.AssertOutput("on start")
+	assert.Equal("sync: 2/3", out[0].Text())
+	assert.Equal(false, out[0]["urgent"])
+
+	assert.Equal("0", <-f.since)
+	f.setStatus("music", `{"state": "syncing", "needBytes": 2000, "globalBytes": 3000}`)
+	f.events <- `[{"id": 5, "type": "StateChanged"}, {"id": 6, "type": "FolderCompletion"}]`
+	out = tester.AssertOutput("on events")
+	assert.Equal("sync: 50%", out[0].Text())
+	for since := range f.since {
+		if since != "0" {
+			assert.Equal("6", since, "events since last received")
+			break
+		}
+	}
+
+	f.setStatus("docs", `{"state": "error", "error": "folder marker missing", "globalBytes": 1000}`)
+	f.setStatus("music", `{"state": "idle", "errors": 2, "globalBytes": 3000}`)
+	scheduler.AdvanceBy(time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("sync: 3 errors", out[0].Text())
+	assert.Equal(true, out[0]["urgent"])
+
+	var info Info
+	s.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(2, len(info.Folders), "paused folders are skipped")
+	assert.Equal(Folder{ID: "docs", Label: "Documents", State: "error",
+		GlobalBytes: 1000, Error: "folder marker missing"}, info.Folders[0])
+	assert.Equal(100.0, Info{}.Completion())
+
+	s.OutputFunc(DefaultOutput)
+	tester.AssertOutput("on output func change")
+	s.UrgentWhen(nil)
+	out = tester.AssertOutput("on urgent func change")
+	_, ok := out[0]["urgent"]
+	assert.False(ok)
+
+	s = Server(srv.URL, secret.Value("wrong"))
+	tester = testModule.NewOutputTester(t, s)
+	tester.AssertError("with wrong API key")
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/soumya92/barista/base"
 	"github.com/soumya92/barista/base/multi"
 	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/format"
 )
 
 // Info wraps the result of sysinfo and makes it more useful.
@@ -56,12 +57,12 @@ func (b Bytes) In(unit string) float64 {
 
 // IEC returns the size formatted in base 2.
 func (b Bytes) IEC() string {
-	return humanize.IBytes(uint64(b))
+	return format.IBytes(uint64(b))
 }
 
 // SI returns the size formatted in base 10.
 func (b Bytes) SI() string {
-	return humanize.Bytes(uint64(b))
+	return format.Bytes(uint64(b))
 }
 
 // Module represents a sysinfo multi-module, and provides an interface
@@ -0,0 +1,132 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+// Constants for the systemd dbus API.
+const (
+	systemdDest    = "org.freedesktop.systemd1"
+	systemdPath    = dbus.ObjectPath("/org/freedesktop/systemd1")
+	managerIface   = "org.freedesktop.systemd1.Manager"
+	unitIface      = "org.freedesktop.systemd1.Unit"
+	propsIface     = "org.freedesktop.DBus.Properties"
+	propsChanged   = propsIface + ".PropertiesChanged"
+	jobModeReplace = "replace"
+)
+
+// dbusUnit is the d-bus client for a systemd unit.
+type dbusUnit struct {
+	name     string
+	user     bool
+	manager  dbus.BusObject
+	unit     dbus.BusObject
+	unitPath dbus.ObjectPath
+	signals  chan *dbus.Signal
+}
+
+func (d *dbusUnit) connect() error {
+	// A private connection is required since we're using Signal.
+	var conn *dbus.Conn
+	var err error
+	if d.user {
+		conn, err = dbus.SessionBusPrivate()
+	} else {
+		conn, err = dbus.SystemBusPrivate()
+	}
+	if err != nil {
+		return err
+	}
+	if err := d.setup(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	return nil
+}
+
+// setup initialises the private connection, and subscribes to changes of
+// the unit's properties.
+func (d *dbusUnit) setup(conn *dbus.Conn) error {
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := conn.Auth(nil); err != nil {
+		return err
+	}
+	if err := conn.Hello(); err != nil {
+		return err
+	}
+	d.manager = conn.Object(systemdDest, systemdPath)
+	// LoadUnit returns the object path even if the unit is not currently
+	// loaded, as opposed to GetUnit which fails for inactive units.
+	err := d.manager.Call(managerIface+".LoadUnit", 0, d.name).Store(&d.unitPath)
+	if err != nil {
+		return err
+	}
+	d.unit = conn.Object(systemdDest, d.unitPath)
+	// Systemd only emits signals to subscribed clients.
+	if err := d.manager.Call(managerIface+".Subscribe", 0).Err; err != nil {
+		return err
+	}
+	matchRule := strings.Join([]string{
+		"type='signal'",
+		"interface='" + propsIface + "'",
+		"member='PropertiesChanged'",
+		"path='" + string(d.unitPath) + "'",
+	}, ",")
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		return err
+	}
+	d.signals = make(chan *dbus.Signal, 10)
+	conn.Signal(d.signals)
+	return nil
+}
+
+func (d *dbusUnit) info() (Info, error) {
+	info := Info{Name: d.name}
+	for prop, dest := range map[string]*string{
+		"Description": &info.Description,
+		"ActiveState": &info.ActiveState,
+		"SubState":    &info.SubState,
+	} {
+		v, err := d.unit.GetProperty(unitIface + "." + prop)
+		if err != nil {
+			return Info{}, err
+		}
+		*dest, _ = v.Value().(string)
+	}
+	return info, nil
+}
+
+func (d *dbusUnit) call(method string) error {
+	return d.manager.Call(managerIface+"."+method, 0, d.name, jobModeReplace).Err
+}
+
+// watch handles dbus signals from systemd, and calls the given function
+// when the unit properties change.
+func (d *dbusUnit) watch(f func()) error {
+	for v := range d.signals {
+		if v.Path != d.unitPath || v.Name != propsChanged {
+			continue
+		}
+		if len(v.Body) < 1 || v.Body[0] != unitIface {
+			continue
+		}
+		f()
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package systemd provides an i3bar module that shows the state of a systemd unit.
+
+The module watches the unit over DBus, so changes are reflected immediately,
+and it supports starting, stopping, and restarting the unit using systemd's
+Manager API. Units can be either system units or user units:
+ systemd.Unit("sshd.service")
+ systemd.UserUnit("syncthing.service")
+*/
+package systemd
+
+import (
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the current state of a systemd unit.
+type Info struct {
+	// Name of the unit, e.g. "sshd.service".
+	Name string
+	// Description of the unit, from the unit file.
+	Description string
+	// ActiveState is the high-level unit state, e.g. "active", "failed".
+	ActiveState string
+	// SubState is the low-level, unit type specific state, e.g. "running", "exited".
+	SubState string
+}
+
+// Active returns true if the unit is active.
+func (i Info) Active() bool {
+	return i.ActiveState == "active"
+}
+
+// Failed returns true if the unit has failed.
+func (i Info) Failed() bool {
+	return i.ActiveState == "failed"
+}
+
+// Transitioning returns true if the unit is starting or stopping.
+func (i Info) Transitioning() bool {
+	return i.ActiveState == "activating" ||
+		i.ActiveState == "deactivating" ||
+		i.ActiveState == "reloading"
+}
+
+// Controller provides an interface to control the systemd unit,
+// used in the click handler.
+type Controller interface {
+	// Start starts the unit.
+	Start()
+
+	// Stop stops the unit.
+	Stop()
+
+	// Restart restarts the unit, starting it if it is not running.
+	Restart()
+}
+
+// Module is the public interface for a systemd unit module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control the unit.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+// unit reads and controls a systemd unit, and notifies on changes.
+type unit interface {
+	// connect sets up the connection to systemd.
+	connect() error
+	// info returns the current state of the unit.
+	info() (Info, error)
+	// call calls a method of systemd's Manager API for the unit.
+	call(method string) error
+	// watch calls the given function whenever the unit's properties
+	// change, until the connection fails.
+	watch(func()) error
+}
+
+type module struct {
+	*base.Base
+	unit       unit
+	connected  bool
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// Unit constructs an instance of the systemd module for a system unit.
+func Unit(name string) Module {
+	return newModule(name, &dbusUnit{name: name})
+}
+
+// UserUnit constructs an instance of the systemd module for a user unit,
+// managed by the user's systemd instance on the session bus.
+func UserUnit(name string) Module {
+	return newModule(name, &dbusUnit{name: name, user: true})
+}
+
+func newModule(name string, u unit) *module {
+	m := &module{
+		Base: base.New(),
+		unit: u,
+		info: Info{Name: name},
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the unit name and state,
+	// only shown if the unit is not active.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{if not .Active}}{{.Name}}: {{.ActiveState}}{{end}}`))
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler restarts the unit on left click if it has failed.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft && i.Failed() {
+		c.Restart()
+	}
+}
+
+func (m *module) Start() {
+	m.callManager("StartUnit")
+}
+
+func (m *module) Stop() {
+	m.callManager("StopUnit")
+}
+
+func (m *module) Restart() {
+	m.callManager("RestartUnit")
+}
+
+func (m *module) callManager(method string) {
+	m.Lock()
+	connected := m.connected
+	m.Unlock()
+	if !connected {
+		return
+	}
+	m.Error(m.unit.call(method))
+}
+
+// Stream connects to systemd and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	if m.Error(m.unit.connect()) {
+		return ch
+	}
+	m.Lock()
+	m.connected = true
+	m.Unlock()
+	m.OnUpdate(m.update)
+	m.refresh()
+	go func() { m.Error(m.unit.watch(m.refresh)) }()
+	return ch
+}
+
+// refresh reads the current unit properties and updates the module.
+func (m *module) refresh() {
+	info, err := m.unit.info()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	m.info = info
+	m.Unlock()
+	m.Update()
+}
+
+func (m *module) update() {
+	m.Lock()
+	info := m.info
+	out := m.outputFunc(info)
+	m.Unlock()
+	if info.Failed() {
+		out.Urgent(true)
+	}
+	m.Output(out)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeUnit struct {
+	sync.Mutex
+	state      Info
+	connectErr error
+	calls      chan string
+	changes    chan struct{}
+}
+
+func newFakeUnit(state Info) *fakeUnit {
+	return &fakeUnit{
+		state:   state,
+		calls:   make(chan string, 10),
+		changes: make(chan struct{}, 10),
+	}
+}
+
+func (f *fakeUnit) connect() error {
+	return f.connectErr
+}
+
+func (f *fakeUnit) info() (Info, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.state, nil
+}
+
+func (f *fakeUnit) call(method string) error {
+	f.calls <- method
+	return nil
+}
+
+func (f *fakeUnit) watch(update func()) error {
+	for range f.changes {
+		update()
+	}
+	return errors.New("connection closed")
+}
+
+// setState changes the unit state and simulates the PropertiesChanged signal.
+func (f *fakeUnit) setState(activeState, subState string) {
+	f.Lock()
+	f.state.ActiveState = activeState
+	f.state.SubState = subState
+	f.Unlock()
+	f.changes <- struct{}{}
+}
+
+func (f *fakeUnit) assertCalled(t *testing.T, method string, message string) {
+	select {
+	case m := <-f.calls:
+		assert.Equal(t, method, m, message)
+	default:
+		assert.Fail(t, "expected a call to "+method, message)
+	}
+}
+
+func TestUnit(t *testing.T) {
+	u := newFakeUnit(Info{
+		Name:        "foo.service",
+		Description: "Foo",
+		ActiveState: "active",
+		SubState:    "running",
+	})
+	m := newModule("foo.service", u)
+	tester := testModule.NewOutputTester(t, m)
+	out := tester.AssertOutput("on start")
+	assert.Equal(t, "", out[0].Text(), "no text when active")
+
+	u.setState("failed", "failed")
+	out = tester.AssertOutput("on property change")
+	assert.Equal(t, "foo.service: failed", out[0].Text())
+	assert.Equal(t, true, out[0]["urgent"], "failed units are urgent")
+
+	m.Click(bar.Event{Button: bar.ButtonRight})
+	assert.Empty(t, u.calls, "right click does nothing")
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	u.assertCalled(t, "RestartUnit", "left click on failed unit")
+
+	u.setState("activating", "start")
+	out = tester.AssertOutput("on property change")
+	assert.Equal(t, "foo.service: activating", out[0].Text())
+	assert.Nil(t, out[0]["urgent"], "only failed units are urgent")
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Empty(t, u.calls, "left click only restarts failed units")
+
+	m.OutputTemplate(outputs.TextTemplate(`{{.Description}} {{.SubState}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal(t, "Foo start", out[0].Text())
+
+	m.OnClick(func(i Info, c Controller, e bar.Event) {
+		switch e.Button {
+		case bar.ButtonLeft:
+			c.Start()
+		case bar.ButtonRight:
+			c.Stop()
+		}
+	})
+	m.Click(bar.Event{Button: bar.ButtonLeft})
+	u.assertCalled(t, "StartUnit", "custom click handler")
+	m.Click(bar.Event{Button: bar.ButtonRight})
+	u.assertCalled(t, "StopUnit", "custom click handler")
+}
+
+func TestConnectionError(t *testing.T) {
+	u := newFakeUnit(Info{Name: "foo.service"})
+	u.connectErr = errors.New("no bus")
+	m := newModule("foo.service", u)
+	tester := testModule.NewOutputTester(t, m)
+	assert.Equal(t, "no bus", tester.AssertError("on connection error"))
+	m.Click(bar.Event{Button: bar.ButtonMiddle})
+	assert.Empty(t, u.calls, "no calls without a connection")
+}
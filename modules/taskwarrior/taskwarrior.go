@@ -0,0 +1,323 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package taskwarrior provides an i3bar module that shows Taskwarrior tasks.
+
+Tasks matching a filter are read using "task export", and the module updates
+whenever the Taskwarrior data directory changes, as well as periodically so
+that tasks become overdue on time. By default the module shows the number of
+pending and overdue tasks, and clicking it opens the task list in a terminal.
+*/
+package taskwarrior
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Task represents a single Taskwarrior task.
+type Task struct {
+	ID          int
+	UUID        string
+	Description string
+	Project     string
+	Tags        []string
+	Status      string
+	// Due is the due date of the task, or zero if it has none.
+	Due     time.Time
+	Urgency float64
+}
+
+// Info represents the tasks matching the filter.
+type Info struct {
+	Tasks []Task
+	// Now is the time at which the tasks were read,
+	// used to determine which tasks are overdue.
+	Now time.Time
+}
+
+// Pending returns the number of pending tasks.
+func (i Info) Pending() int {
+	count := 0
+	for _, t := range i.Tasks {
+		if t.Status == "pending" {
+			count++
+		}
+	}
+	return count
+}
+
+// Overdue returns the number of pending tasks that are past their due date.
+func (i Info) Overdue() int {
+	count := 0
+	for _, t := range i.Tasks {
+		if t.Status == "pending" && !t.Due.IsZero() && t.Due.Before(i.Now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Next returns the pending task with the highest urgency, and false
+// if there are no pending tasks.
+func (i Info) Next() (Task, bool) {
+	var next Task
+	found := false
+	for _, t := range i.Tasks {
+		if t.Status == "pending" && (!found || t.Urgency > next.Urgency) {
+			next = t
+			found = true
+		}
+	}
+	return next, found
+}
+
+// Controller provides an interface to open the task list from the click handler.
+type Controller interface {
+	// Open opens a terminal showing the tasks matching the filter.
+	Open()
+}
+
+// Module is the public interface for a taskwarrior module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to open the task list.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency, in addition
+	// to updates when the task data changes.
+	RefreshInterval(time.Duration) Module
+
+	// Terminal sets the terminal command used to show the task list.
+	// The task command is appended to the given arguments.
+	Terminal(...string) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	filter     []string
+	terminal   []string
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the taskwarrior module for tasks matching
+// the given filter, e.g. New("+work"). If no filter is given, all pending
+// tasks are shown.
+func New(filter ...string) Module {
+	if len(filter) == 0 {
+		filter = []string{"status:pending"}
+	}
+	m := &module{Base: base.New(), filter: filter}
+	m.RefreshInterval(5 * time.Minute)
+	m.Terminal("x-terminal-emulator", "-e", "sh", "-c")
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the number of pending and overdue tasks.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{with .Pending}}{{.}} tasks{{end}}{{with .Overdue}}, {{.}} overdue{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) Terminal(terminal ...string) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.terminal = terminal
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler opens the task list on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft {
+		c.Open()
+	}
+}
+
+// runCommand runs a command, without waiting for it to finish.
+var runCommand = func(args ...string) error {
+	return exec.Command(args[0], args[1:]...).Start()
+}
+
+func (m *module) Open() {
+	m.Lock()
+	args := append([]string(nil), m.terminal...)
+	m.Unlock()
+	// The shell keeps the terminal open until a key is pressed,
+	// since the list command exits immediately.
+	args = append(args, "task "+strings.Join(m.filter, " ")+" list; read -n 1")
+	m.Error(runCommand(args...))
+}
+
+// export runs "task export" with the given filter.
+var export = func(filter ...string) ([]byte, error) {
+	// Disable hooks and confirmation prompts, which could otherwise
+	// block or have side effects.
+	args := append([]string{"rc.hooks=off", "rc.confirmation=off"}, filter...)
+	return exec.Command("task", append(args, "export")...).Output()
+}
+
+// dataDir returns the Taskwarrior data directory.
+var dataDir = func() (string, error) {
+	out, err := exec.Command("task", "_get", "rc.data.location").Output()
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(string(out))
+	if strings.HasPrefix(dir, "~/") {
+		dir = filepath.Join(os.Getenv("HOME"), dir[2:])
+	}
+	return dir, nil
+}
+
+// Stream starts watching the task data directory for changes, and then
+// returns the output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	dir, err := dataDir()
+	if m.Error(err) {
+		return ch
+	}
+	w, err := fsnotify.NewWatcher()
+	if m.Error(err) {
+		return ch
+	}
+	if err := w.Add(dir); m.Error(err) {
+		w.Close()
+		return ch
+	}
+	go m.listen(w)
+	return ch
+}
+
+// listen triggers an update whenever the task data changes.
+func (m *module) listen(w *fsnotify.Watcher) {
+	defer w.Close()
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			// Lock files are created and removed for every command,
+			// including the export used to update the module.
+			if strings.HasSuffix(e.Name, ".lock") {
+				continue
+			}
+			m.Update()
+		case err := <-w.Errors:
+			m.Error(err)
+			return
+		}
+	}
+}
+
+// taskTime is the format used for dates in task export.
+const taskTime = "20060102T150405Z"
+
+func (m *module) update() {
+	data, err := export(m.filter...)
+	if m.Error(err) {
+		return
+	}
+	var exported []struct {
+		ID          int
+		UUID        string
+		Description string
+		Project     string
+		Tags        []string
+		Status      string
+		Due         string
+		Urgency     float64
+	}
+	if m.Error(json.Unmarshal(data, &exported)) {
+		return
+	}
+	info := Info{Now: scheduler.Now()}
+	for _, t := range exported {
+		task := Task{
+			ID:          t.ID,
+			UUID:        t.UUID,
+			Description: t.Description,
+			Project:     t.Project,
+			Tags:        t.Tags,
+			Status:      t.Status,
+			Urgency:     t.Urgency,
+		}
+		if t.Due != "" {
+			if task.Due, err = time.Parse(taskTime, t.Due); m.Error(err) {
+				return
+			}
+		}
+		info.Tasks = append(info.Tasks, task)
+	}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
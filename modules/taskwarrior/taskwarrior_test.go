@@ -0,0 +1,142 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskwarrior
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+var exportMu sync.Mutex
+var exportData string
+var exportErr error
+var exportFilter []string
+
+func setExport(data string, err error) {
+	exportMu.Lock()
+	defer exportMu.Unlock()
+	exportData = data
+	exportErr = err
+}
+
+func init() {
+	export = func(filter ...string) ([]byte, error) {
+		exportMu.Lock()
+		defer exportMu.Unlock()
+		exportFilter = filter
+		return []byte(exportData), exportErr
+	}
+}
+
+const tasks = `[
+{"id": 1, "uuid": "a", "description": "Write report", "project": "work",
+	"status": "pending", "due": "00010101T120000Z", "urgency": 8.5},
+{"id": 2, "uuid": "b", "description": "Buy milk", "tags": ["errand"],
+	"status": "pending", "urgency": 12.1},
+{"id": 0, "uuid": "c", "description": "Old task", "status": "completed",
+	"due": "00010101T000000Z", "urgency": 0}
+]`
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	dir, err := ioutil.TempDir("", "task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dataDir = func() (string, error) { return dir, nil }
+	pending := filepath.Join(dir, "pending.data")
+	lock := filepath.Join(dir, "pending.data.lock")
+	for _, f := range []string{pending, lock} {
+		ioutil.WriteFile(f, []byte{}, 0644)
+	}
+	// Changing the modification time generates a single event.
+	touch := func(file string) {
+		now := time.Now()
+		os.Chtimes(file, now, now)
+	}
+	commands := make(chan string, 10)
+	runCommand = func(args ...string) error {
+		commands <- strings.Join(args, " ")
+		return nil
+	}
+
+	setExport(tasks, nil)
+	tw := New()
+	tester := testModule.NewOutputTester(t, tw)
+	out := tester.AssertOutput("on start")
+	assert.Equal("2 tasks", out[0].Text())
+	assert.Equal([]string{"status:pending"}, exportFilter)
+
+	scheduler.AdvanceBy(12*time.Hour + time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("2 tasks, 1 overdue", out[0].Text())
+
+	setExport(`[]`, nil)
+	touch(lock)
+	tester.AssertNoOutput("on lock file change")
+	touch(pending)
+	out = tester.AssertOutput("on data change")
+	assert.Equal("", out[0].Text())
+
+	tw.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal("x-terminal-emulator -e sh -c task status:pending list; read -n 1", <-commands)
+
+	setExport(tasks, nil)
+	var info Info
+	tw.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	next, ok := info.Next()
+	assert.True(ok)
+	assert.Equal("Buy milk", next.Description)
+	assert.Equal([]string{"errand"}, next.Tags)
+	assert.Equal(time.Time{}.Add(12*time.Hour), info.Tasks[0].Due)
+	_, ok = Info{}.Next()
+	assert.False(ok)
+
+	setExport(`[{"id": 1, "status": "pending", "due": "tomorrow"}]`, nil)
+	scheduler.AdvanceBy(5 * time.Minute)
+	tester.AssertError("on invalid date")
+
+	setExport(`not json`, nil)
+	tw.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertEmpty("clears error")
+	tester.AssertError("on invalid json")
+
+	setExport("", errors.New("task not found"))
+	tw.Click(bar.Event{Button: bar.ButtonRight})
+	tester.AssertEmpty("clears error")
+	tester.AssertError("on export error")
+
+	dataDir = func() (string, error) { return filepath.Join(dir, "missing"), nil }
+	tester = testModule.NewOutputTester(t, New("+work"))
+	tester.AssertError("on missing data directory")
+}
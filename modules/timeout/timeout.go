@@ -0,0 +1,175 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package timeout provides a module that "wraps" an existing module, and marks
+its output as stale if the module does not refresh in time, e.g. when an
+HTTP request hangs, instead of showing outdated information indefinitely.
+
+The timeout should allow for the module's refresh interval, as well as the
+time a refresh takes:
+
+	w := weather.New(provider) // Refreshes every 10 minutes by default.
+	t := timeout.New(w, 12*time.Minute)
+
+By default stale output is shown in the "degraded" color from the scheme,
+which can be changed using StaleStyle, e.g.
+
+	t.StaleStyle(func(o bar.Output) bar.Output {
+	  return outputs.Textf("%s (stale)", o[0].Text())
+	})
+*/
+package timeout
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+)
+
+// Module represents a timeout module, which forwards clicks and
+// pause/resume events to the wrapped module.
+type Module interface {
+	bar.Module
+	bar.Clickable
+	bar.Pausable
+
+	// StaleStyle sets the function used to style the previous output when
+	// the module has not refreshed in time. The function receives a copy
+	// of the output, which it can modify.
+	StaleStyle(func(bar.Output) bar.Output) Module
+}
+
+type module struct {
+	bar.Module
+	timeout    time.Duration
+	scheduler  scheduler.Scheduler
+	mutex      sync.Mutex
+	channel    chan bar.Output
+	staleFunc  func(bar.Output) bar.Output
+	lastOutput bar.Output
+	lastUpdate time.Time
+	stale      bool
+}
+
+// New wraps an existing module, and shows its output as stale if it does
+// not output anything for the given duration.
+func New(original bar.Module, timeout time.Duration) Module {
+	m := &module{
+		Module:  original,
+		timeout: timeout,
+		staleFunc: func(o bar.Output) bar.Output {
+			return o.Color(colors.Scheme("degraded"))
+		},
+	}
+	m.scheduler = scheduler.Do(m.markStale)
+	return m
+}
+
+func (m *module) StaleStyle(staleFunc func(bar.Output) bar.Output) Module {
+	m.mutex.Lock()
+	m.staleFunc = staleFunc
+	m.mutex.Unlock()
+	m.refreshStale()
+	return m
+}
+
+// Stream sets up the output pipeline, which restarts the timeout on each
+// output from the wrapped module.
+func (m *module) Stream() <-chan bar.Output {
+	m.mutex.Lock()
+	m.channel = make(chan bar.Output, 10)
+	m.lastUpdate = scheduler.Now()
+	m.mutex.Unlock()
+	m.scheduler.After(m.timeout)
+	go m.pipe(m.Module.Stream())
+	return m.channel
+}
+
+func (m *module) pipe(input <-chan bar.Output) {
+	for out := range input {
+		m.mutex.Lock()
+		m.lastOutput = out
+		m.lastUpdate = scheduler.Now()
+		m.stale = false
+		m.scheduler.After(m.timeout)
+		m.channel <- out
+		m.mutex.Unlock()
+	}
+}
+
+// markStale shows the previous output as stale, if the wrapped module has
+// not refreshed since the timeout was started.
+func (m *module) markStale() {
+	m.mutex.Lock()
+	if scheduler.Now().Sub(m.lastUpdate) < m.timeout {
+		// Refreshed or resumed in the meantime.
+		m.mutex.Unlock()
+		return
+	}
+	m.stale = true
+	m.mutex.Unlock()
+	m.refreshStale()
+}
+
+// refreshStale outputs the stale version of the previous output, if the
+// module is stale and the previous output was not empty.
+func (m *module) refreshStale() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.stale || len(m.lastOutput) == 0 || m.channel == nil {
+		return
+	}
+	// Copy the output, since the segments may still be in use by the bar.
+	out := make(bar.Output, 0, len(m.lastOutput))
+	for _, s := range m.lastOutput {
+		segment := bar.Segment{}
+		for k, v := range s {
+			segment[k] = v
+		}
+		out = append(out, segment)
+	}
+	m.channel <- m.staleFunc(out)
+}
+
+// Click passes through the click event if supported by the wrapped module.
+func (m *module) Click(e bar.Event) {
+	if clickable, ok := m.Module.(bar.Clickable); ok {
+		clickable.Click(e)
+	}
+}
+
+// Pause stops the timeout, since modules usually do not refresh while
+// paused, and passes through the pause event if supported.
+func (m *module) Pause() {
+	m.scheduler.Stop()
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// Resume restarts the timeout, and passes through the resume event if
+// supported by the wrapped module.
+func (m *module) Resume() {
+	m.mutex.Lock()
+	m.lastUpdate = scheduler.Now()
+	m.mutex.Unlock()
+	m.scheduler.After(m.timeout)
+	if pausable, ok := m.Module.(bar.Pausable); ok {
+		pausable.Resume()
+	}
+}
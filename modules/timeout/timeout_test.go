@@ -0,0 +1,100 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestTimeout(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	colors.Set("degraded", bar.Color("#ffff00"))
+	original := testModule.New(t)
+	m := New(original, time.Minute)
+	tester := testModule.NewOutputTester(t, m)
+
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("stale without any output")
+
+	original.Output(outputs.Text("fresh"))
+	out := tester.AssertOutput("passes through output")
+	assert.Nil(t, out[0]["color"], "fresh output is not styled")
+
+	scheduler.AdvanceBy(59 * time.Second)
+	tester.AssertNoOutput("before timeout")
+	scheduler.AdvanceBy(time.Second)
+	out = tester.AssertOutput("on timeout")
+	assert.Equal(t, "fresh", out[0].Text(), "previous output is shown")
+	assert.Equal(t, bar.Color("#ffff00"), out[0]["color"], "in the stale style")
+
+	original.Output(outputs.Text("new"))
+	out = tester.AssertOutput("on refresh")
+	assert.Nil(t, out[0]["color"], "fresh output is not styled")
+
+	scheduler.AdvanceBy(30 * time.Second)
+	original.Output(outputs.Text("newer"))
+	tester.AssertOutput("on refresh")
+	scheduler.AdvanceBy(30 * time.Second)
+	tester.AssertNoOutput("timeout restarted on output")
+
+	m.StaleStyle(func(o bar.Output) bar.Output {
+		return outputs.Textf("%s?", o[0].Text())
+	})
+	tester.AssertNoOutput("changing style when not stale")
+	scheduler.AdvanceBy(30 * time.Second)
+	out = tester.AssertOutput("on timeout")
+	assert.Equal(t, "newer?", out[0].Text(), "custom stale style")
+
+	original.Output(outputs.Empty())
+	tester.AssertEmpty("on empty output")
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("empty output is never stale")
+}
+
+func TestPauseResume(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	original := testModule.New(t)
+	m := New(original, time.Minute)
+	tester := testModule.NewOutputTester(t, m)
+	original.Output(outputs.Text("fresh"))
+	tester.AssertOutput("passes through output")
+
+	m.Pause()
+	original.AssertPaused("pause passed through")
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("while paused")
+
+	m.Resume()
+	original.AssertResumed("resume passed through")
+	scheduler.AdvanceBy(59 * time.Second)
+	tester.AssertNoOutput("timeout restarted on resume")
+	scheduler.AdvanceBy(time.Second)
+	tester.AssertOutput("on timeout after resume")
+
+	evt := bar.Event{X: 2}
+	m.Click(evt)
+	assert.Equal(t, evt, original.AssertClicked("click passed through"))
+}
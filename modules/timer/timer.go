@@ -0,0 +1,337 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package timer provides a stopwatch and a countdown timer module.
+
+The stopwatch starts and pauses on click, while the countdown timer
+additionally allows setting the duration by scrolling. When a countdown
+reaches zero, the output is marked urgent until it is reset, and a desktop
+notification can optionally be sent.
+*/
+package timer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/notify"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the current state of a stopwatch or countdown timer.
+type Info struct {
+	// Elapsed is the total time the timer has been running.
+	Elapsed time.Duration
+	// Duration is the total duration of the countdown. Zero for stopwatches.
+	Duration time.Duration
+	// Running is true if the timer is currently running.
+	Running bool
+	// Finished is true if a countdown has reached zero.
+	Finished  bool
+	countdown bool
+}
+
+// Remaining returns the time left before a countdown finishes.
+// Stopwatches always return zero.
+func (i Info) Remaining() time.Duration {
+	if !i.countdown || i.Elapsed >= i.Duration {
+		return 0
+	}
+	return i.Duration - i.Elapsed
+}
+
+// IsCountdown returns true if the info is from a countdown timer.
+func (i Info) IsCountdown() bool {
+	return i.countdown
+}
+
+// Display returns the duration that should be displayed on the bar,
+// which is the remaining time for countdowns, and the elapsed time
+// for stopwatches, truncated to the second.
+func (i Info) Display() time.Duration {
+	if i.countdown {
+		// Round up so that the countdown shows 0 only when finished.
+		return (i.Remaining() + time.Second - 1) / time.Second * time.Second
+	}
+	return i.Elapsed / time.Second * time.Second
+}
+
+// Controller provides an interface to control the timer,
+// used in the click handler.
+type Controller interface {
+	// Start starts (or resumes) the timer.
+	Start()
+
+	// Stop stops the timer, but retains the elapsed time.
+	// No effect if not running.
+	Stop()
+
+	// Toggle toggles between running and stopped.
+	Toggle()
+
+	// Reset stops the timer and resets the elapsed time to zero.
+	Reset()
+
+	// Adjust changes the countdown duration by the given offset.
+	// Use negative durations to shorten the countdown.
+	// No effect on stopwatches.
+	Adjust(time.Duration)
+}
+
+// Module is the public interface for a timer module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to control the timer.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+
+	// ScrollStep sets the amount by which the countdown duration is changed
+	// on each scroll event by the default click handler.
+	ScrollStep(time.Duration) Module
+
+	// Notify controls whether a desktop notification is sent when a
+	// countdown finishes.
+	Notify(bool) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc func(Info) bar.Output
+	scrollStep time.Duration
+	notify     bool
+	notifier   *notify.Notifier
+	// state is guarded by its own mutex since the controller
+	// methods can be called from click handlers at any time.
+	stateMu   sync.Mutex
+	countdown bool
+	duration  time.Duration
+	elapsed   time.Duration
+	startedAt time.Time
+	running   bool
+	finished  bool
+}
+
+// Stopwatch constructs a stopwatch that counts up from zero.
+func Stopwatch() Module {
+	return newModule(false, 0)
+}
+
+// Countdown constructs a countdown timer with the given initial duration.
+func Countdown(duration time.Duration) Module {
+	return newModule(true, duration)
+}
+
+func newModule(countdown bool, duration time.Duration) *module {
+	m := &module{
+		Base:       base.New(),
+		countdown:  countdown,
+		duration:   duration,
+		scrollStep: time.Minute,
+		notifier:   notify.New("timer"),
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template that's just the time, e.g. "4m35s".
+	m.OutputTemplate(outputs.TextTemplate(`{{.Display}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		f(m.info(), m, e)
+	})
+	return m
+}
+
+func (m *module) ScrollStep(step time.Duration) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.scrollStep = step
+	return m
+}
+
+func (m *module) Notify(notify bool) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.notify = notify
+	return m
+}
+
+// DefaultClickHandler provides useful behaviour out of the box,
+// Click to start/stop, right click to reset, and scroll to change the
+// countdown duration.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	step := time.Minute
+	if m, ok := c.(*module); ok {
+		m.Lock()
+		step = m.scrollStep
+		m.Unlock()
+	}
+	switch e.Button {
+	case bar.ButtonLeft:
+		c.Toggle()
+	case bar.ButtonRight:
+		c.Reset()
+	case bar.ScrollUp, bar.ScrollRight:
+		c.Adjust(step)
+	case bar.ScrollDown, bar.ScrollLeft:
+		c.Adjust(-step)
+	}
+}
+
+func (m *module) Start() {
+	m.stateMu.Lock()
+	if !m.running && !m.finished {
+		m.running = true
+		m.startedAt = scheduler.Now()
+	}
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+func (m *module) Stop() {
+	m.stateMu.Lock()
+	if m.running {
+		m.elapsed += scheduler.Now().Sub(m.startedAt)
+		m.running = false
+	}
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+func (m *module) Toggle() {
+	m.stateMu.Lock()
+	running := m.running
+	m.stateMu.Unlock()
+	if running {
+		m.Stop()
+	} else {
+		m.Start()
+	}
+}
+
+func (m *module) Reset() {
+	m.stateMu.Lock()
+	m.running = false
+	m.finished = false
+	m.elapsed = 0
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+func (m *module) Adjust(offset time.Duration) {
+	m.stateMu.Lock()
+	if m.countdown && !m.finished {
+		m.duration += offset
+		if m.duration < m.elapsed {
+			m.duration = m.elapsed
+		}
+	}
+	m.stateMu.Unlock()
+	m.Update()
+}
+
+// info returns a snapshot of the current timer state.
+func (m *module) info() Info {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	elapsed := m.elapsed
+	if m.running {
+		elapsed += scheduler.Now().Sub(m.startedAt)
+	}
+	return Info{
+		Elapsed:   elapsed,
+		Duration:  m.duration,
+		Running:   m.running,
+		Finished:  m.finished,
+		countdown: m.countdown,
+	}
+}
+
+// checkFinished marks a running countdown as finished if it has reached zero,
+// and returns true if it did so.
+func (m *module) checkFinished() bool {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if !m.countdown || !m.running {
+		return false
+	}
+	if m.elapsed+scheduler.Now().Sub(m.startedAt) < m.duration {
+		return false
+	}
+	m.running = false
+	m.finished = true
+	m.elapsed = m.duration
+	return true
+}
+
+func (m *module) update() {
+	justFinished := m.checkFinished()
+	info := m.info()
+	m.Lock()
+	out := m.outputFunc(info)
+	shouldNotify := m.notify
+	m.Unlock()
+	if info.Finished {
+		out.Urgent(true)
+	}
+	if !info.Running {
+		m.Schedule().Stop()
+	} else {
+		// Schedule the next update when the displayed value changes.
+		next := time.Second - info.Elapsed%time.Second
+		if info.countdown && info.Remaining() < next {
+			next = info.Remaining()
+		}
+		m.Schedule().After(next)
+	}
+	m.Output(out)
+	if justFinished && shouldNotify {
+		go m.notifier.Notify(notify.Notification{
+			Summary: "Timer finished",
+			Body:    info.Duration.String(),
+			Urgency: notify.Critical,
+		})
+	}
+}
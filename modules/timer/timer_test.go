@@ -0,0 +1,137 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestStopwatch(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2017, time.March, 1, 0, 0, 0, 0, time.Local))
+
+	sw := Stopwatch()
+	tester := testModule.NewOutputTester(t, sw)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("0s", out[0].Text())
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("while not running")
+
+	sw.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click to start")
+	assert.Equal("0s", out[0].Text())
+
+	scheduler.NextTick()
+	out = tester.AssertOutput("on tick")
+	assert.Equal("1s", out[0].Text())
+
+	scheduler.AdvanceBy(500 * time.Millisecond)
+	tester.AssertNoOutput("less than a second")
+
+	scheduler.NextTick()
+	out = tester.AssertOutput("on tick")
+	assert.Equal("2s", out[0].Text())
+
+	scheduler.AdvanceBy(300 * time.Millisecond)
+	sw.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click to pause")
+	assert.Equal("2s", out[0].Text())
+
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("while paused")
+
+	sw.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("2s", out[0].Text(), "scroll does not affect stopwatch")
+
+	sw.Click(bar.Event{Button: bar.ButtonRight})
+	out = tester.AssertOutput("on reset")
+	assert.Equal("0s", out[0].Text())
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("after reset")
+}
+
+func TestCountdown(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2017, time.March, 1, 0, 0, 0, 0, time.Local))
+
+	var lastInfo Info
+	cd := Countdown(5 * time.Second).ScrollStep(time.Second).
+		OutputFunc(func(i Info) bar.Output {
+			lastInfo = i
+			return bar.Output{bar.NewSegment(i.Display().String())}
+		})
+	tester := testModule.NewOutputTester(t, cd)
+
+	out := tester.AssertOutput("on start")
+	assert.Equal("5s", out[0].Text())
+	assert.True(lastInfo.IsCountdown())
+
+	cd.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll up")
+	assert.Equal("6s", out[0].Text())
+
+	cd.Click(bar.Event{Button: bar.ScrollDown})
+	cd.Click(bar.Event{Button: bar.ScrollDown})
+	tester.AssertOutput("on scroll down")
+	out = tester.AssertOutput("on scroll down")
+	assert.Equal("4s", out[0].Text())
+
+	cd.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertOutput("on start")
+	assert.True(lastInfo.Running)
+
+	for i := 3; i > 0; i-- {
+		scheduler.NextTick()
+		out = tester.AssertOutput("on tick")
+		assert.Equal((time.Duration(i) * time.Second).String(), out[0].Text())
+		assert.NotContains(out[0], "urgent")
+	}
+
+	scheduler.NextTick()
+	out = tester.AssertOutput("on finish")
+	assert.Equal("0s", out[0].Text())
+	assert.Equal(true, out[0]["urgent"], "urgent when finished")
+	assert.True(lastInfo.Finished)
+	assert.False(lastInfo.Running)
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("after finishing")
+
+	cd.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on click after finishing")
+	assert.Equal(true, out[0]["urgent"], "cannot restart until reset")
+
+	cd.Click(bar.Event{Button: bar.ButtonRight})
+	out = tester.AssertOutput("on reset")
+	assert.Equal("4s", out[0].Text(), "duration is retained on reset")
+	assert.NotContains(out[0], "urgent")
+	assert.False(lastInfo.Finished)
+
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("after reset")
+}
@@ -0,0 +1,253 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package title provides an i3bar module that shows the focused window's title.
+
+The module subscribes to window and workspace events over the i3 or sway IPC
+socket, and reads the focused window from the layout tree. Icons can be shown
+for applications, looked up by the wayland app_id or the X11 window class.
+Titles longer than a maximum length can scroll like a marquee.
+*/
+package title
+
+import (
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/i3ipc"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the focused window.
+type Info struct {
+	// Title is the full title of the window.
+	Title string
+	// AppID is the wayland app_id, only set on sway for native windows.
+	AppID string
+	// Class and Instance are the X11 WM_CLASS of the window.
+	Class    string
+	Instance string
+	// Icon is the configured icon for the application, if any.
+	Icon string
+	// Text is the part of the title currently shown, which is the full title
+	// unless it is longer than the maximum length.
+	Text string
+}
+
+// App returns the app_id of the window, or its class if it has no app_id.
+func (i Info) App() string {
+	if i.AppID != "" {
+		return i.AppID
+	}
+	return i.Class
+}
+
+// Module is the public interface for a window title module.
+type Module interface {
+	base.WithClickHandler
+
+	// Icons sets the icons for applications, keyed by app_id or class.
+	// Keys are matched case-insensitively.
+	Icons(map[string]string) Module
+
+	// MaxLength sets the maximum number of characters shown. Longer titles
+	// scroll through the title at the given interval. A length of 0
+	// (the default) always shows the full title.
+	MaxLength(length int, interval time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	ipc        i3ipc.Client
+	icons      map[string]string
+	maxLength  int
+	interval   time.Duration
+	outputFunc func(Info) bar.Output
+	// stale is set by events to query the focused window on the next update.
+	stale     bool
+	window    i3ipc.Node
+	offset    int
+	marquee   scheduler.Scheduler
+	scrolling bool
+}
+
+// I3 constructs an instance of the title module for i3.
+func I3() Module {
+	return newModule(i3ipc.I3())
+}
+
+// Sway constructs an instance of the title module for sway.
+func Sway() Module {
+	return newModule(i3ipc.Sway())
+}
+
+func newModule(c i3ipc.Client) *module {
+	m := &module{
+		Base:  base.New(),
+		ipc:   c,
+		stale: true,
+	}
+	m.marquee = scheduler.Do(m.scroll)
+	// Default output template is the icon, if any, and the title.
+	m.OutputTemplate(outputs.TextTemplate(`{{with .Icon}}{{.}} {{end}}{{.Text | isolate}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) Icons(icons map[string]string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.icons = map[string]string{}
+	for app, icon := range icons {
+		m.icons[strings.ToLower(app)] = icon
+	}
+	return m
+}
+
+func (m *module) MaxLength(length int, interval time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.maxLength = length
+	m.interval = interval
+	m.offset = 0
+	// Restart scrolling in case the interval changed.
+	m.scrolling = false
+	m.marquee.Stop()
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+// focusedWindow returns the focused window, or an empty node if a workspace
+// (or some other container) is focused instead.
+func (m *module) focusedWindow() (i3ipc.Node, error) {
+	tree, err := m.ipc.Tree()
+	if err != nil {
+		return i3ipc.Node{}, err
+	}
+	focused, ok := tree.Find(func(n i3ipc.Node) bool { return n.Focused })
+	if !ok || (focused.Type != "con" && focused.Type != "floating_con") {
+		return i3ipc.Node{}, nil
+	}
+	return focused, nil
+}
+
+// Stream subscribes to window events, and then returns the output
+// channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go func() {
+		m.Error(m.ipc.Subscribe([]string{"window", "workspace"}, m.handleEvent))
+	}()
+	return ch
+}
+
+func (m *module) handleEvent(msgType uint32, payload []byte) {
+	if msgType != i3ipc.WindowEvent && msgType != i3ipc.WorkspaceEvent {
+		return
+	}
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.stale = true
+}
+
+func (m *module) scroll() {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.offset++
+}
+
+// marqueeSeparator is shown between the end of the title and the start,
+// when scrolling.
+const marqueeSeparator = "   "
+
+// visibleText returns the visible portion of the title. It must be called
+// with the lock held.
+func (m *module) visibleText(title string) string {
+	runes := []rune(title)
+	if m.maxLength <= 0 || len(runes) <= m.maxLength {
+		return title
+	}
+	runes = append(runes, []rune(marqueeSeparator)...)
+	start := m.offset % len(runes)
+	visible := make([]rune, m.maxLength)
+	for i := range visible {
+		visible[i] = runes[(start+i)%len(runes)]
+	}
+	return string(visible)
+}
+
+func (m *module) update() {
+	m.Lock()
+	stale := m.stale
+	m.stale = false
+	m.Unlock()
+	if stale {
+		window, err := m.focusedWindow()
+		if m.Error(err) {
+			return
+		}
+		m.Lock()
+		if window.Name != m.window.Name {
+			m.offset = 0
+		}
+		m.window = window
+		m.Unlock()
+	}
+	m.Lock()
+	w := m.window
+	info := Info{
+		Title:    w.Name,
+		AppID:    w.AppID,
+		Class:    w.WindowProperties.Class,
+		Instance: w.WindowProperties.Instance,
+		Text:     m.visibleText(w.Name),
+	}
+	info.Icon = m.icons[strings.ToLower(info.App())]
+	shouldScroll := info.Text != info.Title
+	startScroll := shouldScroll && !m.scrolling
+	stopScroll := !shouldScroll && m.scrolling
+	m.scrolling = shouldScroll
+	interval := m.interval
+	out := m.outputFunc(info)
+	m.Unlock()
+	if startScroll {
+		m.marquee.Every(interval)
+	}
+	if stopScroll {
+		m.marquee.Stop()
+	}
+	m.Output(out)
+}
@@ -0,0 +1,186 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package title
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/i3ipc"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeI3 struct {
+	sync.Mutex
+	tree   string
+	events chan string
+}
+
+func (f *fakeI3) setTree(tree string) {
+	f.Lock()
+	defer f.Unlock()
+	f.tree = tree
+}
+
+func (f *fakeI3) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msgType, _, err := i3ipc.ReadMessage(conn)
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case i3ipc.Subscribe:
+			i3ipc.WriteMessage(conn, i3ipc.Subscribe, `{"success": true}`)
+			for e := range f.events {
+				i3ipc.WriteMessage(conn, i3ipc.WindowEvent, e)
+			}
+			return
+		case i3ipc.GetTree:
+			f.Lock()
+			tree := f.tree
+			f.Unlock()
+			i3ipc.WriteMessage(conn, i3ipc.GetTree, tree)
+		}
+	}
+}
+
+func (f *fakeI3) listen(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "i3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "ipc.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return sock, func() {
+		close(f.events)
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+const i3Tree = `{"type": "root", "nodes": [
+	{"type": "output", "name": "DP-1", "nodes": [
+		{"type": "workspace", "name": "1", "nodes": [
+			{"type": "con", "name": "vim", "window_properties": {"class": "URxvt", "instance": "urxvt"}},
+			{"type": "con", "name": "Mozilla Firefox", "focused": true,
+				"window_properties": {"class": "Firefox", "instance": "Navigator"}}
+		]}
+	]}
+]}`
+
+func TestI3(t *testing.T) {
+	assert := assert.New(t)
+	i3 := &fakeI3{tree: i3Tree, events: make(chan string)}
+	sock, cleanup := i3.listen(t)
+	defer cleanup()
+	os.Setenv("I3SOCK", sock)
+
+	w := I3()
+	tester := testModule.NewOutputTester(t, w)
+	out := tester.AssertOutput("on start")
+	assert.Equal("Mozilla Firefox", out[0].Text())
+
+	w.Icons(map[string]string{"firefox": "F", "urxvt": "T"})
+	out = tester.AssertOutput("on icons change")
+	assert.Equal("F Mozilla Firefox", out[0].Text())
+
+	i3.setTree(`{"type": "root", "nodes": [
+		{"type": "workspace", "name": "2", "focused": true, "nodes": []}]}`)
+	i3.events <- `{"change": "close"}`
+	out = tester.AssertOutput("on empty workspace")
+	assert.Equal("", out[0].Text())
+
+	i3.setTree(`{"type": "root", "nodes": [
+		{"type": "workspace", "name": "1", "floating_nodes": [
+			{"type": "floating_con", "nodes": [
+				{"type": "con", "name": "mutt", "focused": true,
+					"window_properties": {"class": "URxvt", "instance": "mutt"}}
+			]}
+		]}]}`)
+	i3.events <- `{"change": "focus"}`
+	out = tester.AssertOutput("on focusing floating window")
+	assert.Equal("T mutt", out[0].Text())
+
+	var info Info
+	w.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(Info{Title: "mutt", Class: "URxvt", Instance: "mutt", Icon: "T", Text: "mutt"}, info)
+	assert.Equal("URxvt", info.App())
+
+	i3.setTree(`{"bad json`)
+	i3.events <- `{"change": "title"}`
+	tester.AssertError("on invalid tree")
+}
+
+func TestSwayMarquee(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	sway := &fakeI3{
+		tree: `{"type": "root", "nodes": [
+			{"type": "con", "name": "abcdef", "app_id": "foot", "focused": true}]}`,
+		events: make(chan string),
+	}
+	sock, cleanup := sway.listen(t)
+	defer cleanup()
+	os.Setenv("SWAYSOCK", sock)
+
+	w := Sway().Icons(map[string]string{"Foot": ">"}).MaxLength(4, time.Second)
+	tester := testModule.NewOutputTester(t, w)
+	out := tester.AssertOutput("on start")
+	assert.Equal("> abcd", out[0].Text())
+
+	for _, expected := range []string{"bcde", "cdef", "def ", "ef  ", "f   ", "   a", "  ab", " abc", "abcd"} {
+		scheduler.NextTick()
+		out = tester.AssertOutput("on marquee tick")
+		assert.Equal("> "+expected, out[0].Text())
+	}
+
+	sway.setTree(`{"type": "root", "nodes": [
+		{"type": "con", "name": "abc", "app_id": "foot", "focused": true}]}`)
+	sway.events <- `{"change": "title"}`
+	out = tester.AssertOutput("on title change")
+	assert.Equal("> abc", out[0].Text())
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertNoOutput("marquee stops for short titles")
+
+	os.Setenv("SWAYSOCK", "")
+	tester = testModule.NewOutputTester(t, Sway())
+	tester.AssertError("without sway socket")
+}
@@ -0,0 +1,316 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package todotxt provides an i3bar module that shows tasks from a todo.txt file.
+
+The file is parsed using the todo.txt format (https://github.com/todotxt/todo.txt),
+and watched using inotify so that the module updates whenever it changes. By
+default the module shows the number of incomplete tasks and the top task,
+which is the first task with the highest priority. Clicking the module marks
+the top task as done.
+*/
+package todotxt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/watchers/file"
+)
+
+// Task represents a single line in the todo.txt file.
+type Task struct {
+	// Text is the task description, without the completion marker,
+	// priority, or dates.
+	Text string
+	// Priority is the priority letter ("A" to "Z"), or "" if none.
+	Priority string
+	Done     bool
+	Projects []string
+	Contexts []string
+	// line is the original line and index in the file, used to
+	// find the task when marking it done.
+	line  string
+	index int
+}
+
+// Info represents the tasks in the todo.txt file.
+type Info struct {
+	Tasks []Task
+}
+
+// Count returns the number of incomplete tasks.
+func (i Info) Count() int {
+	count := 0
+	for _, t := range i.Tasks {
+		if !t.Done {
+			count++
+		}
+	}
+	return count
+}
+
+// Priority returns the number of incomplete tasks with the given priority.
+func (i Info) Priority(priority string) int {
+	count := 0
+	for _, t := range i.Tasks {
+		if !t.Done && t.Priority == priority {
+			count++
+		}
+	}
+	return count
+}
+
+// Top returns the first incomplete task with the highest priority, or an
+// empty task if all tasks are complete.
+func (i Info) Top() Task {
+	var top Task
+	found := false
+	for _, t := range i.Tasks {
+		if t.Done {
+			continue
+		}
+		if !found || higherPriority(t.Priority, top.Priority) {
+			top = t
+			found = true
+		}
+	}
+	return top
+}
+
+// higherPriority returns true if priority a is higher than b.
+// Tasks without a priority have the lowest priority.
+func higherPriority(a, b string) bool {
+	if a == "" {
+		return false
+	}
+	return b == "" || a < b
+}
+
+// Controller provides an interface to complete tasks from the click handler.
+type Controller interface {
+	// Done marks the top task as done.
+	Done()
+}
+
+// Module is the public interface for a todo.txt module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to complete tasks.
+type Module interface {
+	base.Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	file       string
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the todo.txt module for the given file.
+func New(file string) Module {
+	m := &module{Base: base.New(), file: file}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the number of tasks and the top task.
+	m.OutputTemplate(outputs.TextTemplate(`{{with .Count}}{{.}}: {{$.Top.Text}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler marks the top task as done on left click.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft && i.Count() > 0 {
+		c.Done()
+	}
+}
+
+// parse parses a single line of a todo.txt file.
+func parse(line string) Task {
+	t := Task{line: line}
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "x" {
+		t.Done = true
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && len(fields[0]) == 3 &&
+		fields[0][0] == '(' && fields[0][2] == ')' &&
+		fields[0][1] >= 'A' && fields[0][1] <= 'Z' {
+		t.Priority = fields[0][1:2]
+		fields = fields[1:]
+	}
+	// Completion and creation dates.
+	for i := 0; i < 2 && len(fields) > 0 && isDate(fields[0]); i++ {
+		fields = fields[1:]
+	}
+	for _, f := range fields {
+		switch {
+		case len(f) > 1 && f[0] == '+':
+			t.Projects = append(t.Projects, f[1:])
+		case len(f) > 1 && f[0] == '@':
+			t.Contexts = append(t.Contexts, f[1:])
+		}
+	}
+	t.Text = strings.Join(fields, " ")
+	return t
+}
+
+// dateFormat is the format of dates in todo.txt.
+const dateFormat = "2006-01-02"
+
+func isDate(s string) bool {
+	if len(s) != len(dateFormat) {
+		return false
+	}
+	for i, c := range s {
+		if dateFormat[i] == '-' {
+			if c != '-' {
+				return false
+			}
+		} else if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// read reads and parses the todo.txt file, and also returns the lines
+// of the file so that tasks can be modified.
+func (m *module) read() (Info, []string, error) {
+	bytes, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	info := Info{}
+	lines := strings.Split(string(bytes), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		t := parse(line)
+		t.index = i
+		info.Tasks = append(info.Tasks, t)
+	}
+	return info, lines, nil
+}
+
+// complete returns the line for a completed task. The priority is
+// preserved as a pri: tag, following the todo.txt convention.
+func complete(t Task) string {
+	line := strings.TrimSpace(t.line)
+	if t.Priority != "" {
+		line = strings.TrimSpace(line[3:]) + " pri:" + t.Priority
+	}
+	return fmt.Sprintf("x %s %s", scheduler.Now().Format(dateFormat), line)
+}
+
+func (m *module) Done() {
+	// Re-read the file in case it was modified since the last update.
+	info, lines, err := m.read()
+	if m.Error(err) {
+		return
+	}
+	if info.Count() == 0 {
+		return
+	}
+	top := info.Top()
+	lines[top.index] = complete(top)
+	stat, err := os.Stat(m.file)
+	if m.Error(err) {
+		return
+	}
+	// The watcher will update the module.
+	m.Error(ioutil.WriteFile(m.file, []byte(strings.Join(lines, "\n")), stat.Mode()))
+}
+
+// Stream starts watching the todo.txt file for changes, and then
+// returns the output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	w, err := file.Watch(m.file)
+	if m.Error(err) {
+		return ch
+	}
+	go m.listen(w)
+	return ch
+}
+
+// listen triggers an update whenever the todo.txt file changes.
+func (m *module) listen(w *file.Watcher) {
+	defer w.Close()
+	for {
+		select {
+		case <-w.Updates:
+			m.Update()
+		case err := <-w.Errors:
+			m.Error(err)
+			return
+		}
+	}
+}
+
+func (m *module) update() {
+	info, _, err := m.read()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
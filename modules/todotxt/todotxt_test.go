@@ -0,0 +1,115 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package todotxt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	task := parse("(A) 2018-01-01 Call mom +family @phone due:2018-01-10")
+	assert.Equal("A", task.Priority)
+	assert.False(task.Done)
+	assert.Equal("Call mom +family @phone due:2018-01-10", task.Text)
+	assert.Equal([]string{"family"}, task.Projects)
+	assert.Equal([]string{"phone"}, task.Contexts)
+
+	task = parse("x 2018-01-05 2018-01-01 Pay rent +home")
+	assert.True(task.Done)
+	assert.Equal("", task.Priority)
+	assert.Equal("Pay rent +home", task.Text)
+
+	task = parse("(a) lowercase is not a priority")
+	assert.Equal("", task.Priority)
+	assert.Equal("(a) lowercase is not a priority", task.Text)
+
+	task = parse("xylophone lessons @ 2018-01-01")
+	assert.False(task.Done)
+	assert.Equal("xylophone lessons @ 2018-01-01", task.Text)
+	assert.Empty(task.Contexts)
+}
+
+func TestInfo(t *testing.T) {
+	assert := assert.New(t)
+	i := Info{Tasks: []Task{
+		parse("Buy milk"),
+		parse("(B) Fix bike"),
+		parse("x (A) Old task"),
+		parse("(A) File taxes"),
+		parse("(B) Water plants"),
+	}}
+	assert.Equal(4, i.Count())
+	assert.Equal(1, i.Priority("A"), "completed tasks are not counted")
+	assert.Equal(2, i.Priority("B"))
+	assert.Equal(1, i.Priority(""))
+	assert.Equal("File taxes", i.Top().Text)
+	assert.Equal("", Info{}.Top().Text)
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.Local))
+	dir, err := ioutil.TempDir("", "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "todo.txt")
+	ioutil.WriteFile(file, []byte("Buy milk\n(B) Fix bike\n(A) 2018-01-01 File taxes\n"), 0600)
+
+	todo := New(file)
+	tester := testModule.NewOutputTester(t, todo)
+	out := tester.AssertOutput("on start")
+	assert.Equal("3: File taxes", out[0].Text())
+
+	todo.Click(bar.Event{Button: bar.ButtonLeft})
+	out = tester.AssertOutput("on marking done")
+	assert.Equal("2: Fix bike", out[0].Text())
+	contents, _ := ioutil.ReadFile(file)
+	assert.Equal("Buy milk\n(B) Fix bike\nx 2018-01-05 2018-01-01 File taxes pri:A\n", string(contents))
+	stat, _ := os.Stat(file)
+	assert.Equal(os.FileMode(0600), stat.Mode(), "file mode is preserved")
+
+	ioutil.WriteFile(filepath.Join(dir, "done.txt"), []byte("x Other\n"), 0644)
+	tester.AssertNoOutput("on other file change")
+
+	// Editors often write a new file and rename it.
+	ioutil.WriteFile(file+".new", []byte("x done\n"), 0644)
+	tester.AssertNoOutput("on temporary file")
+	os.Rename(file+".new", file)
+	out = tester.AssertOutput("on file replaced")
+	assert.Equal("", out[0].Text(), "nothing shown when all tasks are done")
+
+	todo.Click(bar.Event{Button: bar.ButtonLeft})
+	tester.AssertNoOutput("no tasks to mark done")
+
+	os.Remove(file)
+	tester.AssertError("on file removed")
+
+	tester = testModule.NewOutputTester(t, New(filepath.Join(dir, "missing", "todo.txt")))
+	tester.AssertError("on missing directory")
+}
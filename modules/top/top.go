@@ -0,0 +1,357 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package top provides an i3bar module that shows the top processes by CPU or
+memory usage, like a lightweight inline top.
+
+Processes are sampled from /proc, and the module keeps the top N processes,
+showing one at a time. By default, scrolling cycles through them. CPU usage is
+computed between consecutive samples, so nothing is shown until the second
+sample when sorting by CPU.
+*/
+package top
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/modules/meminfo"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Process represents the resource usage of a single process.
+type Process struct {
+	PID  int
+	Name string
+	// CPU is the fraction of a single CPU used since the previous sample,
+	// as shown by top. It can exceed 1 for multi-threaded processes.
+	CPU float64
+	// Memory is the resident set size of the process.
+	Memory meminfo.Bytes
+}
+
+// CPUPct returns the CPU usage as a rounded percentage.
+func (p Process) CPUPct() int {
+	return int(p.CPU*100 + 0.5)
+}
+
+// Info represents the top processes, and the one currently displayed.
+type Info struct {
+	// Processes are the top processes, in descending order of usage.
+	Processes []Process
+	Current   int
+}
+
+// Process returns the currently displayed process.
+func (i Info) Process() Process {
+	if i.Current < 0 || i.Current >= len(i.Processes) {
+		return Process{}
+	}
+	return i.Processes[i.Current]
+}
+
+// SortOrder determines how the top processes are chosen.
+type SortOrder int
+
+const (
+	// ByCPU sorts processes by CPU usage.
+	ByCPU SortOrder = iota
+	// ByMemory sorts processes by resident memory.
+	ByMemory
+)
+
+// Controller provides an interface to cycle through processes from the click handler.
+type Controller interface {
+	// Next shows the next process, cycling back to the top.
+	Next()
+	// Previous shows the previous process, cycling to the bottom.
+	Previous()
+}
+
+// Module is the public interface for a top module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to cycle through processes.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// SortBy sets the usage used to choose the top processes.
+	SortBy(SortOrder) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	count      int
+	sortOrder  SortOrder
+	outputFunc func(Info) bar.Output
+	info       Info
+	// CPU times from the previous sample, used to compute CPU usage.
+	lastTotal uint64
+	lastTimes map[int]uint64
+	usage     map[int]float64
+}
+
+// New constructs an instance of the top module that keeps
+// the given number of top processes.
+func New(count int) Module {
+	m := &module{Base: base.New(), count: count}
+	// Default is to refresh every 3s, matching the behaviour of top.
+	m.RefreshInterval(3 * time.Second)
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the name and usage of the current process.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{if .Processes}}{{.Process.Name}} {{.Process.CPUPct}}% {{.Process.Memory.IEC}}{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) SortBy(sortOrder SortOrder) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.sortOrder = sortOrder
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler cycles through the processes on scroll.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ScrollDown, bar.ScrollRight, bar.ButtonForward:
+		c.Next()
+	case bar.ScrollUp, bar.ScrollLeft, bar.ButtonBack:
+		c.Previous()
+	}
+}
+
+func (m *module) Next() {
+	m.moveBy(1)
+}
+
+func (m *module) Previous() {
+	m.moveBy(-1)
+}
+
+func (m *module) moveBy(delta int) {
+	m.Lock()
+	count := len(m.info.Processes)
+	if count > 0 {
+		m.info.Current = ((m.info.Current+delta)%count + count) % count
+	}
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
+
+var fs = hostfs.Fs()
+
+var pageSize = uint64(os.Getpagesize())
+
+// sample is the raw resource usage of a process.
+type sample struct {
+	Process
+	// time is the total user and system time in clock ticks.
+	time uint64
+}
+
+// readCPU returns the total time spent by all CPUs in clock ticks,
+// and the number of CPUs.
+func readCPU() (total uint64, cpus int, err error) {
+	f, err := fs.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			cpus++
+			continue
+		}
+		for _, field := range fields[1:] {
+			val, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			total += val
+		}
+	}
+	return total, cpus, s.Err()
+}
+
+// readProcess reads the usage of a single process from /proc/[pid]/stat.
+func readProcess(pid int) (sample, error) {
+	bytes, err := afero.ReadFile(fs, fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return sample{}, err
+	}
+	stat := string(bytes)
+	// The name can contain spaces and parentheses, but is always
+	// followed by the last ')' in the file.
+	start := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return sample{}, fmt.Errorf("unexpected stat for %d: %q", pid, stat)
+	}
+	// Fields after the name start from (3) state, see proc(5).
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return sample{}, fmt.Errorf("unexpected stat for %d: %q", pid, stat)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	rss, _ := strconv.ParseUint(fields[21], 10, 64)
+	return sample{
+		Process: Process{
+			PID:    pid,
+			Name:   stat[start+1 : end],
+			Memory: meminfo.Bytes(rss * pageSize),
+		},
+		time: utime + stime,
+	}, nil
+}
+
+func readProcesses() ([]sample, error) {
+	entries, err := afero.ReadDir(fs, "/proc")
+	if err != nil {
+		return nil, err
+	}
+	var samples []sample
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// Processes can exit while being read, so errors are ignored.
+		if s, err := readProcess(pid); err == nil {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
+
+func (m *module) update() {
+	total, cpus, err := readCPU()
+	if m.Error(err) {
+		return
+	}
+	samples, err := readProcesses()
+	if m.Error(err) {
+		return
+	}
+	m.Lock()
+	first := m.lastTimes == nil
+	// Usage cannot be computed over an empty interval, which happens when
+	// the module is updated between samples, e.g. by changing the sort
+	// order. Keep the previous usage in that case.
+	if elapsed := total - m.lastTotal; elapsed > 0 || first {
+		times := make(map[int]uint64, len(samples))
+		usage := make(map[int]float64, len(samples))
+		for _, s := range samples {
+			times[s.PID] = s.time
+			if last, ok := m.lastTimes[s.PID]; ok && elapsed > 0 && s.time >= last {
+				usage[s.PID] = float64(s.time-last) / float64(elapsed) * float64(cpus)
+			}
+		}
+		m.lastTotal = total
+		m.lastTimes = times
+		m.usage = usage
+	}
+	processes := make([]Process, 0, len(samples))
+	for _, s := range samples {
+		p := s.Process
+		p.CPU = m.usage[s.PID]
+		processes = append(processes, p)
+	}
+	switch m.sortOrder {
+	case ByCPU:
+		if first {
+			processes = nil
+		}
+		sort.SliceStable(processes, func(i, j int) bool {
+			return processes[i].CPU > processes[j].CPU
+		})
+	case ByMemory:
+		sort.SliceStable(processes, func(i, j int) bool {
+			return processes[i].Memory > processes[j].Memory
+		})
+	}
+	if len(processes) > m.count {
+		processes = processes[:m.count]
+	}
+	m.info.Processes = processes
+	if m.info.Current >= len(processes) {
+		m.info.Current = 0
+	}
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
+}
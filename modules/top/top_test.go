@@ -0,0 +1,120 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func writeStat(total uint64) {
+	// Two CPUs, with the total split across user and idle time.
+	afero.WriteFile(fs, "/proc/stat", []byte(fmt.Sprintf(
+		"cpu  %d 0 0 %d 0 0 0 0 0 0\ncpu0 0 0 0 0\ncpu1 0 0 0 0\nintr 0\n",
+		total/2, total-total/2)), 0644)
+}
+
+func writeProcess(pid int, name string, time uint64, rssPages uint64) {
+	afero.WriteFile(fs, fmt.Sprintf("/proc/%d/stat", pid), []byte(fmt.Sprintf(
+		"%d (%s) S 1 1 1 0 -1 0 0 0 0 0 %d 0 0 0 20 0 1 0 100 0 %d 0\n",
+		pid, name, time, rssPages)), 0644)
+}
+
+func TestReadProcess(t *testing.T) {
+	assert := assert.New(t)
+	fs = afero.NewMemMapFs()
+	writeProcess(42, "Web Content (x)", 1500, 10)
+	s, err := readProcess(42)
+	assert.NoError(err)
+	assert.Equal("Web Content (x)", s.Name)
+	assert.Equal(uint64(1500), s.time)
+	assert.Equal(uint64(10*pageSize), uint64(s.Memory))
+
+	_, err = readProcess(43)
+	assert.Error(err, "missing process")
+}
+
+func TestTop(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+	writeStat(1000)
+	writeProcess(1, "init", 100, 100)
+	writeProcess(20, "firefox", 200, 5000)
+	writeProcess(300, "make", 0, 200)
+
+	tp := New(2)
+	tp.OutputTemplate(func(i interface{}) bar.Output {
+		info := i.(Info)
+		p := info.Process()
+		return bar.Output{bar.NewSegment(fmt.Sprintf(
+			"%d/%d %s %d%%", info.Current, len(info.Processes), p.Name, p.CPUPct()))}
+	})
+	tester := testModule.NewOutputTester(t, tp)
+	out := tester.AssertOutput("on start")
+	assert.Equal("0/0  0%", out[0].Text(), "no cpu usage on first sample")
+
+	writeStat(2000)
+	writeProcess(20, "firefox", 300, 5000)
+	writeProcess(300, "make", 250, 200)
+	scheduler.NextTick()
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("0/2 make 50%", out[0].Text())
+
+	tp.Click(bar.Event{Button: bar.ScrollDown})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("1/2 firefox 20%", out[0].Text())
+
+	tp.Click(bar.Event{Button: bar.ScrollDown})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("0/2 make 50%", out[0].Text(), "wraps around")
+
+	tp.Click(bar.Event{Button: bar.ScrollUp})
+	out = tester.AssertOutput("on scroll")
+	assert.Equal("1/2 firefox 20%", out[0].Text())
+
+	tp.SortBy(ByMemory)
+	out = tester.AssertOutput("on sort order change")
+	assert.Equal("1/2 make 50%", out[0].Text(), "keeps usage between samples")
+
+	fs.Remove("/proc/20/stat")
+	scheduler.NextTick()
+	out = tester.AssertOutput("when process exits")
+	assert.Equal("1/2 init 0%", out[0].Text())
+
+	fs.Remove("/proc/stat")
+	scheduler.NextTick()
+	tester.AssertError("when /proc/stat is missing")
+}
+
+func TestDefaultOutput(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+	pageSize = 4096
+	writeStat(1000)
+	writeProcess(1, "init", 100, 256)
+
+	tester := testModule.NewOutputTester(t, New(3).SortBy(ByMemory))
+	out := tester.AssertOutput("on start")
+	assert.Equal("init 0% 1.0 MiB", out[0].Text())
+}
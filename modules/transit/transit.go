@@ -0,0 +1,181 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package transit provides an i3bar module that shows the next public transit
+departures from a stop.
+
+Departures are fetched using a Provider, so that any transit API can be used.
+A provider is included for the transport.rest family of APIs, which provide
+real-time departures for many European networks. The default output shows the
+next three departures, with the time remaining until each one.
+*/
+package transit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Departure represents a single departure from the stop.
+type Departure struct {
+	// Line is the name of the route, e.g. "S1" or "42".
+	Line string
+	// Destination is the final stop, or the direction of travel.
+	Destination string
+	// Time is the expected departure time, including any delay.
+	Time time.Time
+	// Delay is the delay from the scheduled departure time, if known.
+	Delay time.Duration
+}
+
+// Provider gets departures from a transit API.
+type Provider interface {
+	// Departures returns the upcoming departures from the given stop.
+	Departures(stop string) ([]Departure, error)
+}
+
+// Info represents the upcoming departures from the stop.
+type Info struct {
+	Departures []Departure
+	// Now is the time at which the departures were rendered,
+	// used to compute the countdowns.
+	Now time.Time
+}
+
+// Countdown returns the time until the given departure as a short string,
+// e.g. "now", "4m", or "1h5m".
+func (i Info) Countdown(d Departure) string {
+	until := d.Time.Sub(i.Now)
+	if until < time.Minute {
+		return "now"
+	}
+	until = until.Truncate(time.Minute)
+	if until < time.Hour {
+		return fmt.Sprintf("%dm", int(until.Minutes()))
+	}
+	return fmt.Sprintf("%dh%dm", int(until.Hours()), int(until.Minutes())%60)
+}
+
+// Module is the public interface for a transit module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// Count sets the maximum number of departures shown.
+	Count(int) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	provider   Provider
+	stop       string
+	count      int
+	outputFunc func(Info) bar.Output
+}
+
+// New constructs an instance of the transit module that shows the next
+// departures from the given stop, using the given provider.
+func New(provider Provider, stop string) Module {
+	m := &module{
+		Base:     base.New(),
+		provider: provider,
+		stop:     stop,
+		count:    3,
+	}
+	// Refresh every minute to keep the countdowns and delays current.
+	m.RefreshInterval(time.Minute)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows each departure's line and countdown, e.g.
+// "S1 3m, U2 7m", or nothing if there are no upcoming departures.
+func DefaultOutput(i Info) bar.Output {
+	if len(i.Departures) == 0 {
+		return outputs.Empty()
+	}
+	var parts []string
+	for _, d := range i.Departures {
+		parts = append(parts, d.Line+" "+i.Countdown(d))
+	}
+	return outputs.Text(strings.Join(parts, ", "))
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) Count(count int) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.count = count
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) update() {
+	departures, err := m.provider.Departures(m.stop)
+	if m.Error(err) {
+		return
+	}
+	now := scheduler.Now()
+	// Providers may include departures that have just left,
+	// and may not return them in order once delays are included.
+	var upcoming []Departure
+	for _, d := range departures {
+		if !d.Time.Before(now) {
+			upcoming = append(upcoming, d)
+		}
+	}
+	sort.SliceStable(upcoming, func(a, b int) bool {
+		return upcoming[a].Time.Before(upcoming[b].Time)
+	})
+	m.Lock()
+	if len(upcoming) > m.count {
+		upcoming = upcoming[:m.count]
+	}
+	out := m.outputFunc(Info{Departures: upcoming, Now: now})
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestTransportRest(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stops/900100003/departures" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"departures": [
+			{"when": "2018-01-05T10:04:00+01:00", "delay": 60, "direction": "Spandau",
+				"line": {"name": "S3"}},
+			{"when": null, "delay": null, "direction": "Erkner", "line": {"name": "S3"}},
+			{"when": "2018-01-05T10:07:00+01:00", "delay": null, "direction": "Ruhleben",
+				"line": {"name": "U2"}}]}`))
+	}))
+	defer srv.Close()
+
+	cet := time.FixedZone("CET", 60*60)
+	departures, err := TransportRest(srv.URL).Departures("900100003")
+	assert.NoError(err)
+	assert.Equal(2, len(departures), "cancelled departures are skipped")
+	assert.Equal("S3", departures[0].Line)
+	assert.Equal("Spandau", departures[0].Destination)
+	assert.True(time.Date(2018, 1, 5, 10, 4, 0, 0, cet).Equal(departures[0].Time))
+	assert.Equal(time.Minute, departures[0].Delay)
+	assert.Equal("U2", departures[1].Line)
+	assert.Equal(time.Duration(0), departures[1].Delay)
+
+	_, err = TransportRest(srv.URL).Departures("123")
+	assert.Error(err, "unknown stop")
+}
+
+func TestCountdown(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	i := Info{Now: now}
+	assert.Equal("now", i.Countdown(Departure{Time: now.Add(30 * time.Second)}))
+	assert.Equal("4m", i.Countdown(Departure{Time: now.Add(4*time.Minute + 50*time.Second)}))
+	assert.Equal("1h5m", i.Countdown(Departure{Time: now.Add(65 * time.Minute)}))
+}
+
+type testProvider struct {
+	sync.Mutex
+	departures []Departure
+	err        error
+	stop       string
+}
+
+func (t *testProvider) Departures(stop string) ([]Departure, error) {
+	t.Lock()
+	defer t.Unlock()
+	t.stop = stop
+	return t.departures, t.err
+}
+
+func (t *testProvider) set(departures []Departure, err error) {
+	t.Lock()
+	defer t.Unlock()
+	t.departures = departures
+	t.err = err
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	at := func(d time.Duration) time.Time { return time.Time{}.Add(d) }
+
+	p := &testProvider{}
+	p.set([]Departure{
+		{Line: "S3", Time: at(7 * time.Minute)},
+		{Line: "U2", Time: at(2 * time.Minute)},
+		{Line: "S5", Time: at(12 * time.Minute)},
+		{Line: "S7", Time: at(20 * time.Minute)},
+	}, nil)
+	tr := New(p, "stop-1")
+	tester := testModule.NewOutputTester(t, tr)
+	out := tester.AssertOutput("on start")
+	assert.Equal("U2 2m, S3 7m, S5 12m", out[0].Text())
+	assert.Equal("stop-1", p.stop)
+
+	scheduler.AdvanceBy(time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("U2 1m, S3 6m, S5 11m", out[0].Text())
+
+	tr.Count(2)
+	out = tester.AssertOutput("on count change")
+	assert.Equal("U2 1m, S3 6m", out[0].Text())
+
+	scheduler.AdvanceBy(2 * time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("S3 4m, S5 9m", out[0].Text(), "departed trains are removed")
+
+	var info Info
+	tr.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	assert.Equal(at(3*time.Minute), info.Now)
+	assert.Equal(2, len(info.Departures))
+
+	p.set(nil, nil)
+	tr.OutputFunc(DefaultOutput)
+	out = tester.AssertOutput("with no departures")
+	assert.Empty(out)
+
+	p.set(nil, errors.New("timeout"))
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertError("on provider error")
+}
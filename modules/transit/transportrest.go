@@ -0,0 +1,65 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transit
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/soumya92/barista/base/httpclient"
+)
+
+// client is shared by all stops, e.g. to reuse connections.
+var client = httpclient.New()
+
+type transportRest string
+
+// TransportRest returns a provider that uses a transport.rest API
+// (e.g. "https://v6.db.transport.rest" for Deutsche Bahn, or
+// "https://v6.vbb.transport.rest" for Berlin), with stops identified
+// by the API's stop IDs.
+func TransportRest(apiURL string) Provider {
+	return transportRest(apiURL)
+}
+
+func (t transportRest) Departures(stop string) ([]Departure, error) {
+	u := fmt.Sprintf("%s/stops/%s/departures?duration=120", t, url.PathEscape(stop))
+	var r struct {
+		Departures []struct {
+			// When is null for cancelled departures.
+			When      *time.Time
+			Delay     int
+			Direction string
+			Line      struct{ Name string }
+		}
+	}
+	if err := client.GetJSON(u, &r); err != nil {
+		return nil, err
+	}
+	var departures []Departure
+	for _, d := range r.Departures {
+		if d.When == nil {
+			continue
+		}
+		departures = append(departures, Departure{
+			Line:        d.Line.Name,
+			Destination: d.Direction,
+			Time:        *d.When,
+			Delay:       time.Duration(d.Delay) * time.Second,
+		})
+	}
+	return departures, nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updates
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// commandProvider checks for updates by running a command and parsing
+// the packages from its output.
+type commandProvider struct {
+	args []string
+	// okCodes are additional exit codes that indicate success,
+	// since some tools use the exit code to signal available updates.
+	okCodes []int
+	// parse returns the package name from a line, or "" to skip it.
+	// It returns false if the rest of the output should be ignored.
+	parse   func(string) (string, bool)
+	upgrade string
+}
+
+// output runs a command and returns its output and exit code.
+var output = func(args ...string) (string, int, error) {
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(interface{ ExitStatus() int }); ok {
+			return string(out), status.ExitStatus(), err
+		}
+	}
+	return string(out), 0, err
+}
+
+func (c commandProvider) Updates() ([]string, error) {
+	out, code, err := output(c.args...)
+	if err != nil {
+		ok := false
+		for _, okCode := range c.okCodes {
+			if code == okCode {
+				ok = true
+			}
+		}
+		if !ok {
+			return nil, err
+		}
+	}
+	var packages []string
+	for _, line := range strings.Split(out, "\n") {
+		pkg, more := c.parse(strings.TrimSpace(line))
+		if !more {
+			break
+		}
+		if pkg != "" {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, nil
+}
+
+func (c commandProvider) UpgradeCommand() string {
+	return c.upgrade
+}
+
+// firstField returns the first whitespace-separated field of a line.
+func firstField(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", true
+	}
+	return fields[0], true
+}
+
+// Pacman returns a provider that checks for updates using checkupdates
+// (from pacman-contrib), which uses a separate database so that checking
+// for updates does not cause partial upgrades.
+func Pacman() Provider {
+	return commandProvider{
+		args: []string{"checkupdates"},
+		// checkupdates exits with 2 if there are no updates.
+		okCodes: []int{2},
+		// Lines are "name old-version -> new-version".
+		parse:   firstField,
+		upgrade: "sudo pacman -Syu",
+	}
+}
+
+// Apt returns a provider that checks for updates using apt. It only lists
+// updates from the existing package lists, which can be kept current using
+// e.g. the apt daily timer.
+func Apt() Provider {
+	return commandProvider{
+		args: []string{"apt", "list", "--upgradable"},
+		// Lines are "name/suite version arch [upgradable from: version]".
+		parse: func(line string) (string, bool) {
+			if !strings.Contains(line, "[upgradable from:") {
+				return "", true
+			}
+			return strings.SplitN(line, "/", 2)[0], true
+		},
+		upgrade: "sudo apt update && sudo apt upgrade",
+	}
+}
+
+// Dnf returns a provider that checks for updates using dnf.
+func Dnf() Provider {
+	return commandProvider{
+		args: []string{"dnf", "check-update", "--quiet"},
+		// dnf exits with 100 if there are updates.
+		okCodes: []int{100},
+		// Lines are "name.arch version repo". A list of obsoleted packages
+		// may follow the updates, which should not be counted again.
+		parse: func(line string) (string, bool) {
+			if strings.HasPrefix(line, "Obsoleting Packages") {
+				return "", false
+			}
+			if len(strings.Fields(line)) != 3 {
+				return "", true
+			}
+			return firstField(line)
+		},
+		upgrade: "sudo dnf upgrade",
+	}
+}
+
+// Flatpak returns a provider that checks for updates to flatpak applications.
+func Flatpak() Provider {
+	return commandProvider{
+		args: []string{"flatpak", "remote-ls", "--updates", "--columns=application"},
+		// Lines are just the application ID.
+		parse:   firstField,
+		upgrade: "flatpak update",
+	}
+}
+
+// Snap returns a provider that checks for updates to snaps.
+func Snap() Provider {
+	return commandProvider{
+		args: []string{"snap", "refresh", "--list"},
+		// Lines are "name version rev publisher notes", after a header line.
+		// If there are no updates, a message is printed to stderr instead.
+		parse: func(line string) (string, bool) {
+			if strings.HasPrefix(line, "Name ") {
+				return "", true
+			}
+			return firstField(line)
+		},
+		upgrade: "sudo snap refresh",
+	}
+}
+
+// combinedProvider combines updates from multiple providers.
+type combinedProvider []Provider
+
+// Combine returns a provider that combines the updates from all the given
+// providers, e.g. system packages and flatpak applications. The upgrade
+// command runs each provider's upgrade command in turn.
+func Combine(providers ...Provider) Provider {
+	return combinedProvider(providers)
+}
+
+func (c combinedProvider) Updates() ([]string, error) {
+	var packages []string
+	for _, p := range c {
+		pkgs, err := p.Updates()
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkgs...)
+	}
+	return packages, nil
+}
+
+func (c combinedProvider) UpgradeCommand() string {
+	var cmds []string
+	for _, p := range c {
+		cmds = append(cmds, p.UpgradeCommand())
+	}
+	return strings.Join(cmds, " && ")
+}
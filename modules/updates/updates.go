@@ -0,0 +1,192 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package updates provides an i3bar module that shows pending package updates.
+
+Updates are checked periodically using a Provider for the system's package
+manager. Providers are available for pacman (using checkupdates), apt, and
+dnf, as well as flatpak and snap applications. Providers can be combined to
+show system packages and applications together, and custom providers can be
+used for other package managers. By default, nothing is shown when the system
+is up to date, and clicking the module opens a terminal running the upgrade
+command.
+*/
+package updates
+
+import (
+	"os/exec"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Provider checks for package updates using a specific package manager.
+type Provider interface {
+	// Updates returns the names of packages with available updates.
+	Updates() ([]string, error)
+	// UpgradeCommand returns the shell command that upgrades all packages.
+	UpgradeCommand() string
+}
+
+// Info represents the available package updates.
+type Info struct {
+	// Packages is the list of packages with available updates.
+	Packages []string
+}
+
+// Count returns the number of available updates.
+func (i Info) Count() int {
+	return len(i.Packages)
+}
+
+// Controller provides an interface to upgrade packages from the click handler.
+type Controller interface {
+	// Upgrade opens a terminal running the upgrade command.
+	Upgrade()
+}
+
+// Module is the public interface for an updates module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to start an upgrade.
+type Module interface {
+	base.Module
+
+	// RefreshInterval configures the polling frequency for updates.
+	RefreshInterval(time.Duration) Module
+
+	// Terminal sets the terminal command used to run the upgrade command.
+	// The upgrade command is appended to the given arguments.
+	Terminal(...string) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	provider   Provider
+	terminal   []string
+	outputFunc func(Info) bar.Output
+	info       Info
+}
+
+// New constructs an instance of the updates module using the given provider.
+func New(provider Provider) Module {
+	m := &module{
+		Base:     base.New(),
+		provider: provider,
+	}
+	// Checking for updates is slow, and updates are rarely urgent.
+	m.RefreshInterval(time.Hour)
+	m.Terminal("x-terminal-emulator", "-e", "sh", "-c")
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	// Default output template is the number of updates, if any.
+	m.OutputTemplate(outputs.TextTemplate(`{{with .Count}}{{.}} updates{{end}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) Terminal(terminal ...string) Module {
+	m.Lock()
+	defer m.Unlock()
+	m.terminal = terminal
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+// DefaultClickHandler starts an upgrade on left click, if there are updates.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	if e.Button == bar.ButtonLeft && i.Count() > 0 {
+		c.Upgrade()
+	}
+}
+
+// runCommand runs a command and waits for it to finish. It only returns an
+// error if the command could not be started, since the exit status of the
+// terminal is not meaningful.
+var runCommand = func(args ...string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	cmd.Wait()
+	return nil
+}
+
+func (m *module) Upgrade() {
+	m.Lock()
+	args := append([]string(nil), m.terminal...)
+	m.Unlock()
+	args = append(args, m.provider.UpgradeCommand())
+	go func() {
+		if !m.Error(runCommand(args...)) {
+			// Check for updates again once the terminal is closed.
+			m.Update()
+		}
+	}()
+}
+
+func (m *module) update() {
+	packages, err := m.provider.Updates()
+	if m.Error(err) {
+		return
+	}
+	info := Info{Packages: packages}
+	m.Lock()
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
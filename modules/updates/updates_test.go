@@ -0,0 +1,153 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func fakeOutput(out string, code int) {
+	output = func(args ...string) (string, int, error) {
+		if code != 0 {
+			return out, code, errors.New("exit status")
+		}
+		return out, 0, nil
+	}
+}
+
+func TestProviders(t *testing.T) {
+	assert := assert.New(t)
+
+	fakeOutput("linux 4.14.1-1 -> 4.14.2-1\nvim 8.0.1-1 -> 8.0.2-1\n", 0)
+	pkgs, err := Pacman().Updates()
+	assert.NoError(err)
+	assert.Equal([]string{"linux", "vim"}, pkgs)
+
+	fakeOutput("", 2)
+	pkgs, err = Pacman().Updates()
+	assert.NoError(err, "exit code 2 means no updates")
+	assert.Empty(pkgs)
+
+	fakeOutput("", 1)
+	_, err = Pacman().Updates()
+	assert.Error(err)
+
+	fakeOutput(`Listing... Done
+bash/stable 4.4-5+deb9u1 amd64 [upgradable from: 4.4-5]
+tzdata/stable-updates 2017c-0+deb9u1 all [upgradable from: 2017b-0+deb9u1]
+`, 0)
+	pkgs, err = Apt().Updates()
+	assert.NoError(err)
+	assert.Equal([]string{"bash", "tzdata"}, pkgs)
+
+	fakeOutput(`
+kernel.x86_64     4.14.3-300.fc27    updates
+vim-common.x86_64 2:8.0.1359-1.fc27  updates
+Obsoleting Packages
+grub2-tools.x86_64 1:2.02-19.fc27    updates
+    grub2-tools.x86_64 1:2.02-18.fc27 @fedora
+`, 100)
+	pkgs, err = Dnf().Updates()
+	assert.NoError(err, "exit code 100 means updates are available")
+	assert.Equal([]string{"kernel.x86_64", "vim-common.x86_64"}, pkgs)
+
+	fakeOutput("", 0)
+	pkgs, err = Dnf().Updates()
+	assert.NoError(err)
+	assert.Empty(pkgs)
+
+	fakeOutput("org.gimp.GIMP\norg.gnome.Platform\n", 0)
+	pkgs, err = Flatpak().Updates()
+	assert.NoError(err)
+	assert.Equal([]string{"org.gimp.GIMP", "org.gnome.Platform"}, pkgs)
+
+	fakeOutput(`Name     Version  Rev   Publisher   Notes
+core     16-2.30  3887  canonical✓  core
+firefox  57.0.1   52    mozilla✓    -
+`, 0)
+	pkgs, err = Snap().Updates()
+	assert.NoError(err)
+	assert.Equal([]string{"core", "firefox"}, pkgs)
+
+	fakeOutput("", 0)
+	pkgs, err = Snap().Updates()
+	assert.NoError(err)
+	assert.Empty(pkgs)
+}
+
+func TestCombine(t *testing.T) {
+	assert := assert.New(t)
+	c := Combine(&testProvider{packages: []string{"a"}}, &testProvider{packages: []string{"b", "c"}})
+	pkgs, err := c.Updates()
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b", "c"}, pkgs)
+	assert.Equal("upgrade-all && upgrade-all", c.UpgradeCommand())
+
+	c = Combine(&testProvider{packages: []string{"a"}}, &testProvider{err: errors.New("offline")})
+	_, err = c.Updates()
+	assert.Error(err)
+}
+
+type testProvider struct {
+	packages []string
+	err      error
+}
+
+func (t *testProvider) Updates() ([]string, error) { return t.packages, t.err }
+func (t *testProvider) UpgradeCommand() string     { return "upgrade-all" }
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	commands := make(chan string, 10)
+	runCommand = func(args ...string) error {
+		commands <- strings.Join(args, " ")
+		return nil
+	}
+
+	p := &testProvider{}
+	u := New(p)
+	tester := testModule.NewOutputTester(t, u)
+	out := tester.AssertOutput("on start")
+	assert.Equal("", out[0].Text(), "nothing shown when up to date")
+
+	u.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Empty(commands, "no upgrade when up to date")
+
+	p.packages = []string{"a", "b", "c"}
+	scheduler.AdvanceBy(time.Hour)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("3 updates", out[0].Text())
+
+	u.Terminal("urxvt", "-e", "bash", "-c")
+	p.packages = nil
+	u.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal("urxvt -e bash -c upgrade-all", <-commands)
+	out = tester.AssertOutput("after upgrade")
+	assert.Equal("", out[0].Text())
+
+	p.err = errors.New("network down")
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertError("on provider error")
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package uptime provides an i3bar module that shows the system uptime.
+
+The uptime is read from /proc/uptime, which includes time spent suspended.
+The module also tracks the total time spent suspended, by comparing the uptime
+to the monotonic clock (which stops during suspend), and uses it to detect
+resumes, so that the time since the last resume can also be displayed.
+*/
+package uptime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"golang.org/x/sys/unix"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/hostfs"
+	"github.com/soumya92/barista/format"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Info represents the system uptime.
+type Info struct {
+	// Uptime is the time since boot, including time spent suspended.
+	Uptime time.Duration
+	// Suspended is the total time spent suspended since boot.
+	Suspended time.Duration
+	// SinceResume is the time since the last resume from suspend, or since
+	// boot if the system has not been suspended. It is zero if the system
+	// was last resumed before the module started, since the time of that
+	// resume is not known.
+	SinceResume time.Duration
+	// resumeKnown distinguishes an unknown resume time from a resume
+	// that just happened.
+	resumeKnown bool
+}
+
+// Compact returns the uptime in a compact format, e.g. "3d 4h".
+func (i Info) Compact() string {
+	return Compact(i.Uptime)
+}
+
+// CompactSinceResume returns the time since the last resume in a compact
+// format, or an empty string if the time of the last resume is not known.
+func (i Info) CompactSinceResume() string {
+	if !i.resumeKnown {
+		return ""
+	}
+	return Compact(i.SinceResume)
+}
+
+// Compact formats a duration using the two largest units, from days, hours,
+// and minutes, e.g. "3d 4h", "4h 12m", "12m".
+func Compact(d time.Duration) string {
+	return format.Duration(d, time.Minute)
+}
+
+// Module represents an uptime bar module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	outputFunc func(Info) bar.Output
+	// lastSuspended is the total suspended time at the last update.
+	lastSuspended time.Duration
+	// resumedAt is the uptime at the last observed resume,
+	// or -1 if the last resume was not observed.
+	resumedAt time.Duration
+	started   bool
+}
+
+// New constructs an instance of the uptime module.
+func New() Module {
+	m := &module{Base: base.New()}
+	m.RefreshInterval(time.Minute)
+	// Default output template is the compact uptime.
+	m.OutputTemplate(outputs.TextTemplate(`{{.Compact}}`))
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+var fs = hostfs.Fs()
+
+// monotonic returns the current value of the monotonic clock,
+// which does not advance while the system is suspended.
+var monotonic = func() (time.Duration, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+// Suspends shorter than this are ignored, since the clocks are read at
+// slightly different times.
+const minSuspend = time.Second
+
+func readUptime() (time.Duration, error) {
+	bytes, err := afero.ReadFile(fs, "/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(bytes))
+	if len(fields) < 1 {
+		return 0, fmt.Errorf("unexpected /proc/uptime: %q", bytes)
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func (m *module) update() {
+	uptime, err := readUptime()
+	if m.Error(err) {
+		return
+	}
+	awake, err := monotonic()
+	if m.Error(err) {
+		return
+	}
+	suspended := uptime - awake
+	if suspended < minSuspend {
+		suspended = 0
+	}
+	m.Lock()
+	if !m.started {
+		m.started = true
+		m.resumedAt = -1
+		if suspended == 0 {
+			m.resumedAt = 0
+		}
+	} else if suspended-m.lastSuspended >= minSuspend {
+		m.resumedAt = uptime
+	}
+	m.lastSuspended = suspended
+	info := Info{Uptime: uptime, Suspended: suspended}
+	if m.resumedAt >= 0 {
+		info.SinceResume = uptime - m.resumedAt
+		info.resumeKnown = true
+	}
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
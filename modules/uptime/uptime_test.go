@@ -0,0 +1,86 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uptime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestCompact(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("0m", Compact(30*time.Second))
+	assert.Equal("12m", Compact(12*time.Minute+5*time.Second))
+	assert.Equal("4h 0m", Compact(4*time.Hour))
+	assert.Equal("4h 12m", Compact(4*time.Hour+12*time.Minute))
+	assert.Equal("3d 4h", Compact(76*time.Hour+59*time.Minute))
+}
+
+func TestUptime(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	fs = afero.NewMemMapFs()
+
+	var uptime, awake time.Duration
+	setUptime := func(up, aw time.Duration) {
+		uptime, awake = up, aw
+		afero.WriteFile(fs, "/proc/uptime",
+			[]byte(fmt.Sprintf("%.2f 1234.56\n", uptime.Seconds())), 0644)
+	}
+	monotonic = func() (time.Duration, error) { return awake, nil }
+
+	setUptime(4*time.Hour+12*time.Minute, 4*time.Hour+12*time.Minute)
+	u := New()
+	u.OutputTemplate(func(i interface{}) bar.Output {
+		info := i.(Info)
+		return bar.Output{bar.NewSegment(info.Compact() + "/" + info.CompactSinceResume())}
+	})
+	tester := testModule.NewOutputTester(t, u)
+	out := tester.AssertOutput("on start")
+	assert.Equal("4h 12m/4h 12m", out[0].Text(), "not suspended since boot")
+
+	setUptime(5*time.Hour, 4*time.Hour+13*time.Minute)
+	scheduler.NextTick()
+	out = tester.AssertOutput("after resume")
+	assert.Equal("5h 0m/0m", out[0].Text())
+
+	setUptime(5*time.Hour+20*time.Minute, 4*time.Hour+33*time.Minute)
+	scheduler.NextTick()
+	out = tester.AssertOutput("after refresh")
+	assert.Equal("5h 20m/20m", out[0].Text())
+
+	fs.Remove("/proc/uptime")
+	scheduler.NextTick()
+	tester.AssertError("when /proc/uptime is missing")
+
+	setUptime(2*time.Hour, time.Hour)
+	u2 := New()
+	u2.OutputTemplate(func(i interface{}) bar.Output {
+		info := i.(Info)
+		return bar.Output{bar.NewSegment(
+			fmt.Sprintf("%v [%s]", info.Suspended, info.CompactSinceResume()))}
+	})
+	tester2 := testModule.NewOutputTester(t, u2)
+	out = tester2.AssertOutput("on start")
+	assert.Equal("1h0m0s []", out[0].Text(), "last resume unknown")
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uv
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/base/httpclient"
+	"github.com/soumya92/barista/base/secret"
+)
+
+// API endpoints, overridden in tests.
+var (
+	openMeteoAPI   = "https://api.open-meteo.com"
+	airQualityAPI  = "https://air-quality-api.open-meteo.com"
+	openWeatherAPI = "https://api.openweathermap.org"
+)
+
+// client is shared by all providers, e.g. to reuse connections.
+var client = httpclient.New()
+
+func coords(lat, lng float64) url.Values {
+	qp := url.Values{}
+	qp.Add("latitude", fmt.Sprintf("%f", lat))
+	qp.Add("longitude", fmt.Sprintf("%f", lng))
+	qp.Add("timeformat", "unixtime")
+	return qp
+}
+
+// pollenTypes are the pollen variables supported by Open-Meteo,
+// without the "_pollen" suffix.
+var pollenTypes = []string{"alder", "birch", "grass", "mugwort", "olive", "ragweed"}
+
+type openMeteo struct{}
+
+// OpenMeteo returns a provider that gets the UV index and pollen levels from
+// Open-Meteo. Pollen levels are only available in Europe.
+func OpenMeteo() Provider {
+	return openMeteo{}
+}
+
+func (openMeteo) Get(lat, lng float64, pollen bool) (Info, error) {
+	var r struct {
+		Current struct {
+			Time    int64
+			UVIndex float64 `json:"uv_index"`
+		}
+		Daily struct {
+			Sunrise []int64
+			Sunset  []int64
+		}
+	}
+	qp := coords(lat, lng)
+	qp.Add("current", "uv_index")
+	qp.Add("daily", "sunrise,sunset")
+	qp.Add("timezone", "auto")
+	qp.Add("forecast_days", "1")
+	if err := client.GetJSON(openMeteoAPI+"/v1/forecast?"+qp.Encode(), &r); err != nil {
+		return Info{}, err
+	}
+	if len(r.Daily.Sunrise) < 1 || len(r.Daily.Sunset) < 1 {
+		return Info{}, errors.New("Bad response from Open-Meteo")
+	}
+	info := Info{
+		Index:   r.Current.UVIndex,
+		Sunrise: time.Unix(r.Daily.Sunrise[0], 0),
+		Sunset:  time.Unix(r.Daily.Sunset[0], 0),
+		Updated: time.Unix(r.Current.Time, 0),
+	}
+	if !pollen {
+		return info, nil
+	}
+	var vars []string
+	for _, p := range pollenTypes {
+		vars = append(vars, p+"_pollen")
+	}
+	// Values are null outside the area covered by the pollen forecast.
+	var a struct {
+		Current map[string]*float64
+	}
+	qp = coords(lat, lng)
+	qp.Add("current", strings.Join(vars, ","))
+	if err := client.GetJSON(airQualityAPI+"/v1/air-quality?"+qp.Encode(), &a); err != nil {
+		return Info{}, err
+	}
+	for _, p := range pollenTypes {
+		if v := a.Current[p+"_pollen"]; v != nil {
+			if info.Pollen == nil {
+				info.Pollen = map[string]float64{}
+			}
+			info.Pollen[p] = *v
+		}
+	}
+	return info, nil
+}
+
+type openWeatherMap struct{ apiKey secret.Secret }
+
+// OpenWeatherMap returns a provider that gets the UV index from the
+// OpenWeatherMap One Call API using the given API key. Pollen levels are
+// not available from OpenWeatherMap.
+func OpenWeatherMap(apiKey secret.Secret) Provider {
+	return openWeatherMap{apiKey}
+}
+
+func (o openWeatherMap) Get(lat, lng float64, pollen bool) (Info, error) {
+	key, err := o.apiKey.Get()
+	if err != nil {
+		return Info{}, err
+	}
+	var r struct {
+		Current struct {
+			Dt      int64
+			Sunrise int64
+			Sunset  int64
+			UVI     float64
+		}
+	}
+	qp := url.Values{}
+	qp.Add("lat", fmt.Sprintf("%f", lat))
+	qp.Add("lon", fmt.Sprintf("%f", lng))
+	qp.Add("exclude", "minutely,hourly,daily,alerts")
+	qp.Add("appid", key)
+	if err := client.GetJSON(openWeatherAPI+"/data/3.0/onecall?"+qp.Encode(), &r); err != nil {
+		return Info{}, err
+	}
+	if r.Current.Dt == 0 {
+		return Info{}, errors.New("Bad response from OWM")
+	}
+	return Info{
+		Index:   r.Current.UVI,
+		Sunrise: time.Unix(r.Current.Sunrise, 0),
+		Sunset:  time.Unix(r.Current.Sunset, 0),
+		Updated: time.Unix(r.Current.Dt, 0),
+	}, nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package uv provides an i3bar module that shows the UV index, and optionally
+pollen levels, for a location.
+
+Conditions are fetched using a Provider, with providers available for
+Open-Meteo, which needs no API key and includes pollen forecasts for Europe,
+and the OpenWeatherMap One Call API. The module is only shown during daylight
+hours, and is not refreshed at night. The default output is coloured using
+the standard WHO colours for the UV risk level.
+*/
+package uv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Level represents the risk of harm from unprotected sun exposure.
+type Level int
+
+// UV risk levels, as defined by the WHO.
+const (
+	Low Level = iota
+	Moderate
+	High
+	VeryHigh
+	Extreme
+)
+
+var levelNames = []string{"Low", "Moderate", "High", "Very High", "Extreme"}
+
+func (l Level) String() string {
+	if l < Low || l > Extreme {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// Color returns the standard WHO colour for the risk level.
+func (l Level) Color() bar.Color {
+	switch l {
+	case Low:
+		return colors.Hex("#289500")
+	case Moderate:
+		return colors.Hex("#f7e400")
+	case High:
+		return colors.Hex("#f85900")
+	case VeryHigh:
+		return colors.Hex("#d8001d")
+	}
+	return colors.Hex("#6b49c8")
+}
+
+// Info represents the current UV index and pollen levels.
+type Info struct {
+	// Index is the UV index, usually between 0 and 11+.
+	Index float64
+	// Pollen is the concentration of each type of pollen in grains/m³,
+	// keyed by plant (e.g. "grass", "birch"). It is nil if pollen is
+	// not enabled, or not available for the location.
+	Pollen map[string]float64
+	// Sunrise and Sunset are the daylight hours for the current day.
+	Sunrise, Sunset time.Time
+	Updated         time.Time
+}
+
+// Level returns the risk level for the UV index.
+func (i Info) Level() Level {
+	switch {
+	case i.Index < 3:
+		return Low
+	case i.Index < 6:
+		return Moderate
+	case i.Index < 8:
+		return High
+	case i.Index < 11:
+		return VeryHigh
+	}
+	return Extreme
+}
+
+// Daylight returns true if the given time is between sunrise and sunset.
+func (i Info) Daylight(t time.Time) bool {
+	return !t.Before(i.Sunrise) && t.Before(i.Sunset)
+}
+
+// MaxPollen returns the type and concentration of the most prevalent pollen,
+// and false if there is no pollen information.
+func (i Info) MaxPollen() (string, float64, bool) {
+	name, max, ok := "", 0.0, false
+	for n, v := range i.Pollen {
+		if !ok || v > max || (v == max && n < name) {
+			name, max, ok = n, v, true
+		}
+	}
+	return name, max, ok
+}
+
+// Provider gets the UV index and daylight hours, and optionally pollen
+// levels, from a weather service.
+type Provider interface {
+	// Get returns the current conditions at the given location.
+	Get(lat, lng float64, pollen bool) (Info, error)
+}
+
+// Module is the public interface for a UV index module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency during the day.
+	RefreshInterval(time.Duration) Module
+
+	// Provider sets the weather service used to get the UV index.
+	Provider(Provider) Module
+
+	// Pollen configures whether pollen levels are also fetched.
+	Pollen(bool) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+}
+
+type module struct {
+	*base.Base
+	lat, lng   float64
+	provider   Provider
+	pollen     bool
+	interval   time.Duration
+	outputFunc func(Info) bar.Output
+}
+
+// New constructs an instance of the UV index module for the given location,
+// using Open-Meteo.
+func New(lat, lng float64) Module {
+	m := &module{
+		Base:     base.New(),
+		lat:      lat,
+		lng:      lng,
+		provider: OpenMeteo(),
+		// UV forecasts are hourly.
+		interval: 30 * time.Minute,
+	}
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows the UV index coloured by the risk level, followed by
+// the most prevalent pollen if available.
+func DefaultOutput(i Info) bar.Output {
+	text := fmt.Sprintf("UV %.0f", i.Index)
+	if name, value, ok := i.MaxPollen(); ok {
+		text = fmt.Sprintf("%s %s %.0f", text, name, value)
+	}
+	return outputs.Text(text).Color(i.Level().Color())
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.interval = interval
+	return m
+}
+
+func (m *module) Provider(provider Provider) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.provider = provider
+	return m
+}
+
+func (m *module) Pollen(pollen bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.pollen = pollen
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) update() {
+	m.Lock()
+	provider, pollen, interval := m.provider, m.pollen, m.interval
+	m.Unlock()
+	// Schedule the next refresh before fetching, so that errors are retried.
+	m.Schedule().After(interval)
+	info, err := provider.Get(m.lat, m.lng, pollen)
+	if m.Error(err) {
+		return
+	}
+	// At night, hide the module and wait for sunrise. After sunset, the
+	// provider still returns today's sunrise, so use the same time tomorrow.
+	now := scheduler.Now()
+	if !info.Daylight(now) {
+		sunrise := info.Sunrise
+		if !sunrise.After(now) {
+			days := now.Sub(sunrise)/(24*time.Hour) + 1
+			sunrise = sunrise.Add(days * 24 * time.Hour)
+		}
+		m.Schedule().At(sunrise)
+		m.Clear()
+		return
+	}
+	m.Lock()
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
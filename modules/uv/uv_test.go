@@ -0,0 +1,189 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uv
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestInfo(t *testing.T) {
+	assert := assert.New(t)
+	for index, level := range map[float64]Level{
+		0: Low, 2.9: Low, 3: Moderate, 5.5: Moderate, 6: High,
+		8: VeryHigh, 10.9: VeryHigh, 11: Extreme, 14: Extreme,
+	} {
+		assert.Equal(level, Info{Index: index}.Level(), "UV %v", index)
+	}
+	assert.Equal("Very High", VeryHigh.String())
+	assert.Equal("Level(9)", Level(9).String())
+
+	_, _, ok := Info{}.MaxPollen()
+	assert.False(ok)
+	name, value, ok := Info{Pollen: map[string]float64{
+		"birch": 12, "grass": 40, "alder": 40, "olive": 0,
+	}}.MaxPollen()
+	assert.True(ok)
+	assert.Equal("alder", name, "ties broken by name")
+	assert.Equal(40.0, value)
+}
+
+func TestOpenMeteo(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/forecast":
+			assert.Equal("uv_index", r.URL.Query().Get("current"))
+			assert.Equal("51.507400", r.URL.Query().Get("latitude"))
+			w.Write([]byte(`{
+				"current": {"time": 1515146400, "uv_index": 4.35},
+				"daily": {"sunrise": [1515139500], "sunset": [1515168420]}}`))
+		case "/v1/air-quality":
+			w.Write([]byte(`{"current": {"time": 1515146400,
+				"alder_pollen": 3.2, "birch_pollen": 0, "grass_pollen": 18.5,
+				"mugwort_pollen": null, "olive_pollen": null, "ragweed_pollen": null}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	openMeteoAPI = srv.URL
+	airQualityAPI = srv.URL
+
+	info, err := OpenMeteo().Get(51.5074, -0.1278, false)
+	assert.NoError(err)
+	assert.Equal(4.35, info.Index)
+	assert.Nil(info.Pollen)
+	assert.Equal(time.Unix(1515139500, 0), info.Sunrise)
+	assert.Equal(time.Unix(1515168420, 0), info.Sunset)
+	assert.Equal(time.Unix(1515146400, 0), info.Updated)
+
+	info, err = OpenMeteo().Get(51.5074, -0.1278, true)
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"alder": 3.2, "birch": 0, "grass": 18.5}, info.Pollen)
+
+	airQualityAPI = srv.URL + "/missing"
+	_, err = OpenMeteo().Get(51.5074, -0.1278, true)
+	assert.Error(err)
+}
+
+func TestOpenWeatherMap(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/data/3.0/onecall", r.URL.Path)
+		if r.URL.Query().Get("appid") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"current": {"dt": 1515146400,
+			"sunrise": 1515139500, "sunset": 1515168420, "uvi": 0.8}}`))
+	}))
+	defer srv.Close()
+	openWeatherAPI = srv.URL
+
+	info, err := OpenWeatherMap(secret.Value("key")).Get(51.5074, -0.1278, true)
+	assert.NoError(err)
+	assert.Equal(Info{
+		Index:   0.8,
+		Sunrise: time.Unix(1515139500, 0),
+		Sunset:  time.Unix(1515168420, 0),
+		Updated: time.Unix(1515146400, 0),
+	}, info, "no pollen")
+
+	_, err = OpenWeatherMap(secret.Value("bad")).Get(51.5074, -0.1278, false)
+	assert.Error(err)
+}
+
+type testProvider struct {
+	sync.Mutex
+	info   Info
+	err    error
+	pollen bool
+}
+
+func (t *testProvider) Get(lat, lng float64, pollen bool) (Info, error) {
+	t.Lock()
+	defer t.Unlock()
+	t.pollen = pollen
+	return t.info, t.err
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	day := time.Date(2018, 1, 5, 0, 0, 0, 0, time.UTC)
+	scheduler.AdvanceTo(day.Add(6 * time.Hour))
+
+	p := &testProvider{info: Info{
+		Index:   6.2,
+		Sunrise: day.Add(8 * time.Hour),
+		Sunset:  day.Add(16 * time.Hour),
+	}}
+	u := New(51.5074, -0.1278).Provider(p)
+	tester := testModule.NewOutputTester(t, u)
+	tester.AssertEmpty("before sunrise")
+	assert.Equal(day.Add(8*time.Hour), scheduler.NextTick(), "refreshes at sunrise")
+	out := tester.AssertOutput("at sunrise")
+	assert.Equal("UV 6", out[0].Text())
+	assert.Equal(High.Color(), out[0]["color"])
+
+	p.Lock()
+	p.info.Pollen = map[string]float64{"grass": 25}
+	p.Unlock()
+	u.Pollen(true)
+	out = tester.AssertOutput("on pollen change")
+	assert.Equal("UV 6 grass 25", out[0].Text())
+	p.Lock()
+	assert.True(p.pollen)
+	p.Unlock()
+
+	u.RefreshInterval(time.Hour)
+	tester.AssertOutput("on interval change")
+	assert.Equal(day.Add(9*time.Hour), scheduler.NextTick())
+	tester.AssertOutput("on refresh")
+
+	p.Lock()
+	p.err = errors.New("rate limited")
+	p.Unlock()
+	scheduler.NextTick()
+	tester.AssertError("on error")
+	p.Lock()
+	p.err = nil
+	p.Unlock()
+	assert.Equal(day.Add(11*time.Hour), scheduler.NextTick(), "retried")
+	tester.AssertOutput("on retry")
+
+	u.OutputTemplate(outputs.TextTemplate(`{{.Level}}`))
+	out = tester.AssertOutput("on template change")
+	assert.Equal("High", out[0].Text())
+
+	for scheduler.NextTick().Before(day.Add(16 * time.Hour)) {
+		tester.AssertOutput("during the day")
+	}
+	tester.AssertEmpty("at sunset")
+	assert.Equal(day.Add(32*time.Hour), scheduler.NextTick(), "refreshes at next sunrise")
+	tester.AssertOutput("next day")
+}
@@ -16,14 +16,10 @@
 package vpn
 
 import (
-	"net"
-	"syscall"
-
-	"github.com/vishvananda/netlink"
-
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
 	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/watchers/netlink"
 )
 
 // State represents the vpn state.
@@ -62,14 +58,14 @@ type module struct {
 	outputFunc func(State) bar.Output
 	intf       string
 	state      State
-	lastFlags  uint32
 }
 
 // New constructs an instance of the VPN module for the specified interface.
 func New(iface string) Module {
 	m := &module{
-		Base: base.New(),
-		intf: iface,
+		Base:  base.New(),
+		intf:  iface,
+		state: Disconnected,
 	}
 	// Default output template that's just 'VPN' when connected.
 	m.OutputTemplate(outputs.TextTemplate("{{if .Connected}}VPN{{end}}"))
@@ -101,44 +97,21 @@ func (m *module) Stream() <-chan bar.Output {
 }
 
 func (m *module) worker() {
-	// Initial state.
-	m.state = Disconnected
-	if link, err := netlink.LinkByName(m.intf); err == nil {
-		if link.Attrs().Flags&net.FlagUp == net.FlagUp {
-			m.state = Connected
-		} else {
-			m.state = Waiting
-		}
-	}
-	m.Update()
-
-	// Watch for changes.
-	ch := make(chan netlink.LinkUpdate)
-	done := make(chan struct{})
-	defer close(done)
-	netlink.LinkSubscribe(ch, done)
-	for update := range ch {
-		if update.Attrs().Name != m.intf {
+	w := netlink.WatchLink(m.intf)
+	defer w.Close()
+	for range w.Updates {
+		link, err := w.Get()
+		if m.Error(err) {
 			continue
 		}
-		newFlags := update.IfInfomsg.Flags
-		shouldUpdate := false
-		if m.lastFlags&syscall.IFF_UP != newFlags&syscall.IFF_UP {
-			shouldUpdate = true
-		}
-		if m.lastFlags&syscall.IFF_RUNNING != newFlags&syscall.IFF_RUNNING {
-			shouldUpdate = true
-		}
-		if shouldUpdate {
-			m.lastFlags = newFlags
-			m.state = Disconnected
-			if newFlags&syscall.IFF_RUNNING == syscall.IFF_RUNNING {
-				m.state = Connected
-			} else if newFlags&syscall.IFF_UP == syscall.IFF_UP {
-				m.state = Waiting
-			}
-			m.Update()
+		m.state = Disconnected
+		switch link.State {
+		case netlink.Running:
+			m.state = Connected
+		case netlink.Up:
+			m.state = Waiting
 		}
+		m.Update()
 	}
 }
 
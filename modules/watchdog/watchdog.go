@@ -0,0 +1,261 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package watchdog provides a module that "wraps" an existing module, and
+detects when it is stuck: when it has not output anything, or (for modules
+built on base) an update has been running, for longer than the expected
+window. This protects against modules that silently freeze, e.g. on a
+deadlock or a request without a timeout.
+
+The output of a stuck module is shown dimmed, using the "dimmed" color from
+the scheme (or grey if it is not set), which can be changed using StaleStyle.
+The window should allow for the module's refresh interval:
+
+	w := watchdog.New(weather.New(provider), 15*time.Minute)
+
+Since goroutines cannot be stopped, a stuck module cannot be fixed in place,
+but the watchdog can replace it with a new instance, ignoring the stuck one:
+
+	w := watchdog.New(nil, 15*time.Minute).Restart(func() bar.Module {
+		return weather.New(provider)
+	})
+*/
+package watchdog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("modules/watchdog")
+
+// Module represents a watchdog module, which forwards clicks and
+// pause/resume events to the wrapped module.
+type Module interface {
+	bar.Module
+	bar.Clickable
+	bar.Pausable
+
+	// StaleStyle sets the function used to style the previous output when
+	// the module is stuck. The function receives a copy of the output,
+	// which it can modify.
+	StaleStyle(func(bar.Output) bar.Output) Module
+
+	// Restart sets a function that constructs a new instance of the wrapped
+	// module, which replaces the module whenever it is stuck. If the module
+	// passed to New is nil, it is constructed immediately.
+	Restart(func() bar.Module) Module
+}
+
+// busyReporter is implemented by modules that report how long an update
+// has been running, e.g. those built on base.Base.
+type busyReporter interface {
+	Busy() time.Time
+}
+
+type module struct {
+	window     time.Duration
+	scheduler  scheduler.Scheduler
+	mutex      sync.Mutex
+	current    bar.Module
+	restart    func() bar.Module
+	channel    chan bar.Output
+	staleFunc  func(bar.Output) bar.Output
+	lastOutput bar.Output
+	lastUpdate time.Time
+	stale      bool
+	paused     bool
+	// Incremented on each restart, so that outputs from replaced modules
+	// are ignored.
+	generation int
+}
+
+// New wraps an existing module, and shows its output as stale if it does
+// not output anything, or an update is running, for the given duration.
+func New(original bar.Module, window time.Duration) Module {
+	m := &module{
+		current: original,
+		window:  window,
+		staleFunc: func(o bar.Output) bar.Output {
+			dimmed := colors.Scheme("dimmed")
+			if dimmed == "" {
+				dimmed = colors.Hex("#888888")
+			}
+			return o.Color(dimmed)
+		},
+	}
+	m.scheduler = scheduler.Do(m.check)
+	return m
+}
+
+func (m *module) StaleStyle(staleFunc func(bar.Output) bar.Output) Module {
+	m.mutex.Lock()
+	m.staleFunc = staleFunc
+	m.mutex.Unlock()
+	m.refreshStale()
+	return m
+}
+
+func (m *module) Restart(restart func() bar.Module) Module {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.restart = restart
+	if m.current == nil {
+		m.current = restart()
+	}
+	return m
+}
+
+// Stream sets up the output pipeline, and starts checking the module.
+func (m *module) Stream() <-chan bar.Output {
+	m.mutex.Lock()
+	m.channel = make(chan bar.Output, 10)
+	m.lastUpdate = scheduler.Now()
+	gen, current := m.generation, m.current
+	m.mutex.Unlock()
+	// Check a few times per window, so that stuck modules are detected
+	// soon after the window passes.
+	m.scheduler.Every(m.window / 4)
+	go m.pipe(gen, current.Stream())
+	return m.channel
+}
+
+func (m *module) pipe(gen int, input <-chan bar.Output) {
+	for out := range input {
+		m.mutex.Lock()
+		if gen != m.generation {
+			// Replaced by a restart, but still drained since modules
+			// cannot be stopped.
+			m.mutex.Unlock()
+			continue
+		}
+		m.lastOutput = out
+		m.lastUpdate = scheduler.Now()
+		m.stale = false
+		m.channel <- out
+		m.mutex.Unlock()
+	}
+}
+
+// stuck returns true if the module has not output anything, or an update
+// has been running, for longer than the window. It must be called with
+// the mutex held.
+func (m *module) stuck(now time.Time) bool {
+	if now.Sub(m.lastUpdate) >= m.window {
+		return true
+	}
+	if b, ok := m.current.(busyReporter); ok {
+		since := b.Busy()
+		return !since.IsZero() && now.Sub(since) >= m.window
+	}
+	return false
+}
+
+// check marks the module as stale if it is stuck, and restarts it if
+// configured to do so.
+func (m *module) check() {
+	m.mutex.Lock()
+	now := scheduler.Now()
+	if m.paused || !m.stuck(now) {
+		m.mutex.Unlock()
+		return
+	}
+	wasStale := m.stale
+	m.stale = true
+	if !wasStale {
+		log.Info("module stuck", "type", fmt.Sprintf("%T", m.current))
+	}
+	var gen int
+	var current bar.Module
+	if m.restart != nil {
+		m.generation++
+		gen = m.generation
+		m.current = m.restart()
+		m.lastUpdate = now
+		current = m.current
+	}
+	m.mutex.Unlock()
+	if !wasStale {
+		m.refreshStale()
+	}
+	if current != nil {
+		log.Info("restarting module", "type", fmt.Sprintf("%T", current))
+		go m.pipe(gen, current.Stream())
+	}
+}
+
+// refreshStale outputs the stale version of the previous output, if the
+// module is stale and the previous output was not empty.
+func (m *module) refreshStale() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.stale || len(m.lastOutput) == 0 || m.channel == nil {
+		return
+	}
+	// Copy the output, since the segments may still be in use by the bar.
+	out := make(bar.Output, 0, len(m.lastOutput))
+	for _, s := range m.lastOutput {
+		segment := bar.Segment{}
+		for k, v := range s {
+			segment[k] = v
+		}
+		out = append(out, segment)
+	}
+	m.channel <- m.staleFunc(out)
+}
+
+func (m *module) currentModule() bar.Module {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.current
+}
+
+// Click passes through the click event if supported by the wrapped module.
+func (m *module) Click(e bar.Event) {
+	if clickable, ok := m.currentModule().(bar.Clickable); ok {
+		clickable.Click(e)
+	}
+}
+
+// Pause stops checking the module, since modules usually do not refresh
+// while paused, and passes through the pause event if supported.
+func (m *module) Pause() {
+	m.mutex.Lock()
+	m.paused = true
+	m.mutex.Unlock()
+	m.scheduler.Stop()
+	if pausable, ok := m.currentModule().(bar.Pausable); ok {
+		pausable.Pause()
+	}
+}
+
+// Resume restarts the window, and passes through the resume event if
+// supported by the wrapped module.
+func (m *module) Resume() {
+	m.mutex.Lock()
+	m.paused = false
+	m.lastUpdate = scheduler.Now()
+	m.mutex.Unlock()
+	m.scheduler.Every(m.window / 4)
+	if pausable, ok := m.currentModule().(bar.Pausable); ok {
+		pausable.Resume()
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchdog
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestNoOutput(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	original := testModule.New(t)
+	m := New(original, time.Minute)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("fresh"))
+	out := tester.AssertOutput("passes through output")
+	assert.Nil(t, out[0]["color"], "fresh output is not styled")
+
+	scheduler.AdvanceBy(45 * time.Second)
+	tester.AssertNoOutput("within window")
+	scheduler.AdvanceBy(15 * time.Second)
+	out = tester.AssertOutput("when stuck")
+	assert.Equal(t, "fresh", out[0].Text(), "previous output is shown")
+	assert.Equal(t, bar.Color("#888888"), out[0]["color"], "dimmed")
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("stale output is only shown once")
+
+	original.Output(outputs.Text("new"))
+	out = tester.AssertOutput("on refresh")
+	assert.Nil(t, out[0]["color"], "fresh output is not styled")
+
+	colors.Set("dimmed", bar.Color("#444444"))
+	defer colors.Set("dimmed", bar.Color(""))
+	scheduler.AdvanceBy(time.Minute)
+	out = tester.AssertOutput("when stuck again")
+	assert.Equal(t, bar.Color("#444444"), out[0]["color"], "dimmed color from scheme")
+
+	m.StaleStyle(func(o bar.Output) bar.Output {
+		return outputs.Textf("%s?", o[0].Text())
+	})
+	out = tester.AssertOutput("on style change while stale")
+	assert.Equal(t, "new?", out[0].Text(), "custom stale style")
+}
+
+// busyModule is a test module that reports a running update.
+type busyModule struct {
+	*testModule.TestModule
+	mutex sync.Mutex
+	since time.Time
+}
+
+func (b *busyModule) Busy() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.since
+}
+
+func (b *busyModule) setBusy(since time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.since = since
+}
+
+func TestBusy(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	original := &busyModule{TestModule: testModule.New(t)}
+	m := New(original, time.Minute)
+	tester := testModule.NewOutputTester(t, m)
+
+	original.Output(outputs.Text("fresh"))
+	tester.AssertOutput("passes through output")
+
+	original.setBusy(scheduler.Now())
+	scheduler.AdvanceBy(30 * time.Second)
+	original.Output(outputs.Text("progress"))
+	tester.AssertOutput("passes through output")
+	scheduler.AdvanceBy(30 * time.Second)
+	out := tester.AssertOutput("when update is stuck, despite recent output")
+	assert.Equal(t, "progress", out[0].Text())
+
+	original.setBusy(time.Time{})
+	original.Output(outputs.Text("done"))
+	tester.AssertOutput("passes through output")
+	scheduler.AdvanceBy(45 * time.Second)
+	tester.AssertNoOutput("within window")
+}
+
+func TestRestart(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	var instances []*testModule.TestModule
+	var mutex sync.Mutex
+	latest := func() *testModule.TestModule {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return instances[len(instances)-1]
+	}
+	m := New(nil, time.Minute).Restart(func() bar.Module {
+		mutex.Lock()
+		defer mutex.Unlock()
+		instances = append(instances, testModule.New(t))
+		return instances[len(instances)-1]
+	})
+	first := latest()
+	first.AssertNotStarted("before streaming")
+	tester := testModule.NewOutputTester(t, m)
+	first.AssertStarted("on stream")
+
+	first.Output(outputs.Text("first"))
+	tester.AssertOutput("passes through output")
+
+	scheduler.AdvanceBy(time.Minute)
+	out := tester.AssertOutput("when stuck")
+	assert.Equal(t, "first", out[0].Text())
+	second := latest()
+	assert.True(t, first != second, "new instance constructed")
+	second.AssertStarted("new instance streamed")
+
+	first.Output(outputs.Text("late"))
+	tester.AssertNoOutput("replaced module is ignored")
+	second.Output(outputs.Text("second"))
+	out = tester.AssertOutput("output from new instance")
+	assert.Equal(t, "second", out[0].Text())
+
+	m.Click(bar.Event{X: 1})
+	second.AssertClicked("click passed to new instance")
+	first.AssertNotClicked("not passed to replaced instance")
+}
+
+func TestPauseResume(t *testing.T) {
+	scheduler.TestMode(true)
+	defer scheduler.TestMode(false)
+	original := testModule.New(t)
+	m := New(original, time.Minute)
+	tester := testModule.NewOutputTester(t, m)
+	original.Output(outputs.Text("fresh"))
+	tester.AssertOutput("passes through output")
+
+	m.Pause()
+	original.AssertPaused("pause passed through")
+	scheduler.AdvanceBy(time.Hour)
+	tester.AssertNoOutput("while paused")
+
+	m.Resume()
+	original.AssertResumed("resume passed through")
+	scheduler.AdvanceBy(45 * time.Second)
+	tester.AssertNoOutput("window restarted on resume")
+	scheduler.AdvanceBy(15 * time.Second)
+	tester.AssertOutput("when stuck after resume")
+}
@@ -19,12 +19,14 @@ available at https://darksky.net/.
 package darksky
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/soumya92/barista/base/httpclient"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/location"
 	"github.com/soumya92/barista/modules/weather"
 )
 
@@ -33,7 +35,8 @@ import (
 type Config struct {
 	lat    float64
 	lon    float64
-	apiKey string
+	here   bool
+	apiKey secret.Secret
 }
 
 // Coords creates a dark sky configuration for the given
@@ -42,29 +45,56 @@ func Coords(lat, lon float64) *Config {
 	return &Config{lat: lat, lon: lon}
 }
 
+// Here creates a dark sky configuration for the current location,
+// as provided by the location package.
+func Here() *Config {
+	return &Config{here: true}
+}
+
 // APIKey sets the API key.
-func (c *Config) APIKey(apiKey string) *Config {
+func (c *Config) APIKey(apiKey secret.Secret) *Config {
 	c.apiKey = apiKey
 	return c
 }
 
-// Provider wraps a Dark Sky API url so that
+// client is shared by all weather lookups, e.g. to reuse connections.
+var client = httpclient.New()
+
+// Provider wraps a Dark Sky API configuration so that
 // it can be used as a weather.Provider.
-type Provider string
+type Provider Config
 
 // Build builds a weather provider from the configuration.
 func (c *Config) Build() weather.Provider {
-	// Build the Dark Sky URL.
+	return Provider(*c)
+}
+
+// url builds the Dark Sky URL, resolving the API key.
+func (ds Provider) url() (string, error) {
+	if ds.apiKey == nil {
+		return "", errors.New("darksky: missing API key")
+	}
+	apiKey, err := ds.apiKey.Get()
+	if err != nil {
+		return "", err
+	}
+	lat, lon := ds.lat, ds.lon
+	if ds.here {
+		var ok bool
+		if lat, lon, ok = location.Get(); !ok {
+			return "", location.ErrUnknown
+		}
+	}
 	qp := url.Values{}
 	qp.Add("exclude", "minutely,hourly,alerts,flags")
 	qp.Add("units", "us")
 	dsURL := url.URL{
 		Scheme:   "https",
 		Host:     "api.darksky.net",
-		Path:     fmt.Sprintf("/forecast/%s/%f,%f", c.apiKey, c.lat, c.lon),
+		Path:     fmt.Sprintf("/forecast/%s/%f,%f", apiKey, lat, lon),
 		RawQuery: qp.Encode(),
 	}
-	return Provider(dsURL.String())
+	return dsURL.String(), nil
 }
 
 // dsWeather represents a dark sky json response.
@@ -118,15 +148,19 @@ func getCondition(icon string) weather.Condition {
 	return weather.ConditionUnknown
 }
 
+// Local implements weather.LocalProvider, for providers built using Here().
+func (ds Provider) Local() bool {
+	return ds.here
+}
+
 // GetWeather gets weather information from OpenWeatherMap.
 func (ds Provider) GetWeather() (*weather.Weather, error) {
-	response, err := http.Get(string(ds))
+	dsURL, err := ds.url()
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 	d := dsWeather{}
-	err = json.NewDecoder(response.Body).Decode(&d)
+	err = client.GetJSON(dsURL, &d)
 	if err != nil {
 		return nil, err
 	}
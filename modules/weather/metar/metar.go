@@ -23,12 +23,12 @@ import (
 	"encoding/xml"
 	"fmt"
 	"math"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/soumya92/barista/base/httpclient"
 	"github.com/soumya92/barista/modules/weather"
 )
 
@@ -57,6 +57,9 @@ func (c *Config) IncludeFlightCat() *Config {
 	return c
 }
 
+// client is shared by all weather lookups, e.g. to reuse connections.
+var client = httpclient.New()
+
 // Provider wraps an ADDS XML url and configuration
 // so that it can be used as a weather.Provider.
 type Provider struct {
@@ -293,14 +296,13 @@ func (m metar) getCondition() weather.Condition {
 
 // GetWeather gets weather information from NOAA ADDS.
 func (p Provider) GetWeather() (*weather.Weather, error) {
-	response, err := http.Get(p.url)
+	body, err := client.Get(p.url)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 
 	var resp addsResponse
-	err = xml.NewDecoder(response.Body).Decode(&resp)
+	err = xml.Unmarshal(body, &resp)
 	if err != nil {
 		return nil, err
 	}
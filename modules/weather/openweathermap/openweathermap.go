@@ -19,12 +19,13 @@ available at https://openweathermap.org/api.
 package openweathermap
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/soumya92/barista/base/httpclient"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/location"
 	"github.com/soumya92/barista/modules/weather"
 )
 
@@ -32,7 +33,8 @@ import (
 // from which a weather.Provider can be built.
 type Config struct {
 	query  map[string]string
-	apiKey string
+	here   bool
+	apiKey secret.Secret
 }
 
 // CityID queries OWM by city id. Recommended.
@@ -57,6 +59,12 @@ func Coords(lat, lon float64) *Config {
 	}}
 }
 
+// Here queries OWM using the current location,
+// as provided by the location package.
+func Here() *Config {
+	return &Config{here: true}
+}
+
 // Zipcode queries OWM using a zip code or post code and country.
 func Zipcode(zip, country string) *Config {
 	return &Config{query: map[string]string{
@@ -65,35 +73,54 @@ func Zipcode(zip, country string) *Config {
 }
 
 // APIKey sets the API key if a different api key is preferred.
-func (c *Config) APIKey(apiKey string) *Config {
+func (c *Config) APIKey(apiKey secret.Secret) *Config {
 	c.apiKey = apiKey
 	return c
 }
 
-// Provider wraps an open weather map API url so that
+// client is shared by all weather lookups, e.g. to reuse connections.
+var client = httpclient.New()
+
+// Provider wraps an open weather map API configuration so that
 // it can be used as a weather.Provider.
-type Provider string
+type Provider Config
 
 // Build builds a weather provider from the configuration.
 func (c *Config) Build() weather.Provider {
-	// Build the OWM URL.
-	qp := url.Values{}
-	apiKey := c.apiKey
+	p := Provider(*c)
 	// Use barista's API key if no API key was explicitly provided.
-	if apiKey == "" {
-		apiKey = "9c51204f81fc8e1998981de83a7cabc9"
+	if p.apiKey == nil {
+		p.apiKey = secret.Value("9c51204f81fc8e1998981de83a7cabc9")
+	}
+	return p
+}
+
+// url builds the OWM URL, resolving the API key.
+func (owm Provider) url() (string, error) {
+	apiKey, err := owm.apiKey.Get()
+	if err != nil {
+		return "", err
 	}
+	qp := url.Values{}
 	qp.Add("appid", apiKey)
-	for key, value := range c.query {
+	for key, value := range owm.query {
 		qp.Add(key, value)
 	}
+	if owm.here {
+		lat, lon, ok := location.Get()
+		if !ok {
+			return "", location.ErrUnknown
+		}
+		qp.Add("lat", fmt.Sprintf("%f", lat))
+		qp.Add("lon", fmt.Sprintf("%f", lon))
+	}
 	owmURL := url.URL{
 		Scheme:   "http",
 		Host:     "api.openweathermap.org",
 		Path:     "/data/2.5/weather",
 		RawQuery: qp.Encode(),
 	}
-	return Provider(owmURL.String())
+	return owmURL.String(), nil
 }
 
 // owmWeather represents an openweathermap json response.
@@ -171,15 +198,19 @@ func getCondition(owmCondition int) weather.Condition {
 	return weather.ConditionUnknown
 }
 
+// Local implements weather.LocalProvider, for providers built using Here().
+func (owm Provider) Local() bool {
+	return owm.here
+}
+
 // GetWeather gets weather information from OpenWeatherMap.
 func (owm Provider) GetWeather() (*weather.Weather, error) {
-	response, err := http.Get(string(owm))
+	owmURL, err := owm.url()
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 	o := owmWeather{}
-	err = json.NewDecoder(response.Body).Decode(&o)
+	err = client.GetJSON(owmURL, &o)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,114 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openweathermap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/location"
+	"github.com/soumya92/barista/modules/weather"
+	"github.com/soumya92/barista/testing/httpclient"
+)
+
+func init() {
+	// Retrying failed requests would only slow down the error tests.
+	client.Retry(0, 0)
+}
+
+const owmURL = "http://api.openweathermap.org/data/2.5/weather"
+
+func TestWeather(t *testing.T) {
+	stub := httpclient.Stub(t)
+	defer stub.Close()
+	stub.Handle(owmURL).Body(`{
+		"weather": [{"id": 802, "description": "scattered clouds"}],
+		"main": {"temp": 293.15, "pressure": 1013, "humidity": 40},
+		"wind": {"speed": 5, "deg": 270},
+		"clouds": {"all": 40},
+		"sys": {"sunrise": 1500000000, "sunset": 1500040000},
+		"name": "Springfield",
+		"dt": 1500020000
+	}`)
+
+	w, err := CityID("1234").APIKey(secret.Value("key")).Build().GetWeather()
+	assert.Nil(t, err)
+	assert.Equal(t, "Springfield", w.Location)
+	assert.Equal(t, weather.Condition(weather.PartlyCloudy), w.Condition)
+	assert.Equal(t, "scattered clouds", w.Description)
+	assert.Equal(t, 20, w.Temperature.C())
+	assert.Equal(t, 40.0, w.Humidity)
+	assert.InDelta(t, 1013.0, w.Pressure.Millibar(), 0.01)
+	assert.InDelta(t, 5.0, w.Wind.Speed.Ms(), 0.01)
+	assert.Equal(t, weather.Direction(270), w.Wind.Direction)
+	assert.Equal(t, time.Unix(1500000000, 0), w.Sunrise)
+	assert.Equal(t, time.Unix(1500020000, 0), w.Updated)
+
+	reqs := stub.Requests(owmURL)
+	assert.Equal(t, 1, len(reqs))
+	assert.Equal(t, "1234", reqs[0].URL.Query().Get("id"))
+	assert.Equal(t, "key", reqs[0].URL.Query().Get("appid"))
+}
+
+func TestErrors(t *testing.T) {
+	stub := httpclient.Stub(t)
+	defer stub.Close()
+	stub.Handle(owmURL).Body(`{"weather": []}`).Times(1)
+	stub.Handle(owmURL).Status(401).Times(1)
+	stub.Handle(owmURL).Fail(nil)
+
+	_, err := Coords(1.5, -2.5).Build().GetWeather()
+	assert.Error(t, err, "empty response")
+	_, err = Zipcode("12345", "us").Build().GetWeather()
+	assert.Error(t, err, "unauthorized")
+	_, err = CityName("London", "uk").Build().GetWeather()
+	assert.Error(t, err, "network error")
+
+	reqs := stub.Requests(owmURL)
+	assert.Equal(t, "1.500000", reqs[0].URL.Query().Get("lat"))
+	assert.Equal(t, "12345,us", reqs[1].URL.Query().Get("zip"))
+	assert.Equal(t, "London,uk", reqs[2].URL.Query().Get("q"))
+}
+
+func TestHere(t *testing.T) {
+	stub := httpclient.Stub(t)
+	defer stub.Close()
+	stub.Handle(owmURL).Body(`{"weather": [{"id": 800}], "name": "London"}`)
+
+	p := Here().Build()
+	assert.True(t, p.(weather.LocalProvider).Local(), "uses location")
+	assert.False(t, CityID("2643743").Build().(weather.LocalProvider).Local())
+	if _, _, ok := location.Get(); !ok {
+		_, err := p.GetWeather()
+		assert.Equal(t, location.ErrUnknown, err, "location unknown")
+	}
+
+	location.Set(51.5, -0.12)
+	w, err := p.GetWeather()
+	assert.Nil(t, err)
+	assert.Equal(t, "London", w.Location)
+	location.Set(48.85, 2.35)
+	_, err = p.GetWeather()
+	assert.Nil(t, err)
+
+	reqs := stub.Requests(owmURL)
+	assert.Equal(t, 2, len(reqs))
+	assert.Equal(t, "51.500000", reqs[0].URL.Query().Get("lat"))
+	assert.Equal(t, "-0.120000", reqs[0].URL.Query().Get("lon"))
+	assert.Equal(t, "48.850000", reqs[1].URL.Query().Get("lat"), "follows location")
+}
@@ -20,6 +20,9 @@ import (
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/cache"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/location"
 	"github.com/soumya92/barista/outputs"
 )
 
@@ -95,6 +98,14 @@ type Provider interface {
 	GetWeather() (*Weather, error)
 }
 
+// LocalProvider is implemented by providers that can use the current
+// location from the location package, e.g. openweathermap.Here().
+type LocalProvider interface {
+	Provider
+	// Local returns true if the provider uses the current location.
+	Local() bool
+}
+
 // Module is the public interface for a weather module.
 // In addition to bar.Module, it also provides an expanded OnClick,
 // which allows click handlers to get the current weather.
@@ -112,6 +123,11 @@ type Module interface {
 
 	// OnClick sets a click handler for the module.
 	OnClick(func(Weather, bar.Event)) Module
+
+	// Cache saves the weather under the given key after every fetch, and
+	// uses the saved weather on start if it is newer than the refresh
+	// interval, so that restarting the bar does not fetch it again.
+	Cache(key string) Module
 }
 
 type module struct {
@@ -120,6 +136,12 @@ type module struct {
 	outputFunc func(Weather) bar.Output
 	// cache last weather info for click handler.
 	lastWeather Weather
+	interval    time.Duration
+	cacheKey    string
+	// restored is true when the last weather came from the cache, so the
+	// regular refresh schedule needs to be restored on the next update.
+	restored bool
+	loaded   bool
 }
 
 // New constructs an instance of the weather module with the provided configuration.
@@ -127,19 +149,25 @@ func New(provider Provider) Module {
 	m := &module{
 		Base:     base.New(),
 		provider: provider,
+		// Default is to refresh every 10 minutes
+		interval: 10 * time.Minute,
 	}
-	// Default is to refresh every 10 minutes
-	m.Schedule().Every(10 * time.Minute)
+	m.Schedule().Every(m.interval)
 	// Default output template is just the temperature and conditions.
-	m.OutputTemplate(outputs.TextTemplate(`{{.Temperature.C}}℃ {{.Description}}`))
+	m.OutputTemplate(outputs.TextTemplate(`{{.Temperature.C | celsius}} {{.Description}}`))
 	// Update weather when asked.
 	m.OnUpdate(m.update)
+	// Update weather for the new location when it changes.
+	if p, ok := provider.(LocalProvider); ok && p.Local() {
+		location.OnChange(m.Update)
+	}
 	return m
 }
 
 func (m *module) OutputFunc(outputFunc func(Weather) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
 	m.outputFunc = outputFunc
-	m.Update()
 	return m
 }
 
@@ -150,29 +178,73 @@ func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
 }
 
 func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Lock()
+	m.interval = interval
+	m.Unlock()
 	m.Schedule().Every(interval)
 	return m
 }
 
+func (m *module) Cache(key string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.cacheKey = key
+	m.loaded = false
+	return m
+}
+
 func (m *module) OnClick(f func(Weather, bar.Event)) Module {
 	if f == nil {
 		m.Base.OnClick(nil)
 		return m
 	}
 	m.Base.OnClick(func(e bar.Event) {
-		f(m.lastWeather, e)
+		m.Lock()
+		w := m.lastWeather
+		m.Unlock()
+		f(w, e)
 	})
 	return m
 }
 
 func (m *module) update() {
+	m.Lock()
+	key, interval := m.cacheKey, m.interval
+	load := key != "" && !m.loaded
+	m.loaded = true
+	restored := m.restored
+	m.restored = false
+	m.Unlock()
+	if load {
+		var cached Weather
+		if saved, ok := cache.Load(key, &cached); ok && scheduler.Now().Before(saved.Add(interval)) {
+			m.Schedule().At(saved.Add(interval))
+			m.Output(m.setWeather(&cached, true))
+			return
+		}
+	}
+	if restored {
+		m.Schedule().Every(interval)
+	}
 	weather, err := m.provider.GetWeather()
 	if m.Error(err) {
 		return
 	}
+	if weather != nil && key != "" {
+		cache.Save(key, *weather)
+	}
+	m.Output(m.setWeather(weather, false))
+}
+
+// setWeather updates the last weather, unless it is nil, and returns the
+// output for it.
+func (m *module) setWeather(weather *Weather, restored bool) bar.Output {
+	m.Lock()
+	defer m.Unlock()
 	if weather != nil {
 		// nil weather means unchanged.
 		m.lastWeather = *weather
 	}
-	m.Output(m.outputFunc(m.lastWeather))
+	m.restored = restored
+	return m.outputFunc(m.lastWeather)
 }
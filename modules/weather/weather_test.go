@@ -0,0 +1,177 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weather
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/cache"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/location"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type testProvider struct {
+	sync.Mutex
+	weather Weather
+	err     error
+	calls   int
+}
+
+func (t *testProvider) GetWeather() (*Weather, error) {
+	t.Lock()
+	defer t.Unlock()
+	t.calls++
+	if t.err != nil {
+		return nil, t.err
+	}
+	w := t.weather
+	return &w, nil
+}
+
+func (t *testProvider) set(location string, err error) {
+	t.Lock()
+	defer t.Unlock()
+	t.weather = Weather{Location: location, Temperature: 300}
+	t.err = err
+}
+
+func (t *testProvider) callCount() int {
+	t.Lock()
+	defer t.Unlock()
+	return t.calls
+}
+
+func locationOutput(w Weather) bar.Output {
+	return outputs.Text(w.Location)
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+
+	p := &testProvider{}
+	p.set("London", nil)
+	w := New(p).OutputFunc(locationOutput).RefreshInterval(time.Minute)
+	tester := testModule.NewOutputTester(t, w)
+	assert.Equal("London", tester.AssertOutput("on start")[0].Text(), "on start")
+
+	p.set("Paris", nil)
+	scheduler.AdvanceBy(time.Minute)
+	assert.Equal("Paris", tester.AssertOutput("on refresh")[0].Text(), "on refresh")
+
+	p.set("", errors.New("offline"))
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertError("on provider error")
+}
+
+// localProvider is a test provider that uses the current location.
+type localProvider struct{ testProvider }
+
+func (l *localProvider) Local() bool { return true }
+
+func TestLocationChange(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+
+	p := &localProvider{}
+	p.set("London", nil)
+	w := New(p).OutputFunc(locationOutput)
+	tester := testModule.NewOutputTester(t, w)
+	assert.Equal("London", tester.AssertOutput("on start")[0].Text())
+
+	fixed := &testProvider{}
+	fixed.set("Berlin", nil)
+	fixedTester := testModule.NewOutputTester(t, New(fixed).OutputFunc(locationOutput))
+	assert.Equal("Berlin", fixedTester.AssertOutput("on start")[0].Text())
+
+	p.set("Paris", nil)
+	location.Set(48.85, 2.35)
+	assert.Equal("Paris", tester.AssertOutput("on location change")[0].Text())
+	fixedTester.AssertNoOutput("on location change without Here()")
+}
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	dir, err := ioutil.TempDir("", "weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", dir)
+	defer func() {
+		os.Unsetenv("XDG_CACHE_HOME")
+		os.RemoveAll(dir)
+	}()
+	// Modules from previous runs of this test are still refreshing, so use
+	// keys that are unique to this run.
+	key := filepath.Base(dir)
+	staleKey := key + "-stale"
+
+	p := &testProvider{}
+	p.set("London", nil)
+	w := New(p).OutputFunc(locationOutput).Cache(key)
+	tester := testModule.NewOutputTester(t, w)
+	assert.Equal("London", tester.AssertOutput("on start")[0].Text(), "on start")
+	assert.Equal(1, p.callCount())
+
+	// A new module uses the cached weather until it is stale.
+	scheduler.AdvanceBy(4 * time.Minute)
+	tester.AssertNoOutput("before refresh")
+	p2 := &testProvider{}
+	p2.set("Paris", nil)
+	var clicked Weather
+	w2 := New(p2).OutputFunc(locationOutput).Cache(key).
+		OnClick(func(w Weather, e bar.Event) { clicked = w })
+	tester2 := testModule.NewOutputTester(t, w2)
+	assert.Equal("London", tester2.AssertOutput("on start")[0].Text(), "on start")
+	assert.Equal(0, p2.callCount(), "cached weather is used")
+	w2.Click(bar.Event{Button: bar.ButtonLeft})
+	assert.Equal("London", clicked.Location, "cached weather passed to click handler")
+	assert.Equal(Temperature(300), clicked.Temperature)
+
+	scheduler.AdvanceBy(5 * time.Minute)
+	tester2.AssertNoOutput("while cache is fresh")
+	scheduler.AdvanceBy(time.Minute)
+	assert.Equal("Paris", tester2.AssertOutput("when cache expires")[0].Text(), "when cache expires")
+	assert.Equal(1, p2.callCount())
+	tester.AssertOutput("on refresh")
+
+	// The regular schedule resumes once the cached weather expires.
+	scheduler.AdvanceBy(10 * time.Minute)
+	tester2.AssertOutput("on refresh")
+	assert.Equal(2, p2.callCount())
+	tester.AssertOutput("on refresh")
+
+	// Stale weather is not used.
+	cache.Save(staleKey, Weather{Location: "London"})
+	scheduler.AdvanceBy(time.Minute)
+	p3 := &testProvider{}
+	p3.set("Berlin", nil)
+	w3 := New(p3).OutputFunc(locationOutput).RefreshInterval(time.Minute).Cache(staleKey)
+	tester3 := testModule.NewOutputTester(t, w3)
+	assert.Equal("Berlin", tester3.AssertOutput("on start")[0].Text(), "on start")
+	assert.Equal(1, p3.callCount())
+}
@@ -19,14 +19,16 @@ available at https://www.wunderground.com/weather/api/.
 package wunderground
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/soumya92/barista/base/httpclient"
+	"github.com/soumya92/barista/base/secret"
+	"github.com/soumya92/barista/location"
 	"github.com/soumya92/barista/modules/weather"
 )
 
@@ -34,7 +36,8 @@ import (
 // from which a weather.Provider can be built.
 type Config struct {
 	query  string
-	apiKey string
+	here   bool
+	apiKey secret.Secret
 }
 
 // USCity queries by a US City and State.
@@ -57,6 +60,12 @@ func Coords(lat, lon float64) *Config {
 	return &Config{query: fmt.Sprintf("%f,%f", lat, lon)}
 }
 
+// Here queries wunderground using the current location,
+// as provided by the location package.
+func Here() *Config {
+	return &Config{here: true}
+}
+
 // Airport queries by airport code (e.g. KSEA).
 func Airport(code string) *Config {
 	return &Config{query: code}
@@ -75,24 +84,46 @@ func ZMW(zmw string) *Config {
 }
 
 // APIKey sets the API key.
-func (c *Config) APIKey(apiKey string) *Config {
+func (c *Config) APIKey(apiKey secret.Secret) *Config {
 	c.apiKey = apiKey
 	return c
 }
 
-// Provider wraps a Weather Underground API url so that
+// client is shared by all weather lookups, e.g. to reuse connections.
+var client = httpclient.New()
+
+// Provider wraps a Weather Underground API configuration so that
 // it can be used as a weather.Provider.
-type Provider string
+type Provider Config
 
 // Build builds a weather provider from the configuration.
 func (c *Config) Build() weather.Provider {
-	// Build the OWM URL.
+	return Provider(*c)
+}
+
+// url builds the Weather Underground URL, resolving the API key.
+func (wu Provider) url() (string, error) {
+	if wu.apiKey == nil {
+		return "", errors.New("wunderground: missing API key")
+	}
+	apiKey, err := wu.apiKey.Get()
+	if err != nil {
+		return "", err
+	}
+	query := wu.query
+	if wu.here {
+		lat, lon, ok := location.Get()
+		if !ok {
+			return "", location.ErrUnknown
+		}
+		query = fmt.Sprintf("%f,%f", lat, lon)
+	}
 	wURL := url.URL{
 		Scheme: "http",
 		Host:   "api.wunderground.com",
-		Path:   fmt.Sprintf("/api/%s/conditions/q/%s.json", c.apiKey, c.query),
+		Path:   fmt.Sprintf("/api/%s/conditions/q/%s.json", apiKey, query),
 	}
-	return Provider(wURL.String())
+	return wURL.String(), nil
 }
 
 // wuWeather represents a Weather Underground json response.
@@ -154,15 +185,19 @@ func parseUnixTime(unix string) time.Time {
 	return time.Unix(intVal, 0)
 }
 
+// Local implements weather.LocalProvider, for providers built using Here().
+func (wu Provider) Local() bool {
+	return wu.here
+}
+
 // GetWeather gets weather information from Weather Underground.
 func (wu Provider) GetWeather() (*weather.Weather, error) {
-	response, err := http.Get(string(wu))
+	wURL, err := wu.url()
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 	w := wuWeather{}
-	err = json.NewDecoder(response.Body).Decode(&w)
+	err = client.GetJSON(wURL, &w)
 	if err != nil {
 		return nil, err
 	}
@@ -18,17 +18,14 @@
 package wlan
 
 import (
-	"net"
 	"os/exec"
 	"strconv"
 	"strings"
-	"syscall"
-
-	"github.com/vishvananda/netlink"
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
 	"github.com/soumya92/barista/outputs"
+	"github.com/soumya92/barista/watchers/netlink"
 )
 
 // Info represents the wireless card status.
@@ -76,7 +73,6 @@ type module struct {
 	outputFunc func(Info) bar.Output
 	intf       string
 	info       Info
-	lastFlags  uint32
 }
 
 // New constructs an instance of the wlan module for the specified interface.
@@ -97,8 +93,9 @@ func (m *module) Stream() <-chan bar.Output {
 }
 
 func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
 	m.outputFunc = outputFunc
-	m.Update()
+	m.UnlockAndUpdate()
 	return m
 }
 
@@ -109,69 +106,48 @@ func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
 }
 
 func (m *module) worker() {
-	// Initial state.
-	link, err := netlink.LinkByName(m.intf)
-	if m.Error(err) {
-		return
-	}
-	m.info = Info{}
-	if link.Attrs().Flags&net.FlagUp == net.FlagUp {
-		if m.Error(m.getWifiInfo()) {
-			return
-		}
-		if m.info.SSID == "" {
-			m.info.State = Disconnected
-		}
-	}
-	m.Update()
-
-	// Watch for changes.
-	ch := make(chan netlink.LinkUpdate)
-	done := make(chan struct{})
-	defer close(done)
-	netlink.LinkSubscribe(ch, done)
-	for update := range ch {
-		if update.Attrs().Name != m.intf {
+	w := netlink.WatchLink(m.intf)
+	defer w.Close()
+	for range w.Updates {
+		link, err := w.Get()
+		if m.Error(err) {
 			continue
 		}
-		newFlags := update.IfInfomsg.Flags
-		shouldUpdate := false
-		if m.lastFlags&syscall.IFF_UP != newFlags&syscall.IFF_UP {
-			shouldUpdate = true
-		}
-		if m.lastFlags&syscall.IFF_RUNNING != newFlags&syscall.IFF_RUNNING {
-			shouldUpdate = true
-		}
-		if shouldUpdate {
-			m.lastFlags = newFlags
-			m.info = Info{}
-			if newFlags&syscall.IFF_UP != syscall.IFF_UP {
-				m.info.State = Disabled
-			} else if newFlags&syscall.IFF_RUNNING != syscall.IFF_RUNNING {
-				m.info.State = Disconnected
-			} else {
-				if m.Error(m.getWifiInfo()) {
-					return
-				}
+		info := Info{}
+		switch link.State {
+		case netlink.Missing, netlink.Down:
+			info.State = Disabled
+		case netlink.Up:
+			info.State = Disconnected
+		default:
+			// Wireless information is not available from the link, and
+			// must be re-queried whenever the link changes.
+			if m.Error(m.getWifiInfo(&info)) {
+				continue
+			}
+			if info.SSID == "" {
+				info.State = Disconnected
 			}
-			m.Update()
 		}
+		m.Lock()
+		m.info = info
+		m.UnlockAndUpdate()
 	}
 }
 
-func (m *module) getWifiInfo() error {
+func (m *module) getWifiInfo(info *Info) error {
 	var err error
-	m.info.State = Connected
-	m.info.SSID, _ = m.iwgetid("-r")
-	m.info.AccessPointMAC, _ = m.iwgetid("-a")
+	info.State = Connected
+	info.SSID, _ = m.iwgetid("-r")
+	info.AccessPointMAC, _ = m.iwgetid("-a")
 	if ch, err := m.iwgetid("-c"); err == nil {
-		m.info.Channel, err = strconv.Atoi(ch)
+		info.Channel, err = strconv.Atoi(ch)
 		if err != nil {
 			return err
 		}
 	}
 	if freq, err := m.iwgetid("-f"); err == nil {
-		m.info.Frequency, err = strconv.ParseFloat(freq, 64)
+		info.Frequency, err = strconv.ParseFloat(freq, 64)
 		if err != nil {
 			return err
 		}
@@ -185,5 +161,8 @@ func (m *module) iwgetid(flag string) (string, error) {
 }
 
 func (m *module) update() {
-	m.Output(m.outputFunc(m.info))
+	m.Lock()
+	out := m.outputFunc(m.info)
+	m.Unlock()
+	m.Output(out)
 }
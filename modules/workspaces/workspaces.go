@@ -0,0 +1,276 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package workspaces provides an i3bar module that shows i3 or sway workspaces.
+
+The module subscribes to workspace and binding mode events over the IPC socket,
+and by default shows each workspace as a separate segment, like the workspace
+buttons built into i3bar, followed by the binding mode if it is not the default.
+This allows barista to show workspaces when running under other bars, or with
+the i3bar workspace buttons disabled. Clicking a workspace switches to it, and
+scrolling switches to the next or previous workspace on the focused output.
+Workspaces can be limited to a single output, for a bar on each output.
+
+Focused, visible, and inactive workspaces use the "focused", "visible", and
+"inactive" colors from the color scheme, and urgent workspaces are marked
+urgent.
+*/
+package workspaces
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/i3ipc"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Workspace represents a single workspace.
+type Workspace struct {
+	// Num is the workspace number, or -1 for named workspaces
+	// that do not start with a number.
+	Num     int    `json:"num"`
+	Name    string `json:"name"`
+	Output  string `json:"output"`
+	Focused bool   `json:"focused"`
+	Visible bool   `json:"visible"`
+	Urgent  bool   `json:"urgent"`
+}
+
+// Info represents the current workspaces, in the order returned by i3,
+// and the current binding mode.
+type Info struct {
+	Workspaces []Workspace
+	// Mode is the name of the current binding mode, "default" normally.
+	Mode string
+}
+
+// Focused returns the focused workspace, and false if no workspace is focused.
+func (i Info) Focused() (Workspace, bool) {
+	for _, w := range i.Workspaces {
+		if w.Focused {
+			return w, true
+		}
+	}
+	return Workspace{}, false
+}
+
+// Controller provides an interface to switch workspaces from the click handler.
+type Controller interface {
+	// Focus switches to the workspace with the given name.
+	Focus(name string)
+	// Next switches to the next workspace on the focused output.
+	Next()
+	// Previous switches to the previous workspace on the focused output.
+	Previous()
+}
+
+// Module is the public interface for a workspaces module.
+// In addition to bar.Module, it also provides an expanded OnClick,
+// which allows click handlers to switch workspaces.
+type Module interface {
+	base.Module
+
+	// ForOutput limits the workspaces shown to those on the named output.
+	ForOutput(string) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// OnClick sets a click handler for the module.
+	OnClick(func(Info, Controller, bar.Event)) Module
+}
+
+type module struct {
+	*base.Base
+	ipc        i3ipc.Client
+	output     string
+	outputFunc func(Info) bar.Output
+	info       Info
+	mode       string
+}
+
+// I3 constructs an instance of the workspaces module for i3.
+func I3() Module {
+	return newModule(i3ipc.I3())
+}
+
+// Sway constructs an instance of the workspaces module for sway.
+func Sway() Module {
+	return newModule(i3ipc.Sway())
+}
+
+func newModule(c i3ipc.Client) *module {
+	m := &module{
+		Base: base.New(),
+		ipc:  c,
+		mode: "default",
+	}
+	// Set default click handler in New(), can be overridden later.
+	m.OnClick(DefaultClickHandler)
+	m.OutputFunc(DefaultOutput)
+	m.OnUpdate(m.update)
+	return m
+}
+
+// DefaultOutput shows each workspace as a segment, using the workspace
+// name as the instance, so click handlers can identify the clicked workspace.
+// The binding mode is shown as an urgent segment without an instance, only
+// if it is not the default.
+func DefaultOutput(i Info) bar.Output {
+	out := outputs.Multi()
+	for _, w := range i.Workspaces {
+		segment := outputs.Text(w.Name).Color(workspaceColor(w))
+		if w.Urgent {
+			segment.Urgent(true)
+		}
+		out.Add(w.Name, segment)
+	}
+	if i.Mode != "" && i.Mode != "default" {
+		out.Add("", outputs.Text(i.Mode).Urgent(true))
+	}
+	return out.KeepSeparators(true).Build()
+}
+
+func workspaceColor(w Workspace) bar.Color {
+	switch {
+	case w.Focused:
+		return colors.Scheme("focused")
+	case w.Visible:
+		return colors.Scheme("visible")
+	}
+	return colors.Scheme("inactive")
+}
+
+// DefaultClickHandler switches to the clicked workspace on left click,
+// and to the next or previous workspace on scroll.
+func DefaultClickHandler(i Info, c Controller, e bar.Event) {
+	switch e.Button {
+	case bar.ButtonLeft:
+		if e.Instance != "" {
+			c.Focus(e.Instance)
+		}
+	case bar.ScrollDown, bar.ScrollRight:
+		c.Next()
+	case bar.ScrollUp, bar.ScrollLeft:
+		c.Previous()
+	}
+}
+
+func (m *module) ForOutput(output string) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.output = output
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) OnClick(f func(Info, Controller, bar.Event)) Module {
+	if f == nil {
+		m.Base.OnClick(nil)
+		return m
+	}
+	m.Base.OnClick(func(e bar.Event) {
+		m.Lock()
+		info := m.info
+		m.Unlock()
+		f(info, m, e)
+	})
+	return m
+}
+
+func (m *module) Focus(name string) {
+	// The workspace event will update the module.
+	m.Error(m.ipc.Command("workspace " + strconv.Quote(name)))
+}
+
+func (m *module) Next() {
+	m.Error(m.ipc.Command("workspace next_on_output"))
+}
+
+func (m *module) Previous() {
+	m.Error(m.ipc.Command("workspace prev_on_output"))
+}
+
+// Stream subscribes to workspace and mode events, and then returns the
+// output channel from the base module.
+func (m *module) Stream() <-chan bar.Output {
+	// Older versions of i3 do not support querying the binding mode,
+	// so errors are ignored, and the mode will be updated by events.
+	// This happens before starting, so that the initial output has the mode.
+	var state struct{ Name string }
+	if err := m.ipc.Call(i3ipc.GetBindingState, "", &state); err == nil && state.Name != "" {
+		m.setMode(state.Name)
+	}
+	ch := m.Base.Stream()
+	go func() {
+		m.Error(m.ipc.Subscribe([]string{"workspace", "mode"}, m.handleEvent))
+	}()
+	return ch
+}
+
+func (m *module) handleEvent(msgType uint32, payload []byte) {
+	switch msgType {
+	case i3ipc.WorkspaceEvent:
+		m.Update()
+	case i3ipc.ModeEvent:
+		var event struct{ Change string }
+		if json.Unmarshal(payload, &event) == nil {
+			m.setMode(event.Change)
+		}
+	}
+}
+
+func (m *module) setMode(mode string) {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.mode = mode
+}
+
+func (m *module) update() {
+	var workspaces []Workspace
+	if m.Error(m.ipc.Call(i3ipc.GetWorkspaces, "", &workspaces)) {
+		return
+	}
+	m.Lock()
+	info := Info{Mode: m.mode}
+	for _, w := range workspaces {
+		if m.output == "" || w.Output == m.output {
+			info.Workspaces = append(info.Workspaces, w)
+		}
+	}
+	m.info = info
+	out := m.outputFunc(info)
+	m.Unlock()
+	m.Output(out)
+}
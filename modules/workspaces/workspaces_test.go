@@ -0,0 +1,197 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspaces
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/i3ipc"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeI3 struct {
+	sync.Mutex
+	workspaces string
+	mode       string
+	events     chan event
+	commands   chan string
+}
+
+type event struct {
+	msgType uint32
+	payload string
+}
+
+func (f *fakeI3) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msgType, payload, err := i3ipc.ReadMessage(conn)
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case i3ipc.Subscribe:
+			i3ipc.WriteMessage(conn, i3ipc.Subscribe, `{"success": true}`)
+			for e := range f.events {
+				i3ipc.WriteMessage(conn, e.msgType, e.payload)
+			}
+			return
+		case i3ipc.GetWorkspaces:
+			f.Lock()
+			workspaces := f.workspaces
+			f.Unlock()
+			i3ipc.WriteMessage(conn, i3ipc.GetWorkspaces, workspaces)
+		case i3ipc.GetBindingState:
+			f.Lock()
+			mode := f.mode
+			f.Unlock()
+			i3ipc.WriteMessage(conn, i3ipc.GetBindingState, `{"name": "`+mode+`"}`)
+		case i3ipc.RunCommand:
+			f.commands <- string(payload)
+			i3ipc.WriteMessage(conn, i3ipc.RunCommand, `[{"success": true}]`)
+		}
+	}
+}
+
+// listen starts a fake i3 IPC server, and returns the socket path.
+func (f *fakeI3) listen(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "i3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "ipc.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return sock, func() {
+		close(f.events)
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestI3(t *testing.T) {
+	assert := assert.New(t)
+	colors.LoadFromMap(map[string]string{
+		"focused":  "#ffffff",
+		"visible":  "#aaaaaa",
+		"inactive": "#555555",
+	})
+	i3 := &fakeI3{
+		workspaces: `[
+			{"num": 1, "name": "1", "output": "DP-1", "focused": true, "visible": true},
+			{"num": 2, "name": "2: www", "output": "DP-1"},
+			{"num": -1, "name": "mail", "output": "HDMI-1", "visible": true, "urgent": true}]`,
+		mode:     "default",
+		events:   make(chan event),
+		commands: make(chan string, 10),
+	}
+	sock, cleanup := i3.listen(t)
+	defer cleanup()
+	os.Setenv("I3SOCK", sock)
+
+	w := I3()
+	tester := testModule.NewOutputTester(t, w)
+	out := tester.AssertOutput("on start")
+	assert.Equal(3, len(out))
+	assert.Equal(bar.NewSegment("1").Instance("1").Color(colors.Hex("#ffffff")), out[0])
+	assert.Equal(bar.NewSegment("2: www").Instance("2: www").Color(colors.Hex("#555555")), out[1])
+	assert.Equal(bar.NewSegment("mail").Instance("mail").Color(colors.Hex("#aaaaaa")).Urgent(true), out[2])
+
+	i3.Lock()
+	i3.workspaces = `[{"num": 2, "name": "2: www", "output": "DP-1", "focused": true}]`
+	i3.Unlock()
+	i3.events <- event{i3ipc.WorkspaceEvent, `{"change": "focus"}`}
+	out = tester.AssertOutput("on workspace event")
+	assert.Equal(1, len(out))
+	assert.Equal("2: www", out[0].Text())
+
+	w.Click(bar.Event{Button: bar.ButtonLeft, Instance: "2: www"})
+	assert.Equal(`workspace "2: www"`, <-i3.commands)
+	w.Click(bar.Event{Button: bar.ButtonRight, Instance: "2: www"})
+	assert.Empty(i3.commands)
+	w.Click(bar.Event{Button: bar.ScrollDown, Instance: "2: www"})
+	assert.Equal("workspace next_on_output", <-i3.commands)
+	w.Click(bar.Event{Button: bar.ScrollUp})
+	assert.Equal("workspace prev_on_output", <-i3.commands)
+
+	var info Info
+	w.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return nil
+	})
+	tester.AssertOutput("on output func change")
+	focused, ok := info.Focused()
+	assert.True(ok)
+	assert.Equal(Workspace{Num: 2, Name: "2: www", Output: "DP-1", Focused: true}, focused)
+	_, ok = Info{}.Focused()
+	assert.False(ok)
+}
+
+func TestSway(t *testing.T) {
+	assert := assert.New(t)
+	sway := &fakeI3{
+		workspaces: `[
+			{"num": 1, "name": "1", "output": "eDP-1", "focused": true, "visible": true},
+			{"num": 2, "name": "2", "output": "DP-2", "visible": true},
+			{"num": 3, "name": "3", "output": "eDP-1"}]`,
+		mode:     "resize",
+		events:   make(chan event),
+		commands: make(chan string, 10),
+	}
+	sock, cleanup := sway.listen(t)
+	defer cleanup()
+	os.Setenv("SWAYSOCK", sock)
+
+	w := Sway().ForOutput("eDP-1")
+	tester := testModule.NewOutputTester(t, w)
+	out := tester.AssertOutput("on start")
+	assert.Equal(3, len(out), "workspaces on output, and mode")
+	assert.Equal("1", out[0].Text())
+	assert.Equal("3", out[1].Text())
+	assert.Equal(bar.NewSegment("resize").Instance("").Urgent(true), out[2])
+
+	sway.events <- event{i3ipc.ModeEvent, `{"change": "default", "pango_markup": false}`}
+	out = tester.AssertOutput("on mode change")
+	assert.Equal(2, len(out), "default mode is not shown")
+
+	w.ForOutput("DP-2")
+	out = tester.AssertOutput("on output change")
+	assert.Equal(1, len(out))
+	assert.Equal("2", out[0].Text())
+
+	os.Setenv("SWAYSOCK", "")
+	tester = testModule.NewOutputTester(t, Sway())
+	tester.AssertError("without sway socket")
+}
@@ -0,0 +1,261 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package zfs provides an i3bar module that shows the health of ZFS pools.
+
+Pool health and capacity are read using "zpool list", and scrub progress from
+"zpool status". By default the module shows the capacity of each pool, its
+health if it is not online, and the progress of any running scrub. The module
+is urgent when any pool is unhealthy, e.g. DEGRADED or FAULTED.
+*/
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/format"
+	"github.com/soumya92/barista/outputs"
+)
+
+// Bytes represents a size in bytes.
+type Bytes uint64
+
+// In gets the size in a specific unit, e.g. "b" or "MB".
+func (b Bytes) In(unit string) float64 {
+	base, err := humanize.ParseBytes("1" + unit)
+	if err != nil {
+		base = 1
+	}
+	return float64(b) / float64(base)
+}
+
+// IEC returns the size formatted in base 2.
+func (b Bytes) IEC() string {
+	return format.IBytes(uint64(b))
+}
+
+// SI returns the size formatted in base 10.
+func (b Bytes) SI() string {
+	return format.Bytes(uint64(b))
+}
+
+// Pool represents the state of a single ZFS pool.
+type Pool struct {
+	Name string
+	// Health is the pool health, e.g. "ONLINE", "DEGRADED", or "FAULTED".
+	Health    string
+	Size      Bytes
+	Allocated Bytes
+	Free      Bytes
+	// Capacity is the percentage of the pool that is allocated.
+	Capacity int
+	// Scrubbing is true while a scrub is running, and ScrubProgress is
+	// its progress as a percentage.
+	Scrubbing     bool
+	ScrubProgress float64
+}
+
+// Healthy returns true if the pool is online.
+func (p Pool) Healthy() bool {
+	return p.Health == "ONLINE"
+}
+
+// Info represents the state of all monitored pools.
+type Info struct {
+	Pools []Pool
+}
+
+// Healthy returns true if all pools are online.
+func (i Info) Healthy() bool {
+	for _, p := range i.Pools {
+		if !p.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+// Unhealthy returns true if any pool is not online.
+func (i Info) Unhealthy() bool {
+	return !i.Healthy()
+}
+
+// Module is the public interface for a zfs module.
+type Module interface {
+	base.WithClickHandler
+
+	// RefreshInterval configures the polling frequency.
+	RefreshInterval(time.Duration) Module
+
+	// OutputFunc configures a module to display the output of a user-defined function.
+	OutputFunc(func(Info) bar.Output) Module
+
+	// OutputTemplate configures a module to display the output of a template.
+	OutputTemplate(func(interface{}) bar.Output) Module
+
+	// UrgentWhen configures a module to mark its output as urgent based on a
+	// user-defined function.
+	UrgentWhen(func(Info) bool) Module
+}
+
+type module struct {
+	*base.Base
+	pools      []string
+	outputFunc func(Info) bar.Output
+	urgentFunc func(Info) bool
+}
+
+// New constructs an instance of the zfs module for the given pools,
+// or all imported pools if none are given.
+func New(pools ...string) Module {
+	m := &module{Base: base.New(), pools: pools}
+	m.RefreshInterval(time.Minute)
+	// Default output template is the capacity of each pool, its health if
+	// not online, and scrub progress if scrubbing.
+	m.OutputTemplate(outputs.TextTemplate(
+		`{{range $i, $p := .Pools}}{{if $i}} {{end}}{{$p.Name}} {{$p.Capacity}}%` +
+			`{{if not $p.Healthy}} {{$p.Health}}{{end}}` +
+			`{{if $p.Scrubbing}} scrub {{printf "%.0f" $p.ScrubProgress}}%{{end}}{{end}}`))
+	// Unhealthy pools need attention by default.
+	m.UrgentWhen(Info.Unhealthy)
+	m.OnUpdate(m.update)
+	return m
+}
+
+func (m *module) RefreshInterval(interval time.Duration) Module {
+	m.Schedule().Every(interval)
+	return m
+}
+
+func (m *module) OutputFunc(outputFunc func(Info) bar.Output) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.outputFunc = outputFunc
+	return m
+}
+
+func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
+	return m.OutputFunc(func(i Info) bar.Output {
+		return template(i)
+	})
+}
+
+func (m *module) UrgentWhen(urgentFunc func(Info) bool) Module {
+	m.Lock()
+	defer m.UnlockAndUpdate()
+	m.urgentFunc = urgentFunc
+	return m
+}
+
+// output runs a command and returns its output.
+var output = func(args ...string) ([]byte, error) {
+	return exec.Command(args[0], args[1:]...).Output()
+}
+
+// listPools reads the health and capacity of pools from "zpool list",
+// using scripted (-H) and parsable (-p) output.
+func listPools(names []string) ([]Pool, error) {
+	args := append([]string{"zpool", "list", "-H", "-p", "-o", "name,health,size,alloc,free,cap"}, names...)
+	out, err := output(args...)
+	if err != nil {
+		return nil, err
+	}
+	var pools []Pool
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) != 6 {
+			continue
+		}
+		p := Pool{Name: fields[0], Health: fields[1]}
+		var values [3]uint64
+		for i, f := range fields[2:5] {
+			// Unavailable pools have "-" for sizes.
+			values[i], _ = strconv.ParseUint(f, 10, 64)
+		}
+		p.Size, p.Allocated, p.Free = Bytes(values[0]), Bytes(values[1]), Bytes(values[2])
+		p.Capacity, _ = strconv.Atoi(strings.TrimSuffix(fields[5], "%"))
+		pools = append(pools, p)
+	}
+	return pools, s.Err()
+}
+
+var scrubDoneRegexp = regexp.MustCompile(`([0-9.]+)% done`)
+
+// scrubProgress returns the progress of running scrubs by pool name,
+// from "zpool status".
+func scrubProgress(names []string) (map[string]float64, error) {
+	out, err := output(append([]string{"zpool", "status"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	progress := map[string]float64{}
+	pool := ""
+	scrubbing := false
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(line, "pool:"):
+			pool = strings.TrimSpace(strings.TrimPrefix(line, "pool:"))
+			scrubbing = false
+		case strings.HasPrefix(line, "scan:"):
+			scrubbing = strings.Contains(line, "scrub in progress")
+			if scrubbing {
+				progress[pool] = 0
+			}
+		case strings.HasPrefix(line, "config:"):
+			scrubbing = false
+		case scrubbing:
+			// The percentage is on one of the lines following "scan:".
+			if match := scrubDoneRegexp.FindStringSubmatch(line); match != nil {
+				progress[pool], _ = strconv.ParseFloat(match[1], 64)
+			}
+		}
+	}
+	return progress, s.Err()
+}
+
+func (m *module) update() {
+	pools, err := listPools(m.pools)
+	if m.Error(err) {
+		return
+	}
+	progress, err := scrubProgress(m.pools)
+	if m.Error(err) {
+		return
+	}
+	for i, p := range pools {
+		pools[i].ScrubProgress, pools[i].Scrubbing = progress[p.Name]
+	}
+	info := Info{Pools: pools}
+	m.Lock()
+	out := m.outputFunc(info)
+	if m.urgentFunc != nil {
+		out.Urgent(m.urgentFunc(info))
+	}
+	m.Unlock()
+	m.Output(out)
+}
@@ -0,0 +1,187 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+type fakeZpool struct {
+	sync.Mutex
+	list   string
+	status string
+	err    error
+	args   []string
+}
+
+func (f *fakeZpool) output(args ...string) ([]byte, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.args = append(f.args, strings.Join(args, " "))
+	if f.err != nil {
+		return nil, f.err
+	}
+	if args[1] == "list" {
+		return []byte(f.list), nil
+	}
+	return []byte(f.status), nil
+}
+
+func (f *fakeZpool) set(list, status string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.list, f.status, f.err = list, status, err
+}
+
+const healthyList = "tank\tONLINE\t4000000000000\t1000000000000\t3000000000000\t25\n" +
+	"backup\tONLINE\t2000000000000\t1800000000000\t200000000000\t90\n"
+
+const healthyStatus = `  pool: backup
+ state: ONLINE
+  scan: scrub repaired 0B in 01:02:03 with 0 errors on Sun Jan 14 01:26:04 2018
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  sdc       ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: tank
+ state: ONLINE
+  scan: scrub in progress since Sun Jan 14 00:24:01 2018
+	1.23T scanned at 1.2G/s, 800G issued at 500M/s, 2.00T total
+	0B repaired, 39.06% done, 00:40:00 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  mirror-0  ONLINE       0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     ONLINE       0     0     0
+
+errors: No known data errors
+`
+
+func TestParsing(t *testing.T) {
+	assert := assert.New(t)
+	f := &fakeZpool{}
+	output = f.output
+	f.set(healthyList+"old\tUNAVAIL\t-\t-\t-\t-\n", healthyStatus, nil)
+
+	pools, err := listPools([]string{"tank", "backup", "old"})
+	assert.NoError(err)
+	assert.Equal([]Pool{
+		{Name: "tank", Health: "ONLINE", Size: 4000000000000,
+			Allocated: 1000000000000, Free: 3000000000000, Capacity: 25},
+		{Name: "backup", Health: "ONLINE", Size: 2000000000000,
+			Allocated: 1800000000000, Free: 200000000000, Capacity: 90},
+		{Name: "old", Health: "UNAVAIL"},
+	}, pools)
+	assert.Equal("zpool list -H -p -o name,health,size,alloc,free,cap tank backup old", f.args[0])
+
+	progress, err := scrubProgress(nil)
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"tank": 39.06}, progress)
+	assert.Equal("zpool status", f.args[1])
+
+	f.set("", `  pool: tank
+ state: ONLINE
+  scan: scrub in progress since Sun Jan 14 00:24:01 2018
+    3.45G scanned out of 10.2G at 100M/s, 0h1m to go
+    0 repaired, 33.80% done
+config:
+`, nil)
+	progress, err = scrubProgress(nil)
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"tank": 33.8}, progress, "older zfs versions")
+
+	assert.InDelta(4000.0, Bytes(4000000000000).In("GB"), 0.001)
+	assert.Equal("3.6 TiB", Bytes(4000000000000).IEC())
+	assert.Equal("4.0 TB", Bytes(4000000000000).SI())
+}
+
+func TestModule(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	f := &fakeZpool{}
+	output = f.output
+	f.set(healthyList, healthyStatus, nil)
+
+	z := New()
+	tester := testModule.NewOutputTester(t, z)
+	out := tester.AssertOutput("on start")
+	assert.Equal("tank 25% scrub 39% backup 90%", out[0].Text())
+	assert.Equal(false, out[0]["urgent"])
+
+	f.set("tank\tDEGRADED\t4000000000000\t1000000000000\t3000000000000\t25\n", `  pool: tank
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.
+  scan: resilvered 1.2G in 00:01:00 with 0 errors on Sun Jan 14 01:00:00 2018
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     0
+	  mirror-0  DEGRADED     0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     UNAVAIL      0     0     0
+`, nil)
+	scheduler.AdvanceBy(time.Minute)
+	out = tester.AssertOutput("on refresh")
+	assert.Equal("tank 25% DEGRADED", out[0].Text())
+	assert.Equal(true, out[0]["urgent"], "degraded pool")
+
+	var info Info
+	z.OutputFunc(func(i Info) bar.Output {
+		info = i
+		return outputs.Text(i.Pools[0].Allocated.IEC())
+	})
+	out = tester.AssertOutput("on output func change")
+	assert.Equal("931 GiB", out[0].Text())
+	assert.False(info.Healthy())
+	assert.False(info.Pools[0].Scrubbing)
+
+	z.UrgentWhen(nil)
+	out = tester.AssertOutput("on urgent func change")
+	_, ok := out[0]["urgent"]
+	assert.False(ok)
+
+	pools := New("tank", "backup")
+	tester = testModule.NewOutputTester(t, pools)
+	tester.AssertOutput("on start")
+	f.Lock()
+	args := f.args[len(f.args)-2:]
+	f.Unlock()
+	assert.Equal([]string{
+		"zpool list -H -p -o name,health,size,alloc,free,cap tank backup",
+		"zpool status tank backup",
+	}, args)
+
+	f.set("", "", errors.New("exit status 1"))
+	scheduler.AdvanceBy(time.Minute)
+	tester.AssertError("on zpool error")
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package notify sends desktop notifications using the
+org.freedesktop.Notifications d-bus API, for modules that want to show a toast
+for significant events (battery critical, timer finished, build failed) in
+addition to changing their output.
+
+Each Notifier is rate limited, so that a flapping condition does not flood the
+desktop with notifications, and by default replaces its previous notification
+instead of adding another one.
+
+Typical usage would be:
+
+	var notifier = notify.New("build").RateLimit(time.Minute)
+	...
+	if build.Failed {
+		notifier.Notify(notify.Notification{
+			Summary: "Build failed",
+			Body:    build.Target,
+			Urgency: notify.Critical,
+		})
+	}
+*/
+package notify
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("notify")
+
+// Urgency is the urgency level of a notification.
+type Urgency byte
+
+// Urgency levels defined by the notification specification.
+const (
+	Low Urgency = iota
+	Normal
+	Critical
+)
+
+// Notification represents a single desktop notification.
+type Notification struct {
+	Summary string
+	Body    string
+	// Icon is an icon name from the desktop's icon theme, or a file:// URI.
+	Icon    string
+	Urgency Urgency
+	// Timeout is how long the notification is shown for,
+	// or 0 to use the notification daemon's default.
+	Timeout time.Duration
+}
+
+// ErrRateLimited is returned when a notification is dropped because the
+// notifier has sent another notification too recently.
+var ErrRateLimited = errors.New("notify: rate limited")
+
+// Notifier sends desktop notifications for an application.
+type Notifier struct {
+	appName string
+
+	mutex    sync.Mutex
+	interval time.Duration
+	replace  bool
+	sent     bool
+	lastSent time.Time
+	lastID   uint32
+}
+
+// New constructs a notifier that sends notifications as the given
+// application, allowing at most one notification every 10 seconds.
+func New(appName string) *Notifier {
+	return &Notifier{
+		appName:  appName,
+		interval: 10 * time.Second,
+		replace:  true,
+	}
+}
+
+// RateLimit sets the minimum interval between notifications. Notifications
+// sent more frequently are dropped. An interval of 0 disables rate limiting.
+func (n *Notifier) RateLimit(interval time.Duration) *Notifier {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.interval = interval
+	return n
+}
+
+// Replace controls whether each notification replaces the previous one sent
+// by this notifier (if it is still shown), or is added alongside it.
+func (n *Notifier) Replace(replace bool) *Notifier {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.replace = replace
+	return n
+}
+
+// Send sends a notification with normal urgency.
+func (n *Notifier) Send(summary, body string) error {
+	return n.Notify(Notification{Summary: summary, Body: body, Urgency: Normal})
+}
+
+// Notify sends a notification, returning ErrRateLimited if it was dropped.
+func (n *Notifier) Notify(note Notification) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	now := scheduler.Now()
+	if n.sent && now.Sub(n.lastSent) < n.interval {
+		log.Fine("rate limited", "app", n.appName, "summary", note.Summary)
+		return ErrRateLimited
+	}
+	replaces := uint32(0)
+	if n.replace {
+		replaces = n.lastID
+	}
+	id, err := send(n.appName, replaces, note)
+	if err != nil {
+		log.Error("notification failed", "app", n.appName, "err", err)
+		return err
+	}
+	n.sent = true
+	n.lastSent = now
+	n.lastID = id
+	return nil
+}
+
+// Constants for the notifications d-bus API.
+const (
+	notifyDest  = "org.freedesktop.Notifications"
+	notifyPath  = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notifyIface = "org.freedesktop.Notifications"
+)
+
+// send delivers a notification and returns its id. It is a variable so that
+// tests can capture notifications without a session bus.
+var send = func(appName string, replaces uint32, note Notification) (uint32, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return 0, err
+	}
+	timeout := int32(-1)
+	if note.Timeout > 0 {
+		timeout = int32(note.Timeout / time.Millisecond)
+	}
+	hints := map[string]dbus.Variant{"urgency": dbus.MakeVariant(byte(note.Urgency))}
+	var id uint32
+	err = conn.Object(notifyDest, notifyPath).Call(notifyIface+".Notify", 0,
+		appName, replaces, note.Icon, note.Summary, note.Body,
+		[]string{}, hints, timeout).Store(&id)
+	return id, err
+}
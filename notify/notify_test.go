@@ -0,0 +1,92 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+type sent struct {
+	appName  string
+	replaces uint32
+	Notification
+}
+
+// capture replaces the d-bus call, and returns a function that returns
+// the notifications sent since the last call.
+func capture(t *testing.T, err error) func() []sent {
+	var notes []sent
+	id := uint32(0)
+	old := send
+	t.Cleanup(func() { send = old })
+	send = func(appName string, replaces uint32, note Notification) (uint32, error) {
+		if err != nil {
+			return 0, err
+		}
+		notes = append(notes, sent{appName, replaces, note})
+		id++
+		return id, nil
+	}
+	return func() []sent {
+		n := notes
+		notes = nil
+		return n
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	scheduler.TestMode(true)
+	notes := capture(t, nil)
+
+	n := New("test")
+	assert.NoError(n.Send("first", "body"))
+	assert.Equal([]sent{{"test", 0, Notification{Summary: "first", Body: "body", Urgency: Normal}}}, notes())
+
+	assert.Equal(ErrRateLimited, n.Send("second", ""))
+	scheduler.AdvanceBy(5 * time.Second)
+	assert.Equal(ErrRateLimited, n.Notify(Notification{Summary: "third", Urgency: Critical}))
+	assert.Empty(notes(), "rate limited")
+
+	scheduler.AdvanceBy(5 * time.Second)
+	assert.NoError(n.Notify(Notification{Summary: "fourth", Urgency: Critical}))
+	s := notes()
+	assert.Equal(1, len(s))
+	assert.Equal("fourth", s[0].Summary)
+	assert.Equal(uint32(1), s[0].replaces, "replaces the previous notification")
+
+	n.RateLimit(0).Replace(false)
+	assert.NoError(n.Send("fifth", ""))
+	assert.NoError(n.Send("sixth", ""))
+	s = notes()
+	assert.Equal(2, len(s), "no rate limit")
+	assert.Equal(uint32(0), s[1].replaces, "not replacing")
+}
+
+func TestError(t *testing.T) {
+	scheduler.TestMode(true)
+	fail := errors.New("no session bus")
+	capture(t, fail)
+	n := New("test")
+	assert.Equal(t, fail, n.Send("first", ""))
+	capture(t, nil)
+	assert.NoError(t, n.Send("again", ""), "failed notifications are not rate limited")
+}
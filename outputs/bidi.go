@@ -0,0 +1,72 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outputs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Unicode bidi control characters used to isolate text.
+const (
+	firstStrongIsolate = "\u2068"
+	popDirIsolate      = "\u2069"
+)
+
+// rtlScripts are the scripts written right-to-left.
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Adlam,
+	unicode.Arabic,
+	unicode.Hanifi_Rohingya,
+	unicode.Hebrew,
+	unicode.Mandaic,
+	unicode.Mende_Kikakui,
+	unicode.Nko,
+	unicode.Samaritan,
+	unicode.Syriac,
+	unicode.Thaana,
+	unicode.Yezidi,
+}
+
+// rtlMarks are the bidi control characters that start right-to-left text:
+// the right-to-left mark, embedding, override, and isolate.
+const rtlMarks = "\u200f\u202b\u202e\u2067"
+
+// HasRTL returns true if the text contains any right-to-left characters,
+// e.g. Arabic or Hebrew letters.
+func HasRTL(text string) bool {
+	for _, r := range text {
+		if unicode.IsOneOf(rtlScripts, r) || strings.ContainsRune(rtlMarks, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Isolate wraps text that contains right-to-left characters in bidi
+// isolation marks, so that it is laid out in its own direction without
+// reordering the text around it. For example, an Arabic song title
+// between an icon and a duration keeps the icon on the left and the
+// duration on the right. Text without right-to-left characters is
+// returned unchanged.
+//
+// Isolate is also available in templates as "isolate", e.g.
+// {{.Title | isolate}} - {{.Artist | isolate}}.
+func Isolate(text string) string {
+	if !HasRTL(text) {
+		return text
+	}
+	return firstStrongIsolate + text + popDirIsolate
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestHasRTL(t *testing.T) {
+	assert.False(t, HasRTL(""))
+	assert.False(t, HasRTL("Bohemian Rhapsody"))
+	assert.False(t, HasRTL("Привет, 世界"), "left-to-right scripts")
+	assert.True(t, HasRTL("שלום"), "hebrew")
+	assert.True(t, HasRTL("track: أغنية"), "arabic in latin text")
+	assert.True(t, HasRTL("ab\u200fc"), "right-to-left mark")
+}
+
+func TestIsolate(t *testing.T) {
+	assert.Equal(t, "Bohemian Rhapsody", Isolate("Bohemian Rhapsody"),
+		"left-to-right text is unchanged")
+	assert.Equal(t, "\u2068שלום 42\u2069", Isolate("שלום 42"))
+
+	tpl := TextTemplate(`♪ {{.Title | isolate}} - {{.Artist | isolate}}`)
+	out := tpl(map[string]string{"Title": "أغنية", "Artist": "Someone"})
+	assert.Equal(t, "♪ \u2068أغنية\u2069 - Someone", textOf(out))
+
+	pangoTpl := PangoTemplate(`<b>{{.Title | isolate}}</b>`)
+	out = pangoTpl(map[string]string{"Title": "<שלום>"})
+	assert.Equal(t, "<b>\u2068&lt;שלום&gt;\u2069</b>", textOf(out),
+		"isolated text is still escaped")
+}
@@ -19,9 +19,14 @@ import (
 	"bytes"
 	"fmt"
 	htmlTemplate "html/template"
+	"math"
+	"reflect"
 	textTemplate "text/template"
+	"time"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/format"
+	"github.com/soumya92/barista/locale"
 	"github.com/soumya92/barista/pango"
 )
 
@@ -70,9 +75,104 @@ func Pango(things ...interface{}) bar.Output {
 	return PangoUnsafe(pango.Span(things...).Pango())
 }
 
+// TemplateFuncs returns the functions available in templates created using
+// TextTemplate and PangoTemplate, which format values for the current locale:
+//
+//	number: formats a number with the given precision, e.g. {{.Min1 | number 2}}.
+//	date: formats a time using a layout, e.g. {{.Now | date "Monday 2 January"}}.
+//	bytes, ibytes: format a size in SI or IEC units, e.g. {{.Used | ibytes}}.
+//	bits: formats a number of bits in SI units, e.g. {{.Rate | bits}}.
+//	celsius, fahrenheit: format a temperature in degrees celsius.
+//	duration: formats a duration compactly, e.g. {{.Remaining | duration}}.
+//	plural: formats a count with a word, e.g. {{.Count | plural "update" "updates"}}.
+//	ordinal: formats an ordinal number, e.g. "2nd" for {{.Day | ordinal}}.
+//	humanizeNumber: formats a count compactly, e.g. "1.2k" for {{.Stars | humanizeNumber}}.
+//	pad: right-aligns a value to a minimum width, e.g. {{.Load | number 1 | pad 5}}.
+//	isolate: isolates right-to-left text from its surroundings, see Isolate.
+//
+// See the format package for details.
+func TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"number": func(precision int, value interface{}) (string, error) {
+			f, err := toFloat("number", value)
+			return locale.Current().FormatFloat(f, precision), err
+		},
+		"date": func(layout string, t time.Time) string {
+			return locale.Current().FormatTime(t, layout)
+		},
+		"bytes":      sizeFunc("bytes", format.Bytes),
+		"ibytes":     sizeFunc("ibytes", format.IBytes),
+		"bits":       sizeFunc("bits", format.Bits),
+		"celsius":    tempFunc("celsius", format.Celsius),
+		"fahrenheit": tempFunc("fahrenheit", format.Fahrenheit),
+		"duration": func(d time.Duration) string {
+			return format.Duration(d, time.Second)
+		},
+		"plural": func(one, other string, value interface{}) (string, error) {
+			f, err := toFloat("plural", value)
+			return count(f) + " " + locale.Current().Plural(f, one, other), err
+		},
+		"ordinal": func(value interface{}) (string, error) {
+			f, err := toFloat("ordinal", value)
+			return locale.Current().FormatOrdinal(int64(f)), err
+		},
+		"humanizeNumber": func(value interface{}) (string, error) {
+			f, err := toFloat("humanizeNumber", value)
+			return format.Compact(f), err
+		},
+		"pad": func(width int, value interface{}) string {
+			return format.Pad(fmt.Sprint(value), width)
+		},
+		"isolate": func(value interface{}) string {
+			return Isolate(fmt.Sprint(value))
+		},
+	}
+}
+
+// count formats a count for plural, as an integer if it has no fraction.
+func count(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return locale.Current().FormatInt(int64(f))
+	}
+	return format.Number(f)
+}
+
+// sizeFunc adapts a size format function to accept any numeric value.
+func sizeFunc(name string, fn func(uint64) string) interface{} {
+	return func(value interface{}) (string, error) {
+		f, err := toFloat(name, value)
+		if err == nil && f < 0 {
+			err = fmt.Errorf("%s: expected a non-negative number, got %v", name, value)
+		}
+		return fn(uint64(f)), err
+	}
+}
+
+// tempFunc adapts a temperature format function to accept any numeric value.
+func tempFunc(name string, fn func(float64) string) interface{} {
+	return func(value interface{}) (string, error) {
+		f, err := toFloat(name, value)
+		return fn(f), err
+	}
+}
+
+// toFloat converts any numeric value to a float64 for formatting.
+func toFloat(name string, value interface{}) (float64, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+	return 0, fmt.Errorf("%s: expected a number, got %v", name, value)
+}
+
 // TextTemplate creates a TemplateFunc from the given text template.
 func TextTemplate(tpl string) TemplateFunc {
-	t := textTemplate.Must(textTemplate.New("text").Parse(tpl))
+	t := textTemplate.Must(textTemplate.New("text").Funcs(TemplateFuncs()).Parse(tpl))
 	return func(arg interface{}) bar.Output {
 		var out bytes.Buffer
 		if err := t.Execute(&out, arg); err != nil {
@@ -85,7 +185,7 @@ func TextTemplate(tpl string) TemplateFunc {
 // PangoTemplate creates a TemplateFunc from the given pango template.
 // It uses go's html/template to escape input properly.
 func PangoTemplate(tpl string) TemplateFunc {
-	t := htmlTemplate.Must(htmlTemplate.New("pango").Parse(tpl))
+	t := htmlTemplate.Must(htmlTemplate.New("pango").Funcs(TemplateFuncs()).Parse(tpl))
 	return func(arg interface{}) bar.Output {
 		var out bytes.Buffer
 		if err := t.Execute(&out, arg); err != nil {
@@ -18,10 +18,12 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchrcom/testify/assert"
 
 	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/locale"
 	"github.com/soumya92/barista/pango"
 )
 
@@ -154,6 +156,63 @@ func TestPangoTemplate(t *testing.T) {
 	}
 }
 
+func TestTemplateFuncs(t *testing.T) {
+	defer locale.SetLocale(locale.English)
+	when := time.Date(2017, time.March, 5, 14, 30, 0, 0, time.UTC)
+	text := TextTemplate(`{{.Fraction | number 2}} {{.Number | number 1}} {{.When | date "Mon 2 Jan"}}`)
+	pango := PangoTemplate(`<b>{{.Fraction | number 2}}</b> {{.When | date "January"}}`)
+	arg := map[string]interface{}{"Fraction": 2.71828, "Number": uint8(42), "When": when}
+	assert.Equal(t, "2.72 42.0 Sun 5 Mar", textOf(text(arg)), "english")
+	assert.Equal(t, "<b>2.72</b> March", textOf(pango(arg)), "english")
+	locale.Set("de")
+	assert.Equal(t, "2,72 42,0 So 5 Mär", textOf(text(arg)), "localized")
+	assert.Equal(t, "<b>2,72</b> März", textOf(pango(arg)), "localized")
+
+	out := TextTemplate(`{{.Text | number 2}}`)(testObject)
+	assert.Contains(t, textOf(out), "expected a number", "non-numeric value")
+
+	padded := TextTemplate(`{{.Number | pad 4}}|{{.Fraction | number 1 | pad 4}}`)
+	assert.Equal(t, "\u2007\u200742|\u20072,7", textOf(padded(arg)), "padded")
+}
+
+func TestUnitTemplateFuncs(t *testing.T) {
+	defer locale.SetLocale(locale.English)
+	type size uint64
+	units := TextTemplate(`{{.Size | ibytes}} {{.Size | bytes}} {{.Rate | bits}} ` +
+		`{{.Temp | celsius}} {{.Temp | fahrenheit}} {{.Left | duration}}`)
+	arg := map[string]interface{}{
+		"Size": size(1536), "Rate": 12345678, "Temp": 45.2,
+		"Left": 12*time.Minute + 5*time.Second,
+	}
+	assert.Equal(t, "1.5 KiB 1.5 kB 12 Mb 45°C 113°F 12m 5s", textOf(units(arg)))
+	locale.Set("fr")
+	assert.Equal(t, "1,5 Kio 1,5 ko 12 Mb 45°C 113°F 12m 5s", textOf(units(arg)))
+
+	out := TextTemplate(`{{.Number | ibytes}}`)(map[string]int{"Number": -1})
+	assert.Contains(t, textOf(out), "non-negative", "negative size")
+	out = TextTemplate(`{{.Text | celsius}}`)(testObject)
+	assert.Contains(t, textOf(out), "expected a number", "non-numeric value")
+}
+
+func TestCountTemplateFuncs(t *testing.T) {
+	defer locale.SetLocale(locale.English)
+	counts := TextTemplate(`{{.Updates | plural "update" "updates"}}, ` +
+		`{{.Day | ordinal}}, {{.Stars | humanizeNumber}}`)
+	arg := map[string]interface{}{"Updates": 1, "Day": uint8(22), "Stars": 1234}
+	assert.Equal(t, "1 update, 22nd, 1.2k", textOf(counts(arg)))
+	arg = map[string]interface{}{"Updates": 1234, "Day": 3, "Stars": 999}
+	assert.Equal(t, "1,234 updates, 3rd, 999", textOf(counts(arg)))
+	arg = map[string]interface{}{"Updates": 0, "Day": 1, "Stars": 5.6e6}
+	assert.Equal(t, "0 updates, 1st, 5.6M", textOf(counts(arg)))
+	locale.Set("fr")
+	assert.Equal(t, "0 mise à jour, 1er, 5,6M",
+		textOf(TextTemplate(`{{.Updates | plural "mise à jour" "mises à jour"}}, `+
+			`{{.Day | ordinal}}, {{.Stars | humanizeNumber}}`)(arg)))
+
+	out := TextTemplate(`{{.Text | plural "a" "b"}}`)(testObject)
+	assert.Contains(t, textOf(out), "expected a number", "non-numeric value")
+}
+
 func TestComposite(t *testing.T) {
 	tests := []struct {
 		desc     string
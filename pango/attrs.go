@@ -306,3 +306,21 @@ func (l LetterSpacing) AttrValue() string {
 	// Pango spacing is 1/1024ths of a point.
 	return fmt.Sprintf("%d", int(float64(l)*1024))
 }
+
+// FontFeatures sets OpenType font features, as a comma-separated list of
+// feature tags, e.g. "tnum" or "smcp, liga=0". Requires pango 1.38.
+type FontFeatures string
+
+// TabularNumbers requests fixed-width digits, so that changing numbers do
+// not change the width of the text.
+var TabularNumbers Attribute = FontFeatures("tnum")
+
+// AttrName returns the name of the pango 'font_features' attribute.
+func (f FontFeatures) AttrName() string {
+	return "font_features"
+}
+
+// AttrValue returns the font features as a pango 'font_features' value.
+func (f FontFeatures) AttrValue() string {
+	return string(f)
+}
@@ -31,6 +31,7 @@ func TestBasicAttributes(t *testing.T) {
 		BgAlpha(1.0),
 		Rise(-100),
 		LetterSpacing(0.5),
+		FontFeatures("smcp, liga=0"),
 	).Pango()
 	assert.Equal(t, `<span`+
 		` face='monospace'`+
@@ -40,6 +41,7 @@ func TestBasicAttributes(t *testing.T) {
 		` background_alpha='65535'`+
 		` rise='-100'`+
 		` letter_spacing='512'`+
+		` font_features='smcp, liga=0'`+
 		`></span>`, out)
 }
 
@@ -52,6 +54,7 @@ func TestKeywordAttributes(t *testing.T) {
 		Condensed,
 		UnderlineError,
 		NoStrikethrough,
+		TabularNumbers,
 	).Pango()
 	assert.Equal(t, `<span`+
 		` size='small'`+
@@ -61,6 +64,7 @@ func TestKeywordAttributes(t *testing.T) {
 		` stretch='condensed'`+
 		` underline='error'`+
 		` strikethrough='false'`+
+		` font_features='tnum'`+
 		`></span>`, out)
 }
 
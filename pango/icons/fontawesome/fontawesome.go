@@ -36,12 +36,14 @@ func Icon(name string, style ...pango.Attribute) pango.Node {
 	return provider.Icon(name, style...)
 }
 
-// Load initialises the fontawesome icon provider from the given repo.
+// Load initialises the fontawesome icon provider from the given repo,
+// and registers it as "fontawesome" for use with icons.Icon.
 func Load(repoPath string) error {
 	c := icons.Config{
 		RepoPath: repoPath,
 		FilePath: "scss/_variables.scss",
 		Font:     "FontAwesome",
+		Cache:    "icons-fontawesome",
 	}
 	var err error
 	provider, err = c.LoadByLines(func(line string, add func(string, string)) error {
@@ -64,5 +66,8 @@ func Load(repoPath string) error {
 		add(name, sym)
 		return nil
 	})
+	if err == nil {
+		icons.Register("fontawesome", provider)
+	}
 	return err
 }
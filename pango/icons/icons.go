@@ -33,6 +33,18 @@ Example usage:
     material.Icon("today", colors.Hex("#ddd")),
     now.Sprintf("%H:%M"),
   )
+
+Loaded fonts are also registered by name, so that the icon set can be
+switched at runtime, falling back to text for icons that are missing:
+  icons.Register("text", icons.NewProvider(map[string]string{"today": "T"}))
+  icons.UseInstalled("material", "text")
+  ...
+  return pango.Span(icons.Icon("today"), now.Sprintf("%H:%M"))
+  ...
+  icons.Use("fontawesome", "text") // modules on base re-render.
+
+The supported fonts cache the parsed icons in the user's cache directory, so
+the source files are only parsed again when they change.
 */
 package icons
 
@@ -42,9 +54,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/afero"
 
+	"github.com/soumya92/barista/base/cache"
 	"github.com/soumya92/barista/pango"
 )
 
@@ -52,6 +66,7 @@ import (
 type Provider struct {
 	symbols map[string]string
 	attrs   []pango.Attribute
+	font    string
 }
 
 // Icon creates a pango span that renders the named icon.
@@ -86,7 +101,18 @@ type Config struct {
 	RepoPath string
 	FilePath string
 	Font     string
-	attrs    []pango.Attribute
+	// Cache, if set, is the key under which the parsed icons are cached,
+	// so that they are only parsed again when the source file changes.
+	Cache string
+	attrs []pango.Attribute
+}
+
+// cachedIcons is the on-disk cache of the icons parsed from a source file.
+type cachedIcons struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Symbols map[string]string
 }
 
 // Styles sets any default pango styles (e.g. weight, baseline)
@@ -104,7 +130,8 @@ var fs = afero.NewOsFs()
 // function an io.Reader for the source file, and a function to add
 // icons to the provider's map.
 func (c *Config) LoadFromFile(parseFile func(io.Reader, func(string, string)) error) (*Provider, error) {
-	f, err := fs.Open(filepath.Join(c.RepoPath, c.FilePath))
+	path := filepath.Join(c.RepoPath, c.FilePath)
+	f, err := fs.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -112,10 +139,29 @@ func (c *Config) LoadFromFile(parseFile func(io.Reader, func(string, string)) er
 	i := Provider{
 		symbols: make(map[string]string),
 		attrs:   append(c.attrs, pango.Font(c.Font)),
+		font:    c.Font,
+	}
+	var stat cachedIcons
+	if c.Cache != "" {
+		if info, err := f.Stat(); err == nil {
+			stat = cachedIcons{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+		}
+		var cached cachedIcons
+		_, ok := cache.Load(c.Cache, &cached)
+		if ok && cached.Path == stat.Path && cached.Size == stat.Size &&
+			cached.ModTime.Equal(stat.ModTime) && cached.Symbols != nil {
+			i.symbols = cached.Symbols
+			return &i, nil
+		}
 	}
 	err = parseFile(f, func(name, symbol string) {
 		i.symbols[name] = symbol
 	})
+	if err == nil && stat.Path != "" {
+		stat.Symbols = i.symbols
+		// Errors are ignored, since the icons can always be parsed again.
+		cache.Save(c.Cache, stat)
+	}
 	return &i, err
 }
 
@@ -17,7 +17,10 @@ package icons
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchrcom/testify/assert"
@@ -163,3 +166,106 @@ icon2
 		"additional attributes in Config are added to provider's output",
 	)
 }
+
+func TestRegistry(t *testing.T) {
+	changes := 0
+	defer OnChange(func() { changes++ })()
+
+	font := &Provider{
+		symbols: map[string]string{"music": "M", "home": "H"},
+		attrs:   []pango.Attribute{pango.Font("testfont")},
+		font:    "testfont",
+	}
+	Register("font", font)
+	Register("text", NewProvider(map[string]string{"music": "♪", "time": "T"}))
+	assert.Equal(t, 0, changes, "no change when registering unused providers")
+	assert.Equal(t, "", Icon("music").Pango(), "no icons before Use")
+
+	Use("font", "text")
+	assert.Equal(t, 1, changes, "change notified on Use")
+	assert.Equal(t, "<span face='testfont'>M</span>", Icon("music").Pango())
+	assert.Equal(t, "T", Icon("time").Pango(),
+		"falls back to later providers")
+	assert.Equal(t, "", Icon("unknown").Pango())
+
+	Use("text", "font")
+	assert.Equal(t, 2, changes)
+	assert.Equal(t, "<span color='#ff0000'>♪</span>",
+		Icon("music", colors.Hex("#f00")).Pango(), "order of preference")
+	assert.Equal(t, "<span face='testfont'>H</span>", Icon("home").Pango())
+
+	Register("text", NewProvider(map[string]string{"music": "m"}))
+	assert.Equal(t, 3, changes, "change notified when replacing a used provider")
+	assert.Equal(t, "m", Icon("music").Pango())
+
+	installed := map[string]bool{}
+	defer func(f func(string) bool) { fontInstalled = f }(fontInstalled)
+	fontInstalled = func(font string) bool { return installed[font] }
+	UseInstalled("font", "text", "missing")
+	assert.Equal(t, 4, changes)
+	assert.Equal(t, "m", Icon("music").Pango(),
+		"providers with missing fonts skipped")
+	assert.Equal(t, "", Icon("home").Pango())
+
+	installed["testfont"] = true
+	UseInstalled("font", "text")
+	assert.Equal(t, "<span face='testfont'>M</span>", Icon("music").Pango(),
+		"provider used when font is installed")
+
+	var nilProvider *Provider
+	assert.False(t, nilProvider.Has("music"))
+	Use()
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "icons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_CACHE_HOME", dir)
+	defer os.Unsetenv("XDG_CACHE_HOME")
+
+	fs = afero.NewMemMapFs()
+	afero.WriteFile(fs, "icons", []byte("icon1"), 0644)
+	c := &Config{FilePath: "icons", Font: "test", Cache: "icons-test"}
+	parses := 0
+	parse := func(line string, addFunc func(string, string)) error {
+		parses++
+		addFunc(line, "I")
+		return nil
+	}
+
+	provider, err := c.LoadByLines(parse)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, parses)
+	assert.Equal(t, "<span face='test'>I</span>", provider.Icon("icon1").Pango())
+
+	provider, err = c.LoadByLines(parse)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, parses, "cached icons are used")
+	assert.Equal(t, "<span face='test'>I</span>", provider.Icon("icon1").Pango(),
+		"cached icons are used")
+
+	afero.WriteFile(fs, "icons", []byte("icon2"), 0644)
+	fs.Chtimes("icons", time.Now(), time.Now().Add(time.Hour))
+	provider, err = c.LoadByLines(parse)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, parses, "parsed again when the file changes")
+	assert.True(t, provider.Has("icon2"))
+	assert.False(t, provider.Has("icon1"))
+
+	c.Cache = "icons-other"
+	_, err = c.LoadByLines(func(string, func(string, string)) error {
+		return fmt.Errorf("some error")
+	})
+	assert.Error(t, err)
+	provider, err = c.LoadByLines(parse)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, parses, "icons are not cached on error")
+
+	c.Cache = ""
+	c.LoadByLines(parse)
+	c.LoadByLines(parse)
+	assert.Equal(t, 5, parses, "icons are not cached without a key")
+}
@@ -36,12 +36,14 @@ func Icon(name string, style ...pango.Attribute) pango.Node {
 	return provider.Icon(name, style...)
 }
 
-// Load initialises the ionicons icon provider from the given repo.
+// Load initialises the ionicons icon provider from the given repo,
+// and registers it as "ionicons" for use with icons.Icon.
 func Load(repoPath string) error {
 	c := icons.Config{
 		RepoPath: repoPath,
 		FilePath: "scss/_ionicons-variables.scss",
 		Font:     "Ionicons",
+		Cache:    "icons-ionicons",
 	}
 	var err error
 	provider, err = c.LoadByLines(func(line string, add func(string, string)) error {
@@ -64,5 +66,8 @@ func Load(repoPath string) error {
 		add(name, sym)
 		return nil
 	})
+	if err == nil {
+		icons.Register("ionicons", provider)
+	}
 	return err
 }
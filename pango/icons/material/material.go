@@ -36,12 +36,14 @@ func Icon(name string, style ...pango.Attribute) pango.Node {
 	return provider.Icon(name, style...)
 }
 
-// Load initialises the material design icon provider from the given repo.
+// Load initialises the material design icon provider from the given repo,
+// and registers it as "material" for use with icons.Icon.
 func Load(repoPath string) error {
 	c := icons.Config{
 		RepoPath: repoPath,
 		FilePath: "iconfont/codepoints",
 		Font:     "Material Icons",
+		Cache:    "icons-material",
 	}
 	c.Styles(
 		pango.Ultralight,
@@ -63,5 +65,8 @@ func Load(repoPath string) error {
 		add(name, symbol)
 		return nil
 	})
+	if err == nil {
+		icons.Register("material", provider)
+	}
 	return err
 }
@@ -39,12 +39,14 @@ func Icon(name string, style ...pango.Attribute) pango.Node {
 }
 
 // Load initialises the material design (community) icon provider
-// from the given repo.
+// from the given repo, and registers it as "material_community" for use
+// with icons.Icon.
 func Load(repoPath string) error {
 	c := icons.Config{
 		RepoPath: repoPath,
 		FilePath: "scss/_variables.scss",
 		Font:     "Material Design Icons",
+		Cache:    "icons-material_community",
 	}
 	c.Styles(pango.Ultralight)
 	started := false
@@ -73,5 +75,8 @@ func Load(repoPath string) error {
 		add(name, sym)
 		return nil
 	})
+	if err == nil {
+		icons.Register("material_community", provider)
+	}
 	return err
 }
@@ -0,0 +1,120 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icons
+
+import (
+	"os/exec"
+	"sync"
+
+	"github.com/soumya92/barista/base/listeners"
+	"github.com/soumya92/barista/pango"
+)
+
+// registry holds loaded icon providers by name, and the names of the
+// providers currently used by Icon, in order of preference.
+var registry = map[string]*Provider{}
+var order []string
+var registryMu sync.RWMutex
+
+// onChange is notified whenever the providers used by Icon change.
+var onChange listeners.Set
+
+// NewProvider creates an icon provider from a map of icon names to symbols,
+// with optional default styles. Without a font, it can be used as a text
+// fallback (e.g. {"music": "♪"}) for when no icon font is available.
+func NewProvider(symbols map[string]string, attrs ...pango.Attribute) *Provider {
+	p := &Provider{symbols: map[string]string{}, attrs: attrs}
+	for name, symbol := range symbols {
+		p.symbols[name] = symbol
+	}
+	return p
+}
+
+// Has returns true if the provider has an icon with the given name.
+func (p *Provider) Has(name string) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.symbols[name]
+	return ok
+}
+
+// Register adds an icon provider under a name, e.g. "material", replacing
+// any provider previously registered with that name. The bundled icon fonts
+// register themselves when loaded.
+func Register(name string, p *Provider) {
+	registryMu.Lock()
+	registry[name] = p
+	used := false
+	for _, n := range order {
+		used = used || n == name
+	}
+	registryMu.Unlock()
+	if used {
+		onChange.Notify()
+	}
+}
+
+// Use sets the registered providers used by Icon, in order of preference.
+// An icon missing from the first provider is looked up in the next, and so
+// on, which can be used to fall back to text when icon fonts are missing.
+// Modules built on base re-render whenever this changes.
+func Use(names ...string) {
+	registryMu.Lock()
+	order = append([]string(nil), names...)
+	registryMu.Unlock()
+	onChange.Notify()
+}
+
+// UseInstalled is like Use, but skips providers whose font is not installed,
+// so that bars degrade gracefully when an icon font is missing. Providers
+// without a font (e.g. text fallbacks) are always used.
+func UseInstalled(names ...string) {
+	var installed []string
+	registryMu.RLock()
+	for _, name := range names {
+		if p, ok := registry[name]; ok && (p.font == "" || fontInstalled(p.font)) {
+			installed = append(installed, name)
+		}
+	}
+	registryMu.RUnlock()
+	Use(installed...)
+}
+
+// fontInstalled uses fontconfig to check whether a font is installed, and
+// can be replaced in tests.
+var fontInstalled = func(font string) bool {
+	return exec.Command("fc-list", "-q", font).Run() == nil
+}
+
+// Icon creates a pango span that renders the named icon, using the first
+// provider that has it from the ones currently in use (see Use).
+func Icon(name string, style ...pango.Attribute) pango.Node {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, n := range order {
+		if p := registry[n]; p.Has(name) {
+			return p.Icon(name, style...)
+		}
+	}
+	return pango.Span()
+}
+
+// OnChange adds a function that will be called whenever the providers used
+// by Icon change, and returns a function that removes it. Modules built on
+// base are updated automatically, so that their icons are re-rendered.
+func OnChange(f func()) (remove func()) {
+	return onChange.Add(f)
+}
@@ -46,12 +46,14 @@ type typiconsConfig struct {
 	} `yaml:"glyphs"`
 }
 
-// Load initialises the typicons icon provider from the given repo.
+// Load initialises the typicons icon provider from the given repo,
+// and registers it as "typicons" for use with icons.Icon.
 func Load(repoPath string) error {
 	c := icons.Config{
 		RepoPath: repoPath,
 		FilePath: "config.yml",
 		Font:     "Typicons",
+		Cache:    "icons-typicons",
 	}
 	var err error
 	provider, err = c.LoadFromFile(func(f io.Reader, add func(string, string)) error {
@@ -74,5 +76,8 @@ func Load(repoPath string) error {
 		}
 		return nil
 	})
+	if err == nil {
+		icons.Register("typicons", provider)
+	}
 	return err
 }
@@ -34,8 +34,10 @@ package pango
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"html"
+	"io"
 	"strings"
 )
 
@@ -131,3 +133,23 @@ func Tag(tagName string, things ...interface{}) Node {
 func Span(things ...interface{}) Node {
 	return Tag("span", things...)
 }
+
+// PlainText returns the text content of pango markup, without any tags
+// and with entities decoded, or the markup itself if it cannot be parsed.
+func PlainText(markup string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<markup>" + markup + "</markup>"))
+	decoder.Entity = xml.HTMLEntity
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return text.String()
+		}
+		if err != nil {
+			return markup
+		}
+		if data, ok := token.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+}
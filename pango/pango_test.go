@@ -117,6 +117,14 @@ func TestStringifying(t *testing.T) {
 	}
 }
 
+func TestPlainText(t *testing.T) {
+	assert.Equal(t, "plain", PlainText("plain"))
+	assert.Equal(t, "Bold & small text", PlainText(
+		Span(Tag("b", "Bold"), " & ", Tag("small", "small text")).Pango()))
+	assert.Equal(t, "a b", PlainText("a&nbsp;b"), "html entities")
+	assert.Equal(t, "<b>unclosed", PlainText("<b>unclosed"), "invalid markup")
+}
+
 var result string
 var resultNode Node
 
@@ -0,0 +1,221 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powersave
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/modules/breaks"
+)
+
+// IdleSaver turns power saving on while the user is idle.
+type IdleSaver struct {
+	saver
+	after     time.Duration
+	idleFunc  func() (time.Duration, error)
+	scheduler scheduler.Scheduler
+	// stopIdle stops the default idle source, if it was started.
+	stopIdle func()
+}
+
+// WhenIdle constructs a power saver that slows refresh intervals down
+// by a factor of 10 once the user has been idle for the given duration.
+// Idle time is read using xprintidle on X11, and swayidle on Wayland.
+func WhenIdle(after time.Duration) *IdleSaver {
+	s := &IdleSaver{after: after}
+	s.name, s.factor = "idle", 10
+	s.scheduler = scheduler.Do(s.check)
+	return s
+}
+
+// Scale sets the factor for refresh intervals while the user is idle.
+func (s *IdleSaver) Scale(factor float64) *IdleSaver {
+	s.setScale(factor)
+	return s
+}
+
+// Pause adds modules that are paused while the user is idle. Modules that
+// do not support pausing are ignored.
+func (s *IdleSaver) Pause(modules ...bar.Module) *IdleSaver {
+	s.addModules(modules)
+	return s
+}
+
+// IdleSource sets the function used to get the user's current idle time,
+// e.g. breaks.XPrintIdle.
+func (s *IdleSaver) IdleSource(idleFunc func() (time.Duration, error)) *IdleSaver {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopDefaultSource()
+	s.idleFunc = idleFunc
+	return s
+}
+
+// Start checks the idle time now and then every 5 seconds, so that the
+// full refresh rate is restored quickly once the user is active again.
+// If no idle source was set, the default one is started here.
+func (s *IdleSaver) Start() *IdleSaver {
+	s.mutex.Lock()
+	if s.idleFunc == nil {
+		s.idleFunc, s.stopIdle = defaultIdleSource()
+	}
+	s.mutex.Unlock()
+	s.check()
+	s.scheduler.EveryAligned(5 * time.Second)
+	return s
+}
+
+// Stop stops checking the idle time, and turns power saving off.
+// The default idle source is also stopped, and restarted by the next Start.
+func (s *IdleSaver) Stop() {
+	s.scheduler.Stop()
+	s.set(false)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopDefaultSource()
+}
+
+// stopDefaultSource stops the default idle source if it was started.
+// It must be called with the mutex held.
+func (s *IdleSaver) stopDefaultSource() {
+	if s.stopIdle != nil {
+		s.stopIdle()
+		s.stopIdle = nil
+		s.idleFunc = nil
+	}
+}
+
+// check turns power saving on or off based on the idle time.
+func (s *IdleSaver) check() {
+	s.mutex.Lock()
+	idle, err := s.idleFunc()
+	after := s.after
+	s.mutex.Unlock()
+	if err != nil {
+		// Without idle time, assume the user is active rather than keep
+		// the bar slowed down indefinitely.
+		log.Error("failed to get idle time", "error", err)
+		idle = 0
+	}
+	s.set(idle >= after)
+}
+
+// defaultIdleSource returns the idle source for the current display server,
+// along with a function that stops it. It can be replaced in tests.
+var defaultIdleSource = func() (func() (time.Duration, error), func()) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		s := newSwayIdle()
+		return s.idleTime, s.stop
+	}
+	return breaks.XPrintIdle, func() {}
+}
+
+// swayIdle tracks idle state reported by a swayidle process.
+type swayIdle struct {
+	mutex     sync.Mutex
+	idleSince time.Time
+	err       error
+	kill      func() error
+}
+
+// swayIdleNotice is how long swayidle waits before reporting idle, which is
+// added to the time since the report.
+const swayIdleNotice = time.Second
+
+// startSwayIdle starts swayidle, returning its output and functions that
+// wait for it to exit and kill it. It can be replaced in tests.
+var startSwayIdle = func() (out io.Reader, wait, kill func() error, err error) {
+	cmd := exec.Command("swayidle", "-w",
+		"timeout", "1", "echo idle",
+		"resume", "echo active")
+	out, err = cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, err
+	}
+	return out, cmd.Wait, cmd.Process.Kill, nil
+}
+
+// SwayIdle returns an idle source for Wayland compositors that support the
+// idle notification protocol, using events from a swayidle process.
+func SwayIdle() func() (time.Duration, error) {
+	return newSwayIdle().idleTime
+}
+
+func newSwayIdle() *swayIdle {
+	s := &swayIdle{}
+	out, wait, kill, err := startSwayIdle()
+	if err != nil {
+		s.err = err
+	} else {
+		s.kill = kill
+		go s.watch(bufio.NewScanner(out), wait)
+	}
+	return s
+}
+
+// stop kills the swayidle process, if it is still running.
+func (s *swayIdle) stop() {
+	if s.kill != nil {
+		s.kill()
+	}
+}
+
+func (s *swayIdle) watch(events *bufio.Scanner, wait func() error) {
+	for events.Scan() {
+		s.mutex.Lock()
+		switch strings.TrimSpace(events.Text()) {
+		case "idle":
+			s.idleSince = scheduler.Now().Add(-swayIdleNotice)
+		case "active":
+			s.idleSince = time.Time{}
+		}
+		s.mutex.Unlock()
+	}
+	err := events.Err()
+	if waitErr := wait(); waitErr != nil {
+		err = waitErr
+	}
+	if err == nil {
+		err = errors.New("swayidle exited")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.idleSince = time.Time{}
+	s.err = err
+}
+
+func (s *swayIdle) idleTime() (time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.idleSince.IsZero() {
+		return 0, nil
+	}
+	return scheduler.Now().Sub(s.idleSince), nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powersave
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+type fakeIdle struct {
+	sync.Mutex
+	idle time.Duration
+	err  error
+}
+
+func (f *fakeIdle) set(idle time.Duration, err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.idle, f.err = idle, err
+}
+
+func (f *fakeIdle) get() (time.Duration, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.idle, f.err
+}
+
+func init() {
+	defaultIdleSource = func() (func() (time.Duration, error), func()) {
+		return func() (time.Duration, error) { return 0, nil }, func() {}
+	}
+}
+
+func TestIdle(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	sch := scheduler.Do(func() {}).Every(10 * time.Second)
+	defer sch.Stop()
+
+	idle := &fakeIdle{}
+	m := &pausableModule{}
+	s := WhenIdle(5 * time.Minute).Scale(6).Pause(m).IdleSource(idle.get).Start()
+	defer s.Stop()
+	assert.False(t, s.Active(), "while active")
+
+	idle.set(4*time.Minute, nil)
+	scheduler.AdvanceBy(5 * time.Second)
+	waitActive(t, &s.saver, false, "before idle duration")
+
+	idle.set(5*time.Minute, nil)
+	scheduler.AdvanceBy(5 * time.Second)
+	waitActive(t, &s.saver, true, "after idle duration")
+	assert.True(t, m.isPaused(), "module paused while idle")
+	assert.Equal(t, scheduler.Now().Add(time.Minute), sch.NextTrigger(),
+		"interval scaled while idle")
+
+	idle.set(time.Second, nil)
+	scheduler.AdvanceBy(5 * time.Second)
+	waitActive(t, &s.saver, false, "on activity")
+	assert.False(t, m.isPaused(), "module resumed on activity")
+	// 55s of the scaled interval were left, which is rescaled, not restarted.
+	assert.Equal(t, scheduler.Now().Add(55*time.Second/6), sch.NextTrigger(),
+		"normal interval restored on activity")
+
+	idle.set(time.Hour, errors.New("something"))
+	scheduler.AdvanceBy(5 * time.Second)
+	waitActive(t, &s.saver, false, "on error")
+}
+
+func TestCombinedSavers(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	sch := scheduler.Do(func() {}).Every(10 * time.Second)
+	defer sch.Stop()
+
+	setBattery("BAT0", "Discharging", 80)
+	idle := &fakeIdle{}
+	idle.set(time.Hour, nil)
+	shared, onBattery, whenIdle := &pausableModule{}, &pausableModule{}, &pausableModule{}
+
+	b := New().Scale(2).Pause(shared, onBattery).Start()
+	defer b.Stop()
+	i := WhenIdle(time.Minute).Scale(3).Pause(shared, whenIdle).IdleSource(idle.get).Start()
+	defer i.Stop()
+
+	waitActive(t, &b.saver, true, "on battery")
+	waitActive(t, &i.saver, true, "idle")
+	assert.Equal(t, scheduler.Now().Add(time.Minute), sch.NextTrigger(),
+		"scales combined")
+	assert.True(t, shared.isPaused())
+
+	idle.set(0, nil)
+	scheduler.AdvanceBy(5 * time.Second)
+	waitActive(t, &i.saver, false, "active")
+	assert.Equal(t, scheduler.Now().Add(55*time.Second/3), sch.NextTrigger(),
+		"battery scale remains")
+	assert.True(t, shared.isPaused(), "still paused on battery")
+	assert.True(t, onBattery.isPaused())
+	assert.False(t, whenIdle.isPaused())
+
+	b.Stop()
+	assert.False(t, shared.isPaused(), "resumed when no saver is active")
+	assert.False(t, onBattery.isPaused())
+	assert.Equal(t, scheduler.Now().Add(55*time.Second/6), sch.NextTrigger())
+}
+
+// waitFor waits a short while for the swayidle events to be processed.
+func waitFor(cond func() bool) {
+	for i := 0; i < 100 && !cond(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSwayIdle(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	events, w := io.Pipe()
+	exited := make(chan error, 1)
+	startSwayIdle = func() (io.Reader, func() error, func() error, error) {
+		return events, func() error { return <-exited }, nil, nil
+	}
+
+	idleTime := SwayIdle()
+	idle, err := idleTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), idle, "initially active")
+
+	w.Write([]byte("idle\n"))
+	waitFor(func() bool { idle, _ := idleTime(); return idle > 0 })
+	scheduler.AdvanceBy(time.Minute)
+	idle, _ = idleTime()
+	assert.Equal(t, time.Minute+time.Second, idle,
+		"idle time includes swayidle notice period")
+
+	w.Write([]byte("active\n"))
+	waitFor(func() bool { idle, _ := idleTime(); return idle == 0 })
+	idle, _ = idleTime()
+	assert.Equal(t, time.Duration(0), idle, "active after resume")
+
+	w.Write([]byte("idle\n"))
+	exited <- errors.New("killed")
+	w.Close()
+	waitFor(func() bool { _, err := idleTime(); return err != nil })
+	idle, err = idleTime()
+	assert.Error(t, err, "after swayidle exits")
+	assert.Equal(t, time.Duration(0), idle)
+
+	startSwayIdle = func() (io.Reader, func() error, func() error, error) {
+		return nil, nil, nil, errors.New("not found")
+	}
+	_, err = SwayIdle()()
+	assert.Error(t, err, "when swayidle cannot be started")
+}
+
+func TestDefaultIdleSource(t *testing.T) {
+	scheduler.TestMode(true)
+	oldSource := defaultIdleSource
+	defer func() { defaultIdleSource = oldSource }()
+	started, stopped := 0, 0
+	defaultIdleSource = func() (func() (time.Duration, error), func()) {
+		started++
+		return func() (time.Duration, error) { return time.Hour, nil },
+			func() { stopped++ }
+	}
+
+	s := WhenIdle(time.Minute)
+	assert.Equal(t, 0, started, "not started on construction")
+
+	s.Start()
+	assert.Equal(t, 1, started, "started with saver")
+	waitActive(t, &s.saver, true, "uses default source")
+
+	s.Stop()
+	assert.Equal(t, 1, stopped, "stopped with saver")
+
+	s.Start()
+	assert.Equal(t, 2, started, "restarted with saver")
+	s.IdleSource(func() (time.Duration, error) { return 0, nil })
+	assert.Equal(t, 2, stopped, "stopped when replaced")
+	s.Stop()
+	assert.Equal(t, 2, stopped, "custom source is not stopped")
+
+	killed := make(chan bool, 1)
+	events, w := io.Pipe()
+	defer w.Close()
+	startSwayIdle = func() (io.Reader, func() error, func() error, error) {
+		return events, func() error { return nil },
+			func() error { killed <- true; return nil }, nil
+	}
+	sway := newSwayIdle()
+	sway.stop()
+	assert.True(t, <-killed, "swayidle is killed on stop")
+}
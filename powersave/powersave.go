@@ -0,0 +1,247 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package powersave slows down the whole bar while the machine is running on
+battery or the user is idle, instead of each module having its own power
+saving options.
+
+While power saving is on, the intervals of all repeating schedules (see
+scheduler.SetScale) are multiplied by a factor, and non-essential modules,
+e.g. those that make network requests, are paused. The normal cadence is
+restored, and paused modules resumed, as soon as the machine is back on AC
+or the user is active again.
+
+Typical usage would be:
+
+	powersave.New().
+		Scale(3).
+		Below(50).
+		Pause(weatherModule, stocksModule).
+		Start()
+
+	powersave.WhenIdle(5 * time.Minute).
+		Scale(10).
+		Pause(weatherModule, stocksModule).
+		Start()
+
+When both are active, the factors are multiplied, and modules stay paused
+until neither wants them paused.
+
+Clocks and other schedules set using EveryAligned are not slowed down. Note
+that modules paused for power saving are also resumed when the bar itself is
+resumed (e.g. on SIGUSR2), until power saving next turns on.
+*/
+package powersave
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/logging"
+	"github.com/soumya92/barista/modules/battery"
+)
+
+var log = logging.New("powersave")
+
+// batteryInfo reads the battery, and can be replaced in tests.
+var batteryInfo = battery.Get
+
+// saver holds the state common to all power savers.
+type saver struct {
+	mutex   sync.Mutex
+	name    string
+	factor  float64
+	modules []bar.Pausable
+	active  bool
+}
+
+// Active returns true if power saving is on.
+func (s *saver) Active() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.active
+}
+
+func (s *saver) setScale(factor float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.factor = factor
+	if s.active {
+		apply(s, true)
+	}
+}
+
+func (s *saver) addModules(modules []bar.Module) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var added []bar.Pausable
+	for _, m := range modules {
+		p, ok := m.(bar.Pausable)
+		if !ok {
+			log.Info("module cannot be paused", "type", fmt.Sprintf("%T", m))
+			continue
+		}
+		added = append(added, p)
+	}
+	s.modules = append(s.modules, added...)
+	if s.active {
+		stateMutex.Lock()
+		defer stateMutex.Unlock()
+		for _, m := range added {
+			pause(m)
+		}
+	}
+}
+
+// set turns power saving on or off, if it is not already.
+func (s *saver) set(save bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.active == save {
+		return
+	}
+	s.active = save
+	if save {
+		log.Info("power saving on", "when", s.name, "scale", s.factor)
+	} else {
+		log.Info("power saving off", "when", s.name)
+	}
+	apply(s, save)
+}
+
+// scales and pauses combine all active savers, so that one saver turning
+// off does not undo another that is still on.
+var (
+	stateMutex sync.Mutex
+	scales     = map[*saver]float64{}
+	pauses     = map[bar.Pausable]int{}
+)
+
+// apply adds or removes the effects of a saver, which must be locked.
+func apply(s *saver, save bool) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	_, wasSaving := scales[s]
+	if save {
+		scales[s] = s.factor
+	} else {
+		delete(scales, s)
+	}
+	if save != wasSaving {
+		for _, m := range s.modules {
+			if save {
+				pause(m)
+			} else {
+				resume(m)
+			}
+		}
+	}
+	factor := 1.0
+	for _, f := range scales {
+		factor *= f
+	}
+	scheduler.SetScale(factor)
+}
+
+// pause pauses a module if no other saver has already paused it.
+func pause(m bar.Pausable) {
+	pauses[m]++
+	if pauses[m] == 1 {
+		m.Pause()
+	}
+}
+
+// resume resumes a module if no other saver still needs it paused.
+func resume(m bar.Pausable) {
+	if pauses[m]--; pauses[m] <= 0 {
+		delete(pauses, m)
+		m.Resume()
+	}
+}
+
+// Saver turns power saving on and off based on the battery.
+type Saver struct {
+	saver
+	battery   string
+	threshold int
+	scheduler scheduler.Scheduler
+}
+
+// New constructs a power saver for the default battery (BAT0), which
+// doubles refresh intervals whenever the battery is discharging.
+func New() *Saver {
+	s := &Saver{battery: "BAT0", threshold: 100}
+	s.name, s.factor = "on battery", 2
+	s.scheduler = scheduler.Do(s.check)
+	return s
+}
+
+// Battery sets the name of the battery to check, e.g. "BAT1".
+func (s *Saver) Battery(name string) *Saver {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.battery = name
+	return s
+}
+
+// Scale sets the factor for refresh intervals while saving power.
+func (s *Saver) Scale(factor float64) *Saver {
+	s.setScale(factor)
+	return s
+}
+
+// Below only saves power while the battery is discharging and at or below
+// the given percentage, instead of whenever it is discharging.
+func (s *Saver) Below(percent int) *Saver {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.threshold = percent
+	return s
+}
+
+// Pause adds modules that are paused while saving power. Modules that do
+// not support pausing are ignored.
+func (s *Saver) Pause(modules ...bar.Module) *Saver {
+	s.addModules(modules)
+	return s
+}
+
+// Start checks the battery now and then every minute, turning power
+// saving on or off as needed.
+func (s *Saver) Start() *Saver {
+	s.check()
+	// Aligned schedules are not scaled, so the check itself does not slow
+	// down while saving power.
+	s.scheduler.EveryAligned(time.Minute)
+	return s
+}
+
+// Stop stops checking the battery, and turns power saving off.
+func (s *Saver) Stop() {
+	s.scheduler.Stop()
+	s.set(false)
+}
+
+// check turns power saving on or off based on the battery.
+func (s *Saver) check() {
+	s.mutex.Lock()
+	info := batteryInfo(s.battery)
+	save := info.Status == "Discharging" && info.Capacity <= s.threshold
+	s.mutex.Unlock()
+	s.set(save)
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package powersave
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/modules/battery"
+)
+
+type pausableModule struct {
+	sync.Mutex
+	paused bool
+}
+
+func (p *pausableModule) Stream() <-chan bar.Output { return nil }
+func (p *pausableModule) Pause()                    { p.Lock(); p.paused = true; p.Unlock() }
+func (p *pausableModule) Resume()                   { p.Lock(); p.paused = false; p.Unlock() }
+func (p *pausableModule) isPaused() bool            { p.Lock(); defer p.Unlock(); return p.paused }
+
+type plainModule struct{}
+
+func (plainModule) Stream() <-chan bar.Output { return nil }
+
+var infoMutex sync.Mutex
+var infos = map[string]battery.Info{}
+
+func setBattery(name string, status string, capacity int) {
+	infoMutex.Lock()
+	defer infoMutex.Unlock()
+	infos[name] = battery.Info{Status: status, Capacity: capacity}
+}
+
+func init() {
+	batteryInfo = func(name string) battery.Info {
+		infoMutex.Lock()
+		defer infoMutex.Unlock()
+		return infos[name]
+	}
+}
+
+// waitActive waits for power saving to be turned on or off, since
+// schedulers trigger asynchronously.
+func waitActive(t *testing.T, s *saver, active bool, msg string) {
+	for i := 0; i < 100 && s.Active() != active; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, active, s.Active(), msg)
+}
+
+func TestPowersave(t *testing.T) {
+	scheduler.TestMode(true)
+	scheduler.AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	sch := scheduler.Do(func() {}).Every(10 * time.Second)
+	defer sch.Stop()
+
+	setBattery("BAT0", "Charging", 80)
+	m := &pausableModule{}
+	s := New().Scale(3).Pause(m, plainModule{}).Start()
+	defer s.Stop()
+
+	assert.False(t, s.Active(), "while charging")
+	assert.Equal(t, scheduler.Now().Add(10*time.Second), sch.NextTrigger())
+
+	setBattery("BAT0", "Discharging", 80)
+	assert.False(t, s.Active(), "until the next check")
+	scheduler.AdvanceBy(time.Minute)
+	waitActive(t, &s.saver, true, "on battery")
+	assert.True(t, m.isPaused(), "module paused while saving power")
+	assert.Equal(t, scheduler.Now().Add(30*time.Second), sch.NextTrigger(),
+		"interval scaled while saving power")
+
+	setBattery("BAT0", "Full", 100)
+	scheduler.AdvanceBy(time.Minute)
+	waitActive(t, &s.saver, false, "back on AC")
+	assert.False(t, m.isPaused(), "module resumed")
+	assert.Equal(t, scheduler.Now().Add(10*time.Second), sch.NextTrigger(),
+		"normal interval restored")
+
+	setBattery("BAT0", "Discharging", 40)
+	scheduler.AdvanceBy(time.Minute)
+	waitActive(t, &s.saver, true, "below threshold")
+	s.Stop()
+	assert.False(t, s.Active(), "stopped")
+	assert.False(t, m.isPaused(), "module resumed on stop")
+	assert.Equal(t, scheduler.Now().Add(10*time.Second), sch.NextTrigger(),
+		"normal interval restored on stop")
+}
+
+func TestBelow(t *testing.T) {
+	scheduler.TestMode(true)
+	setBattery("BAT1", "Discharging", 60)
+	s := New().Battery("BAT1").Below(50).Start()
+	defer s.Stop()
+	assert.False(t, s.Active(), "above threshold")
+
+	setBattery("BAT1", "Discharging", 50)
+	scheduler.AdvanceBy(time.Minute)
+	waitActive(t, &s.saver, true, "at threshold")
+
+	m := &pausableModule{}
+	s.Pause(m)
+	assert.True(t, m.isPaused(), "module added while active is paused")
+
+	setBattery("BAT1", "Charging", 50)
+	scheduler.AdvanceBy(time.Minute)
+	waitActive(t, &s.saver, false, "charging")
+	assert.False(t, m.isPaused())
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package profiling records what each module refresh costs, to find the
+modules that use the most CPU or memory, e.g. when the bar is draining the
+battery.
+
+Profiling is opt-in, since it adds some overhead to every refresh:
+
+	profiling.Enable(true)
+
+Once enabled, each refresh of a module built on base (i.e. each call of its
+update function) records the wall time, the CPU time used by the refreshing
+thread, and the memory allocated. Allocations are counted for the whole
+process, so they are only approximate if other modules refresh at the same
+time. Work done in other goroutines started by the refresh is not counted.
+
+The results are available using Report, or as a table using Write, which is
+also served at /profile by the diagnostics endpoint. The profiler module
+shows the slowest modules on the bar.
+*/
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Stats are the recorded costs of a module's refreshes.
+type Stats struct {
+	// The name of the module, usually its package, e.g. "modules/weather",
+	// with a suffix (e.g. "modules/weather#2") for each additional module
+	// with the same name.
+	Name      string
+	Refreshes int64
+	// The total wall time and CPU time spent refreshing.
+	Wall time.Duration
+	CPU  time.Duration
+	// The longest wall time of a single refresh.
+	MaxWall time.Duration
+	// The total number of bytes allocated while refreshing.
+	Allocated uint64
+}
+
+// Profile records the refreshes of a single module.
+type Profile struct {
+	mutex sync.Mutex
+	stats Stats
+}
+
+var (
+	mutex    sync.Mutex
+	enabled  bool
+	profiles []*Profile
+	names    = map[string]int{}
+)
+
+// Enable enables or disables profiling. Refreshes that are already running
+// when profiling is enabled are not recorded.
+func Enable(enable bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	enabled = enable
+}
+
+// Enabled returns true if profiling is enabled.
+func Enabled() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return enabled
+}
+
+// New creates a profile for a module with the given name, which is
+// included in the report.
+func New(name string) *Profile {
+	mutex.Lock()
+	defer mutex.Unlock()
+	names[name]++
+	if count := names[name]; count > 1 {
+		name = fmt.Sprintf("%s#%d", name, count)
+	}
+	p := &Profile{stats: Stats{Name: name}}
+	profiles = append(profiles, p)
+	return p
+}
+
+// Start starts measuring a refresh, and returns a function that records it
+// once the refresh is done. It does nothing if profiling is disabled. The
+// calling goroutine is locked to its thread until the refresh is recorded,
+// so that the CPU time of the thread can be measured.
+func (p *Profile) Start() (done func()) {
+	if !Enabled() {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	start, startCPU, startAlloc := time.Now(), threadCPU(), allocated()
+	return func() {
+		wall := time.Since(start)
+		cpu, alloc := threadCPU()-startCPU, allocated()-startAlloc
+		runtime.UnlockOSThread()
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		p.stats.Refreshes++
+		p.stats.Wall += wall
+		p.stats.CPU += cpu
+		p.stats.Allocated += alloc
+		if wall > p.stats.MaxWall {
+			p.stats.MaxWall = wall
+		}
+	}
+}
+
+// Stats returns the recorded costs of the module's refreshes.
+func (p *Profile) Stats() Stats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats
+}
+
+// Report returns the stats of all modules that have been refreshed while
+// profiling was enabled, using the most CPU time first.
+func Report() []Stats {
+	mutex.Lock()
+	all := append([]*Profile(nil), profiles...)
+	mutex.Unlock()
+	var report []Stats
+	for _, p := range all {
+		if s := p.Stats(); s.Refreshes > 0 {
+			report = append(report, s)
+		}
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].CPU != report[j].CPU {
+			return report[i].CPU > report[j].CPU
+		}
+		return report[i].Wall > report[j].Wall
+	})
+	return report
+}
+
+// Reset clears the recorded stats of all modules, e.g. to profile the bar
+// under different conditions.
+func Reset() {
+	mutex.Lock()
+	all := append([]*Profile(nil), profiles...)
+	mutex.Unlock()
+	for _, p := range all {
+		p.mutex.Lock()
+		p.stats = Stats{Name: p.stats.Name}
+		p.mutex.Unlock()
+	}
+}
+
+// Write writes the report as a table, with the averages per refresh.
+func Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "module\trefreshes\tcpu\tavg cpu\twall\tavg wall\tmax wall\tallocated\t")
+	for _, s := range Report() {
+		n := time.Duration(s.Refreshes)
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\t%v\t%d\t\n",
+			s.Name, s.Refreshes,
+			round(s.CPU), round(s.CPU/n),
+			round(s.Wall), round(s.Wall/n), round(s.MaxWall),
+			s.Allocated)
+	}
+	return tw.Flush()
+}
+
+// round rounds durations for display, keeping sub-millisecond values
+// readable.
+func round(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(100 * time.Microsecond)
+}
+
+// threadCPU returns the CPU time used by the current thread, replaced in
+// tests.
+var threadCPU = func() time.Duration {
+	var usage unix.Rusage
+	if unix.Getrusage(unix.RUSAGE_THREAD, &usage) != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// allocated returns the bytes allocated by the process so far, replaced in
+// tests.
+var allocated = func() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
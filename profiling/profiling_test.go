@@ -0,0 +1,112 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiling
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+// fakeCosts replaces the CPU time and allocation counters with values that
+// are advanced by the test.
+func fakeCosts(t *testing.T) (cpu *time.Duration, alloc *uint64) {
+	cpu, alloc = new(time.Duration), new(uint64)
+	oldCPU, oldAlloc := threadCPU, allocated
+	threadCPU = func() time.Duration { return *cpu }
+	allocated = func() uint64 { return *alloc }
+	t.Cleanup(func() {
+		threadCPU, allocated = oldCPU, oldAlloc
+		Enable(false)
+		mutex.Lock()
+		profiles, names = nil, map[string]int{}
+		mutex.Unlock()
+	})
+	return cpu, alloc
+}
+
+func TestProfile(t *testing.T) {
+	cpu, alloc := fakeCosts(t)
+	p := New("modules/test")
+	p.Start()()
+	assert.Equal(t, int64(0), p.Stats().Refreshes, "not recorded while disabled")
+
+	Enable(true)
+	done := p.Start()
+	*cpu += 20 * time.Millisecond
+	*alloc += 1024
+	time.Sleep(5 * time.Millisecond)
+	done()
+
+	done = p.Start()
+	*cpu += 10 * time.Millisecond
+	*alloc += 512
+	done()
+
+	s := p.Stats()
+	assert.Equal(t, "modules/test", s.Name)
+	assert.Equal(t, int64(2), s.Refreshes)
+	assert.Equal(t, 30*time.Millisecond, s.CPU)
+	assert.Equal(t, uint64(1536), s.Allocated)
+	assert.True(t, s.MaxWall >= 5*time.Millisecond, "max wall time")
+	assert.True(t, s.Wall >= s.MaxWall, "total wall time")
+
+	assert.Equal(t, "modules/test#2", New("modules/test").Stats().Name,
+		"duplicate names are numbered")
+}
+
+func TestReport(t *testing.T) {
+	cpu, _ := fakeCosts(t)
+	Enable(true)
+	idle := New("idle")
+	cheap, busy := New("cheap"), New("busy")
+	for _, r := range []struct {
+		p   *Profile
+		cpu time.Duration
+	}{{cheap, time.Millisecond}, {busy, time.Second}, {busy, time.Second}} {
+		done := r.p.Start()
+		*cpu += r.cpu
+		done()
+	}
+	assert.Equal(t, int64(0), idle.Stats().Refreshes)
+
+	var names []string
+	for _, s := range Report() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"busy", "cheap"}, names,
+		"most cpu time first, without modules that were not refreshed")
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, Write(out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "avg cpu")
+	assert.Regexp(t, `^\s*busy\s+2\s+2s\s+1s\s`, lines[1])
+
+	Reset()
+	assert.Empty(t, Report(), "reset clears stats")
+}
+
+func TestThreadCPU(t *testing.T) {
+	start := threadCPU()
+	for end := time.Now().Add(20 * time.Millisecond); time.Now().Before(end); {
+	}
+	assert.True(t, threadCPU() > start, "cpu time increases when busy")
+	assert.True(t, allocated() > 0, "reads allocations")
+}
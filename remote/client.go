@@ -0,0 +1,175 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+)
+
+// client is a bar module that shows the output of a remote module.
+type client struct {
+	*base.Base
+	network string
+	address string
+	output  bar.Output
+	connErr error
+
+	// The current connection, used to send events. It has its own mutex so
+	// that events can be sent while the module is updating.
+	connMutex sync.Mutex
+	enc       *json.Encoder
+	paused    bool
+}
+
+// Dial constructs a module that connects to a module served on the given
+// network address (see net.Dial), e.g. Dial("unix", "/tmp/module.sock").
+// Clicks, pauses, and refreshes are delivered to the remote module. If the
+// connection fails or is lost, an error is shown until it is re-established.
+func Dial(network, address string) base.Module {
+	m := &client{Base: base.New(), network: network, address: address}
+	m.OnClick(func(e bar.Event) {
+		m.send(message{Type: typeEvent, Event: &e})
+	})
+	m.OnUpdate(m.update)
+	return m
+}
+
+// Stream connects to the remote module, and then returns the output channel
+// from the base module.
+func (m *client) Stream() <-chan bar.Output {
+	ch := m.Base.Stream()
+	go m.listen()
+	return ch
+}
+
+// retryDelay is how long to wait before reconnecting to the remote module.
+var retryDelay = 10 * time.Second
+
+func (m *client) listen() {
+	for {
+		err := m.receive()
+		// Show the error until the connection is re-established.
+		m.Lock()
+		m.connErr = err
+		m.Unlock()
+		m.Base.Update()
+		time.Sleep(retryDelay)
+	}
+}
+
+// receive receives outputs from the remote module until the connection
+// fails.
+func (m *client) receive() error {
+	conn, err := net.Dial(m.network, m.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	m.connMutex.Lock()
+	m.enc = json.NewEncoder(conn)
+	paused := m.paused
+	m.connMutex.Unlock()
+	defer func() {
+		m.connMutex.Lock()
+		m.enc = nil
+		m.connMutex.Unlock()
+	}()
+	if paused {
+		m.send(message{Type: typePause})
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+		if msg.Type != typeOutput {
+			continue
+		}
+		m.Lock()
+		m.output = msg.Output
+		m.connErr = nil
+		m.Unlock()
+		m.Base.Update()
+	}
+}
+
+// send sends a message to the remote module, dropping it if there is no
+// connection.
+func (m *client) send(msg message) {
+	m.connMutex.Lock()
+	defer m.connMutex.Unlock()
+	if m.enc == nil {
+		log.Fine("not connected", "address", m.address, "type", msg.Type)
+		return
+	}
+	if err := m.enc.Encode(msg); err != nil {
+		log.Fine("send failed", "address", m.address, "error", err)
+	}
+}
+
+// Update asks the remote module to update its output.
+func (m *client) Update() {
+	m.send(message{Type: typeRefresh})
+	m.Base.Update()
+}
+
+// Pause pauses the module, and the remote module if no other bar is using it.
+func (m *client) Pause() {
+	m.Base.Pause()
+	m.connMutex.Lock()
+	m.paused = true
+	m.connMutex.Unlock()
+	m.send(message{Type: typePause})
+}
+
+// Resume resumes the module, and the remote module.
+func (m *client) Resume() {
+	m.Base.Resume()
+	m.connMutex.Lock()
+	m.paused = false
+	m.connMutex.Unlock()
+	m.send(message{Type: typeResume})
+}
+
+func (m *client) update() {
+	m.Lock()
+	err, out := m.connErr, m.output
+	m.Unlock()
+	if err != nil {
+		m.Error(err)
+		return
+	}
+	if out == nil {
+		m.Clear()
+		return
+	}
+	// The bar adds to the segments it receives, so each update needs its
+	// own copy of the output.
+	copied := make(bar.Output, len(out))
+	for i, segment := range out {
+		copied[i] = bar.Segment{}
+		for k, v := range segment {
+			copied[i][k] = v
+		}
+	}
+	m.Output(copied)
+}
@@ -0,0 +1,259 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package remote runs modules in a separate process, or on another machine, so
+that heavy or crash-prone integrations are isolated from the bar.
+
+The process running the module serves it on a socket:
+
+	l, _ := net.Listen("unix", "/tmp/barista-weather.sock")
+	remote.Serve(l, weather.New(provider))
+
+and the bar adds a module that connects to it:
+
+	bar.Run(remote.Dial("unix", "/tmp/barista-weather.sock"), ...)
+
+The bar keeps running if the remote process crashes or is restarted, showing
+an error until the connection is re-established.
+
+The protocol is newline-delimited JSON, one message per line, so that remote
+modules can also be written in other languages. Each message has a "type":
+
+	{"type": "output", "output": [{"full_text": "..."}]}
+	    Sent by the module for each new output, using i3bar segments.
+	    The latest output is also sent as soon as a bar connects.
+	{"type": "event", "event": {"button": 1, "instance": "..."}}
+	    Sent by the bar for clicks on the module.
+	{"type": "pause"}, {"type": "resume"}
+	    Sent by the bar when it is paused or resumed.
+	{"type": "refresh"}
+	    Sent by the bar when the module is asked to update, e.g. from
+	    I3Bar.Refresh.
+
+Unknown message types are ignored, so that the protocol can be extended.
+There is no authentication, so TCP sockets should only be exposed through
+e.g. an SSH tunnel.
+*/
+package remote
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/logging"
+)
+
+var log = logging.New("remote")
+
+// Message types in the protocol.
+const (
+	typeOutput  = "output"
+	typeEvent   = "event"
+	typePause   = "pause"
+	typeResume  = "resume"
+	typeRefresh = "refresh"
+)
+
+// message is a single line of the protocol.
+type message struct {
+	Type   string     `json:"type"`
+	Output bar.Output `json:"output,omitempty"`
+	Event  *bar.Event `json:"event,omitempty"`
+}
+
+// server streams a module to all connected bars.
+type server struct {
+	module bar.Module
+	mutex  sync.Mutex
+	conns  map[*serverConn]bool
+	last   *message
+	// pauseMutex serialises pausing and resuming the module.
+	pauseMutex sync.Mutex
+	paused     bool
+}
+
+// serverConn is a single connected bar. Outputs are written from a separate
+// goroutine for each bar, so that a slow bar does not delay the others.
+type serverConn struct {
+	conn   net.Conn
+	paused bool
+	// next is the latest output that has not been written yet. Outputs
+	// replace each other, so a bar that falls behind only gets the latest.
+	mutex sync.Mutex
+	next  *message
+	wake  chan struct{}
+	done  chan struct{}
+}
+
+// writeTimeout is how long a bar can take to read an output before it is
+// disconnected.
+var writeTimeout = 10 * time.Second
+
+// push queues the output to be written to the bar.
+func (c *serverConn) push(msg *message) {
+	c.mutex.Lock()
+	c.next = msg
+	c.mutex.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// write writes the queued outputs to the bar until it disconnects.
+func (c *serverConn) write() {
+	enc := json.NewEncoder(c.conn)
+	for {
+		select {
+		case <-c.wake:
+		case <-c.done:
+			return
+		}
+		c.mutex.Lock()
+		msg := c.next
+		c.next = nil
+		c.mutex.Unlock()
+		if msg == nil {
+			continue
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := enc.Encode(msg); err != nil {
+			log.Fine("send failed", "remote", c.conn.RemoteAddr(), "error", err)
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// Serve starts the module, and streams its outputs to each bar that connects
+// to the listener, delivering clicks and other events from the bars to the
+// module. It blocks until the listener is closed.
+func Serve(l net.Listener, m bar.Module) error {
+	s := &server{module: m, conns: map[*serverConn]bool{}}
+	go s.stream(m.Stream())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.closeAll()
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+// stream sends each output from the module to all connected bars.
+func (s *server) stream(ch <-chan bar.Output) {
+	for out := range ch {
+		msg := &message{Type: typeOutput, Output: out}
+		s.mutex.Lock()
+		s.last = msg
+		for c := range s.conns {
+			c.push(msg)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// serve handles a connected bar until it disconnects.
+func (s *server) serve(conn net.Conn) {
+	c := &serverConn{
+		conn: conn,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go c.write()
+	// Queue the latest output while registering the connection, so that
+	// it cannot replace a newer output from the module.
+	s.mutex.Lock()
+	s.conns[c] = true
+	if s.last != nil {
+		c.push(s.last)
+	}
+	s.mutex.Unlock()
+	log.Fine("connected", "remote", conn.RemoteAddr())
+	defer func() {
+		close(c.done)
+		conn.Close()
+		s.mutex.Lock()
+		delete(s.conns, c)
+		s.mutex.Unlock()
+		s.updatePaused()
+		log.Fine("disconnected", "remote", conn.RemoteAddr())
+	}()
+	dec := json.NewDecoder(conn)
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		s.handle(c, msg)
+	}
+}
+
+// handle delivers a message from a bar to the module.
+func (s *server) handle(c *serverConn, msg message) {
+	switch msg.Type {
+	case typeEvent:
+		if clickable, ok := s.module.(bar.Clickable); ok && msg.Event != nil {
+			clickable.Click(*msg.Event)
+		}
+	case typeRefresh:
+		if refresher, ok := s.module.(bar.Refresher); ok {
+			refresher.Update()
+		}
+	case typePause, typeResume:
+		s.mutex.Lock()
+		c.paused = msg.Type == typePause
+		s.mutex.Unlock()
+		s.updatePaused()
+	default:
+		log.Fine("unknown message", "type", msg.Type)
+	}
+}
+
+// updatePaused pauses the module while every connected bar is paused, and
+// resumes it otherwise.
+func (s *server) updatePaused() {
+	s.pauseMutex.Lock()
+	defer s.pauseMutex.Unlock()
+	s.mutex.Lock()
+	paused := len(s.conns) > 0
+	for c := range s.conns {
+		paused = paused && c.paused
+	}
+	changed := paused != s.paused
+	s.paused = paused
+	s.mutex.Unlock()
+	pausable, ok := s.module.(bar.Pausable)
+	if !changed || !ok {
+		return
+	}
+	if paused {
+		pausable.Pause()
+	} else {
+		pausable.Resume()
+	}
+}
+
+func (s *server) closeAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func init() {
+	retryDelay = 10 * time.Millisecond
+}
+
+func listen(t *testing.T, socket string, m bar.Module) net.Listener {
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go Serve(l, m)
+	return l
+}
+
+// waitForText waits for output with the given text, skipping any errors
+// shown while reconnecting.
+func waitForText(o *testModule.OutputTester, text string, message string) bar.Output {
+	for i := 0; i < 100; i++ {
+		out := o.AssertOutput(message)
+		if len(out) > 0 && out[0].Text() == text {
+			return out
+		}
+	}
+	assert.Fail(o, "expected output", "%s: %s", message, text)
+	return nil
+}
+
+func TestRemote(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "remote.sock")
+	remote := testModule.New(t)
+	l := listen(t, socket, remote)
+
+	m := Dial("unix", socket)
+	o := testModule.NewOutputTester(t, m)
+	o.AssertEmpty("until remote output")
+
+	remote.Output(outputs.Text("hello").Color(colors.Hex("#f00")).MinWidth(20))
+	out := o.AssertOutput("remote output")
+	remote.AssertStarted("when served")
+	assert.Equal(t, "hello", out[0].Text())
+	assert.Equal(t, "#ff0000", out[0]["color"])
+	assert.Equal(t, 20.0, out[0]["min_width"])
+
+	m.Click(bar.Event{Button: bar.ButtonLeft, Instance: "a", X: 5})
+	e := remote.AssertClicked("click forwarded")
+	assert.Equal(t, bar.Event{Button: bar.ButtonLeft, Instance: "a", X: 5}, e)
+
+	m.Update()
+	remote.AssertUpdated("refresh forwarded")
+	o.AssertOutput("local update on refresh")
+
+	m.Pause()
+	remote.AssertPaused("pause forwarded")
+	remote.Output(outputs.Text("while paused"))
+	o.AssertNoOutput("while paused")
+	m.Resume()
+	remote.AssertResumed("resume forwarded")
+	out = o.AssertOutput("output on resume")
+	assert.Equal(t, "while paused", out[0].Text())
+
+	m2 := Dial("unix", socket)
+	o2 := testModule.NewOutputTester(t, m2)
+	waitForText(o2, "while paused", "latest output on connect")
+	m.Pause()
+	remote.AssertNoPauseResume("while another bar is not paused")
+	m2.Pause()
+	remote.AssertPaused("when all bars are paused")
+	m2.Resume()
+	remote.AssertResumed("when any bar resumes")
+
+	remote.Output(outputs.Empty())
+	o2.AssertEmpty("empty output")
+
+	l.Close()
+	o2.AssertError("when disconnected")
+
+	remote2 := testModule.New(t)
+	l = listen(t, socket, remote2)
+	defer l.Close()
+	remote2.Output(outputs.Text("world"))
+	waitForText(o2, "world", "after reconnect")
+}
+
+func TestProtocol(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "remote.sock")
+	remote := testModule.New(t)
+	l := listen(t, socket, remote)
+	defer l.Close()
+	remote.Output(outputs.Text("a"))
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, `{"type":"output","output":[{"full_text":"a"}]}`, strings.TrimSpace(line))
+
+	conn.Write([]byte(`{"type": "unknown"}` + "\n"))
+	conn.Write([]byte(`{"type": "event", "event": {"button": 3, "instance": "i"}}` + "\n"))
+	e := remote.AssertClicked("event from a non-go client")
+	assert.Equal(t, bar.ButtonRight, e.Button)
+	assert.Equal(t, "i", e.Instance)
+
+	conn.Write([]byte(`{"type": "pause"}` + "\n"))
+	remote.AssertPaused("pause from a non-go client")
+	conn.Close()
+	remote.AssertResumed("when the only bar disconnects")
+}
+
+func TestStalledBar(t *testing.T) {
+	writeTimeout = 100 * time.Millisecond
+	defer func() { writeTimeout = 10 * time.Second }()
+	socket := filepath.Join(t.TempDir(), "remote.sock")
+	remote := testModule.New(t)
+	l := listen(t, socket, remote)
+	defer l.Close()
+
+	remote.Output(outputs.Text("start"))
+
+	// A bar that stops reading after the first output.
+	stalled, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+	_, err = bufio.NewReader(stalled).ReadString('\n')
+	assert.Nil(t, err, "first output")
+
+	m := Dial("unix", socket)
+	o := testModule.NewOutputTester(t, m)
+	waitForText(o, "start", "latest output on connect")
+	big := strings.Repeat("x", 1<<20)
+	remote.Output(outputs.Text(big))
+	waitForText(o, big, "large output")
+	remote.Output(outputs.Text("done"))
+	waitForText(o, "done", "outputs not blocked by a stalled bar")
+
+	// Reading would unblock the write, so only read once it has timed out.
+	time.Sleep(3 * writeTimeout)
+	stalled.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = ioutil.ReadAll(stalled)
+	assert.Nil(t, err, "stalled bar is disconnected")
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// config-bar runs a bar built from a YAML config file, for users who want a
+// bar without writing any Go. See the config package for the file format.
+//
+// The config file defaults to $XDG_CONFIG_HOME/barista/config.yaml, and can
+// be changed using the -config flag. The bar is updated when the file
+// changes, or on SIGHUP.
+//
+// Logs are written to the file given by -log_file (standard error by
+// default), with the verbosity set by -log (see the logging package), which
+// can be changed while running by writing to the socket given by
+// -log_socket.
+//
+// The -diagnostics flag serves profiles and module statistics on a local
+// address, e.g. -diagnostics=localhost:6060 (see the diagnostics package).
+// SIGQUIT writes a dump of the bar to the log, for reporting hung bars.
+//
+// The -dbus flag publishes the bar on the session bus under the given name,
+// e.g. -dbus=org.barista.Bar, for scripts (see the dbusapi package).
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/config"
+	_ "github.com/soumya92/barista/config/builtin"
+	"github.com/soumya92/barista/dbusapi"
+	"github.com/soumya92/barista/diagnostics"
+	"github.com/soumya92/barista/logging"
+)
+
+func defaultConfig() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "barista", "config.yaml")
+}
+
+func main() {
+	path := flag.String("config", defaultConfig(), "path to the config file")
+	logRules := flag.String("log", "", "log verbosity rules, e.g. '*=info,modules/*=fine'")
+	logFile := flag.String("log_file", "", "path to the log file")
+	logSocket := flag.String("log_socket", "", "unix socket to change the log verbosity on")
+	debugAddr := flag.String("diagnostics", "", "local address to serve diagnostics on")
+	busName := flag.String("dbus", "", "session bus name to publish the bar under")
+	flag.Parse()
+	if *logRules != "" {
+		if err := logging.Configure(*logRules); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *logFile != "" {
+		if err := logging.SetFile(*logFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *logSocket != "" {
+		if err := logging.Listen(*logSocket); err != nil {
+			log.Fatal(err)
+		}
+	}
+	b := bar.New()
+	r, err := config.NewReloader(b, *path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Keep running with the last good config if the file has errors, which
+	// are logged by the config package.
+	if _, err := r.Watch(nil); err != nil {
+		log.Println(err)
+	}
+	diagnostics.DumpOnSignal(b)
+	if *debugAddr != "" {
+		if _, err := diagnostics.Listen(b, *debugAddr); err != nil {
+			log.Println(err)
+		}
+	}
+	if *busName != "" {
+		if _, err := dbusapi.Export(b, *busName); err != nil {
+			log.Println(err)
+		}
+	}
+	log.Fatal(b.Run())
+}
@@ -0,0 +1,485 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package snapshot renders the current state of a bar to an HTML page or a PNG
+image, for documenting configurations, sharing setups, and visual regression
+testing.
+
+Segment colors, borders, urgency, separators, widths, and alignment are
+rendered the way i3bar would, and pango markup is approximated using CSS.
+
+Typical usage would be:
+
+	s := snapshot.Take(b)
+	s.Style.Font = "DejaVu Sans Mono 10"
+	f, _ := os.Create("bar.html")
+	s.WriteHTML(f)
+
+PNG images are rendered using pango-view, which is part of pango, so that
+markup and fonts are rendered exactly as on the bar:
+
+	f, _ := os.Create("bar.png")
+	err := s.WritePNG(f)
+*/
+package snapshot
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base/scheduler"
+	"github.com/soumya92/barista/pango"
+)
+
+// Style controls how parts of the bar not specified by outputs are drawn.
+type Style struct {
+	// Font is a pango font description, e.g. "monospace 10".
+	Font string
+	// Default colors for the bar, as CSS or pango colors (e.g. "#000000").
+	Background string
+	Foreground string
+	Separator  string
+	// Colors for urgent segments.
+	UrgentBackground string
+	UrgentForeground string
+	UrgentBorder     string
+}
+
+// DefaultStyle is the style used by i3bar without a colors configuration.
+var DefaultStyle = Style{
+	Font:             "monospace 10",
+	Background:       "#000000",
+	Foreground:       "#ffffff",
+	Separator:        "#666666",
+	UrgentBackground: "#900000",
+	UrgentForeground: "#ffffff",
+	UrgentBorder:     "#2f343a",
+}
+
+// Module is the last output of a module on the bar.
+type Module struct {
+	Name   string
+	Output bar.Output
+}
+
+// Snapshot is the state of a bar at a point in time.
+type Snapshot struct {
+	Time    time.Time
+	Modules []Module
+	Style   Style
+}
+
+// Take captures the current outputs of all modules on the bar, in the order
+// they are shown.
+func Take(b *bar.I3Bar) Snapshot {
+	s := Snapshot{Time: scheduler.Now(), Style: DefaultStyle}
+	for _, m := range b.Stats().Modules {
+		s.Modules = append(s.Modules, Module{Name: m.Name, Output: m.Output})
+	}
+	return s
+}
+
+// segment is a visible segment with its values normalised, since segments
+// read back from JSON (e.g. from history) have different types.
+type segment struct {
+	text           string
+	pango          bool
+	color          string
+	background     string
+	border         string
+	urgent         bool
+	minWidth       string
+	align          string
+	separator      bool
+	separatorWidth int
+}
+
+// segments returns all the visible segments in the snapshot.
+func (s Snapshot) segments() []segment {
+	var segments []segment
+	for _, m := range s.Modules {
+		for _, seg := range m.Output {
+			text := stringValue(seg, "full_text")
+			if text == "" {
+				continue
+			}
+			sep, ok := seg["separator"].(bool)
+			if !ok {
+				sep = true
+			}
+			sepWidth, ok := intValue(seg, "separator_block_width")
+			if !ok {
+				sepWidth = 9
+			}
+			out := segment{
+				text:           text,
+				pango:          stringValue(seg, "markup") == string(bar.MarkupPango),
+				color:          stringValue(seg, "color"),
+				background:     stringValue(seg, "background"),
+				border:         stringValue(seg, "border"),
+				align:          stringValue(seg, "align"),
+				separator:      sep,
+				separatorWidth: sepWidth,
+			}
+			out.urgent, _ = seg["urgent"].(bool)
+			if w, ok := intValue(seg, "min_width"); ok && w > 0 {
+				out.minWidth = fmt.Sprintf("%dpx", w)
+			} else if w := stringValue(seg, "min_width"); w != "" {
+				out.minWidth = fmt.Sprintf("%dch", len([]rune(w)))
+			}
+			segments = append(segments, out)
+		}
+	}
+	if len(segments) > 0 {
+		// i3bar does not draw a separator after the last segment.
+		segments[len(segments)-1].separator = false
+		segments[len(segments)-1].separatorWidth = 0
+	}
+	return segments
+}
+
+func stringValue(s bar.Segment, key string) string {
+	switch v := s[key].(type) {
+	case string:
+		return v
+	case bar.Color:
+		return string(v)
+	case bar.Markup:
+		return string(v)
+	case bar.TextAlignment:
+		return string(v)
+	}
+	return ""
+}
+
+func intValue(s bar.Segment, key string) (int, bool) {
+	switch v := s[key].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// WriteHTML writes the snapshot as a standalone HTML page.
+func (s Snapshot) WriteHTML(w io.Writer) error {
+	font, size := cssFont(s.Style.Font)
+	var out strings.Builder
+	fmt.Fprintf(&out, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>barista snapshot %s</title>
+<style>
+.bar { display: flex; justify-content: flex-end; align-items: stretch;
+  white-space: pre; padding: 0 4px; font-family: %s; font-size: %s;
+  background: %s; color: %s; line-height: 1.6; }
+.segment { display: inline-block; box-sizing: border-box; padding: 0 1px; }
+.separator, .gap { display: inline-block; }
+.separator::after { content: ""; display: inline-block; height: 70%%;
+  vertical-align: middle; border-left: 1px solid %s; }
+</style>
+</head>
+<body>
+<div class="bar">`,
+		html.EscapeString(s.Time.Format(time.RFC3339)),
+		html.EscapeString(font), size, s.Style.Background, s.Style.Foreground, s.Style.Separator)
+	for _, seg := range s.segments() {
+		var css []string
+		color, background, border := seg.color, seg.background, seg.border
+		if seg.urgent {
+			color, background, border =
+				s.Style.UrgentForeground, s.Style.UrgentBackground, s.Style.UrgentBorder
+		}
+		if color != "" {
+			css = append(css, "color: "+color)
+		}
+		if background != "" {
+			css = append(css, "background: "+background)
+		}
+		if border != "" {
+			css = append(css, "border: 1px solid "+border)
+		}
+		if seg.minWidth != "" {
+			css = append(css, "min-width: "+seg.minWidth)
+		}
+		switch seg.align {
+		case string(bar.AlignCenter):
+			css = append(css, "text-align: center")
+		case string(bar.AlignEnd):
+			css = append(css, "text-align: right")
+		}
+		out.WriteString(`<span class="segment"`)
+		if len(css) > 0 {
+			fmt.Fprintf(&out, ` style="%s"`, html.EscapeString(strings.Join(css, "; ")))
+		}
+		out.WriteString(">")
+		if seg.pango {
+			out.WriteString(pangoToHTML(seg.text))
+		} else {
+			out.WriteString(html.EscapeString(seg.text))
+		}
+		out.WriteString("</span>")
+		if seg.separatorWidth > 0 {
+			class := "gap"
+			if seg.separator {
+				class = "separator"
+			}
+			fmt.Fprintf(&out, `<span class="%s" style="width: %dpx; text-align: center"></span>`,
+				class, seg.separatorWidth)
+		}
+	}
+	out.WriteString("</div>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// cssFont converts a pango font description into a CSS font family and size.
+func cssFont(desc string) (family, size string) {
+	family, size = desc, "10pt"
+	if i := strings.LastIndex(desc, " "); i > 0 {
+		if pt, err := strconv.ParseFloat(desc[i+1:], 64); err == nil {
+			family = desc[:i]
+			size = fmt.Sprintf("%gpt", pt)
+		}
+	}
+	return quoteFamily(family), size
+}
+
+func quoteFamily(family string) string {
+	switch family {
+	case "monospace", "sans-serif", "serif":
+		return family
+	case "sans":
+		return "sans-serif"
+	}
+	return fmt.Sprintf("'%s', monospace", strings.Replace(family, "'", "", -1))
+}
+
+// tagStyles are the CSS equivalents of pango's convenience tags.
+var tagStyles = map[string]string{
+	"b":     "font-weight: bold",
+	"big":   "font-size: larger",
+	"i":     "font-style: italic",
+	"s":     "text-decoration: line-through",
+	"small": "font-size: smaller",
+	"sub":   "vertical-align: sub; font-size: smaller",
+	"sup":   "vertical-align: super; font-size: smaller",
+	"tt":    "font-family: monospace",
+	"u":     "text-decoration: underline",
+}
+
+// pangoToHTML approximates pango markup using HTML spans with inline styles.
+// Invalid markup is rendered as escaped text, as i3bar would show it.
+func pangoToHTML(markup string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<markup>" + markup + "</markup>"))
+	decoder.Entity = xml.HTMLEntity
+	var out strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			return html.EscapeString(markup)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "markup" {
+				continue
+			}
+			var css []string
+			if style, ok := tagStyles[t.Name.Local]; ok {
+				css = append(css, style)
+			}
+			for _, attr := range t.Attr {
+				if style := spanStyle(attr.Name.Local, attr.Value); style != "" {
+					css = append(css, style)
+				}
+			}
+			out.WriteString("<span")
+			if len(css) > 0 {
+				fmt.Fprintf(&out, ` style="%s"`, html.EscapeString(strings.Join(css, "; ")))
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			if t.Name.Local != "markup" {
+				out.WriteString("</span>")
+			}
+		case xml.CharData:
+			out.WriteString(html.EscapeString(string(t)))
+		}
+	}
+}
+
+// spanStyle returns the CSS for a pango span attribute, or an empty string
+// if it cannot be approximated.
+func spanStyle(name, value string) string {
+	switch name {
+	case "color", "foreground", "fgcolor":
+		return "color: " + value
+	case "background", "bgcolor":
+		return "background: " + value
+	case "face", "font_family", "font-family":
+		return "font-family: " + quoteFamily(value)
+	case "font", "font_desc":
+		family, size := cssFont(value)
+		return fmt.Sprintf("font-family: %s; font-size: %s", family, size)
+	case "size", "font_size":
+		if v, err := strconv.Atoi(value); err == nil {
+			return fmt.Sprintf("font-size: %gpt", float64(v)/1024)
+		}
+		return "font-size: " + value
+	case "weight", "font_weight":
+		switch value {
+		case "ultralight":
+			value = "200"
+		case "light":
+			value = "300"
+		case "heavy", "ultrabold":
+			value = "800"
+		}
+		return "font-weight: " + value
+	case "style", "font_style":
+		return "font-style: " + value
+	case "variant", "font_variant":
+		if value == "smallcaps" {
+			return "font-variant: small-caps"
+		}
+	case "underline":
+		if value != "none" {
+			return "text-decoration: underline"
+		}
+	case "strikethrough":
+		if value == "true" {
+			return "text-decoration: line-through"
+		}
+	case "rise":
+		if v, err := strconv.Atoi(value); err == nil {
+			return fmt.Sprintf("vertical-align: %gpt", float64(v)/1024)
+		}
+	case "alpha", "fgalpha":
+		return "opacity: " + value
+	case "font_features":
+		return "font-feature-settings: " + cssFeatures(value)
+	}
+	return ""
+}
+
+// cssFeatures converts pango font features (e.g. "tnum, smcp=1") to CSS.
+func cssFeatures(features string) string {
+	var out []string
+	for _, f := range strings.Split(features, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) == 2 {
+			out = append(out, fmt.Sprintf(`"%s" %s`, parts[0], parts[1]))
+		} else {
+			out = append(out, fmt.Sprintf(`"%s"`, parts[0]))
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// Markup returns the snapshot as a single line of pango markup, using
+// spaces and '|' to approximate separators.
+func (s Snapshot) Markup() string {
+	var out strings.Builder
+	for _, seg := range s.segments() {
+		color, background := seg.color, seg.background
+		if seg.urgent {
+			color, background = s.Style.UrgentForeground, s.Style.UrgentBackground
+		}
+		text := seg.text
+		if !seg.pango {
+			text = pango.Text(text).Pango()
+		}
+		var attrs []string
+		if color != "" {
+			attrs = append(attrs, fmt.Sprintf("color='%s'", html.EscapeString(color)))
+		}
+		if background != "" {
+			attrs = append(attrs, fmt.Sprintf("background='%s'", html.EscapeString(background)))
+		}
+		if len(attrs) > 0 {
+			text = fmt.Sprintf("<span %s>%s</span>", strings.Join(attrs, " "), text)
+		}
+		out.WriteString(text)
+		// Roughly one space per 4px, with the separator in the middle.
+		gap := seg.separatorWidth / 4
+		switch {
+		case seg.separator:
+			pad := strings.Repeat(" ", gap/2)
+			fmt.Fprintf(&out, "%s<span color='%s'>|</span>%s",
+				pad, html.EscapeString(s.Style.Separator), pad)
+		case gap > 0:
+			out.WriteString(strings.Repeat(" ", gap))
+		}
+	}
+	return out.String()
+}
+
+// pangoView runs pango-view, and can be replaced in tests.
+var pangoView = func(args ...string) error {
+	out, err := exec.Command("pango-view", args...).CombinedOutput()
+	if err != nil && len(out) > 0 {
+		return fmt.Errorf("pango-view: %s", strings.TrimSpace(string(out)))
+	}
+	return err
+}
+
+// WritePNG renders the snapshot to a PNG image using pango-view.
+func (s Snapshot) WritePNG(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "barista-snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	textFile := filepath.Join(dir, "bar.txt")
+	pngFile := filepath.Join(dir, "bar.png")
+	if err := os.WriteFile(textFile, []byte(s.Markup()), 0600); err != nil {
+		return err
+	}
+	err = pangoView("--markup", "-q",
+		"--font="+s.Style.Font,
+		"--background="+s.Style.Background,
+		"--foreground="+s.Style.Foreground,
+		"--margin=4",
+		"--output="+pngFile,
+		textFile)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(pngFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/colors"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestTake(t *testing.T) {
+	b := testBar.New(t)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	b.AdvanceTo(start)
+	m1, m2 := testModule.New(t), testModule.New(t)
+	b.Bar.Add(m1, m2)
+	b.Start()
+	defer b.Close()
+
+	m1.Output(outputs.Text("a"))
+	b.AssertText([]string{"a"}, "first output")
+	m2.Output(outputs.Text("b").Color(colors.Hex("#f00")))
+	b.AssertText([]string{"a", "b"}, "second output")
+
+	s := Take(b.Bar)
+	assert.Equal(t, start, s.Time)
+	assert.Equal(t, DefaultStyle, s.Style)
+	assert.Equal(t, 2, len(s.Modules))
+	assert.Equal(t, "0", s.Modules[0].Name)
+	assert.Equal(t, "b", s.Modules[1].Output[0].Text())
+	assert.Equal(t, "a <span color='#666666'>|</span> <span color='#ff0000'>b</span>",
+		s.Markup())
+}
+
+func snapshotOf(outputs ...bar.Output) Snapshot {
+	s := Snapshot{Style: DefaultStyle}
+	for _, o := range outputs {
+		s.Modules = append(s.Modules, Module{Output: o})
+	}
+	return s
+}
+
+func TestHTML(t *testing.T) {
+	s := snapshotOf(
+		outputs.Text("plain & simple").Color(colors.Hex("#0f0")),
+		bar.Output{
+			bar.NewSegment("<b>bold</b>").Markup(bar.MarkupPango),
+			bar.NewSegment("").Background(colors.Hex("#fff")),
+			bar.NewSegment("x").MinWidth(50).Align(bar.AlignCenter).
+				Separator(false).SeparatorWidth(0),
+		},
+		outputs.Text("urgent").Urgent(true).Border(colors.Hex("#00f")),
+		bar.Output{{"full_text": "from json", "min_width": 40.0,
+			"separator_block_width": 4.0, "separator": false, "markup": "none"}},
+		bar.Output{{"full_text": "last", "min_width": "00:00"}},
+	)
+	s.Style.Font = "DejaVu Sans Mono 12"
+	var buf bytes.Buffer
+	assert.Nil(t, s.WriteHTML(&buf))
+	page := buf.String()
+
+	assert.Contains(t, page, "font-family: &#39;DejaVu Sans Mono&#39;, monospace; font-size: 12pt")
+	assert.Contains(t, page,
+		`<span class="segment" style="color: #00ff00">plain &amp; simple</span>`+
+			`<span class="separator" style="width: 9px; text-align: center"></span>`)
+	assert.Contains(t, page,
+		`<span class="segment"><span style="font-weight: bold">bold</span></span>`,
+		"pango markup")
+	assert.NotContains(t, page, "#ffffff\"", "empty segments are hidden")
+	assert.Contains(t, page,
+		`<span class="segment" style="min-width: 50px; text-align: center">x</span><span`,
+		"no separator or gap")
+	assert.NotContains(t, page, `text-align: center">x</span><span class="gap"`)
+	assert.Contains(t, page,
+		`style="color: #ffffff; background: #900000; border: 1px solid #2f343a">urgent</span>`,
+		"urgent colors")
+	assert.Contains(t, page,
+		`style="min-width: 40px">from json</span><span class="gap" style="width: 4px`,
+		"values read back from json")
+	assert.Contains(t, page, `style="min-width: 5ch">last</span></div>`,
+		"no gap after the last segment")
+	assert.Equal(t, 3, strings.Count(page, `class="separator"`),
+		"no separator after the last segment")
+}
+
+func TestPangoToHTML(t *testing.T) {
+	for _, tc := range []struct{ markup, html string }{
+		{"text", "text"},
+		{"a &amp; b", "a &amp; b"},
+		{"<i>it</i><u>ul</u>", `<span style="font-style: italic">it</span>` +
+			`<span style="text-decoration: underline">ul</span>`},
+		{"<span color='#f00' background='blue' face='Icons' size='10240'>x</span>",
+			`<span style="color: #f00; background: blue; ` +
+				`font-family: &#39;Icons&#39;, monospace; font-size: 10pt">x</span>`},
+		{"<span weight='ultralight' rise='-2048' strikethrough='true'>y</span>",
+			`<span style="font-weight: 200; vertical-align: -2pt; text-decoration: line-through">y</span>`},
+		{"<span font_features='tnum, smcp=1' fgalpha='50%'>1</span>",
+			`<span style="font-feature-settings: &#34;tnum&#34;, &#34;smcp&#34; 1; opacity: 50%">1</span>`},
+		{"<span font='sans 8' unknown='1'>z</span>",
+			`<span style="font-family: sans-serif; font-size: 8pt">z</span>`},
+		{"<b>unclosed", "&lt;b&gt;unclosed"},
+	} {
+		assert.Equal(t, tc.html, pangoToHTML(tc.markup), tc.markup)
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var args []string
+	pangoView = func(a ...string) error {
+		args = a
+		for _, arg := range a {
+			if strings.HasPrefix(arg, "--output=") {
+				return os.WriteFile(strings.TrimPrefix(arg, "--output="), []byte("png"), 0600)
+			}
+		}
+		return errors.New("no output")
+	}
+	s := snapshotOf(outputs.Text("a <3"), outputs.Text("b").Urgent(true))
+	var buf bytes.Buffer
+	assert.Nil(t, s.WritePNG(&buf))
+	assert.Equal(t, "png", buf.String())
+	assert.Contains(t, args, "--markup")
+	assert.Contains(t, args, "--font=monospace 10")
+	assert.Contains(t, args, "--background=#000000")
+	assert.Equal(t,
+		"a &lt;3 <span color='#666666'>|</span> <span color='#ffffff' background='#900000'>b</span>",
+		s.Markup())
+
+	pangoView = func(...string) error { return errors.New("not found") }
+	assert.Error(t, s.WritePNG(&buf), "pango-view errors")
+}
@@ -0,0 +1,344 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package bar provides a test harness that runs a complete bar against
+in-memory streams, for testing whole-bar setups.
+
+The test bar decodes the i3bar JSON written by the bar, allows making
+assertions on the latest output, and injects click events in the same
+format as i3bar, so tests exercise the complete bar protocol. The raw
+output is also recorded, and can be compared against golden files to
+verify the protocol encoding byte-for-byte.
+
+Importing this package puts the timing package (and base/scheduler) into
+test mode, so that modules constructed in tests are scheduled on virtual
+time. End-to-end tests of scheduled modules then control time using the
+AdvanceTo, AdvanceBy, and NextTick methods of the test bar, and run
+instantly and deterministically.
+
+Typical usage would be:
+
+	b := testBar.New(t)
+	b.Bar.Add(clock, volume)
+	b.Start()
+	b.AssertText([]string{"12:00", "50%"}, "on start")
+	b.Click(1)
+	b.AdvanceBy(time.Minute)
+	b.AssertText([]string{"12:01", "50%"}, "after a minute")
+*/
+package bar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/locale"
+	"github.com/soumya92/barista/timing"
+)
+
+func init() {
+	// Enabled at import, rather than in New, so that modules constructed
+	// before the test bar (e.g. for Run) also use virtual time.
+	timing.TestMode(true)
+	// Tests expect English output, whatever the locale of the machine.
+	locale.SetLocale(locale.English)
+}
+
+// Time to wait for events. Overridden in tests.
+var positiveTimeout = time.Second
+
+// Time to wait before concluding that there is no output.
+var negativeTimeout = 10 * time.Millisecond
+
+// TestBar runs a bar on in-memory streams, and decodes its output.
+type TestBar struct {
+	// Bar is the bar under test. Modules can be added to it, and it can
+	// be configured, before Start is called.
+	Bar *bar.I3Bar
+
+	t       *testing.T
+	stdin   *io.PipeWriter
+	stdout  *io.PipeReader
+	outputs chan bar.Output
+	errors  chan error
+	latest  bar.Output
+
+	// The raw output of the bar, up to the end of the last decoded value.
+	recordingMutex sync.Mutex
+	recording      bytes.Buffer
+	recordingEnd   int64
+}
+
+// New creates a new test bar for the given testing.T.
+func New(t *testing.T) *TestBar {
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	return &TestBar{
+		Bar:     bar.NewOnIo(stdinReader, stdoutWriter),
+		t:       t,
+		stdin:   stdinWriter,
+		stdout:  stdoutReader,
+		outputs: make(chan bar.Output, 100),
+		errors:  make(chan error, 1),
+	}
+}
+
+// Run creates a test bar with the given modules, and starts it.
+func Run(t *testing.T, modules ...bar.Module) *TestBar {
+	b := New(t)
+	b.Bar.Add(modules...)
+	b.Start()
+	return b
+}
+
+// Start runs the bar, and waits for it to write the i3bar header.
+func (b *TestBar) Start() {
+	go func() { b.errors <- b.Bar.Run() }()
+	decoder := json.NewDecoder(io.TeeReader(b.stdout, recorder{b}))
+	header := make(chan error)
+	go b.decode(decoder, header)
+	select {
+	case err := <-header:
+		assert.Nil(b.t, err, "bar header and output array")
+	case err := <-b.errors:
+		assert.Fail(b.t, "bar exited on start", "%v", err)
+		return
+	case <-time.After(positiveTimeout):
+		assert.Fail(b.t, "expected bar header")
+		return
+	}
+	// Start the infinite array of events.
+	io.WriteString(b.stdin, "[")
+}
+
+// Close stops the bar by closing its streams.
+func (b *TestBar) Close() {
+	b.stdin.Close()
+	b.stdout.Close()
+}
+
+// decode decodes the header and each full bar written to stdout.
+func (b *TestBar) decode(decoder *json.Decoder, header chan<- error) {
+	var h map[string]interface{}
+	if err := decoder.Decode(&h); err != nil {
+		header <- err
+		return
+	}
+	b.recorded(decoder)
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		header <- fmt.Errorf("expected output array, got %v (%v)", tok, err)
+		return
+	}
+	header <- nil
+	for decoder.More() {
+		var out bar.Output
+		if err := decoder.Decode(&out); err != nil {
+			return
+		}
+		b.recorded(decoder)
+		b.outputs <- out
+	}
+}
+
+// recorder writes the raw output of the bar to the recording.
+type recorder struct{ *TestBar }
+
+func (r recorder) Write(data []byte) (int, error) {
+	r.recordingMutex.Lock()
+	defer r.recordingMutex.Unlock()
+	return r.recording.Write(data)
+}
+
+// recorded marks the end of a decoded value in the recording, so that the
+// recording only includes complete values.
+func (b *TestBar) recorded(decoder *json.Decoder) {
+	b.recordingMutex.Lock()
+	defer b.recordingMutex.Unlock()
+	b.recordingEnd = decoder.InputOffset()
+}
+
+// Recording returns the raw output of the bar, including the header,
+// up to the end of the last output that was printed.
+func (b *TestBar) Recording() string {
+	b.recordingMutex.Lock()
+	defer b.recordingMutex.Unlock()
+	return string(b.recording.Bytes()[:b.recordingEnd])
+}
+
+// UpdateGoldenEnv is the environment variable that, when set, makes
+// AssertGolden write the recording to the golden file instead of
+// comparing against it.
+const UpdateGoldenEnv = "BARISTA_UPDATE_GOLDEN"
+
+// AssertGolden asserts that the recording of the bar so far is identical
+// to the contents of the given golden file. Since the recording ends at the
+// last decoded output, tests should consume all outputs (e.g. using
+// NextOutput) before comparing.
+func (b *TestBar) AssertGolden(file string) {
+	actual := b.Recording()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		assert.Nil(b.t, ioutil.WriteFile(file, []byte(actual), 0644),
+			"writing golden file %s", file)
+		return
+	}
+	expected, err := ioutil.ReadFile(file)
+	if err != nil {
+		assert.Fail(b.t, "could not read golden file",
+			"%v (set %s=1 to create it)", err, UpdateGoldenEnv)
+		return
+	}
+	assert.Equal(b.t, string(expected), actual,
+		"output differs from golden file %s (set %s=1 to update)", file, UpdateGoldenEnv)
+}
+
+// NextOutput asserts that the bar is printed, and returns the printed output.
+func (b *TestBar) NextOutput(message string) bar.Output {
+	select {
+	case out := <-b.outputs:
+		b.latest = out
+		return out
+	case <-time.After(positiveTimeout):
+		assert.Fail(b.t, "expected an update", message)
+		return nil
+	}
+}
+
+// AssertNoOutput asserts that the bar is not printed.
+func (b *TestBar) AssertNoOutput(message string) {
+	select {
+	case out := <-b.outputs:
+		b.latest = out
+		assert.Fail(b.t, "expected no update", "%s: got %v", message, out)
+	case <-time.After(negativeTimeout):
+	}
+}
+
+// LatestOutput returns the most recently printed output, consuming any
+// pending updates without waiting for new ones.
+func (b *TestBar) LatestOutput() bar.Output {
+	for {
+		select {
+		case out := <-b.outputs:
+			b.latest = out
+		default:
+			return b.latest
+		}
+	}
+}
+
+// Now returns the current virtual time.
+func (b *TestBar) Now() time.Time {
+	return timing.Now()
+}
+
+// AdvanceTo advances the virtual time to the given time, and triggers any
+// schedulers (and so module updates) that were due in the meantime.
+func (b *TestBar) AdvanceTo(when time.Time) {
+	timing.AdvanceTo(when)
+}
+
+// AdvanceBy advances the virtual time by the given duration, and triggers
+// any schedulers that were due in the meantime.
+func (b *TestBar) AdvanceBy(duration time.Duration) {
+	timing.AdvanceBy(duration)
+}
+
+// NextTick advances the virtual time to the next scheduled tick, triggers
+// the schedulers due at that time, and returns the new time.
+func (b *TestBar) NextTick() time.Time {
+	return timing.NextTick()
+}
+
+// PendingOutput returns the next printed output if there is one, without
+// waiting for the bar to print, or failing the test if it has not.
+func (b *TestBar) PendingOutput() (bar.Output, bool) {
+	select {
+	case out := <-b.outputs:
+		b.latest = out
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// AssertText asserts that the bar is eventually printed with the given
+// texts, consuming any intermediate updates.
+func (b *TestBar) AssertText(expected []string, message string) {
+	if texts(b.LatestOutput()) == fmt.Sprint(expected) {
+		return
+	}
+	timeout := time.After(positiveTimeout)
+	for {
+		select {
+		case out := <-b.outputs:
+			b.latest = out
+			if texts(out) == fmt.Sprint(expected) {
+				return
+			}
+		case <-timeout:
+			assert.Equal(b.t, fmt.Sprint(expected), texts(b.latest), message)
+			return
+		}
+	}
+}
+
+func texts(out bar.Output) string {
+	t := []string{}
+	for _, s := range out {
+		t = append(t, s.Text())
+	}
+	return fmt.Sprint(t)
+}
+
+// Click sends a left click to the segment at the given position in the
+// latest output.
+func (b *TestBar) Click(position int) {
+	b.SendEvent(position, bar.Event{Button: bar.ButtonLeft})
+}
+
+// SendEvent sends an event to the segment at the given position in the
+// latest output, in the same way as i3bar would. The instance of the
+// segment is used if the event does not specify one.
+func (b *TestBar) SendEvent(position int, e bar.Event) {
+	out := b.LatestOutput()
+	if position < 0 || position >= len(out) {
+		assert.Fail(b.t, "no segment to click", "position %d of %d", position, len(out))
+		return
+	}
+	segment := out[position]
+	if e.Instance == "" {
+		e.Instance, _ = segment["instance"].(string)
+	}
+	name, _ := segment["name"].(string)
+	event, err := json.Marshal(struct {
+		bar.Event
+		Name string `json:"name"`
+	}{e, name})
+	if err != nil {
+		assert.Fail(b.t, "could not encode event", "%v", err)
+		return
+	}
+	b.stdin.Write(append(event, ','))
+}
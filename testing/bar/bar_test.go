@@ -0,0 +1,163 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/modules/clock"
+	"github.com/soumya92/barista/outputs"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestOutputs(t *testing.T) {
+	m1 := testModule.New(t)
+	m2 := testModule.New(t)
+	b := Run(t, m1, m2)
+	defer b.Close()
+	b.AssertNoOutput("until a module updates")
+	assert.Empty(t, b.LatestOutput(), "before any output")
+
+	m2.Output(outputs.Text("world"))
+	out := b.NextOutput("on module update")
+	assert.Equal(t, 1, len(out))
+	assert.Equal(t, "world", out[0].Text())
+
+	m1.Output(outputs.Text("hello"))
+	b.AssertText([]string{"hello", "world"}, "on second module update")
+	assert.Equal(t, "hello", b.LatestOutput()[0].Text())
+
+	m1.Output(bar.Output{
+		bar.NewSegment("a"),
+		bar.NewSegment("b]},{\"full_text\":\"c"),
+	})
+	b.AssertText([]string{"a", "b]},{\"full_text\":\"c", "world"},
+		"decodes json instead of splitting on delimiters")
+
+	_, ok := b.PendingOutput()
+	assert.False(t, ok, "no pending output")
+	m2.Output(outputs.Text("last"))
+	for !ok {
+		out, ok = b.PendingOutput()
+	}
+	assert.Equal(t, "last", out[2].Text(), "pending output")
+	assert.Equal(t, "last", b.LatestOutput()[2].Text(), "pending output is latest")
+}
+
+func TestClicks(t *testing.T) {
+	m1 := testModule.New(t)
+	m2 := testModule.New(t)
+	b := New(t)
+	b.Bar.Add(m1, m2)
+	b.Start()
+	defer b.Close()
+
+	m1.Output(outputs.Text("foo"))
+	m2.Output(bar.Output{
+		bar.NewSegment("bar").Instance("x"),
+		bar.NewSegment("baz").Instance("y"),
+	})
+	b.AssertText([]string{"foo", "bar", "baz"}, "on start")
+
+	b.Click(0)
+	e := m1.AssertClicked("click on first segment")
+	assert.Equal(t, bar.ButtonLeft, e.Button)
+	m2.AssertNotClicked("click on other module")
+
+	b.SendEvent(2, bar.Event{Button: bar.ScrollDown, X: 10})
+	e = m2.AssertClicked("event on last segment")
+	assert.Equal(t, bar.Event{Button: bar.ScrollDown, X: 10, Instance: "y"}, e,
+		"uses instance from the segment")
+	m1.AssertNotClicked("event on other module")
+
+	b.SendEvent(1, bar.Event{Button: bar.ButtonRight, Instance: "z"})
+	e = m2.AssertClicked("event with instance")
+	assert.Equal(t, "z", e.Instance)
+
+	fakeT := &testing.T{}
+	b.t = fakeT
+	b.Click(3)
+	assert.True(t, fakeT.Failed(), "click on non-existent segment")
+}
+
+func TestGolden(t *testing.T) {
+	m := testModule.New(t)
+	b := Run(t, m)
+	defer b.Close()
+	m.Output(outputs.Text("foo"))
+	b.NextOutput("on update")
+
+	header := `{"version":1,"stop_signal":10,"cont_signal":12,"click_events":true}` + "\n["
+	first := `[{"full_text":"foo","name":"0"}]`
+	assert.Equal(t, header+first, b.Recording(), "recording of header and output")
+
+	m.Output(outputs.Text("bar").Urgent(true))
+	b.NextOutput("on second update")
+	second := `[{"full_text":"bar","name":"0","urgent":true}]`
+	assert.Equal(t, header+first+"\n,\n"+second, b.Recording(),
+		"recording includes separators between outputs")
+
+	dir, err := ioutil.TempDir("", "golden")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "bar.golden")
+
+	fakeT := &testing.T{}
+	b.t = fakeT
+	b.AssertGolden(golden)
+	assert.True(t, fakeT.Failed(), "missing golden file")
+
+	os.Setenv(UpdateGoldenEnv, "1")
+	b.t = t
+	b.AssertGolden(golden)
+	os.Unsetenv(UpdateGoldenEnv)
+	contents, _ := ioutil.ReadFile(golden)
+	assert.Equal(t, b.Recording(), string(contents), "golden file is updated")
+	b.AssertGolden(golden)
+
+	ioutil.WriteFile(golden, []byte(header+first), 0644)
+	fakeT = &testing.T{}
+	b.t = fakeT
+	b.AssertGolden(golden)
+	assert.True(t, fakeT.Failed(), "mismatched golden file")
+}
+
+func TestTiming(t *testing.T) {
+	start := time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)
+	b := New(t)
+	defer b.Close()
+	b.AdvanceTo(start)
+	assert.Equal(t, start, b.Now(), "virtual time")
+
+	c := clock.New().Timezone("UTC").OutputFormat("15:04").Granularity(time.Minute)
+	b.Bar.Add(c)
+	b.Start()
+	b.AssertText([]string{"12:00"}, "on start")
+
+	b.AdvanceBy(30 * time.Second)
+	b.AssertNoOutput("before the next minute")
+	b.AdvanceBy(30 * time.Second)
+	b.AssertText([]string{"12:01"}, "after a minute")
+
+	assert.Equal(t, start.Add(2*time.Minute), b.NextTick(), "next tick")
+	b.AssertText([]string{"12:02"}, "on next tick")
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package event provides helpers to synthesise mouse events in tests, and
+send them to a module or to a segment of a test bar.
+
+Builders are values, so they can be shared and extended without affecting
+each other. Typical usage would be:
+
+	event.ScrollUp.On(volume)
+	event.Left.With(bar.ShiftKey).At(10, 5).On(volume)
+	event.Right.OnSegment(testBar, 2)
+*/
+package event
+
+import (
+	"github.com/soumya92/barista/bar"
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+// Builder builds a bar.Event.
+type Builder struct {
+	event bar.Event
+}
+
+// Button returns a builder for events with the given button.
+func Button(button bar.Button) Builder {
+	return Builder{bar.Event{Button: button}}
+}
+
+// Builders for each button.
+var (
+	Left        = Button(bar.ButtonLeft)
+	Right       = Button(bar.ButtonRight)
+	Middle      = Button(bar.ButtonMiddle)
+	Back        = Button(bar.ButtonBack)
+	Forward     = Button(bar.ButtonForward)
+	ScrollUp    = Button(bar.ScrollUp)
+	ScrollDown  = Button(bar.ScrollDown)
+	ScrollLeft  = Button(bar.ScrollLeft)
+	ScrollRight = Button(bar.ScrollRight)
+)
+
+// At sets the coordinates of the event.
+func (b Builder) At(x, y int) Builder {
+	b.event.X = x
+	b.event.Y = y
+	return b
+}
+
+// With adds modifiers to the event.
+func (b Builder) With(modifiers ...bar.Modifiers) Builder {
+	for _, m := range modifiers {
+		b.event.Modifiers |= m
+	}
+	return b
+}
+
+// Instance sets the instance of the event. Events sent to a test bar
+// default to the instance of the segment.
+func (b Builder) Instance(instance string) Builder {
+	b.event.Instance = instance
+	return b
+}
+
+// Event returns the built event.
+func (b Builder) Event() bar.Event {
+	return b.event
+}
+
+// On sends the event to the given module.
+func (b Builder) On(m bar.Clickable) {
+	m.Click(b.event)
+}
+
+// Repeat sends the event to the given module n times, e.g. to scroll
+// multiple steps.
+func (b Builder) Repeat(n int, m bar.Clickable) {
+	for i := 0; i < n; i++ {
+		b.On(m)
+	}
+}
+
+// OnSegment sends the event to the segment at the given position in the
+// latest output of the test bar.
+func (b Builder) OnSegment(t *testBar.TestBar, position int) {
+	t.SendEvent(position, b.event)
+}
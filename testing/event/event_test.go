@@ -0,0 +1,70 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+	testModule "github.com/soumya92/barista/testing/module"
+)
+
+func TestBuilder(t *testing.T) {
+	assert.Equal(t, bar.Event{Button: bar.ButtonLeft}, Left.Event())
+	assert.Equal(t, bar.Event{Button: bar.ScrollDown}, Button(bar.ScrollDown).Event())
+
+	e := ScrollUp.At(10, 5).With(bar.ShiftKey).With(bar.ControlKey, bar.Mod1Key).Instance("foo")
+	assert.Equal(t, bar.Event{
+		Button:    bar.ScrollUp,
+		X:         10,
+		Y:         5,
+		Modifiers: bar.ShiftKey | bar.ControlKey | bar.Mod1Key,
+		Instance:  "foo",
+	}, e.Event())
+	assert.Equal(t, bar.Event{Button: bar.ScrollUp}, ScrollUp.Event(),
+		"builders are not modified")
+}
+
+func TestModule(t *testing.T) {
+	m := testModule.New(t)
+	Right.At(1, 2).On(m)
+	assert.Equal(t, bar.Event{Button: bar.ButtonRight, X: 1, Y: 2},
+		m.AssertClicked("on click"))
+
+	ScrollDown.Repeat(3, m)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, bar.ScrollDown, m.AssertClicked("on repeated scroll").Button)
+	}
+	m.AssertNotClicked("after repeated events")
+}
+
+func TestBar(t *testing.T) {
+	m1 := testModule.New(t)
+	m2 := testModule.New(t)
+	b := testBar.Run(t, m1, m2)
+	defer b.Close()
+	m1.Output(outputs.Text("foo"))
+	m2.Output(bar.Output{bar.NewSegment("bar").Instance("x")})
+	b.AssertText([]string{"foo", "bar"}, "on start")
+
+	Middle.With(bar.Mod4Key).OnSegment(b, 1)
+	assert.Equal(t, bar.Event{Button: bar.ButtonMiddle, Modifiers: bar.Mod4Key, Instance: "x"},
+		m2.AssertClicked("on click"), "modifiers are sent through the bar")
+	m1.AssertNotClicked("on click on other module")
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package hostfs builds temporary sysfs and procfs trees for tests, and
+points base/hostfs at them, so that modules reading hardware information
+can be tested deterministically using their real code paths.
+
+Typical usage would be:
+
+	f := hostfs.New(t)
+	defer f.Close()
+	f.Battery("BAT0", map[string]interface{}{"STATUS": "Charging"})
+	f.Hwmon(0, "coretemp").Sensor("temp1", 52000, "Package id 0")
+	f.NetDev("eth0", 1024, 10, 2048, 20)
+*/
+package hostfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/hostfs"
+)
+
+// Fixture is a temporary host filesystem tree.
+type Fixture struct {
+	t        assert.TestingT
+	dir      string
+	previous string
+	netDevs  map[string][4]uint64
+}
+
+// New creates an empty fixture tree, and makes it the host root until
+// Close is called.
+func New(t assert.TestingT) *Fixture {
+	dir, err := ioutil.TempDir("", "hostfs")
+	assert.Nil(t, err, "creating fixture tree")
+	f := &Fixture{t: t, dir: dir, previous: hostfs.Root(), netDevs: map[string][4]uint64{}}
+	hostfs.SetRoot(dir)
+	return f
+}
+
+// Close restores the previous host root and removes the fixture tree.
+func (f *Fixture) Close() {
+	hostfs.SetRoot(f.previous)
+	os.RemoveAll(f.dir)
+}
+
+// Path returns the real path of the given path in the fixture tree.
+func (f *Fixture) Path(path string) string {
+	return filepath.Join(f.dir, path)
+}
+
+// WriteFile writes a file in the fixture tree, creating any parent
+// directories. sysfs values are followed by a newline, so one is added
+// if missing.
+func (f *Fixture) WriteFile(path, contents string) *Fixture {
+	real := f.Path(path)
+	assert.Nil(f.t, os.MkdirAll(filepath.Dir(real), 0755), "creating parent of %s", path)
+	if !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	assert.Nil(f.t, ioutil.WriteFile(real, []byte(contents), 0644), "writing %s", path)
+	return f
+}
+
+// Remove removes a file or directory from the fixture tree, e.g. to
+// simulate a device being unplugged.
+func (f *Fixture) Remove(path string) *Fixture {
+	assert.Nil(f.t, os.RemoveAll(f.Path(path)), "removing %s", path)
+	return f
+}
+
+// Battery writes the uevent file for a power supply, using the given
+// properties without the POWER_SUPPLY_ prefix (e.g. "STATUS", "ENERGY_NOW").
+func (f *Fixture) Battery(name string, props map[string]interface{}) *Fixture {
+	lines := []string{"POWER_SUPPLY_NAME=" + name}
+	for key, value := range props {
+		if key == "NAME" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("POWER_SUPPLY_%s=%v", key, value))
+	}
+	sort.Strings(lines[1:])
+	return f.WriteFile(
+		filepath.Join("/sys/class/power_supply", name, "uevent"),
+		strings.Join(lines, "\n"))
+}
+
+// Hwmon is a hardware monitoring chip in a fixture tree.
+type Hwmon struct {
+	fixture *Fixture
+	dir     string
+}
+
+// Hwmon creates a hardware monitoring chip with the given index and name,
+// at /sys/class/hwmon/hwmon<index>.
+func (f *Fixture) Hwmon(index int, chip string) *Hwmon {
+	dir := fmt.Sprintf("/sys/class/hwmon/hwmon%d", index)
+	f.WriteFile(filepath.Join(dir, "name"), chip)
+	return &Hwmon{f, dir}
+}
+
+// Sensor writes the input (e.g. "temp1" or "fan2") of the chip, in the raw
+// sysfs units (e.g. millidegrees), and its label if not empty.
+func (h *Hwmon) Sensor(input string, value interface{}, label string) *Hwmon {
+	h.fixture.WriteFile(filepath.Join(h.dir, input+"_input"), fmt.Sprint(value))
+	if label != "" {
+		h.fixture.WriteFile(filepath.Join(h.dir, input+"_label"), label)
+	}
+	return h
+}
+
+// File writes any other file of the chip, e.g. "fan2_min".
+func (h *Hwmon) File(name string, contents string) *Hwmon {
+	h.fixture.WriteFile(filepath.Join(h.dir, name), contents)
+	return h
+}
+
+// NetDev sets the counters for a network interface, in both /proc/net/dev
+// and /sys/class/net/<iface>/statistics.
+func (f *Fixture) NetDev(iface string, rxBytes, rxPackets, txBytes, txPackets uint64) *Fixture {
+	f.netDevs[iface] = [4]uint64{rxBytes, rxPackets, txBytes, txPackets}
+	stats := filepath.Join("/sys/class/net", iface, "statistics")
+	f.WriteFile(filepath.Join(stats, "rx_bytes"), fmt.Sprint(rxBytes))
+	f.WriteFile(filepath.Join(stats, "rx_packets"), fmt.Sprint(rxPackets))
+	f.WriteFile(filepath.Join(stats, "tx_bytes"), fmt.Sprint(txBytes))
+	f.WriteFile(filepath.Join(stats, "tx_packets"), fmt.Sprint(txPackets))
+
+	var ifaces []string
+	for name := range f.netDevs {
+		ifaces = append(ifaces, name)
+	}
+	sort.Strings(ifaces)
+	lines := []string{
+		"Inter-|   Receive                                                |  Transmit",
+		" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed",
+	}
+	for _, name := range ifaces {
+		c := f.netDevs[name]
+		lines = append(lines, fmt.Sprintf("%6s: %d %d 0 0 0 0 0 0 %d %d 0 0 0 0 0 0",
+			name, c[0], c[1], c[2], c[3]))
+	}
+	return f.WriteFile("/proc/net/dev", strings.Join(lines, "\n"))
+}
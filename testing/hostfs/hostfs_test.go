@@ -0,0 +1,70 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/hostfs"
+)
+
+func read(t *testing.T, path string) string {
+	data, err := afero.ReadFile(hostfs.Fs(), path)
+	assert.Nil(t, err, "reading %s", path)
+	return string(data)
+}
+
+func TestFixture(t *testing.T) {
+	f := New(t)
+	assert.Equal(t, f.dir, hostfs.Root(), "fixture is the host root")
+
+	f.WriteFile("/proc/uptime", "1.5 2.5")
+	assert.Equal(t, "1.5 2.5\n", read(t, "/proc/uptime"))
+	f.Remove("/proc/uptime")
+	_, err := hostfs.Fs().Stat("/proc/uptime")
+	assert.True(t, os.IsNotExist(err), "removed file")
+
+	f.Battery("BAT0", map[string]interface{}{"STATUS": "Charging", "ENERGY_NOW": 5000})
+	assert.Equal(t,
+		"POWER_SUPPLY_NAME=BAT0\nPOWER_SUPPLY_ENERGY_NOW=5000\nPOWER_SUPPLY_STATUS=Charging\n",
+		read(t, "/sys/class/power_supply/BAT0/uevent"))
+
+	f.Hwmon(1, "coretemp").
+		Sensor("temp1", 52000, "Package id 0").
+		Sensor("fan2", 1150, "").
+		File("fan2_min", "300")
+	assert.Equal(t, "coretemp\n", read(t, "/sys/class/hwmon/hwmon1/name"))
+	assert.Equal(t, "52000\n", read(t, "/sys/class/hwmon/hwmon1/temp1_input"))
+	assert.Equal(t, "Package id 0\n", read(t, "/sys/class/hwmon/hwmon1/temp1_label"))
+	assert.Equal(t, "300\n", read(t, "/sys/class/hwmon/hwmon1/fan2_min"))
+	_, err = hostfs.Fs().Stat("/sys/class/hwmon/hwmon1/fan2_label")
+	assert.True(t, os.IsNotExist(err), "no label")
+
+	f.NetDev("wlan0", 1, 2, 3, 4)
+	f.NetDev("eth0", 10, 20, 30, 40)
+	assert.Equal(t, "30\n", read(t, "/sys/class/net/eth0/statistics/tx_bytes"))
+	dev := read(t, "/proc/net/dev")
+	assert.Contains(t, dev, "  eth0: 10 20 0 0 0 0 0 0 30 40 0 0 0 0 0 0\n ")
+	assert.Contains(t, dev, " wlan0: 1 2 0 0 0 0 0 0 3 4 0 0 0 0 0 0\n")
+
+	f.Close()
+	assert.Equal(t, "/", hostfs.Root(), "previous root is restored")
+	_, err = os.Stat(f.dir)
+	assert.True(t, os.IsNotExist(err), "fixture tree is removed")
+}
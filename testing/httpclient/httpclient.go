@@ -0,0 +1,253 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package httpclient stubs the shared HTTP transport used by base/httpclient,
+so that modules backed by web APIs can be tested without the network.
+
+Responses are registered for url prefixes, and can have a status, headers,
+latency, or fail with a network error. Multiple responses can be queued for
+the same url, e.g. to test recovery from errors.
+
+Typical usage would be:
+
+	stub := httpclient.Stub(t)
+	defer stub.Close()
+	stub.Handle("https://api.example.com/quote").Status(503).Times(1)
+	stub.Handle("https://api.example.com/quote").JSON(quote{Price: 42})
+	// ... test the module
+	assert.Equal(t, 2, len(stub.Requests("https://api.example.com/quote")))
+
+Clients retry failed requests by default, so tests of error handling
+should usually disable retries for the module's client.
+*/
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/httpclient"
+)
+
+// Server is a stubbed transport that serves canned responses.
+type Server struct {
+	t        assert.TestingT
+	previous http.RoundTripper
+
+	mutex    sync.Mutex
+	routes   map[string][]*Response
+	requests []*http.Request
+}
+
+// Stub replaces the shared transport with a stub server. Requests for urls
+// without a registered response fail the test and return 404 Not Found.
+// Close must be called to restore the previous transport.
+func Stub(t assert.TestingT) *Server {
+	s := &Server{t: t, routes: map[string][]*Response{}}
+	s.previous = httpclient.SetTransport(s)
+	return s
+}
+
+// Close restores the transport that was replaced by the stub.
+func (s *Server) Close() {
+	httpclient.SetTransport(s.previous)
+}
+
+// Handle queues a new response for requests to urls starting with the given
+// prefix. The longest matching prefix is used for each request. Each queued
+// response is used once, or as many times as set using Times, before the
+// next one. The last response for a prefix is used for all subsequent
+// requests, unless it is limited using Times.
+func (s *Server) Handle(prefix string) *Response {
+	r := &Response{status: http.StatusOK, header: http.Header{}}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.routes[prefix] = append(s.routes[prefix], r)
+	return r
+}
+
+// Requests returns all requests made to urls starting with the given prefix,
+// in the order they were made. The bodies of the returned requests can be
+// read, even after the request was made.
+func (s *Server) Requests(prefix string) []*http.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var requests []*http.Request
+	for _, r := range s.requests {
+		if strings.HasPrefix(r.URL.String(), prefix) {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// RoundTrip conforms to http.RoundTripper.
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := s.next(req)
+	if r == nil {
+		assert.Fail(s.t, "unexpected request", "no response for %s", req.URL)
+		return s.response(req, http.StatusNotFound, http.Header{}, nil), nil
+	}
+	if r.latency > 0 {
+		select {
+		case <-time.After(r.latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return s.response(req, r.status, r.header, r.body), nil
+}
+
+// recorded returns a copy of the request for Requests, with its own copy
+// of the body, so that tests can inspect the body (e.g. using ParseForm)
+// after the client has consumed the original.
+func recorded(req *http.Request) *http.Request {
+	if req.Body == nil {
+		return req
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r := req.Clone(req.Context())
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return r
+}
+
+// next records the request and returns the response for it, or nil if no
+// response was registered.
+func (s *Server) next(req *http.Request) *Response {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, recorded(req))
+	url := req.URL.String()
+	longest := ""
+	found := false
+	for prefix := range s.routes {
+		if strings.HasPrefix(url, prefix) && (!found || len(prefix) > len(longest)) {
+			longest = prefix
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	responses := s.routes[longest]
+	for len(responses) > 0 {
+		r := responses[0]
+		exhausted := r.times > 0 && r.used >= r.times
+		// Responses without a limit are used once if more are queued.
+		replaced := r.times == 0 && r.used > 0 && len(responses) > 1
+		if !exhausted && !replaced {
+			break
+		}
+		responses = responses[1:]
+	}
+	s.routes[longest] = responses
+	if len(responses) == 0 {
+		return nil
+	}
+	responses[0].used++
+	return responses[0]
+}
+
+func (s *Server) response(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// Response is a canned response for a url prefix.
+type Response struct {
+	status  int
+	header  http.Header
+	body    []byte
+	latency time.Duration
+	err     error
+	times   int
+	used    int
+}
+
+// Status sets the status code of the response.
+func (r *Response) Status(status int) *Response {
+	r.status = status
+	return r
+}
+
+// Header sets a header on the response, e.g. Cache-Control or ETag.
+func (r *Response) Header(key, value string) *Response {
+	r.header.Set(key, value)
+	return r
+}
+
+// Body sets the body of the response.
+func (r *Response) Body(body string) *Response {
+	r.body = []byte(body)
+	return r
+}
+
+// JSON sets the body of the response to the JSON encoding of the value.
+func (r *Response) JSON(value interface{}) *Response {
+	body, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	r.body = body
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// Latency delays the response by the given duration. Requests that time out
+// before then fail with the timeout error.
+func (r *Response) Latency(latency time.Duration) *Response {
+	r.latency = latency
+	return r
+}
+
+// Fail makes requests fail with the given error, as if the network failed.
+// A generic error is used if err is nil.
+func (r *Response) Fail(err error) *Response {
+	if err == nil {
+		err = errors.New("stubbed network error")
+	}
+	r.err = err
+	return r
+}
+
+// Times sets the number of requests the response is used for, after which
+// the next queued response is used.
+func (r *Response) Times(times int) *Response {
+	r.times = times
+	return r
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/httpclient"
+)
+
+var client = httpclient.New().Retry(0, 0)
+
+func TestResponses(t *testing.T) {
+	stub := Stub(t)
+	defer stub.Close()
+	stub.Handle("https://example.com/").Body("root")
+	stub.Handle("https://example.com/json").JSON(map[string]int{"a": 1})
+	stub.Handle("https://example.com/error").Status(503)
+
+	body, err := client.Get("https://example.com/foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "root", string(body), "prefix match")
+
+	var out map[string]int
+	assert.Nil(t, client.GetJSON("https://example.com/json?q=1", &out))
+	assert.Equal(t, map[string]int{"a": 1}, out, "longest prefix match")
+
+	_, err = client.Get("https://example.com/error")
+	assert.Error(t, err, "error status")
+
+	assert.Equal(t, 1, len(stub.Requests("https://example.com/json")))
+	assert.Equal(t, 3, len(stub.Requests("https://example.com/")))
+	assert.Equal(t, "q=1", stub.Requests("https://example.com/json")[0].URL.RawQuery)
+
+	fakeT := &testing.T{}
+	stub.t = fakeT
+	_, err = client.Get("https://other.example.com/")
+	assert.Error(t, err, "unregistered url")
+	assert.True(t, fakeT.Failed(), "unregistered url fails the test")
+}
+
+func TestQueuedResponses(t *testing.T) {
+	stub := Stub(t)
+	defer stub.Close()
+	stub.Handle("https://example.com/").Fail(nil).Times(2)
+	stub.Handle("https://example.com/").Body("ok")
+	stub.Handle("https://example.com/").Body("last").Times(1)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Get("https://example.com/")
+		assert.Error(t, err, "network error")
+	}
+	body, err := client.Get("https://example.com/")
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", string(body), "after limited responses")
+	body, _ = client.Get("https://example.com/")
+	assert.Equal(t, "last", string(body), "unlimited responses are used once if more are queued")
+
+	fakeT := &testing.T{}
+	stub.t = fakeT
+	_, err = client.Get("https://example.com/")
+	assert.Error(t, err, "after all responses are used")
+	assert.True(t, fakeT.Failed(), "after all responses are used")
+}
+
+func TestFailures(t *testing.T) {
+	stub := Stub(t)
+	defer stub.Close()
+	stub.Handle("https://example.com/fail").Fail(errors.New("foo"))
+	stub.Handle("https://example.com/slow").Latency(50 * time.Millisecond).Body("slow")
+	stub.Handle("https://example.com/timeout").Latency(time.Minute)
+	stub.Handle("https://example.com/cached").Body("cached").Header("Cache-Control", "max-age=60")
+
+	_, err := client.Get("https://example.com/fail")
+	assert.Contains(t, err.Error(), "foo")
+
+	start := time.Now()
+	body, err := client.Get("https://example.com/slow")
+	assert.Nil(t, err)
+	assert.Equal(t, "slow", string(body))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond, "latency")
+
+	_, err = httpclient.New().Retry(0, 0).Timeout(10 * time.Millisecond).
+		Get("https://example.com/timeout")
+	assert.Error(t, err, "times out")
+
+	// A new client, since responses are cached per client.
+	cachingClient := httpclient.New().Retry(0, 0)
+	cachingClient.Get("https://example.com/cached")
+	cachingClient.Get("https://example.com/cached")
+	assert.Equal(t, 1, len(stub.Requests("https://example.com/cached")),
+		"headers are used by the client")
+}
+
+func TestClose(t *testing.T) {
+	stub := Stub(t)
+	stub.Handle("https://example.com/").Body("stub")
+	inner := Stub(t)
+	inner.Handle("https://example.com/").Body("inner")
+	body, _ := client.Get("https://example.com/")
+	assert.Equal(t, "inner", string(body))
+	inner.Close()
+	body, _ = client.Get("https://example.com/")
+	assert.Equal(t, "stub", string(body), "previous transport is restored")
+	stub.Close()
+}
+
+func TestRequestBody(t *testing.T) {
+	stub := Stub(t)
+	defer stub.Close()
+	stub.Handle("https://example.com/token").JSON(map[string]string{"token": "abc"})
+
+	c := &http.Client{Transport: httpclient.Transport()}
+	resp, err := c.PostForm("https://example.com/token", url.Values{"code": {"123"}})
+	assert.Nil(t, err)
+	resp.Body.Close()
+
+	requests := stub.Requests("https://example.com/token")
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "POST", requests[0].Method)
+	assert.Nil(t, requests[0].ParseForm())
+	assert.Equal(t, "123", requests[0].PostForm.Get("code"), "body is recorded")
+}
@@ -16,6 +16,7 @@
 package module
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -27,13 +28,17 @@ import (
 // Time to wait for events. Overridden in tests.
 var positiveTimeout = time.Second
 
-// TestModule represents a bar.Module used for testing.
+// TestModule represents a bar.Module used for testing. It also implements
+// Update, so it can be used as a base.Module in tests of groups and other
+// modules that wrap modules.
 type TestModule struct {
 	assert  *assert.Assertions
 	started bool
+	mutex   sync.Mutex
 	outputs chan bar.Output
 	pauses  chan bool
 	events  chan bar.Event
+	updates chan bool
 }
 
 // New creates a new module with the given testingT that can be used
@@ -46,6 +51,8 @@ func New(t assert.TestingT) *TestModule {
 
 // Stream conforms to bar.Module.
 func (t *TestModule) Stream() <-chan bar.Output {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	if t.started {
 		panic("already streaming!")
 	}
@@ -68,6 +75,11 @@ func (t *TestModule) Resume() {
 	t.pauses <- false
 }
 
+// Update conforms to base.Module.
+func (t *TestModule) Update() {
+	t.updates <- true
+}
+
 // Output queues output to be sent over the channel on the next read.
 func (t *TestModule) Output(out bar.Output) {
 	t.outputs <- out
@@ -75,11 +87,15 @@ func (t *TestModule) Output(out bar.Output) {
 
 // AssertStarted asserts that the module was started.
 func (t *TestModule) AssertStarted(message string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.assert.True(t.started, message)
 }
 
 // AssertNotStarted asserts that the module was not started.
 func (t *TestModule) AssertNotStarted(message string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.assert.False(t.started, message)
 }
 
@@ -135,18 +151,41 @@ func (t *TestModule) AssertNotClicked(message string) {
 	}
 }
 
-// Reset clears the history of pause/resume/click/stream invocations,
+// AssertUpdated asserts that an update was requested, and consumes it.
+// Calling this multiple times asserts multiple updates.
+func (t *TestModule) AssertUpdated(message string) {
+	select {
+	case <-t.updates:
+	case <-time.After(positiveTimeout):
+		t.assert.Fail("expected an update", message)
+	}
+}
+
+// AssertNotUpdated asserts that no updates were requested.
+func (t *TestModule) AssertNotUpdated(message string) {
+	select {
+	case <-t.updates:
+		t.assert.Fail("expected no update", message)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// Reset clears the history of pause/resume/click/update/stream invocations,
 // flushes any buffered events and resets the output channel.
 func (t *TestModule) Reset() {
 	if t.outputs != nil {
 		close(t.outputs)
 		close(t.events)
 		close(t.pauses)
+		close(t.updates)
 	}
 	t.outputs = make(chan bar.Output, 100)
 	t.events = make(chan bar.Event, 100)
 	t.pauses = make(chan bool, 100)
+	t.updates = make(chan bool, 100)
+	t.mutex.Lock()
 	t.started = false
+	t.mutex.Unlock()
 }
 
 // OutputTester groups an output channel and testing.T to simplify
@@ -113,6 +113,29 @@ func TestClick(t *testing.T) {
 	assert.True(t, fakeT.Failed(), "AssertNotClicked when clicked")
 }
 
+func TestUpdate(t *testing.T) {
+	positiveTimeout = 10 * time.Millisecond
+
+	m := New(t)
+	m.AssertNotUpdated("no updates initially")
+	m.Update()
+	m.Update()
+	m.AssertUpdated("when module is updated")
+	m.AssertUpdated("updates are buffered")
+	m.AssertNotUpdated("updates cleared after assertions")
+
+	fakeT := &testing.T{}
+	m = New(fakeT)
+	m.AssertUpdated("fails when not updated")
+	assert.True(t, fakeT.Failed(), "AssertUpdated when not updated")
+
+	fakeT = &testing.T{}
+	m = New(fakeT)
+	m.Update()
+	m.AssertNotUpdated("fails when updated")
+	assert.True(t, fakeT.Failed(), "AssertNotUpdated when updated")
+}
+
 func TestPause(t *testing.T) {
 	positiveTimeout = 10 * time.Millisecond
 
@@ -189,11 +212,13 @@ func TestReset(t *testing.T) {
 	m.Output(outputs.Empty())
 	m.Output(outputs.Text("test"))
 	m.Click(bar.Event{})
+	m.Update()
 	m.Stream()
 	m.AssertStarted("some assertions before reset")
 	m.AssertPaused("some assertions before reset")
 	m.Reset()
 	m.AssertNotClicked("reset resets events")
+	m.AssertNotUpdated("reset resets updates")
 	m.AssertNoPauseResume("reset resets pause/resume")
 	var ch <-chan bar.Output
 	assert.NotPanics(t, func() { ch = m.Stream() }, "start after reset")
@@ -0,0 +1,195 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package output provides assertions on module output, for tests that check
+the text, colours, urgency, and structure of the output instead of comparing
+raw segments.
+
+Failures describe the property and segment being checked, along with the
+complete segment, so that it is clear what was different.
+
+Typical usage would be:
+
+	out := tester.AssertOutput("on start")
+	assertOutput := output.New(t, out, "on start")
+	assertOutput.Text("85%").Color(red).Urgent(false)
+
+or, for output with multiple segments:
+
+	assertOutput.Texts("eth0", "wlan0")
+	assertOutput.Segment(1).Instance("wlan0").Color(green)
+*/
+package output
+
+import (
+	"fmt"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+)
+
+// Assertions groups assertions on a bar.Output.
+type Assertions struct {
+	t       assert.TestingT
+	out     bar.Output
+	message string
+}
+
+// New creates assertions on the given output. The message is included in
+// all failures, to distinguish between multiple outputs being checked.
+func New(t assert.TestingT, out bar.Output, message string) *Assertions {
+	return &Assertions{t, out, message}
+}
+
+// Len asserts that the output has the given number of segments.
+func (a *Assertions) Len(length int) *Assertions {
+	assert.Equal(a.t, length, len(a.out), "%s: number of segments in %v", a.message, a.out)
+	return a
+}
+
+// Empty asserts that the output has no segments.
+func (a *Assertions) Empty() *Assertions {
+	return a.Len(0)
+}
+
+// Texts asserts that the output has segments with exactly the given texts.
+func (a *Assertions) Texts(texts ...string) *Assertions {
+	actual := []string{}
+	for _, s := range a.out {
+		actual = append(actual, s.Text())
+	}
+	if texts == nil {
+		texts = []string{}
+	}
+	assert.Equal(a.t, texts, actual, "%s: texts of segments", a.message)
+	return a
+}
+
+// Segment returns assertions on the segment at the given index. If there
+// is no such segment, it fails and returns assertions on an empty segment.
+func (a *Assertions) Segment(index int) *Segment {
+	if index < 0 || index >= len(a.out) {
+		assert.Fail(a.t, "no such segment",
+			"%s: segment %d of %d in %v", a.message, index, len(a.out), a.out)
+		return &Segment{a, index, bar.Segment{}}
+	}
+	return &Segment{a, index, a.out[index]}
+}
+
+// Text asserts that the output has a single segment with the given text,
+// and returns assertions on that segment.
+func (a *Assertions) Text(text string) *Segment {
+	if len(a.out) != 1 {
+		assert.Fail(a.t, "expected a single segment", "%s: got %v", a.message, a.out)
+		return &Segment{a, 0, bar.Segment{}}
+	}
+	return a.Segment(0).Text(text)
+}
+
+// Segment groups assertions on a single segment of an output.
+type Segment struct {
+	output  *Assertions
+	index   int
+	segment bar.Segment
+}
+
+// equal asserts that the given key of the segment has the expected value.
+func (s *Segment) equal(key string, expected, actual interface{}) *Segment {
+	assert.Equal(s.output.t, expected, actual, "%s: %s of segment %d in %v",
+		s.output.message, key, s.index, s.segment)
+	return s
+}
+
+// stringValue returns the value of the given key as a string, which allows
+// comparing outputs decoded from json with outputs from modules.
+func (s *Segment) stringValue(key string) string {
+	if v, ok := s.segment[key]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// Text asserts that the segment has the given text.
+func (s *Segment) Text(text string) *Segment {
+	return s.equal("full_text", text, s.stringValue("full_text"))
+}
+
+// ShortText asserts that the segment has the given short text, where empty
+// means no short text.
+func (s *Segment) ShortText(text string) *Segment {
+	return s.equal("short_text", text, s.stringValue("short_text"))
+}
+
+// Color asserts that the segment has the given colour, where empty means
+// the default colour.
+func (s *Segment) Color(color bar.Color) *Segment {
+	return s.equal("color", color, bar.Color(s.stringValue("color")))
+}
+
+// Background asserts that the segment has the given background colour,
+// where empty means the default background.
+func (s *Segment) Background(color bar.Color) *Segment {
+	return s.equal("background", color, bar.Color(s.stringValue("background")))
+}
+
+// Border asserts that the segment has the given border colour, where empty
+// means the default border.
+func (s *Segment) Border(color bar.Color) *Segment {
+	return s.equal("border", color, bar.Color(s.stringValue("border")))
+}
+
+// Urgent asserts the urgency of the segment, where unset is not urgent.
+func (s *Segment) Urgent(urgent bool) *Segment {
+	actual, _ := s.segment["urgent"].(bool)
+	return s.equal("urgent", urgent, actual)
+}
+
+// Markup asserts that the segment has the given markup, where unset is
+// treated as bar.MarkupNone.
+func (s *Segment) Markup(markup bar.Markup) *Segment {
+	actual := bar.Markup(s.stringValue("markup"))
+	if actual == "" {
+		actual = bar.MarkupNone
+	}
+	return s.equal("markup", markup, actual)
+}
+
+// Instance asserts that the segment has the given instance.
+func (s *Segment) Instance(instance string) *Segment {
+	return s.equal("instance", instance, s.stringValue("instance"))
+}
+
+// Has asserts that the segment has a value for the given key.
+func (s *Segment) Has(key string) *Segment {
+	_, ok := s.segment[key]
+	assert.True(s.output.t, ok, "%s: %s of segment %d is not set in %v",
+		s.output.message, key, s.index, s.segment)
+	return s
+}
+
+// Lacks asserts that the segment does not have a value for the given key.
+func (s *Segment) Lacks(key string) *Segment {
+	_, ok := s.segment[key]
+	assert.False(s.output.t, ok, "%s: %s of segment %d is set in %v",
+		s.output.message, key, s.index, s.segment)
+	return s
+}
+
+// Segment returns assertions on another segment of the same output, to
+// allow chaining assertions on multiple segments.
+func (s *Segment) Segment(index int) *Segment {
+	return s.output.Segment(index)
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/outputs"
+)
+
+func TestSingleSegment(t *testing.T) {
+	out := outputs.Text("85%").Color(bar.Color("red")).Urgent(true)
+	New(t, out, "single segment").
+		Len(1).
+		Texts("85%").
+		Text("85%").
+		Color("red").
+		Background("").
+		Border("").
+		Urgent(true).
+		Markup(bar.MarkupNone).
+		ShortText("").
+		Instance("").
+		Has("color").
+		Lacks("background")
+
+	New(t, outputs.Empty(), "empty").Empty().Texts()
+	New(t, outputs.Text("foo"), "unset urgency").Text("foo").Urgent(false)
+}
+
+func TestMultipleSegments(t *testing.T) {
+	out := bar.Output{
+		bar.NewSegment("eth0").Instance("eth0"),
+		bar.NewSegment("<b>wlan0</b>").Instance("wlan0").
+			Markup(bar.MarkupPango).Background(bar.Color("#00ff00")),
+	}
+	New(t, out, "multiple segments").
+		Texts("eth0", "<b>wlan0</b>").
+		Segment(0).Instance("eth0").Background("").
+		Segment(1).Instance("wlan0").Markup(bar.MarkupPango).Background("#00ff00")
+}
+
+func TestDecodedOutput(t *testing.T) {
+	bytes, _ := json.Marshal(outputs.Text("foo").Color(bar.Color("#ff0000")))
+	var out bar.Output
+	assert.Nil(t, json.Unmarshal(bytes, &out))
+	New(t, out, "decoded from json").Text("foo").Color("#ff0000")
+}
+
+func TestFailures(t *testing.T) {
+	out := bar.Output{
+		bar.NewSegment("foo").Color(bar.Color("red")),
+		bar.NewSegment("bar"),
+	}
+	for name, assertion := range map[string]func(*Assertions){
+		"length":        func(a *Assertions) { a.Len(1) },
+		"empty":         func(a *Assertions) { a.Empty() },
+		"texts":         func(a *Assertions) { a.Texts("foo") },
+		"single":        func(a *Assertions) { a.Text("foo") },
+		"missing":       func(a *Assertions) { a.Segment(2) },
+		"text":          func(a *Assertions) { a.Segment(1).Text("baz") },
+		"color":         func(a *Assertions) { a.Segment(0).Color("blue") },
+		"default color": func(a *Assertions) { a.Segment(1).Color("red") },
+		"urgent":        func(a *Assertions) { a.Segment(0).Urgent(true) },
+		"markup":        func(a *Assertions) { a.Segment(0).Markup(bar.MarkupPango) },
+		"has":           func(a *Assertions) { a.Segment(1).Has("color") },
+		"lacks":         func(a *Assertions) { a.Segment(0).Lacks("color") },
+	} {
+		fakeT := &testing.T{}
+		assertion(New(fakeT, out, name))
+		assert.True(t, fakeT.Failed(), "fails on mismatched %s", name)
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package pango parses pango markup for tests, and asserts on its structure
+and attributes rather than on the exact markup, which changes with the
+order of attributes, quoting, and escaping.
+
+Typical usage would be:
+
+	markup := pango.New(t, out[0].Text(), "on start")
+	markup.Text("Red Bold Text")
+	markup.Span("Bold Text").Attr("weight", "bold").Color("#ff0000").Depth(2)
+	markup.Span(iconGlyph).Font("FontAwesome")
+*/
+package pango
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+// Node is a parsed pango node, either an element or text.
+type Node struct {
+	// Tag is the name of the element, or empty for text nodes.
+	Tag string
+	// Attrs are the attributes of the element.
+	Attrs map[string]string
+	// Text is the unescaped text of a text node.
+	Text     string
+	Children []*Node
+	Parent   *Node
+}
+
+// Parse parses pango markup into a tree, rooted at a node with no tag.
+func Parse(markup string) (*Node, error) {
+	root := &Node{Attrs: map[string]string{}}
+	decoder := xml.NewDecoder(strings.NewReader("<markup>" + markup + "</markup>"))
+	decoder.Strict = true
+	current := root
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				// The wrapping element.
+				continue
+			}
+			n := &Node{Tag: t.Name.Local, Attrs: map[string]string{}, Parent: current}
+			for _, a := range t.Attr {
+				n.Attrs[a.Name.Local] = a.Value
+			}
+			current.Children = append(current.Children, n)
+			current = n
+		case xml.EndElement:
+			depth--
+			if depth > 0 {
+				current = current.Parent
+			}
+		case xml.CharData:
+			current.Children = append(current.Children,
+				&Node{Text: string(t), Parent: current})
+		}
+	}
+	if current != root {
+		return nil, errors.New("unclosed tags")
+	}
+	return root, nil
+}
+
+// PlainText returns the text of the node and all its descendants.
+func (n *Node) PlainText() string {
+	if n.Tag == "" && n.Children == nil {
+		return n.Text
+	}
+	var out strings.Builder
+	for _, c := range n.Children {
+		out.WriteString(c.PlainText())
+	}
+	return out.String()
+}
+
+// Depth returns the number of elements enclosing the node.
+func (n *Node) Depth() int {
+	depth := 0
+	for p := n.Parent; p != nil && p.Parent != nil; p = p.Parent {
+		depth++
+	}
+	if n.Tag != "" && n.Parent != nil {
+		depth++
+	}
+	return depth
+}
+
+// Attr returns the value of the attribute in effect for the node, which is
+// the value set by the nearest enclosing element (or the node itself).
+func (n *Node) Attr(names ...string) (string, bool) {
+	for e := n; e != nil; e = e.Parent {
+		for _, name := range names {
+			if v, ok := e.Attrs[name]; ok {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// find returns the innermost element whose plain text contains the text.
+func (n *Node) find(text string) *Node {
+	if !strings.Contains(n.PlainText(), text) {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Tag == "" {
+			continue
+		}
+		if found := c.find(text); found != nil {
+			return found
+		}
+	}
+	return n
+}
+
+func (n *Node) String() string {
+	if n.Tag == "" && n.Parent != nil {
+		return fmt.Sprintf("%q", n.Text)
+	}
+	var parts []string
+	for _, c := range n.Children {
+		parts = append(parts, c.String())
+	}
+	if n.Tag == "" {
+		return strings.Join(parts, " ")
+	}
+	return fmt.Sprintf("<%s %v>[%s]", n.Tag, n.Attrs, strings.Join(parts, " "))
+}
+
+// Assertions groups assertions on pango markup.
+type Assertions struct {
+	t       assert.TestingT
+	root    *Node
+	message string
+}
+
+// New parses the markup and returns assertions on it. Invalid markup fails
+// the test, and is treated as empty.
+func New(t assert.TestingT, markup string, message string) *Assertions {
+	root, err := Parse(markup)
+	if err != nil {
+		assert.Fail(t, "invalid pango markup", "%s: %v in %q", message, err, markup)
+		root = &Node{Attrs: map[string]string{}}
+	}
+	return &Assertions{t, root, message}
+}
+
+// Root returns the parsed markup, for assertions not covered here.
+func (a *Assertions) Root() *Node {
+	return a.root
+}
+
+// Text asserts that the plain text of the markup (without any tags) is
+// the given text.
+func (a *Assertions) Text(text string) *Assertions {
+	assert.Equal(a.t, text, a.root.PlainText(), "%s: text of markup", a.message)
+	return a
+}
+
+// Span returns assertions on the innermost element that contains the given
+// text, which may be split across multiple nested elements. If no element
+// contains the text, it fails and returns assertions on the whole markup.
+func (a *Assertions) Span(text string) *Element {
+	n := a.root.find(text)
+	if n == nil {
+		assert.Fail(a.t, "text not found", "%s: %q in %v", a.message, text, a.root)
+		n = a.root
+	}
+	return &Element{a, n, text}
+}
+
+// Element groups assertions on an element of pango markup.
+type Element struct {
+	markup *Assertions
+	node   *Node
+	text   string
+}
+
+// Tag asserts the name of the element, e.g. "span" or "b".
+func (e *Element) Tag(tag string) *Element {
+	assert.Equal(e.markup.t, tag, e.node.Tag, "%s: tag of element containing %q in %v",
+		e.markup.message, e.text, e.markup.root)
+	return e
+}
+
+// Depth asserts the number of elements enclosing the text, including the
+// element itself.
+func (e *Element) Depth(depth int) *Element {
+	assert.Equal(e.markup.t, depth, e.node.Depth(), "%s: nesting of %q in %v",
+		e.markup.message, e.text, e.markup.root)
+	return e
+}
+
+// Attr asserts the value of an attribute in effect for the element, which
+// may be set by an enclosing element. The first name is the preferred name,
+// and any others are aliases that pango also accepts.
+func (e *Element) Attr(value string, names ...string) *Element {
+	actual, ok := e.node.Attr(names...)
+	if !ok {
+		assert.Fail(e.markup.t, "attribute not set", "%s: %s of %q in %v",
+			e.markup.message, names[0], e.text, e.markup.root)
+		return e
+	}
+	assert.Equal(e.markup.t, value, actual, "%s: %s of %q in %v",
+		e.markup.message, names[0], e.text, e.markup.root)
+	return e
+}
+
+// Lacks asserts that no value for the attribute is in effect for the element.
+func (e *Element) Lacks(names ...string) *Element {
+	actual, ok := e.node.Attr(names...)
+	assert.False(e.markup.t, ok, "%s: %s of %q is %q in %v",
+		e.markup.message, names[0], e.text, actual, e.markup.root)
+	return e
+}
+
+// Color asserts the foreground colour in effect for the element.
+func (e *Element) Color(color string) *Element {
+	return e.Attr(color, "color", "foreground", "fgcolor")
+}
+
+// Background asserts the background colour in effect for the element.
+func (e *Element) Background(color string) *Element {
+	return e.Attr(color, "background", "bgcolor")
+}
+
+// Font asserts the font face in effect for the element, e.g. for icons.
+func (e *Element) Font(face string) *Element {
+	return e.Attr(face, "face", "font_family")
+}
+
+// Weight asserts the font weight in effect for the element.
+func (e *Element) Weight(weight string) *Element {
+	return e.Attr(weight, "weight", "font_weight")
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pango
+
+import (
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/pango"
+)
+
+func TestParse(t *testing.T) {
+	root, err := Parse(`a<span color='red'>b<b>c &amp; d</b></span>`)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc & d", root.PlainText())
+	assert.Equal(t, 2, len(root.Children))
+	span := root.Children[1]
+	assert.Equal(t, "span", span.Tag)
+	assert.Equal(t, "red", span.Attrs["color"])
+	assert.Equal(t, 1, span.Depth())
+	bold := span.Children[1]
+	assert.Equal(t, "b", bold.Tag)
+	assert.Equal(t, 2, bold.Depth())
+	color, ok := bold.Attr("color")
+	assert.True(t, ok, "attributes are inherited")
+	assert.Equal(t, "red", color)
+
+	for _, invalid := range []string{
+		"<span>unclosed", "</span>", "<span color='red>", "a & b",
+	} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, "parsing %q", invalid)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	markup := pango.Span(
+		"Red ",
+		pango.Span("Bold", pango.Bold, " Text"),
+		bar.Color("#ff0000"),
+		pango.Font("DejaVu Sans"),
+	).Pango()
+
+	m := New(t, markup, "simple markup")
+	m.Text("Red Bold Text")
+	m.Span("Red").Tag("span").Depth(1).Color("#ff0000").Font("DejaVu Sans").Lacks("weight")
+	m.Span("Bold").Tag("span").Depth(2).Weight("bold").Color("#ff0000")
+	m.Span("Red Bold").Depth(1)
+	m.Span("Text").Depth(2).Attr("bold", "weight", "font_weight").Lacks("background")
+
+	fakeT := &testing.T{}
+	m = New(fakeT, markup, "failing")
+	m.Text("Red Bold Text")
+	m.Span("Bold").Color("#ff0000").Weight("bold")
+	assert.False(t, fakeT.Failed(), "passing assertions")
+
+	for name, fn := range map[string]func(*Assertions){
+		"text":       func(m *Assertions) { m.Text("Red Bold") },
+		"missing":    func(m *Assertions) { m.Span("Blue") },
+		"tag":        func(m *Assertions) { m.Span("Bold").Tag("b") },
+		"depth":      func(m *Assertions) { m.Span("Bold").Depth(1) },
+		"color":      func(m *Assertions) { m.Span("Bold").Color("#00ff00") },
+		"unset":      func(m *Assertions) { m.Span("Red").Weight("bold") },
+		"background": func(m *Assertions) { m.Span("Red").Background("red") },
+		"lacks":      func(m *Assertions) { m.Span("Bold").Lacks("face", "font_family") },
+		"font":       func(m *Assertions) { m.Span("Red").Font("FontAwesome") },
+	} {
+		fakeT := &testing.T{}
+		fn(New(fakeT, markup, name))
+		assert.True(t, fakeT.Failed(), "%s assertion fails", name)
+	}
+
+	fakeT = &testing.T{}
+	New(fakeT, "<span>", "invalid").Text("")
+	assert.True(t, fakeT.Failed(), "invalid markup fails")
+}
+
+func TestAliases(t *testing.T) {
+	m := New(t, `<span foreground='red' bgcolor='blue' font_family='Icons'>x</span>`, "aliases")
+	m.Span("x").Color("red").Background("blue").Font("Icons")
+	assert.Equal(t, "x", m.Root().PlainText())
+}
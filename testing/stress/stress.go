@@ -0,0 +1,268 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package stress hammers modules and bars with concurrent updates, clicks,
+and pause/resume cycles, to shake out data races in module code when run
+with the race detector (go test -race).
+
+Each kind of action is performed by a number of goroutines for a fixed
+duration, in random order. Panics in the module are reported as test
+failures, and the module is required to still produce output when it is
+updated or clicked once the stress test is over.
+
+Typical usage would be:
+
+	func TestStress(t *testing.T) {
+		stress.New(t).Duration(time.Second).Module(myModule)
+	}
+*/
+package stress
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+// Time to wait for output after the stress test. Overridden in tests.
+var positiveTimeout = time.Second
+
+// updatable is implemented by modules that can be updated on demand,
+// e.g. base.Module.
+type updatable interface {
+	Update()
+}
+
+// Test configures a stress test. The zero value is not usable, use New.
+type Test struct {
+	t        assert.TestingT
+	duration time.Duration
+	workers  int
+	events   []bar.Event
+}
+
+// New creates a new stress test with sensible defaults: 4 goroutines for
+// each action, running for 100ms, with clicks of all buttons.
+func New(t assert.TestingT) *Test {
+	return &Test{
+		t:        t,
+		duration: 100 * time.Millisecond,
+		workers:  4,
+		events: []bar.Event{
+			{Button: bar.ButtonLeft},
+			{Button: bar.ButtonRight},
+			{Button: bar.ButtonMiddle},
+			{Button: bar.ButtonBack},
+			{Button: bar.ButtonForward},
+			{Button: bar.ScrollUp},
+			{Button: bar.ScrollDown},
+			{Button: bar.ScrollLeft},
+			{Button: bar.ScrollRight},
+		},
+	}
+}
+
+// Duration sets how long the module or bar is stressed for.
+func (s *Test) Duration(duration time.Duration) *Test {
+	s.duration = duration
+	return s
+}
+
+// Workers sets the number of goroutines performing each action.
+func (s *Test) Workers(workers int) *Test {
+	s.workers = workers
+	return s
+}
+
+// Events sets the events sent to clickable modules, for modules where some
+// clicks have undesirable side effects (e.g. launching programs). With no
+// events, modules are not clicked.
+func (s *Test) Events(events ...bar.Event) *Test {
+	s.events = events
+	return s
+}
+
+// Module streams the module, and concurrently updates, clicks, pauses, and
+// resumes it (depending on which of these it supports), while consuming all
+// of its output. Once done, the module is resumed, updated, and clicked, and
+// must produce an output.
+func (s *Test) Module(m bar.Module) {
+	// Stream before starting any actions, since modules expect to be
+	// streamed before being updated or resumed.
+	stream := m.Stream()
+	outputs := make(chan bar.Output, 1)
+	go func() {
+		for out := range stream {
+			select {
+			case outputs <- out:
+			default:
+			}
+		}
+	}()
+	s.run(m)
+	s.assertResponsive([]bar.Module{m}, outputs)
+}
+
+// Bar stresses a started test bar, by concurrently updating, pausing, and
+// resuming the given modules (which should be the modules on the bar),
+// while sending events from the simulated i3bar to random segments of its
+// output. Once done, the modules are resumed, updated, and clicked, and the
+// bar must print a new output.
+func (s *Test) Bar(b *testBar.TestBar, modules ...bar.Module) {
+	clicking := make(chan struct{})
+	waiting := make(chan struct{})
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	outputs := make(chan bar.Output, 1)
+	// Only one goroutine uses the test bar, since it is not safe for
+	// concurrent use. Consuming its output is required even without clicks,
+	// since the bar blocks when nobody reads its output.
+	go func() {
+		defer close(finished)
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	click:
+		for {
+			select {
+			case <-clicking:
+				break click
+			default:
+			}
+			out := b.LatestOutput()
+			if len(out) == 0 || len(s.events) == 0 {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			b.SendEvent(r.Intn(len(out)), s.events[r.Intn(len(s.events))])
+		}
+		b.LatestOutput()
+		close(waiting)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if out, ok := b.PendingOutput(); ok {
+				select {
+				case outputs <- out:
+				default:
+				}
+			} else {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	s.run(modules...)
+	close(clicking)
+	<-waiting
+	s.assertResponsive(modules, outputs)
+	close(done)
+	<-finished
+}
+
+// run performs the actions supported by each module from multiple
+// goroutines until the duration elapses.
+func (s *Test) run(modules ...bar.Module) {
+	var actions []func()
+	for _, m := range modules {
+		m := m
+		if u, ok := m.(updatable); ok {
+			actions = append(actions, u.Update)
+		}
+		if p, ok := m.(bar.Pausable); ok {
+			actions = append(actions, p.Pause, p.Resume)
+		}
+		if c, ok := m.(bar.Clickable); ok && len(s.events) > 0 {
+			actions = append(actions, func() {
+				c.Click(s.events[rand.Intn(len(s.events))])
+			})
+		}
+	}
+	if len(actions) == 0 {
+		return
+	}
+	deadline := time.Now().Add(s.duration)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers*len(actions); i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for time.Now().Before(deadline) {
+				if !s.perform(actions[r.Intn(len(actions))]) {
+					return
+				}
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+	wg.Wait()
+}
+
+// perform runs the action, reporting any panics as failures.
+func (s *Test) perform(action func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			assert.Fail(s.t, "panic during stress test", fmt.Sprint(r))
+			ok = false
+		}
+	}()
+	action()
+	return true
+}
+
+// assertResponsive resumes the modules, then updates and clicks them, and
+// asserts that a new output follows. Modules that can only be streamed are
+// not required to produce output.
+func (s *Test) assertResponsive(modules []bar.Module, outputs <-chan bar.Output) {
+	drain(outputs)
+	acted := false
+	for _, m := range modules {
+		if p, ok := m.(bar.Pausable); ok {
+			s.perform(p.Resume)
+		}
+		if u, ok := m.(updatable); ok {
+			s.perform(u.Update)
+			acted = true
+		}
+		if c, ok := m.(bar.Clickable); ok && len(s.events) > 0 {
+			s.perform(func() { c.Click(s.events[0]) })
+			acted = true
+		}
+	}
+	if !acted {
+		return
+	}
+	select {
+	case <-outputs:
+	case <-time.After(positiveTimeout):
+		assert.Fail(s.t, "no output after stress test")
+	}
+}
+
+func drain(ch <-chan bar.Output) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stress
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/outputs"
+	testBar "github.com/soumya92/barista/testing/bar"
+)
+
+func init() {
+	positiveTimeout = 100 * time.Millisecond
+}
+
+// counter is a module that counts updates and clicks.
+type counter struct {
+	*base.Base
+	mu              sync.Mutex
+	updates, clicks int
+}
+
+func newCounter() *counter {
+	c := &counter{Base: base.New()}
+	c.OnUpdate(func() {
+		c.mu.Lock()
+		c.updates++
+		count := c.updates
+		c.mu.Unlock()
+		c.Output(outputs.Textf("%d", count))
+	})
+	c.OnClick(func(bar.Event) {
+		c.mu.Lock()
+		c.clicks++
+		c.mu.Unlock()
+	})
+	return c
+}
+
+func (c *counter) counts() (updates, clicks int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updates, c.clicks
+}
+
+func TestModule(t *testing.T) {
+	c := newCounter()
+	New(t).Duration(20 * time.Millisecond).Workers(2).Module(c)
+	updates, clicks := c.counts()
+	assert.True(t, updates > 1, "module was updated")
+	assert.True(t, clicks > 0, "module was clicked")
+}
+
+func TestNoEvents(t *testing.T) {
+	c := newCounter()
+	New(t).Duration(10 * time.Millisecond).Events().Module(c)
+	updates, clicks := c.counts()
+	assert.True(t, updates > 1, "module was updated")
+	assert.Equal(t, 0, clicks, "module was not clicked without events")
+}
+
+// streamOnly is a module that only supports streaming.
+type streamOnly chan bar.Output
+
+func (s streamOnly) Stream() <-chan bar.Output { return s }
+
+func TestStreamOnly(t *testing.T) {
+	fakeT := &testing.T{}
+	New(fakeT).Duration(10 * time.Millisecond).Module(make(streamOnly))
+	assert.False(t, fakeT.Failed(), "no output required from module without updates")
+}
+
+// stuck is a module that stops producing output once it's updated.
+type stuck struct {
+	streamOnly
+	once sync.Once
+}
+
+func (s *stuck) Update() { s.once.Do(func() { close(s.streamOnly) }) }
+
+func TestUnresponsive(t *testing.T) {
+	fakeT := &testing.T{}
+	New(fakeT).Duration(10 * time.Millisecond).Module(&stuck{streamOnly: make(streamOnly)})
+	assert.True(t, fakeT.Failed(), "fails when module does not output after stress")
+}
+
+// panicky is a module that panics on clicks.
+type panicky struct{ *counter }
+
+func (p panicky) Click(bar.Event) { panic("clicked") }
+
+func TestPanic(t *testing.T) {
+	fakeT := &testing.T{}
+	New(fakeT).Duration(10 * time.Millisecond).Module(panicky{newCounter()})
+	assert.True(t, fakeT.Failed(), "panics fail the test")
+}
+
+func TestBar(t *testing.T) {
+	first, second := newCounter(), newCounter()
+	b := testBar.Run(t, first, second)
+	defer b.Close()
+	New(t).Duration(20*time.Millisecond).Workers(2).Bar(b, first, second)
+	for _, c := range []*counter{first, second} {
+		updates, _ := c.counts()
+		assert.True(t, updates > 1, "module on bar was updated")
+	}
+	_, firstClicks := first.counts()
+	_, secondClicks := second.counts()
+	assert.True(t, firstClicks+secondClicks > 0, "modules were clicked through the bar")
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *",
+		"* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *",
+		"5-1 * * * *", "a * * * *", "* * * FOO *", "1-x * * * *",
+	} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, "%q", expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2018-01-05 is a Friday.
+	start := time.Date(2018, 1, 5, 16, 58, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		expr     string
+		expected []time.Time
+	}{
+		{"* * * * *", []time.Time{
+			time.Date(2018, 1, 5, 16, 59, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 17, 0, 0, 0, time.UTC),
+		}},
+		{"*/5 9-17 * * MON-FRI", []time.Time{
+			time.Date(2018, 1, 5, 17, 0, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 17, 5, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 17, 10, 0, 0, time.UTC),
+		}},
+		{"55 9-17 * * MON-FRI", []time.Time{
+			time.Date(2018, 1, 5, 17, 55, 0, 0, time.UTC),
+			time.Date(2018, 1, 8, 9, 55, 0, 0, time.UTC),
+		}},
+		{"0,30 12 * * *", []time.Time{
+			time.Date(2018, 1, 6, 12, 0, 0, 0, time.UTC),
+			time.Date(2018, 1, 6, 12, 30, 0, 0, time.UTC),
+			time.Date(2018, 1, 7, 12, 0, 0, 0, time.UTC),
+		}},
+		{"15/20 * * * *", []time.Time{
+			time.Date(2018, 1, 5, 17, 15, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 17, 35, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 17, 55, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 18, 15, 0, 0, time.UTC),
+		}},
+		{"0 0 13 * 5", []time.Time{
+			// Friday, or the 13th.
+			time.Date(2018, 1, 12, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 1, 13, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 1, 19, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 8 * * 7", []time.Time{
+			time.Date(2018, 1, 7, 8, 0, 0, 0, time.UTC),
+			time.Date(2018, 1, 14, 8, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 29 feb *", []time.Time{
+			time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@monthly", []time.Time{
+			time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@hourly", []time.Time{
+			time.Date(2018, 1, 5, 17, 0, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 18, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 30 2 *", []time.Time{{}}},
+	} {
+		c, err := ParseCron(tc.expr)
+		if !assert.NoError(t, err, tc.expr) {
+			continue
+		}
+		now := start
+		for _, expected := range tc.expected {
+			now = c.Next(now)
+			assert.Equal(t, expected, now, tc.expr)
+		}
+	}
+}
+
+func TestCronScheduler(t *testing.T) {
+	TestMode(true)
+	AdvanceTo(time.Date(2018, 1, 5, 16, 58, 30, 0, time.UTC))
+
+	_, err := Cron("invalid")
+	assert.Error(t, err)
+
+	s, err := Cron("*/5 9-17 * * MON-FRI")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 1, 5, 17, 0, 0, 0, time.UTC), NextTick())
+	assertTick(t, s, "on schedule")
+	assert.Equal(t, time.Date(2018, 1, 5, 17, 5, 0, 0, time.UTC), NextTick())
+	assertTick(t, s, "on schedule")
+
+	s.After(time.Minute)
+	assert.Equal(t, time.Date(2018, 1, 5, 17, 6, 0, 0, time.UTC), NextTick())
+	assertTick(t, s, "after delay")
+	AdvanceBy(time.Hour)
+	assertNoTick(t, s, "cron schedule replaced")
+
+	_, err = s.Cron("0 0 30 2 *")
+	assert.NoError(t, err)
+	AdvanceBy(24 * time.Hour)
+	assertNoTick(t, s, "impossible schedule")
+}
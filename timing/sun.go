@@ -0,0 +1,182 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"math"
+	"time"
+
+	"github.com/soumya92/barista/location"
+)
+
+// Solar events are computed using the sunrise equation, which is accurate
+// to within a minute or two away from the poles. Sunrise and sunset are
+// when the top of the sun's disc touches the horizon, accounting for
+// atmospheric refraction.
+const (
+	julianUnixEpoch = 2440587.5
+	julian2000      = 2451545.0
+	sunAltitude     = -0.833
+	earthTilt       = 23.4397
+)
+
+func sinDeg(d float64) float64 { return math.Sin(d * math.Pi / 180) }
+func cosDeg(d float64) float64 { return math.Cos(d * math.Pi / 180) }
+
+// solarEvents returns the julian dates of sunrise and sunset on the n-th
+// day after J2000, at the given latitude and longitude (in degrees, with
+// north and east positive). ok is false if the sun does not rise or set
+// on that day, e.g. during polar day or night.
+func solarEvents(n, lat, lng float64) (rise, set float64, ok bool) {
+	// Mean solar time, solar mean anomaly, and the equation of the centre.
+	j := n - lng/360
+	m := math.Mod(357.5291+0.98560028*j, 360)
+	c := 1.9148*sinDeg(m) + 0.02*sinDeg(2*m) + 0.0003*sinDeg(3*m)
+	// Ecliptic longitude, and the solar transit (local noon).
+	l := math.Mod(m+c+180+102.9372, 360)
+	transit := julian2000 + j + 0.0053*sinDeg(m) - 0.0069*sinDeg(2*l)
+	// Declination of the sun, and the hour angle at sunrise and sunset.
+	sinDecl := sinDeg(l) * sinDeg(earthTilt)
+	cosDecl := math.Sqrt(1 - sinDecl*sinDecl)
+	cosHour := (sinDeg(sunAltitude) - sinDeg(lat)*sinDecl) / (cosDeg(lat) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return 0, 0, false
+	}
+	hour := math.Acos(cosHour) * 180 / math.Pi
+	return transit - hour/360, transit + hour/360, true
+}
+
+func fromJulian(j float64) time.Time {
+	secs := (j - julianUnixEpoch) * 86400
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
+
+// nextSolarEvent returns the first sunrise or sunset after the given time,
+// or the zero time if there is none within a year.
+func nextSolarEvent(t time.Time, lat, lng float64, sunset bool) time.Time {
+	day := math.Floor(float64(t.Unix())/86400+julianUnixEpoch-julian2000) - 1
+	for i := 0.0; i <= 367; i++ {
+		rise, set, ok := solarEvents(day+i, lat, lng)
+		if !ok {
+			continue
+		}
+		event := rise
+		if sunset {
+			event = set
+		}
+		if e := fromJulian(event); e.After(t) {
+			return e.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
+
+// NextSunrise returns the first sunrise after the given time, at the given
+// latitude and longitude (in degrees, with north and east positive), or the
+// zero time if the sun does not rise within a year.
+func NextSunrise(t time.Time, lat, lng float64) time.Time {
+	return nextSolarEvent(t, lat, lng, false)
+}
+
+// NextSunset returns the first sunset after the given time, at the given
+// latitude and longitude (in degrees, with north and east positive), or the
+// zero time if the sun does not set within a year.
+func NextSunset(t time.Time, lat, lng float64) time.Time {
+	return nextSolarEvent(t, lat, lng, true)
+}
+
+// AtSunrise creates a scheduler that ticks at every sunrise at the given
+// latitude and longitude.
+func AtSunrise(lat, lng float64) *Scheduler {
+	return NewScheduler().Sunrise(lat, lng)
+}
+
+// AtSunset creates a scheduler that ticks at every sunset at the given
+// latitude and longitude.
+func AtSunset(lat, lng float64) *Scheduler {
+	return NewScheduler().Sunset(lat, lng)
+}
+
+// Sunrise sets the scheduler to tick at every sunrise at the given
+// latitude and longitude. This replaces any pending ticks.
+func (s *Scheduler) Sunrise(lat, lng float64) *Scheduler {
+	return s.repeat(func(t time.Time) time.Time {
+		return NextSunrise(t, lat, lng)
+	})
+}
+
+// Sunset sets the scheduler to tick at every sunset at the given
+// latitude and longitude. This replaces any pending ticks.
+func (s *Scheduler) Sunset(lat, lng float64) *Scheduler {
+	return s.repeat(func(t time.Time) time.Time {
+		return NextSunset(t, lat, lng)
+	})
+}
+
+// AtSunriseHere creates a scheduler that ticks at every sunrise at the
+// current location.
+func AtSunriseHere() *Scheduler {
+	return NewScheduler().SunriseHere()
+}
+
+// AtSunsetHere creates a scheduler that ticks at every sunset at the
+// current location.
+func AtSunsetHere() *Scheduler {
+	return NewScheduler().SunsetHere()
+}
+
+// SunriseHere sets the scheduler to tick at every sunrise at the current
+// location, as provided by the location package. The next tick is
+// rescheduled whenever the location changes, and the scheduler does not tick
+// while the location is unknown. This replaces any pending ticks.
+func (s *Scheduler) SunriseHere() *Scheduler {
+	return s.here(NextSunrise)
+}
+
+// SunsetHere sets the scheduler to tick at every sunset at the current
+// location, as provided by the location package. The next tick is
+// rescheduled whenever the location changes, and the scheduler does not tick
+// while the location is unknown. This replaces any pending ticks.
+func (s *Scheduler) SunsetHere() *Scheduler {
+	return s.here(NextSunset)
+}
+
+// here sets the scheduler to tick at the solar events returned by next for
+// the current location, following any changes to the location.
+func (s *Scheduler) here(next func(time.Time, float64, float64) time.Time) *Scheduler {
+	s.repeat(func(t time.Time) time.Time {
+		lat, lng, ok := location.Get()
+		if !ok {
+			return time.Time{}
+		}
+		return next(t, lat, lng)
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopFollowing = location.OnChange(s.locationChanged)
+	return s
+}
+
+// locationChanged reschedules the next tick of a solar schedule at the
+// current location.
+func (s *Scheduler) locationChanged() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nextFn == nil {
+		return
+	}
+	s.next = s.nextFn(Now())
+	s.scheduleNext()
+}
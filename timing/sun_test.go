@@ -0,0 +1,125 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/location"
+)
+
+func TestSolarEvents(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		desc     string
+		after    time.Time
+		lat, lng float64
+		sunrise  time.Time
+		sunset   time.Time
+	}{
+		{
+			"London, summer solstice",
+			time.Date(2018, 6, 21, 0, 0, 0, 0, time.UTC), 51.5074, -0.1278,
+			time.Date(2018, 6, 21, 3, 43, 0, 0, time.UTC),
+			time.Date(2018, 6, 21, 20, 21, 0, 0, time.UTC),
+		},
+		{
+			"New York, winter",
+			time.Date(2018, 1, 5, 10, 0, 0, 0, ny), 40.7128, -74.0060,
+			time.Date(2018, 1, 6, 7, 20, 0, 0, ny),
+			time.Date(2018, 1, 5, 16, 42, 0, 0, ny),
+		},
+		{
+			"Sydney",
+			time.Date(2018, 1, 5, 0, 0, 0, 0, time.UTC), -33.8688, 151.2093,
+			time.Date(2018, 1, 5, 18, 52, 0, 0, time.UTC),
+			time.Date(2018, 1, 5, 9, 10, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		sunrise := NextSunrise(tc.after, tc.lat, tc.lng)
+		assert.WithinDuration(t, tc.sunrise, sunrise, 2*time.Minute, tc.desc)
+		assert.Equal(t, tc.after.Location(), sunrise.Location(), tc.desc)
+		assert.WithinDuration(t, tc.sunset,
+			NextSunset(tc.after, tc.lat, tc.lng), 2*time.Minute, tc.desc)
+	}
+
+	midsummer := time.Date(2018, 6, 21, 12, 0, 0, 0, time.UTC)
+	sunset := NextSunset(midsummer, 69.6492, 18.9553)
+	assert.True(t, sunset.After(midsummer.AddDate(0, 1, 0)),
+		"no sunset during polar day, got %v", sunset)
+	assert.True(t, NextSunrise(midsummer, 90, 0).IsZero(), "no sunrise at the north pole")
+}
+
+func TestSunSchedulers(t *testing.T) {
+	TestMode(true)
+	AdvanceTo(time.Date(2018, 6, 21, 12, 0, 0, 0, time.UTC))
+	lat, lng := 51.5074, -0.1278
+
+	sunset := AtSunset(lat, lng)
+	sunrise := AtSunrise(lat, lng)
+	next := NextSunset(Now(), lat, lng)
+	assert.Equal(t, next, sunset.NextTick())
+	AdvanceTo(next.Add(-time.Minute))
+	assertNoTick(t, sunset, "before sunset")
+	AdvanceTo(next)
+	assertTick(t, sunset, "at sunset")
+	assertNoTick(t, sunrise, "at sunset")
+
+	next = NextSunrise(Now(), lat, lng)
+	AdvanceTo(next)
+	assertTick(t, sunrise, "at sunrise")
+	assert.Equal(t, NextSunrise(next, lat, lng), sunrise.NextTick(), "next sunrise")
+
+	AdvanceTo(NextSunset(Now(), lat, lng))
+	assertTick(t, sunset, "at next sunset")
+	sunrise.Stop()
+	sunset.Stop()
+}
+
+func TestSunSchedulersHere(t *testing.T) {
+	TestMode(true)
+	AdvanceTo(time.Date(2018, 6, 21, 12, 0, 0, 0, time.UTC))
+	london := [2]float64{51.5074, -0.1278}
+	tokyo := [2]float64{35.6762, 139.6503}
+
+	sunset := AtSunsetHere()
+	sunrise := NewScheduler().SunriseHere()
+	location.Set(london[0], london[1])
+	next := NextSunset(Now(), london[0], london[1])
+	assert.Equal(t, next, sunset.NextTick(), "scheduled once the location is known")
+	AdvanceTo(next)
+	assertTick(t, sunset, "at sunset")
+	assertNoTick(t, sunrise, "at sunset")
+
+	location.Set(tokyo[0], tokyo[1])
+	assert.Equal(t, NextSunset(Now(), tokyo[0], tokyo[1]), sunset.NextTick(),
+		"rescheduled when the location changes")
+	next = NextSunrise(Now(), tokyo[0], tokyo[1])
+	assert.Equal(t, next, sunrise.NextTick())
+	AdvanceTo(next)
+	assertTick(t, sunrise, "at sunrise")
+
+	sunrise.Sunrise(london[0], london[1])
+	next = NextSunrise(Now(), london[0], london[1])
+	location.Set(40.7128, -74.0060)
+	assert.Equal(t, next, sunrise.NextTick(),
+		"location changes ignored by schedules at a fixed location")
+	sunrise.Stop()
+	sunset.Stop()
+}
@@ -0,0 +1,318 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package timing provides schedulers that deliver ticks on a channel, for
+modules that need to wait for a time or interval in their own goroutine
+instead of running an update function.
+
+Typical usage would be:
+
+	sch := timing.Repeat(time.Minute)
+	for range sch.C {
+	    // do something every minute.
+	}
+
+Schedulers can be changed at any time by calling Every, EveryAligned, At,
+After, Cron, Sunrise, Sunset, SunriseHere, or SunsetHere, which replace any
+pending ticks, and stopped using Stop.
+Repeating schedules can be spread out using Jitter, e.g.
+
+	timing.Repeat(10 * time.Minute).Jitter(time.Minute)
+
+delays each tick by up to a minute, so that many modules polling an API
+at the same interval do not all send their requests at the same time.
+
+In test mode, time is virtual and does not pass until a test calls
+AdvanceBy, AdvanceTo, or NextTick, which trigger any schedulers that were
+due in the meantime. Test mode is shared with the base/scheduler package,
+so it also controls the refresh schedules of modules built on base.
+
+The bar pauses all schedulers while it is hidden, using Pause and Resume,
+so modules do not need to handle pausing themselves. Any schedulers that
+were due while paused are triggered together on resume.
+*/
+package timing
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+// Scheduler delivers ticks on a channel at a specific time, after a
+// delay, or at an interval.
+type Scheduler struct {
+	// C receives a value on each tick. Ticks are not queued, so if a
+	// tick is not received before the next one, only one is delivered.
+	C <-chan struct{}
+
+	ch  chan struct{}
+	sch scheduler.Scheduler
+
+	mu sync.Mutex
+	// For cron, solar, and jittered schedules, implemented as a series
+	// of single ticks, nextFn returns the time of the tick following the
+	// one scheduled at next.
+	nextFn func(time.Time) time.Time
+	next   time.Time
+	// For solar schedules at the current location, removes the listener
+	// that reschedules the next tick when the location changes.
+	stopFollowing func()
+	// For schedules set using Every, the interval and maximum jitter.
+	interval time.Duration
+	jitter   time.Duration
+}
+
+// NewScheduler creates a scheduler that does not tick until
+// one of Every, At, or After is called.
+func NewScheduler() *Scheduler {
+	ch := make(chan struct{}, 1)
+	s := &Scheduler{C: ch, ch: ch}
+	s.sch = scheduler.Do(s.tick)
+	return s
+}
+
+// Repeat creates a scheduler that ticks at the given interval.
+func Repeat(interval time.Duration) *Scheduler {
+	return NewScheduler().Every(interval)
+}
+
+// At creates a scheduler that ticks once at the given time.
+func At(when time.Time) *Scheduler {
+	return NewScheduler().At(when)
+}
+
+// After creates a scheduler that ticks once after the given delay.
+func After(delay time.Duration) *Scheduler {
+	return NewScheduler().After(delay)
+}
+
+// Cron creates a scheduler that ticks on a cron schedule,
+// e.g. "*/5 9-17 * * MON-FRI". See ParseCron for the syntax.
+func Cron(expr string) (*Scheduler, error) {
+	return NewScheduler().Cron(expr)
+}
+
+// Now returns the current time, which is the virtual time in test mode.
+func Now() time.Time {
+	return scheduler.Now()
+}
+
+// TestMode enables or disables test mode for all schedulers, including
+// those in the base/scheduler package. Entering test mode resets the
+// virtual time to the zero time, so tests should start with AdvanceTo.
+// Only schedulers created after enabling test mode are controlled by it.
+func TestMode(enabled bool) {
+	scheduler.TestMode(enabled)
+}
+
+// AdvanceBy advances the virtual time by the given duration, and triggers
+// any schedulers that were due in the meantime.
+func AdvanceBy(duration time.Duration) {
+	scheduler.AdvanceBy(duration)
+}
+
+// AdvanceTo advances the virtual time to the given time, and triggers
+// any schedulers that were due in the meantime.
+func AdvanceTo(when time.Time) {
+	scheduler.AdvanceTo(when)
+}
+
+// NextTick advances the virtual time to the next scheduled tick, triggers
+// the schedulers due at that time, and returns the new time.
+func NextTick() time.Time {
+	return scheduler.NextTick()
+}
+
+// Pause suspends all schedulers, including those in the base/scheduler
+// package, until Resume is called. Ticks are not delivered while paused.
+func Pause() {
+	scheduler.Pause()
+}
+
+// Resume continues all schedulers, and delivers a single tick to each
+// scheduler that was due while paused.
+func Resume() {
+	scheduler.Resume()
+}
+
+func (s *Scheduler) tick() {
+	s.mu.Lock()
+	if s.nextFn != nil {
+		// Compute the next tick from the scheduled time rather than now,
+		// in case of a slow timer, but skip any ticks that were missed,
+		// e.g. while paused.
+		now := Now()
+		s.next = s.nextFn(s.next)
+		for !s.next.IsZero() && !s.next.After(now) {
+			s.next = s.nextFn(s.next)
+		}
+		s.scheduleNext()
+	}
+	s.mu.Unlock()
+	select {
+	case s.ch <- struct{}{}:
+	default:
+	}
+}
+
+// drain removes any pending tick from the channel.
+func (s *Scheduler) drain() {
+	select {
+	case <-s.ch:
+	default:
+	}
+}
+
+// scheduleNext schedules the next tick for schedules that use nextFn,
+// and must be called with the mutex held.
+func (s *Scheduler) scheduleNext() {
+	if s.next.IsZero() {
+		s.sch.Stop()
+	} else {
+		s.sch.At(s.next)
+	}
+}
+
+// clearRepeat removes any schedule that uses nextFn.
+func (s *Scheduler) clearRepeat() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextFn = nil
+	s.interval = 0
+	if s.stopFollowing != nil {
+		s.stopFollowing()
+		s.stopFollowing = nil
+	}
+}
+
+// randDuration returns a random duration in [0, max), replaced in tests.
+var randDuration = func(max time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// Cron sets the scheduler to tick on a cron schedule.
+// This replaces any pending ticks, unless the expression is invalid.
+func (s *Scheduler) Cron(expr string) (*Scheduler, error) {
+	c, err := ParseCron(expr)
+	if err != nil {
+		return s, err
+	}
+	return s.repeat(c.Next), nil
+}
+
+// repeat sets the scheduler to tick at the times returned by next, which
+// returns the first tick after a given time, or the zero time to stop.
+func (s *Scheduler) repeat(next func(time.Time) time.Time) *Scheduler {
+	s.clearRepeat()
+	s.mu.Lock()
+	s.nextFn = next
+	s.next = next(Now())
+	s.scheduleNext()
+	s.mu.Unlock()
+	s.drain()
+	return s
+}
+
+// Every sets the scheduler to tick at an interval, with a random delay
+// for each tick if jitter is set. This replaces any pending ticks.
+func (s *Scheduler) Every(interval time.Duration) *Scheduler {
+	s.clearRepeat()
+	s.mu.Lock()
+	s.interval = interval
+	if s.jitter <= 0 {
+		s.sch.Every(interval)
+	} else {
+		// Ticks are delayed from a regular grid, so that the jitter does
+		// not accumulate.
+		jitter := s.jitter
+		grid := Now()
+		s.nextFn = func(time.Time) time.Time {
+			grid = grid.Add(interval)
+			return grid.Add(randDuration(jitter))
+		}
+		s.next = s.nextFn(time.Time{})
+		s.scheduleNext()
+	}
+	s.mu.Unlock()
+	s.drain()
+	return s
+}
+
+// Jitter delays each tick of schedules set using Every by a random
+// duration up to the given maximum, so that many modules polling at the
+// same interval spread out their requests instead of all waking up at the
+// same time. The jitter should be smaller than the interval, and zero
+// disables it. If the scheduler is already repeating, it is restarted
+// with the jitter.
+func (s *Scheduler) Jitter(max time.Duration) *Scheduler {
+	s.mu.Lock()
+	s.jitter = max
+	interval := s.interval
+	s.mu.Unlock()
+	if interval > 0 {
+		s.Every(interval)
+	}
+	return s
+}
+
+// EveryAligned sets the scheduler to tick at an interval, aligned to
+// multiples of the interval, e.g. at the start of every minute.
+// This replaces any pending ticks.
+func (s *Scheduler) EveryAligned(interval time.Duration) *Scheduler {
+	s.clearRepeat()
+	s.sch.EveryAligned(interval)
+	s.drain()
+	return s
+}
+
+// At sets the scheduler to tick once at a specific time.
+// This replaces any pending ticks.
+func (s *Scheduler) At(when time.Time) *Scheduler {
+	s.clearRepeat()
+	s.sch.At(when)
+	s.drain()
+	return s
+}
+
+// After sets the scheduler to tick once after a delay.
+// This replaces any pending ticks.
+func (s *Scheduler) After(delay time.Duration) *Scheduler {
+	s.clearRepeat()
+	s.sch.After(delay)
+	s.drain()
+	return s
+}
+
+// NextTick returns the time of the next tick, or the zero time if the
+// scheduler is not scheduled to tick.
+func (s *Scheduler) NextTick() time.Time {
+	return s.sch.NextTrigger()
+}
+
+// LastTick returns the time of the last tick, or the zero time if the
+// scheduler has never ticked.
+func (s *Scheduler) LastTick() time.Time {
+	return s.sch.LastTrigger()
+}
+
+// Stop cancels all further ticks, including any pending tick.
+func (s *Scheduler) Stop() {
+	s.clearRepeat()
+	s.sch.Stop()
+	s.drain()
+}
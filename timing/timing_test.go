@@ -0,0 +1,182 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+
+	"github.com/soumya92/barista/base/scheduler"
+)
+
+func assertTick(t *testing.T, s *Scheduler, message string) {
+	select {
+	case <-s.C:
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected a tick", message)
+	}
+}
+
+func assertNoTick(t *testing.T, s *Scheduler, message string) {
+	select {
+	case <-s.C:
+		assert.Fail(t, "unexpected tick", message)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSchedulers(t *testing.T) {
+	TestMode(true)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	AdvanceTo(start)
+	assert.Equal(t, start, Now())
+
+	r := Repeat(time.Minute)
+	assertNoTick(t, r, "before interval")
+	AdvanceBy(time.Minute)
+	assertTick(t, r, "after interval")
+	AdvanceBy(time.Minute)
+	assertTick(t, r, "after another interval")
+
+	AdvanceBy(time.Minute)
+	AdvanceBy(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	assertTick(t, r, "missed ticks are coalesced")
+	assertNoTick(t, r, "missed ticks are coalesced")
+
+	AdvanceBy(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	r.Every(time.Hour)
+	assertNoTick(t, r, "pending tick removed on reschedule")
+	AdvanceBy(time.Minute)
+	assertNoTick(t, r, "after rescheduling")
+	r.Stop()
+	AdvanceBy(time.Hour)
+	assertNoTick(t, r, "when stopped")
+
+	a := At(Now().Add(time.Hour))
+	AdvanceBy(30 * time.Minute)
+	assertNoTick(t, a, "before time")
+	AdvanceBy(30 * time.Minute)
+	assertTick(t, a, "at time")
+	AdvanceBy(time.Hour)
+	assertNoTick(t, a, "only once")
+
+	d := After(time.Second)
+	NextTick()
+	assertTick(t, d, "after delay")
+	d.After(time.Minute)
+	assert.Equal(t, Now().Add(time.Minute), NextTick())
+	assertTick(t, d, "after rescheduling")
+
+	AdvanceBy(15 * time.Second)
+	r.EveryAligned(time.Minute)
+	aligned := Now().Truncate(time.Minute)
+	assert.Equal(t, aligned.Add(time.Minute), NextTick())
+	assertTick(t, r, "aligned to minute")
+	r.Stop()
+
+	n := NewScheduler()
+	AdvanceBy(time.Hour)
+	assertNoTick(t, n, "new scheduler does not tick")
+}
+
+func TestJitter(t *testing.T) {
+	TestMode(true)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	AdvanceTo(start)
+
+	delays := []time.Duration{10 * time.Second, 50 * time.Second, 0, 0}
+	var maxes []time.Duration
+	oldRand := randDuration
+	defer func() { randDuration = oldRand }()
+	randDuration = func(max time.Duration) time.Duration {
+		maxes = append(maxes, max)
+		d := delays[0]
+		delays = delays[1:]
+		return d
+	}
+
+	r := Repeat(time.Minute).Jitter(time.Minute)
+	assert.Equal(t, start.Add(70*time.Second), NextTick(), "first tick is jittered")
+	assertTick(t, r, "first tick")
+	assert.Equal(t, start.Add(170*time.Second), NextTick(),
+		"jitter does not accumulate")
+	assertTick(t, r, "second tick")
+	assert.Equal(t, start.Add(180*time.Second), NextTick(), "zero jitter")
+	assertTick(t, r, "third tick")
+	assert.Equal(t, []time.Duration{time.Minute, time.Minute, time.Minute, time.Minute}, maxes)
+
+	r.Jitter(0)
+	now := Now()
+	assert.Equal(t, now.Add(time.Minute), NextTick(), "jitter disabled")
+	assertTick(t, r, "without jitter")
+
+	assert.Equal(t, Now().Add(time.Minute), r.NextTick(), "next tick")
+	assert.Equal(t, now.Add(time.Minute), r.LastTick(), "last tick")
+
+	a := NewScheduler().Jitter(time.Minute).After(time.Minute)
+	assert.Equal(t, Now().Add(time.Minute), NextTick(), "jitter only applies to Every")
+	assertTick(t, a, "after delay")
+	r.Stop()
+}
+
+func TestTestModeWithBaseScheduler(t *testing.T) {
+	TestMode(true)
+	AdvanceTo(time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC), scheduler.Now())
+
+	updated := make(chan bool, 10)
+	scheduler.Do(func() { updated <- true }).Every(time.Minute)
+	s := Repeat(30 * time.Second)
+
+	assert.Equal(t, time.Date(2018, 1, 5, 10, 0, 30, 0, time.UTC), NextTick())
+	assertTick(t, s, "timing scheduler")
+	assert.Empty(t, updated)
+
+	AdvanceBy(30 * time.Second)
+	assertTick(t, s, "timing scheduler")
+	select {
+	case <-updated:
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected base scheduler to trigger")
+	}
+
+	TestMode(false)
+	assert.WithinDuration(t, time.Now(), Now(), time.Minute, "real time")
+}
+
+func TestPause(t *testing.T) {
+	TestMode(true)
+	start := time.Date(2018, 1, 5, 10, 0, 0, 0, time.UTC)
+	AdvanceTo(start)
+
+	c, err := Cron("*/5 * * * *")
+	assert.NoError(t, err)
+	Pause()
+	AdvanceBy(5 * time.Minute)
+	assertNoTick(t, c, "while paused")
+	AdvanceBy(15 * time.Minute)
+	Resume()
+	assertTick(t, c, "on resume")
+	assertNoTick(t, c, "missed ticks are skipped")
+	AdvanceTo(start.Add(24 * time.Minute))
+	assertNoTick(t, c, "before next tick")
+	AdvanceBy(time.Minute)
+	assertTick(t, c, "after resume")
+	c.Stop()
+}
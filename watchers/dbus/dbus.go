@@ -0,0 +1,384 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package dbus provides watchers for d-bus objects, for modules that show
+information from d-bus services (e.g. UPower, BlueZ, MPRIS, NetworkManager).
+
+All watchers share a single connection to each bus, instead of each module
+opening and managing its own. Watchers cache the properties of an object,
+keep them up to date using the PropertiesChanged signal, and refetch them
+when the service restarts. If the connection to the bus is lost, it is
+re-established, and all watchers are refreshed.
+
+Typical usage would be:
+
+	w := dbus.WatchProperties(dbus.System,
+		"net.hadess.PowerProfiles", "/net/hadess/PowerProfiles",
+		"net.hadess.PowerProfiles")
+	for range w.Updates {
+		props, err := w.Get()
+		// ... update the module using props["ActiveProfile"]
+	}
+*/
+package dbus
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	godbus "github.com/godbus/dbus"
+
+	"github.com/soumya92/barista/base"
+)
+
+// BusType identifies a d-bus bus.
+type BusType int
+
+const (
+	// Session is the per-user session bus.
+	Session BusType = iota
+	// System is the system-wide bus.
+	System
+)
+
+// Constants for the d-bus API.
+const (
+	busIface         = "org.freedesktop.DBus"
+	propsIface       = "org.freedesktop.DBus.Properties"
+	propsChanged     = propsIface + ".PropertiesChanged"
+	nameOwnerChanged = busIface + ".NameOwnerChanged"
+)
+
+// ErrNotConnected is returned while there is no connection to the bus.
+var ErrNotConnected = errors.New("not connected to d-bus")
+
+// ErrServiceUnavailable is returned while the watched service is not running.
+var ErrServiceUnavailable = errors.New("d-bus service not available")
+
+// conn is the part of a d-bus connection used by watchers, so that tests
+// can use a fake bus.
+type conn interface {
+	Object(dest string, path godbus.ObjectPath) godbus.BusObject
+	BusObject() godbus.BusObject
+	Signal(chan<- *godbus.Signal)
+}
+
+// dial opens a new connection to the bus, replaced in tests.
+var dial = func(t BusType) (conn, error) {
+	// A private connection is required since we're using Signal.
+	var c *godbus.Conn
+	var err error
+	if t == System {
+		c, err = godbus.SystemBusPrivate()
+	} else {
+		c, err = godbus.SessionBusPrivate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Need to handle auth and handshake ourselves for private buses.
+	if err := c.Auth(nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.Hello(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// reconnectDelay is the delay before reconnecting to the bus after the
+// connection is lost or fails, replaced in tests.
+var reconnectDelay = 5 * time.Second
+
+// bus manages the shared connection to a bus, and dispatches signals to
+// the watchers using it.
+type bus struct {
+	sync.Mutex
+	busType  BusType
+	conn     conn
+	watchers map[*PropertiesWatcher]bool
+}
+
+var (
+	busesMutex sync.Mutex
+	buses      = map[BusType]*bus{}
+)
+
+// getBus returns the shared bus of the given type, connecting on first use.
+func getBus(t BusType) *bus {
+	busesMutex.Lock()
+	defer busesMutex.Unlock()
+	b, ok := buses[t]
+	if !ok {
+		b = &bus{busType: t, watchers: map[*PropertiesWatcher]bool{}}
+		buses[t] = b
+		go b.run()
+	}
+	return b
+}
+
+// run connects to the bus and dispatches signals, reconnecting whenever
+// the connection is lost.
+func (b *bus) run() {
+	for {
+		c, err := dial(b.busType)
+		if err != nil {
+			b.fail(err)
+			time.Sleep(reconnectDelay)
+			continue
+		}
+		signals := make(chan *godbus.Signal, 10)
+		c.Signal(signals)
+		b.Lock()
+		b.conn = c
+		watchers := b.all()
+		b.Unlock()
+		for _, w := range watchers {
+			go w.subscribe(c)
+		}
+		for s := range signals {
+			b.Lock()
+			watchers := b.all()
+			b.Unlock()
+			for _, w := range watchers {
+				w.handle(c, s)
+			}
+		}
+		// The signal channel is closed when the connection is closed.
+		b.Lock()
+		b.conn = nil
+		b.Unlock()
+		b.fail(ErrNotConnected)
+		time.Sleep(reconnectDelay)
+	}
+}
+
+// all returns all watchers using the bus. Must be called with the lock held.
+func (b *bus) all() []*PropertiesWatcher {
+	var watchers []*PropertiesWatcher
+	for w := range b.watchers {
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
+
+// fail reports an error to all watchers using the bus.
+func (b *bus) fail(err error) {
+	b.Lock()
+	watchers := b.all()
+	b.Unlock()
+	for _, w := range watchers {
+		w.set(nil, err)
+	}
+}
+
+func (b *bus) add(w *PropertiesWatcher) {
+	b.Lock()
+	b.watchers[w] = true
+	c := b.conn
+	b.Unlock()
+	if c != nil {
+		go w.subscribe(c)
+	}
+}
+
+func (b *bus) remove(w *PropertiesWatcher) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.watchers, w)
+}
+
+// connection returns the current connection to the bus, if any.
+func (b *bus) connection() conn {
+	b.Lock()
+	defer b.Unlock()
+	return b.conn
+}
+
+// PropertiesWatcher watches the properties of an interface on a d-bus object.
+type PropertiesWatcher struct {
+	// Updates receives a value whenever the properties change, or an
+	// error occurs. Updates are coalesced, so a slow receiver gets a
+	// single update for all pending changes.
+	Updates <-chan struct{}
+
+	bus      *bus
+	service  string
+	path     godbus.ObjectPath
+	iface    string
+	notifier *base.Notifier
+
+	mutex sync.Mutex
+	props map[string]interface{}
+	err   error
+}
+
+// WatchProperties starts watching the properties of the given interface on
+// the given object, using the shared connection to the bus.
+func WatchProperties(busType BusType, service string, path godbus.ObjectPath, iface string) *PropertiesWatcher {
+	n := base.NewNotifier()
+	w := &PropertiesWatcher{
+		Updates:  n.C,
+		service:  service,
+		path:     path,
+		iface:    iface,
+		notifier: n,
+		bus:      getBus(busType),
+	}
+	w.bus.add(w)
+	return w
+}
+
+// Get returns a copy of the cached properties, or the error that occurred
+// while connecting to the bus or fetching the properties. Until the
+// properties are first fetched, it returns no properties and no error.
+func (w *PropertiesWatcher) Get() (map[string]interface{}, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.err != nil {
+		return nil, w.err
+	}
+	props := map[string]interface{}{}
+	for k, v := range w.props {
+		props[k] = v
+	}
+	return props, nil
+}
+
+// Call calls a method on the object. Methods without an interface prefix
+// are called on the watched interface.
+func (w *PropertiesWatcher) Call(method string, args ...interface{}) *godbus.Call {
+	c := w.bus.connection()
+	if c == nil {
+		return &godbus.Call{Err: ErrNotConnected}
+	}
+	if !strings.Contains(method, ".") {
+		method = w.iface + "." + method
+	}
+	return c.Object(w.service, w.path).Call(method, 0, args...)
+}
+
+// Set sets a property on the watched interface. The cached value is updated
+// when the service signals the change.
+func (w *PropertiesWatcher) Set(name string, value interface{}) error {
+	return w.Call(propsIface+".Set", w.iface, name, godbus.MakeVariant(value)).Err
+}
+
+// Close stops watching the properties.
+func (w *PropertiesWatcher) Close() {
+	w.bus.remove(w)
+}
+
+// set replaces the cached properties and error, and notifies the receiver.
+func (w *PropertiesWatcher) set(props map[string]interface{}, err error) {
+	w.mutex.Lock()
+	w.props = props
+	w.err = err
+	w.mutex.Unlock()
+	w.notifier.Notify()
+}
+
+// subscribe adds the match rules for the watched object, and then fetches
+// the current properties.
+func (w *PropertiesWatcher) subscribe(c conn) {
+	for _, rule := range []string{
+		matchRule(
+			"type='signal'",
+			"interface='"+propsIface+"'",
+			"member='PropertiesChanged'",
+			"path='"+string(w.path)+"'",
+			"arg0='"+w.iface+"'",
+		),
+		matchRule(
+			"type='signal'",
+			"interface='"+busIface+"'",
+			"member='NameOwnerChanged'",
+			"arg0='"+w.service+"'",
+		),
+	} {
+		if err := c.BusObject().Call(busIface+".AddMatch", 0, rule).Err; err != nil {
+			w.set(nil, err)
+			return
+		}
+	}
+	w.refresh(c)
+}
+
+func matchRule(parts ...string) string {
+	return strings.Join(parts, ",")
+}
+
+// refresh fetches all properties from the service.
+func (w *PropertiesWatcher) refresh(c conn) {
+	var variants map[string]godbus.Variant
+	err := c.Object(w.service, w.path).
+		Call(propsIface+".GetAll", 0, w.iface).
+		Store(&variants)
+	if err != nil {
+		w.set(nil, err)
+		return
+	}
+	props := map[string]interface{}{}
+	for k, v := range variants {
+		props[k] = v.Value()
+	}
+	w.set(props, nil)
+}
+
+// handle updates the cached properties for a signal from the bus.
+func (w *PropertiesWatcher) handle(c conn, s *godbus.Signal) {
+	switch s.Name {
+	case propsChanged:
+		if s.Path != w.path || len(s.Body) < 3 {
+			return
+		}
+		if iface, _ := s.Body[0].(string); iface != w.iface {
+			return
+		}
+		changed, _ := s.Body[1].(map[string]godbus.Variant)
+		invalidated, _ := s.Body[2].([]string)
+		w.mutex.Lock()
+		if len(invalidated) > 0 || w.props == nil {
+			w.mutex.Unlock()
+			// Invalidated properties are not included in the signal, and
+			// must be fetched (as must all properties if the last fetch
+			// failed), without blocking signal delivery.
+			go w.refresh(c)
+			return
+		}
+		for k, v := range changed {
+			w.props[k] = v.Value()
+		}
+		w.mutex.Unlock()
+		w.notifier.Notify()
+	case nameOwnerChanged:
+		if len(s.Body) < 3 {
+			return
+		}
+		if name, _ := s.Body[0].(string); name != w.service {
+			return
+		}
+		if owner, _ := s.Body[2].(string); owner == "" {
+			w.set(nil, ErrServiceUnavailable)
+			return
+		}
+		// The service (re)started, so fetch all properties.
+		go w.refresh(c)
+	}
+}
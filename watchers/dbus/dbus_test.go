@@ -0,0 +1,258 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbus
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	godbus "github.com/godbus/dbus"
+	"github.com/stretchrcom/testify/assert"
+)
+
+func init() {
+	reconnectDelay = 10 * time.Millisecond
+}
+
+const (
+	testService = "org.example.Service"
+	testPath    = godbus.ObjectPath("/org/example/Service")
+	testIface   = "org.example.Service"
+)
+
+// fakeBus is a fake d-bus connection with a single object.
+type fakeBus struct {
+	sync.Mutex
+	props   map[string]godbus.Variant
+	signals chan<- *godbus.Signal
+	calls   chan string
+}
+
+func (f *fakeBus) Object(dest string, path godbus.ObjectPath) godbus.BusObject {
+	return &fakeObject{f, dest, path}
+}
+
+func (f *fakeBus) BusObject() godbus.BusObject {
+	return &fakeObject{f, busIface, "/org/freedesktop/DBus"}
+}
+
+func (f *fakeBus) Signal(ch chan<- *godbus.Signal) {
+	f.Lock()
+	defer f.Unlock()
+	f.signals = ch
+}
+
+// emit sends a signal to the watchers, as the bus would.
+func (f *fakeBus) emit(name string, path godbus.ObjectPath, body ...interface{}) {
+	f.Lock()
+	ch := f.signals
+	f.Unlock()
+	ch <- &godbus.Signal{Name: name, Path: path, Body: body}
+}
+
+// disconnect closes the signal channel, as a closed connection would.
+func (f *fakeBus) disconnect() {
+	f.Lock()
+	defer f.Unlock()
+	close(f.signals)
+}
+
+func (f *fakeBus) setProps(props map[string]interface{}) {
+	f.Lock()
+	defer f.Unlock()
+	f.props = map[string]godbus.Variant{}
+	for k, v := range props {
+		f.props[k] = godbus.MakeVariant(v)
+	}
+}
+
+type fakeObject struct {
+	bus  *fakeBus
+	dest string
+	path godbus.ObjectPath
+}
+
+func (o *fakeObject) Call(method string, flags godbus.Flags, args ...interface{}) *godbus.Call {
+	f := o.bus
+	switch method {
+	case busIface + ".AddMatch":
+		return &godbus.Call{}
+	case propsIface + ".GetAll":
+		f.Lock()
+		defer f.Unlock()
+		if f.props == nil || o.path != testPath {
+			return &godbus.Call{Err: errors.New("no such object")}
+		}
+		props := map[string]godbus.Variant{}
+		for k, v := range f.props {
+			props[k] = v
+		}
+		return &godbus.Call{Body: []interface{}{props}}
+	}
+	f.calls <- method
+	return &godbus.Call{}
+}
+
+func (o *fakeObject) Go(string, godbus.Flags, chan *godbus.Call, ...interface{}) *godbus.Call {
+	panic("not implemented")
+}
+
+func (o *fakeObject) GetProperty(string) (godbus.Variant, error) {
+	panic("not implemented")
+}
+
+func (o *fakeObject) Destination() string     { return o.dest }
+func (o *fakeObject) Path() godbus.ObjectPath { return o.path }
+
+// newFakeBus replaces the shared buses with a new fake bus, on which the
+// first dials fail with the given errors.
+func newFakeBus(props map[string]interface{}, dialErrs ...error) *fakeBus {
+	f := &fakeBus{calls: make(chan string, 10)}
+	if props != nil {
+		f.setProps(props)
+	}
+	var dialMutex sync.Mutex
+	busesMutex.Lock()
+	buses = map[BusType]*bus{}
+	dial = func(BusType) (conn, error) {
+		dialMutex.Lock()
+		defer dialMutex.Unlock()
+		if len(dialErrs) > 0 {
+			err := dialErrs[0]
+			dialErrs = dialErrs[1:]
+			return nil, err
+		}
+		return f, nil
+	}
+	busesMutex.Unlock()
+	return f
+}
+
+func assertUpdated(t *testing.T, w *PropertiesWatcher, message string) {
+	select {
+	case <-w.Updates:
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected an update", message)
+	}
+}
+
+func assertNotUpdated(t *testing.T, w *PropertiesWatcher, message string) {
+	select {
+	case <-w.Updates:
+		assert.Fail(t, "unexpected update", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// waitForProps waits until the watcher has fetched some properties, and
+// they satisfy the given condition, if any.
+func waitForProps(t *testing.T, w *PropertiesWatcher, message string, conds ...func(map[string]interface{}) bool) map[string]interface{} {
+	timeout := time.After(time.Second)
+	for {
+		props, err := w.Get()
+		if err == nil && len(props) > 0 && (len(conds) == 0 || conds[0](props)) {
+			return props
+		}
+		select {
+		case <-w.Updates:
+		case <-timeout:
+			props, err := w.Get()
+			assert.Fail(t, "timed out waiting for properties", "%s: %v, %v", message, props, err)
+			return nil
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	f := newFakeBus(map[string]interface{}{"Percentage": 50.0, "State": uint32(1)})
+	w := WatchProperties(System, testService, testPath, testIface)
+	defer w.Close()
+
+	props := waitForProps(t, w, "on start")
+	assert.Equal(t, map[string]interface{}{"Percentage": 50.0, "State": uint32(1)}, props)
+
+	f.emit(propsChanged, testPath, testIface,
+		map[string]godbus.Variant{"Percentage": godbus.MakeVariant(45.0)}, []string{})
+	assertUpdated(t, w, "on properties changed")
+	props, _ = w.Get()
+	assert.Equal(t, 45.0, props["Percentage"], "changed property")
+	assert.Equal(t, uint32(1), props["State"], "other properties are kept")
+
+	props["State"] = uint32(5)
+	props, _ = w.Get()
+	assert.Equal(t, uint32(1), props["State"], "Get returns a copy")
+
+	f.emit(propsChanged, "/other", testIface,
+		map[string]godbus.Variant{"Percentage": godbus.MakeVariant(1.0)}, []string{})
+	f.emit(propsChanged, testPath, "org.example.Other",
+		map[string]godbus.Variant{"Percentage": godbus.MakeVariant(1.0)}, []string{})
+	assertNotUpdated(t, w, "on other objects or interfaces")
+
+	f.setProps(map[string]interface{}{"Percentage": 40.0, "State": uint32(2)})
+	f.emit(propsChanged, testPath, testIface,
+		map[string]godbus.Variant{}, []string{"State"})
+	assertUpdated(t, w, "on properties invalidated")
+	props, _ = w.Get()
+	assert.Equal(t, uint32(2), props["State"], "invalidated properties are fetched")
+
+	f.emit(nameOwnerChanged, "/org/freedesktop/DBus", testService, ":1.5", "")
+	assertUpdated(t, w, "on service exit")
+	_, err := w.Get()
+	assert.Equal(t, ErrServiceUnavailable, err)
+
+	f.setProps(map[string]interface{}{"Percentage": 100.0})
+	f.emit(nameOwnerChanged, "/org/freedesktop/DBus", "org.example.Other", "", ":1.6")
+	assertNotUpdated(t, w, "on other service start")
+	f.emit(nameOwnerChanged, "/org/freedesktop/DBus", testService, "", ":1.7")
+	props = waitForProps(t, w, "on service restart")
+	assert.Equal(t, map[string]interface{}{"Percentage": 100.0}, props)
+}
+
+func TestCalls(t *testing.T) {
+	f := newFakeBus(map[string]interface{}{"Percentage": 50.0})
+	w := WatchProperties(Session, testService, testPath, testIface)
+	defer w.Close()
+	waitForProps(t, w, "on start")
+
+	assert.NoError(t, w.Call("Suspend").Err)
+	assert.Equal(t, testIface+".Suspend", <-f.calls, "uses watched interface")
+	assert.NoError(t, w.Call("org.example.Other.Method", 1).Err)
+	assert.Equal(t, "org.example.Other.Method", <-f.calls)
+	assert.NoError(t, w.Set("Percentage", 10.0))
+	assert.Equal(t, propsIface+".Set", <-f.calls)
+}
+
+func TestReconnect(t *testing.T) {
+	f := newFakeBus(map[string]interface{}{"State": uint32(1)}, errors.New("no bus"))
+	w := WatchProperties(System, testService, testPath, testIface)
+	defer w.Close()
+
+	props := waitForProps(t, w, "after dial error")
+	assert.Equal(t, uint32(1), props["State"])
+
+	f.setProps(map[string]interface{}{"State": uint32(2)})
+	f.disconnect()
+	waitForProps(t, w, "properties refetched after reconnecting",
+		func(props map[string]interface{}) bool { return props["State"] == uint32(2) })
+
+	other := WatchProperties(System, testService, "/missing", testIface)
+	defer other.Close()
+	assertUpdated(t, other, "on fetch error")
+	_, err := other.Get()
+	assert.Error(t, err, "error fetching properties")
+	assert.Equal(t, 1, len(buses), "connection is shared")
+}
@@ -0,0 +1,219 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package file provides a watcher for changes to a file or directory, built on
+inotify, for modules that display the contents of files.
+
+Watching a file directly misses changes when editors and tools replace the
+file (e.g. by writing a temporary file and renaming it), and fails if the
+file or its directory does not exist yet. Instead, the watcher watches the
+deepest existing directory on the way to the file, and moves the watch as
+directories are created or removed. If the path is a directory, changes to
+its contents are also reported, e.g. for maildirs.
+
+Changes are debounced, so that a burst of events, such as an editor saving
+a file in several steps, results in a single update once the changes have
+settled.
+
+Typical usage would be:
+
+	w, err := file.Watch(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for {
+		select {
+		case <-w.Updates:
+			// re-read the file
+		case err := <-w.Errors:
+			return err
+		}
+	}
+*/
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the debounce delay used by Watch.
+const DefaultDebounce = 50 * time.Millisecond
+
+// Watcher watches a single file or directory for changes.
+type Watcher struct {
+	// Updates receives a value after the file changes, once no further
+	// changes have occurred for the debounce delay. Changes are coalesced,
+	// so a slow receiver gets a single update for all pending changes.
+	Updates <-chan struct{}
+
+	// Errors receives an error if watching fails, after which the watcher
+	// stops and no further updates are sent.
+	Errors <-chan error
+
+	path     string
+	debounce time.Duration
+	fsw      *fsnotify.Watcher
+	watched  map[string]bool
+	updates  chan struct{}
+	errors   chan error
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Watch starts watching the given file or directory, which need not exist
+// yet, using the default debounce delay.
+func Watch(path string) (*Watcher, error) {
+	return WatchDebounced(path, DefaultDebounce)
+}
+
+// WatchDebounced starts watching the given file or directory, which need
+// not exist yet, using the given debounce delay.
+func WatchDebounced(path string, debounce time.Duration) (*Watcher, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	updates := make(chan struct{}, 1)
+	errors := make(chan error, 1)
+	w := &Watcher{
+		Updates:  updates,
+		Errors:   errors,
+		path:     path,
+		debounce: debounce,
+		fsw:      fsw,
+		watched:  map[string]bool{},
+		updates:  updates,
+		errors:   errors,
+		done:     make(chan struct{}),
+	}
+	if err := w.rewatch(); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching for changes.
+func (w *Watcher) Close() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *Watcher) run() {
+	defer w.fsw.Close()
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case e, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			changed, err := w.handle(e)
+			if err != nil {
+				w.errors <- err
+				return
+			}
+			if changed {
+				settled = time.After(w.debounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if ok {
+				w.errors <- err
+			}
+			return
+		case <-settled:
+			settled = nil
+			select {
+			case w.updates <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// handle processes an inotify event, moving the watch if needed, and
+// returns true if the event could have changed the watched path.
+func (w *Watcher) handle(e fsnotify.Event) (bool, error) {
+	name := filepath.Clean(e.Name)
+	switch {
+	case name == w.path:
+		// The path itself may have been created, removed, or replaced
+		// by a directory (or vice versa), so update the watches.
+		return true, w.rewatch()
+	case filepath.Dir(name) == w.path:
+		// Contents of a watched directory.
+		return true, nil
+	case strings.HasPrefix(w.path, name+string(filepath.Separator)):
+		// A directory on the way to the path was created or removed.
+		return true, w.rewatch()
+	}
+	// Other files in a watched directory.
+	return false, nil
+}
+
+// rewatch watches the deepest existing directory that contains the path,
+// and the path itself if it is a directory, replacing any previous watches.
+func (w *Watcher) rewatch() error {
+	wanted := map[string]bool{}
+	if stat, err := os.Stat(w.path); err == nil && stat.IsDir() {
+		wanted[w.path] = true
+	}
+	dir := filepath.Dir(w.path)
+	for {
+		if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	wanted[dir] = true
+	for path := range w.watched {
+		if !wanted[path] {
+			// The watch is already gone if the directory was removed.
+			w.fsw.Remove(path)
+			delete(w.watched, path)
+		}
+	}
+	for path := range wanted {
+		if w.watched[path] {
+			continue
+		}
+		if err := w.fsw.Add(path); err != nil {
+			if os.IsNotExist(err) {
+				// Removed since it was checked, so look again.
+				return w.rewatch()
+			}
+			return err
+		}
+		w.watched[path] = true
+	}
+	return nil
+}
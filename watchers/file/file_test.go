@@ -0,0 +1,171 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+const debounce = 20 * time.Millisecond
+
+func tempDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func watch(t *testing.T, path string) *Watcher {
+	w, err := WatchDebounced(path, debounce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func assertUpdated(t *testing.T, w *Watcher, message string) {
+	select {
+	case <-w.Updates:
+	case err := <-w.Errors:
+		assert.Fail(t, "unexpected error: "+err.Error(), message)
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected an update", message)
+	}
+}
+
+func assertNotUpdated(t *testing.T, w *Watcher, message string) {
+	select {
+	case <-w.Updates:
+		assert.Fail(t, "unexpected update", message)
+	case err := <-w.Errors:
+		assert.Fail(t, "unexpected error: "+err.Error(), message)
+	case <-time.After(5 * debounce):
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	file := filepath.Join(dir, "todo.txt")
+
+	w := watch(t, file)
+	defer w.Close()
+	assertNotUpdated(t, w, "on start")
+
+	ioutil.WriteFile(file, []byte("a"), 0644)
+	assertUpdated(t, w, "on create")
+
+	ioutil.WriteFile(file, []byte("b"), 0644)
+	assertUpdated(t, w, "on write")
+
+	ioutil.WriteFile(filepath.Join(dir, "done.txt"), []byte("x"), 0644)
+	assertNotUpdated(t, w, "on other file change")
+
+	ioutil.WriteFile(file+".new", []byte("c"), 0644)
+	os.Rename(file+".new", file)
+	assertUpdated(t, w, "on replace")
+
+	os.Remove(file)
+	assertUpdated(t, w, "on remove")
+}
+
+func TestDebounce(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	file := filepath.Join(dir, "config")
+
+	w := watch(t, file)
+	defer w.Close()
+	for i := 0; i < 10; i++ {
+		ioutil.WriteFile(file, []byte{byte(i)}, 0644)
+	}
+	assertUpdated(t, w, "after changes settle")
+	assertNotUpdated(t, w, "changes are coalesced")
+}
+
+func TestMissingDirectories(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	file := filepath.Join(dir, "a", "b", "config")
+
+	w := watch(t, file)
+	defer w.Close()
+
+	os.Mkdir(filepath.Join(dir, "a"), 0755)
+	os.Mkdir(filepath.Join(dir, "a", "b"), 0755)
+	// Drain any updates from the directories being created, which may
+	// or may not be coalesced.
+	time.Sleep(5 * debounce)
+	select {
+	case <-w.Updates:
+	default:
+	}
+
+	ioutil.WriteFile(file, []byte("a"), 0644)
+	assertUpdated(t, w, "on create in new directory")
+
+	ioutil.WriteFile(filepath.Join(dir, "a", "other"), []byte("x"), 0644)
+	assertNotUpdated(t, w, "on other file change")
+
+	os.RemoveAll(filepath.Join(dir, "a"))
+	assertUpdated(t, w, "on directory removed")
+
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	time.Sleep(5 * debounce)
+	select {
+	case <-w.Updates:
+	default:
+	}
+	ioutil.WriteFile(file, []byte("b"), 0644)
+	assertUpdated(t, w, "on create in re-created directory")
+}
+
+func TestDirectory(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	maildir := filepath.Join(dir, "inbox", "new")
+	os.MkdirAll(maildir, 0755)
+
+	w := watch(t, maildir)
+	defer w.Close()
+
+	ioutil.WriteFile(filepath.Join(maildir, "1"), []byte("mail"), 0644)
+	assertUpdated(t, w, "on new file in directory")
+
+	os.Remove(filepath.Join(maildir, "1"))
+	assertUpdated(t, w, "on file removed from directory")
+
+	ioutil.WriteFile(filepath.Join(dir, "inbox", "other"), []byte("x"), 0644)
+	assertNotUpdated(t, w, "on change outside directory")
+}
+
+func TestClose(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	file := filepath.Join(dir, "file")
+
+	w := watch(t, file)
+	w.Close()
+	w.Close()
+	ioutil.WriteFile(file, []byte("a"), 0644)
+	assertNotUpdated(t, w, "after close")
+}
@@ -0,0 +1,302 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package netlink provides watchers for network links, for modules that show
+information about network interfaces (e.g. vpn, wlan).
+
+All watchers share a single netlink subscription for link, address, and
+route changes, instead of each module subscribing (or polling) on its own.
+Watchers cache the state of a link, and refresh it whenever the kernel
+signals a change to the link, its addresses, or the routes through it.
+If the subscription fails, it is re-established and all watchers are
+refreshed.
+
+Wireless information (SSID, frequency, etc.) is not available through
+rtnetlink, so wireless modules should watch the link and re-query the
+wireless state when the link changes.
+
+Typical usage would be:
+
+	w := netlink.WatchLink("wlan0")
+	for range w.Updates {
+		link, err := w.Get()
+		// ... update the module using link.State and link.IPs
+	}
+*/
+package netlink
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	nl "github.com/vishvananda/netlink"
+
+	"github.com/soumya92/barista/base"
+)
+
+// State represents the operational state of a link.
+type State int
+
+const (
+	// Missing means the link does not exist.
+	Missing State = iota
+	// Down means the link is administratively down.
+	Down
+	// Up means the link is up, but not running (e.g. no carrier).
+	Up
+	// Running means the link is up and running.
+	Running
+)
+
+// Link represents the state of a network link.
+type Link struct {
+	Name         string
+	Index        int
+	State        State
+	HardwareAddr net.HardwareAddr
+	IPs          []net.IP
+}
+
+// subscribe subscribes to link, address, and route changes until done is
+// closed, replaced in tests. The channels are closed if the subscription
+// fails after it has started.
+var subscribe = func(
+	links chan<- nl.LinkUpdate,
+	addrs chan<- nl.AddrUpdate,
+	routes chan<- nl.RouteUpdate,
+	done <-chan struct{},
+) error {
+	if err := nl.LinkSubscribe(links, done); err != nil {
+		return err
+	}
+	if err := nl.AddrSubscribe(addrs, done); err != nil {
+		return err
+	}
+	return nl.RouteSubscribe(routes, done)
+}
+
+// linkByName and addrList are used to fetch the state of a link,
+// replaced in tests.
+var (
+	linkByName = nl.LinkByName
+	addrList   = nl.AddrList
+)
+
+// resubscribeDelay is the delay before resubscribing after the subscription
+// fails, replaced in tests.
+var resubscribeDelay = 5 * time.Second
+
+// hub manages the shared subscription, and dispatches changes to the
+// watchers using it.
+type hub struct {
+	sync.Mutex
+	started  bool
+	watchers map[*LinkWatcher]bool
+}
+
+var shared = &hub{watchers: map[*LinkWatcher]bool{}}
+
+// run subscribes to netlink and dispatches changes, resubscribing whenever
+// the subscription fails.
+func (h *hub) run() {
+	for {
+		links := make(chan nl.LinkUpdate, 10)
+		addrs := make(chan nl.AddrUpdate, 10)
+		routes := make(chan nl.RouteUpdate, 10)
+		done := make(chan struct{})
+		if err := subscribe(links, addrs, routes, done); err != nil {
+			close(done)
+			for _, w := range h.all() {
+				w.set(Link{Name: w.name}, err)
+			}
+			time.Sleep(resubscribeDelay)
+			continue
+		}
+		for _, w := range h.all() {
+			w.refresh()
+		}
+		h.dispatch(links, addrs, routes)
+		close(done)
+		time.Sleep(resubscribeDelay)
+	}
+}
+
+// dispatch refreshes the watchers affected by each change, until any of the
+// subscription channels is closed.
+func (h *hub) dispatch(links <-chan nl.LinkUpdate, addrs <-chan nl.AddrUpdate, routes <-chan nl.RouteUpdate) {
+	for {
+		var affected func(*LinkWatcher) bool
+		select {
+		case u, ok := <-links:
+			if !ok {
+				return
+			}
+			name := u.Attrs().Name
+			affected = func(w *LinkWatcher) bool { return w.name == name }
+		case u, ok := <-addrs:
+			if !ok {
+				return
+			}
+			affected = func(w *LinkWatcher) bool { return w.index() == u.LinkIndex }
+		case u, ok := <-routes:
+			if !ok {
+				return
+			}
+			affected = func(w *LinkWatcher) bool { return w.index() == u.LinkIndex }
+		}
+		for _, w := range h.all() {
+			if affected(w) {
+				w.refresh()
+			}
+		}
+	}
+}
+
+// all returns all watchers using the hub.
+func (h *hub) all() []*LinkWatcher {
+	h.Lock()
+	defer h.Unlock()
+	var watchers []*LinkWatcher
+	for w := range h.watchers {
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
+
+func (h *hub) add(w *LinkWatcher) {
+	h.Lock()
+	h.watchers[w] = true
+	start := !h.started
+	h.started = true
+	h.Unlock()
+	if start {
+		go h.run()
+	} else {
+		go w.refresh()
+	}
+}
+
+func (h *hub) remove(w *LinkWatcher) {
+	h.Lock()
+	defer h.Unlock()
+	delete(h.watchers, w)
+}
+
+// LinkWatcher watches the state of a network link.
+type LinkWatcher struct {
+	// Updates receives a value whenever the link, its addresses, or the
+	// routes through it change, or an error occurs. Updates are coalesced,
+	// so a slow receiver gets a single update for all pending changes.
+	Updates <-chan struct{}
+
+	name     string
+	notifier *base.Notifier
+
+	mutex   sync.Mutex
+	link    Link
+	err     error
+	fetched bool
+}
+
+// WatchLink starts watching the link with the given name, using the shared
+// netlink subscription. Links that do not exist yet are reported as Missing,
+// and are picked up when they are created.
+func WatchLink(name string) *LinkWatcher {
+	n := base.NewNotifier()
+	w := &LinkWatcher{
+		Updates:  n.C,
+		name:     name,
+		notifier: n,
+		link:     Link{Name: name},
+	}
+	shared.add(w)
+	return w
+}
+
+// Get returns the current state of the link, or the error that occurred
+// while subscribing or fetching the state of the link.
+func (w *LinkWatcher) Get() (Link, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.link, w.err
+}
+
+// Close stops watching the link.
+func (w *LinkWatcher) Close() {
+	shared.remove(w)
+}
+
+func (w *LinkWatcher) index() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.link.Index
+}
+
+// set replaces the cached state and error, and notifies the receiver if
+// either has changed (or this is the first fetch).
+func (w *LinkWatcher) set(link Link, err error) {
+	w.mutex.Lock()
+	changed := !w.fetched || !reflect.DeepEqual(link, w.link) || err != w.err
+	w.link = link
+	w.err = err
+	w.fetched = true
+	w.mutex.Unlock()
+	if changed {
+		w.notifier.Notify()
+	}
+}
+
+// refresh fetches the state and addresses of the link.
+func (w *LinkWatcher) refresh() {
+	l, err := linkByName(w.name)
+	if _, ok := err.(nl.LinkNotFoundError); ok {
+		w.set(Link{Name: w.name}, nil)
+		return
+	}
+	if err != nil {
+		w.set(Link{Name: w.name}, err)
+		return
+	}
+	attrs := l.Attrs()
+	link := Link{
+		Name:         w.name,
+		Index:        attrs.Index,
+		State:        state(attrs.RawFlags),
+		HardwareAddr: attrs.HardwareAddr,
+	}
+	addrs, err := addrList(l, nl.FAMILY_ALL)
+	if err != nil {
+		w.set(link, err)
+		return
+	}
+	for _, a := range addrs {
+		link.IPs = append(link.IPs, a.IP)
+	}
+	w.set(link, nil)
+}
+
+func state(flags uint32) State {
+	switch {
+	case flags&syscall.IFF_UP == 0:
+		return Down
+	case flags&syscall.IFF_RUNNING == 0:
+		return Up
+	default:
+		return Running
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netlink
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchrcom/testify/assert"
+	nl "github.com/vishvananda/netlink"
+)
+
+// fakeNetlink is a fake kernel with a set of links.
+type fakeNetlink struct {
+	sync.Mutex
+	links        map[string]*nl.Device
+	addrs        map[int][]nl.Addr
+	subscribeErr error
+	linkCh       chan<- nl.LinkUpdate
+	addrCh       chan<- nl.AddrUpdate
+	routeCh      chan<- nl.RouteUpdate
+	subscribed   chan struct{}
+}
+
+var fake = &fakeNetlink{
+	links:      map[string]*nl.Device{},
+	addrs:      map[int][]nl.Addr{},
+	subscribed: make(chan struct{}, 10),
+}
+
+func init() {
+	resubscribeDelay = 10 * time.Millisecond
+	subscribe = func(links chan<- nl.LinkUpdate, addrs chan<- nl.AddrUpdate, routes chan<- nl.RouteUpdate, done <-chan struct{}) error {
+		fake.Lock()
+		defer fake.Unlock()
+		if err := fake.subscribeErr; err != nil {
+			fake.subscribeErr = nil
+			return err
+		}
+		fake.linkCh, fake.addrCh, fake.routeCh = links, addrs, routes
+		fake.subscribed <- struct{}{}
+		return nil
+	}
+	linkByName = func(name string) (nl.Link, error) {
+		fake.Lock()
+		defer fake.Unlock()
+		if l, ok := fake.links[name]; ok {
+			c := *l
+			return &c, nil
+		}
+		return nil, nl.LinkNotFoundError{}
+	}
+	addrList = func(l nl.Link, family int) ([]nl.Addr, error) {
+		fake.Lock()
+		defer fake.Unlock()
+		return fake.addrs[l.Attrs().Index], nil
+	}
+}
+
+// setLink adds or updates a link, and signals the change.
+func (f *fakeNetlink) setLink(name string, index int, flags uint32, ips ...string) {
+	f.Lock()
+	l := &nl.Device{LinkAttrs: nl.LinkAttrs{Name: name, Index: index, RawFlags: flags}}
+	f.links[name] = l
+	var addrs []nl.Addr
+	for _, ip := range ips {
+		addrs = append(addrs, nl.Addr{IPNet: &net.IPNet{IP: net.ParseIP(ip)}})
+	}
+	f.addrs[index] = addrs
+	ch := f.linkCh
+	f.Unlock()
+	ch <- nl.LinkUpdate{Link: l}
+}
+
+// setAddrs updates the addresses of a link, and signals the change.
+func (f *fakeNetlink) setAddrs(index int, ips ...string) {
+	f.Lock()
+	var addrs []nl.Addr
+	for _, ip := range ips {
+		addrs = append(addrs, nl.Addr{IPNet: &net.IPNet{IP: net.ParseIP(ip)}})
+	}
+	f.addrs[index] = addrs
+	ch := f.addrCh
+	f.Unlock()
+	ch <- nl.AddrUpdate{LinkIndex: index}
+}
+
+// fail closes the subscription, as netlink does when the socket fails.
+func (f *fakeNetlink) fail() {
+	f.Lock()
+	defer f.Unlock()
+	close(f.linkCh)
+}
+
+func waitForSubscription(t *testing.T) {
+	select {
+	case <-fake.subscribed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "expected a subscription")
+	}
+}
+
+// waitForLink waits until the watcher's link satisfies the given condition.
+func waitForLink(t *testing.T, w *LinkWatcher, message string, cond func(Link, error) bool) Link {
+	timeout := time.After(time.Second)
+	for {
+		l, err := w.Get()
+		if cond(l, err) {
+			return l
+		}
+		select {
+		case <-w.Updates:
+		case <-timeout:
+			assert.Fail(t, "timed out waiting for link", "%s: %+v, %v", message, l, err)
+			return l
+		}
+	}
+}
+
+func hasState(s State) func(Link, error) bool {
+	return func(l Link, err error) bool { return err == nil && l.State == s }
+}
+
+func TestLinkWatcher(t *testing.T) {
+	fake.Lock()
+	fake.links = map[string]*nl.Device{
+		"eth0": {LinkAttrs: nl.LinkAttrs{Name: "eth0", Index: 2}},
+	}
+	fake.addrs = map[int][]nl.Addr{}
+	subscribed := fake.linkCh != nil
+	fake.Unlock()
+
+	w := WatchLink("eth0")
+	defer w.Close()
+	if !subscribed {
+		waitForSubscription(t)
+	}
+	l := waitForLink(t, w, "initial state", hasState(Down))
+	assert.Equal(t, 2, l.Index)
+	assert.Empty(t, l.IPs)
+
+	missing := WatchLink("wlan0")
+	defer missing.Close()
+	waitForLink(t, missing, "missing link", func(l Link, err error) bool {
+		return err == nil && l.State == Missing && l.Name == "wlan0"
+	})
+
+	fake.setLink("eth0", 2, syscall.IFF_UP)
+	waitForLink(t, w, "link up", hasState(Up))
+
+	fake.setLink("eth0", 2, syscall.IFF_UP|syscall.IFF_RUNNING)
+	waitForLink(t, w, "link running", hasState(Running))
+
+	fake.setAddrs(2, "192.168.1.2", "fe80::1")
+	l = waitForLink(t, w, "addresses", func(l Link, err error) bool {
+		return len(l.IPs) == 2
+	})
+	assert.Equal(t, "192.168.1.2", l.IPs[0].String())
+	assert.Equal(t, "fe80::1", l.IPs[1].String())
+
+	fake.setLink("wlan0", 3, syscall.IFF_UP|syscall.IFF_RUNNING, "10.0.0.1")
+	l = waitForLink(t, missing, "link created", hasState(Running))
+	assert.Equal(t, 3, l.Index)
+	assert.Equal(t, "10.0.0.1", l.IPs[0].String())
+
+	select {
+	case <-w.Updates:
+		assert.Fail(t, "unexpected update", "for changes to other links")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.Lock()
+	fake.subscribeErr = errors.New("foo")
+	fake.Unlock()
+	fake.fail()
+	waitForLink(t, w, "subscription error", func(l Link, err error) bool {
+		return err != nil && err.Error() == "foo"
+	})
+	waitForSubscription(t)
+	waitForLink(t, w, "resubscribed", hasState(Running))
+	waitForLink(t, missing, "resubscribed", hasState(Running))
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchrcom/testify/assert"
+)
+
+func TestFrames(t *testing.T) {
+	assert := assert.New(t)
+	for _, size := range []int{0, 125, 126, 200, 70000} {
+		payload := bytes.Repeat([]byte("x"), size)
+		for _, mask := range []bool{true, false} {
+			var buf bytes.Buffer
+			assert.NoError(WriteFrame(&buf, OpText, payload, mask))
+			fin, opcode, read, err := ReadFrame(bufio.NewReader(&buf))
+			assert.NoError(err)
+			assert.True(fin)
+			assert.Equal(byte(OpText), opcode)
+			assert.Equal(payload, read, "size %d, masked %v", size, mask)
+		}
+	}
+	_, _, _, err := ReadFrame(bufio.NewReader(bytes.NewReader([]byte{0x81, 5, 'a'})))
+	assert.Error(err, "truncated frame")
+}
+
+func TestReadMessage(t *testing.T) {
+	assert := assert.New(t)
+	client, server := net.Pipe()
+	defer server.Close()
+	c := &Conn{conn: client, reader: bufio.NewReader(client)}
+	go func() {
+		WriteFrame(server, OpPing, []byte("hi"), false)
+		// Fragmented message.
+		server.Write([]byte{OpText, 4})
+		server.Write([]byte(`{"a"`))
+		server.Write([]byte{0x80 | OpContinuation, 4})
+		server.Write([]byte(`: 1}`))
+		WriteFrame(server, OpBinary, []byte("bin"), false)
+		WriteFrame(server, OpClose, nil, false)
+	}()
+	pongs := make(chan string, 1)
+	go func() {
+		_, opcode, payload, _ := ReadFrame(bufio.NewReader(server))
+		if opcode == OpPong {
+			pongs <- string(payload)
+		}
+	}()
+	var value map[string]int
+	assert.NoError(c.ReadJSON(&value))
+	assert.Equal(map[string]int{"a": 1}, value)
+	assert.Equal("hi", <-pongs)
+	msg, err := c.ReadMessage()
+	assert.NoError(err)
+	assert.Equal("bin", string(msg))
+	_, err = c.ReadMessage()
+	assert.Equal(io.EOF, err, "on close")
+}
+
+func TestDial(t *testing.T) {
+	assert := assert.New(t)
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/socket" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		received <- r.Header.Get("Authorization") + " " + r.URL.RawQuery
+		conn, reader, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, payload, _ := ReadFrame(reader)
+		WriteFrame(conn, OpText, bytes.ToUpper(payload), false)
+	}))
+	defer server.Close()
+	wsURL := strings.Replace(server.URL, "http", "ws", 1)
+
+	c, err := Dial(wsURL+"/socket?a=b", http.Header{"Authorization": {"Bearer abc"}})
+	assert.NoError(err)
+	assert.Equal("Bearer abc a=b", <-received)
+	assert.NoError(c.WriteJSON("hello"))
+	msg, err := c.ReadMessage()
+	assert.NoError(err)
+	assert.Equal(`"HELLO"`, string(msg))
+	c.Close()
+
+	_, err = Dial(wsURL+"/other", nil)
+	assert.Error(err, "not found")
+	_, err = Dial(server.URL, nil)
+	assert.Error(err, "unsupported scheme")
+}